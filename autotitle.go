@@ -6,7 +6,11 @@ package autotitle
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 	"slices"
@@ -17,13 +21,24 @@ import (
 	"github.com/mydehq/autotitle/internal/backup"
 	"github.com/mydehq/autotitle/internal/config"
 	"github.com/mydehq/autotitle/internal/database"
+	"github.com/mydehq/autotitle/internal/dupes"
+	"github.com/mydehq/autotitle/internal/history"
+	"github.com/mydehq/autotitle/internal/journal"
+	"github.com/mydehq/autotitle/internal/learn"
 	"github.com/mydehq/autotitle/internal/matcher"
+	"github.com/mydehq/autotitle/internal/mediaserver"
 	"github.com/mydehq/autotitle/internal/provider"
-	_ "github.com/mydehq/autotitle/internal/provider/filler" // Register filler sources
+	"github.com/mydehq/autotitle/internal/provider/filler" // Also registers filler sources
 	"github.com/mydehq/autotitle/internal/renamer"
+	"github.com/mydehq/autotitle/internal/review"
+	"github.com/mydehq/autotitle/internal/serve"
+	"github.com/mydehq/autotitle/internal/sinks"
+	"github.com/mydehq/autotitle/internal/sorter"
 	"github.com/mydehq/autotitle/internal/tagger"
 	"github.com/mydehq/autotitle/internal/types"
+	"github.com/mydehq/autotitle/internal/util"
 	"github.com/mydehq/autotitle/internal/version"
+	"github.com/mydehq/autotitle/internal/watch"
 )
 
 // Re-export types
@@ -34,13 +49,35 @@ type (
 	Event           = types.Event
 	EventHandler    = types.EventHandler
 	MediaSummary    = types.MediaSummary
+	DatabaseStats   = types.DatabaseStats
+	CacheStats      = types.CacheStats
+	UsageStats      = types.UsageStats
+	ReviewItem      = types.ReviewItem
+	Alias           = types.Alias
+	ParsedFilename  = types.ParsedFilename
+	RestoreEntry    = types.RestoreEntry
+	SortGroup       = types.SortGroup
+	BackupReport    = types.BackupReport
+	BackupMethod    = types.BackupMethod
+	Batch           = types.Batch
+	DuplicateSet    = types.DuplicateSet
+	DedupeAction    = types.DedupeAction
+	ConflictAction  = types.ConflictAction
 	SearchResult    = types.SearchResult
 	MediaType       = types.MediaType
 	OperationStatus = types.OperationStatus
 	EventType       = types.EventType
-
-	Pattern      = matcher.Pattern
-	TemplateVars = matcher.TemplateVars
+	SkipReason      = types.SkipReason
+	Clock           = types.Clock
+	EventSinks      = sinks.Set
+	PriorityConfig  = types.PriorityConfig
+	Library         = types.Library
+	ConfigReload    = types.ConfigReload
+	BatchManifest   = types.BatchManifest
+
+	Pattern          = matcher.Pattern
+	TemplateVars     = matcher.TemplateVars
+	SimilarityMetric = types.SimilarityMetric
 )
 
 // Event Types & Status
@@ -55,6 +92,25 @@ const (
 	StatusSuccess = types.StatusSuccess
 	StatusSkipped = types.StatusSkipped
 	StatusFailed  = types.StatusFailed
+
+	ReasonNoPattern = types.ReasonNoPattern
+	ReasonNoEpisode = types.ReasonNoEpisode
+	ReasonCollision = types.ReasonCollision
+	ReasonUnchanged = types.ReasonUnchanged
+	ReasonLocked    = types.ReasonLocked
+	ReasonError     = types.ReasonError
+
+	DedupeReport = types.DedupeReport
+	DedupeLink   = types.DedupeLink
+	DedupeRemove = types.DedupeRemove
+
+	BackupLink     = types.BackupLink
+	BackupCopy     = types.BackupCopy
+	BackupSnapshot = types.BackupSnapshot
+
+	ConflictKeepNew  = types.ConflictKeepNew
+	ConflictRestore  = types.ConflictRestore
+	ConflictKeepBoth = types.ConflictKeepBoth
 )
 
 // Option is a functional option for configuring operations
@@ -62,9 +118,10 @@ type Option func(*Options)
 
 // Options holds configuration for autotitle operations
 type Options struct {
-	DryRun   bool
-	NoBackup bool
-	NoTag    bool
+	DryRun      bool
+	NoBackup    bool
+	NoTag       bool
+	AcceptTitle bool // Rename even if the filenames and database titles differ
 
 	Events types.EventHandler
 	Offset *int
@@ -78,6 +135,34 @@ type Options struct {
 
 	// Search options
 	Providers []string
+
+	// Preview options
+	Pattern string
+	Fields  []string
+	Preset  string // Output preset for MigrateTemplate
+
+	NumberingBase *int // First local episode number used by Preview
+
+	// Undo options
+	Episodes    []int
+	FilesGlob   string
+	Resolutions map[string]types.ConflictAction // By original name
+
+	// Sort options
+	SortOnly      bool     // Move and configure, but don't rename
+	MinConfidence *float64 // Overrides sort.min_confidence
+
+	// Dupes options
+	PreferGroups []string // Overrides dupes.prefer_groups
+
+	// Settle overrides watch.settle for NewDaemon
+	Settle time.Duration
+
+	// IOLimit overrides backup.max_mbps for this run (0 for no cap)
+	IOLimit *float64
+
+	// Clock overrides the current time (testing)
+	Clock types.Clock
 }
 
 var defaultEvents types.EventHandler
@@ -88,13 +173,91 @@ func SetDefaultEventHandler(h types.EventHandler) {
 	defaultEvents = h
 }
 
+// SetReadOnly turns read-only mode on or off for the whole process. In
+// read-only mode nothing is written to disk: operations that only plan
+// (Rename, Resume, MigrateTemplate, Sort, Dedupe, ...) behave as with
+// WithDryRun, fetched databases are kept in memory, and operations that
+// must write (Undo, Clean, Tag, Init, ...) fail with types.ErrReadOnly.
+func SetReadOnly(on bool) {
+	util.SetReadOnly(on)
+}
+
+// SetPriority lowers the CPU niceness and IO scheduling class of the whole
+// process, e.g. to the priority set under priority in the global config, so
+// renames and backup copies don't starve other users of the disk. Only
+// supported on Linux.
+func SetPriority(p PriorityConfig) error {
+	return util.SetPriority(p)
+}
+
+// InstallScheduledTask registers a Windows Scheduled Task named name that
+// runs this executable with args every day at (HH:MM), e.g. a nightly
+// "sort D:\Downloads". An existing task of that name is replaced. Only
+// supported on Windows; elsewhere use cron or a systemd timer.
+func InstallScheduledTask(name string, args []string, at string) error {
+	at, err := util.TaskTime(at)
+	if err != nil {
+		return err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate the autotitle executable: %w", err)
+	}
+	command, err := util.TaskCommand(exe, args)
+	if err != nil {
+		return err
+	}
+	return util.InstallTask(name, command, at)
+}
+
+// UninstallScheduledTask removes a task added by InstallScheduledTask
+func UninstallScheduledTask(name string) error {
+	return util.UninstallTask(name)
+}
+
+// OpenEventSinks opens the event sinks configured under events.sinks in the
+// global config. Pass events to the returned set with its Send or Handler
+// methods, and Close it when done. Sinks that fail to open are left out and
+// reported in the error; the set holds the ones that opened.
+func OpenEventSinks() (*EventSinks, error) {
+	globalCfg, err := config.LoadGlobal()
+	if err != nil {
+		return &EventSinks{}, err
+	}
+	return sinks.Open(globalCfg.Events.Sinks)
+}
+
 func (o *Options) emit(t types.EventType, msg string) {
+	o.emitEvent(types.Event{Type: t, Message: msg})
+}
+
+// dryRun reports whether to only plan: with WithDryRun, or in read-only mode
+func (o *Options) dryRun() bool {
+	return o.DryRun || util.ReadOnly()
+}
+
+// applyIOLimit sets the per-run copy speed cap on bm, if any
+func (o *Options) applyIOLimit(bm types.BackupManager) {
+	if o.IOLimit != nil {
+		bm.WithRateLimit(*o.IOLimit)
+	}
+}
+
+// clock returns the injected clock, or the system clock
+func (o *Options) clock() types.Clock {
+	if o.Clock != nil {
+		return o.Clock
+	}
+	return types.SystemClock{}
+}
+
+func (o *Options) emitEvent(e types.Event) {
 	if o.Events != nil {
-		o.Events(types.Event{Type: t, Message: msg})
+		o.Events(e)
 	} else if defaultEvents != nil {
-		defaultEvents(types.Event{Type: t, Message: msg})
-	} else if t == types.EventWarning || t == types.EventError {
-		fmt.Fprintf(os.Stderr, "Warning: %s\n", msg)
+		defaultEvents(e)
+	} else if e.Type == types.EventWarning || e.Type == types.EventError {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", e.Message)
 	}
 }
 
@@ -143,261 +306,1740 @@ func WithForce() Option {
 	return func(o *Options) { o.Force = true }
 }
 
+// WithIOLimit caps backup copies at mbps megabytes per second for this run,
+// overriding backup.max_mbps from the global config; 0 removes the cap
+func WithIOLimit(mbps float64) Option {
+	return func(o *Options) { o.IOLimit = &mbps }
+}
+
 // WithNoTagging disables MKV metadata embedding even if mkvpropedit is available.
 func WithNoTagging() Option {
 	return func(o *Options) { o.NoTag = true }
 }
 
-// WithProvider filters search results to specific providers
+// WithAcceptTitle makes Rename go ahead when the series title in the
+// filenames is unlike the database title (see title_guard in the global
+// config), e.g. once the user has confirmed the map file's URL
+func WithAcceptTitle() Option {
+	return func(o *Options) { o.AcceptTitle = true }
+}
+
+// WithProvider filters search results to specific providers, overriding
+// those of the library searched for (Sort)
 func WithProvider(providers ...string) Option {
 	return func(o *Options) { o.Providers = append(o.Providers, providers...) }
 }
 
-// Rename renames media files in the specified directory
-func Rename(ctx context.Context, path string, opts ...Option) ([]types.RenameOperation, error) {
-	options := &Options{}
+// WithPattern sets the input pattern used by Preview instead of guessing one
+func WithPattern(pattern string) Option {
+	return func(o *Options) { o.Pattern = pattern }
+}
 
-	for _, opt := range opts {
-		opt(options)
-	}
+// WithFields sets the output fields used by Preview
+func WithFields(fields ...string) Option {
+	return func(o *Options) { o.Fields = append(o.Fields, fields...) }
+}
 
-	// Load config
-	cfg, err := config.Load(path)
-	if err != nil {
-		return nil, err
-	}
+// WithNumberingBase sets the first local episode number (0 or 1) used by
+// Preview, as numbering_base does for a target
+func WithNumberingBase(base int) Option {
+	return func(o *Options) { o.NumberingBase = &base }
+}
 
-	// Resolve target
-	target, err := cfg.ResolveTarget(path)
-	if err != nil {
-		return nil, err
-	}
+// WithPreset sets the output preset (auto, episode or movie) used by
+// MigrateTemplate, and by Rename for patterns that set none, overriding the
+// preset of the library the folder is in
+func WithPreset(preset string) Option {
+	return func(o *Options) { o.Preset = preset }
+}
 
-	// Get provider for URL
-	prov, err := provider.GetProviderForURL(target.URL)
-	if err != nil {
-		return nil, err
-	}
+// WithEpisodes limits Undo to the given episode numbers
+func WithEpisodes(episodes ...int) Option {
+	return func(o *Options) { o.Episodes = episodes }
+}
 
-	// Extract ID
-	id, err := prov.ExtractID(target.URL)
-	if err != nil {
-		return nil, err
-	}
+// WithFilesGlob limits Undo to files whose original or renamed name matches glob
+func WithFilesGlob(glob string) Option {
+	return func(o *Options) { o.FilesGlob = glob }
+}
 
-	// Initialize database
-	db, err := database.NewRepository("")
-	if err != nil {
-		return nil, err
+// WithConflictResolutions sets how Undo resolves each conflicting file, by
+// its original name; conflicts without one still abort the restore
+func WithConflictResolutions(r map[string]ConflictAction) Option {
+	return func(o *Options) { o.Resolutions = r }
+}
+
+// WithSortOnly makes Sort stop after moving files and writing map files
+func WithSortOnly() Option {
+	return func(o *Options) {
+		o.SortOnly = true
 	}
+}
 
-	// If local options specify a FillerURL, prefer that over the config file
-	fillerURL := target.FillerURL
-	if options.FillerURL != "" {
-		fillerURL = options.FillerURL
+// WithMinConfidence sets the match confidence (0-1) Sort needs to move a group
+// unattended; weaker matches are parked for review
+func WithMinConfidence(c float64) Option {
+	return func(o *Options) {
+		o.MinConfidence = &c
 	}
+}
 
-	force := options.Force
+// WithPreferGroups ranks release groups for FindDuplicates, best first,
+// overriding dupes.prefer_groups
+func WithPreferGroups(groups ...string) Option {
+	return func(o *Options) { o.PreferGroups = append(o.PreferGroups, groups...) }
+}
 
-	dbGenOpts := []Option{
-		WithFiller(fillerURL),
-	}
-	if force {
-		dbGenOpts = append(dbGenOpts, WithForce())
-	}
+// WithSettle sets how long a folder's files must stay unchanged before the
+// Daemon renames it, overriding watch.settle
+func WithSettle(d time.Duration) Option {
+	return func(o *Options) { o.Settle = d }
+}
 
-	if force {
-		options.emit(types.EventInfo, "Force refreshing database...")
-	} else if !db.Exists(prov.Name(), id) {
-		options.emit(types.EventInfo, "Database not found; fetching data...")
-	}
+// WithClock injects the clock used for refresh expiry, timestamps and rate limiting
+func WithClock(c types.Clock) Option {
+	return func(o *Options) { o.Clock = c }
+}
 
-	_, genErr := DBGen(ctx, target.URL, dbGenOpts...)
-	if genErr != nil {
-		options.emit(types.EventWarning, fmt.Sprintf("Failed to update database: %v", genErr))
+// WithNow injects the current time; sleeping still uses the real clock
+func WithNow(now func() time.Time) Option {
+	return WithClock(types.NowFunc(now))
+}
+
+// Rename renames media files in the specified directory.
+// If ctx is cancelled mid-batch, it stops after the current file and returns
+// the operations so far (the rest still pending) with types.ErrInterrupted.
+func Rename(ctx context.Context, path string, opts ...Option) ([]types.RenameOperation, error) {
+	options := &Options{}
+
+	for _, opt := range opts {
+		opt(options)
 	}
 
-	// Load media from database
-	media, err := db.Load(ctx, prov.Name(), id)
+	r, _, globalCfg, target, media, err := prepareRename(ctx, path, options)
 	if err != nil {
 		return nil, err
 	}
+	if err := checkTitle(r, path, media, globalCfg.TitleGuard, options); err != nil {
+		return nil, err
+	}
+	target = defaultPreset(target, path, options)
 
-	if media == nil {
-		if genErr != nil {
-			return nil, fmt.Errorf("failed to generate database: %w", genErr)
-		}
-		return nil, types.ErrDatabaseNotFound{Provider: prov.Name(), ID: id}
+	// Execute rename
+	started := options.clock().Now()
+	ops, err := r.Execute(ctx, path, target, media)
+	if anyRenamed(ops) && !options.dryRun() {
+		emitManifest(path, started, ops, options)
+	}
+	if err == nil && anyRenamed(ops) {
+		refreshMediaServers(ctx, path, options)
 	}
+	return ops, err
+}
 
-	// Load global config
-	globalCfg, err := config.LoadGlobal()
-	if err != nil {
-		options.emit(types.EventWarning, fmt.Sprintf("Failed to load global config: %v", err))
-		globalCfg = &types.GlobalConfig{
-			API:    types.APIConfig{RateLimit: 2.0, Timeout: 30},
-			Backup: types.BackupConfig{Enabled: true, DirName: "backups"},
+// emitManifest reports the files a batch renamed as a types.BatchManifest,
+// which the webhook sink POSTs for audit trails
+func emitManifest(dir string, started time.Time, ops []types.RenameOperation, options *Options) {
+	m := types.BatchManifest{Directory: dir, Started: started, Finished: options.clock().Now()}
+	for _, op := range ops {
+		if op.Status != types.StatusSuccess {
+			continue
 		}
-	}
+		from, to := filepath.Base(op.SourcePath), filepath.Base(op.TargetPath)
+		m.Mappings = append(m.Mappings, types.ManifestMapping{
+			From: from, To: to, FromSHA256: nameSum(from), ToSHA256: nameSum(to),
+		})
+	}
+	options.emitEvent(types.Event{
+		Type:    types.EventInfo,
+		Message: fmt.Sprintf("Renamed %d file(s) in %s", len(m.Mappings), dir),
+		Data:    m,
+	})
+}
 
-	// Create renamer
-	r := renamer.New(db, globalCfg.Backup, globalCfg.Formats)
-	if options.DryRun {
-		r.WithDryRun()
-	}
-	if options.NoBackup {
-		r.WithNoBackup()
-	}
-	if options.Events != nil {
-		r.WithEvents(options.Events)
-	} else if defaultEvents != nil {
-		r.WithEvents(defaultEvents)
-	}
+// nameSum is the hex SHA-256 of a file name
+func nameSum(name string) string {
+	sum := sha256.Sum256([]byte(name))
+	return hex.EncodeToString(sum[:])
+}
 
-	if options.Offset != nil {
-		r.WithOffset(*options.Offset)
+// defaultPreset applies the preset of WithPreset, or else of the library
+// path is in, to the patterns of target that set none
+func defaultPreset(target *types.Target, path string, options *Options) *types.Target {
+	preset := options.Preset
+	if preset == "" {
+		globalCfg, _ := config.LoadGlobal()
+		if lib := config.LibraryOf(globalCfg, path); lib != nil {
+			preset = lib.Preset
+		}
 	}
-
-	// Wire tagging: on by default if mkvpropedit is available, off if --no-tag
-	taggingEnabled := !options.NoTag && tagger.IsAvailable()
-	if globalCfg.Tagging.Enabled != nil {
-		taggingEnabled = *globalCfg.Tagging.Enabled && !options.NoTag
+	if preset == "" {
+		return target
 	}
-	r.WithTagging(taggingEnabled)
-
-	// Execute rename
-	return r.Execute(ctx, path, target, media)
+	t := target.Clone()
+	for i := range t.Patterns {
+		if t.Patterns[i].Output.Preset == "" {
+			t.Patterns[i].Output.Preset = preset
+		}
+	}
+	return t
 }
 
-// Init creates a new map file in the specified directory
-func Init(ctx context.Context, path string, opts ...Option) error {
+// RenameAll renames every folder under root that has a map file, e.g. the
+// series folders of a library. A folder that fails is reported and skipped;
+// cancellation or a rate limit stops the whole run, returning the
+// operations so far.
+func RenameAll(ctx context.Context, root string, opts ...Option) ([]types.RenameOperation, error) {
 	options := &Options{}
 	for _, opt := range opts {
 		opt(options)
 	}
 
-	absPath, err := filepath.Abs(path)
+	dirs, err := mapFileDirs(root)
 	if err != nil {
-		return fmt.Errorf("failed to resolve path: %w", err)
+		return nil, err
+	}
+	if len(dirs) == 0 {
+		return nil, fmt.Errorf("no map files found under %s", root)
 	}
 
-	// Load global config
-	globalCfg, _ := config.LoadGlobal()
-	defaults := config.GetDefaults()
-
-	mapFileName := defaults.MapFile
-	formats := defaults.Formats
-	if globalCfg != nil {
-		if globalCfg.MapFile != "" {
-			mapFileName = globalCfg.MapFile
-		}
-		if len(globalCfg.Formats) > 0 {
-			formats = globalCfg.Formats
+	var ops []types.RenameOperation
+	for _, dir := range dirs {
+		if err := ctx.Err(); err != nil {
+			return ops, err
 		}
-	}
 
-	mapPath := filepath.Join(absPath, mapFileName)
-	if _, err := os.Stat(mapPath); err == nil {
-		if !options.Force {
-			return fmt.Errorf("map file already exists: %s", mapPath)
+		options.emit(types.EventInfo, fmt.Sprintf("Renaming %s", dir))
+		dirOps, err := Rename(ctx, dir, opts...)
+		ops = append(ops, dirOps...)
+		var rateErr types.ErrRateLimited
+		if errors.Is(err, context.Canceled) || errors.As(err, &rateErr) {
+			return ops, err
+		}
+		if err != nil {
+			options.emit(types.EventWarning, fmt.Sprintf("Skipped %s: %v", dir, err))
 		}
-		// Warning when overriding
-		options.emit(types.EventWarning, fmt.Sprintf("Overwriting existing map file: %s", mapPath))
 	}
+	return ops, nil
+}
 
-	// Analyze directory for patterns and media presence
-	scanResult, err := config.Scan(absPath, formats)
-	if err != nil {
-		return fmt.Errorf("failed to analyze directory: %w", err)
-	}
+// mapFileDirs lists root and the folders under it that have a map file,
+// leaving out ignored directories
+func mapFileDirs(root string) ([]string, error) {
+	globalCfg, _ := config.LoadGlobal()
+	ig := config.NewIgnorer(globalCfg)
 
-	if !scanResult.HasMedia && scanResult.TotalFiles == 0 {
-		if !options.Force {
-			return fmt.Errorf("no files found in directory")
+	var dirs []string
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
 		}
-		options.emit(types.EventWarning, "No files found in directory. Use standard configuration.")
-	} else if !scanResult.HasMedia && scanResult.TotalFiles > 0 {
-		if !options.Force {
-			return fmt.Errorf("no media files found in directory (use --force to initialize anyway)")
+		if !d.IsDir() {
+			return nil
 		}
-		options.emit(types.EventWarning, "No media files found. Use standard configuration.")
+		if path != root && ig.SkipDir(d.Name()) {
+			return filepath.SkipDir
+		}
+		if _, err := os.Stat(config.MapFilePath(path)); err == nil {
+			dirs = append(dirs, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", root, err)
 	}
+	return dirs, nil
+}
 
-	// Build configuration
-	url := options.URL
-	fillerURL := options.FillerURL
+// Daemon is watch mode: it renames the folders under its roots as their
+// files change, and picks up edits to map files and the global config
+// without a restart. Create it with NewDaemon, then Run it.
+type Daemon struct {
+	roots   []string
+	opts    []Option
+	options *Options
+	watcher *watch.Watcher
+	ignorer *config.Ignorer
+	maps    map[string]*types.Config // Last valid map file by path, to tell what an edit changed
+	own     map[string][]string      // Names the last rename of a folder touched, by folder
+	fresh   map[string]bool          // Folders created while watching and not set up yet
+
+	mu       sync.Mutex
+	status   serve.Status
+	checked  time.Time // When status.Providers were checked
+	checking bool      // A provider check is running
+}
 
-	offset := 0
-	if options.Offset != nil {
-		offset = *options.Offset
-	}
+// Daemon status, as served by Daemon.Serve
+type (
+	DaemonStatus = serve.Status
+	DaemonRun    = serve.Run
+)
 
-	// Generate default config
-	cfg := config.GenerateDefault(url, fillerURL, scanResult.DetectedPatterns, options.Separator, offset, options.Padding)
+// Daemon states
+const (
+	DaemonStarting = serve.StateStarting
+	DaemonWatching = serve.StateWatching
+	DaemonRenaming = serve.StateRenaming
+	DaemonStopped  = serve.StateStopped
+)
 
-	// If detection failed but we have global patterns, prefer those over hardcoded defaults
-	if len(scanResult.DetectedPatterns) == 0 && globalCfg != nil && len(globalCfg.Patterns) > 0 {
-		cfg.Targets[0].Patterns = globalCfg.Patterns
-		// Apply overrides to these global patterns
-		for i := range cfg.Targets[0].Patterns {
-			if offset != 0 {
-				cfg.Targets[0].Patterns[i].Output.Offset = offset
-			}
-			if options.Separator != "" {
-				cfg.Targets[0].Patterns[i].Output.Separator = options.Separator
-			}
-			if options.Padding > 0 {
-				cfg.Targets[0].Patterns[i].Output.Padding = options.Padding
-			}
-		}
-	}
+// maxDaemonRuns is how many folder runs a daemon's status keeps
+const maxDaemonRuns = 20
 
-	return config.Save(mapPath, cfg)
-}
+// providerCheckTTL is how long a daemon reuses its provider checks
+const providerCheckTTL = 5 * time.Minute
 
-// Tag embeds MKV metadata into all matched files in the given directory
-// without renaming them. Requires mkvpropedit (MKVToolNix) to be installed.
-func Tag(ctx context.Context, path string, opts ...Option) error {
+// NewDaemon pins the global config (see Daemon.Reload) and starts watching
+// roots and every folder under them. The options apply to every rename.
+func NewDaemon(roots []string, opts ...Option) (*Daemon, error) {
 	options := &Options{}
 	for _, opt := range opts {
 		opt(options)
 	}
 
-	if !tagger.IsAvailable() {
-		return fmt.Errorf("mkvpropedit not found; please install MKVToolNix")
+	_, globalCfg, err := config.PinGlobal()
+	if err != nil {
+		return nil, err
+	}
+	if err := config.ApplyGlobal(globalCfg); err != nil {
+		config.UnpinGlobal()
+		return nil, err
 	}
 
-	// Load config
-	cfg, err := config.Load(path)
-	if err != nil {
-		return err
+	d := &Daemon{
+		opts:    opts,
+		options: options,
+		ignorer: config.NewIgnorer(globalCfg),
+		maps:    make(map[string]*types.Config),
+		own:     make(map[string][]string),
+		fresh:   make(map[string]bool),
+		status:  serve.Status{State: DaemonStarting},
 	}
-	target, err := cfg.ResolveTarget(path)
-	if err != nil {
-		return err
+	for _, root := range roots {
+		abs, err := filepath.Abs(root)
+		if err != nil {
+			config.UnpinGlobal()
+			return nil, fmt.Errorf("failed to resolve path: %w", err)
+		}
+		d.roots = append(d.roots, abs)
 	}
-	prov, err := provider.GetProviderForURL(target.URL)
-	if err != nil {
-		return err
+	d.status.Roots = d.roots
+
+	settle := options.Settle
+	if settle == 0 {
+		settle = time.Duration(globalCfg.Watch.Settle) * time.Second
 	}
-	id, err := prov.ExtractID(target.URL)
-	if err != nil {
-		return err
+	globalPath := config.FindGlobal()
+	if globalPath == "" {
+		globalPath, _ = config.UserGlobalPath()
 	}
-	db, err := database.NewRepository("")
+	d.watcher, err = watch.New(watch.Options{
+		Roots:  d.roots,
+		Global: globalPath,
+		Settle: settle,
+		Skip:   func(name string) bool { return d.ignorer.SkipDir(name) },
+	})
 	if err != nil {
-		return err
+		config.UnpinGlobal()
+		return nil, fmt.Errorf("failed to watch: %w", err)
 	}
-	media, err := db.Load(ctx, prov.Name(), id)
+	return d, nil
+}
+
+// Run renames changed folders until ctx is cancelled, and returns its error.
+// A folder is renamed once its files have been unchanged for watch.settle
+// seconds (or WithSettle), and again after an edit to its map file. Renames
+// run one folder at a time, and reloads only between them.
+func (d *Daemon) Run(ctx context.Context) error {
+	defer config.UnpinGlobal()
+	d.update(func(s *serve.Status) { s.State, s.Started = DaemonWatching, d.options.clock().Now() })
+	defer d.update(func(s *serve.Status) { s.State = DaemonStopped })
+	d.options.emit(types.EventInfo, fmt.Sprintf("Watching %s", strings.Join(d.roots, ", ")))
+	return d.watcher.Run(ctx, watch.Handler{
+		Folder: func(c watch.Change) { d.folderChanged(ctx, c) },
+		Global: d.reloadGlobal,
+		Error: func(err error) {
+			d.options.emit(types.EventWarning, fmt.Sprintf("Watch error: %v", err))
+		},
+	})
+}
+
+// Listen binds addr for Serve, checking serve in the global config first:
+// without a token it refuses to listen beyond loopback.
+func (d *Daemon) Listen(addr string) (net.Listener, error) {
+	globalCfg, err := config.LoadGlobal()
+	if err != nil {
+		return nil, err
+	}
+	o := serve.FromConfig(globalCfg.Serve)
+	if _, err := serve.Handler(d, o); err != nil {
+		return nil, err
+	}
+	return serve.Listen(addr, o)
+}
+
+// Serve serves the daemon's health probes and status on ln (see Listen and
+// FetchDaemonStatus) until ctx is cancelled:
+//
+//	GET /healthz  200 while the daemon runs, 503 once it stopped
+//	GET /readyz   200 once it is watching, 503 before
+//	GET /status   DaemonStatus as JSON; ?providers=false skips the provider checks
+//
+// serve in the global config secures it: /status takes serve.token (or
+// $AUTOTITLE_TOKEN) as a bearer token, serve.tls_cert and serve.tls_key turn
+// on HTTPS, and serve.allow limits the clients.
+func (d *Daemon) Serve(ctx context.Context, ln net.Listener) error {
+	globalCfg, err := config.LoadGlobal()
+	if err != nil {
+		ln.Close()
+		return err
+	}
+	o := serve.FromConfig(globalCfg.Serve)
+	h, err := serve.Handler(d, o)
+	if err != nil {
+		ln.Close()
+		return err
+	}
+	return serve.Serve(ctx, ln, h, o)
+}
+
+// Status returns the daemon's state, its roots, the results of its latest
+// folder runs and, if providers is set, whether each provider answers. The
+// provider checks are live requests, and reused for a few minutes; while
+// one is running, other callers get the previous results.
+func (d *Daemon) Status(ctx context.Context, providers bool) DaemonStatus {
+	d.mu.Lock()
+	stale := providers && !d.checking && d.options.clock().Now().Sub(d.checked) > providerCheckTTL
+	if stale {
+		d.checking = true
+	}
+	d.mu.Unlock()
+	if stale {
+		health := CheckProviders(ctx)
+		d.update(func(s *serve.Status) {
+			s.Providers = health
+			d.checked = d.options.clock().Now()
+			d.checking = false
+		})
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	s := d.status
+	s.Runs = slices.Clone(s.Runs)
+	if !providers {
+		s.Providers = nil
+	}
+	return s
+}
+
+// update changes the status under the lock
+func (d *Daemon) update(f func(s *serve.Status)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	f(&d.status)
+}
+
+// record adds the result of renaming a folder to the status
+func (d *Daemon) record(dir string, ops []types.RenameOperation, err error) {
+	run := serve.Run{Dir: dir, At: d.options.clock().Now()}
+	for _, op := range ops {
+		switch op.Status {
+		case types.StatusSuccess:
+			run.Renamed++
+		case types.StatusSkipped:
+			run.Skipped++
+		case types.StatusFailed:
+			run.Failed++
+		}
+	}
+	if err != nil {
+		run.Error = err.Error()
+	}
+	d.update(func(s *serve.Status) {
+		s.Runs = append([]serve.Run{run}, s.Runs[:min(len(s.Runs), maxDaemonRuns-1)]...)
+	})
+}
+
+// FetchDaemonStatus asks the daemon serving on addr (see Daemon.Serve) for
+// its status, with the provider checks if providers is set. The token and
+// TLS certificate come from serve in the global config, as for the daemon.
+func FetchDaemonStatus(ctx context.Context, addr string, providers bool) (*DaemonStatus, error) {
+	var cfg types.ServeConfig
+	if globalCfg, err := config.LoadGlobal(); err == nil {
+		cfg = globalCfg.Serve
+	}
+	return serve.Fetch(ctx, addr, providers, serve.FromConfig(cfg))
+}
+
+// Reload has Run reload the global config before the next folder, as it
+// does on its own when the file changes. The new config replaces the old
+// one whole; one that fails to load is reported and the old one kept.
+func (d *Daemon) Reload() {
+	d.watcher.Reload()
+}
+
+// folderChanged renames a folder whose files settled
+func (d *Daemon) folderChanged(ctx context.Context, c watch.Change) {
+	// The daemon's own renames come back as changes
+	own := d.own[c.Dir]
+	delete(d.own, c.Dir)
+	c.Files = slices.DeleteFunc(c.Files, func(name string) bool { return slices.Contains(own, name) })
+	if len(c.Files) == 0 && !c.Created {
+		return
+	}
+
+	opts := d.opts
+	mapPath := config.MapFilePath(c.Dir)
+	if slices.Contains(c.Files, filepath.Base(mapPath)) {
+		if !d.reloadMapFile(mapPath) {
+			return
+		}
+	} else if !c.Created {
+		opts = append(slices.Clip(opts), d.onlyChanged(c))
+	}
+
+	if c.Created {
+		d.fresh[c.Dir] = true
+	}
+
+	d.update(func(s *serve.Status) { s.State = DaemonRenaming })
+	ops, err := Rename(ctx, c.Dir, opts...)
+	var notFound types.ErrConfigNotFound
+	if errors.As(err, &notFound) && d.fresh[c.Dir] && d.setUp(ctx, c.Dir, mapPath) {
+		ops, err = Rename(ctx, c.Dir, opts...)
+	}
+	d.update(func(s *serve.Status) { s.State = DaemonWatching })
+	for _, op := range ops {
+		if op.Status == types.StatusSuccess {
+			d.own[c.Dir] = append(d.own[c.Dir], filepath.Base(op.SourcePath), filepath.Base(op.TargetPath))
+		}
+	}
+	if !errors.As(err, &notFound) {
+		delete(d.fresh, c.Dir)
+	}
+	var mismatch types.ErrTitleMismatch
+	switch {
+	case errors.As(err, &notFound), errors.Is(err, context.Canceled):
+		return
+	case err == nil:
+	case errors.As(err, &mismatch):
+		d.options.emit(types.EventWarning, fmt.Sprintf("Skipped %s: %v (rename it once with --accept-title)", c.Dir, err))
+	default:
+		d.options.emit(types.EventWarning, fmt.Sprintf("Rename failed in %s: %v", c.Dir, err))
+	}
+	d.record(c.Dir, ops, err)
+}
+
+// setUp gives a folder created while watching a map file (see setupFolder)
+// and reports whether it got one. A folder without media files yet, or
+// whose search failed, is left for its next change; one parked for review
+// is not tried again.
+func (d *Daemon) setUp(ctx context.Context, dir, mapPath string) bool {
+	g, err := setupFolder(ctx, dir, d.opts...)
+	var searchFailed types.ErrSearchFailed
+	switch {
+	case errors.Is(err, context.Canceled):
+		return false
+	case errors.As(err, &searchFailed):
+		// Searched again on the folder's next change
+		d.options.emit(types.EventWarning, fmt.Sprintf("Failed to set up %s: %v", dir, err))
+		return false
+	case err != nil:
+		delete(d.fresh, dir)
+		d.options.emit(types.EventWarning, fmt.Sprintf("Failed to set up %s: %v", dir, err))
+		return false
+	case g == nil:
+		return false
+	}
+	delete(d.fresh, dir)
+	if g.Parked || d.options.dryRun() {
+		return false
+	}
+	// Written by the daemon, so not a change to rename again
+	d.own[dir] = append(d.own[dir], filepath.Base(mapPath))
+	if cfg, err := config.LoadFile(mapPath); err == nil {
+		d.maps[mapPath] = cfg
+	}
+	return true
+}
+
+// onlyChanged passes on the events of a rename, except skips of files the
+// change did not touch: the files renamed before match no input pattern,
+// and would be reported again with every new episode
+func (d *Daemon) onlyChanged(c watch.Change) Option {
+	return WithEvents(func(e types.Event) {
+		if op, ok := e.Data.(types.RenameOperation); ok && op.Status == types.StatusSkipped && !slices.Contains(c.Files, filepath.Base(op.SourcePath)) {
+			return
+		}
+		d.options.emitEvent(e)
+	})
+}
+
+// reloadMapFile reports what an edit changed in the map file at path. It
+// returns false if the folder is not to be renamed: the file is invalid
+// (mid-edit, or broken) or gone.
+func (d *Daemon) reloadMapFile(path string) bool {
+	cfg, err := config.LoadFile(path)
+	var notFound types.ErrConfigNotFound
+	if errors.As(err, &notFound) {
+		delete(d.maps, path)
+		d.options.emitEvent(types.Event{Type: types.EventInfo, Message: fmt.Sprintf("Map file removed: %s", path), Data: types.ConfigReload{Path: path}})
+		return false
+	}
+	if err != nil {
+		d.options.emit(types.EventWarning, fmt.Sprintf("Map file %s not reloaded: %v", path, err))
+		return false
+	}
+
+	old := d.maps[path]
+	d.maps[path] = cfg
+	changed := config.Changed(old, cfg)
+	switch {
+	case old == nil:
+		d.options.emitEvent(types.Event{Type: types.EventInfo, Message: fmt.Sprintf("Loaded map file %s", path), Data: types.ConfigReload{Path: path}})
+	case len(changed) > 0:
+		d.options.emitEvent(types.Event{
+			Type:    types.EventInfo,
+			Message: fmt.Sprintf("Reloaded map file %s: %s changed", path, strings.Join(changed, ", ")),
+			Data:    types.ConfigReload{Path: path, Changed: changed},
+		})
+	}
+	return true
+}
+
+// reloadGlobal pins the global config again
+func (d *Daemon) reloadGlobal() {
+	old, globalCfg, err := config.PinGlobal()
+	now := d.options.clock().Now()
+	if err != nil {
+		d.update(func(s *serve.Status) { s.Reloaded, s.ReloadError = now, err.Error() })
+		d.options.emit(types.EventWarning, fmt.Sprintf("Global config not reloaded, keeping the previous one: %v", err))
+		return
+	}
+	d.update(func(s *serve.Status) { s.Reloaded, s.ReloadError = now, "" })
+	if err := config.ApplyGlobal(globalCfg); err != nil {
+		d.options.emit(types.EventWarning, fmt.Sprintf("Global config not applied: %v", err))
+	}
+	d.ignorer = config.NewIgnorer(globalCfg)
+
+	changed := config.Changed(old, globalCfg)
+	if len(changed) == 0 {
+		return
+	}
+	d.options.emitEvent(types.Event{
+		Type:    types.EventInfo,
+		Message: fmt.Sprintf("Reloaded global config: %s changed", strings.Join(changed, ", ")),
+		Data:    types.ConfigReload{Path: config.FindGlobal(), Changed: changed},
+	})
+}
+
+// checkTitle guards against a map file pointing at the wrong series: when
+// the series name the files carry is unlike every title of media, it warns
+// or, with title_guard.action confirm, returns types.ErrTitleMismatch for
+// the caller to confirm. A dry-run only warns.
+func checkTitle(r *renamer.Renamer, dir string, media *types.Media, guard types.TitleGuardConfig, options *Options) error {
+	switch guard.Action {
+	case types.TitleGuardOff:
+		return nil
+	case "", types.TitleGuardConfirm, types.TitleGuardWarn:
+	default:
+		return fmt.Errorf("invalid title_guard.action %q (use confirm, warn or off)", guard.Action)
+	}
+	if options.AcceptTitle {
+		return nil
+	}
+
+	series, err := r.SeriesTitle(dir)
+	if err != nil || series == "" {
+		return err
+	}
+	similarity := renamer.TitleSimilarity(series, media)
+	if similarity >= guard.MinSimilarity {
+		return nil
+	}
+
+	mismatch := types.ErrTitleMismatch{Series: series, Title: media.Title, Similarity: similarity}
+	if guard.Action == types.TitleGuardWarn || options.dryRun() {
+		options.emit(types.EventWarning, mismatch.Error())
+		return nil
+	}
+	return mismatch
+}
+
+// anyRenamed reports whether a batch renamed at least one file
+func anyRenamed(ops []types.RenameOperation) bool {
+	return slices.ContainsFunc(ops, func(op types.RenameOperation) bool { return op.Status == types.StatusSuccess })
+}
+
+// refreshMediaServers asks the media servers in the global config to rescan
+// dir after its files changed names. Failures are only warnings.
+func refreshMediaServers(ctx context.Context, dir string, options *Options) {
+	if options.dryRun() {
+		return
+	}
+	globalCfg, _ := config.LoadGlobal()
+	if globalCfg == nil || len(globalCfg.MediaServers) == 0 {
+		return
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return
+	}
+	for _, s := range globalCfg.MediaServers {
+		if err := mediaserver.Refresh(ctx, s, absDir); err != nil {
+			options.emit(types.EventWarning, fmt.Sprintf("Could not refresh %s: %v", mediaserver.Name(s), err))
+		} else {
+			options.emit(types.EventInfo, fmt.Sprintf("Asked %s to scan %s", mediaserver.Name(s), absDir))
+		}
+	}
+}
+
+// Resume continues the rename batch interrupted in path, as it was planned:
+// neither the config nor the database is consulted again, and files already
+// renamed are not touched. It returns the whole batch, or
+// types.ErrBatchNotFound if nothing is left to resume.
+// WithDryRun lists the renames left, WithNoBackup and WithNoTagging opt out
+// of the backup and tagging the batch started with.
+func Resume(ctx context.Context, path string, opts ...Option) ([]types.RenameOperation, error) {
+	options := &Options{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	db, err := database.NewRepository("")
+	if err != nil {
+		return nil, err
+	}
+	batch, err := journal.New(filepath.Dir(db.Path())).Get(absPath)
+	if err != nil {
+		return nil, err
+	}
+	if batch == nil || batch.Pending() == 0 {
+		return nil, types.ErrBatchNotFound{Directory: absPath}
+	}
+
+	globalCfg, _ := config.LoadGlobal()
+	backupCfg := types.BackupConfig{Enabled: batch.Backup, DirName: batch.BackupDir}
+	if globalCfg != nil {
+		backupCfg.MaxMBps = globalCfg.Backup.MaxMBps
+		backupCfg.Snapshot = globalCfg.Backup.Snapshot
+	}
+	r := renamer.New(db, backupCfg, nil)
+	if globalCfg != nil {
+		tg, err := tagger.New(globalCfg.Tagging)
+		if err != nil {
+			return nil, err
+		}
+		r.WithTagger(tg)
+	}
+	r.WithClock(options.clock())
+	options.applyIOLimit(r.BackupManager)
+	r.WithTagging(batch.Tag && !options.NoTag)
+	if options.dryRun() {
+		r.WithDryRun()
+	}
+	if options.NoBackup {
+		r.WithNoBackup()
+	}
+	if options.Events != nil {
+		r.WithEvents(options.Events)
+	} else if defaultEvents != nil {
+		r.WithEvents(defaultEvents)
+	}
+
+	ops, err := r.Resume(ctx, batch)
+	if err == nil && anyRenamed(ops) {
+		refreshMediaServers(ctx, absPath, options)
+	}
+	return ops, err
+}
+
+// ResumableBatch returns the interrupted rename batch of path, or nil
+func ResumableBatch(ctx context.Context, path string) (*types.Batch, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	j, err := batchJournal()
+	if err != nil {
+		return nil, err
+	}
+	return j.Get(absPath)
+}
+
+// batchJournal opens the journal of unfinished rename batches
+func batchJournal() (*journal.Journal, error) {
+	db, err := database.NewRepository("")
+	if err != nil {
+		return nil, err
+	}
+	return journal.New(filepath.Dir(db.Path())), nil
+}
+
+// prepareRename loads the config and media for the directory at path and
+// sets up a renamer for it. It also returns the global config it was set up
+// from.
+func prepareRename(ctx context.Context, path string, options *Options) (*renamer.Renamer, *types.Config, *types.GlobalConfig, *types.Target, *types.Media, error) {
+	// Load config and resolve target
+	cfg, target, err := loadTarget(ctx, path, options)
+	if err != nil {
+		return nil, nil, nil, nil, nil, err
+	}
+
+	// Get provider for URL
+	prov, err := provider.GetProviderForURL(target.URL)
+	if err != nil {
+		return nil, nil, nil, nil, nil, err
+	}
+
+	// Extract ID
+	id, err := prov.ExtractID(target.URL)
+	if err != nil {
+		return nil, nil, nil, nil, nil, err
+	}
+
+	// Initialize database
+	db, err := database.NewRepository("")
+	if err != nil {
+		return nil, nil, nil, nil, nil, err
+	}
+
+	// If local options specify a FillerURL, prefer that over the config file
+	fillerURL := target.FillerURL
+	if options.FillerURL != "" {
+		fillerURL = options.FillerURL
+	}
+
+	force := options.Force
+
+	dbGenOpts := []Option{
+		WithFiller(fillerURL),
+	}
+	if force {
+		dbGenOpts = append(dbGenOpts, WithForce())
+	}
+	if options.Clock != nil {
+		dbGenOpts = append(dbGenOpts, WithClock(options.Clock))
+	}
+
+	if force {
+		options.emit(types.EventInfo, "Force refreshing database...")
+	} else if !db.Exists(prov.Name(), id) {
+		options.emit(types.EventInfo, "Database not found; fetching data...")
+	}
+
+	_, genErr := DBGen(ctx, target.URL, dbGenOpts...)
+	if genErr != nil {
+		options.emit(types.EventWarning, fmt.Sprintf("Failed to update database: %v", genErr))
+	}
+
+	// Load media from database
+	media, err := db.Load(ctx, prov.Name(), id)
+	if err != nil {
+		return nil, nil, nil, nil, nil, err
+	}
+	db.Touch(prov.Name(), id)
+
+	if media == nil {
+		if genErr != nil {
+			return nil, nil, nil, nil, nil, fmt.Errorf("failed to generate database: %w", genErr)
+		}
+		return nil, nil, nil, nil, nil, types.ErrDatabaseNotFound{Provider: prov.Name(), ID: id}
+	}
+
+	// Load global config
+	globalCfg, err := config.LoadGlobal()
+	if err != nil {
+		options.emit(types.EventWarning, fmt.Sprintf("Failed to load global config: %v", err))
+		globalCfg = &types.GlobalConfig{
+			API:        types.APIConfig{RateLimit: 2.0, Timeout: 30},
+			Backup:     types.BackupConfig{Enabled: true, DirName: "backups"},
+			TitleGuard: config.GetDefaults().TitleGuard,
+		}
+	}
+
+	// Create renamer; music and manga targets rename tracks and chapters instead of videos
+	r := renamer.New(db, target.EffectiveBackup(globalCfg.Backup), config.FormatsFor(globalCfg, media.Type))
+	r.WithIgnorer(config.NewIgnorer(globalCfg).WithBackupDir(target.BackupDir))
+
+	cleaner, err := matcher.NewTitleCleaner(globalCfg.TitleRules)
+	if err != nil {
+		return nil, nil, nil, nil, nil, err
+	}
+	r.WithTitleCleaner(cleaner)
+	r.WithSubtitles(globalCfg.Subtitles)
+	if media.Type.Video() {
+		r.WithThumbnails(globalCfg.Thumbnails)
+		r.WithChapters(globalCfg.Chapters)
+	}
+	r.WithClock(options.clock())
+	options.applyIOLimit(r.BackupManager)
+	if options.dryRun() {
+		r.WithDryRun()
+	}
+	if options.NoBackup {
+		r.WithNoBackup()
+	}
+	if options.Events != nil {
+		r.WithEvents(options.Events)
+	} else if defaultEvents != nil {
+		r.WithEvents(defaultEvents)
+	}
+
+	if options.Offset != nil {
+		r.WithOffset(*options.Offset)
+	}
+
+	tg, err := tagger.New(globalCfg.Tagging)
+	if err != nil {
+		return nil, nil, nil, nil, nil, err
+	}
+	r.WithTagger(tg)
+
+	// Wire tagging: on by default if a tagging tool is available, off if --no-tag
+	taggingEnabled := !options.NoTag && tg.IsAvailable()
+	if globalCfg.Tagging.Enabled != nil {
+		taggingEnabled = *globalCfg.Tagging.Enabled && !options.NoTag
+	}
+	// The taggers write video containers and ebooks
+	r.WithTagging(taggingEnabled && (media.Type.Video() || media.Type == types.MediaTypeNovel))
+
+	return r, cfg, globalCfg, target, media, nil
+}
+
+// loadTarget loads the map file governing path and resolves its target. A
+// directory without a map file is governed by the closest map file above it
+// with a path template target, e.g. "{{YEAR}}/{{SEASON_NAME}}/*", whose
+// placeholders are filled from each target's media.
+func loadTarget(ctx context.Context, path string, options *Options) (*types.Config, *types.Target, error) {
+	cfg, err := config.Load(path)
+	var notFound types.ErrConfigNotFound
+	if errors.As(err, &notFound) {
+		if parent, perr := config.FindTemplateConfig(path); perr != nil {
+			return nil, nil, perr
+		} else if parent != nil {
+			cfg, err = parent, nil
+		}
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	target, err := cfg.ResolveTarget(path)
+	if err == nil {
+		return cfg, target, nil
+	}
+	if t := resolveTemplateTarget(ctx, cfg, path, options); t != nil {
+		return cfg, t, nil
+	}
+	return nil, nil, err
+}
+
+// resolveTemplateTarget returns the path template target of cfg that
+// expands to path, or nil. Targets whose pattern can't match are skipped
+// before their media is loaded.
+func resolveTemplateTarget(ctx context.Context, cfg *types.Config, path string, options *Options) *types.Target {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil
+	}
+	db, err := database.NewRepository("")
+	if err != nil {
+		return nil
+	}
+
+	for i := range cfg.Targets {
+		t := &cfg.Targets[i]
+		if !config.IsPathTemplate(t.Path) {
+			continue
+		}
+		if ok, _ := filepath.Match(filepath.Join(cfg.BaseDir, config.PathGlob(t.Path)), absPath); !ok {
+			continue
+		}
+
+		expanded, err := expandTargetPath(ctx, db, t, options)
+		if err != nil {
+			options.emit(types.EventWarning, fmt.Sprintf("Skipped target %s (%s): %v", t.Path, t.URL, err))
+			continue
+		}
+		if ok, _ := filepath.Match(filepath.Join(cfg.BaseDir, expanded), absPath); ok {
+			return t
+		}
+	}
+	return nil
+}
+
+// expandTargetPath fills a path template from the target's media, fetching
+// it if not cached, or again if the cached entry predates a needed field
+func expandTargetPath(ctx context.Context, db *database.Repository, t *types.Target, options *Options) (string, error) {
+	prov, err := provider.GetProviderForURL(t.URL)
+	if err != nil {
+		return "", err
+	}
+	id, err := prov.ExtractID(t.URL)
+	if err != nil {
+		return "", err
+	}
+
+	fetched := false
+	for {
+		if !db.Exists(prov.Name(), id) || fetched {
+			opts := []Option{WithClock(options.clock()), WithFiller(t.FillerURL)}
+			if fetched {
+				opts = append(opts, WithForce())
+			}
+			if _, err := DBGen(ctx, t.URL, opts...); err != nil {
+				return "", err
+			}
+		}
+		media, err := db.Load(ctx, prov.Name(), id)
+		if err != nil {
+			return "", err
+		}
+		if media == nil {
+			return "", types.ErrDatabaseNotFound{Provider: prov.Name(), ID: id}
+		}
+		expanded, err := config.ExpandPath(t.Path, media)
+		if err == nil || fetched {
+			return expanded, err
+		}
+		fetched = true
+	}
+}
+
+// MigrateTemplate re-renames the files autotitle already renamed under root
+// to a new output template, set with WithFields and/or WithPreset. Each
+// directory with a map file is rendered again from the original names in its
+// backup, so fields taken from the original name (RES, SOURCE, ...) survive;
+// files without a recorded original are left alone. The backup keeps the
+// originals for Undo and the map file is updated to the new template.
+func MigrateTemplate(ctx context.Context, root string, opts ...Option) ([]types.RenameOperation, error) {
+	options := &Options{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	switch options.Preset {
+	case "", types.PresetAuto, types.PresetEpisode, types.PresetMovie:
+	default:
+		return nil, fmt.Errorf("unknown output preset %q (use auto, episode or movie)", options.Preset)
+	}
+	if len(options.Fields) == 0 && options.Preset == "" {
+		return nil, fmt.Errorf("an output preset or fields are required")
+	}
+
+	dirs, err := mapFileDirs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var ops []types.RenameOperation
+	for _, dir := range dirs {
+		if err := ctx.Err(); err != nil {
+			return ops, err
+		}
+
+		dirOps, err := migrateDir(ctx, dir, options)
+		if errors.Is(err, context.Canceled) {
+			return append(ops, dirOps...), err
+		}
+		if err != nil {
+			options.emit(types.EventWarning, fmt.Sprintf("Skipped %s: %v", dir, err))
+			continue
+		}
+		ops = append(ops, dirOps...)
+	}
+	return ops, nil
+}
+
+// migrateDir re-renames one directory for MigrateTemplate
+func migrateDir(ctx context.Context, dir string, options *Options) ([]types.RenameOperation, error) {
+	r, cfg, _, target, media, err := prepareRename(ctx, dir, options)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := r.BackupManager.Plan(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	origins := make(map[string]string, len(entries))
+	for _, e := range entries {
+		origins[e.Renamed] = e.Original
+	}
+	r.WithOrigins(origins)
+
+	options.emit(types.EventInfo, fmt.Sprintf("Migrating %s", dir))
+
+	migrated := target.Clone()
+	for i := range migrated.Patterns {
+		if len(options.Fields) > 0 {
+			migrated.Patterns[i].Output.Fields = options.Fields
+		}
+		if options.Preset != "" {
+			migrated.Patterns[i].Output.Preset = options.Preset
+		}
+	}
+
+	ops, err := r.Execute(ctx, dir, migrated, media)
+	if err != nil || options.dryRun() {
+		return ops, err
+	}
+
+	// Later runs should keep the new names
+	index := 0
+	for i := range cfg.Targets {
+		if &cfg.Targets[i] == target {
+			index = i
+		}
+	}
+	if err := config.SetOutput(config.MapFilePath(dir), index, options.Fields, options.Preset); err != nil {
+		return ops, err
+	}
+	if anyRenamed(ops) {
+		refreshMediaServers(ctx, dir, options)
+	}
+	return ops, nil
+}
+
+// Init creates a new map file in the specified directory
+func Init(ctx context.Context, path string, opts ...Option) error {
+	options := &Options{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("failed to resolve path: %w", err)
+	}
+
+	// Load global config
+	globalCfg, _ := config.LoadGlobal()
+	defaults := config.GetDefaults()
+
+	mapFileName := defaults.MapFile
+	formats := defaults.Formats
+	if globalCfg != nil {
+		if globalCfg.MapFile != "" {
+			mapFileName = globalCfg.MapFile
+		}
+		if len(globalCfg.Formats) > 0 {
+			formats = globalCfg.Formats
+		}
+	}
+
+	mapPath := filepath.Join(absPath, mapFileName)
+	if _, err := os.Stat(mapPath); err == nil {
+		if !options.Force {
+			return fmt.Errorf("map file already exists: %s", mapPath)
+		}
+		// Warning when overriding
+		options.emit(types.EventWarning, fmt.Sprintf("Overwriting existing map file: %s", mapPath))
+	}
+
+	// Analyze directory for patterns and media presence
+	scanResult, err := config.Scan(absPath, formats, config.NewIgnorer(globalCfg))
+	if err != nil {
+		return fmt.Errorf("failed to analyze directory: %w", err)
+	}
+
+	if !scanResult.HasMedia && scanResult.TotalFiles == 0 {
+		if !options.Force {
+			return fmt.Errorf("no files found in directory")
+		}
+		options.emit(types.EventWarning, "No files found in directory. Use standard configuration.")
+	} else if !scanResult.HasMedia && scanResult.TotalFiles > 0 {
+		if !options.Force {
+			return fmt.Errorf("no media files found in directory (use --force to initialize anyway)")
+		}
+		options.emit(types.EventWarning, "No media files found. Use standard configuration.")
+	}
+
+	// Build configuration
+	url := options.URL
+	fillerURL := options.FillerURL
+
+	offset := 0
+	if options.Offset != nil {
+		offset = *options.Offset
+	}
+
+	// Generate default config
+	cfg := config.GenerateDefault(url, fillerURL, scanResult.DetectedPatterns, options.Separator, offset, options.Padding)
+
+	// If detection failed but we have global patterns, prefer those over hardcoded defaults
+	if len(scanResult.DetectedPatterns) == 0 && globalCfg != nil && len(globalCfg.Patterns) > 0 {
+		cfg.Targets[0].Patterns = globalCfg.Patterns
+		// Apply overrides to these global patterns
+		for i := range cfg.Targets[0].Patterns {
+			if offset != 0 {
+				cfg.Targets[0].Patterns[i].Output.Offset = offset
+			}
+			if options.Separator != "" {
+				cfg.Targets[0].Patterns[i].Output.Separator = options.Separator
+			}
+			if options.Padding > 0 {
+				cfg.Targets[0].Patterns[i].Output.Padding = options.Padding
+			}
+		}
+	}
+
+	return config.Save(mapPath, cfg)
+}
+
+// Sort organizes a dump directory of loose files from different series. Files
+// are grouped by the series name in their filenames, each group is matched to
+// a provider by search, then moved into its own folder with a generated map
+// file and renamed. A group without a good match is parked for review,
+// unless a provider failed: then it is left where it is, with Error set, for
+// the next sort. With WithDryRun only the plan is returned. In a library
+// only its providers are searched, unless WithProvider names others.
+func Sort(ctx context.Context, dir string, opts ...Option) ([]types.SortGroup, error) {
+	options := &Options{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve path: %w", err)
+	}
+
+	globalCfg, _ := config.LoadGlobal()
+	defaults := config.GetDefaults()
+	mapFileName, formats := defaults.MapFile, defaults.Formats
+	minConfidence := defaults.Sort.MinConfidence
+	if globalCfg != nil {
+		if globalCfg.MapFile != "" {
+			mapFileName = globalCfg.MapFile
+		}
+		if len(globalCfg.Formats) > 0 {
+			formats = globalCfg.Formats
+		}
+		minConfidence = globalCfg.Sort.MinConfidence
+	}
+	if options.MinConfidence != nil {
+		minConfidence = *options.MinConfidence
+	}
+	providers := options.Providers
+	if lib := config.LibraryOf(globalCfg, absDir); lib != nil && len(providers) == 0 {
+		providers = lib.Providers
+	}
+	if len(providers) == 0 {
+		providers = videoProviders()
+	}
+
+	queue, err := reviewQueue()
+	if err != nil {
+		return nil, err
+	}
+	learned, err := learnStore()
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := mediaFiles(absDir, formats)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := sorter.Group(files)
+	candidates := make([][]types.SearchResult, len(groups))
+	for i := range groups {
+		g := &groups[i]
+		var searchErr error
+		if a, _ := learned.Alias(g.Name); a != nil {
+			// Matched by hand in an earlier review or init
+			g.Match, g.Confidence = &a.Match, 1
+			g.Patterns = mergePatterns(a.Patterns, g.Patterns)
+			candidates[i] = []types.SearchResult{a.Match}
+			options.emit(types.EventInfo, fmt.Sprintf("Using learned match for %q → %s", g.Name, a.Match.Title))
+		} else {
+			var results []types.SearchResult
+			results, searchErr = Search(ctx, g.Name, WithProvider(providers...))
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			// Groups are video files, so an OST or manga of the same name is never a match
+			results = slices.DeleteFunc(results, func(r types.SearchResult) bool { return !r.Type.Video() })
+			candidates[i] = sorter.Rank(g.Name, results)
+			g.Match, g.Confidence = sorter.BestMatch(g.Name, results)
+		}
+
+		switch {
+		case searchErr != nil && (g.Match == nil || g.Confidence < minConfidence):
+			// A provider that failed may have had the match: neither park nor
+			// move the group, and let the next sort search again
+			g.Match, g.Confidence = nil, 0
+			g.Error = searchErr.Error()
+			options.emit(types.EventWarning, fmt.Sprintf("Left %q (%d files) unsorted: %v", g.Name, len(g.Files), searchErr))
+		case g.Match == nil:
+			g.Error = "no provider match"
+			g.Parked = true
+			options.emit(types.EventWarning, fmt.Sprintf("No match for %q (%d files); parked for review", g.Name, len(g.Files)))
+		case g.Confidence < minConfidence:
+			g.Error = fmt.Sprintf("low confidence (%.0f%%)", g.Confidence*100)
+			g.Parked = true
+			options.emit(types.EventWarning, fmt.Sprintf("Low confidence for %q → %s (%.0f%%); parked for review", g.Name, g.Match.Title, g.Confidence*100))
+		default:
+			g.Folder = filepath.Join(absDir, sorter.FolderName(g.Match.Title))
+		}
+	}
+
+	if options.dryRun() {
+		return groups, nil
+	}
+
+	for i := range groups {
+		g := &groups[i]
+		id := review.ItemID(absDir, g.Name)
+		if g.Parked {
+			item := types.ReviewItem{
+				ID:         id,
+				Dir:        absDir,
+				Group:      *g,
+				Candidates: candidates[i][:min(len(candidates[i]), 5)],
+				Added:      options.clock().Now(),
+			}
+			if err := queue.Put(item); err != nil {
+				options.emit(types.EventError, fmt.Sprintf("Failed to park %q for review: %v", g.Name, err))
+			}
+			continue
+		}
+		if g.Folder == "" {
+			// Not searched
+			continue
+		}
+		if err := queue.Remove(id); err != nil {
+			options.emit(types.EventWarning, fmt.Sprintf("Failed to update review queue: %v", err))
+		}
+
+		if err := sortGroup(g, mapFileName, options); err != nil {
+			g.Error = err.Error()
+			options.emit(types.EventError, fmt.Sprintf("Failed to sort %q: %v", g.Name, err))
+			continue
+		}
+		options.emit(types.EventSuccess, fmt.Sprintf("Sorted %d files into %s", len(g.Files), filepath.Base(g.Folder)))
+
+		if !options.SortOnly {
+			if _, err := Rename(ctx, g.Folder, opts...); err != nil {
+				options.emit(types.EventWarning, fmt.Sprintf("Rename failed in %s: %v", filepath.Base(g.Folder), err))
+			}
+		}
+	}
+	return groups, nil
+}
+
+// setupFolder gives a series folder without a map file one, as Sort does a
+// group, for watch mode: the folder name is searched, and a match at or
+// above sort.min_confidence is written to a new map file; a weaker one, or
+// none, parks the folder for review, which sets it up in place. It returns
+// nil if the folder has no media files yet, and ErrSearchFailed instead of
+// parking it if a provider failed.
+func setupFolder(ctx context.Context, dir string, opts ...Option) (*types.SortGroup, error) {
+	options := &Options{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	globalCfg, _ := config.LoadGlobal()
+	defaults := config.GetDefaults()
+	mapFileName, formats := defaults.MapFile, defaults.Formats
+	minConfidence := defaults.Sort.MinConfidence
+	if globalCfg != nil {
+		if globalCfg.MapFile != "" {
+			mapFileName = globalCfg.MapFile
+		}
+		if len(globalCfg.Formats) > 0 {
+			formats = globalCfg.Formats
+		}
+		minConfidence = globalCfg.Sort.MinConfidence
+	}
+	providers := options.Providers
+	if lib := config.LibraryOf(globalCfg, dir); lib != nil && len(providers) == 0 {
+		providers = lib.Providers
+	}
+	if len(providers) == 0 {
+		providers = videoProviders()
+	}
+
+	files, err := mediaFiles(dir, formats)
+	if err != nil || len(files) == 0 {
+		return nil, err
+	}
+	name := sorter.FolderSeries(filepath.Base(dir))
+	if name == "" {
+		name = filepath.Base(dir)
+	}
+	g := &types.SortGroup{Name: name, Files: files, Folder: dir, InPlace: true}
+	for _, fg := range sorter.Group(files) {
+		g.Patterns = mergePatterns(g.Patterns, fg.Patterns)
+	}
+
+	queue, err := reviewQueue()
+	if err != nil {
+		return nil, err
+	}
+	learned, err := learnStore()
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []types.SearchResult
+	if a, _ := learned.Alias(name); a != nil {
+		g.Match, g.Confidence = &a.Match, 1
+		g.Patterns = mergePatterns(a.Patterns, g.Patterns)
+		options.emit(types.EventInfo, fmt.Sprintf("Using learned match for %q → %s", name, a.Match.Title))
+	} else {
+		results, err := Search(ctx, name, WithProvider(providers...))
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		results = slices.DeleteFunc(results, func(r types.SearchResult) bool { return !r.Type.Video() })
+		candidates = sorter.Rank(name, results)
+		g.Match, g.Confidence = sorter.BestMatch(name, results)
+		if err != nil && (g.Match == nil || g.Confidence < minConfidence) {
+			// A provider that failed may have had the match: not parked
+			return nil, err
+		}
+	}
+	switch {
+	case g.Match == nil:
+		g.Error, g.Parked = "no provider match", true
+		options.emit(types.EventWarning, fmt.Sprintf("No match for new folder %s; parked for review", dir))
+	case g.Confidence < minConfidence:
+		g.Error, g.Parked = fmt.Sprintf("low confidence (%.0f%%)", g.Confidence*100), true
+		options.emit(types.EventWarning, fmt.Sprintf("Low confidence for new folder %s → %s (%.0f%%); parked for review", dir, g.Match.Title, g.Confidence*100))
+	}
+	if options.dryRun() {
+		return g, nil
+	}
+
+	id := review.ItemID(dir, name)
+	if g.Parked {
+		item := types.ReviewItem{
+			ID:         id,
+			Dir:        dir,
+			Group:      *g,
+			Candidates: candidates[:min(len(candidates), 5)],
+			Added:      options.clock().Now(),
+		}
+		if err := queue.Put(item); err != nil {
+			return nil, fmt.Errorf("failed to park %s for review: %w", dir, err)
+		}
+		return g, nil
+	}
+	if err := queue.Remove(id); err != nil {
+		options.emit(types.EventWarning, fmt.Sprintf("Failed to update review queue: %v", err))
+	}
+	if err := sortGroup(g, mapFileName, options); err != nil {
+		return nil, err
+	}
+	options.emit(types.EventSuccess, fmt.Sprintf("Set up %s as %s", filepath.Base(dir), g.Match.Title))
+	return g, nil
+}
+
+// videoProviders returns the names of the providers of video, the only ones
+// a sort group can match
+func videoProviders() []string {
+	var names []string
+	for _, p := range provider.ListProviderDetails() {
+		if p.Type.Video() {
+			names = append(names, p.Name)
+		}
+	}
+	return names
+}
+
+// mediaFiles lists the names of the files in dir with one of formats
+func mediaFiles(dir string, formats []string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+	var files []string
+	for _, e := range entries {
+		ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(e.Name())), ".")
+		if !e.IsDir() && slices.Contains(formats, ext) {
+			files = append(files, e.Name())
+		}
+	}
+	return files, nil
+}
+
+// mergePatterns returns the learned patterns followed by the guessed ones
+// not already among them
+func mergePatterns(learned, guessed []string) []string {
+	merged := slices.Clone(learned)
+	for _, p := range guessed {
+		if !slices.Contains(merged, p) {
+			merged = append(merged, p)
+		}
+	}
+	return merged
+}
+
+// reviewQueue opens the queue of sort groups parked for review
+func reviewQueue() (*review.Queue, error) {
+	db, err := database.NewRepository("")
+	if err != nil {
+		return nil, err
+	}
+	return review.New(filepath.Dir(db.Path())), nil
+}
+
+// learnStore opens the store of matches corrected by hand
+func learnStore() (*learn.Store, error) {
+	db, err := database.NewRepository("")
+	if err != nil {
+		return nil, err
+	}
+	return learn.New(filepath.Dir(db.Path())), nil
+}
+
+// Learn records a match the user chose by hand for the files in dir (and
+// the input patterns they settled on, if any), so that later runs on files
+// of the same series reuse it: Sort skips the search and review, and
+// LearnedMatch offers it to the init wizard.
+func Learn(ctx context.Context, dir string, match types.SearchResult, patterns []string, opts ...Option) error {
+	options := &Options{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	files, err := mediaFiles(dir, configuredFormats())
+	if err != nil {
+		return err
+	}
+	learned, err := learnStore()
+	if err != nil {
+		return err
+	}
+	for _, g := range sorter.Group(files) {
+		a := types.Alias{Name: g.Name, Match: match, Patterns: patterns, Learned: options.clock().Now()}
+		if err := learned.Learn(a); err != nil {
+			return fmt.Errorf("failed to remember match for %q: %w", g.Name, err)
+		}
+	}
+	return nil
+}
+
+// LearnedMatch returns the match learned for the series of the files in
+// dir, or nil if there is none
+func LearnedMatch(ctx context.Context, dir string) (*types.Alias, error) {
+	files, err := mediaFiles(dir, configuredFormats())
+	if err != nil {
+		return nil, err
+	}
+	learned, err := learnStore()
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range sorter.Group(files) {
+		if a, err := learned.Alias(g.Name); err != nil || a != nil {
+			return a, err
+		}
+	}
+	return nil, nil
+}
+
+// configuredFormats returns the media extensions from the global config
+func configuredFormats() []string {
+	if globalCfg, _ := config.LoadGlobal(); globalCfg != nil && len(globalCfg.Formats) > 0 {
+		return globalCfg.Formats
+	}
+	return config.GetDefaults().Formats
+}
+
+// ReviewQueue returns the sort groups parked for review, oldest first
+func ReviewQueue(ctx context.Context) ([]types.ReviewItem, error) {
+	queue, err := reviewQueue()
+	if err != nil {
+		return nil, err
+	}
+	return queue.List()
+}
+
+// AcceptReview resolves a parked sort group with match: its files are moved
+// into the match's folder (or, for a folder parked by watch mode, left in
+// it) and renamed as Sort would have, the item leaves the queue, and the
+// match is learned so later sorts of the same series skip the search. With
+// WithDryRun only the resolved group is returned.
+func AcceptReview(ctx context.Context, id string, match types.SearchResult, opts ...Option) (*types.SortGroup, error) {
+	options := &Options{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	queue, err := reviewQueue()
+	if err != nil {
+		return nil, err
+	}
+	item, err := queue.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return nil, types.ErrReviewItemNotFound{ID: id}
+	}
+
+	g := item.Group
+	g.Match = &match
+	g.Confidence = sorter.Confidence(g.Name, match.Title)
+	g.Folder = filepath.Join(item.Dir, sorter.FolderName(match.Title))
+	if g.InPlace {
+		g.Folder = item.Dir
+	}
+	g.Parked, g.Error = false, ""
+	if options.dryRun() {
+		return &g, nil
+	}
+
+	mapFileName := config.GetDefaults().MapFile
+	if globalCfg, _ := config.LoadGlobal(); globalCfg != nil && globalCfg.MapFile != "" {
+		mapFileName = globalCfg.MapFile
+	}
+	if err := sortGroup(&g, mapFileName, options); err != nil {
+		return nil, fmt.Errorf("failed to sort %q: %w", g.Name, err)
+	}
+	if g.InPlace {
+		options.emit(types.EventSuccess, fmt.Sprintf("Set up %s as %s", filepath.Base(g.Folder), match.Title))
+	} else {
+		options.emit(types.EventSuccess, fmt.Sprintf("Sorted %d files into %s", len(g.Files), filepath.Base(g.Folder)))
+	}
+
+	if err := queue.Remove(id); err != nil {
+		options.emit(types.EventWarning, fmt.Sprintf("Failed to update review queue: %v", err))
+	}
+	if learned, err := learnStore(); err == nil {
+		err = learned.Learn(types.Alias{Name: g.Name, Match: match, Learned: options.clock().Now()})
+		if err != nil {
+			options.emit(types.EventWarning, fmt.Sprintf("Failed to remember match for %q: %v", g.Name, err))
+		}
+	}
+
+	if !options.SortOnly {
+		if _, err := Rename(ctx, g.Folder, opts...); err != nil {
+			options.emit(types.EventWarning, fmt.Sprintf("Rename failed in %s: %v", filepath.Base(g.Folder), err))
+		}
+	}
+	return &g, nil
+}
+
+// sortGroup moves the group's files, and the subtitles named after them,
+// into its folder, unless it is in place, and writes a map file there unless
+// one exists
+func sortGroup(g *types.SortGroup, mapFileName string, options *Options) error {
+	if err := util.CheckWritable("move files"); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(g.Folder, 0755); err != nil {
+		return err
+	}
+
+	mapPath := filepath.Join(g.Folder, mapFileName)
+	if _, err := os.Stat(mapPath); os.IsNotExist(err) {
+		cfg := config.GenerateDefault(g.Match.URL, filler.DeriveURLFromProvider(g.Match.URL), g.Patterns, options.Separator, 0, options.Padding)
+		if err := config.Save(mapPath, cfg); err != nil {
+			return err
+		}
+	}
+
+	if g.InPlace {
+		return nil
+	}
+	dir := filepath.Dir(g.Folder)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+	formats := config.GetDefaults().Formats
+	if globalCfg, _ := config.LoadGlobal(); globalCfg != nil && len(globalCfg.Formats) > 0 {
+		formats = globalCfg.Formats
+	}
+
+	for _, file := range g.Files {
+		// Subtitles ("Ep 01.eng.srt", "Ep 01 [English].ass") go with their video
+		for i, name := range append([]string{file}, renamer.Subtitles(names, file, formats)...) {
+			target := filepath.Join(g.Folder, name)
+			if _, err := os.Stat(target); err == nil {
+				options.emit(types.EventWarning, fmt.Sprintf("Skipped %s: already exists in %s", name, filepath.Base(g.Folder)))
+				if i == 0 {
+					break
+				}
+				continue
+			}
+			if err := os.Rename(filepath.Join(dir, name), target); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Tag embeds metadata into all matched files in the given directory without
+// renaming them. Each file is tagged by the backend mapped to its extension
+// (tagging.formats in the global config); files mapped to none are skipped.
+func Tag(ctx context.Context, path string, opts ...Option) error {
+	options := &Options{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	globalCfg, _ := config.LoadGlobal()
+	var taggingCfg types.TaggingConfig
+	if globalCfg != nil {
+		taggingCfg = globalCfg.Tagging
+	}
+	tg, err := tagger.New(taggingCfg)
+	if err != nil {
+		return err
+	}
+	if !tg.IsAvailable() {
+		return fmt.Errorf("no tagging tool found; please install MKVToolNix, AtomicParsley, ffmpeg or calibre, or enable the epub backend")
+	}
+
+	// Load config
+	_, target, err := loadTarget(ctx, path, options)
+	if err != nil {
+		return err
+	}
+	prov, err := provider.GetProviderForURL(target.URL)
+	if err != nil {
+		return err
+	}
+	id, err := prov.ExtractID(target.URL)
+	if err != nil {
+		return err
+	}
+	db, err := database.NewRepository("")
+	if err != nil {
+		return err
+	}
+	media, err := db.Load(ctx, prov.Name(), id)
 	if err != nil {
 		return err
 	}
 	if media == nil {
 		return types.ErrDatabaseNotFound{Provider: prov.Name(), ID: id}
 	}
+	db.Touch(prov.Name(), id)
 
-	// Walk directory and tag MKV files that have matching episodes by filename
-	entries, err := os.ReadDir(path)
+	// Walk directory and tag files that have matching episodes by filename
+	entries, err := config.NewIgnorer(globalCfg).ReadDir(path)
 	if err != nil {
 		return fmt.Errorf("failed to read directory: %w", err)
 	}
@@ -417,7 +2059,7 @@ func Tag(ctx context.Context, path string, opts ...Option) error {
 			continue
 		}
 		name := entry.Name()
-		if !strings.EqualFold(filepath.Ext(name), ".mkv") {
+		if !tg.IsTaggable(name) {
 			continue
 		}
 		// Try to match episode number from filename using media episode list
@@ -444,13 +2086,110 @@ func Tag(ctx context.Context, path string, opts ...Option) error {
 			AirDate:     matchedEp.AirDate,
 		}
 		filePath := filepath.Join(path, name)
-		if err := tagger.TagFile(ctx, filePath, info); err != nil {
+		if err := tg.TagFile(ctx, filePath, info); err != nil {
 			emit(types.EventWarning, fmt.Sprintf("Tagging failed for %s: %v", name, err))
 		} else {
 			emit(types.EventSuccess, fmt.Sprintf("Tagged: %s", name))
 		}
 	}
-	return nil
+	return nil
+}
+
+// Preview returns the name a single file would be given, without needing a
+// map file or the file itself on disk. The provider URL must be set with WithURL.
+// The input pattern is guessed from the filename unless WithPattern is used, and
+// the output fields default to the global config's first pattern. The file is
+// named as Rename would name it in the folder filename is in, so the preset
+// of that folder's library applies.
+func Preview(ctx context.Context, filename string, opts ...Option) (string, error) {
+	options := &Options{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	if options.URL == "" {
+		return "", fmt.Errorf("provider URL is required")
+	}
+	if base := options.NumberingBase; base != nil && *base != 0 && *base != 1 {
+		return "", fmt.Errorf("numbering base must be 0 or 1, got %d", *base)
+	}
+
+	prov, err := provider.GetProviderForURL(options.URL)
+	if err != nil {
+		return "", err
+	}
+	id, err := prov.ExtractID(options.URL)
+	if err != nil {
+		return "", err
+	}
+
+	dbGenOpts := []Option{WithFiller(options.FillerURL)}
+	if options.Force {
+		dbGenOpts = append(dbGenOpts, WithForce())
+	}
+	_, genErr := DBGen(ctx, options.URL, dbGenOpts...)
+
+	db, err := database.NewRepository("")
+	if err != nil {
+		return "", err
+	}
+	media, err := db.Load(ctx, prov.Name(), id)
+	if err != nil {
+		return "", err
+	}
+	if media == nil {
+		if genErr != nil {
+			return "", fmt.Errorf("failed to generate database: %w", genErr)
+		}
+		return "", types.ErrDatabaseNotFound{Provider: prov.Name(), ID: id}
+	}
+
+	name := filepath.Base(filename)
+	input := options.Pattern
+	if input == "" {
+		input = matcher.GuessPattern(name)
+	}
+	pattern, err := matcher.Compile(input)
+	if err != nil {
+		return "", err
+	}
+	match, ok := pattern.MatchTyped(name)
+	if !ok {
+		return "", types.ErrPatternNotMatched{Filename: name}
+	}
+
+	output := config.GetDefaults().Patterns[0].Output
+	var titleRules []types.TitleRule
+	if globalCfg, err := config.LoadGlobal(); err == nil {
+		if len(globalCfg.Patterns) > 0 {
+			output = globalCfg.Patterns[0].Output
+		}
+		titleRules = globalCfg.TitleRules
+	}
+	cleaner, err := matcher.NewTitleCleaner(titleRules)
+	if err != nil {
+		return "", err
+	}
+	if len(options.Fields) > 0 {
+		output.Fields = options.Fields
+	}
+	if options.Separator != "" {
+		output.Separator = options.Separator
+	}
+	if options.Padding > 0 {
+		output.Padding = options.Padding
+	}
+
+	// Name the file as Rename would in the folder it is in
+	target := &types.Target{
+		Patterns:      []types.Pattern{{Input: []string{input}, Output: output}},
+		NumberingBase: options.NumberingBase,
+	}
+	if abs, err := filepath.Abs(filepath.Dir(filename)); err == nil {
+		target = defaultPreset(target, abs, options)
+	}
+	_, newName, err := renamer.NewName(media, target, &target.Patterns[0], match, options.Offset, cleaner)
+	return newName, err
 }
 
 // DBGen generates a database from a provider URL
@@ -487,81 +2226,197 @@ func DBGen(ctx context.Context, url string, opts ...Option) (bool, error) {
 		return false, err
 	}
 
+	// Never fall back to a cached entry the provider no longer vouches for
+	if validator, ok := prov.(types.CacheValidator); ok && db.Exists(prov.Name(), id) {
+		if existing, _ := db.Load(ctx, prov.Name(), id); existing != nil && !validator.ValidCache(existing) {
+			options.emit(types.EventInfo, "Cached database no longer valid; fetching again...")
+			if err := db.Delete(ctx, prov.Name(), id); err != nil {
+				return false, err
+			}
+		}
+	}
+
 	// Check if exists
 	if !options.Force && db.Exists(prov.Name(), id) {
 
 		// Load existing data to check expiration
 		existing, err := db.Load(ctx, prov.Name(), id)
-		if err == nil && existing != nil {
-
-			// If finished airing, no new episodes will come
-			if existing.Status == "Finished Airing" {
+		if err != nil || existing == nil {
+			return false, nil // Skip
+		}
+		if !database.NeedsRefresh(existing, options.clock().Now()) {
+			// Still fresh, but the config may point at a different filler list
+			if options.FillerURL == "" || existing.FillerURL == options.FillerURL {
 				return false, nil // Skip
 			}
-
-			// If next episode is known and in the future, wait
-			if existing.NextEpisodeAirDate != nil {
-				t, err := time.Parse(time.RFC3339, *existing.NextEpisodeAirDate)
-				if err == nil && t.After(time.Now()) {
-					return false, nil // Skip
+			options.emit(types.EventInfo, "Filler URL changed; updating filler data...")
+			if err := applyFillers(ctx, existing, options.FillerURL); err != nil {
+				if errors.Is(err, context.Canceled) {
+					return false, err
 				}
+				options.emit(types.EventWarning, fmt.Sprintf("Failed to fetch filler data; keeping the existing entry: %v", err))
+				return false, nil
 			}
-		} else {
-			return false, nil
+			return true, db.Save(ctx, existing)
 		}
 	}
 
-	// Fetch media
-	media, err := prov.FetchMedia(ctx, id)
+	// Report page progress for long fetches
+	fetchCtx := types.ContextWithClock(ctx, options.clock())
+	fetchCtx = types.ContextWithProgress(fetchCtx, func(p types.FetchProgress) {
+		options.emitEvent(types.Event{
+			Type:    types.EventProgress,
+			Message: fmt.Sprintf("Fetched page %d/%d (%d episodes)", p.Page, p.LastPage, p.Episodes),
+			Data:    p,
+		})
+	})
+
+	// Keep the cached entry to spot episodes the provider renumbered
+	var cached *types.Media
+	if db.Exists(prov.Name(), id) {
+		cached, _ = db.Load(ctx, prov.Name(), id)
+	}
+
+	// Fetch media, resuming an earlier rate-limited fetch when supported
+	var media *types.Media
+	if resumable, ok := prov.(types.ResumableProvider); ok {
+		partial, _ := db.LoadPartial(ctx, prov.Name(), id)
+		if partial != nil {
+			options.emit(types.EventInfo, fmt.Sprintf("Resuming fetch from page %d...", partial.ResumePage))
+		}
+		media, err = resumable.FetchMediaFrom(fetchCtx, id, partial)
+	} else {
+		media, err = prov.FetchMedia(fetchCtx, id)
+	}
 	if err != nil {
+		// Keep complete pages so the next run resumes instead of starting over
+		var rateErr types.ErrRateLimited
+		interrupted := errors.As(err, &rateErr) || errors.Is(err, context.Canceled)
+		if interrupted && media != nil && len(media.Episodes) > 0 {
+			if saveErr := db.SavePartial(ctx, media); saveErr != nil {
+				return false, saveErr
+			}
+			return false, types.ErrPartialFetch{Provider: prov.Name(), ID: id, Episodes: len(media.Episodes), Err: err}
+		}
 		return false, err
 	}
 
-	// Fetch filler if URL provided
+	// Fetch filler if URL provided; a failure leaves the episodes unflagged
 	if options.FillerURL != "" {
-		fillerSource, err := provider.GetFillerSourceForURL(options.FillerURL)
-		if err == nil {
-			slug, err := fillerSource.ExtractSlug(options.FillerURL)
-			if err == nil {
-				fillers, err := fillerSource.FetchFillers(ctx, slug)
-				if err == nil {
-					for i := range media.Episodes {
-						if slices.Contains(fillers, media.Episodes[i].Number) {
-							media.Episodes[i].IsFiller = true
-						}
-					}
-					media.FillerSource = fillerSource.Name()
-				}
-			}
+		if err := applyFillers(ctx, media, options.FillerURL); err != nil {
+			options.emit(types.EventWarning, fmt.Sprintf("Failed to fetch filler data: %v", err))
 		}
 	}
 
+	if r := database.DetectRenumbering(cached, media); r != nil {
+		options.emitEvent(types.Event{Type: types.EventWarning, Message: renumberingMessage(media, r), Data: *r})
+	}
+
 	// Save to database
+	media.ResumePage = 0
 	if err := db.Save(ctx, media); err != nil {
 		return false, err
 	}
+	_ = db.DeletePartial(prov.Name(), id)
+	evictCache(db, globalCfg, prov.Name()+"/"+id, options)
 
 	return true, nil
 }
 
+// evictCache drops the least recently used database entries beyond
+// cache.max_size, never the keep entry ("provider/id") just fetched
+func evictCache(db *database.Repository, globalCfg *types.GlobalConfig, keep string, options *Options) {
+	if globalCfg == nil {
+		return
+	}
+	maxSize, err := util.ParseSize(globalCfg.Cache.MaxSize)
+	if err != nil {
+		options.emit(types.EventWarning, fmt.Sprintf("Ignoring cache.max_size: %v", err))
+		return
+	}
+	removed, err := db.Evict(maxSize, keep)
+	if err != nil {
+		options.emit(types.EventWarning, fmt.Sprintf("Failed to trim the cache: %v", err))
+	}
+	if len(removed) > 0 {
+		options.emit(types.EventInfo, fmt.Sprintf("Evicted %d least recently used database file(s) to stay under cache.max_size", len(removed)))
+	}
+}
+
+// renumberingMessage explains a detected renumbering and how to recover
+func renumberingMessage(media *types.Media, r *types.Renumbering) string {
+	var changes []string
+	if len(r.Removed) > 0 {
+		changes = append(changes, "removed "+util.FormatRanges(r.Removed))
+	}
+	if len(r.Moved) > 0 {
+		changes = append(changes, "moved "+util.FormatRanges(r.Moved))
+	}
+	return fmt.Sprintf("%s renumbered %q (%d → %d episodes; %s). Files renamed before may carry wrong titles; re-verify them with: autotitle --dry-run <path>",
+		strings.ToUpper(media.Provider), media.Title, r.OldCount, r.NewCount, strings.Join(changes, ", "))
+}
+
+// applyFillers flags media's filler episodes from the filler list at fillerURL
+// and records where they came from
+func applyFillers(ctx context.Context, media *types.Media, fillerURL string) error {
+	fillerSource, err := provider.GetFillerSourceForURL(fillerURL)
+	if err != nil {
+		return err
+	}
+	slug, err := fillerSource.ExtractSlug(fillerURL)
+	if err != nil {
+		return err
+	}
+	fillers, err := fillerSource.FetchFillers(ctx, slug)
+	if err != nil {
+		return err
+	}
+
+	for i := range media.Episodes {
+		media.Episodes[i].IsFiller = slices.Contains(fillers, media.Episodes[i].Number)
+	}
+	media.FillerSource = fillerSource.Name()
+	media.FillerURL = fillerURL
+	return nil
+}
+
 // Search queries the configured providers for media matching the query in parallel.
-// If WithProvider is used, it only queries those specific providers.
+// If WithProvider is used, it only queries those specific providers. If any
+// provider failed it returns ErrSearchFailed, along with the results of the
+// others.
 func Search(ctx context.Context, query string, opts ...Option) ([]types.SearchResult, error) {
 	ch := SearchStream(ctx, query, opts...)
 	var results []types.SearchResult
+	var failed []error
 	for r := range ch {
+		if r.Error != nil {
+			failed = append(failed, r.Error)
+		}
 		results = append(results, r)
 	}
+	if err := ctx.Err(); err != nil {
+		return results, err
+	}
+	if len(failed) > 0 {
+		return results, types.ErrSearchFailed{Query: query, Errors: failed}
+	}
 	return results, nil
 }
 
 var (
-	searchCache   = make(map[string][]types.SearchResult)
+	searchCache   = make(map[string][]types.SearchResult) // Keyed by provider and query
 	searchCacheMu sync.RWMutex
 )
 
+func searchCacheKey(provider, query string) string {
+	return provider + "\x00" + query
+}
+
 // SearchStream queries providers in parallel and streams results as they arrive.
-// Results are cached in memory. The returned channel is closed when all providers have responded.
+// Each provider gets its own timeout (api.search_timeout, api.search_timeouts);
+// a late provider yields a single TimedOut result instead of stalling the rest.
+// Results are cached in memory per provider, so providers that answered are not
+// queried again. The returned channel is closed when all providers are done.
 func SearchStream(ctx context.Context, query string, opts ...Option) <-chan types.SearchResult {
 	options := &Options{}
 	for _, opt := range opts {
@@ -570,38 +2425,26 @@ func SearchStream(ctx context.Context, query string, opts ...Option) <-chan type
 
 	ch := make(chan types.SearchResult, 32)
 
-	// Check cache
-	searchCacheMu.RLock()
-	if cached, ok := searchCache[query]; ok && len(options.Providers) == 0 {
-		searchCacheMu.RUnlock()
-		go func() {
-			for _, r := range cached {
-				ch <- r
-			}
-			close(ch)
-		}()
-		return ch
-	}
-	searchCacheMu.RUnlock()
-
 	globalCfg, _ := config.LoadGlobal()
+	if globalCfg == nil {
+		d := config.GetDefaults()
+		globalCfg = &d
+	}
 
 	// Determine which providers to query
-	var names []string
-	if len(options.Providers) > 0 {
-		for _, name := range options.Providers {
-			if _, err := provider.GetProvider(name); err == nil {
-				names = append(names, name)
-			}
-		}
-	} else {
+	names := options.Providers
+	if len(names) == 0 {
 		names = provider.ListProviders()
 	}
 
-	var results []types.SearchResult
-	var resultsMu sync.Mutex
-	var anyError bool
-	var errorMu sync.Mutex
+	send := func(r types.SearchResult) bool {
+		select {
+		case ch <- r:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
 
 	var wg sync.WaitGroup
 	for _, name := range names {
@@ -609,30 +2452,46 @@ func SearchStream(ctx context.Context, query string, opts ...Option) <-chan type
 		if err != nil {
 			continue
 		}
-		if globalCfg != nil {
-			prov.Configure(&globalCfg.API)
-		}
+
 		wg.Add(1)
 		go func(p types.Provider) {
 			defer wg.Done()
-			res, err := p.Search(ctx, query)
+
+			key := searchCacheKey(p.Name(), query)
+			searchCacheMu.RLock()
+			cached, ok := searchCache[key]
+			searchCacheMu.RUnlock()
+			if ok {
+				for _, r := range cached {
+					if !send(r) {
+						return
+					}
+				}
+				return
+			}
+
+			p.Configure(&globalCfg.API)
+			timeout := globalCfg.API.SearchTimeoutFor(p.Name())
+			pctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			res, err := p.Search(pctx, query)
 			if err != nil {
-				errorMu.Lock()
-				anyError = true
-				errorMu.Unlock()
-				select {
-				case ch <- types.SearchResult{Provider: p.Name(), Error: err}:
-				case <-ctx.Done():
+				r := types.SearchResult{Provider: p.Name(), Error: err}
+				if ctx.Err() == nil && errors.Is(pctx.Err(), context.DeadlineExceeded) {
+					r.Error = types.ErrSearchTimeout{Provider: p.Name(), Timeout: timeout}
+					r.TimedOut = true
 				}
+				send(r)
 				return
 			}
+
+			searchCacheMu.Lock()
+			searchCache[key] = res
+			searchCacheMu.Unlock()
+
 			for _, r := range res {
-				resultsMu.Lock()
-				results = append(results, r)
-				resultsMu.Unlock()
-				select {
-				case ch <- r:
-				case <-ctx.Done():
+				if !send(r) {
 					return
 				}
 			}
@@ -641,11 +2500,6 @@ func SearchStream(ctx context.Context, query string, opts ...Option) <-chan type
 
 	go func() {
 		wg.Wait()
-		if len(options.Providers) == 0 && !anyError {
-			searchCacheMu.Lock()
-			searchCache[query] = results
-			searchCacheMu.Unlock()
-		}
 		close(ch)
 	}()
 	return ch
@@ -667,6 +2521,50 @@ func DBList(ctx context.Context, providerFilter string) ([]types.MediaSummary, e
 	return db.List(ctx, providerFilter)
 }
 
+// DBStats returns a summary of the cached databases
+func DBStats(ctx context.Context, opts ...Option) (*types.DatabaseStats, error) {
+	options := &Options{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	db, err := database.NewRepository("")
+	if err != nil {
+		return nil, err
+	}
+	return db.WithClock(options.clock()).Stats(ctx)
+}
+
+// CacheUsage reports the size of the database cache against cache.max_size
+func CacheUsage(ctx context.Context) (*types.CacheStats, error) {
+	db, err := database.NewRepository("")
+	if err != nil {
+		return nil, err
+	}
+	globalCfg, err := config.LoadGlobal()
+	if err != nil {
+		return nil, err
+	}
+	maxSize, err := util.ParseSize(globalCfg.Cache.MaxSize)
+	if err != nil {
+		return nil, fmt.Errorf("cache.max_size: %w", err)
+	}
+	return db.CacheStats(maxSize)
+}
+
+// Stats summarizes the local rename history. It reads only the ledger on disk.
+func Stats(ctx context.Context) (*types.UsageStats, error) {
+	db, err := database.NewRepository("")
+	if err != nil {
+		return nil, err
+	}
+	entries, err := history.New(filepath.Dir(db.Path())).Entries()
+	if err != nil {
+		return nil, err
+	}
+	return history.Summarize(entries), nil
+}
+
 // DBInfo returns information about a specific database entry
 func DBInfo(ctx context.Context, prov, id string) (*types.Media, error) {
 	db, err := database.NewRepository("")
@@ -703,11 +2601,11 @@ func DBPath() (string, error) {
 	return db.Path(), nil
 }
 
-// Undo restores files from backup
-func Undo(ctx context.Context, path string) error {
+// newBackupManager creates a backup manager using the configured backup dir name
+func newBackupManager() (*backup.Manager, error) {
 	db, err := database.NewRepository("")
 	if err != nil {
-		return err
+		return nil, err
 	}
 	cacheRoot := filepath.Dir(db.Path())
 
@@ -718,46 +2616,267 @@ func Undo(ctx context.Context, path string) error {
 	}
 
 	bm := backup.New(cacheRoot, dirName)
-	if defaultEvents != nil {
+	if globalCfg != nil {
+		bm.WithRateLimit(globalCfg.Backup.MaxMBps)
+		bm.WithSnapshots(globalCfg.Backup.Snapshot)
+	}
+	return bm, nil
+}
+
+// Undo restores files from backup.
+// WithEpisodes and WithFilesGlob restore only the selected files.
+// WithForce overwrites files created under an original name since the rename;
+// otherwise such conflicts abort the restore with types.ErrRestoreConflict,
+// unless WithConflictResolutions says what to do with each.
+// A backup kept in a snapshot autotitle cannot read files from is rolled back
+// whole by backup.snapshot.rollback, or returns types.ErrSnapshotRollback.
+func Undo(ctx context.Context, path string, opts ...Option) error {
+	options := &Options{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	bm, err := newBackupManager()
+	if err != nil {
+		return err
+	}
+	if options.Events != nil {
+		bm.WithEvents(options.Events)
+	} else if defaultEvents != nil {
 		bm.WithEvents(defaultEvents)
 	}
-	return bm.Restore(ctx, path)
+	bm.Overwrite = options.Force
+	bm.Resolutions = options.Resolutions
+	options.applyIOLimit(bm)
+
+	keep, err := undoSelection(options)
+	if err != nil {
+		return err
+	}
+	if keep == nil {
+		if err := bm.Restore(ctx, path); err != nil {
+			return err
+		}
+		refreshMediaServers(ctx, path, options)
+		// A rolled back batch is not to be resumed
+		return discardBatch(path)
+	}
+	if err := bm.RestoreOnly(ctx, path, keep); err != nil {
+		return err
+	}
+	refreshMediaServers(ctx, path, options)
+	return nil
+}
+
+// discardBatch drops the interrupted batch of path from the journal, if any
+func discardBatch(path string) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	j, err := batchJournal()
+	if err != nil {
+		return err
+	}
+	return j.Remove(absPath)
+}
+
+// UndoPlan lists what Undo would restore and remove, without touching any files.
+// It honours the same selection options as Undo.
+func UndoPlan(ctx context.Context, path string, opts ...Option) ([]types.RestoreEntry, error) {
+	options := &Options{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	bm, err := newBackupManager()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := bm.Plan(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	keep, err := undoSelection(options)
+	if err != nil || keep == nil {
+		return entries, err
+	}
+	var selected []types.RestoreEntry
+	for _, e := range entries {
+		if keep(e) {
+			selected = append(selected, e)
+		}
+	}
+	return selected, nil
+}
+
+// undoSelection builds the entry filter for WithEpisodes and WithFilesGlob,
+// or returns nil when every entry should be restored
+func undoSelection(options *Options) (func(types.RestoreEntry) bool, error) {
+	if len(options.Episodes) == 0 && options.FilesGlob == "" {
+		return nil, nil
+	}
+	if options.FilesGlob != "" {
+		if _, err := filepath.Match(options.FilesGlob, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob %q: %w", options.FilesGlob, err)
+		}
+	}
+	return func(e types.RestoreEntry) bool {
+		if len(options.Episodes) > 0 && !slices.Contains(options.Episodes, e.Episode) {
+			return false
+		}
+		if options.FilesGlob != "" {
+			origMatch, _ := filepath.Match(options.FilesGlob, e.Original)
+			newMatch, _ := filepath.Match(options.FilesGlob, e.Renamed)
+			return origMatch || newMatch
+		}
+		return true
+	}, nil
 }
 
 // Clean removes the backup for a directory
 func Clean(ctx context.Context, path string) error {
-	db, err := database.NewRepository("")
+	bm, err := newBackupManager()
 	if err != nil {
 		return err
 	}
-	cacheRoot := filepath.Dir(db.Path())
+	return bm.Clean(ctx, path)
+}
 
-	globalCfg, _ := config.LoadGlobal()
-	dirName := backup.DefaultDirName
-	if globalCfg != nil && globalCfg.Backup.DirName != "" {
-		dirName = globalCfg.Backup.DirName
+// CleanMetadata removes NAS/OS metadata clutter (@eaDir, .DS_Store, Thumbs.db, ...)
+// from a directory and returns the removed paths. With WithDryRun nothing is removed.
+func CleanMetadata(ctx context.Context, path string, opts ...Option) ([]string, error) {
+	options := &Options{}
+	for _, opt := range opts {
+		opt(options)
 	}
 
-	bm := backup.New(cacheRoot, dirName)
-	return bm.Clean(ctx, path)
+	found, err := config.FindMetadataClutter(path)
+	if err != nil {
+		return nil, err
+	}
+	if options.dryRun() {
+		return found, nil
+	}
+
+	for _, p := range found {
+		if err := os.RemoveAll(p); err != nil {
+			return nil, fmt.Errorf("failed to remove %s: %w", p, err)
+		}
+	}
+	return found, nil
 }
 
 // CleanAll removes all backups globally
 func CleanAll(ctx context.Context) error {
-	db, err := database.NewRepository("")
+	bm, err := newBackupManager()
 	if err != nil {
 		return err
 	}
-	cacheRoot := filepath.Dir(db.Path())
+	return bm.CleanAll(ctx)
+}
+
+// VerifyBackups checks that every registered backup can still be restored
+func VerifyBackups(ctx context.Context) ([]types.BackupReport, error) {
+	bm, err := newBackupManager()
+	if err != nil {
+		return nil, err
+	}
+	return bm.Verify(ctx)
+}
+
+// FindDuplicates scans the media files under root for identical contents,
+// e.g. the same episode kept in two series folders. Within each set, the
+// first file is the copy Dedupe keeps: the one from the best-ranked release
+// group (dupes.prefer_groups or WithPreferGroups), else the first by path.
+func FindDuplicates(ctx context.Context, root string, opts ...Option) ([]types.DuplicateSet, error) {
+	options := &Options{}
+	for _, opt := range opts {
+		opt(options)
+	}
 
 	globalCfg, _ := config.LoadGlobal()
-	dirName := backup.DefaultDirName
-	if globalCfg != nil && globalCfg.Backup.DirName != "" {
-		dirName = globalCfg.Backup.DirName
+	files, err := dupes.Scan(ctx, root, config.NewIgnorer(globalCfg), configuredFormats())
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", root, err)
+	}
+	options.emit(types.EventInfo, fmt.Sprintf("Comparing %d files...", len(files)))
+	sets, err := dupes.Find(ctx, files)
+	if err != nil {
+		return nil, err
 	}
 
-	bm := backup.New(cacheRoot, dirName)
-	return bm.CleanAll(ctx)
+	groups := options.PreferGroups
+	if len(groups) == 0 && globalCfg != nil {
+		groups = globalCfg.Dupes.PreferGroups
+	}
+	dupes.Rank(sets, groups)
+	return sets, nil
+}
+
+// Dedupe replaces the redundant copies in sets with hardlinks to the kept
+// file (types.DedupeLink) or removes them (types.DedupeRemove), and returns
+// the bytes freed. WithDryRun only reports what would be done.
+func Dedupe(ctx context.Context, sets []types.DuplicateSet, action types.DedupeAction, opts ...Option) (int64, error) {
+	options := &Options{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	verb := map[types.DedupeAction]string{types.DedupeLink: "Linked", types.DedupeRemove: "Removed"}[action]
+	if verb == "" {
+		return 0, fmt.Errorf("unknown dedupe action %q (use link or remove)", action)
+	}
+
+	var freed int64
+	for _, set := range sets {
+		if err := ctx.Err(); err != nil {
+			return freed, err
+		}
+		if options.dryRun() {
+			for _, dup := range set.Files[1:] {
+				options.emit(types.EventInfo, fmt.Sprintf("[DRY-RUN] %s: %s", verb, dup))
+			}
+			freed += set.Wasted()
+			continue
+		}
+
+		done, err := dupes.Apply(set, action)
+		for _, dup := range done {
+			options.emit(types.EventSuccess, fmt.Sprintf("%s: %s", verb, dup))
+		}
+		freed += set.Size * int64(len(done))
+		if err != nil {
+			options.emit(types.EventError, fmt.Sprintf("Failed to dedupe %s: %v", set.Files[0], err))
+		}
+	}
+	return freed, nil
+}
+
+// FindLibrary returns the library called name in the global config
+// (ignoring case), or nil if there is none
+func FindLibrary(name string) (*types.Library, error) {
+	globalCfg, err := config.LoadGlobal()
+	if err != nil {
+		return nil, err
+	}
+	return config.FindLibrary(globalCfg, name), nil
+}
+
+// Libraries returns the libraries of the global config, roots resolved
+func Libraries() ([]types.Library, error) {
+	globalCfg, err := config.LoadGlobal()
+	if err != nil {
+		return nil, err
+	}
+	return globalCfg.Libraries, nil
+}
+
+// LibraryOf returns the library path is in, or nil
+func LibraryOf(path string) *types.Library {
+	globalCfg, _ := config.LoadGlobal()
+	return config.LibraryOf(globalCfg, path)
 }
 
 // Version returns the version string
@@ -773,14 +2892,46 @@ var (
 	ListProviders           = provider.ListProviders
 	ListFillerSources       = provider.ListFillerSources
 	ListFillerSourceDetails = provider.ListFillerSourceDetails
+	ListProviderDetails     = provider.ListProviderDetails
 )
 
 // FillerSourceInfo holds metadata about a registered filler source
 type FillerSourceInfo = provider.FillerSourceInfo
 
+// ProviderInfo holds metadata about a registered provider
+type ProviderInfo = provider.ProviderInfo
+
+// ProviderHealth is the parser schema and live health of a provider or
+// filler source
+type ProviderHealth = provider.Health
+
+// CheckProviders reports which API version or page layout each provider
+// and filler source parses, and checks with a live request that each still
+// understands the response, so an outdated parser can be told apart from
+// a problem with the files
+func CheckProviders(ctx context.Context) []ProviderHealth {
+	var api *types.APIConfig
+	if globalCfg, _ := config.LoadGlobal(); globalCfg != nil {
+		api = &globalCfg.API
+	}
+	return provider.CheckHealth(ctx, api)
+}
+
+// Title similarity metrics. matching.similarity in the global config
+// selects one by name; a program using autotitle can register its own
+// metric and select it there, or with SetSimilarity, which wins over the
+// config.
+var (
+	RegisterSimilarity = matcher.RegisterSimilarity
+	SetSimilarity      = matcher.SetSimilarity
+	ListSimilarities   = matcher.ListSimilarities
+	Similarity         = matcher.Similarity
+)
+
 // Pattern utilities
 var (
 	CompilePattern             = matcher.Compile
 	GuessPattern               = matcher.GuessPattern
+	ParseFilename              = matcher.ParseFilename
 	GenerateFilenameFromFields = matcher.GenerateFilenameFromFields
 )
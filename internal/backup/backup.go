@@ -3,27 +3,39 @@ package backup
 
 import (
 	"context"
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"io"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/mydehq/autotitle/internal/types"
+	"github.com/mydehq/autotitle/internal/util"
 )
 
 const (
 	RegistryFileName = "backup_registry.json"
 	MappingsFileName = "mappings.json"
+	EpisodesFileName = "episodes.json"
 	DefaultDirName   = ".autotitle_backup"
 )
 
 // Manager handles backup operations
 type Manager struct {
 	registryPath string // ~/.cache/autotitle/backup_registry.json
-	dirName      string // Backup dir name (from config)
+	dirName      string // Backup dir name, or an absolute backup root (from config)
 	Events       types.EventHandler
+	Overwrite    bool                            // Restore over files created since the rename
+	Resolutions  map[string]types.ConflictAction // Per conflict, by original name; overrides Overwrite
+	Clock        types.Clock                     // Source of backup timestamps
+	rateLimit    int64                           // Max bytes per second for copies, 0 for no limit
+
+	snapshot types.SnapshotConfig // Snapshot instead of backing up files, if Type is set
 }
 
 // New creates a new BackupManager
@@ -34,6 +46,7 @@ func New(cacheRoot string, dirName string) *Manager {
 	return &Manager{
 		registryPath: filepath.Join(cacheRoot, RegistryFileName),
 		dirName:      dirName,
+		Clock:        types.SystemClock{},
 	}
 }
 
@@ -43,15 +56,62 @@ func (m *Manager) WithEvents(h types.EventHandler) types.BackupManager {
 	return m
 }
 
+// WithClock sets the clock used for backup timestamps
+func (m *Manager) WithClock(c types.Clock) types.BackupManager {
+	m.Clock = c
+	return m
+}
+
+// WithRateLimit caps copies at mbps megabytes per second; 0 removes the cap
+func (m *Manager) WithRateLimit(mbps float64) types.BackupManager {
+	m.rateLimit = int64(max(mbps, 0) * 1e6)
+	return m
+}
+
 func (m *Manager) emit(t types.EventType, msg string) {
 	if m.Events != nil {
 		m.Events(types.Event{Type: t, Message: msg})
 	}
 }
 
+// newBackupPath returns where a new backup of absDir is created. A relative
+// dirName lives inside absDir; an absolute one is a shared root holding one
+// subdirectory per source directory.
+func (m *Manager) newBackupPath(absDir string) string {
+	if !filepath.IsAbs(m.dirName) {
+		return filepath.Join(absDir, m.dirName)
+	}
+	sum := sha256.Sum256([]byte(absDir))
+	return filepath.Join(m.dirName, fmt.Sprintf("%s-%x", filepath.Base(absDir), sum[:4]))
+}
+
+// locate returns the backup path registered for absDir, so backups made with a
+// different dirName (e.g. a per-target backup_dir) are still found
+func (m *Manager) locate(absDir string) string {
+	if r := m.record(absDir); r != nil {
+		return r.Path
+	}
+	return m.newBackupPath(absDir)
+}
+
+// record returns the latest registry record of absDir, or nil
+func (m *Manager) record(absDir string) *types.BackupRecord {
+	records, _ := m.ListAll(context.Background())
+	for i := len(records) - 1; i >= 0; i-- {
+		if records[i].SourceDir == absDir {
+			return &records[i]
+		}
+	}
+	return nil
+}
+
 // Backup creates a backup of files before renaming
-// mappings is a map of oldName -> newName
-func (m *Manager) Backup(ctx context.Context, dir string, mappings map[string]string) error {
+// mappings is a map of oldName -> newName, episodes of oldName -> episode number
+func (m *Manager) Backup(ctx context.Context, dir string, mappings map[string]string, episodes map[string]int) error {
+	if err := util.CheckWritable("create backups"); err != nil {
+		return err
+	}
+
 	absDir, err := filepath.Abs(dir)
 	if err != nil {
 		return fmt.Errorf("failed to resolve source dir: %w", err)
@@ -60,93 +120,519 @@ func (m *Manager) Backup(ctx context.Context, dir string, mappings map[string]st
 	// Clean any previous backup for this directory first
 	_ = m.Clean(ctx, dir)
 
-	// Create backup directory inside the input directory
-	backupPath := filepath.Join(absDir, m.dirName)
+	if m.snapshot.Type != "" {
+		return m.snapshotBackup(ctx, absDir, mappings, episodes)
+	}
+
+	// Create backup directory (inside the input directory unless dirName is absolute)
+	backupPath := m.newBackupPath(absDir)
 	if err := os.MkdirAll(backupPath, 0755); err != nil {
 		return fmt.Errorf("failed to create backup dir: %w", err)
 	}
 
-	// Copy original files to backup
+	// Copy original files to backup; an incomplete backup is useless, so drop it on abort
 	for oldName := range mappings {
 		src := filepath.Join(absDir, oldName)
 		dst := filepath.Join(backupPath, oldName)
-		if err := copyFile(src, dst); err != nil {
+		if err := m.copyFile(ctx, src, dst); err != nil {
+			_ = os.RemoveAll(backupPath)
 			return fmt.Errorf("failed to backup file %s: %w", oldName, err)
 		}
 		m.emit(types.EventInfo, fmt.Sprintf("Backed up: %s", oldName))
 	}
 
-	// Write mappings.json
-	mappingsPath := filepath.Join(backupPath, MappingsFileName)
-	mappingsData, err := json.MarshalIndent(mappings, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal mappings: %w", err)
-	}
-	if err := os.WriteFile(mappingsPath, mappingsData, 0644); err != nil {
-		return fmt.Errorf("failed to write mappings file: %w", err)
+	// Write mappings.json and episodes.json
+	if err := writeMappings(backupPath, mappings, episodes); err != nil {
+		return err
 	}
 
 	// Add to global registry
 	record := types.BackupRecord{
 		Path:      backupPath,
 		SourceDir: absDir,
-		Timestamp: time.Now(),
+		Timestamp: m.Clock.Now(),
 	}
 	return m.addRegistry(record)
 }
 
-// Restore restores files from backup (undo rename)
+// Estimate reports how Backup would store mappings: in a snapshot if
+// configured, hard linked when the backup is on the same filesystem as dir,
+// else copied. Filesystems without hard links (e.g. FAT) still copy, so
+// links are a best guess.
+func (m *Manager) Estimate(ctx context.Context, dir string, mappings map[string]string) ([]types.BackupEstimate, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve source dir: %w", err)
+	}
+
+	if m.snapshot.Type != "" {
+		estimates := make([]types.BackupEstimate, 0, len(mappings))
+		for _, oldName := range slices.Sorted(maps.Keys(mappings)) {
+			estimates = append(estimates, types.BackupEstimate{Name: oldName, Method: types.BackupSnapshot})
+		}
+		return estimates, nil
+	}
+
+	// The backup dir may not exist yet; it will share its nearest ancestor's filesystem
+	target := m.newBackupPath(absDir)
+	for {
+		if _, err := os.Stat(target); err == nil {
+			break
+		}
+		parent := filepath.Dir(target)
+		if parent == target {
+			break
+		}
+		target = parent
+	}
+	same, err := util.SameDevice(absDir, target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check backup filesystem: %w", err)
+	}
+
+	estimates := make([]types.BackupEstimate, 0, len(mappings))
+	for _, oldName := range slices.Sorted(maps.Keys(mappings)) {
+		e := types.BackupEstimate{Name: oldName, Method: types.BackupLink}
+		if !same {
+			info, err := os.Stat(filepath.Join(absDir, oldName))
+			if err != nil {
+				return nil, err
+			}
+			e.Method, e.Bytes = types.BackupCopy, info.Size()
+		}
+		estimates = append(estimates, e)
+	}
+	return estimates, nil
+}
+
+// Restore restores files from backup (undo rename).
+// It returns types.ErrRestoreConflict without touching any files if a restore
+// would overwrite a file created since the rename, unless Overwrite is set or
+// Resolutions resolves it.
 func (m *Manager) Restore(ctx context.Context, dir string) error {
+	return m.RestoreOnly(ctx, dir, nil)
+}
+
+// RestoreOnly restores the entries selected by keep (all if nil). Unselected
+// entries stay renamed and remain in the backup for a later undo.
+//
+// A backup kept in a snapshot is restored from the snapshot's view of the
+// directory. Without one, only the whole directory can be rolled back: by
+// the rollback command if set, else types.ErrSnapshotRollback says how.
+func (m *Manager) RestoreOnly(ctx context.Context, dir string, keep func(types.RestoreEntry) bool) error {
+	if err := util.CheckWritable("restore backups"); err != nil {
+		return err
+	}
+
 	absDir, err := filepath.Abs(dir)
 	if err != nil {
 		return fmt.Errorf("failed to resolve dir: %w", err)
 	}
 
-	backupPath := filepath.Join(absDir, m.dirName)
+	all, err := m.Plan(ctx, dir)
+	if err != nil {
+		return err
+	}
+
+	var entries, remaining []types.RestoreEntry
+	kept := 0
+	for _, e := range all {
+		switch {
+		case keep != nil && !keep(e):
+			remaining = append(remaining, e)
+		case e.Conflict && m.Resolutions[e.Original] == types.ConflictKeepNew:
+			remaining = append(remaining, e)
+			kept++
+		default:
+			entries = append(entries, e)
+		}
+	}
+	if len(entries) == 0 {
+		if kept > 0 {
+			m.emit(types.EventInfo, "Kept every new file; nothing was restored")
+			return nil
+		}
+		return fmt.Errorf("no backed up files match the selection")
+	}
+
+	// Restoring a name still held by an unselected renamed file would destroy it
+	for _, e := range entries {
+		for _, r := range remaining {
+			if e.Original == r.Renamed && r.Original != r.Renamed {
+				return fmt.Errorf("restoring %s would overwrite %s, which is not selected", e.Original, r.Renamed)
+			}
+		}
+	}
+
+	if !m.Overwrite {
+		var conflicts []string
+		for _, e := range entries {
+			if e.Conflict && m.Resolutions[e.Original] == "" {
+				conflicts = append(conflicts, e.Original)
+			}
+		}
+		if len(conflicts) > 0 {
+			return types.ErrRestoreConflict{Files: conflicts}
+		}
+	}
 
-	// Read mappings
-	mappingsPath := filepath.Join(backupPath, MappingsFileName)
-	data, err := os.ReadFile(mappingsPath)
+	backupPath := m.locate(absDir)
+	files, snap := m.files(absDir)
+	if files == "" {
+		if len(remaining) > 0 {
+			return fmt.Errorf("snapshot %s can only be rolled back whole; undo without a selection", snap.ID)
+		}
+		if err := m.rollback(ctx, *snap, absDir); err != nil {
+			return err
+		}
+		return m.Clean(ctx, dir)
+	}
+	for _, e := range entries {
+		if _, err := os.Stat(filepath.Join(files, e.Original)); err != nil {
+			return fmt.Errorf("backup of %s is missing: %w", e.Original, err)
+		}
+	}
+
+	// Remove renamed files and conflicts first, so chained renames
+	// (01 → 02, 02 → 03) don't clobber a freshly restored original
+	for _, e := range entries {
+		if e.Original != e.Renamed {
+			if err := removeIfExists(filepath.Join(absDir, e.Renamed)); err != nil {
+				return fmt.Errorf("failed to remove renamed file %s: %w", e.Renamed, err)
+			}
+		}
+		if e.Conflict && m.Resolutions[e.Original] != types.ConflictKeepBoth {
+			if err := removeIfExists(filepath.Join(absDir, e.Original)); err != nil {
+				return fmt.Errorf("failed to replace file %s: %w", e.Original, err)
+			}
+		}
+	}
+
+	// Names the restore is about to take, so a numbered name avoids them
+	taken := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		taken[e.Original] = true
+	}
+
+	for _, e := range entries {
+		src := filepath.Join(files, e.Original)
+		dst := filepath.Join(absDir, e.Original)
+		if e.Original == e.Renamed {
+			continue
+		}
+		name := e.Original
+		if e.Conflict && m.Resolutions[e.Original] == types.ConflictKeepBoth {
+			name = numberedName(absDir, e.Original, taken)
+			taken[name] = true
+			dst = filepath.Join(absDir, name)
+		}
+		if err := m.copyFile(ctx, src, dst); err != nil {
+			return fmt.Errorf("failed to restore file %s: %w", e.Original, err)
+		}
+		m.emit(types.EventSuccess, fmt.Sprintf("Restored: %s → %s", e.Renamed, name))
+	}
+
+	// Clean up backup after a full restore
+	if len(remaining) == 0 {
+		return m.Clean(ctx, dir)
+	}
+
+	// Otherwise drop only the restored entries from the backup
+	mappings := make(map[string]string, len(remaining))
+	episodes := make(map[string]int, len(remaining))
+	for _, r := range remaining {
+		mappings[r.Original] = r.Renamed
+		if r.Episode != 0 {
+			episodes[r.Original] = r.Episode
+		}
+	}
+	if err := writeMappings(backupPath, mappings, episodes); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		_ = os.Remove(filepath.Join(backupPath, e.Original))
+	}
+	return nil
+}
+
+// Plan reports what Restore would do, sorted by original name.
+// An entry conflicts when a file other than the backed-up original or its
+// renamed copy now exists under the original name.
+func (m *Manager) Plan(ctx context.Context, dir string) ([]types.RestoreEntry, error) {
+	absDir, err := filepath.Abs(dir)
 	if err != nil {
-		return fmt.Errorf("no backup found for directory: %w", err)
+		return nil, fmt.Errorf("failed to resolve dir: %w", err)
 	}
 
-	var mappings map[string]string
-	if err := json.Unmarshal(data, &mappings); err != nil {
-		return fmt.Errorf("failed to parse mappings: %w", err)
+	backupPath := m.locate(absDir)
+	mappings, episodes, err := readMappings(backupPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, types.ErrBackupNotFound{Directory: absDir}
+		}
+		return nil, err
 	}
 
+	renamed := make(map[string]bool, len(mappings))
+	for _, newName := range mappings {
+		renamed[newName] = true
+	}
+
+	// A snapshot without a view is rolled back whole, so nothing conflicts
+	files, _ := m.files(absDir)
+	entries := make([]types.RestoreEntry, 0, len(mappings))
 	for oldName, newName := range mappings {
-		src := filepath.Join(backupPath, oldName)
-		dst := filepath.Join(absDir, oldName)
-		renamedPath := filepath.Join(absDir, newName)
+		entry := types.RestoreEntry{Original: oldName, Renamed: newName, Episode: episodes[oldName]}
+		// Names taken by another renamed file are freed during the restore
+		if oldName != newName && !renamed[oldName] && files != "" {
+			entry.Conflict = isConflict(filepath.Join(absDir, oldName), filepath.Join(files, oldName))
+		}
+		entries = append(entries, entry)
+	}
+
+	slices.SortFunc(entries, func(a, b types.RestoreEntry) int {
+		return strings.Compare(a.Original, b.Original)
+	})
+	return entries, nil
+}
+
+// Remap records that renamed files were renamed again; renames maps each
+// current name to its new one. The originals stay in the backup, so Restore
+// still brings back the names from before the first rename.
+func (m *Manager) Remap(ctx context.Context, dir string, renames map[string]string) error {
+	if err := util.CheckWritable("update backups"); err != nil {
+		return err
+	}
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve dir: %w", err)
+	}
+
+	backupPath := m.locate(absDir)
+	mappings, episodes, err := readMappings(backupPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return types.ErrBackupNotFound{Directory: absDir}
+		}
+		return err
+	}
 
-		// Restore original first
-		if err := copyFile(src, dst); err != nil {
-			return fmt.Errorf("failed to restore file %s: %w", oldName, err)
+	for oldName, current := range mappings {
+		if newName, ok := renames[current]; ok {
+			mappings[oldName] = newName
 		}
+	}
+	return writeMappings(backupPath, mappings, episodes)
+}
 
-		// Only remove renamed file IF it's different from the original
-		if oldName != newName {
-			if _, err := os.Stat(renamedPath); err == nil {
-				_ = os.Remove(renamedPath)
+// Trim drops the entries not selected by keep from the backup, along with
+// their copies, e.g. when a batch was interrupted before renaming them.
+// The backup is removed if no entry is left.
+func (m *Manager) Trim(ctx context.Context, dir string, keep func(types.RestoreEntry) bool) error {
+	if err := util.CheckWritable("update backups"); err != nil {
+		return err
+	}
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve dir: %w", err)
+	}
+
+	entries, err := m.Plan(ctx, dir)
+	if err != nil {
+		return err
+	}
+
+	mappings := make(map[string]string, len(entries))
+	episodes := make(map[string]int, len(entries))
+	var dropped []string
+	for _, e := range entries {
+		if !keep(e) {
+			dropped = append(dropped, e.Original)
+			continue
+		}
+		mappings[e.Original] = e.Renamed
+		if e.Episode != 0 {
+			episodes[e.Original] = e.Episode
+		}
+	}
+	if len(dropped) == 0 {
+		return nil
+	}
+	if len(mappings) == 0 {
+		return m.Clean(ctx, dir)
+	}
+
+	backupPath := m.locate(absDir)
+	if err := writeMappings(backupPath, mappings, episodes); err != nil {
+		return err
+	}
+	for _, name := range dropped {
+		_ = os.Remove(filepath.Join(backupPath, name))
+	}
+	return nil
+}
+
+// Extend adds files to the existing backup of dir, e.g. the rest of a
+// resumed batch; files already backed up are kept as they are. Without a
+// backup it creates one like Backup.
+func (m *Manager) Extend(ctx context.Context, dir string, mappings map[string]string, episodes map[string]int) error {
+	if err := util.CheckWritable("update backups"); err != nil {
+		return err
+	}
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve source dir: %w", err)
+	}
+
+	backupPath := m.locate(absDir)
+	current, currentEpisodes, err := readMappings(backupPath)
+	if os.IsNotExist(err) {
+		return m.Backup(ctx, dir, mappings, episodes)
+	}
+	if err != nil {
+		return err
+	}
+
+	// A snapshot already holds the rest of the batch, as it was before
+	_, snap := m.files(absDir)
+
+	var added []string
+	for oldName, newName := range mappings {
+		if _, ok := current[oldName]; ok {
+			continue
+		}
+		if snap != nil {
+			current[oldName] = newName
+			if ep, ok := episodes[oldName]; ok {
+				currentEpisodes[oldName] = ep
+			}
+			added = append(added, oldName)
+			continue
+		}
+		src := filepath.Join(absDir, oldName)
+		dst := filepath.Join(backupPath, oldName)
+		if err := m.copyFile(ctx, src, dst); err != nil {
+			for _, name := range added {
+				_ = os.Remove(filepath.Join(backupPath, name))
 			}
+			_ = os.Remove(dst)
+			return fmt.Errorf("failed to backup file %s: %w", oldName, err)
 		}
-		m.emit(types.EventSuccess, fmt.Sprintf("Restored: %s → %s", newName, oldName))
+		m.emit(types.EventInfo, fmt.Sprintf("Backed up: %s", oldName))
+		added = append(added, oldName)
+
+		current[oldName] = newName
+		if ep, ok := episodes[oldName]; ok {
+			currentEpisodes[oldName] = ep
+		}
+	}
+	if len(added) == 0 {
+		return nil
+	}
+	return writeMappings(backupPath, current, currentEpisodes)
+}
+
+// readMappings reads the oldName -> newName mappings of a backup directory,
+// along with the episode numbers if they were recorded
+func readMappings(backupPath string) (map[string]string, map[string]int, error) {
+	data, err := os.ReadFile(filepath.Join(backupPath, MappingsFileName))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var mappings map[string]string
+	if err := json.Unmarshal(data, &mappings); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse mappings: %w", err)
+	}
+
+	// Backups made before episodes were recorded have no episodes.json
+	episodes := make(map[string]int)
+	if data, err := os.ReadFile(filepath.Join(backupPath, EpisodesFileName)); err == nil {
+		if err := json.Unmarshal(data, &episodes); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse episodes: %w", err)
+		}
+	}
+	return mappings, episodes, nil
+}
+
+// writeMappings writes mappings.json and, if any are known, episodes.json
+func writeMappings(backupPath string, mappings map[string]string, episodes map[string]int) error {
+	mappingsData, err := json.MarshalIndent(mappings, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal mappings: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(backupPath, MappingsFileName), mappingsData, 0644); err != nil {
+		return fmt.Errorf("failed to write mappings file: %w", err)
+	}
+
+	episodesPath := filepath.Join(backupPath, EpisodesFileName)
+	if len(episodes) == 0 {
+		return removeIfExists(episodesPath)
+	}
+	episodesData, err := json.MarshalIndent(episodes, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal episodes: %w", err)
+	}
+	if err := os.WriteFile(episodesPath, episodesData, 0644); err != nil {
+		return fmt.Errorf("failed to write episodes file: %w", err)
+	}
+	return nil
+}
+
+func removeIfExists(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// isConflict reports whether path exists and is not the backed-up file itself
+func isConflict(path, backupFile string) bool {
+	current, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	backed, err := os.Stat(backupFile)
+	if err != nil {
+		return true
 	}
+	return !os.SameFile(current, backed)
+}
 
-	// Clean up backup after successful restore
-	return m.Clean(ctx, dir)
+// numberedName returns name with the first free " (N)" before its extension:
+// neither in dir nor taken
+func numberedName(dir, name string, taken map[string]bool) string {
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	for n := 1; ; n++ {
+		candidate := fmt.Sprintf("%s (%d)%s", stem, n, ext)
+		if taken[candidate] {
+			continue
+		}
+		if _, err := os.Lstat(filepath.Join(dir, candidate)); os.IsNotExist(err) {
+			return candidate
+		}
+	}
 }
 
 // Clean removes backup for a specific directory
 func (m *Manager) Clean(ctx context.Context, dir string) error {
+	if err := util.CheckWritable("remove backups"); err != nil {
+		return err
+	}
+
 	absDir, err := filepath.Abs(dir)
 	if err != nil {
 		return fmt.Errorf("failed to resolve dir: %w", err)
 	}
 
-	backupPath := filepath.Join(absDir, m.dirName)
+	backupPath := m.locate(absDir)
+	if r := m.record(absDir); r != nil && r.Snapshot != nil {
+		m.removeSnapshot(ctx, *r.Snapshot, absDir)
+	}
 
 	// Remove backup directory
 	if err := os.RemoveAll(backupPath); err != nil {
@@ -159,12 +645,19 @@ func (m *Manager) Clean(ctx context.Context, dir string) error {
 
 // CleanAll removes all backups globally using registry
 func (m *Manager) CleanAll(ctx context.Context) error {
+	if err := util.CheckWritable("remove backups"); err != nil {
+		return err
+	}
+
 	records, err := m.ListAll(ctx)
 	if err != nil {
 		return err
 	}
 
 	for _, r := range records {
+		if r.Snapshot != nil {
+			m.removeSnapshot(ctx, *r.Snapshot, r.SourceDir)
+		}
 		_ = os.RemoveAll(r.Path) // Ignore individual errors
 	}
 
@@ -189,6 +682,71 @@ func (m *Manager) ListAll(ctx context.Context) ([]types.BackupRecord, error) {
 	return records, nil
 }
 
+// Verify checks each registered backup: its directory and mappings must be
+// readable and every backed-up file must exist. Copies whose size differs
+// from the renamed file are reported as possibly corrupt.
+func (m *Manager) Verify(ctx context.Context) ([]types.BackupReport, error) {
+	records, err := m.ListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make([]types.BackupReport, 0, len(records))
+	for _, r := range records {
+		reports = append(reports, verifyBackup(r))
+	}
+	return reports, nil
+}
+
+func verifyBackup(r types.BackupRecord) types.BackupReport {
+	report := types.BackupReport{Record: r}
+
+	if _, err := os.Stat(r.Path); err != nil {
+		report.Problems = append(report.Problems, "backup directory is missing")
+		return report
+	}
+
+	mappings, _, err := readMappings(r.Path)
+	if err != nil {
+		report.Problems = append(report.Problems, fmt.Sprintf("mappings are unreadable: %v", err))
+		return report
+	}
+	report.Files = len(mappings)
+
+	files := r.Path
+	if r.Snapshot != nil {
+		if r.Snapshot.View == "" {
+			return report // Only the snapshot tool can tell
+		}
+		if _, err := os.Stat(r.Snapshot.View); err != nil {
+			report.Problems = append(report.Problems, fmt.Sprintf("snapshot %s is missing", r.Snapshot.ID))
+			return report
+		}
+		files = r.Snapshot.View
+	}
+
+	names := slices.Sorted(maps.Keys(mappings))
+	for _, oldName := range names {
+		backed, err := os.Stat(filepath.Join(files, oldName))
+		if err != nil {
+			report.Problems = append(report.Problems, fmt.Sprintf("backup of %s is missing", oldName))
+			continue
+		}
+
+		// Hard links share the renamed file's data; copies should match its size
+		current, err := os.Stat(filepath.Join(r.SourceDir, mappings[oldName]))
+		if err != nil || os.SameFile(backed, current) {
+			continue
+		}
+		if backed.Size() != current.Size() {
+			report.Problems = append(report.Problems, fmt.Sprintf("backup of %s is %d bytes but %s is %d bytes",
+				oldName, backed.Size(), mappings[oldName], current.Size()))
+		}
+	}
+
+	return report
+}
+
 func (m *Manager) addRegistry(r types.BackupRecord) error {
 	records, _ := m.ListAll(context.Background())
 	records = append(records, r)
@@ -218,28 +776,103 @@ func (m *Manager) saveRegistry(records []types.BackupRecord) error {
 	return os.WriteFile(m.registryPath, data, 0644)
 }
 
-func copyFile(src, dst string) error {
+// copyChunkSize is the buffer size for byte-wise copies
+const copyChunkSize = 4 << 20
+
+// progressInterval throttles EventProgress emission during long copies
+const progressInterval = 250 * time.Millisecond
+
+// copyFile hard links src to dst, falling back to a chunked copy that emits
+// EventProgress events and stops when ctx is cancelled. A partially written
+// dst is removed on failure.
+func (m *Manager) copyFile(ctx context.Context, src, dst string) (err error) {
 
 	// Try hard link first
 	if err := os.Link(src, dst); err == nil {
 		return nil
 	}
 
-	// Fallback to bitwise copy
+	// Fallback to chunked copy
 	in, err := os.Open(src)
 	if err != nil {
 		return err
 	}
 	defer func() { _ = in.Close() }()
 
-	out, err := os.Create(dst)
+	info, err := in.Stat()
 	if err != nil {
 		return err
 	}
-	defer func() { _ = out.Close() }()
 
-	if _, err = io.Copy(out, in); err != nil {
+	out, err := os.Create(dst)
+	if err != nil {
 		return err
 	}
+	defer func() {
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			_ = os.Remove(dst)
+		}
+	}()
+
+	return m.streamCopy(ctx, out, in, types.CopyProgress{File: filepath.Base(src), Total: info.Size()})
+}
+
+// streamCopy copies src to dst in chunks, checking ctx between chunks and
+// emitting throttled progress plus a final event on completion. With a rate
+// limit, chunks shrink to a quarter second's worth and the copy sleeps
+// whenever it gets ahead of the limit.
+func (m *Manager) streamCopy(ctx context.Context, dst io.Writer, src io.Reader, progress types.CopyProgress) error {
+	chunk := int64(copyChunkSize)
+	if m.rateLimit > 0 {
+		chunk = max(min(chunk, m.rateLimit/4), 1)
+	}
+	buf := make([]byte, chunk)
+	lastEmit := time.Now()
+	start := m.Clock.Now()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		n, rerr := src.Read(buf)
+		if n > 0 {
+			if _, werr := dst.Write(buf[:n]); werr != nil {
+				return werr
+			}
+			progress.Copied += int64(n)
+			if m.rateLimit > 0 {
+				due := time.Duration(float64(progress.Copied) / float64(m.rateLimit) * float64(time.Second))
+				if ahead := due - m.Clock.Now().Sub(start); ahead > 0 {
+					m.Clock.Sleep(ahead)
+				}
+			}
+			if time.Since(lastEmit) >= progressInterval {
+				m.emitProgress(progress)
+				lastEmit = time.Now()
+			}
+		}
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			return rerr
+		}
+	}
+
+	m.emitProgress(progress)
 	return nil
 }
+
+func (m *Manager) emitProgress(p types.CopyProgress) {
+	if m.Events != nil {
+		m.Events(types.Event{
+			Type:    types.EventProgress,
+			Message: fmt.Sprintf("Copying %s", p.File),
+			Data:    p,
+		})
+	}
+}
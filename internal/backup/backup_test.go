@@ -0,0 +1,110 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/mydehq/autotitle/internal/types"
+)
+
+func TestStreamCopy_Progress(t *testing.T) {
+	var events []types.Event
+	m := New(t.TempDir(), "")
+	m.WithEvents(func(e types.Event) { events = append(events, e) })
+
+	data := bytes.Repeat([]byte("x"), copyChunkSize*2+10)
+	var out bytes.Buffer
+	err := m.streamCopy(context.Background(), &out, bytes.NewReader(data), types.CopyProgress{File: "ep.mkv", Total: int64(len(data))})
+	if err != nil {
+		t.Fatalf("streamCopy failed: %v", err)
+	}
+	if out.Len() != len(data) {
+		t.Errorf("Copied %d bytes, want %d", out.Len(), len(data))
+	}
+
+	if len(events) == 0 {
+		t.Fatal("Expected at least one progress event")
+	}
+	last, ok := events[len(events)-1].Data.(types.CopyProgress)
+	if !ok || last.Copied != last.Total {
+		t.Errorf("Final progress event should report completion, got %+v", events[len(events)-1])
+	}
+}
+
+func TestStreamCopy_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	m := New(t.TempDir(), "")
+	var out bytes.Buffer
+	err := m.streamCopy(ctx, &out, bytes.NewReader(make([]byte, 1024)), types.CopyProgress{Total: 1024})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("Expected nothing copied after cancellation, got %d bytes", out.Len())
+	}
+}
+
+// sleepClock is a clock that only advances when slept on
+type sleepClock struct {
+	now   time.Time
+	slept time.Duration
+}
+
+func (c *sleepClock) Now() time.Time { return c.now }
+
+func (c *sleepClock) Sleep(d time.Duration) {
+	c.now = c.now.Add(d)
+	c.slept += d
+}
+
+func TestStreamCopy_RateLimit(t *testing.T) {
+	clock := &sleepClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	m := New(t.TempDir(), "")
+	m.WithClock(clock)
+	m.WithRateLimit(1) // 1 MB/s
+
+	data := make([]byte, 3_000_000)
+	var out bytes.Buffer
+	if err := m.streamCopy(context.Background(), &out, bytes.NewReader(data), types.CopyProgress{Total: int64(len(data))}); err != nil {
+		t.Fatalf("streamCopy failed: %v", err)
+	}
+	if out.Len() != len(data) {
+		t.Errorf("Copied %d bytes, want %d", out.Len(), len(data))
+	}
+	if clock.slept != 3*time.Second {
+		t.Errorf("Expected 3s of throttling for 3 MB at 1 MB/s, slept %v", clock.slept)
+	}
+}
+
+func TestManager_Estimate(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "ep1.mkv"), []byte("video"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A backup inside the directory is always on its filesystem
+	m := New(t.TempDir(), "")
+	estimates, err := m.Estimate(context.Background(), dir, map[string]string{"ep1.mkv": "Show - 01.mkv"})
+	if err != nil {
+		t.Fatalf("Estimate failed: %v", err)
+	}
+	want := []types.BackupEstimate{{Name: "ep1.mkv", Method: types.BackupLink}}
+	if !slices.Equal(estimates, want) {
+		t.Errorf("Estimate = %+v, want %+v", estimates, want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, DefaultDirName)); !os.IsNotExist(err) {
+		t.Error("Estimate should not create the backup dir")
+	}
+
+	if _, err := m.Estimate(context.Background(), dir, map[string]string{"missing.mkv": "x.mkv"}); err != nil {
+		t.Errorf("A linked estimate should not need the file size, got %v", err)
+	}
+}
@@ -0,0 +1,221 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/mydehq/autotitle/internal/types"
+	"github.com/mydehq/autotitle/internal/util"
+)
+
+// btrfsRootInode is the inode number of every btrfs subvolume root
+const btrfsRootInode = 256
+
+// WithSnapshots makes Backup snapshot the filesystem instead of linking or
+// copying files, as set under backup.snapshot
+func (m *Manager) WithSnapshots(cfg types.SnapshotConfig) *Manager {
+	m.snapshot = cfg
+	return m
+}
+
+// snapshotBackup backs up dir by taking a snapshot: the backup dir only
+// holds the mappings, the registry records the snapshot
+func (m *Manager) snapshotBackup(ctx context.Context, absDir string, mappings map[string]string, episodes map[string]int) error {
+	snap, err := m.takeSnapshot(ctx, absDir)
+	if err != nil {
+		return fmt.Errorf("failed to snapshot %s: %w", absDir, err)
+	}
+	m.emit(types.EventInfo, fmt.Sprintf("Took %s snapshot %s", snap.Type, snap.ID))
+
+	backupPath := m.newBackupPath(absDir)
+	if err := os.MkdirAll(backupPath, 0755); err != nil {
+		return fmt.Errorf("failed to create backup dir: %w", err)
+	}
+	if err := writeMappings(backupPath, mappings, episodes); err != nil {
+		return err
+	}
+	return m.addRegistry(types.BackupRecord{
+		Path:      backupPath,
+		SourceDir: absDir,
+		Timestamp: m.Clock.Now(),
+		Snapshot:  &snap,
+	})
+}
+
+// takeSnapshot snapshots the filesystem holding dir
+func (m *Manager) takeSnapshot(ctx context.Context, dir string) (types.Snapshot, error) {
+	// Folders of one run may share a filesystem, so the name tells them apart
+	sum := sha256.Sum256([]byte(dir))
+	name := fmt.Sprintf("autotitle-%s-%x", m.Clock.Now().UTC().Format("20060102-150405"), sum[:4])
+	switch m.snapshot.Type {
+	case types.SnapshotBtrfs:
+		root, err := subvolumeRoot(dir)
+		if err != nil {
+			return types.Snapshot{}, err
+		}
+		if err := os.MkdirAll(filepath.Join(root, types.BtrfsSnapshotDir), 0755); err != nil {
+			return types.Snapshot{}, err
+		}
+		id := filepath.Join(root, types.BtrfsSnapshotDir, name)
+		if _, err := run(ctx, nil, "btrfs", "subvolume", "snapshot", "-r", root, id); err != nil {
+			return types.Snapshot{}, err
+		}
+		return types.Snapshot{Type: types.SnapshotBtrfs, ID: id, View: within(id, root, dir)}, nil
+
+	case types.SnapshotZFS:
+		out, err := run(ctx, nil, "zfs", "list", "-H", "-o", "name,mountpoint", dir)
+		if err != nil {
+			return types.Snapshot{}, err
+		}
+		dataset, mountpoint, ok := strings.Cut(strings.TrimSpace(out), "\t")
+		if !ok {
+			return types.Snapshot{}, fmt.Errorf("unexpected zfs list output %q", out)
+		}
+		id := dataset + "@" + name
+		if _, err := run(ctx, nil, "zfs", "snapshot", id); err != nil {
+			return types.Snapshot{}, err
+		}
+		view := ""
+		if filepath.IsAbs(mountpoint) {
+			view = within(filepath.Join(mountpoint, ".zfs", "snapshot", name), mountpoint, dir)
+		}
+		return types.Snapshot{Type: types.SnapshotZFS, ID: id, View: view}, nil
+
+	case types.SnapshotCommand:
+		if m.snapshot.Create == "" {
+			return types.Snapshot{}, fmt.Errorf("backup.snapshot.create is required for command snapshots")
+		}
+		out, err := runHook(ctx, m.snapshot.Create, dir, "")
+		if err != nil {
+			return types.Snapshot{}, err
+		}
+		lines := strings.Split(strings.TrimSpace(out), "\n")
+		id := strings.TrimSpace(lines[len(lines)-1])
+		if id == "" {
+			return types.Snapshot{}, fmt.Errorf("backup.snapshot.create printed no snapshot ID")
+		}
+		return types.Snapshot{Type: types.SnapshotCommand, ID: id}, nil
+	}
+	return types.Snapshot{}, fmt.Errorf("unknown snapshot type %q (use btrfs, zfs or command)", m.snapshot.Type)
+}
+
+// deleteSnapshot removes a snapshot once its backup is cleaned. Command
+// snapshots without a delete command are left for the user.
+func (m *Manager) deleteSnapshot(ctx context.Context, snap types.Snapshot, dir string) error {
+	switch snap.Type {
+	case types.SnapshotBtrfs:
+		_, err := run(ctx, nil, "btrfs", "subvolume", "delete", snap.ID)
+		return err
+	case types.SnapshotZFS:
+		_, err := run(ctx, nil, "zfs", "destroy", snap.ID)
+		return err
+	case types.SnapshotCommand:
+		if m.snapshot.Delete == "" {
+			return nil
+		}
+		_, err := runHook(ctx, m.snapshot.Delete, dir, snap.ID)
+		return err
+	}
+	return nil
+}
+
+// removeSnapshot deletes the snapshot of a cleaned backup; a snapshot that
+// cannot be deleted is only reported, it takes no more than its changes
+func (m *Manager) removeSnapshot(ctx context.Context, snap types.Snapshot, dir string) {
+	if err := m.deleteSnapshot(ctx, snap, dir); err != nil {
+		m.emit(types.EventWarning, fmt.Sprintf("Failed to delete snapshot %s: %v", snap.ID, err))
+	}
+}
+
+// rollback restores a whole directory from a snapshot it cannot read files
+// from: with the rollback command if set, else it returns
+// types.ErrSnapshotRollback explaining how to do it by hand
+func (m *Manager) rollback(ctx context.Context, snap types.Snapshot, dir string) error {
+	if snap.Type == types.SnapshotCommand && m.snapshot.Rollback != "" {
+		if _, err := runHook(ctx, m.snapshot.Rollback, dir, snap.ID); err != nil {
+			return fmt.Errorf("snapshot rollback failed: %w", err)
+		}
+		m.emit(types.EventSuccess, fmt.Sprintf("Rolled %s back to snapshot %s", dir, snap.ID))
+		return nil
+	}
+
+	hint := "restore it with your snapshot tool, or set backup.snapshot.rollback"
+	switch snap.Type {
+	case types.SnapshotBtrfs:
+		hint = fmt.Sprintf("the snapshot is gone or not mounted; restore the files from %s", snap.ID)
+	case types.SnapshotZFS:
+		hint = fmt.Sprintf("roll back the whole dataset with: zfs rollback -r %s", snap.ID)
+	}
+	return types.ErrSnapshotRollback{Directory: dir, Snapshot: snap, Hint: hint}
+}
+
+// subvolumeRoot returns the root of the btrfs subvolume holding dir: the
+// first parent with the subvolume root inode, on the same device as dir
+func subvolumeRoot(dir string) (string, error) {
+	dev, ino, err := util.FileID(dir)
+	if err != nil {
+		return "", err
+	}
+	for ino != btrfsRootInode {
+		parent := filepath.Dir(dir)
+		pdev, pino, err := util.FileID(parent)
+		if parent == dir || err != nil || pdev != dev {
+			return "", fmt.Errorf("%s is not on a btrfs subvolume", dir)
+		}
+		dir, ino = parent, pino
+	}
+	return dir, nil
+}
+
+// files returns where the originals of a backup are: the backup dir, or
+// the snapshot's view of absDir ("" if it has none) along with the snapshot
+func (m *Manager) files(absDir string) (string, *types.Snapshot) {
+	r := m.record(absDir)
+	if r == nil {
+		return m.newBackupPath(absDir), nil
+	}
+	if r.Snapshot != nil {
+		if _, err := os.Stat(r.Snapshot.View); r.Snapshot.View == "" || err != nil {
+			return "", r.Snapshot
+		}
+		return r.Snapshot.View, r.Snapshot
+	}
+	return r.Path, nil
+}
+
+// within returns where dir, under root, is in a copy of root at copyRoot
+func within(copyRoot, root, dir string) string {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(copyRoot, rel)
+}
+
+// runHook runs a configured shell command with the directory and snapshot
+// in its environment, returning what it printed
+func runHook(ctx context.Context, command, dir, snapshot string) (string, error) {
+	env := append(os.Environ(), "AUTOTITLE_DIR="+dir)
+	if snapshot != "" {
+		env = append(env, "AUTOTITLE_SNAPSHOT="+snapshot)
+	}
+	return run(ctx, env, "sh", "-c", command)
+}
+
+// run runs a command, returning its output, or its error output on failure
+func run(ctx context.Context, env []string, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = env
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s failed: %w\noutput: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,150 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mydehq/autotitle/internal/types"
+)
+
+// commandSnapshots fakes a snapshot tool with shell commands that log what
+// they are asked to do in log
+func commandSnapshots(log string) types.SnapshotConfig {
+	return types.SnapshotConfig{
+		Type:   types.SnapshotCommand,
+		Create: `echo "creating"; echo snap-1`,
+		Delete: `echo "delete $AUTOTITLE_SNAPSHOT" >> ` + log,
+	}
+}
+
+func TestManager_SnapshotBackup(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	log := filepath.Join(t.TempDir(), "log")
+	if err := os.WriteFile(filepath.Join(dir, "ep1.mkv"), []byte("video"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := New(t.TempDir(), "")
+	m.WithSnapshots(commandSnapshots(log))
+	if err := m.Backup(ctx, dir, map[string]string{"ep1.mkv": "Show - 01.mkv"}, nil); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+
+	records, _ := m.ListAll(ctx)
+	if len(records) != 1 || records[0].Snapshot == nil || records[0].Snapshot.ID != "snap-1" {
+		t.Fatalf("registry = %+v, want one record of snapshot snap-1", records)
+	}
+	if _, err := os.Stat(filepath.Join(records[0].Path, "ep1.mkv")); !os.IsNotExist(err) {
+		t.Error("A snapshot backup should not link or copy files")
+	}
+
+	reports, err := m.Verify(ctx)
+	if err != nil || len(reports) != 1 || len(reports[0].Problems) > 0 {
+		t.Errorf("Verify = %+v, %v; want no problems", reports, err)
+	}
+
+	if err := m.Clean(ctx, dir); err != nil {
+		t.Fatalf("Clean failed: %v", err)
+	}
+	data, _ := os.ReadFile(log)
+	if strings.TrimSpace(string(data)) != "delete snap-1" {
+		t.Errorf("delete log = %q, want the snapshot deleted", data)
+	}
+}
+
+func TestManager_SnapshotRollback(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	log := filepath.Join(t.TempDir(), "log")
+	mappings := map[string]string{"ep1.mkv": "Show - 01.mkv", "ep2.mkv": "Show - 02.mkv"}
+	for newName := range mappings {
+		if err := os.WriteFile(filepath.Join(dir, newName), []byte("video"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := commandSnapshots(log)
+	m := New(t.TempDir(), "")
+	m.WithSnapshots(cfg)
+	if err := m.Backup(ctx, dir, mappings, nil); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+
+	// Without a rollback command, undo says how to roll back
+	err := m.Restore(ctx, dir)
+	var rb types.ErrSnapshotRollback
+	if !errors.As(err, &rb) || rb.Snapshot.ID != "snap-1" || rb.Directory != dir {
+		t.Fatalf("Restore = %v, want ErrSnapshotRollback for snap-1", err)
+	}
+
+	// A snapshot is rolled back whole, never in part
+	err = m.RestoreOnly(ctx, dir, func(e types.RestoreEntry) bool { return e.Original == "ep1.mkv" })
+	if err == nil || !strings.Contains(err.Error(), "rolled back whole") {
+		t.Errorf("partial RestoreOnly = %v, want a whole-rollback error", err)
+	}
+
+	cfg.Rollback = `echo "rollback $AUTOTITLE_SNAPSHOT $AUTOTITLE_DIR" >> ` + log
+	m.WithSnapshots(cfg)
+	if err := m.Restore(ctx, dir); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	data, _ := os.ReadFile(log)
+	want := "rollback snap-1 " + dir + "\ndelete snap-1\n"
+	if string(data) != want {
+		t.Errorf("log = %q, want %q", data, want)
+	}
+	if records, _ := m.ListAll(ctx); len(records) != 0 {
+		t.Errorf("registry = %+v, want the backup cleaned after the rollback", records)
+	}
+}
+
+func TestManager_SnapshotView(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	view := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "Show - 01.mkv"), []byte("video"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(view, "ep1.mkv"), []byte("video"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A snapshot with a view is restored file by file, as a backup dir is
+	m := New(t.TempDir(), "")
+	if err := m.addRegistry(types.BackupRecord{
+		Path:      m.newBackupPath(dir),
+		SourceDir: dir,
+		Snapshot:  &types.Snapshot{Type: types.SnapshotBtrfs, ID: "snap-1", View: view},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(m.newBackupPath(dir), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeMappings(m.newBackupPath(dir), map[string]string{"ep1.mkv": "Show - 01.mkv"}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	keep := func(types.RestoreEntry) bool { return true }
+	if err := m.RestoreOnly(ctx, dir, keep); err != nil {
+		t.Fatalf("RestoreOnly failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "ep1.mkv")); err != nil {
+		t.Errorf("ep1.mkv was not restored from the view: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(view, "ep1.mkv")); err != nil {
+		t.Error("Restoring should leave the snapshot as it is")
+	}
+}
+
+func TestWithin(t *testing.T) {
+	got := within("/mnt/.autotitle-snapshots/s1", "/mnt", "/mnt/Anime/Show")
+	if want := filepath.Join("/mnt/.autotitle-snapshots/s1", "Anime", "Show"); got != want {
+		t.Errorf("within = %q, want %q", got, want)
+	}
+}
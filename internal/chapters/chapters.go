@@ -0,0 +1,283 @@
+// Package chapters finds an episode's opening and ending with ffmpeg's
+// black frame and silence detection and writes them into MKVs as chapters
+// ("Opening", "Ending"), which media servers offer as skip-intro markers.
+package chapters
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/mydehq/autotitle/internal/types"
+	"github.com/mydehq/autotitle/internal/util"
+)
+
+const (
+	ffmpegBin = "ffmpeg"
+	mkvBin    = "mkvpropedit"
+
+	// DefaultWindow is how much of the start and end is searched
+	DefaultWindow = 6 * time.Minute
+
+	// Openings and endings are cut at scene breaks this far apart; anime
+	// ones run 90 seconds, so that length wins among several candidates
+	minSegment   = 20 * time.Second
+	maxSegment   = 120 * time.Second
+	typicalSongs = 90 * time.Second
+
+	// A black frame and a silence this close together are one scene break
+	breakTolerance = 500 * time.Millisecond
+)
+
+// Chapter is a chapter start and its name
+type Chapter struct {
+	Start time.Duration
+	Title string
+}
+
+// lookPath reports whether a tool is in $PATH; replaced in tests
+var lookPath = func(bin string) bool {
+	_, err := exec.LookPath(bin)
+	return err == nil
+}
+
+// IsAvailable returns true if ffmpeg and mkvpropedit are in $PATH
+func IsAvailable() bool {
+	return lookPath(ffmpegBin) && lookPath(mkvBin)
+}
+
+// IsSupported reports whether chapters can be written to path (Matroska only)
+func IsSupported(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".mkv", ".mk3d":
+		return true
+	}
+	return false
+}
+
+// Mark detects the opening and ending of video and writes them as chapters.
+// It returns the chapters written, or none if the file already has chapters
+// (unless cfg.Overwrite) or no opening or ending was found.
+func Mark(ctx context.Context, video string, cfg types.ChapterConfig) ([]Chapter, error) {
+	if err := util.CheckWritable("write chapters"); err != nil {
+		return nil, err
+	}
+	if !IsSupported(video) {
+		return nil, nil
+	}
+	if !IsAvailable() {
+		return nil, fmt.Errorf("ffmpeg and mkvpropedit are needed to write chapters to %s", filepath.Base(video))
+	}
+	window := DefaultWindow
+	if cfg.Window != "" {
+		d, err := time.ParseDuration(cfg.Window)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid chapters.window %q (use e.g. 6m)", cfg.Window)
+		}
+		window = d
+	}
+
+	head, err := analyze(ctx, video, 0, window)
+	if err != nil {
+		return nil, err
+	}
+	if head.hasChapters && !cfg.Overwrite {
+		return nil, nil
+	}
+	var tail scan
+	if start := head.duration - window; start > window {
+		if tail, err = analyze(ctx, video, start, window); err != nil {
+			return nil, err
+		}
+	}
+
+	chapters := layout(head.duration, findSegment(head.breaks), findSegment(tail.breaks))
+	if chapters == nil {
+		return nil, nil
+	}
+	return chapters, write(ctx, video, chapters)
+}
+
+// scan is what one ffmpeg pass found
+type scan struct {
+	duration    time.Duration // Of the whole file
+	hasChapters bool
+	breaks      []time.Duration // Scene breaks: black frames with silence
+}
+
+var (
+	reDuration = regexp.MustCompile(`Duration: (\d+):(\d+):(\d+(?:\.\d+)?)`)
+	reBlack    = regexp.MustCompile(`black_start:\s*([\d.]+)\s+black_end:\s*([\d.]+)`)
+	reSilence  = regexp.MustCompile(`silence_(start|end):\s*(-?[\d.]+)`)
+)
+
+// analyze runs black frame and silence detection over window from start
+func analyze(ctx context.Context, video string, start, window time.Duration) (scan, error) {
+	args := []string{"-nostdin", "-hide_banner"}
+	if start > 0 {
+		args = append(args, "-ss", seconds(start))
+	}
+	args = append(args, "-t", seconds(window), "-i", video,
+		"-vf", "blackdetect=d=0.05:pix_th=0.10", "-af", "silencedetect=n=-45dB:d=0.3",
+		"-f", "null", "-")
+	cmd := exec.CommandContext(ctx, ffmpegBin, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return scan{}, fmt.Errorf("ffmpeg failed on %s: %w", filepath.Base(video), err)
+	}
+	s := parseOutput(stderr.String())
+	for i := range s.breaks {
+		s.breaks[i] += start
+	}
+	return s, nil
+}
+
+// parseOutput reads the duration, existing chapters and scene breaks from
+// ffmpeg's log. Times are relative to where the pass started.
+func parseOutput(log string) scan {
+	var s scan
+	var blacks, silences [][2]time.Duration
+	var silenceStart time.Duration
+	sc := bufio.NewScanner(strings.NewReader(log))
+	for sc.Scan() {
+		line := sc.Text()
+		if m := reDuration.FindStringSubmatch(line); m != nil && s.duration == 0 {
+			h, _ := strconv.Atoi(m[1])
+			mins, _ := strconv.Atoi(m[2])
+			sec, _ := strconv.ParseFloat(m[3], 64)
+			s.duration = time.Duration(h)*time.Hour + time.Duration(mins)*time.Minute + parseSeconds(sec)
+		}
+		if strings.TrimSpace(line) == "Chapters:" {
+			s.hasChapters = true
+		}
+		if m := reBlack.FindStringSubmatch(line); m != nil {
+			from, _ := strconv.ParseFloat(m[1], 64)
+			to, _ := strconv.ParseFloat(m[2], 64)
+			blacks = append(blacks, [2]time.Duration{parseSeconds(from), parseSeconds(to)})
+		}
+		if m := reSilence.FindStringSubmatch(line); m != nil {
+			v, _ := strconv.ParseFloat(m[2], 64)
+			if m[1] == "start" {
+				silenceStart = parseSeconds(max(v, 0))
+			} else {
+				silences = append(silences, [2]time.Duration{silenceStart, parseSeconds(v)})
+			}
+		}
+	}
+
+	for _, b := range blacks {
+		for _, q := range silences {
+			if b[0] <= q[1]+breakTolerance && q[0] <= b[1]+breakTolerance {
+				s.breaks = append(s.breaks, (b[0]+b[1])/2)
+				break
+			}
+		}
+	}
+	return s
+}
+
+// findSegment picks the pair of scene breaks most likely to enclose an
+// opening or ending: between minSegment and maxSegment apart, as close to
+// typicalSongs as possible
+func findSegment(breaks []time.Duration) *[2]time.Duration {
+	var best *[2]time.Duration
+	bestDiff := time.Duration(math.MaxInt64)
+	for i, a := range breaks {
+		for _, b := range breaks[i+1:] {
+			length := b - a
+			if length < minSegment || length > maxSegment {
+				continue
+			}
+			if diff := (length - typicalSongs).Abs(); diff < bestDiff {
+				best, bestDiff = &[2]time.Duration{a, b}, diff
+			}
+		}
+	}
+	return best
+}
+
+// layout turns the opening and ending into chapters covering the episode
+func layout(duration time.Duration, opening, ending *[2]time.Duration) []Chapter {
+	if opening == nil && ending == nil {
+		return nil
+	}
+	var chapters []Chapter
+	switch {
+	case opening == nil:
+		chapters = append(chapters, Chapter{0, "Episode"})
+	case opening[0] > time.Second:
+		chapters = append(chapters, Chapter{0, "Prologue"})
+		fallthrough
+	default:
+		chapters = append(chapters, Chapter{opening[0], "Opening"}, Chapter{opening[1], "Episode"})
+	}
+	if ending != nil {
+		chapters = append(chapters, Chapter{ending[0], "Ending"})
+		if duration-ending[1] > time.Second {
+			chapters = append(chapters, Chapter{ending[1], "Preview"})
+		}
+	}
+	return chapters
+}
+
+const chapterXMLTemplate = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE Chapters SYSTEM "matroskachapters.dtd">
+<Chapters>
+  <EditionEntry>{{range .}}
+    <ChapterAtom>
+      <ChapterTimeStart>{{timestamp .Start}}</ChapterTimeStart>
+      <ChapterDisplay>
+        <ChapterString>{{.Title}}</ChapterString>
+        <ChapterLanguage>eng</ChapterLanguage>
+      </ChapterDisplay>
+    </ChapterAtom>{{end}}
+  </EditionEntry>
+</Chapters>
+`
+
+var chapterTmpl = template.Must(template.New("chapters").Funcs(template.FuncMap{"timestamp": timestamp}).Parse(chapterXMLTemplate))
+
+// write replaces the chapters of an MKV with mkvpropedit
+func write(ctx context.Context, video string, chapters []Chapter) error {
+	tmpFile, err := os.CreateTemp("", "autotitle-chapters-*.xml")
+	if err != nil {
+		return fmt.Errorf("failed to create temp chapter file: %w", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if err := chapterTmpl.Execute(tmpFile, chapters); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("failed to write chapter XML: %w", err)
+	}
+	tmpFile.Close()
+
+	cmd := exec.CommandContext(ctx, mkvBin, video, "--chapters", tmpFile.Name())
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("mkvpropedit failed: %w\noutput: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// timestamp formats d as Matroska chapter time, HH:MM:SS.nnnnnnnnn
+func timestamp(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d:%02d.%09d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60, d.Nanoseconds()%int64(time.Second))
+}
+
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
+
+func parseSeconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second)).Round(time.Millisecond)
+}
@@ -0,0 +1,74 @@
+package chapters
+
+import (
+	"testing"
+	"time"
+)
+
+const sampleLog = `Input #0, matroska,webm, from 'ep.mkv':
+  Duration: 00:23:40.02, start: 0.000000, bitrate: 2400 kb/s
+  Chapters:
+    Chapter #0:0: start 0.000000, end 90.000000
+[silencedetect @ 0x1] silence_start: -0.01
+[silencedetect @ 0x1] silence_end: 0.8 | silence_duration: 0.81
+[blackdetect @ 0x2] black_start:0 black_end:0.5 black_duration:0.5
+[blackdetect @ 0x2] black_start:62.1 black_end:62.5 black_duration:0.4
+[silencedetect @ 0x1] silence_start: 61.9
+[silencedetect @ 0x1] silence_end: 62.6 | silence_duration: 0.7
+[blackdetect @ 0x2] black_start:100 black_end:100.2 black_duration:0.2
+[blackdetect @ 0x2] black_start:152 black_end:152.4 black_duration:0.4
+[silencedetect @ 0x1] silence_start: 152.1
+[silencedetect @ 0x1] silence_end: 152.5 | silence_duration: 0.4
+`
+
+func TestParseOutput(t *testing.T) {
+	s := parseOutput(sampleLog)
+	if s.duration != 23*time.Minute+40020*time.Millisecond {
+		t.Errorf("duration = %v", s.duration)
+	}
+	if !s.hasChapters {
+		t.Error("expected existing chapters to be noticed")
+	}
+	// The black frame at 100s has no silence, so it isn't a scene break
+	want := []time.Duration{250 * time.Millisecond, 62300 * time.Millisecond, 152200 * time.Millisecond}
+	if len(s.breaks) != len(want) {
+		t.Fatalf("breaks = %v, want %v", s.breaks, want)
+	}
+	for i := range want {
+		if s.breaks[i] != want[i] {
+			t.Errorf("breaks = %v, want %v", s.breaks, want)
+		}
+	}
+}
+
+func TestFindSegment(t *testing.T) {
+	breaks := []time.Duration{5 * time.Second, 60 * time.Second, 150 * time.Second, 155 * time.Second}
+	got := findSegment(breaks)
+	// 60-150 is exactly a 90s song; 5-60 (55s) and 60-155 (95s) also fit
+	if got == nil || got[0] != 60*time.Second || got[1] != 150*time.Second {
+		t.Errorf("findSegment = %v", got)
+	}
+	if findSegment([]time.Duration{time.Second, 5 * time.Second}) != nil {
+		t.Error("expected no segment between breaks 4s apart")
+	}
+}
+
+func TestLayout(t *testing.T) {
+	d := 24 * time.Minute
+	got := layout(d, &[2]time.Duration{60 * time.Second, 150 * time.Second}, &[2]time.Duration{21 * time.Minute, 22*time.Minute + 30*time.Second})
+	want := []string{"Prologue", "Opening", "Episode", "Ending", "Preview"}
+	if len(got) != len(want) {
+		t.Fatalf("layout = %+v", got)
+	}
+	for i, c := range got {
+		if c.Title != want[i] {
+			t.Errorf("chapter %d = %q, want %q", i, c.Title, want[i])
+		}
+	}
+	if layout(d, nil, nil) != nil {
+		t.Error("expected no chapters without an opening or ending")
+	}
+	if got := timestamp(62*time.Minute + 1500*time.Millisecond); got != "01:02:01.500000000" {
+		t.Errorf("timestamp = %q", got)
+	}
+}
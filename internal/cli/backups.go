@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mydehq/autotitle"
+	"github.com/mydehq/autotitle/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var backupsCmd = &cobra.Command{
+	Use:   "backups",
+	Short: "Backup management commands",
+}
+
+var backupsVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check that registered backups can still be restored",
+	Run: func(cmd *cobra.Command, args []string) {
+		runBackupsVerify(cmd)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(backupsCmd)
+	backupsCmd.AddCommand(backupsVerifyCmd)
+}
+
+func runBackupsVerify(cmd *cobra.Command) {
+	reports, err := autotitle.VerifyBackups(cmd.Context())
+	if err != nil {
+		logger.Error("Failed to verify backups", "error", err)
+		os.Exit(1)
+	}
+
+	if len(reports) == 0 {
+		logger.Warn("No backups found")
+		return
+	}
+
+	broken := 0
+	for _, r := range reports {
+		if len(r.Problems) == 0 {
+			logger.Success(fmt.Sprintf("%s %s",
+				ui.StylePath.Render(r.Record.SourceDir),
+				ui.StyleDim.Render(fmt.Sprintf("(%d files, %s)", r.Files, r.Record.Timestamp.Local().Format("2006-01-02 15:04"))),
+			))
+			continue
+		}
+
+		broken++
+		logger.Warn(ui.StylePath.Render(r.Record.SourceDir))
+		for _, p := range r.Problems {
+			logger.Print(fmt.Sprintf("  %s %s", ui.StyleDim.Render("-"), p))
+		}
+	}
+
+	fmt.Println()
+	if broken > 0 {
+		logger.Error(fmt.Sprintf("%d of %d backup(s) can no longer be fully restored", broken, len(reports)))
+		os.Exit(1)
+	}
+	logger.Success(ui.StyleHeader.Render(fmt.Sprintf("All %d backup(s) verified", len(reports))))
+}
@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mydehq/autotitle"
+	"github.com/mydehq/autotitle/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Cache management commands",
+}
+
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show cache size against cache.max_size",
+	Long: `stats reports how much space the database cache takes. With
+cache.max_size set in the global config, each fetch evicts the least
+recently used entries once the cache grows past the cap; entries a rename
+or tag run used recently are kept longest.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		runCacheStats(cmd.Context())
+	},
+}
+
+func init() {
+	cacheCmd.AddCommand(cacheStatsCmd)
+	RootCmd.AddCommand(cacheCmd)
+}
+
+func runCacheStats(ctx context.Context) {
+	stats, err := autotitle.CacheUsage(ctx)
+	if err != nil {
+		logger.Error("Failed to read cache stats", "error", err)
+		os.Exit(1)
+	}
+
+	keyStyle := ui.StyleHeader.Width(15)
+	limit := "none"
+	if stats.MaxSize > 0 {
+		limit = fmt.Sprintf("%s (%.0f%% used)", formatBytes(stats.MaxSize), 100*float64(stats.Size)/float64(stats.MaxSize))
+	}
+
+	logger.Print(fmt.Sprintf("%s %s", keyStyle.Render("Path:"), ui.StylePath.Render(stats.Path)))
+	logger.Print(fmt.Sprintf("%s %d", keyStyle.Render("Files:"), stats.Files))
+	logger.Print(fmt.Sprintf("%s %s", keyStyle.Render("Size:"), formatBytes(stats.Size)))
+	logger.Print(fmt.Sprintf("%s %s", keyStyle.Render("Max Size:"), limit))
+	if stats.Files > 0 {
+		logger.Print(fmt.Sprintf("%s %s", keyStyle.Render("Least Recent:"), stats.LeastRecent.Local().Format(time.DateTime)))
+		logger.Print(fmt.Sprintf("%s %s", keyStyle.Render("Most Recent:"), stats.MostRecent.Local().Format(time.DateTime)))
+	}
+}
@@ -9,7 +9,11 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var flagCleanAll bool
+var (
+	flagCleanAll      bool
+	flagCleanMetadata bool
+	flagCleanDryRun   bool
+)
 
 var cleanCmd = &cobra.Command{
 	Use:   "clean [path]",
@@ -23,6 +27,8 @@ var cleanCmd = &cobra.Command{
 func init() {
 	RootCmd.AddCommand(cleanCmd)
 	cleanCmd.Flags().BoolVarP(&flagCleanAll, "all", "a", false, "Remove all backups globally")
+	cleanCmd.Flags().BoolVarP(&flagCleanMetadata, "metadata", "m", false, "Remove NAS/OS metadata clutter (@eaDir, .DS_Store, Thumbs.db) instead of the backup")
+	cleanCmd.Flags().BoolVarP(&flagCleanDryRun, "dry-run", "d", false, "With --metadata, list clutter without removing it")
 }
 
 func runClean(cmd *cobra.Command, args []string) {
@@ -41,9 +47,40 @@ func runClean(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
+	if flagCleanMetadata {
+		runCleanMetadata(cmd, args[0])
+		return
+	}
+
 	if err := autotitle.Clean(ctx, args[0]); err != nil {
 		logger.Error("Failed to remove backup", "path", args[0], "error", err)
 		os.Exit(1)
 	}
 	logger.Success(fmt.Sprintf("%s: %s", ui.StyleHeader.Render("Removed backup"), ui.StylePath.Render(args[0])))
 }
+
+func runCleanMetadata(cmd *cobra.Command, path string) {
+	var opts []autotitle.Option
+	if flagCleanDryRun {
+		opts = append(opts, autotitle.WithDryRun())
+	}
+
+	removed, err := autotitle.CleanMetadata(cmd.Context(), path, opts...)
+	if err != nil {
+		logger.Error("Failed to clean metadata", "path", path, "error", err)
+		os.Exit(1)
+	}
+	if len(removed) == 0 {
+		logger.Info("No metadata clutter found")
+		return
+	}
+
+	for _, p := range removed {
+		logger.Print(fmt.Sprintf("  %s %s", ui.StyleDim.Render("-"), ui.StylePath.Render(p)))
+	}
+	if flagCleanDryRun {
+		logger.Info(fmt.Sprintf("%s: %d item(s) would be removed", ui.StyleHeader.Render("Dry run"), len(removed)))
+		return
+	}
+	logger.Success(fmt.Sprintf("%s: %d item(s)", ui.StyleHeader.Render("Removed metadata clutter"), len(removed)))
+}
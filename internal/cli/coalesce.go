@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mydehq/autotitle/internal/ui"
+)
+
+const (
+	// coalesceAfter successes are logged one by one before coalescing starts
+	coalesceAfter = 20
+	// coalesceEvery successes, or coalesceInterval, whichever comes first,
+	// make up one coalesced line
+	coalesceEvery    = 100
+	coalesceInterval = 5 * time.Second
+)
+
+// coalescer folds the success lines of large batches ("Renamed: a → b" for
+// each of 5000 files) into a progress line every coalesceEvery files or
+// coalesceInterval, so a slow batch still shows progress between files.
+// Warnings and errors are always logged in full, and --verbose logs every
+// line.
+type coalescer struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	after   func(d time.Duration, f func()) (stop func() bool) // Runs f after d
+	log     func(line string)
+	total   int       // Successes seen
+	pending int       // Successes not logged yet
+	last    string    // Latest pending success message
+	since   time.Time // When the first pending success came in
+	round   int       // Counts flushes, so a late timer of an earlier round does nothing
+	stop    func() bool
+}
+
+// newCoalescer returns a coalescer logging through log on the real clock
+func newCoalescer(log func(string)) *coalescer {
+	return &coalescer{
+		now: time.Now,
+		after: func(d time.Duration, f func()) func() bool {
+			return time.AfterFunc(d, f).Stop
+		},
+		log: log,
+	}
+}
+
+var successes = newCoalescer(func(line string) { logger.Success(line) })
+
+// add logs a success message now, or holds it back for the next coalesced line
+func (c *coalescer) add(msg string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.total++
+	if flagVerbose || c.total <= coalesceAfter {
+		c.log(ui.ColorizeEvent(msg))
+		return
+	}
+	if c.pending == 0 {
+		c.since = c.now()
+		round := c.round
+		c.stop = c.after(coalesceInterval, func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			if c.round == round {
+				c.flushLocked()
+			}
+		})
+	}
+	c.pending++
+	c.last = msg
+	if c.pending >= coalesceEvery || c.now().Sub(c.since) >= coalesceInterval {
+		c.flushLocked()
+	}
+}
+
+// flush logs the coalesced line for the pending successes, if any
+func (c *coalescer) flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.flushLocked()
+}
+
+// flushLocked logs the latest pending success with a count of the others
+func (c *coalescer) flushLocked() {
+	if c.pending == 0 {
+		return
+	}
+	line := ui.ColorizeEvent(c.last)
+	if c.pending > 1 {
+		line += " " + ui.StyleDim.Render(fmt.Sprintf("(+%d more, %d so far)", c.pending-1, c.total))
+	}
+	c.pending = 0
+	c.last = ""
+	c.round++
+	if c.stop != nil {
+		c.stop()
+		c.stop = nil
+	}
+	c.log(line)
+}
+
+// flushSuccesses logs the successes still held back; call it once a
+// command's events are done
+func flushSuccesses() {
+	successes.flush()
+}
@@ -0,0 +1,147 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/mydehq/autotitle"
+	"github.com/mydehq/autotitle/internal/ui"
+)
+
+// fakeCoalescer returns a coalescer on a manual clock and timer, and the
+// lines it logged
+func fakeCoalescer() (c *coalescer, clock *time.Time, fire func(), lines *[]string) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock = &start
+	lines = &[]string{}
+	var timers []func()
+	c = &coalescer{
+		now: func() time.Time { return *clock },
+		after: func(d time.Duration, f func()) func() bool {
+			timers = append(timers, f)
+			return func() bool { return true }
+		},
+		log: func(line string) { *lines = append(*lines, line) },
+	}
+	fire = func() {
+		for _, f := range timers {
+			f()
+		}
+		timers = nil
+	}
+	return c, clock, fire, lines
+}
+
+func TestCoalescer(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(c *coalescer, clock *time.Time, fire func())
+		from int      // Lines logged before are not checked
+		want []string // Line suffixes, in order
+	}{
+		{
+			name: "first successes one by one",
+			run: func(c *coalescer, _ *time.Time, _ func()) {
+				for i := 1; i <= coalesceAfter; i++ {
+					c.add(fmt.Sprintf("Renamed: %d", i))
+				}
+			},
+			want: func() []string {
+				var w []string
+				for i := 1; i <= coalesceAfter; i++ {
+					w = append(w, fmt.Sprintf("Renamed: %d", i))
+				}
+				return w
+			}(),
+		},
+		{
+			name: "every hundred",
+			from: coalesceAfter,
+			run: func(c *coalescer, _ *time.Time, _ func()) {
+				for i := 1; i <= coalesceAfter+2*coalesceEvery+5; i++ {
+					c.add(fmt.Sprintf("Renamed: %d", i))
+				}
+				c.flush()
+			},
+			want: []string{"Renamed: 120 (+99 more, 120 so far)", "Renamed: 220 (+99 more, 220 so far)", "Renamed: 225 (+4 more, 225 so far)"},
+		},
+		{
+			name: "interval reached by the next success",
+			from: coalesceAfter,
+			run: func(c *coalescer, clock *time.Time, _ func()) {
+				for i := 1; i <= coalesceAfter+2; i++ {
+					c.add(fmt.Sprintf("Renamed: %d", i))
+				}
+				*clock = clock.Add(coalesceInterval)
+				c.add("Renamed: 23")
+			},
+			want: []string{"Renamed: 23 (+2 more, 23 so far)"},
+		},
+		{
+			name: "interval reached between successes",
+			from: coalesceAfter,
+			run: func(c *coalescer, clock *time.Time, fire func()) {
+				for i := 1; i <= coalesceAfter+3; i++ {
+					c.add(fmt.Sprintf("Renamed: %d", i))
+				}
+				fire()
+				c.add("Renamed: 24")
+				c.flush()
+			},
+			want: []string{"Renamed: 23 (+2 more, 23 so far)", "Renamed: 24"},
+		},
+		{
+			name: "late timer of a flushed round",
+			from: coalesceAfter,
+			run: func(c *coalescer, _ *time.Time, fire func()) {
+				for i := 1; i <= coalesceAfter+1; i++ {
+					c.add(fmt.Sprintf("Renamed: %d", i))
+				}
+				c.flush()
+				c.add("Renamed: 22")
+				fire()
+			},
+			want: []string{"Renamed: 21", "Renamed: 22"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, clock, fire, lines := fakeCoalescer()
+			tt.run(c, clock, fire)
+
+			got := (*lines)[tt.from:]
+			if len(got) != len(tt.want) {
+				t.Fatalf("logged %d lines, want %d: %q", len(got), len(tt.want), got)
+			}
+			for i, w := range tt.want {
+				if !strings.HasSuffix(got[i], w) {
+					t.Errorf("line %d = %q, want it to end in %q", i, got[i], w)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleEvent_FlushesBeforeWarning(t *testing.T) {
+	var buf bytes.Buffer
+	oldLogger, oldSuccesses := logger, successes
+	t.Cleanup(func() { logger, successes = oldLogger, oldSuccesses })
+	logger = &ui.Logger{Logger: log.New(&buf)}
+	successes, _, _, _ = fakeCoalescer()
+	successes.log = func(line string) { logger.Success(line) }
+
+	for i := 1; i <= coalesceAfter+5; i++ {
+		handleEvent(autotitle.Event{Type: autotitle.EventSuccess, Message: fmt.Sprintf("Renamed: %d", i)})
+	}
+	handleEvent(autotitle.Event{Type: autotitle.EventWarning, Message: "Skipped: odd.mkv"})
+
+	out := buf.String()
+	flushed, warned := strings.Index(out, "Renamed: 25"), strings.Index(out, "Skipped: odd.mkv")
+	if flushed < 0 || warned < 0 || flushed > warned {
+		t.Errorf("the held-back successes should be logged before the warning:\n%s", out)
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/mydehq/autotitle"
 	"github.com/mydehq/autotitle/internal/ui"
@@ -58,6 +60,14 @@ var dbRmCmd = &cobra.Command{
 	},
 }
 
+var dbStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Summarize cached databases",
+	Run: func(cmd *cobra.Command, args []string) {
+		runDBStats(cmd.Context())
+	},
+}
+
 var dbPathCmd = &cobra.Command{
 	Use:   "path",
 	Short: "Show database directory path",
@@ -68,7 +78,7 @@ var dbPathCmd = &cobra.Command{
 
 func init() {
 	RootCmd.AddCommand(dbCmd)
-	dbCmd.AddCommand(dbGenCmd, dbListCmd, dbInfoCmd, dbRmCmd, dbPathCmd)
+	dbCmd.AddCommand(dbGenCmd, dbListCmd, dbInfoCmd, dbStatsCmd, dbRmCmd, dbPathCmd)
 
 	dbGenCmd.Flags().StringVarP(&flagDBFillerURL, "filler", "F", "", "Filler list URL")
 	dbGenCmd.Flags().BoolVarP(&flagDBForce, "force", "f", false, "Overwrite existing database")
@@ -88,7 +98,11 @@ func runDBGen(ctx context.Context, url string) {
 	}
 
 	generated, err := autotitle.DBGen(ctx, url, opts...)
+	endProgress()
+	flushSuccesses()
 	if err != nil {
+		exitOnRateLimit(err)
+		exitOnCancel(err)
 		logger.Error("Failed to generate database", "error", err)
 		os.Exit(1)
 	}
@@ -150,7 +164,63 @@ func runDBInfo(ctx context.Context, target string) {
 	logger.Print(fmt.Sprintf("%s %s", keyStyle.Render("Provider:"), ui.StylePattern.Render(media.Provider)))
 	if media.FillerSource != "" {
 		logger.Print(fmt.Sprintf("%s %s", keyStyle.Render("Filler Source:"), media.FillerSource))
+		if media.FillerURL != "" {
+			logger.Print(fmt.Sprintf("%s %s", keyStyle.Render("Filler URL:"), ui.StylePath.Render(media.FillerURL)))
+		}
+	}
+}
+
+func runDBStats(ctx context.Context) {
+	stats, err := autotitle.DBStats(ctx)
+	if err != nil {
+		logger.Error("Failed to read database stats", "error", err)
+		os.Exit(1)
+	}
+
+	if stats.Series == 0 {
+		logger.Warn("No databases found")
+		return
+	}
+
+	keyStyle := ui.StyleHeader.Width(15)
+
+	providers := make([]string, 0, len(stats.Providers))
+	for name := range stats.Providers {
+		providers = append(providers, name)
+	}
+	slices.Sort(providers)
+
+	logger.Print(fmt.Sprintf("%s %d", keyStyle.Render("Series:"), stats.Series))
+	for _, name := range providers {
+		logger.Print(fmt.Sprintf("  %s %s: %d", ui.StyleDim.Render("-"), ui.StylePattern.Render(name), stats.Providers[name]))
+	}
+	logger.Print(fmt.Sprintf("%s %d", keyStyle.Render("Episodes:"), stats.Episodes))
+	logger.Print(fmt.Sprintf("%s %s", keyStyle.Render("Disk Usage:"), formatBytes(stats.DiskUsage)))
+	logger.Print(fmt.Sprintf("%s %s", keyStyle.Render("Oldest:"), stats.Oldest.Local().Format(time.DateTime)))
+	logger.Print(fmt.Sprintf("%s %s", keyStyle.Render("Newest:"), stats.Newest.Local().Format(time.DateTime)))
+	logger.Print(fmt.Sprintf("%s %d", keyStyle.Render("Due Refresh:"), len(stats.DueForRefresh)))
+	for _, item := range stats.DueForRefresh {
+		logger.Print(fmt.Sprintf("  %s %s/%s: %s",
+			ui.StyleDim.Render("-"),
+			ui.StyleHeader.Render(item.Provider),
+			ui.StylePath.Render(item.ID),
+			item.Title,
+		))
+	}
+}
+
+// formatBytes renders a byte count with a binary unit (e.g. 1.5 MiB)
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
 	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
 }
 
 func runDBRm(ctx context.Context, args []string) {
@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/mydehq/autotitle"
+	"github.com/mydehq/autotitle/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagDupesRoot   string
+	flagDupesLink   bool
+	flagDupesRemove bool
+	flagDupesPrefer []string
+)
+
+var dupesCmd = &cobra.Command{
+	Use:   "dupes --root <path>",
+	Short: "Find duplicate episodes across the library",
+	Long: `dupes compares the media files under --root by content and lists the
+sets of identical files, e.g. the same episode kept in two series folders.
+Files are compared by size and a sampled hash first, so only likely
+duplicates are hashed in full.
+
+The file of each set from the best-ranked release group (--prefer, or
+dupes.prefer_groups in the global config) is kept, else the first by path.
+--link replaces the other copies with hardlinks to it; --remove deletes them.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		runDupes(cmd.Context())
+	},
+}
+
+func init() {
+	dupesCmd.Flags().StringVarP(&flagDupesRoot, "root", "r", "", "Library root (or library name) to scan")
+	dupesCmd.Flags().BoolVar(&flagDupesLink, "link", false, "Replace duplicates with hardlinks to the kept file")
+	dupesCmd.Flags().BoolVar(&flagDupesRemove, "remove", false, "Delete duplicates, keeping one copy")
+	dupesCmd.Flags().StringSliceVar(&flagDupesPrefer, "prefer", nil, "Release groups to keep, best first (e.g. SubsPlease,Erai-raws)")
+	dupesCmd.Flags().BoolVarP(&flagDryRun, "dry-run", "d", false, "Show what --link or --remove would do")
+	dupesCmd.MarkFlagsMutuallyExclusive("link", "remove")
+	_ = dupesCmd.MarkFlagRequired("root")
+	RootCmd.AddCommand(dupesCmd)
+}
+
+func runDupes(ctx context.Context) {
+	var findOpts []autotitle.Option
+	if len(flagDupesPrefer) > 0 {
+		findOpts = append(findOpts, autotitle.WithPreferGroups(flagDupesPrefer...))
+	}
+	root, _ := libraryArg(flagDupesRoot)
+	sets, err := autotitle.FindDuplicates(ctx, root, findOpts...)
+	endProgress()
+	flushSuccesses()
+	if err != nil {
+		exitOnCancel(err)
+		logger.Error("Failed to scan for duplicates", "error", err)
+		os.Exit(1)
+	}
+
+	if len(sets) == 0 {
+		logger.Success("No duplicates found")
+		return
+	}
+
+	var wasted int64
+	for _, set := range sets {
+		wasted += set.Wasted()
+		logger.Print(ui.StyleHeader.Render(fmt.Sprintf("%s (%s)", set.Hash[:12], formatBytes(set.Size))))
+		logger.Print(fmt.Sprintf("  %s %s", ui.StyleCommand.Render("keep"), ui.StylePath.Render(set.Files[0])))
+		for _, dup := range set.Files[1:] {
+			logger.Print(fmt.Sprintf("  %s %s", ui.StyleDim.Render("dupe"), ui.StyleDim.Render(dup)))
+		}
+	}
+	fmt.Println()
+	logger.Info(fmt.Sprintf("%d duplicate set(s), %s reclaimable", len(sets), formatBytes(wasted)))
+
+	action := autotitle.DedupeReport
+	switch {
+	case flagDupesLink:
+		action = autotitle.DedupeLink
+	case flagDupesRemove:
+		action = autotitle.DedupeRemove
+	}
+	if action == autotitle.DedupeReport {
+		return
+	}
+
+	var opts []autotitle.Option
+	if flagDryRun {
+		opts = append(opts, autotitle.WithDryRun())
+	}
+	freed, err := autotitle.Dedupe(ctx, sets, action, opts...)
+	flushSuccesses()
+	if err != nil {
+		exitOnCancel(err)
+		logger.Error("Failed to dedupe", "error", err)
+		os.Exit(1)
+	}
+	fmt.Println()
+	if flagDryRun {
+		logger.Info(fmt.Sprintf("%s: %s would be freed", ui.StyleHeader.Render("Dry run"), formatBytes(freed)))
+		return
+	}
+	logger.Success(fmt.Sprintf("Freed %s", formatBytes(freed)))
+}
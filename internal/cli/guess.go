@@ -43,7 +43,7 @@ func runGuessPattern(path string) {
 		formats = globalCfg.Formats
 	}
 
-	scanResult, err := config.Scan(absPath, formats)
+	scanResult, err := config.Scan(absPath, formats, config.NewIgnorer(globalCfg))
 	if err != nil {
 		logger.Error(fmt.Sprintf("Failed to scan directory: %v", err))
 		os.Exit(1)
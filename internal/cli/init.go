@@ -99,7 +99,7 @@ func runInit(cmd *cobra.Command, path string) {
 	}
 
 	// Scan directory for patterns and media
-	scanResult, err := config.Scan(absPath, defaults.Formats)
+	scanResult, err := config.Scan(absPath, defaults.Formats, nil)
 	if err != nil {
 		logger.Error("Failed to scan directory", "error", err)
 		os.Exit(1)
@@ -123,6 +123,9 @@ func runInit(cmd *cobra.Command, path string) {
 		HasPadding:   hasFlag("padding"),
 		DryRun:       flagDryRun,
 	}
+	if lib := autotitle.LibraryOf(absPath); lib != nil {
+		flags.Providers = lib.Providers
+	}
 
 	ctx := context.Background()
 	if cmd != nil {
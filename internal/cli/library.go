@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mydehq/autotitle"
+	"github.com/mydehq/autotitle/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var librariesCmd = &cobra.Command{
+	Use:   "libraries",
+	Short: "List the libraries of the global config",
+	Long: `libraries lists the named roots under libraries in the global config.
+Commands taking a folder (autotitle, sort, dupes --root, migrate-template)
+accept a library name in its place, unless a folder of that name exists
+in the current directory; a library's preset and providers apply to every
+folder under it.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		runLibraries()
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(librariesCmd)
+}
+
+func runLibraries() {
+	libs, err := autotitle.Libraries()
+	if err != nil {
+		logger.Error("Failed to load global config", "error", err)
+		os.Exit(1)
+	}
+	if len(libs) == 0 {
+		logger.Info(fmt.Sprintf("No libraries; add them under %s in the global config", ui.StylePattern.Render("libraries")))
+		return
+	}
+	for _, l := range libs {
+		logger.Print(fmt.Sprintf("%s %s", ui.StyleHeader.Render(l.Name), ui.StylePath.Render(l.Root)))
+		if l.Preset != "" {
+			logger.Print(fmt.Sprintf("  %s %s", ui.StyleDim.Render("preset:   "), l.Preset))
+		}
+		if len(l.Providers) > 0 {
+			logger.Print(fmt.Sprintf("  %s %s", ui.StyleDim.Render("providers:"), strings.Join(l.Providers, ", ")))
+		}
+	}
+}
+
+// libraryArg resolves a folder argument: the name of a library stands for
+// its root. An existing folder of that name, or anything with a path
+// separator ("./Anime"), is taken as a path.
+func libraryArg(arg string) (string, *autotitle.Library) {
+	if arg == "." || arg == ".." || strings.ContainsAny(arg, `/\`) {
+		return arg, nil
+	}
+	if info, err := os.Stat(arg); err == nil && info.IsDir() {
+		return arg, nil
+	}
+	lib, err := autotitle.FindLibrary(arg)
+	if err != nil {
+		logger.Warn("Failed to load global config", "error", err)
+		return arg, nil
+	}
+	if lib == nil {
+		return arg, nil
+	}
+	logger.Debug(fmt.Sprintf("Library %s: %s", lib.Name, lib.Root))
+	return lib.Root, lib
+}
+
+// runLibrary renames every folder with a map file under a library's root
+func runLibrary(ctx context.Context, cmd *cobra.Command, lib *autotitle.Library) {
+	var excluded []autotitle.RenameOperation
+	rep := newReport(lib.Root)
+	opts := append(renameOptions(cmd), collectEvents(rep, &excluded))
+
+	ops, err := autotitle.RenameAll(ctx, lib.Root, opts...)
+	endProgress()
+	flushSuccesses()
+	writeReport(rep, append(ops, excluded...), err)
+	if errors.Is(err, context.Canceled) && ops != nil {
+		// The folders after the interrupted one are left for a rerun
+		exitInterruptedBatch(append(ops, excluded...), "autotitle resume "+interruptedDir(ops)+" && autotitle "+lib.Name, "")
+	}
+	if err != nil {
+		exitOnRateLimit(err)
+		exitOnCancel(err)
+		logger.Error("Operation failed", "error", err)
+		os.Exit(1)
+	}
+
+	if !flagQuiet {
+		fmt.Println()
+		printSummary(append(ops, excluded...), flagVerbose)
+		if flagDryRun {
+			printDryRunImpact(ops, flagVerbose)
+		}
+	}
+}
+
+// interruptedDir returns the folder whose batch was cut short: the one
+// with renames still pending
+func interruptedDir(ops []autotitle.RenameOperation) string {
+	for _, op := range ops {
+		if op.Status == autotitle.StatusPending {
+			return filepath.Dir(op.SourcePath)
+		}
+	}
+	return "."
+}
@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLibraryArg(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	configDir := filepath.Join(home, ".config", "autotitle")
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	globalCfg := "libraries:\n  - name: Anime\n    path: ~/Anime\n  - name: Movies\n    path: ~/Movies\n"
+	if err := os.WriteFile(filepath.Join(configDir, "config.yml"), []byte(globalCfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A folder called Anime in the working directory wins over the library
+	cwd := t.TempDir()
+	if err := os.Mkdir(filepath.Join(cwd, "Anime"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(cwd)
+
+	if path, lib := libraryArg("Anime"); path != "Anime" || lib != nil {
+		t.Errorf("libraryArg(Anime) = %q, %v; want the local folder", path, lib)
+	}
+	if path, lib := libraryArg("movies"); lib == nil || path != filepath.Join(home, "Movies") {
+		t.Errorf("libraryArg(movies) = %q, %v; want the Movies library", path, lib)
+	}
+	if path, lib := libraryArg("./Movies"); path != "./Movies" || lib != nil {
+		t.Errorf("libraryArg(./Movies) = %q, %v; want the path", path, lib)
+	}
+}
@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mydehq/autotitle"
+	"github.com/mydehq/autotitle/internal/types"
+	"github.com/spf13/cobra"
+)
+
+var flagMigrateTo string
+
+var migrateTemplateCmd = &cobra.Command{
+	Use:   "migrate-template <root|library> --to <preset|fields>",
+	Short: "Re-rename a processed library to a new output template",
+	Long: `migrate-template renames the files autotitle already renamed under root
+to a new output template and updates each map file to match.
+
+--to takes an output preset (auto, episode, movie) or a comma-separated
+field list (e.g. SERIES,-,EP_NUM,-,EP_NAME).
+
+Files are rendered again from the original names recorded in their backup,
+so directories renamed with --no-backup are skipped. Undo still restores
+the names from before the first rename.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		path, _ := libraryArg(args[0])
+		root, err := filepath.Abs(path)
+		if err != nil {
+			logger.Error("Invalid path", "error", err)
+			os.Exit(1)
+		}
+		runMigrateTemplate(cmd, root)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(migrateTemplateCmd)
+	migrateTemplateCmd.Flags().StringVar(&flagMigrateTo, "to", "", "Output preset or comma-separated fields")
+	migrateTemplateCmd.Flags().BoolVarP(&flagDryRun, "dry-run", "d", false, "Preview changes without applying")
+	migrateTemplateCmd.Flags().BoolVarP(&flagNoTag, "no-tag", "T", false, "Disable MKV metadata tagging (mkvpropedit)")
+	_ = migrateTemplateCmd.MarkFlagRequired("to")
+}
+
+func runMigrateTemplate(cmd *cobra.Command, root string) {
+	opts := ioLimitOptions(cmd)
+
+	switch to := strings.TrimSpace(flagMigrateTo); to {
+	case types.PresetAuto, types.PresetEpisode, types.PresetMovie:
+		opts = append(opts, autotitle.WithPreset(to))
+	default:
+		var fields []string
+		for _, f := range strings.Split(to, ",") {
+			if f = strings.TrimSpace(f); f != "" {
+				fields = append(fields, f)
+			}
+		}
+		opts = append(opts, autotitle.WithFields(fields...))
+	}
+
+	if flagDryRun {
+		opts = append(opts, autotitle.WithDryRun())
+	}
+	if flagNoTag {
+		opts = append(opts, autotitle.WithNoTagging())
+	}
+
+	// Collect files the renamer left out of the plan so the summary can group them
+	var excluded []autotitle.RenameOperation
+	opts = append(opts, autotitle.WithEvents(func(e autotitle.Event) {
+		if op, ok := e.Data.(autotitle.RenameOperation); ok {
+			excluded = append(excluded, op)
+		}
+		handleEvent(e)
+	}))
+
+	ops, err := autotitle.MigrateTemplate(cmd.Context(), root, opts...)
+	endProgress()
+	flushSuccesses()
+	if errors.Is(err, context.Canceled) {
+		exitInterruptedBatch(append(ops, excluded...), "autotitle migrate-template "+root+" --to "+flagMigrateTo, "")
+	}
+	if err != nil {
+		exitOnCancel(err)
+		logger.Error("Migration failed", "error", err)
+		os.Exit(1)
+	}
+
+	if !flagQuiet {
+		fmt.Println()
+		printSummary(append(ops, excluded...), flagVerbose)
+	}
+}
@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/mydehq/autotitle"
+	"github.com/spf13/cobra"
+)
+
+var parseCmd = &cobra.Command{
+	Use:   "parse [filename...]",
+	Short: "Print the parsed elements of filenames as JSON",
+	Long: `parse breaks release filenames down into their elements (title, episode,
+release group, resolution, ...) and prints one JSON object per filename,
+using anitopy's key names. No provider or map file is needed.
+
+Filenames are read one per line from stdin when none are given:
+
+  ls ~/Downloads | autotitle parse | jq -r .anime_title`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runParse(args)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(parseCmd)
+}
+
+func runParse(names []string) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	emit := func(name string) {
+		if err := enc.Encode(autotitle.ParseFilename(name)); err != nil {
+			logger.Error("Failed to write output", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	if len(names) > 0 {
+		for _, name := range names {
+			emit(name)
+		}
+		return
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		if name := strings.TrimSpace(scanner.Text()); name != "" {
+			emit(name)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		logger.Error("Failed to read stdin", "error", err)
+		os.Exit(1)
+	}
+}
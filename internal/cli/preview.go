@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mydehq/autotitle"
+	"github.com/mydehq/autotitle/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagPreviewURL       string
+	flagPreviewFillerURL string
+	flagPreviewPattern   string
+	flagPreviewFields    []string
+	flagPreviewSeparator string
+	flagPreviewOffset    int
+	flagPreviewPadding   int
+	flagPreviewBase      int
+)
+
+var previewCmd = &cobra.Command{
+	Use:   "preview <filename>",
+	Short: "Show the name a single file would be given",
+	Long: `preview resolves one filename against a provider URL and prints the name
+it would be renamed to. No map file or real file on disk is needed, which makes
+it handy for testing templates and for bug reports. The preset of the library
+the current directory is in applies, as it would to a rename there.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runPreview(cmd, args[0])
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(previewCmd)
+	previewCmd.Flags().StringVarP(&flagPreviewURL, "url", "u", "", "Provider URL (MAL, TMDB, etc)")
+	previewCmd.Flags().StringVarP(&flagPreviewFillerURL, "filler", "F", "", "Filler list URL")
+	previewCmd.Flags().StringVarP(&flagPreviewPattern, "pattern", "P", "", "Input pattern (guessed from filename if empty)")
+	previewCmd.Flags().StringSliceVar(&flagPreviewFields, "fields", nil, "Output fields (e.g. SERIES,-,EP_NUM,-,EP_NAME)")
+	previewCmd.Flags().StringVarP(&flagPreviewSeparator, "separator", "S", "", "Output separator")
+	previewCmd.Flags().IntVarP(&flagPreviewOffset, "offset", "o", 0, "Shift episode numbers (DB = Local + Offset)")
+	previewCmd.Flags().IntVarP(&flagPreviewPadding, "padding", "p", 0, "Episode number padding (e.g. 2 for 01)")
+	previewCmd.Flags().IntVar(&flagPreviewBase, "numbering-base", 1, "First local episode number (0 or 1)")
+	_ = previewCmd.MarkFlagRequired("url")
+}
+
+func runPreview(cmd *cobra.Command, filename string) {
+	opts := []autotitle.Option{
+		autotitle.WithURL(flagPreviewURL),
+		autotitle.WithFiller(flagPreviewFillerURL),
+		autotitle.WithSeparator(flagPreviewSeparator),
+		autotitle.WithPadding(flagPreviewPadding),
+	}
+	if flagPreviewPattern != "" {
+		opts = append(opts, autotitle.WithPattern(flagPreviewPattern))
+	}
+	if len(flagPreviewFields) > 0 {
+		opts = append(opts, autotitle.WithFields(flagPreviewFields...))
+	}
+	if cmd.Flags().Changed("offset") {
+		opts = append(opts, autotitle.WithOffset(flagPreviewOffset))
+	}
+	if cmd.Flags().Changed("numbering-base") {
+		opts = append(opts, autotitle.WithNumberingBase(flagPreviewBase))
+	}
+
+	name, err := autotitle.Preview(cmd.Context(), filename, opts...)
+	if err != nil {
+		logger.Error("Preview failed", "error", err)
+		os.Exit(1)
+	}
+
+	filename, name = ui.FitPair(filename, name, ui.LineWidth(ui.ArrowWidth))
+	fmt.Printf("%s %s %s\n", ui.StyleDim.Render(filename), ui.StyleDim.Render("→"), ui.StyleCommand.Render(name))
+}
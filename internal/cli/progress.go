@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+	"github.com/mydehq/autotitle/internal/ui"
+)
+
+const progressBarWidth = 30
+
+// progressActive is set while a progress line is drawn and not yet terminated
+var progressActive bool
+
+// isTerminal reports whether stdout is an interactive terminal
+func isTerminal() bool {
+	return isatty.IsTerminal(os.Stdout.Fd()) || isatty.IsCygwinTerminal(os.Stdout.Fd())
+}
+
+// renderProgress redraws a single-line progress bar in place. The line is
+// terminated once done reaches total. Nothing is drawn outside a terminal.
+func renderProgress(label string, done, total int64, detail string) {
+	if flagQuiet || !isTerminal() || total <= 0 {
+		return
+	}
+
+	frac := float64(done) / float64(total)
+	frac = min(max(frac, 0), 1)
+	filled := int(frac * progressBarWidth)
+	bar := strings.Repeat("█", filled) + strings.Repeat("░", progressBarWidth-filled)
+
+	fmt.Printf("\r\033[K%s %s %3.0f%% %s",
+		ui.StyleHeader.Render(label),
+		ui.StylePattern.Render(bar),
+		frac*100,
+		ui.StyleDim.Render(detail),
+	)
+	progressActive = true
+
+	if done >= total {
+		endProgress()
+	}
+}
+
+// endProgress terminates an in-place progress line so regular output can follow
+func endProgress() {
+	if progressActive {
+		fmt.Println()
+		progressActive = false
+	}
+}
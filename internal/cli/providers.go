@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mydehq/autotitle"
+	"github.com/mydehq/autotitle/internal/config"
+	"github.com/mydehq/autotitle/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var providersCmd = &cobra.Command{
+	Use:   "providers",
+	Short: "List supported metadata providers and their URLs",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		runProviders()
+	},
+}
+
+var fillerSourcesCmd = &cobra.Command{
+	Use:     "filler-sources",
+	Aliases: []string{"fillers-sources"},
+	Short:   "List supported filler sources and their URLs",
+	Args:    cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		runFillerSources()
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(providersCmd, fillerSourcesCmd)
+}
+
+func runProviders() {
+	globalCfg, _ := config.LoadGlobal()
+
+	keyStyle := ui.StyleHeader.Width(10)
+	for i, p := range autotitle.ListProviderDetails() {
+		if i > 0 {
+			logger.Print("")
+		}
+
+		status := ui.StyleCommand.Render("ready")
+		if p.RequiresKey {
+			if globalCfg != nil && globalCfg.API.Keys[p.Name] != "" {
+				status = ui.StyleCommand.Render("API key set")
+			} else {
+				status = ui.StyleFlag.Render(fmt.Sprintf("API key missing (set api.keys.%s)", p.Name))
+			}
+		}
+
+		logger.Print(fmt.Sprintf("%s %s", keyStyle.Render("Provider:"), ui.StylePattern.Render(p.Name)))
+		logger.Print(fmt.Sprintf("%s %s", keyStyle.Render("Website:"), ui.StylePath.Render(p.Website)))
+		logger.Print(fmt.Sprintf("%s %s", keyStyle.Render("Type:"), p.Type))
+		logger.Print(fmt.Sprintf("%s %s", keyStyle.Render("Status:"), status))
+		logger.Print(fmt.Sprintf("%s %s", keyStyle.Render("URLs:"), formatMatchURLs(p.MatchURLs)))
+	}
+}
+
+func runFillerSources() {
+	keyStyle := ui.StyleHeader.Width(10)
+	for i, s := range autotitle.ListFillerSourceDetails() {
+		if i > 0 {
+			logger.Print("")
+		}
+		logger.Print(fmt.Sprintf("%s %s", keyStyle.Render("Source:"), ui.StylePattern.Render(s.Name)))
+		logger.Print(fmt.Sprintf("%s %s", keyStyle.Render("Website:"), ui.StylePath.Render(s.Website)))
+		logger.Print(fmt.Sprintf("%s %s", keyStyle.Render("URLs:"), formatMatchURLs(s.MatchURLs)))
+	}
+}
+
+// formatMatchURLs renders URL patterns as a dimmed, comma-separated list
+func formatMatchURLs(urls []string) string {
+	styled := make([]string, len(urls))
+	for i, u := range urls {
+		styled[i] = ui.StyleDim.Render(u + "…")
+	}
+	return strings.Join(styled, ", ")
+}
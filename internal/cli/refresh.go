@@ -0,0 +1,31 @@
+package cli
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var refreshCmd = &cobra.Command{
+	Use:   "refresh [path]",
+	Short: "Re-fetch the database and rename (e.g. after new episodes aired)",
+	Long: `refresh forcibly re-fetches the database for the target in [path]
+and then renames, equivalent to "autotitle --force <path>".`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		path := "."
+		if len(args) > 0 {
+			path = args[0]
+		}
+		flagForce = true
+		runRename(cmd.Context(), cmd, path)
+	},
+}
+
+func init() {
+	refreshCmd.Flags().BoolVarP(&flagDryRun, "dry-run", "d", false, "Preview changes without applying")
+	refreshCmd.Flags().BoolVarP(&flagNoBackup, "no-backup", "n", false, "Skip backup creation")
+	refreshCmd.Flags().BoolVarP(&flagVerbose, "verbose", "V", false, "Verbose output")
+	refreshCmd.Flags().IntVarP(&flagOffset, "offset", "o", 0, "Shift episode numbers (e.g. 12 to map Ep 1 to 13) (DB = Local + Offset)")
+	refreshCmd.Flags().StringVarP(&flagFillerURL, "filler", "F", "", "Override filler source URL")
+	refreshCmd.Flags().BoolVarP(&flagNoTag, "no-tag", "T", false, "Disable MKV metadata tagging (mkvpropedit)")
+	RootCmd.AddCommand(refreshCmd)
+}
@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mydehq/autotitle"
+	"github.com/mydehq/autotitle/internal/config"
+	"github.com/mydehq/autotitle/internal/report"
+	"github.com/mydehq/autotitle/internal/ui"
+)
+
+// flagReport is the file --report writes the run report to
+var flagReport string
+
+// newReport starts the --report of a rename of path, or returns nil
+func newReport(path string) *report.Report {
+	if flagReport == "" {
+		return nil
+	}
+	rep := report.New(path, flagDryRun, time.Now())
+	rep.AddConfig("global", config.FindGlobal())
+	rep.AddConfig("map", config.MapFilePath(path))
+	return rep
+}
+
+// writeReport finishes the report with the outcome of the run and writes
+// it. A report that cannot be written only costs a warning.
+func writeReport(rep *report.Report, ops []autotitle.RenameOperation, err error) {
+	if rep == nil {
+		return
+	}
+	rep.Finish(ops, err, time.Now())
+	if err := rep.Write(flagReport); err != nil {
+		logger.Warn("Could not write report", "error", err)
+		return
+	}
+	logger.Info(fmt.Sprintf("Report written to %s", ui.StylePath.Render(flagReport)))
+}
@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/mydehq/autotitle"
+	"github.com/mydehq/autotitle/internal/types"
+	"github.com/mydehq/autotitle/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var resumeCmd = &cobra.Command{
+	Use:   "resume [path]",
+	Short: "Finish a rename batch interrupted with Ctrl+C",
+	Long: `resume renames the files an interrupted run of "autotitle <path>" did not
+get to, exactly as that run planned them. The config and database are not
+consulted again, and files that were already renamed are left alone.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		path := "."
+		if len(args) > 0 {
+			path = args[0]
+		}
+		runResume(cmd.Context(), cmd, path)
+	},
+}
+
+func init() {
+	resumeCmd.Flags().BoolVarP(&flagDryRun, "dry-run", "d", false, "List the renames left without applying them")
+	resumeCmd.Flags().BoolVarP(&flagNoBackup, "no-backup", "n", false, "Skip backup creation")
+	resumeCmd.Flags().BoolVarP(&flagVerbose, "verbose", "V", false, "Verbose output")
+	resumeCmd.Flags().BoolVarP(&flagNoTag, "no-tag", "T", false, "Disable MKV metadata tagging (mkvpropedit)")
+	RootCmd.AddCommand(resumeCmd)
+}
+
+func runResume(ctx context.Context, cmd *cobra.Command, path string) {
+	opts := ioLimitOptions(cmd)
+	if flagDryRun {
+		opts = append(opts, autotitle.WithDryRun())
+	}
+	if flagNoBackup {
+		opts = append(opts, autotitle.WithNoBackup())
+	}
+	if flagNoTag {
+		opts = append(opts, autotitle.WithNoTagging())
+	}
+
+	ops, err := autotitle.Resume(ctx, path, opts...)
+	endProgress()
+	flushSuccesses()
+	if errors.Is(err, context.Canceled) && ops != nil {
+		exitInterruptedBatch(ops, "autotitle resume "+path, "autotitle undo "+path)
+	}
+	if err != nil {
+		var notFound types.ErrBatchNotFound
+		if errors.As(err, &notFound) {
+			logger.Warn(fmt.Sprintf("Nothing to resume in %s", ui.StylePath.Render(path)))
+			return
+		}
+		logger.Error("Resume failed", "error", err)
+		os.Exit(1)
+	}
+
+	if !flagQuiet {
+		fmt.Println()
+		printSummary(ops, flagVerbose)
+	}
+}
@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"github.com/mydehq/autotitle"
+	"github.com/mydehq/autotitle/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var flagReviewList bool
+
+var reviewCmd = &cobra.Command{
+	Use:   "review",
+	Short: "Resolve sort groups parked for review",
+	Long: `review shows the groups "autotitle sort" did not move because their best
+match was below the confidence threshold (or there was no match), and the
+new folders "autotitle watch" could not set up for the same reason.
+
+For each group you can accept the proposed match, search again, or skip it.
+Accepted groups are sorted and renamed, and the match is remembered so the
+same series is sorted without asking next time.
+
+Outside a terminal, or with --list, the queue is only printed.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if flagReviewList || !isTerminal() {
+			listReview(cmd.Context())
+			return
+		}
+		runReview(cmd.Context(), cmd)
+	},
+}
+
+func init() {
+	reviewCmd.Flags().BoolVarP(&flagReviewList, "list", "l", false, "Print the queue without prompting")
+	reviewCmd.Flags().BoolVarP(&flagNoBackup, "no-backup", "n", false, "Skip backup creation when renaming")
+	reviewCmd.Flags().BoolVarP(&flagNoTag, "no-tag", "T", false, "Disable MKV metadata tagging (mkvpropedit)")
+	reviewCmd.Flags().BoolVar(&flagSortOnly, "no-rename", false, "Move files and write map files without renaming")
+	RootCmd.AddCommand(reviewCmd)
+}
+
+func runReview(ctx context.Context, cmd *cobra.Command) {
+	opts := append(ioLimitOptions(cmd), autotitle.WithEvents(handleEvent))
+	if flagNoBackup {
+		opts = append(opts, autotitle.WithNoBackup())
+	}
+	if flagNoTag {
+		opts = append(opts, autotitle.WithNoTagging())
+	}
+	if flagSortOnly {
+		opts = append(opts, autotitle.WithSortOnly())
+	}
+
+	err := ui.RunReview(ctx, opts...)
+	endProgress()
+	flushSuccesses()
+	if errors.Is(err, huh.ErrUserAborted) {
+		fmt.Println()
+		logger.Warn(ui.StyleDim.Render("Review cancelled"))
+		return
+	}
+	if err != nil {
+		exitOnCancel(err)
+		logger.Error("Review failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+func listReview(ctx context.Context) {
+	items, err := autotitle.ReviewQueue(ctx)
+	if err != nil {
+		logger.Error("Failed to read review queue", "error", err)
+		os.Exit(1)
+	}
+
+	if len(items) == 0 {
+		logger.Info("Nothing to review")
+		return
+	}
+
+	logger.Info(fmt.Sprintf("%s count: %s", ui.StyleHeader.Render("Needs review"), ui.StylePattern.Render(fmt.Sprint(len(items)))))
+	for _, item := range items {
+		g := item.Group
+		proposed := ui.StyleDim.Render("no match")
+		if g.Match != nil {
+			proposed = fmt.Sprintf("%s %s", g.Match.Title, ui.StyleDim.Render(fmt.Sprintf("(%.0f%%, %s)", g.Confidence*100, g.Match.URL)))
+		}
+		// The folder gets a third of a narrow line, the rest is left to wrap
+		dir := ui.TruncateMiddle(item.Dir, ui.LineWidth(0)/3)
+		logger.Print(fmt.Sprintf("  %s %s %s → %s",
+			ui.StyleDim.Render(item.ID),
+			g.Name,
+			ui.StyleDim.Render(fmt.Sprintf("[%d files in %s, %s]", len(g.Files), ui.StylePath.Render(dir), item.Added.Local().Format(time.DateTime))),
+			proposed,
+		))
+	}
+}
@@ -2,12 +2,17 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"time"
 
 	"github.com/charmbracelet/huh"
 	"github.com/charmbracelet/log"
 	"github.com/mydehq/autotitle"
+	"github.com/mydehq/autotitle/internal/config"
+	"github.com/mydehq/autotitle/internal/report"
 	"github.com/mydehq/autotitle/internal/types"
 	"github.com/mydehq/autotitle/internal/ui"
 	"github.com/mydehq/autotitle/internal/version"
@@ -15,20 +20,35 @@ import (
 )
 
 var (
-	flagDryRun    bool
-	flagNoBackup  bool
-	flagVerbose   bool
-	flagQuiet     bool
-	flagNoTag     bool
-	flagOffset    int
-	flagFillerURL string
-	flagForce     bool
+	flagDryRun      bool
+	flagNoBackup    bool
+	flagVerbose     bool
+	flagQuiet       bool
+	flagNoTag       bool
+	flagAcceptTitle bool
+	flagOffset      int
+	flagFillerURL   string
+	flagForce       bool
+	flagReadOnly    bool
+	flagIOLimit     float64
+	flagNice        int
+	flagIOClass     string
 
 	logger *ui.Logger
+
+	// eventSinks receives every event in addition to the terminal output
+	eventSinks *autotitle.EventSinks
 )
 
+// exitRateLimited is returned when an API stays rate limited past the retry
+// budget (EX_TEMPFAIL); rerunning later resumes the interrupted fetch.
+const exitRateLimited = 75
+
+// exitInterrupted is returned when the user cancels with Ctrl+C (128 + SIGINT)
+const exitInterrupted = 130
+
 var RootCmd = &cobra.Command{
-	Use:           "autotitle <path>",
+	Use:           "autotitle <path|library>",
 	Short:         "Rename media files with proper titles",
 	Version:       version.String(),
 	SilenceErrors: true,
@@ -36,6 +56,12 @@ var RootCmd = &cobra.Command{
 	Args:          cobra.MaximumNArgs(1),
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
 		setupLogger()
+		if flagReadOnly {
+			setupReadOnly()
+		}
+		setupGlobal()
+		setupEventSinks()
+		setupPriority(cmd)
 	},
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) == 0 {
@@ -43,16 +69,34 @@ var RootCmd = &cobra.Command{
 			fmt.Println(ui.StyleHeader.Render("Try running:"))
 			fmt.Printf("    %s %s\n", ui.StyleCommand.Render("autotitle ."), ui.StyleDim.Render("  Process current directory"))
 			fmt.Printf("    %s %s\n", ui.StyleCommand.Render("autotitle -h"), ui.StyleDim.Render(" Show all commands and flags"))
+			if config.FindGlobal() == "" {
+				fmt.Printf("    %s %s\n", ui.StyleCommand.Render("autotitle setup"), ui.StyleDim.Render("Create your global config (first run)"))
+			}
 			fmt.Println()
 			os.Exit(1)
 		}
+		if _, lib := libraryArg(args[0]); lib != nil {
+			runLibrary(cmd.Context(), cmd, lib)
+			return
+		}
 		runRename(cmd.Context(), cmd, args[0])
 	},
 }
 
 func Execute() {
+	// Cancel long-running work (fetches, backup copies) on Ctrl+C; a rename
+	// batch stops after the current file. A second Ctrl+C quits at once.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	defer closeEventSinks()
+
 	fmt.Println()
-	if err := RootCmd.Execute(); err != nil {
+	if err := RootCmd.ExecuteContext(ctx); err != nil {
 		if logger != nil {
 			logger.Error(err)
 		} else {
@@ -71,7 +115,13 @@ func init() {
 	RootCmd.Flags().StringVarP(&flagFillerURL, "filler", "F", "", "Override filler source URL")
 	RootCmd.Flags().BoolVarP(&flagForce, "force", "f", false, "Force database refresh")
 	RootCmd.Flags().BoolVarP(&flagNoTag, "no-tag", "T", false, "Disable MKV metadata tagging (mkvpropedit)")
+	RootCmd.Flags().BoolVar(&flagAcceptTitle, "accept-title", false, "Rename even if the filenames' series title is unlike the database title")
+	RootCmd.Flags().StringVar(&flagReport, "report", "", "Write a JSON report of the run (plan, results, events, versions) to this file")
 	RootCmd.PersistentFlags().BoolVarP(&flagQuiet, "quiet", "q", false, "Suppress output except errors")
+	RootCmd.PersistentFlags().BoolVar(&flagReadOnly, "assume-readonly", false, "Never write anything (files, backups, caches); implies --dry-run")
+	RootCmd.PersistentFlags().Float64Var(&flagIOLimit, "io-limit", 0, "Cap backup copies at this many MB/s (overrides backup.max_mbps, 0 = no cap)")
+	RootCmd.PersistentFlags().IntVar(&flagNice, "nice", 0, "Run at this CPU niceness, 0-19 (Linux; overrides priority.nice)")
+	RootCmd.PersistentFlags().StringVar(&flagIOClass, "ionice", "", "Run in this IO class: idle or best-effort (Linux; overrides priority.io_class)")
 
 	// Default logger setup (before flags parse)
 	l := log.New(os.Stdout)
@@ -79,19 +129,7 @@ func init() {
 	ui.ConfigureLoggerStyles()
 	logger = &ui.Logger{Logger: l}
 
-	autotitle.SetDefaultEventHandler(func(e autotitle.Event) {
-		msg := ui.ColorizeEvent(e.Message)
-		switch e.Type {
-		case autotitle.EventSuccess:
-			logger.Success(msg)
-		case autotitle.EventWarning:
-			logger.Warn(msg)
-		case autotitle.EventError:
-			logger.Error(msg)
-		default:
-			logger.Debug(msg)
-		}
-	})
+	autotitle.SetDefaultEventHandler(handleEvent)
 
 	colorizeHelp(RootCmd)
 
@@ -99,6 +137,42 @@ func init() {
 	RootCmd.Flags().BoolP("version", "v", false, "Print version information")
 }
 
+// handleEvent logs library events at the level matching their type.
+func handleEvent(e autotitle.Event) {
+	sendToSinks(e)
+	if e.Type == autotitle.EventProgress {
+		switch p := e.Data.(type) {
+		case types.CopyProgress:
+			renderProgress("Copying", p.Copied, p.Total, fmt.Sprintf("%s / %s %s", formatBytes(p.Copied), formatBytes(p.Total), p.File))
+			return
+		case types.FetchProgress:
+			renderProgress("Fetching", int64(p.Page), int64(p.LastPage), fmt.Sprintf("page %d/%d, %d episodes, ETA %s",
+				p.Page, p.LastPage, p.Episodes, p.ETA.Round(time.Second)))
+			return
+		}
+	}
+	endProgress()
+
+	switch e.Type {
+	case autotitle.EventSuccess:
+		successes.add(e.Message)
+		return
+	case autotitle.EventWarning, autotitle.EventError:
+		// Keep problems in order with the successes around them
+		flushSuccesses()
+	}
+
+	msg := ui.ColorizeEvent(e.Message)
+	switch e.Type {
+	case autotitle.EventWarning:
+		logger.Warn(msg)
+	case autotitle.EventError:
+		logger.Error(msg)
+	default:
+		logger.Debug(msg)
+	}
+}
+
 func setupLogger() {
 	if flagQuiet {
 		logger.SetLevel(log.ErrorLevel)
@@ -109,33 +183,90 @@ func setupLogger() {
 	}
 }
 
-func runRename(ctx context.Context, cmd *cobra.Command, path string) {
-	var opts []autotitle.Option
+// setupReadOnly turns on read-only mode for pointing autotitle at a
+// read-only snapshot: every command only plans, and writes are refused
+func setupReadOnly() {
+	autotitle.SetReadOnly(true)
+	flagDryRun = true
+	flagUndoDryRun = true
+	flagCleanDryRun = true
+	logger.Debug("Read-only mode: nothing will be written")
+}
 
-	if flagDryRun {
-		opts = append(opts, autotitle.WithDryRun())
+// setupGlobal installs the process-wide settings of the global config. An
+// invalid config is left to the commands, which report it where they load it.
+func setupGlobal() {
+	globalCfg, err := config.LoadGlobal()
+	if err != nil {
+		return
+	}
+	if err := config.ApplyGlobal(globalCfg); err != nil {
+		logger.Warn("Global config not applied", "error", err)
 	}
+}
 
-	if flagNoBackup {
-		opts = append(opts, autotitle.WithNoBackup())
+// setupPriority lowers the process priority as set under priority in the
+// global config, overridden by --nice and --ionice
+func setupPriority(cmd *cobra.Command) {
+	var p autotitle.PriorityConfig
+	if globalCfg, err := config.LoadGlobal(); err == nil {
+		p = globalCfg.Priority
+	}
+	if cmd.Flags().Changed("nice") {
+		p.Nice = flagNice
+	}
+	if cmd.Flags().Changed("ionice") {
+		p.IOClass = flagIOClass
 	}
+	if err := autotitle.SetPriority(p); err != nil {
+		logger.Warn("Could not lower priority", "error", err)
+	}
+}
 
-	if cmd.Flags().Changed("offset") {
-		opts = append(opts, autotitle.WithOffset(flagOffset))
+// ioLimitOptions passes --io-limit on to the library, if given
+func ioLimitOptions(cmd *cobra.Command) []autotitle.Option {
+	if cmd.Flags().Changed("io-limit") {
+		return []autotitle.Option{autotitle.WithIOLimit(flagIOLimit)}
 	}
+	return nil
+}
 
-	if flagFillerURL != "" {
-		opts = append(opts, autotitle.WithFiller(flagFillerURL))
+// setupEventSinks opens the sinks configured under events.sinks in the
+// global config. A sink that fails to open is reported and left out.
+func setupEventSinks() {
+	var err error
+	eventSinks, err = autotitle.OpenEventSinks()
+	if err != nil {
+		logger.Warn("Event sinks unavailable", "error", err)
 	}
-	if flagForce {
-		opts = append(opts, autotitle.WithForce())
+}
+
+// sendToSinks passes an event on to the configured sinks
+func sendToSinks(e autotitle.Event) {
+	if eventSinks != nil {
+		eventSinks.Send(e)
 	}
+}
 
-	if !flagQuiet {
-		// No need to pass events manually anymore, global default is used
+func closeEventSinks() {
+	if eventSinks != nil {
+		_ = eventSinks.Close()
 	}
+}
+
+func runRename(ctx context.Context, cmd *cobra.Command, path string) {
+	// Collect files the renamer left out of the plan so the summary can group them
+	var excluded []autotitle.RenameOperation
+	rep := newReport(path)
+	opts := append(renameOptions(cmd), collectEvents(rep, &excluded))
 
 	ops, err := autotitle.Rename(ctx, path, opts...)
+	endProgress()
+	flushSuccesses()
+	writeReport(rep, append(ops, excluded...), err)
+	if errors.Is(err, context.Canceled) && ops != nil {
+		exitInterruptedBatch(append(ops, excluded...), "autotitle resume "+path, "autotitle undo "+path)
+	}
 	if err != nil {
 		if _, ok := err.(types.ErrConfigNotFound); ok {
 			logger.Error(fmt.Sprintf("No %s found in %s", ui.StylePattern.Render("_autotitle.yml"), ui.StylePath.Render(path)))
@@ -156,30 +287,142 @@ func runRename(ctx context.Context, cmd *cobra.Command, path string) {
 			}
 			os.Exit(0)
 		}
+		var mismatch types.ErrTitleMismatch
+		if errors.As(err, &mismatch) {
+			if confirmTitle(mismatch) {
+				flagAcceptTitle = true
+				runRename(ctx, cmd, path)
+				return
+			}
+			os.Exit(1)
+		}
+		exitOnRateLimit(err)
+		exitOnCancel(err)
 		logger.Error("Operation failed", "error", err)
 		os.Exit(1)
 	}
 
-	// Summary
-	var success, skipped, failed int
+	if !flagQuiet {
+		fmt.Println()
+		printSummary(append(ops, excluded...), flagVerbose)
+		if flagDryRun {
+			printDryRunImpact(ops, flagVerbose)
+		}
+	}
+}
+
+// renameOptions turns the flags of the root command into rename options
+func renameOptions(cmd *cobra.Command) []autotitle.Option {
+	opts := ioLimitOptions(cmd)
 
+	if flagDryRun {
+		opts = append(opts, autotitle.WithDryRun())
+	}
+
+	if flagNoBackup {
+		opts = append(opts, autotitle.WithNoBackup())
+	}
+
+	if cmd.Flags().Changed("offset") {
+		opts = append(opts, autotitle.WithOffset(flagOffset))
+	}
+
+	if flagFillerURL != "" {
+		opts = append(opts, autotitle.WithFiller(flagFillerURL))
+	}
+	if flagForce {
+		opts = append(opts, autotitle.WithForce())
+	}
+	if flagNoTag {
+		opts = append(opts, autotitle.WithNoTagging())
+	}
+	if flagAcceptTitle {
+		opts = append(opts, autotitle.WithAcceptTitle())
+	}
+	return opts
+}
+
+// collectEvents logs events and records them in the report, if any,
+// collecting the files the renamer left out of the plan into excluded
+func collectEvents(rep *report.Report, excluded *[]autotitle.RenameOperation) autotitle.Option {
+	return autotitle.WithEvents(func(e autotitle.Event) {
+		if op, ok := e.Data.(autotitle.RenameOperation); ok {
+			*excluded = append(*excluded, op)
+		}
+		if rep != nil {
+			rep.Record(e, time.Now())
+		}
+		handleEvent(e)
+	})
+}
+
+// confirmTitle asks whether to rename although the filenames and the database
+// disagree on the series title. Outside a terminal it explains how to go
+// ahead and returns false.
+func confirmTitle(mismatch types.ErrTitleMismatch) bool {
+	logger.Warn(fmt.Sprintf("Files are named %s, but the database title is %s (%.0f%% similar)",
+		ui.StylePattern.Render(mismatch.Series), ui.StylePattern.Render(mismatch.Title), mismatch.Similarity*100))
+	if !isTerminal() {
+		logger.Error(fmt.Sprintf("Check the URL in the map file, or rerun with %s", ui.StyleFlag.Render("--accept-title")))
+		return false
+	}
+
+	fmt.Println()
+	accept := false
+	err := ui.RunForm(huh.NewForm(
+		huh.NewGroup(
+			huh.NewConfirm().
+				Title("Rename anyway?").
+				Description("The URL in the map file may point at another series.").
+				Value(&accept),
+		),
+	).WithTheme(ui.AutotitleTheme()).WithKeyMap(ui.AutotitleKeyMap()))
+	return err == nil && accept
+}
+
+// exitOnCancel exits with exitInterrupted if err stems from a Ctrl+C
+// cancellation, saying how to resume if it cut a database fetch short
+func exitOnCancel(err error) {
+	if !errors.Is(err, context.Canceled) {
+		return
+	}
+	logger.Warn("Cancelled")
+	var partial types.ErrPartialFetch
+	if errors.As(err, &partial) {
+		logger.Info("Fully fetched pages were saved; run the same command again to resume")
+	}
+	os.Exit(exitInterrupted)
+}
+
+// exitInterruptedBatch prints the partial summary of a rename batch cut short
+// by Ctrl+C, how to finish (resume) or roll it back (undo, if not empty), and
+// exits with exitInterrupted
+func exitInterruptedBatch(ops []autotitle.RenameOperation, resume, undo string) {
+	pending := 0
 	for _, op := range ops {
-		switch op.Status {
-		case autotitle.StatusSuccess:
-			success++
-		case autotitle.StatusSkipped:
-			skipped++
-		case autotitle.StatusFailed:
-			failed++
+		if op.Status == autotitle.StatusPending {
+			pending++
 		}
 	}
 
-	if !flagQuiet {
-		fmt.Println()
-		logger.Info(fmt.Sprintf("Summary: renamed=%s skipped=%s failed=%s",
-			ui.StyleCommand.Render(fmt.Sprint(success)),
-			ui.StylePattern.Render(fmt.Sprint(skipped)),
-			ui.StyleFlag.Render(fmt.Sprint(failed)),
-		))
+	fmt.Println()
+	logger.Warn("Interrupted; stopped after the current file")
+	printSummary(ops, flagVerbose)
+	hint := fmt.Sprintf("%d file(s) were not renamed. Run %s to rename the rest", pending, ui.StyleCommand.Render(resume))
+	if undo != "" {
+		hint += fmt.Sprintf(", or %s to restore the renamed files", ui.StyleCommand.Render(undo))
+	}
+	logger.Info(hint)
+	os.Exit(exitInterrupted)
+}
+
+// exitOnRateLimit exits with exitRateLimited if err is a rate-limit exhaustion
+func exitOnRateLimit(err error) {
+	var rateErr types.ErrRateLimited
+	if !errors.As(err, &rateErr) {
+		return
 	}
+	logger.Warn(rateErr.Error())
+	logger.Info("Progress was saved; run the same command again later to resume")
+	os.Exit(exitRateLimited)
 }
@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mydehq/autotitle"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagServiceName string
+	flagServiceAt   string
+)
+
+var serviceCmd = &cobra.Command{
+	Use:   "service",
+	Short: "Run autotitle on a schedule (Windows)",
+}
+
+var serviceInstallCmd = &cobra.Command{
+	Use:   "install [--at HH:MM] -- <command> [args...]",
+	Short: "Register a daily Scheduled Task running an autotitle command",
+	Long: `install registers a Windows Scheduled Task that runs the given autotitle
+command every day at --at, as the current user while they are logged on.
+Installing under a --name that already exists replaces that task.
+
+  autotitle service install --at 03:00 -- sort "D:\Downloads"
+  autotitle service install --name autotitle-show --at 04:00 -- refresh "D:\Anime\Show"
+
+On Linux and macOS, run the same command from cron or a systemd timer.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if args[0] == "service" {
+			logger.Error("A scheduled task can't manage scheduled tasks")
+			os.Exit(1)
+		}
+		if err := autotitle.InstallScheduledTask(flagServiceName, args, flagServiceAt); err != nil {
+			logger.Error("Failed to install scheduled task", "error", err)
+			os.Exit(1)
+		}
+		logger.Success(fmt.Sprintf("Scheduled %q daily at %s: autotitle %s", flagServiceName, flagServiceAt, strings.Join(args, " ")))
+	},
+}
+
+var serviceUninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Remove a Scheduled Task added by service install",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := autotitle.UninstallScheduledTask(flagServiceName); err != nil {
+			logger.Error("Failed to remove scheduled task", "error", err)
+			os.Exit(1)
+		}
+		logger.Success(fmt.Sprintf("Removed scheduled task %q", flagServiceName))
+	},
+}
+
+func init() {
+	serviceCmd.PersistentFlags().StringVar(&flagServiceName, "name", "autotitle", "Scheduled Task name")
+	serviceInstallCmd.Flags().StringVar(&flagServiceAt, "at", "03:00", "Daily start time (HH:MM)")
+	serviceCmd.AddCommand(serviceInstallCmd, serviceUninstallCmd)
+	RootCmd.AddCommand(serviceCmd)
+}
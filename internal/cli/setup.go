@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/charmbracelet/huh"
+	"github.com/mattn/go-isatty"
+	"github.com/mydehq/autotitle/internal/config"
+	"github.com/mydehq/autotitle/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var flagSetupDefaults bool
+
+var setupCmd = &cobra.Command{
+	Use:   "setup",
+	Short: "Create the global config with a guided wizard",
+	Long: `setup walks through the global configuration (~/.config/autotitle/config.yml):
+media formats, backup policy, default output format, tagging and API settings.
+
+An existing config is used as the starting point. Use --defaults to write the
+built-in defaults without prompting.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		runSetup()
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(setupCmd)
+	setupCmd.Flags().BoolVar(&flagSetupDefaults, "defaults", false, "Write the default config without prompting")
+}
+
+func runSetup() {
+	path, err := config.UserGlobalPath()
+	if err != nil {
+		logger.Error("Failed to resolve config path", "error", err)
+		os.Exit(1)
+	}
+
+	if flagSetupDefaults {
+		defaults := config.GetDefaults()
+		if err := config.SaveGlobal(path, &defaults); err != nil {
+			logger.Error("Failed to write config", "error", err)
+			os.Exit(1)
+		}
+		logger.Success(fmt.Sprintf("%s: %s", ui.StyleHeader.Render("Created config"), ui.StylePath.Render(path)))
+		return
+	}
+
+	if !isatty.IsTerminal(os.Stdout.Fd()) && !isatty.IsCygwinTerminal(os.Stdout.Fd()) {
+		logger.Error("setup is interactive; use --defaults in non-interactive mode")
+		os.Exit(1)
+	}
+
+	// Start from the config as written (or defaults), so ${VAR} references
+	// are saved back as they were
+	current, err := config.ReadGlobal()
+	if err != nil {
+		logger.Warn("Failed to load existing config, starting from defaults", "error", err)
+		defaults := config.GetDefaults()
+		current = &defaults
+	}
+
+	if existing := config.FindGlobal(); existing == path {
+		ui.ClearAndPrintBanner(false)
+		overwrite := true
+		err := ui.RunForm(huh.NewForm(
+			huh.NewGroup(
+				huh.NewConfirm().
+					Title("Global config already exists").
+					Description(fmt.Sprintf("Edit %s?", ui.StylePath.Render(path))).
+					Value(&overwrite),
+			),
+		).WithTheme(ui.AutotitleTheme()).WithKeyMap(ui.AutotitleKeyMap()))
+		if err != nil || !overwrite {
+			logger.Warn(ui.StyleDim.Render("Setup cancelled"))
+			return
+		}
+	}
+
+	cfg, confirmed, err := ui.RunSetupWizard(*current)
+	if err != nil {
+		ui.HandleAbort(err)
+		logger.Warn(ui.StyleDim.Render("Setup cancelled"))
+		return
+	}
+	if !confirmed {
+		logger.Warn(ui.StyleDim.Render("Setup cancelled"))
+		return
+	}
+
+	if err := config.SaveGlobal(path, &cfg); err != nil {
+		logger.Error("Failed to write config", "error", err)
+		os.Exit(1)
+	}
+	logger.Success(fmt.Sprintf("%s: %s", ui.StyleHeader.Render("Configuration saved to"), ui.StylePath.Render(path)))
+}
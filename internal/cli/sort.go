@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mydehq/autotitle"
+	"github.com/mydehq/autotitle/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagSortProviders     []string
+	flagSortOnly          bool
+	flagSortMinConfidence float64
+)
+
+var sortCmd = &cobra.Command{
+	Use:   "sort <dump-dir|library>",
+	Short: "Sort a folder of mixed series into per-series folders",
+	Long: `sort groups the loose video files in <dump-dir> by the series name in
+their filenames, matches each group to a provider by search, moves it into
+its own folder with a generated map file, and renames it.
+
+Groups matched below the confidence threshold (sort.min_confidence, or
+--min-confidence) are left in place and parked for "autotitle review",
+so sort is safe to run unattended. Preview the plan first with --dry-run.
+
+Given a library name, its root is sorted: only the library's providers are
+searched (unless --provider is given) and its preset applies.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, _ := libraryArg(args[0])
+		runSort(cmd.Context(), cmd, dir)
+	},
+}
+
+func init() {
+	sortCmd.Flags().BoolVarP(&flagDryRun, "dry-run", "d", false, "Show the plan without moving files")
+	sortCmd.Flags().BoolVarP(&flagNoBackup, "no-backup", "n", false, "Skip backup creation when renaming")
+	sortCmd.Flags().BoolVarP(&flagNoTag, "no-tag", "T", false, "Disable MKV metadata tagging (mkvpropedit)")
+	sortCmd.Flags().StringSliceVarP(&flagSortProviders, "provider", "p", nil, "Only search these providers")
+	sortCmd.Flags().BoolVar(&flagSortOnly, "no-rename", false, "Move files and write map files without renaming")
+	sortCmd.Flags().Float64Var(&flagSortMinConfidence, "min-confidence", 0, "Match confidence (0-1) needed to sort a group unattended")
+	RootCmd.AddCommand(sortCmd)
+}
+
+func runSort(ctx context.Context, cmd *cobra.Command, dir string) {
+	opts := append(ioLimitOptions(cmd), autotitle.WithEvents(handleEvent))
+	if flagDryRun {
+		opts = append(opts, autotitle.WithDryRun())
+	}
+	if flagNoBackup {
+		opts = append(opts, autotitle.WithNoBackup())
+	}
+	if flagNoTag {
+		opts = append(opts, autotitle.WithNoTagging())
+	}
+	if len(flagSortProviders) > 0 {
+		opts = append(opts, autotitle.WithProvider(flagSortProviders...))
+	}
+	if flagSortOnly {
+		opts = append(opts, autotitle.WithSortOnly())
+	}
+	if cmd.Flags().Changed("min-confidence") {
+		opts = append(opts, autotitle.WithMinConfidence(flagSortMinConfidence))
+	}
+
+	groups, err := autotitle.Sort(ctx, dir, opts...)
+	endProgress()
+	flushSuccesses()
+	if err != nil {
+		exitOnCancel(err)
+		logger.Error("Failed to sort", "error", err)
+		os.Exit(1)
+	}
+
+	if len(groups) == 0 {
+		logger.Warn("No video files found")
+		return
+	}
+
+	header := "Sorted"
+	if flagDryRun {
+		header = "Sort plan"
+	}
+	logger.Info(ui.StyleHeader.Render(header))
+	parked, unsorted := 0, 0
+	for _, g := range groups {
+		if g.Parked {
+			parked++
+			logger.Print(fmt.Sprintf("  %s %s %s", ui.StyleDim.Render("?"), g.Name,
+				ui.StyleDim.Render(fmt.Sprintf("(%d files, %s)", len(g.Files), g.Error))))
+			continue
+		}
+		if g.Match == nil {
+			unsorted++
+			logger.Print(fmt.Sprintf("  %s %s %s", ui.StyleError.Render("!"), g.Name,
+				ui.StyleDim.Render(fmt.Sprintf("(%d files, %s)", len(g.Files), g.Error))))
+			continue
+		}
+		detail := fmt.Sprintf("(%d files, %s, %.0f%% match)", len(g.Files), g.Match.URL, g.Confidence*100)
+		name, folder := ui.FitPair(g.Name, filepath.Base(g.Folder)+"/", ui.LineWidth(4+ui.ArrowWidth+1+len(detail)))
+		logger.Print(fmt.Sprintf("  %s %s → %s %s",
+			ui.StyleDim.Render("-"),
+			name,
+			ui.StylePath.Render(folder),
+			ui.StyleDim.Render(detail),
+		))
+	}
+
+	if parked > 0 && !flagDryRun {
+		logger.Warn(fmt.Sprintf("%d groups need review: run %s", parked, ui.StyleCommand.Render("autotitle review")))
+	}
+	if unsorted > 0 {
+		logger.Warn(fmt.Sprintf("%d groups could not be searched: run %s again once the providers answer", unsorted, ui.StyleCommand.Render("autotitle sort")))
+	}
+}
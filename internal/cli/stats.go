@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mydehq/autotitle"
+	"github.com/mydehq/autotitle/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var flagStatsTop int
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show your rename history totals (local only)",
+	Long: `stats reads the local rename history and reports all-time totals.
+Nothing is sent anywhere; the history lives next to the database cache.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		runStats(cmd.Context())
+	},
+}
+
+func init() {
+	statsCmd.Flags().IntVarP(&flagStatsTop, "top", "t", 5, "Number of most-renamed series to show")
+	RootCmd.AddCommand(statsCmd)
+}
+
+func runStats(ctx context.Context) {
+	stats, err := autotitle.Stats(ctx)
+	if err != nil {
+		logger.Error("Failed to read history", "error", err)
+		os.Exit(1)
+	}
+
+	if stats.Runs == 0 {
+		logger.Warn("No rename history yet")
+		return
+	}
+
+	keyStyle := ui.StyleHeader.Width(15)
+
+	logger.Print(fmt.Sprintf("%s %d", keyStyle.Render("Files Renamed:"), stats.Files))
+	logger.Print(fmt.Sprintf("%s %d", keyStyle.Render("Runs:"), stats.Runs))
+	logger.Print(fmt.Sprintf("%s %s", keyStyle.Render("Since:"), stats.First.Local().Format(time.DateTime)))
+	logger.Print(fmt.Sprintf("%s %s", keyStyle.Render("Last Run:"), stats.Last.Local().Format(time.DateTime)))
+	logger.Print(fmt.Sprintf("%s ~%s", keyStyle.Render("Time Saved:"), stats.TimeSaved.Round(time.Minute)))
+
+	top := stats.TopSeries
+	if flagStatsTop >= 0 && len(top) > flagStatsTop {
+		top = top[:flagStatsTop]
+	}
+	if len(top) == 0 {
+		return
+	}
+	logger.Print(keyStyle.Render("Most Renamed:"))
+	for _, s := range top {
+		logger.Print(fmt.Sprintf("  %s %s %s",
+			ui.StyleDim.Render("-"),
+			s.Series,
+			ui.StyleDim.Render(fmt.Sprintf("(%s/%s, %d files)", s.Provider, s.ID, s.Files)),
+		))
+	}
+}
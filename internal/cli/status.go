@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mydehq/autotitle"
+	"github.com/mydehq/autotitle/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagStatusAddr      string
+	flagStatusProviders bool
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show what a running watch is doing",
+	Long: `status asks a running "autotitle watch" serving on --addr (default
+serve.listen) for its state, the results of its latest runs and, with
+--providers, whether the providers answer.
+
+It exits with 1 if the daemon cannot be reached or is not watching.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		runStatus(cmd)
+	},
+}
+
+func init() {
+	statusCmd.Flags().StringVar(&flagStatusAddr, "addr", "", "Address the daemon serves on (overrides serve.listen)")
+	statusCmd.Flags().BoolVar(&flagStatusProviders, "providers", false, "Also check the providers from the daemon")
+	RootCmd.AddCommand(statusCmd)
+}
+
+func runStatus(cmd *cobra.Command) {
+	addr := serveAddr(cmd, "addr", flagStatusAddr)
+	if addr == "" {
+		logger.Error(fmt.Sprintf("No daemon address: pass --addr, or set %s in the global config", ui.StylePattern.Render("serve.listen")))
+		os.Exit(1)
+	}
+	s, err := autotitle.FetchDaemonStatus(cmd.Context(), addr, flagStatusProviders)
+	if err != nil {
+		logger.Error("Failed to reach the daemon", "address", addr, "error", err)
+		os.Exit(1)
+	}
+
+	keyStyle := ui.StyleHeader.Width(10)
+	state := ui.StyleCommand.Render(s.State)
+	if !s.Ready() {
+		state = ui.StyleError.Render(s.State)
+	}
+	logger.Print(fmt.Sprintf("%s %s", keyStyle.Render("State:"), state))
+	if !s.Started.IsZero() {
+		logger.Print(fmt.Sprintf("%s %s %s", keyStyle.Render("Uptime:"), time.Since(s.Started).Round(time.Second), ui.StyleDim.Render("(since "+s.Started.Local().Format(time.DateTime)+")")))
+	}
+	for _, root := range s.Roots {
+		logger.Print(fmt.Sprintf("%s %s", keyStyle.Render("Root:"), ui.StylePath.Render(root)))
+	}
+	if !s.Reloaded.IsZero() {
+		reload := s.Reloaded.Local().Format(time.DateTime)
+		if s.ReloadError != "" {
+			reload += " " + ui.StyleError.Render("failed: "+s.ReloadError)
+		}
+		logger.Print(fmt.Sprintf("%s %s", keyStyle.Render("Reloaded:"), reload))
+	}
+
+	if len(s.Runs) > 0 {
+		logger.Print("")
+		logger.Print(ui.StyleHeader.Render("Latest runs:"))
+		for _, r := range s.Runs {
+			result := fmt.Sprintf("%d renamed, %d skipped, %d failed", r.Renamed, r.Skipped, r.Failed)
+			if r.Error != "" {
+				result = ui.StyleError.Render(r.Error)
+			}
+			logger.Print(fmt.Sprintf("  %s %s %s", ui.StyleDim.Render(r.At.Local().Format(time.DateTime)), ui.StylePath.Render(r.Dir), result))
+		}
+	}
+
+	if len(s.Providers) > 0 {
+		logger.Print("")
+		logger.Print(ui.StyleHeader.Render("Providers:"))
+		var failing []string
+		for _, h := range s.Providers {
+			var status string
+			switch {
+			case !h.Checked:
+				status = ui.StyleDim.Render("no health check")
+			case h.OK():
+				status = ui.StyleCommand.Render("ok") + " " + ui.StyleDim.Render(h.Latency.Round(time.Millisecond).String())
+			default:
+				failing = append(failing, h.Name)
+				status = ui.StyleError.Render("failing: " + h.Error)
+			}
+			logger.Print(fmt.Sprintf("  %s %s", ui.StylePattern.Width(16).Render(h.Name), status))
+		}
+		if len(failing) > 0 {
+			logger.Warn("Provider check failed: " + strings.Join(failing, ", "))
+		}
+	}
+
+	if !s.Ready() {
+		os.Exit(1)
+	}
+}
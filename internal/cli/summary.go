@@ -0,0 +1,143 @@
+package cli
+
+import (
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/mydehq/autotitle"
+	"github.com/mydehq/autotitle/internal/ui"
+)
+
+// reasonLabels holds display labels in the order groups are printed
+var reasonLabels = []struct {
+	reason autotitle.SkipReason
+	label  string
+}{
+	{autotitle.ReasonUnchanged, "already named"},
+	{autotitle.ReasonNoPattern, "no pattern match"},
+	{autotitle.ReasonNoEpisode, "no DB episode"},
+	{autotitle.ReasonCollision, "collision"},
+	{autotitle.ReasonLocked, "locked"},
+	{autotitle.ReasonError, "rename error"},
+}
+
+// printSummary prints rename totals followed by skips and failures grouped by
+// reason. Affected files are listed under each group when verbose is set.
+func printSummary(ops []autotitle.RenameOperation, verbose bool) {
+	var success, skipped, failed int
+	groups := make(map[autotitle.SkipReason][]string)
+
+	for _, op := range ops {
+		switch op.Status {
+		case autotitle.StatusSuccess:
+			success++
+			continue
+		case autotitle.StatusSkipped:
+			skipped++
+		case autotitle.StatusFailed:
+			failed++
+		default:
+			continue
+		}
+		reason := op.Reason
+		if reason == "" {
+			reason = autotitle.ReasonError
+		}
+		groups[reason] = append(groups[reason], filepath.Base(op.SourcePath))
+	}
+
+	logger.Info(fmt.Sprintf("Summary: renamed=%s skipped=%s failed=%s",
+		ui.StyleCommand.Render(fmt.Sprint(success)),
+		ui.StylePattern.Render(fmt.Sprint(skipped)),
+		ui.StyleFlag.Render(fmt.Sprint(failed)),
+	))
+
+	for _, rl := range reasonLabels {
+		files := groups[rl.reason]
+		if len(files) == 0 {
+			continue
+		}
+
+		style := ui.StylePattern
+		if rl.reason == autotitle.ReasonLocked || rl.reason == autotitle.ReasonError || rl.reason == autotitle.ReasonCollision {
+			style = ui.StyleFlag
+		}
+		logger.Print(fmt.Sprintf("  %s %s %s",
+			ui.StyleDim.Render("-"),
+			ui.StyleHeader.Render(rl.label+":"),
+			style.Render(fmt.Sprint(len(files))),
+		))
+
+		if verbose {
+			slices.Sort(files)
+			for _, f := range files {
+				logger.Print(fmt.Sprintf("      %s", ui.StyleDim.Render(ui.TruncateMiddle(f, ui.LineWidth(6)))))
+			}
+		}
+	}
+}
+
+// printDryRunImpact prints what a dry-run would do besides renaming: files
+// tagged per backend, and files backed up by snapshot, hard link or copy with
+// the extra disk space the copies take. Copied files are listed when verbose.
+func printDryRunImpact(ops []autotitle.RenameOperation, verbose bool) {
+	backends := make(map[string]int)
+	var tagged, linked, snapshotted int
+	var copied []string
+	var extra int64
+	for _, op := range ops {
+		if op.TagBackend != "" {
+			backends[op.TagBackend]++
+			tagged++
+		}
+		switch op.Backup {
+		case autotitle.BackupLink:
+			linked++
+		case autotitle.BackupSnapshot:
+			snapshotted++
+		case autotitle.BackupCopy:
+			copied = append(copied, fmt.Sprintf("%s (%s)", filepath.Base(op.SourcePath), formatBytes(op.BackupBytes)))
+			extra += op.BackupBytes
+		}
+	}
+
+	if tagged > 0 {
+		var parts []string
+		for _, b := range slices.Sorted(maps.Keys(backends)) {
+			parts = append(parts, fmt.Sprintf("%s %d", b, backends[b]))
+		}
+		logger.Print(fmt.Sprintf("  %s %s %s %s",
+			ui.StyleDim.Render("-"),
+			ui.StyleHeader.Render("would tag:"),
+			ui.StyleCommand.Render(fmt.Sprint(tagged)),
+			ui.StyleDim.Render("("+strings.Join(parts, ", ")+")"),
+		))
+	}
+
+	if snapshotted > 0 {
+		logger.Print(fmt.Sprintf("  %s %s %s %s",
+			ui.StyleDim.Render("-"),
+			ui.StyleHeader.Render("would back up:"),
+			ui.StyleCommand.Render(fmt.Sprint(snapshotted)),
+			ui.StyleDim.Render("(in a filesystem snapshot)"),
+		))
+	}
+	if linked+len(copied) == 0 {
+		return
+	}
+	logger.Print(fmt.Sprintf("  %s %s %s %s",
+		ui.StyleDim.Render("-"),
+		ui.StyleHeader.Render("would back up:"),
+		ui.StyleCommand.Render(fmt.Sprint(linked+len(copied))),
+		ui.StyleDim.Render(fmt.Sprintf("(linked %d, copied %d, ~%s extra disk space)", linked, len(copied), formatBytes(extra))),
+	))
+	if verbose {
+		slices.Sort(copied)
+		for _, f := range copied {
+			logger.Print(fmt.Sprintf("      %s", ui.StyleDim.Render(ui.TruncateMiddle(f, ui.LineWidth(6)))))
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mydehq/autotitle/internal/support"
+	"github.com/mydehq/autotitle/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagSupportOutput    string
+	flagSupportAnonymize bool
+)
+
+var supportBundleCmd = &cobra.Command{
+	Use:   "support-bundle [path]",
+	Short: "Pack configs, logs, versions and a failing plan for an issue report",
+	Long: `support-bundle writes a tarball to attach to an issue: the global config
+and (with a path) the map file, with API keys and tokens redacted; the
+latest lines of json event sink logs; version, OS and external tools;
+cache stats; and the dry-run plan of path with every event, which shows
+where a rename goes wrong. Nothing is renamed.
+
+With --anonymize, directory paths (the folder, home, config and cache
+dirs) are replaced with hashes. Filenames are kept, as patterns are
+matched against them; check the bundle before sharing it.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		path := ""
+		if len(args) > 0 {
+			path = args[0]
+		}
+		runSupportBundle(cmd, path)
+	},
+}
+
+func init() {
+	supportBundleCmd.Flags().StringVarP(&flagSupportOutput, "output", "o", "", "Tarball to write (default autotitle-support-<time>.tar.gz)")
+	supportBundleCmd.Flags().BoolVar(&flagSupportAnonymize, "anonymize", false, "Replace directory paths with hashes")
+	RootCmd.AddCommand(supportBundleCmd)
+}
+
+func runSupportBundle(cmd *cobra.Command, path string) {
+	now := time.Now()
+	out := flagSupportOutput
+	if out == "" {
+		out = "autotitle-support-" + now.Format("20060102-150405") + ".tar.gz"
+	}
+	name := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(out), ".gz"), ".tar")
+
+	// Written even in read-only mode: the user asked for this one file
+	f, err := os.Create(out)
+	if err != nil {
+		logger.Error("Failed to create support bundle", "error", err)
+		os.Exit(1)
+	}
+	entries, err := support.Build(cmd.Context(), f, name, support.Options{Path: path, Anonymize: flagSupportAnonymize}, now)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(out)
+		logger.Error("Failed to write support bundle", "error", err)
+		os.Exit(1)
+	}
+
+	for _, e := range entries {
+		logger.Debug(fmt.Sprintf("%s %s", ui.StyleDim.Render("added"), e))
+	}
+	logger.Success(fmt.Sprintf("%s %s", ui.StyleHeader.Render("Support bundle:"), ui.StylePath.Render(out)))
+	logger.Info(ui.StyleDim.Render("Check it before sharing; see MANIFEST.txt for what it holds"))
+}
@@ -6,16 +6,16 @@ import (
 	"path/filepath"
 
 	"github.com/mydehq/autotitle"
-	"github.com/mydehq/autotitle/internal/tagger"
 	"github.com/mydehq/autotitle/internal/ui"
 	"github.com/spf13/cobra"
 )
 
 var tagCmd = &cobra.Command{
 	Use:   "tag [path]",
-	Short: "Embed metadata into MKV files without renaming",
+	Short: "Embed metadata into media files without renaming",
 	Long: `tag reads the local _autotitle.yml and embeds episode/series metadata
-into matched MKV files using mkvpropedit (MKVToolNix).
+into matched files using mkvpropedit (MKV/WebM), AtomicParsley (MP4) or
+ffmpeg, as set by tagging.backend in the global config.
 
 Useful for files that are already correctly named.`,
 	Args: cobra.MaximumNArgs(1),
@@ -38,13 +38,9 @@ func init() {
 }
 
 func runTag(cmd *cobra.Command, path string) {
-	if !tagger.IsAvailable() {
-		logger.Error("mkvpropedit not found. Please install MKVToolNix.")
-		os.Exit(1)
-	}
-
 	opts := []autotitle.Option{
 		autotitle.WithEvents(func(e autotitle.Event) {
+			sendToSinks(e)
 			switch e.Type {
 			case autotitle.EventInfo:
 				logger.Info(fmt.Sprintf("%s: %s", ui.StyleHeader.Render("Tag"), e.Message))
@@ -3,12 +3,24 @@ package cli
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/charmbracelet/huh"
 	"github.com/mydehq/autotitle"
+	"github.com/mydehq/autotitle/internal/types"
 	"github.com/mydehq/autotitle/internal/ui"
+	"github.com/mydehq/autotitle/internal/util"
 	"github.com/spf13/cobra"
 )
 
+var (
+	flagUndoDryRun   bool
+	flagUndoForce    bool
+	flagUndoEpisodes string
+	flagUndoFiles    string
+)
+
 var undoCmd = &cobra.Command{
 	Use:   "undo <path>",
 	Short: "Restore files from backup",
@@ -20,14 +32,143 @@ var undoCmd = &cobra.Command{
 
 func init() {
 	RootCmd.AddCommand(undoCmd)
+	undoCmd.Flags().BoolVarP(&flagUndoDryRun, "dry-run", "d", false, "List files that would be restored without changing anything")
+	undoCmd.Flags().BoolVarP(&flagUndoForce, "force", "f", false, "Overwrite files created under an original name since the rename")
+	undoCmd.Flags().StringVarP(&flagUndoEpisodes, "episodes", "e", "", "Restore only these episodes (e.g. 3-5,7)")
+	undoCmd.Flags().StringVar(&flagUndoFiles, "files", "", "Restore only files whose original or new name matches a glob")
 }
 
 func runUndo(cmd *cobra.Command, path string) {
-	if err := autotitle.Undo(cmd.Context(), path); err != nil {
+	opts := ioLimitOptions(cmd)
+	if flagUndoEpisodes != "" {
+		episodes, err := util.ParseRanges(flagUndoEpisodes)
+		if err != nil {
+			logger.Error("Invalid --episodes", "error", err)
+			os.Exit(1)
+		}
+		opts = append(opts, autotitle.WithEpisodes(episodes...))
+	}
+	if flagUndoFiles != "" {
+		opts = append(opts, autotitle.WithFilesGlob(flagUndoFiles))
+	}
+
+	entries, err := autotitle.UndoPlan(cmd.Context(), path, opts...)
+	if err != nil {
 		fmt.Println()
+		logger.Error("Failed to read backup", "error", err)
+		os.Exit(1)
+	}
+
+	if flagUndoDryRun {
+		printRestorePlan(entries)
+		return
+	}
+
+	if len(entries) == 0 {
+		logger.Warn("No backed up files match the selection")
+		return
+	}
+
+	if flagUndoForce {
+		opts = append(opts, autotitle.WithForce())
+	} else if conflicts := restoreConflicts(entries); len(conflicts) > 0 {
+		resolutions := resolveConflicts(conflicts)
+		if resolutions == nil {
+			logger.Warn(ui.StyleDim.Render("Undo cancelled"))
+			return
+		}
+		opts = append(opts, autotitle.WithConflictResolutions(resolutions))
+	}
+
+	if err := autotitle.Undo(cmd.Context(), path, opts...); err != nil {
+		fmt.Println()
+		if rb, ok := err.(types.ErrSnapshotRollback); ok {
+			logger.Warn(fmt.Sprintf("The originals are in %s snapshot %s", rb.Snapshot.Type, ui.StylePath.Render(rb.Snapshot.ID)))
+			logger.Info(rb.Hint)
+			os.Exit(1)
+		}
 		logger.Error("Failed to undo", "error", err)
 		os.Exit(1)
 	}
 	fmt.Println()
 	logger.Success(ui.StyleHeader.Render("Files restored from backup"))
 }
+
+// conflictNote marks a restore that overwrites a file created since the rename
+const conflictNote = "(overwrites existing file)"
+
+// printRestorePlan lists what undo would restore and remove
+func printRestorePlan(entries []autotitle.RestoreEntry) {
+	restored := 0
+	for _, e := range entries {
+		if e.Original == e.Renamed {
+			continue
+		}
+		restored++
+		used := ui.ArrowWidth
+		if e.Conflict {
+			used += len(" " + conflictNote)
+		}
+		renamed, original := ui.FitPair(e.Renamed, e.Original, ui.LineWidth(used))
+		line := fmt.Sprintf("%s %s %s", ui.StyleDim.Render(renamed), ui.StyleDim.Render("→"), ui.StylePath.Render(original))
+		if e.Conflict {
+			line += " " + ui.StyleError.Render(conflictNote)
+		}
+		logger.Print(line)
+	}
+
+	fmt.Println()
+	logger.Info(fmt.Sprintf("%s: %d file(s) would be restored", ui.StyleHeader.Render("Dry run"), restored))
+	if conflicts := restoreConflicts(entries); len(conflicts) > 0 {
+		logger.Warn(fmt.Sprintf("%d file(s) were created under an original name since the rename", len(conflicts)))
+	}
+}
+
+func restoreConflicts(entries []autotitle.RestoreEntry) []string {
+	var conflicts []string
+	for _, e := range entries {
+		if e.Conflict {
+			conflicts = append(conflicts, e.Original)
+		}
+	}
+	return conflicts
+}
+
+// resolveConflicts asks, file by file, what to do with the files created
+// under an original name since the rename. It returns nil if cancelled.
+// Non-interactive sessions never overwrite without --force.
+func resolveConflicts(conflicts []string) map[string]autotitle.ConflictAction {
+	if !isTerminal() {
+		logger.Error("Failed to undo", "error", types.ErrRestoreConflict{Files: conflicts})
+		logger.Info("Use --force to overwrite them")
+		os.Exit(1)
+	}
+
+	actions := make([]autotitle.ConflictAction, len(conflicts))
+	groups := make([]*huh.Group, len(conflicts))
+	for i, name := range conflicts {
+		actions[i] = autotitle.ConflictKeepNew
+		ext := filepath.Ext(name)
+		numbered := strings.TrimSuffix(name, ext) + " (1)" + ext
+		groups[i] = huh.NewGroup(
+			huh.NewSelect[autotitle.ConflictAction]().
+				Title(fmt.Sprintf("%s exists (%d/%d)\n", name, i+1, len(conflicts))).
+				Description("It was created after the rename and differs from the backup\n").
+				Options(
+					huh.NewOption("Keep the new file (leave this one renamed)", autotitle.ConflictKeepNew),
+					huh.NewOption("Restore the original over it", autotitle.ConflictRestore),
+					huh.NewOption(fmt.Sprintf("Keep both (restore as %s)", numbered), autotitle.ConflictKeepBoth),
+				).
+				Value(&actions[i]),
+		)
+	}
+	if err := ui.RunForm(huh.NewForm(groups...).WithTheme(ui.AutotitleTheme()).WithKeyMap(ui.AutotitleKeyMap())); err != nil {
+		return nil
+	}
+
+	resolutions := make(map[string]autotitle.ConflictAction, len(conflicts))
+	for i, name := range conflicts {
+		resolutions[name] = actions[i]
+	}
+	return resolutions
+}
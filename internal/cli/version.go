@@ -2,19 +2,94 @@ package cli
 
 import (
 	"fmt"
+	"maps"
+	"slices"
+	"time"
 
 	"github.com/mydehq/autotitle"
+	"github.com/mydehq/autotitle/internal/report"
+	"github.com/mydehq/autotitle/internal/ui"
 	"github.com/spf13/cobra"
 )
 
+var flagVersionFull bool
+
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Print the version number",
+	Long: `version prints the version of autotitle.
+
+With --full it also lists the Go version, platform and external tools, and
+for each provider and filler source the API version or page layout its
+parser targets, then checks with a live request that the parser still
+understands the response. A failing check points at an outdated parser
+rather than at your files: update autotitle before filing an issue.`,
+	Args: cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
+		if flagVersionFull {
+			runVersionFull(cmd)
+			return
+		}
 		fmt.Printf("autotitle %s\n", autotitle.Version())
 	},
 }
 
 func init() {
 	RootCmd.AddCommand(versionCmd)
+	versionCmd.Flags().BoolVar(&flagVersionFull, "full", false, "Also show the platform, tools and provider parser versions, and check the providers")
+}
+
+func runVersionFull(cmd *cobra.Command) {
+	env := report.CurrentEnvironment()
+	keyStyle := ui.StyleHeader.Width(10)
+	logger.Print(fmt.Sprintf("%s %s", keyStyle.Render("autotitle:"), autotitle.Version()))
+	logger.Print(fmt.Sprintf("%s %s %s/%s", keyStyle.Render("Go:"), env.GoVersion, env.OS, env.Arch))
+	for _, t := range slices.Sorted(maps.Keys(env.Tools)) {
+		path := ui.StyleDim.Render("not found")
+		if env.Tools[t] != "" {
+			path = ui.StylePath.Render(env.Tools[t])
+		}
+		logger.Print(fmt.Sprintf("%s %s %s", keyStyle.Render("Tool:"), t, path))
+	}
+
+	if isTerminal() {
+		logger.Info(ui.StyleDim.Render("Checking providers..."))
+	}
+	health := autotitle.CheckProviders(cmd.Context())
+
+	failing := 0
+	for _, kind := range []string{"provider", "filler"} {
+		logger.Print("")
+		if kind == "provider" {
+			logger.Print(ui.StyleHeader.Render("Providers:"))
+		} else {
+			logger.Print(ui.StyleHeader.Render("Filler sources:"))
+		}
+		for _, h := range health {
+			if h.Kind != kind {
+				continue
+			}
+			schema := ui.StyleDim.Render("schema not reported")
+			if h.Schema != nil {
+				schema = fmt.Sprintf("%s %s", h.Schema.Target, ui.StyleDim.Render("(checked "+h.Schema.Checked+")"))
+			}
+			var status string
+			switch {
+			case !h.Checked:
+				status = ui.StyleDim.Render("no health check")
+			case h.OK():
+				status = ui.StyleCommand.Render("ok") + " " + ui.StyleDim.Render(h.Latency.Round(time.Millisecond).String())
+			default:
+				failing++
+				status = ui.StyleError.Render("failing: " + h.Error)
+			}
+			logger.Print(fmt.Sprintf("  %s %s", ui.StylePattern.Width(16).Render(h.Name), schema))
+			logger.Print(fmt.Sprintf("  %16s %s", "", status))
+		}
+	}
+
+	if failing > 0 {
+		logger.Print("")
+		logger.Warn(fmt.Sprintf("%d check(s) failed. If the site is up and your API keys are set, the parser may be outdated: update autotitle before filing an issue", failing))
+	}
 }
@@ -0,0 +1,176 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/mydehq/autotitle"
+	"github.com/mydehq/autotitle/internal/config"
+	"github.com/mydehq/autotitle/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagWatchSettle time.Duration
+	flagWatchListen string
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch [path|library...]",
+	Short: "Rename folders as new files arrive",
+	Long: `watch keeps running and renames each folder with a map file as files
+arrive in it, once they have been unchanged for watch.settle seconds (or
+--settle), so downloads still being written are left alone.
+
+Without arguments every library in the global config is watched. Edits to
+map files and to the global config are picked up without a restart: the new
+config replaces the old one whole, and one that does not load is reported
+and ignored. Send SIGHUP to reload the global config by hand.
+
+A folder created while watching that has no map file is set up like a
+group of "autotitle sort": its name is searched, and a match at or above
+sort.min_confidence gets a map file and the folder renamed. A weaker one,
+or none, is parked for "autotitle review", which sets the folder up in
+place.
+
+A folder whose filenames' title is unlike the database title is skipped
+with a warning: rename it once by hand with --accept-title. Stop with
+Ctrl+C or SIGTERM.
+
+With --listen (or serve.listen) it also serves health probes and a status
+report over HTTP: GET /healthz, /readyz and /status, which "autotitle
+status" reads. If --listen cannot be served (the address is in use, or is
+beyond loopback without serve.token) watch exits; a serve.listen that
+cannot is reported and watching goes on.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runWatch(cmd.Context(), cmd, args)
+	},
+}
+
+func init() {
+	watchCmd.Flags().BoolVarP(&flagDryRun, "dry-run", "d", false, "Only log the renames")
+	watchCmd.Flags().BoolVarP(&flagNoBackup, "no-backup", "n", false, "Skip backup creation")
+	watchCmd.Flags().BoolVarP(&flagNoTag, "no-tag", "T", false, "Disable MKV metadata tagging (mkvpropedit)")
+	watchCmd.Flags().DurationVar(&flagWatchSettle, "settle", 0, "How long files must be unchanged before a folder is renamed (overrides watch.settle)")
+	watchCmd.Flags().StringVar(&flagWatchListen, "listen", "", "Serve health probes and status on this address, e.g. 127.0.0.1:7979 (overrides serve.listen)")
+	RootCmd.AddCommand(watchCmd)
+}
+
+func runWatch(ctx context.Context, cmd *cobra.Command, args []string) {
+	roots := watchRoots(args)
+	if len(roots) == 0 {
+		logger.Error(fmt.Sprintf("Nothing to watch: give a folder, or add libraries under %s in the global config", ui.StylePattern.Render("libraries")))
+		os.Exit(1)
+	}
+
+	opts := append(ioLimitOptions(cmd), autotitle.WithEvents(watchEvent))
+	if flagDryRun {
+		opts = append(opts, autotitle.WithDryRun())
+	}
+	if flagNoBackup {
+		opts = append(opts, autotitle.WithNoBackup())
+	}
+	if flagNoTag {
+		opts = append(opts, autotitle.WithNoTagging())
+	}
+	if cmd.Flags().Changed("settle") {
+		opts = append(opts, autotitle.WithSettle(flagWatchSettle))
+	}
+
+	d, err := autotitle.NewDaemon(roots, opts...)
+	if err != nil {
+		logger.Error("Failed to start watching", "error", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGTERM)
+	defer stop()
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	go func() {
+		for range hup {
+			d.Reload()
+		}
+	}()
+
+	if addr := serveAddr(cmd, "listen", flagWatchListen); addr != "" {
+		ln, err := d.Listen(addr)
+		switch {
+		case err != nil && cmd.Flags().Changed("listen"):
+			logger.Error("Failed to serve", "address", addr, "error", err)
+			os.Exit(1)
+		case err != nil:
+			logger.Error("Failed to serve", "address", addr, "error", err)
+		default:
+			go func() {
+				if err := d.Serve(ctx, ln); err != nil {
+					logger.Error("Failed to serve", "address", addr, "error", err)
+				}
+			}()
+			logger.Info(fmt.Sprintf("Serving status on %s", ui.StylePath.Render(addr)))
+		}
+	}
+
+	for _, root := range roots {
+		logger.Info(fmt.Sprintf("Watching %s", ui.StylePath.Render(root)))
+	}
+	err = d.Run(ctx)
+	endProgress()
+	flushSuccesses()
+	if err != nil && !errors.Is(err, context.Canceled) {
+		logger.Error("Watch failed", "error", err)
+		os.Exit(1)
+	}
+	logger.Info("Stopped watching")
+}
+
+// serveAddr returns the address of the watch server: the flag if given,
+// else serve.listen
+func serveAddr(cmd *cobra.Command, flag, value string) string {
+	if cmd.Flags().Changed(flag) {
+		return value
+	}
+	if globalCfg, err := config.LoadGlobal(); err == nil {
+		return globalCfg.Serve.Listen
+	}
+	return ""
+}
+
+// watchRoots resolves the folder and library arguments of watch; without
+// any, the roots of every library
+func watchRoots(args []string) []string {
+	var roots []string
+	for _, arg := range args {
+		root, _ := libraryArg(arg)
+		roots = append(roots, root)
+	}
+	if len(args) > 0 {
+		return roots
+	}
+	libs, err := autotitle.Libraries()
+	if err != nil {
+		logger.Warn("Failed to load global config", "error", err)
+	}
+	for _, l := range libs {
+		roots = append(roots, l.Root)
+	}
+	return roots
+}
+
+// watchEvent logs events like handleEvent, and config reloads at info level
+// since they are what a running watch has to say
+func watchEvent(e autotitle.Event) {
+	if _, ok := e.Data.(autotitle.ConfigReload); ok {
+		sendToSinks(e)
+		flushSuccesses()
+		logger.Info(e.Message)
+		return
+	}
+	handleEvent(e)
+}
@@ -5,9 +5,14 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
+	"sync/atomic"
 
+	"github.com/mydehq/autotitle/internal/matcher"
 	"github.com/mydehq/autotitle/internal/types"
+	"github.com/mydehq/autotitle/internal/util"
 	"gopkg.in/yaml.v3"
 )
 
@@ -24,8 +29,11 @@ const GlobalConfigFileName = "config.yml"
 
 // defaults holds the default global configuration values
 var defaults = types.GlobalConfig{
-	MapFile: "_autotitle.yml",
-	Formats: []string{"mkv", "mp4", "avi", "webm", "m4v", "ts", "flv"},
+	MapFile:      "_autotitle.yml",
+	Formats:      []string{"mkv", "mp4", "avi", "webm", "m4v", "ts", "flv", "ogm", "wmv"},
+	MusicFormats: []string{"flac", "mp3", "m4a", "ogg", "opus", "wav", "aac", "ape", "wv"},
+	MangaFormats: []string{"cbz", "cbr", "cb7", "cbt", "pdf"},
+	NovelFormats: []string{"epub", "azw3", "azw", "mobi", "pdf"},
 	Patterns: []types.Pattern{
 		{
 			Input: []string{"{{EP_NUM}}.{{EXT}}", "Episode {{EP_NUM}}.{{EXT}}", "E{{EP_NUM}}.{{EXT}}"},
@@ -45,6 +53,65 @@ var defaults = types.GlobalConfig{
 		Enabled: true,
 		DirName: ".autotitle_backup",
 	},
+	IgnoreDirs: []string{".git", "@eaDir", "#recycle"},
+	Sort: types.SortConfig{
+		MinConfidence: 0.75,
+	},
+	TitleGuard: types.TitleGuardConfig{
+		MinSimilarity: 0.3,
+		Action:        types.TitleGuardConfirm,
+	},
+	Watch: types.WatchConfig{
+		Settle: 10,
+	},
+}
+
+// movieOutput is the movie preset: "Title (Year).ext", or "Title (Year) pt1.ext" for split movies
+var movieOutput = types.OutputConfig{
+	Fields:    []string{"SERIES", "(YEAR)", "PART"},
+	Separator: " ",
+}
+
+// MovieFields returns the output fields of the movie preset
+func MovieFields() []string {
+	return slices.Clone(movieOutput.Fields)
+}
+
+// ResolveOutput applies the output preset for the media type. Auto switches
+// episode-centric fields (those using EP_NUM) to the movie preset for movies.
+func ResolveOutput(out types.OutputConfig, mediaType types.MediaType) types.OutputConfig {
+	movie := out.Preset == types.PresetMovie
+	if (out.Preset == "" || out.Preset == types.PresetAuto) && mediaType == types.MediaTypeMovie {
+		movie = slices.Contains(out.Fields, "EP_NUM")
+	}
+	if !movie {
+		return out
+	}
+
+	resolved := out
+	resolved.Fields = MovieFields()
+	resolved.Separator = movieOutput.Separator
+	return resolved
+}
+
+// FormatsFor returns the file extensions renamed for targets of mediaType:
+// audio for music, comic archives for manga, ebooks for light novels and
+// videos for everything else.
+// Unset lists fall back to the defaults.
+func FormatsFor(g *types.GlobalConfig, mediaType types.MediaType) []string {
+	formats, fallback := g.Formats, defaults.Formats
+	switch mediaType {
+	case types.MediaTypeMusic:
+		formats, fallback = g.MusicFormats, defaults.MusicFormats
+	case types.MediaTypeManga:
+		formats, fallback = g.MangaFormats, defaults.MangaFormats
+	case types.MediaTypeNovel:
+		formats, fallback = g.NovelFormats, defaults.NovelFormats
+	}
+	if len(formats) == 0 {
+		return slices.Clone(fallback)
+	}
+	return formats
 }
 
 // defaultMapFile holds the default configuration for _autotitle.yml
@@ -66,26 +133,26 @@ func GetDefaults() types.GlobalConfig {
 
 // Load loads configuration from a directory
 func Load(dir string) (*types.Config, error) {
+	return LoadFile(MapFilePath(dir))
+}
+
+// MapFilePath returns the path of the map file in dir. The alternate
+// extension (.yml <-> .yaml) is used if only that exists.
+func MapFilePath(dir string) string {
 	// Try to get map file name from global config
 	mapFileName := defaults.MapFile
 	if globalCfg, err := LoadGlobal(); err == nil && globalCfg.MapFile != "" {
 		mapFileName = globalCfg.MapFile
 	}
 
-	// Try primary path first
 	path := filepath.Join(dir, mapFileName)
-	if _, err := os.Stat(path); err == nil {
-		return LoadFile(path)
-	}
-
-	// Try alternate extension (.yml <-> .yaml)
-	altPath := swapYAMLExtension(path)
-	if _, err := os.Stat(altPath); err == nil {
-		return LoadFile(altPath)
+	if _, err := os.Stat(path); err != nil {
+		altPath := swapYAMLExtension(path)
+		if _, err := os.Stat(altPath); err == nil {
+			return altPath
+		}
 	}
-
-	// Return error for primary path
-	return LoadFile(path)
+	return path
 }
 
 // swapYAMLExtension swaps .yml to .yaml and vice versa
@@ -114,47 +181,70 @@ func LoadFile(path string) (*types.Config, error) {
 		return nil, fmt.Errorf("failed to parse map file: %w", err)
 	}
 
-	if err := Validate(&cfg); err != nil {
-		return nil, err
-	}
-
 	absPath, err := filepath.Abs(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to resolve config path: %w", err)
 	}
 	cfg.BaseDir = filepath.Dir(absPath)
 
+	// Interpolate ${VAR} references before validating required fields
+	if err := expandConfig(&cfg, path); err != nil {
+		return nil, err
+	}
+
+	// A relative backup_dir is relative to the map file, like target paths
+	for i := range cfg.Targets {
+		if dir := cfg.Targets[i].BackupDir; dir != "" && !filepath.IsAbs(dir) {
+			cfg.Targets[i].BackupDir = filepath.Join(cfg.BaseDir, dir)
+		}
+	}
+
+	if err := Validate(&cfg); err != nil {
+		return nil, err
+	}
+
 	return &cfg, nil
 }
 
-// LoadGlobal loads the global configuration
-func LoadGlobal() (*types.GlobalConfig, error) {
+// UserGlobalPath returns the per-user global config path (~/.config/autotitle/config.yml)
+func UserGlobalPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user home directory: %w", err)
+	}
+	return filepath.Join(home, ".config", "autotitle", GlobalConfigFileName), nil
+}
+
+// FindGlobal returns the path of the global config file in use, or "" if none exists
+func FindGlobal() string {
 	// Paths to check in order
 	paths := []string{}
 
 	// 1. ~/.config/autotitle/config.yml (and .yaml)
-	home, err := os.UserHomeDir()
-	if err == nil {
-		paths = append(paths, filepath.Join(home, ".config", "autotitle", "config.yml"))
-		paths = append(paths, filepath.Join(home, ".config", "autotitle", "config.yaml"))
+	if userPath, err := UserGlobalPath(); err == nil {
+		paths = append(paths, userPath, swapYAMLExtension(userPath))
 	}
 
 	// 2. /etc/autotitle/config.yml (and .yaml) (Linux/Unix)
 	paths = append(paths, filepath.Join("/etc", "autotitle", "config.yml"))
 	paths = append(paths, filepath.Join("/etc", "autotitle", "config.yaml"))
 
-	var configPath string
 	for _, p := range paths {
 		if _, err := os.Stat(p); err == nil {
-			configPath = p
-			break
+			return p
 		}
 	}
+	return ""
+}
 
-	// Default values
+// ReadGlobal reads the global configuration as written, over the defaults:
+// ${VAR} references are kept. It is what an editor of the file saves back;
+// everything else uses LoadGlobal.
+func ReadGlobal() (*types.GlobalConfig, error) {
 	cfg := &types.GlobalConfig{}
 	*cfg = GetDefaults()
 
+	configPath := FindGlobal()
 	if configPath == "" {
 		return cfg, nil // Return defaults if no config found
 	}
@@ -167,12 +257,131 @@ func LoadGlobal() (*types.GlobalConfig, error) {
 	if err := yaml.Unmarshal(data, cfg); err != nil {
 		return nil, fmt.Errorf("failed to parse global config: %w", err)
 	}
+	return cfg, nil
+}
+
+// LoadGlobal loads the global configuration in effect, with ${VAR}
+// references expanded. While a config is pinned with PinGlobal, a copy of
+// it is returned instead.
+func LoadGlobal() (*types.GlobalConfig, error) {
+	if cfg := pinned.Load(); cfg != nil {
+		clone := cfg.Clone()
+		return &clone, nil
+	}
+	return loadGlobal()
+}
+
+// loadGlobal reads and checks the global config file
+func loadGlobal() (*types.GlobalConfig, error) {
+	cfg, err := ReadGlobal()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := expandGlobal(cfg); err != nil {
+		return nil, fmt.Errorf("invalid global config: %w", err)
+	}
+
+	if err := matcher.CheckCustomPlaceholders(cfg.Placeholders); err != nil {
+		return nil, fmt.Errorf("invalid global config: %w", err)
+	}
+	if err := matcher.CheckSimilarity(cfg.Matching.Similarity); err != nil {
+		return nil, fmt.Errorf("invalid global config: matching.similarity: %w", err)
+	}
+	if err := resolveLibraries(cfg.Libraries); err != nil {
+		return nil, fmt.Errorf("invalid global config: libraries: %w", err)
+	}
+	if err := validateSnapshot(cfg.Backup.Snapshot); err != nil {
+		return nil, fmt.Errorf("invalid global config: backup.snapshot: %w", err)
+	}
 
 	return cfg, nil
 }
 
+// pinned is the global config LoadGlobal serves instead of the file
+var pinned atomic.Pointer[types.GlobalConfig]
+
+// PinGlobal loads the global config and makes LoadGlobal return it, rather
+// than read the file again, until UnpinGlobal. A long-running process pins
+// its config so that a file caught halfway through an edit never reaches a
+// run, and calls PinGlobal again to reload: the new config replaces the old
+// one whole, or, if it does not load, the old one stays pinned. It returns
+// the config pinned before (nil if none) and the one pinned now.
+func PinGlobal() (old, cfg *types.GlobalConfig, err error) {
+	old = pinned.Load()
+	cfg, err = loadGlobal()
+	if err != nil {
+		return old, old, err
+	}
+	pinned.Store(cfg)
+	return old, cfg, nil
+}
+
+// UnpinGlobal lets LoadGlobal read the file again
+func UnpinGlobal() {
+	pinned.Store(nil)
+}
+
+// Changed lists the top-level keys (by their YAML names) whose values differ
+// between two configs of the same type, e.g. two global configs or two map
+// files, for reporting what a reload changed
+func Changed[T any](old, cfg *T) []string {
+	if old == nil || cfg == nil {
+		return nil
+	}
+	a, b := reflect.ValueOf(old).Elem(), reflect.ValueOf(cfg).Elem()
+	var keys []string
+	for i := range a.NumField() {
+		if !a.Type().Field(i).IsExported() || reflect.DeepEqual(a.Field(i).Interface(), b.Field(i).Interface()) {
+			continue
+		}
+		field := a.Type().Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		keys = append(keys, name)
+	}
+	return keys
+}
+
+// ApplyGlobal installs the settings of cfg that apply process-wide: custom
+// placeholders, which every compiled pattern may use, and the similarity
+// metric of matching.similarity. Call it once at startup with the config
+// LoadGlobal returned; LoadGlobal itself changes nothing.
+func ApplyGlobal(cfg *types.GlobalConfig) error {
+	if err := matcher.SetCustomPlaceholders(cfg.Placeholders); err != nil {
+		return fmt.Errorf("invalid global config: %w", err)
+	}
+	if err := matcher.SetConfiguredSimilarity(cfg.Matching.Similarity); err != nil {
+		return fmt.Errorf("invalid global config: matching.similarity: %w", err)
+	}
+	return nil
+}
+
+// validateSnapshot checks the snapshot type, and that a command snapshot
+// has a command to take it
+func validateSnapshot(s types.SnapshotConfig) error {
+	switch s.Type {
+	case "", types.SnapshotBtrfs, types.SnapshotZFS:
+		return nil
+	case types.SnapshotCommand:
+		if s.Create == "" {
+			return fmt.Errorf("create is required for command snapshots")
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown type %q (use btrfs, zfs or command)", s.Type)
+}
+
 // Save saves configuration to a file
 func Save(path string, cfg *types.Config) error {
+	if err := util.CheckWritable("write map files"); err != nil {
+		return err
+	}
 	data, err := yaml.Marshal(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
@@ -185,6 +394,108 @@ func Save(path string, cfg *types.Config) error {
 	return nil
 }
 
+// SetOutput rewrites the output of every pattern of target i in the map file
+// at path: fields replaces the fields unless empty, and preset the preset
+// unless empty. The rest of the file is kept as written, ${VAR}s included.
+func SetOutput(path string, i int, fields []string, preset string) error {
+	if err := util.CheckWritable("write map files"); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read map file: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse map file: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return fmt.Errorf("map file is empty: %s", path)
+	}
+
+	targets := mappingValue(doc.Content[0], "targets")
+	if targets == nil || targets.Kind != yaml.SequenceNode || i >= len(targets.Content) {
+		return fmt.Errorf("map file has no target %d: %s", i, path)
+	}
+	patterns := mappingValue(targets.Content[i], "patterns")
+	if patterns == nil || patterns.Kind != yaml.SequenceNode {
+		return fmt.Errorf("target %d has no patterns: %s", i, path)
+	}
+
+	for _, pattern := range patterns.Content {
+		output := mappingValue(pattern, "output")
+		if output == nil {
+			output = &yaml.Node{Kind: yaml.MappingNode}
+			setMappingValue(pattern, "output", output)
+		}
+		if len(fields) > 0 {
+			seq := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+			for _, f := range fields {
+				seq.Content = append(seq.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: f})
+			}
+			setMappingValue(output, "fields", seq)
+		}
+		if preset != "" {
+			setMappingValue(output, "preset", &yaml.Node{Kind: yaml.ScalarNode, Value: preset})
+		}
+	}
+
+	out, err := yaml.Marshal(&doc)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	if err := os.WriteFile(path, out, 0644); err != nil {
+		return fmt.Errorf("failed to write map file: %w", err)
+	}
+	return nil
+}
+
+// mappingValue returns the value under key in a YAML mapping node, or nil
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	if m.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setMappingValue sets key in a YAML mapping node, appending it if missing
+func setMappingValue(m *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content[i+1] = value
+			return
+		}
+	}
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+}
+
+// SaveGlobal writes the global configuration, creating parent directories as needed
+func SaveGlobal(path string, cfg *types.GlobalConfig) error {
+	if err := util.CheckWritable("write the global config"); err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal global config: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write global config: %w", err)
+	}
+
+	return nil
+}
+
 // SaveToDir saves configuration to the default map file in the specified directory
 func SaveToDir(dir string, cfg *types.Config) error {
 	path := filepath.Join(dir, defaults.MapFile)
@@ -204,6 +515,9 @@ func Validate(cfg *types.Config) error {
 		if target.URL == "" {
 			return fmt.Errorf("target %d: url is required", i)
 		}
+		if target.NumberingBase != nil && *target.NumberingBase != 0 && *target.NumberingBase != 1 {
+			return fmt.Errorf("target %d: numbering_base must be 0 or 1, got %d", i, *target.NumberingBase)
+		}
 		if len(target.Patterns) == 0 {
 			return fmt.Errorf("target %d: at least one pattern is required", i)
 		}
@@ -212,7 +526,17 @@ func Validate(cfg *types.Config) error {
 			if len(pattern.Input) == 0 {
 				return fmt.Errorf("target %d, pattern %d: at least one input pattern is required", i, j)
 			}
-			if len(pattern.Output.Fields) == 0 {
+			switch pattern.Output.Preset {
+			case "", types.PresetAuto, types.PresetEpisode, types.PresetMovie:
+			default:
+				return fmt.Errorf("target %d, pattern %d: unknown output preset %q (use auto, episode or movie)", i, j, pattern.Output.Preset)
+			}
+			switch pattern.Output.ResFormat {
+			case "", types.ResFormatP, types.ResFormatK, types.ResFormatOriginal:
+			default:
+				return fmt.Errorf("target %d, pattern %d: unknown res_format %q (use p, k or original)", i, j, pattern.Output.ResFormat)
+			}
+			if len(pattern.Output.Fields) == 0 && pattern.Output.Preset != types.PresetMovie {
 				return fmt.Errorf("target %d, pattern %d: output fields are required", i, j)
 			}
 		}
@@ -3,7 +3,12 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+
+	"github.com/mydehq/autotitle/internal/matcher"
+	"github.com/mydehq/autotitle/internal/types"
 )
 
 func TestValidate(t *testing.T) {
@@ -226,3 +231,423 @@ func TestGenerateDefaultFieldsSideEffects(t *testing.T) {
 		t.Error("defaultMapFile affected by cfg1 modification! Global Fields slice was mutated.")
 	}
 }
+
+func TestLoadFileExpandsVariables(t *testing.T) {
+	tmpDir := filepath.Join(t.TempDir(), "Naruto")
+	if err := os.MkdirAll(tmpDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	configPath := filepath.Join(tmpDir, "_autotitle.yml")
+	t.Setenv("AUTOTITLE_TEST_MAL_ID", "20")
+
+	content := `targets:
+  - path: "."
+    url: "https://myanimelist.net/anime/${AUTOTITLE_TEST_MAL_ID}"
+    filler_url: "https://animefillerlist.com/shows/${DIRNAME}"
+    patterns:
+      - input: ["Episode {{EP_NUM}}"]
+        output:
+          fields: [SERIES, EP_NUM]
+`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadFile(configPath)
+	if err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+
+	target := cfg.Targets[0]
+	if target.URL != "https://myanimelist.net/anime/20" {
+		t.Errorf("unexpected URL: %s", target.URL)
+	}
+	if target.FillerURL != "https://animefillerlist.com/shows/Naruto" {
+		t.Errorf("unexpected FillerURL: %s", target.FillerURL)
+	}
+}
+
+func TestLoadFileBackupOverrides(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "_autotitle.yml")
+
+	content := `targets:
+  - path: "."
+    url: "https://myanimelist.net/anime/20"
+    backup: false
+    backup_dir: "../backups"
+    patterns:
+      - input: ["Episode {{EP_NUM}}"]
+        output:
+          fields: [SERIES, EP_NUM]
+`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadFile(configPath)
+	if err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+
+	target := cfg.Targets[0]
+	if want := filepath.Join(filepath.Dir(tmpDir), "backups"); target.BackupDir != want {
+		t.Errorf("BackupDir = %q, want %q", target.BackupDir, want)
+	}
+
+	global := GetDefaults().Backup
+	eff := target.EffectiveBackup(global)
+	if eff.Enabled || eff.DirName != target.BackupDir {
+		t.Errorf("EffectiveBackup = %+v, want disabled with dir %q", eff, target.BackupDir)
+	}
+
+	// Targets without overrides inherit the global settings
+	var plain Target
+	if got := plain.EffectiveBackup(global); got != global {
+		t.Errorf("EffectiveBackup without overrides = %+v, want %+v", got, global)
+	}
+}
+
+func TestExpandVarsUndefined(t *testing.T) {
+	if _, err := ExpandVars("https://example.com/${AUTOTITLE_TEST_UNSET}", "/tmp"); err == nil {
+		t.Error("expected error for undefined variable, got nil")
+	}
+
+	got, err := ExpandVars("no variables here", "/tmp")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "no variables here" {
+		t.Errorf("unexpected result: %s", got)
+	}
+}
+
+func TestSaveGlobalRoundTrip(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	path, err := UserGlobalPath()
+	if err != nil {
+		t.Fatalf("UserGlobalPath failed: %v", err)
+	}
+
+	cfg := GetDefaults()
+	cfg.Formats = []string{"mkv"}
+	cfg.Backup.Enabled = false
+	if err := SaveGlobal(path, &cfg); err != nil {
+		t.Fatalf("SaveGlobal failed: %v", err)
+	}
+
+	if got := FindGlobal(); got != path {
+		t.Errorf("FindGlobal() = %q, want %q", got, path)
+	}
+
+	loaded, err := LoadGlobal()
+	if err != nil {
+		t.Fatalf("LoadGlobal failed: %v", err)
+	}
+	if len(loaded.Formats) != 1 || loaded.Formats[0] != "mkv" {
+		t.Errorf("unexpected formats: %v", loaded.Formats)
+	}
+	if loaded.Backup.Enabled {
+		t.Error("expected backup to be disabled")
+	}
+}
+
+func TestLoadGlobalExpandsVars(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("AUTOTITLE_TEST_TRAKT", "secret")
+	t.Setenv("AUTOTITLE_TEST_NAS", "nas.lan")
+
+	path, err := UserGlobalPath()
+	if err != nil {
+		t.Fatalf("UserGlobalPath failed: %v", err)
+	}
+	cfg := GetDefaults()
+	cfg.API.Keys = map[string]string{"trakt": "${AUTOTITLE_TEST_TRAKT}"}
+	cfg.MediaServers = []types.MediaServer{{Type: "plex", URL: "http://${AUTOTITLE_TEST_NAS}:32400", Token: "${AUTOTITLE_TEST_TRAKT}"}}
+	cfg.Events.Sinks = []types.EventSink{
+		{Type: "json", Path: "${HOME}/events.jsonl"},
+		{Type: "webhook", URL: "https://${AUTOTITLE_TEST_NAS}/hook", Secret: "${AUTOTITLE_TEST_TRAKT}"},
+	}
+	cfg.Serve = types.ServeConfig{Token: "${AUTOTITLE_TEST_TRAKT}", TLSCert: "${HOME}/cert.pem"}
+	if err := SaveGlobal(path, &cfg); err != nil {
+		t.Fatalf("SaveGlobal failed: %v", err)
+	}
+
+	loaded, err := LoadGlobal()
+	if err != nil {
+		t.Fatalf("LoadGlobal failed: %v", err)
+	}
+	if loaded.API.Keys["trakt"] != "secret" || loaded.MediaServers[0].URL != "http://nas.lan:32400" || loaded.MediaServers[0].Token != "secret" {
+		t.Errorf("variables not expanded: %+v, %+v", loaded.API.Keys, loaded.MediaServers)
+	}
+	if want := filepath.Join(os.Getenv("HOME"), "events.jsonl"); loaded.Events.Sinks[0].Path != want {
+		t.Errorf("sink path = %q, want %q", loaded.Events.Sinks[0].Path, want)
+	}
+	if s := loaded.Events.Sinks[1]; s.URL != "https://nas.lan/hook" || s.Secret != "secret" {
+		t.Errorf("webhook sink not expanded: %+v", s)
+	}
+	if loaded.Serve.Token != "secret" || loaded.Serve.TLSCert != filepath.Join(os.Getenv("HOME"), "cert.pem") {
+		t.Errorf("serve not expanded: %+v", loaded.Serve)
+	}
+
+	// What an editor saves back keeps the references
+	raw, err := ReadGlobal()
+	if err != nil {
+		t.Fatalf("ReadGlobal failed: %v", err)
+	}
+	if raw.API.Keys["trakt"] != "${AUTOTITLE_TEST_TRAKT}" {
+		t.Errorf("ReadGlobal expanded api.keys: %q", raw.API.Keys["trakt"])
+	}
+
+	cfg.API.Keys["trakt"] = "${AUTOTITLE_TEST_UNSET}"
+	if err := SaveGlobal(path, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadGlobal(); err == nil || !strings.Contains(err.Error(), "api.keys.trakt") {
+		t.Errorf("LoadGlobal = %v, want an error naming api.keys.trakt", err)
+	}
+}
+
+func TestScanSkipsIgnoredDirs(t *testing.T) {
+	dir := t.TempDir()
+	for _, d := range []string{".git", "@eaDir", ".autotitle_backup", "Extras"} {
+		if err := os.Mkdir(filepath.Join(dir, d), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "Episode 01.mkv"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := Scan(dir, []string{"mkv"}, nil)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	// Only the media file and the non-ignored "Extras" directory count
+	if res.TotalFiles != 2 {
+		t.Errorf("TotalFiles = %d, want 2", res.TotalFiles)
+	}
+
+	ig := NewIgnorer(&GlobalConfig{IgnoreDirs: []string{"Extra*"}})
+	if !ig.SkipDir("Extras") || !ig.SkipDir(".autotitle_backup") || ig.SkipDir(".git") {
+		t.Error("custom ignore_dirs should replace the defaults and keep the backup dir")
+	}
+}
+
+func TestScanSkipsMetadataClutter(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "@eaDir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{"Episode 01.mkv", "._Episode 01.mkv", ".DS_Store", "Thumbs.db"} {
+		if err := os.WriteFile(filepath.Join(dir, f), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	res, err := Scan(dir, []string{"mkv"}, nil)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if res.TotalFiles != 1 || len(res.DetectedPatterns) != 1 {
+		t.Errorf("Expected only the real episode to be scanned, got %+v", res)
+	}
+
+	found, err := FindMetadataClutter(dir)
+	if err != nil {
+		t.Fatalf("FindMetadataClutter failed: %v", err)
+	}
+	if len(found) != 4 {
+		t.Errorf("Expected 4 clutter entries, got %v", found)
+	}
+}
+
+func TestResolveOutput(t *testing.T) {
+	episodic := types.OutputConfig{Fields: []string{"SERIES", "EP_NUM", "EP_NAME"}, Separator: " - ", Romanize: true}
+	movieFields := []string{"SERIES", "(YEAR)", "PART"}
+
+	tests := []struct {
+		name      string
+		preset    string
+		fields    []string
+		mediaType types.MediaType
+		want      []string
+	}{
+		{"auto keeps episode fields for anime", "", episodic.Fields, types.MediaTypeAnime, episodic.Fields},
+		{"auto switches episode fields for movies", "", episodic.Fields, types.MediaTypeMovie, movieFields},
+		{"auto keeps movie-friendly fields", types.PresetAuto, []string{"SERIES", "RES"}, types.MediaTypeMovie, []string{"SERIES", "RES"}},
+		{"episode preset never switches", types.PresetEpisode, episodic.Fields, types.MediaTypeMovie, episodic.Fields},
+		{"movie preset always switches", types.PresetMovie, episodic.Fields, types.MediaTypeAnime, movieFields},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := episodic
+			out.Preset = tt.preset
+			out.Fields = tt.fields
+			got := ResolveOutput(out, tt.mediaType)
+			if !slices.Equal(got.Fields, tt.want) {
+				t.Errorf("Fields = %v, want %v", got.Fields, tt.want)
+			}
+			if !got.Romanize {
+				t.Error("Resolving a preset must keep the other output settings")
+			}
+		})
+	}
+}
+
+func TestApplyGlobal(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Cleanup(func() { _ = ApplyGlobal(&types.GlobalConfig{}) })
+
+	path, err := UserGlobalPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := GetDefaults()
+	cfg.Placeholders = []types.PlaceholderDef{{Name: "CRC32", Regex: `[0-9A-F]{8}`}}
+	if err := SaveGlobal(path, &cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	// Loading validates but installs nothing
+	loaded, err := LoadGlobal()
+	if err != nil {
+		t.Fatalf("LoadGlobal failed: %v", err)
+	}
+	p, err := matcher.Compile("Show - {{EP_NUM}} [{{CRC32}}].{{EXT}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := p.MatchTyped("Show - 01 [AB12CD34].mkv"); ok {
+		t.Error("LoadGlobal should not install custom placeholders")
+	}
+
+	if err := ApplyGlobal(loaded); err != nil {
+		t.Fatalf("ApplyGlobal failed: %v", err)
+	}
+	p, err = matcher.Compile("Show - {{EP_NUM}} [{{CRC32}}].{{EXT}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, ok := p.MatchTyped("Show - 01 [AB12CD34].mkv"); !ok || r.Custom["CRC32"] != "AB12CD34" {
+		t.Errorf("ApplyGlobal should install custom placeholders, got %+v, %v", r, ok)
+	}
+
+	cfg.Placeholders = []types.PlaceholderDef{{Name: "EP_NUM", Regex: `\d+`}}
+	if err := SaveGlobal(path, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadGlobal(); err == nil {
+		t.Error("LoadGlobal should reject a placeholder shadowing a built-in one")
+	}
+}
+
+func TestPinGlobal(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Cleanup(UnpinGlobal)
+
+	path, err := UserGlobalPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := GetDefaults()
+	if err := SaveGlobal(path, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	old, pinnedCfg, err := PinGlobal()
+	if err != nil || old != nil || pinnedCfg == nil {
+		t.Fatalf("PinGlobal = %v, %v, %v; want the config pinned", old, pinnedCfg, err)
+	}
+
+	// A file caught halfway through an edit never reaches LoadGlobal
+	if err := os.WriteFile(path, []byte("api: [unclosed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadGlobal(); err != nil {
+		t.Errorf("LoadGlobal = %v, want the pinned config", err)
+	}
+	if _, kept, err := PinGlobal(); err == nil || kept != pinnedCfg {
+		t.Errorf("PinGlobal = %v, %v; want an error and the old config kept", kept, err)
+	}
+
+	cfg.API.RateLimit = 1
+	cfg.MapFile = ".autotitle.yml"
+	if err := SaveGlobal(path, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	old, reloaded, err := PinGlobal()
+	if err != nil {
+		t.Fatalf("PinGlobal failed: %v", err)
+	}
+	if got := Changed(old, reloaded); !slices.Equal(got, []string{"map_file", "api"}) {
+		t.Errorf("Changed = %v, want [map_file api]", got)
+	}
+	if loaded, _ := LoadGlobal(); loaded.MapFile != ".autotitle.yml" {
+		t.Errorf("LoadGlobal map_file = %q, want the reloaded config", loaded.MapFile)
+	}
+}
+
+func TestExpandPath(t *testing.T) {
+	media := &types.Media{Title: "Frieren", Year: 2023, Season: "Fall"}
+
+	if !IsPathTemplate("{{YEAR}}/{{SEASON_NAME}}/*") || IsPathTemplate("Season 2") {
+		t.Error("IsPathTemplate misclassified a path")
+	}
+	if got := PathGlob("{{YEAR}}/{{SEASON_NAME}}/*"); got != "*/*/*" {
+		t.Errorf("PathGlob = %q", got)
+	}
+
+	got, err := ExpandPath("{{YEAR}}/{{SEASON_NAME}}/*", media)
+	if err != nil || got != "2023/Fall/*" {
+		t.Errorf("ExpandPath = %q, %v", got, err)
+	}
+	if _, err := ExpandPath("{{STUDIO}}/*", media); err == nil {
+		t.Error("expected error for an unknown field")
+	}
+	if _, err := ExpandPath("{{CRC32}}/*", media); err == nil || !strings.Contains(err.Error(), "{{CRC32}}") {
+		t.Errorf("ExpandPath = %v, want an error naming {{CRC32}}", err)
+	}
+	if _, err := ExpandPath("{{SEASON_NAME}}/*", &types.Media{Title: "Old"}); err == nil {
+		t.Error("expected error for a missing season")
+	}
+}
+
+func TestFindTemplateConfig(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	root := t.TempDir()
+	series := filepath.Join(root, "2023", "Fall", "Frieren")
+	if err := os.MkdirAll(series, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg, err := FindTemplateConfig(series); cfg != nil || err != nil {
+		t.Fatalf("expected no config, got %v, %v", cfg, err)
+	}
+
+	content := `targets:
+  - path: "{{YEAR}}/{{SEASON_NAME}}/*"
+    url: "https://myanimelist.net/anime/52991"
+    patterns:
+      - input: ["Episode {{EP_NUM}}"]
+        output:
+          fields: [SERIES, EP_NUM]
+`
+	if err := os.WriteFile(filepath.Join(root, "_autotitle.yml"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// A plain map file in between doesn't govern the series folder
+	plain := "targets:\n  - path: \".\"\n    url: \"https://myanimelist.net/anime/1\"\n    patterns:\n      - input: [\"{{EP_NUM}}\"]\n        output:\n          fields: [EP_NUM]\n"
+	if err := os.WriteFile(filepath.Join(root, "2023", "_autotitle.yml"), []byte(plain), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := FindTemplateConfig(series)
+	if err != nil || cfg == nil {
+		t.Fatalf("expected the root config, got %v, %v", cfg, err)
+	}
+	if cfg.BaseDir != root {
+		t.Errorf("expected base dir %s, got %s", root, cfg.BaseDir)
+	}
+}
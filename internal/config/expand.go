@@ -0,0 +1,138 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/mydehq/autotitle/internal/types"
+)
+
+// reVariable matches ${NAME} references inside config string values
+var reVariable = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// builtinVars returns the built-in variables available to a map file located in baseDir.
+// Built-ins take precedence over environment variables of the same name.
+func builtinVars(baseDir string) map[string]string {
+	vars := map[string]string{
+		"DIRNAME": filepath.Base(baseDir),
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		vars["HOME"] = home
+	}
+	return vars
+}
+
+// ExpandVars replaces ${NAME} references in s using the built-in variables
+// for baseDir, falling back to the environment. It returns an error naming
+// the first variable that is neither built-in nor set in the environment.
+func ExpandVars(s, baseDir string) (string, error) {
+	builtins := builtinVars(baseDir)
+
+	var missing string
+	out := reVariable.ReplaceAllStringFunc(s, func(m string) string {
+		name := reVariable.FindStringSubmatch(m)[1]
+		if val, ok := builtins[name]; ok {
+			return val
+		}
+		if val, ok := os.LookupEnv(name); ok {
+			return val
+		}
+		if missing == "" {
+			missing = name
+		}
+		return m
+	})
+
+	if missing != "" {
+		return "", fmt.Errorf("undefined variable ${%s}", missing)
+	}
+	return out, nil
+}
+
+// expandGlobal interpolates variables in the string values of the global
+// config that name places or secrets: backup.dir_name, api.keys, api.users,
+// api.base_urls, events.sinks[].path and media_servers[].url/token/paths.
+// Only the environment (and HOME) is available; there is no map file dir.
+func expandGlobal(cfg *types.GlobalConfig) error {
+	expand := func(field string, s *string) error {
+		val, err := ExpandVars(*s, "")
+		if err != nil {
+			return fmt.Errorf("%s: %w", field, err)
+		}
+		*s = val
+		return nil
+	}
+	expandMap := func(field string, m map[string]string) error {
+		for k, v := range m {
+			if err := expand(field+"."+k, &v); err != nil {
+				return err
+			}
+			m[k] = v
+		}
+		return nil
+	}
+
+	if err := expand("backup.dir_name", &cfg.Backup.DirName); err != nil {
+		return err
+	}
+	if err := expandMap("api.keys", cfg.API.Keys); err != nil {
+		return err
+	}
+	if err := expandMap("api.users", cfg.API.Users); err != nil {
+		return err
+	}
+	if err := expandMap("api.base_urls", cfg.API.BaseURLs); err != nil {
+		return err
+	}
+	for i := range cfg.Events.Sinks {
+		s := &cfg.Events.Sinks[i]
+		field := fmt.Sprintf("events.sinks[%d]", i)
+		if err := expand(field+".path", &s.Path); err != nil {
+			return err
+		}
+		if err := expand(field+".url", &s.URL); err != nil {
+			return err
+		}
+		if err := expand(field+".secret", &s.Secret); err != nil {
+			return err
+		}
+	}
+	for field, s := range map[string]*string{
+		"serve.token": &cfg.Serve.Token, "serve.tls_cert": &cfg.Serve.TLSCert, "serve.tls_key": &cfg.Serve.TLSKey,
+	} {
+		if err := expand(field, s); err != nil {
+			return err
+		}
+	}
+	for i := range cfg.MediaServers {
+		s := &cfg.MediaServers[i]
+		field := fmt.Sprintf("media_servers[%d]", i)
+		if err := expand(field+".url", &s.URL); err != nil {
+			return err
+		}
+		if err := expand(field+".token", &s.Token); err != nil {
+			return err
+		}
+		if err := expandMap(field+".paths", s.Paths); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expandConfig interpolates variables in the string values of every target.
+func expandConfig(cfg *types.Config, path string) error {
+	for i := range cfg.Targets {
+		t := &cfg.Targets[i]
+		for _, field := range []*string{&t.Path, &t.URL, &t.FillerURL, &t.BackupDir} {
+			val, err := ExpandVars(*field, cfg.BaseDir)
+			if err != nil {
+				return types.ErrConfigInvalid{Path: path, Reason: fmt.Sprintf("target %d: %v", i, err)}
+			}
+			*field = val
+		}
+	}
+	return nil
+}
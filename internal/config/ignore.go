@@ -0,0 +1,116 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/mydehq/autotitle/internal/types"
+)
+
+// metadataFiles are clutter files written by NAS indexers and desktop OSes
+// (Synology, QNAP, macOS, Windows). They are never scanned, matched or backed up.
+var metadataFiles = []string{".DS_Store", "._*", "Thumbs.db", "desktop.ini"}
+
+// metadataDirs are clutter directories generated the same way
+var metadataDirs = []string{"@eaDir", ".@__thumb", ".AppleDouble"}
+
+// IsMetadataClutter reports whether a directory entry is NAS/OS-generated metadata
+func IsMetadataClutter(name string, isDir bool) bool {
+	patterns := metadataFiles
+	if isDir {
+		patterns = metadataDirs
+	}
+	return matchAny(patterns, name)
+}
+
+// Ignorer decides which directory entries are skipped by every directory walk:
+// the configured ignore_dirs patterns, the backup and snapshot directories and
+// metadata clutter.
+type Ignorer struct {
+	dirs []string
+}
+
+// NewIgnorer builds the ignore rules from a global config (defaults if nil)
+func NewIgnorer(global *types.GlobalConfig) *Ignorer {
+	if global == nil {
+		d := GetDefaults()
+		global = &d
+	}
+
+	dirs := append([]string{}, global.IgnoreDirs...)
+	dirs = append(dirs, types.BtrfsSnapshotDir)
+
+	// An absolute backup dir is outside the tree; a relative one sits in each media dir
+	backupDir := global.Backup.DirName
+	if backupDir == "" {
+		backupDir = defaults.Backup.DirName
+	}
+	if !filepath.IsAbs(backupDir) {
+		dirs = append(dirs, backupDir)
+	}
+
+	return &Ignorer{dirs: dirs}
+}
+
+// WithBackupDir also ignores dirName, e.g. a per-target backup_dir inside the tree
+func (ig *Ignorer) WithBackupDir(dirName string) *Ignorer {
+	if dirName != "" && !filepath.IsAbs(dirName) {
+		ig.dirs = append(ig.dirs, dirName)
+	}
+	return ig
+}
+
+// SkipDir reports whether a directory with the given base name is ignored.
+// Patterns use filepath.Match syntax (e.g. ".*", "@*").
+func (ig *Ignorer) SkipDir(name string) bool {
+	return matchAny(ig.dirs, name) || IsMetadataClutter(name, true)
+}
+
+// ReadDir lists dir like os.ReadDir, without ignored directories and clutter files
+func (ig *Ignorer) ReadDir(dir string) ([]os.DirEntry, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	kept := entries[:0]
+	for _, e := range entries {
+		if e.IsDir() && ig.SkipDir(e.Name()) {
+			continue
+		}
+		if !e.IsDir() && IsMetadataClutter(e.Name(), false) {
+			continue
+		}
+		kept = append(kept, e)
+	}
+	return kept, nil
+}
+
+// FindMetadataClutter lists the NAS/OS metadata files and directories directly in dir
+func FindMetadataClutter(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var found []string
+	for _, e := range entries {
+		if IsMetadataClutter(e.Name(), e.IsDir()) {
+			found = append(found, filepath.Join(dir, e.Name()))
+		}
+	}
+	return found, nil
+}
+
+// matchAny reports whether name equals or matches (filepath.Match) any pattern
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if pattern == name {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
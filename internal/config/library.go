@@ -0,0 +1,93 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mydehq/autotitle/internal/types"
+)
+
+// resolveLibraries checks the libraries of the global config and resolves
+// their roots: Path made absolute, with ~/ and ${VAR} expanded. Path itself
+// is kept as written, so a config saved back keeps its variables.
+func resolveLibraries(libs []types.Library) error {
+	seen := make(map[string]bool, len(libs))
+	for i := range libs {
+		l := &libs[i]
+		if l.Name == "" {
+			return fmt.Errorf("library %d: name is required", i+1)
+		}
+		if strings.ContainsAny(l.Name, `/\`) {
+			return fmt.Errorf("library %q: name cannot contain a path separator", l.Name)
+		}
+		key := strings.ToLower(l.Name)
+		if seen[key] {
+			return fmt.Errorf("library %q is defined twice", l.Name)
+		}
+		seen[key] = true
+
+		switch l.Preset {
+		case "", types.PresetAuto, types.PresetEpisode, types.PresetMovie:
+		default:
+			return fmt.Errorf("library %q: unknown output preset %q (use auto, episode or movie)", l.Name, l.Preset)
+		}
+
+		if l.Path == "" {
+			return fmt.Errorf("library %q: path is required", l.Name)
+		}
+		path, err := ExpandVars(l.Path, "")
+		if err != nil {
+			return fmt.Errorf("library %q: %w", l.Name, err)
+		}
+		if rest, ok := strings.CutPrefix(path, "~/"); ok {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return err
+			}
+			path = filepath.Join(home, rest)
+		}
+		if l.Root, err = filepath.Abs(path); err != nil {
+			return fmt.Errorf("library %q: %w", l.Name, err)
+		}
+	}
+	return nil
+}
+
+// FindLibrary returns the library of cfg called name (ignoring case), or nil
+func FindLibrary(cfg *types.GlobalConfig, name string) *types.Library {
+	if cfg == nil {
+		return nil
+	}
+	for i := range cfg.Libraries {
+		if strings.EqualFold(cfg.Libraries[i].Name, name) {
+			return &cfg.Libraries[i]
+		}
+	}
+	return nil
+}
+
+// LibraryOf returns the library whose root is path or contains it, the
+// deepest if libraries are nested, or nil
+func LibraryOf(cfg *types.GlobalConfig, path string) *types.Library {
+	if cfg == nil {
+		return nil
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil
+	}
+	var found *types.Library
+	for i := range cfg.Libraries {
+		l := &cfg.Libraries[i]
+		rel, err := filepath.Rel(l.Root, abs)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if found == nil || len(l.Root) > len(found.Root) {
+			found = l
+		}
+	}
+	return found
+}
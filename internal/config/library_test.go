@@ -0,0 +1,78 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/mydehq/autotitle/internal/types"
+)
+
+func TestResolveLibraries(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("MEDIA", "/srv/media")
+
+	libs := []types.Library{
+		{Name: "Anime", Path: "~/Anime", Preset: types.PresetEpisode},
+		{Name: "Movies", Path: "${MEDIA}/Movies"},
+	}
+	if err := resolveLibraries(libs); err != nil {
+		t.Fatalf("resolveLibraries failed: %v", err)
+	}
+	if want := filepath.Join(home, "Anime"); libs[0].Root != want {
+		t.Errorf("Anime root = %q, want %q", libs[0].Root, want)
+	}
+	if libs[1].Root != "/srv/media/Movies" {
+		t.Errorf("Movies root = %q, want /srv/media/Movies", libs[1].Root)
+	}
+	if libs[0].Path != "~/Anime" || libs[1].Path != "${MEDIA}/Movies" {
+		t.Errorf("paths = %q, %q; want them kept as written", libs[0].Path, libs[1].Path)
+	}
+
+	invalid := map[string][]types.Library{
+		"no name":   {{Path: "/a"}},
+		"no path":   {{Name: "a"}},
+		"separator": {{Name: "a/b", Path: "/a"}},
+		"duplicate": {{Name: "TV", Path: "/a"}, {Name: "tv", Path: "/b"}},
+		"preset":    {{Name: "a", Path: "/a", Preset: "music"}},
+		"variable":  {{Name: "a", Path: "${UNSET_LIBRARY_ROOT}/a"}},
+	}
+	for name, libs := range invalid {
+		if err := resolveLibraries(libs); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestLibraryOf(t *testing.T) {
+	cfg := &types.GlobalConfig{Libraries: []types.Library{
+		{Name: "Media", Path: "/srv/media", Root: "/srv/media"},
+		{Name: "Anime", Path: "/srv/media/anime", Root: "/srv/media/anime"},
+		{Name: "Movies", Path: "/srv/movies", Root: "/srv/movies"},
+	}}
+
+	tests := map[string]string{
+		"/srv/media/anime/Frieren": "Anime",
+		"/srv/media/anime":         "Anime",
+		"/srv/media/tv/Severance":  "Media",
+		"/srv/movies":              "Movies",
+		"/srv/movies-old/Akira":    "",
+		"/home/user":               "",
+	}
+	for path, want := range tests {
+		got := ""
+		if lib := LibraryOf(cfg, path); lib != nil {
+			got = lib.Name
+		}
+		if got != want {
+			t.Errorf("LibraryOf(%q) = %q, want %q", path, got, want)
+		}
+	}
+
+	if lib := FindLibrary(cfg, "anime"); lib == nil || lib.Name != "Anime" {
+		t.Errorf("FindLibrary(anime) = %v, want Anime", lib)
+	}
+	if lib := FindLibrary(cfg, "tv"); lib != nil {
+		t.Errorf("FindLibrary(tv) = %v, want nil", lib)
+	}
+}
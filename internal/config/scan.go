@@ -1,7 +1,6 @@
 package config
 
 import (
-	"os"
 	"path/filepath"
 	"slices"
 
@@ -16,9 +15,14 @@ type ScanResult struct {
 }
 
 // Scan scans a directory for media files and guesses renaming patterns.
-// It uses the provided formats list to identify relevant files.
-func Scan(dir string, formats []string) (*ScanResult, error) {
-	entries, err := os.ReadDir(dir)
+// It uses the provided formats list to identify relevant files and skips
+// entries ignored by ig (the default rules if nil).
+func Scan(dir string, formats []string, ig *Ignorer) (*ScanResult, error) {
+	if ig == nil {
+		ig = NewIgnorer(nil)
+	}
+
+	entries, err := ig.ReadDir(dir)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/mydehq/autotitle/internal/matcher"
+	"github.com/mydehq/autotitle/internal/types"
+)
+
+// rePathField matches {{FIELD}} placeholders in a target path
+var rePathField = matcher.RePlaceholder
+
+// IsPathTemplate reports whether a target path is a template: it has
+// {{FIELD}} placeholders or glob wildcards, so one target can govern a
+// series folder anywhere in a layout like "{{YEAR}}/{{SEASON_NAME}}/*".
+func IsPathTemplate(path string) bool {
+	return rePathField.MatchString(path) || strings.ContainsAny(path, "*?[")
+}
+
+// PathGlob turns the placeholders of a target path template into wildcards,
+// to rule out directories before any metadata is fetched
+func PathGlob(path string) string {
+	return rePathField.ReplaceAllString(path, "*")
+}
+
+// ExpandPath fills the placeholders of a target path template from the
+// target's media: YEAR (premiere year) and SEASON_NAME (premiere season,
+// e.g. Fall). Wildcards are left for matching.
+func ExpandPath(path string, media *types.Media) (string, error) {
+	var err error
+	out := rePathField.ReplaceAllStringFunc(path, func(m string) string {
+		field := rePathField.FindStringSubmatch(m)[1]
+		var val string
+		switch field {
+		case "YEAR":
+			if media.Year > 0 {
+				val = strconv.Itoa(media.Year)
+			}
+		case "SEASON_NAME":
+			val = media.Season
+		default:
+			if err == nil {
+				err = fmt.Errorf("unknown path field {{%s}} (use YEAR or SEASON_NAME)", field)
+			}
+			return m
+		}
+		if val == "" && err == nil {
+			err = fmt.Errorf("%s has no %s to fill {{%s}}", media.Title, strings.ToLower(field), field)
+		}
+		return val
+	})
+	return out, err
+}
+
+// FindTemplateConfig looks for the map file governing dir from above: the
+// closest one in a parent directory with a path template target. It returns
+// nil if there is none.
+func FindTemplateConfig(dir string) (*types.Config, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for parent := filepath.Dir(abs); parent != abs; abs, parent = parent, filepath.Dir(parent) {
+		path := MapFilePath(parent)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		cfg, err := LoadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range cfg.Targets {
+			if IsPathTemplate(t.Path) {
+				return cfg, nil
+			}
+		}
+	}
+	return nil, nil
+}
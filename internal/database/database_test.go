@@ -4,6 +4,7 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
 	"time"
 
@@ -99,6 +100,53 @@ func TestRepository_Search(t *testing.T) {
 	}
 }
 
+func TestRepository_SearchAliasesAndRanking(t *testing.T) {
+	repo, err := database.NewRepository(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewRepository failed: %v", err)
+	}
+
+	ctx := context.Background()
+	entries := []*types.Media{
+		{ID: "16498", Provider: "mal", Title: "Attack on Titan", TitleJP: "進撃の巨人",
+			Aliases: []string{"Shingeki no Kyojin"}, Slug: "attack-on-titan"},
+		{ID: "25777", Provider: "mal", Title: "Shingeki no Kyojin Season 2", Slug: "shingeki-no-kyojin-season-2"},
+		{ID: "31240", Provider: "mal", Title: "Re:Zero kara Hajimeru Isekai Seikatsu", Slug: "rezero-kara-hajimeru-isekai-seikatsu"},
+	}
+	for _, m := range entries {
+		if err := repo.Save(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		want  []string // IDs in rank order
+	}{
+		{"shingeki", []string{"25777", "16498"}},           // Title prefix beats alias prefix
+		{"Shingeki no Kyojin", []string{"16498", "25777"}}, // Exact alias beats title prefix
+		{"進撃", []string{"16498"}},
+		{"rezero", []string{"31240"}},
+		{"16498", []string{"16498"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			results, err := repo.Search(ctx, tt.query)
+			if err != nil {
+				t.Fatalf("Search failed: %v", err)
+			}
+			var got []string
+			for _, r := range results {
+				got = append(got, r.ID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestRepository_Delete(t *testing.T) {
 	tmpDir := t.TempDir()
 	repo, err := database.NewRepository(tmpDir)
@@ -122,3 +170,192 @@ func TestRepository_Delete(t *testing.T) {
 		t.Error("Exists returned true after delete")
 	}
 }
+
+func TestRepository_Partial(t *testing.T) {
+	repo, err := database.NewRepository(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewRepository failed: %v", err)
+	}
+
+	ctx := context.Background()
+	partial := &types.Media{
+		ID:         "21",
+		Provider:   "mal",
+		Title:      "One Piece",
+		Slug:       "one-piece",
+		Episodes:   []types.Episode{{Number: 1, Title: "Ep 1"}},
+		ResumePage: 3,
+	}
+
+	if err := repo.SavePartial(ctx, partial); err != nil {
+		t.Fatalf("SavePartial failed: %v", err)
+	}
+
+	// A partial record must not look like a complete entry
+	if repo.Exists("mal", "21") {
+		t.Error("Exists should ignore partial records")
+	}
+
+	loaded, err := repo.LoadPartial(ctx, "mal", "21")
+	if err != nil {
+		t.Fatalf("LoadPartial failed: %v", err)
+	}
+	if loaded == nil || loaded.ResumePage != 3 || len(loaded.Episodes) != 1 {
+		t.Fatalf("Unexpected partial record: %+v", loaded)
+	}
+
+	if err := repo.DeletePartial("mal", "21"); err != nil {
+		t.Fatalf("DeletePartial failed: %v", err)
+	}
+	if loaded, _ := repo.LoadPartial(ctx, "mal", "21"); loaded != nil {
+		t.Error("Expected no partial record after DeletePartial")
+	}
+}
+
+func TestRepository_Stats(t *testing.T) {
+	repo, err := database.NewRepository(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewRepository failed: %v", err)
+	}
+
+	ctx := context.Background()
+	older := time.Now().Add(-48 * time.Hour).Truncate(time.Second)
+	newer := time.Now().Truncate(time.Second)
+	future := time.Now().Add(72 * time.Hour).Format(time.RFC3339)
+
+	entries := []*types.Media{
+		{ID: "1", Provider: "mal", Title: "Finished", Slug: "finished", Status: "Finished Airing",
+			Episodes: []types.Episode{{Number: 1}, {Number: 2}}, LastUpdate: older},
+		{ID: "2", Provider: "mal", Title: "Airing", Slug: "airing", Status: "Currently Airing",
+			Episodes: []types.Episode{{Number: 1}}, LastUpdate: newer},
+		{ID: "3", Provider: "tmdb", Title: "Waiting", Slug: "waiting", Status: "Currently Airing",
+			NextEpisodeAirDate: &future, Episodes: []types.Episode{{Number: 1}}, LastUpdate: newer},
+	}
+	for _, m := range entries {
+		if err := repo.Save(ctx, m); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+	}
+
+	stats, err := repo.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+
+	if stats.Series != 3 || stats.Providers["mal"] != 2 || stats.Providers["tmdb"] != 1 {
+		t.Errorf("Unexpected series counts: %d total, %v", stats.Series, stats.Providers)
+	}
+	if stats.Episodes != 4 {
+		t.Errorf("Expected 4 episodes, got %d", stats.Episodes)
+	}
+	if stats.DiskUsage == 0 {
+		t.Error("Expected non-zero disk usage")
+	}
+	if !stats.Oldest.Equal(older) || !stats.Newest.Equal(newer) {
+		t.Errorf("Unexpected update range: %v - %v", stats.Oldest, stats.Newest)
+	}
+	if len(stats.DueForRefresh) != 1 || stats.DueForRefresh[0].ID != "2" {
+		t.Errorf("Expected only ID 2 due for refresh, got %v", stats.DueForRefresh)
+	}
+}
+
+func TestRepository_MigratesEmptySlugs(t *testing.T) {
+	tmpDir := t.TempDir()
+	ctx := context.Background()
+
+	// Written by an older version that stripped all non-ASCII from slugs
+	providerDir := filepath.Join(tmpDir, "mal")
+	if err := os.MkdirAll(providerDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	legacy := `{"id": "42", "provider": "mal", "title": "ドラゴンボール", "slug": ""}`
+	if err := os.WriteFile(filepath.Join(providerDir, "42@.json"), []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := database.NewRepository(tmpDir)
+	if err != nil {
+		t.Fatalf("NewRepository failed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(providerDir, "42@.json")); !os.IsNotExist(err) {
+		t.Error("Empty-slug file should have been migrated")
+	}
+	if _, err := os.Stat(filepath.Join(providerDir, "42@doragonbooru.json")); err != nil {
+		t.Errorf("Expected migrated file: %v", err)
+	}
+
+	loaded, err := repo.Load(ctx, "mal", "42")
+	if err != nil || loaded == nil || loaded.Slug != "doragonbooru" {
+		t.Errorf("Expected migrated entry with slug, got %+v (%v)", loaded, err)
+	}
+}
+
+func TestNeedsRefresh_AirDateBoundary(t *testing.T) {
+	next := "2024-01-10T00:00:00+00:00"
+	media := &types.Media{Status: "Currently Airing", NextEpisodeAirDate: &next, AirTimeZone: "Asia/Tokyo"}
+
+	// Jikan's midnight UTC stamp is not the broadcast time; the episode is
+	// only certain to have aired once January 10 is over in Tokyo (15:00 UTC)
+	tests := []struct {
+		now  time.Time
+		want bool
+	}{
+		{time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC), false},
+		{time.Date(2024, 1, 10, 14, 59, 59, 0, time.UTC), false},
+		{time.Date(2024, 1, 10, 15, 0, 0, 0, time.UTC), true},
+		{time.Date(2024, 1, 11, 9, 0, 0, 0, time.FixedZone("JST", 9*3600)), true},
+	}
+	for _, tt := range tests {
+		if got := database.NeedsRefresh(media, tt.now); got != tt.want {
+			t.Errorf("NeedsRefresh at %v = %v; want %v", tt.now, got, tt.want)
+		}
+	}
+
+	// Entries cached without a zone use the end of the UTC day
+	media.AirTimeZone = ""
+	if database.NeedsRefresh(media, time.Date(2024, 1, 10, 23, 59, 0, 0, time.UTC)) {
+		t.Error("Expected no refresh before the UTC day is over")
+	}
+}
+
+func TestRepository_Evict(t *testing.T) {
+	tmpDir := t.TempDir()
+	repo, err := database.NewRepository(tmpDir)
+	if err != nil {
+		t.Fatalf("NewRepository failed: %v", err)
+	}
+	ctx := context.Background()
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, id := range []string{"1", "2", "3"} {
+		if err := repo.Save(ctx, &types.Media{ID: id, Provider: "mal", Title: "Show " + id, Slug: "show-" + id}); err != nil {
+			t.Fatal(err)
+		}
+		at := base.Add(time.Duration(i) * time.Hour)
+		repo.WithClock(types.NowFunc(func() time.Time { return at })).Touch("mal", id)
+	}
+	// Using the oldest entry makes 2 the least recently used
+	repo.WithClock(types.NowFunc(func() time.Time { return base.Add(5 * time.Hour) })).Touch("mal", "1")
+
+	stats, err := repo.CacheStats(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Files != 3 || !stats.LeastRecent.Equal(base.Add(time.Hour)) {
+		t.Fatalf("Unexpected stats %+v", stats)
+	}
+
+	// Room for two entries, and 3 must stay even though it isn't the newest
+	removed, err := repo.Evict(stats.Size-1, "mal/3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 1 || repo.Exists("mal", "2") || !repo.Exists("mal", "1") || !repo.Exists("mal", "3") {
+		t.Errorf("Expected only entry 2 evicted, removed %v", removed)
+	}
+
+	if removed, _ := repo.Evict(0, ""); removed != nil {
+		t.Errorf("Expected no eviction without a cap, removed %v", removed)
+	}
+}
@@ -0,0 +1,48 @@
+package database
+
+import (
+	"github.com/mydehq/autotitle/internal/types"
+	"github.com/mydehq/autotitle/internal/util"
+)
+
+// DetectRenumbering compares a refetched entry with the cached one and reports
+// the episodes the provider removed or moved to another number. It returns
+// nil when the numbering is consistent; new episodes and edited titles are
+// not renumbering.
+func DetectRenumbering(cached, fetched *types.Media) *types.Renumbering {
+	if cached == nil || fetched == nil || len(cached.Episodes) == 0 {
+		return nil
+	}
+
+	// Titles are compared by slug so case and punctuation fixes don't count
+	fetchedNums := make(map[int]string, len(fetched.Episodes))
+	fetchedTitles := make(map[string]int, len(fetched.Episodes))
+	for _, ep := range fetched.Episodes {
+		slug := util.Slugify(ep.Title)
+		fetchedNums[ep.Number] = slug
+		if _, ok := fetchedTitles[slug]; slug != "" && !ok {
+			fetchedTitles[slug] = ep.Number
+		}
+	}
+
+	r := &types.Renumbering{OldCount: len(cached.Episodes), NewCount: len(fetched.Episodes)}
+	for _, ep := range cached.Episodes {
+		slug, listed := fetchedNums[ep.Number]
+		if !listed {
+			r.Removed = append(r.Removed, ep.Number)
+			continue
+		}
+		old := util.Slugify(ep.Title)
+		if old == "" || old == slug {
+			continue
+		}
+		if n, ok := fetchedTitles[old]; ok && n != ep.Number {
+			r.Moved = append(r.Moved, ep.Number)
+		}
+	}
+
+	if len(r.Removed) == 0 && len(r.Moved) == 0 {
+		return nil
+	}
+	return r
+}
@@ -9,16 +9,25 @@ import (
 	"path/filepath"
 	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/mydehq/autotitle/internal/types"
+	"github.com/mydehq/autotitle/internal/util"
 )
 
 // Repository implements types.DatabaseRepository
 type Repository struct {
 	baseDir string
+	clock   types.Clock
 }
 
-// NewRepository creates a new database repository
+// readOnlySaves holds the media saved in read-only mode, by file path
+// pattern, so a fetch is still usable for the rest of the process
+var readOnlySaves sync.Map
+
+// NewRepository creates a new database repository. In read-only mode
+// (util.SetReadOnly) saves are kept in memory instead of written.
 func NewRepository(customDir string) (*Repository, error) {
 	dir := customDir
 	if dir == "" {
@@ -29,15 +38,51 @@ func NewRepository(customDir string) (*Repository, error) {
 		dir = filepath.Join(home, ".cache", "autotitle", "db")
 	}
 
+	r := &Repository{baseDir: dir, clock: types.SystemClock{}}
+	if util.ReadOnly() {
+		return r, nil
+	}
+
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create database directory: %w", err)
 	}
+	r.migrateEmptySlugs()
+	return r, nil
+}
+
+// migrateEmptySlugs renames "{ID}@.json" files written when non-ASCII titles
+// produced empty slugs. Failures are ignored; the file stays loadable.
+func (r *Repository) migrateEmptySlugs() {
+	matches, _ := filepath.Glob(filepath.Join(r.baseDir, "*", "*@.json"))
+	for _, path := range matches {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var media types.Media
+		if err := json.Unmarshal(data, &media); err != nil || media.ID == "" || media.Provider == "" {
+			continue
+		}
+		if media.Slug = util.Slugify(media.Title); media.Slug == "" {
+			continue
+		}
+		_ = r.Save(context.Background(), &media)
+	}
+}
 
-	return &Repository{baseDir: dir}, nil
+// WithClock sets the clock used to decide which entries are due for refresh
+func (r *Repository) WithClock(c types.Clock) *Repository {
+	r.clock = c
+	return r
 }
 
 // Save saves media data to the database
 func (r *Repository) Save(ctx context.Context, media *types.Media) error {
+	if util.ReadOnly() {
+		saved := *media
+		readOnlySaves.Store(r.pattern(media.Provider, media.ID), &saved)
+		return nil
+	}
 
 	// Create provider subdirectory
 	providerDir := filepath.Join(r.baseDir, media.Provider)
@@ -74,10 +119,18 @@ func (r *Repository) Save(ctx context.Context, media *types.Media) error {
 	return nil
 }
 
+// pattern returns the glob matching the database files of an entry
+func (r *Repository) pattern(provider, id string) string {
+	return filepath.Join(r.baseDir, provider, id+"@*.json")
+}
+
 // Load loads media data from the database
 func (r *Repository) Load(ctx context.Context, provider, id string) (*types.Media, error) {
-	providerDir := filepath.Join(r.baseDir, provider)
-	pattern := filepath.Join(providerDir, id+"@*.json")
+	pattern := r.pattern(provider, id)
+	if saved, ok := readOnlySaves.Load(pattern); ok && util.ReadOnly() {
+		media := *saved.(*types.Media)
+		return &media, nil
+	}
 
 	matches, err := filepath.Glob(pattern)
 	if err != nil {
@@ -109,14 +162,19 @@ func (r *Repository) Load(ctx context.Context, provider, id string) (*types.Medi
 
 // Exists checks if a database entry exists
 func (r *Repository) Exists(provider, id string) bool {
-	providerDir := filepath.Join(r.baseDir, provider)
-	pattern := filepath.Join(providerDir, id+"@*.json")
+	pattern := r.pattern(provider, id)
+	if _, ok := readOnlySaves.Load(pattern); ok && util.ReadOnly() {
+		return true
+	}
 	matches, _ := filepath.Glob(pattern)
 	return len(matches) > 0
 }
 
 // Delete removes a database entry
 func (r *Repository) Delete(ctx context.Context, provider, id string) error {
+	if err := util.CheckWritable("delete database entries"); err != nil {
+		return err
+	}
 	providerDir := filepath.Join(r.baseDir, provider)
 	pattern := filepath.Join(providerDir, id+"@*.json")
 
@@ -138,8 +196,66 @@ func (r *Repository) Delete(ctx context.Context, provider, id string) error {
 	return nil
 }
 
+// partialPath returns the path of the resume record for an interrupted fetch
+func (r *Repository) partialPath(provider, id string) string {
+	return filepath.Join(r.baseDir, provider, id+".partial")
+}
+
+// SavePartial stores an incomplete fetch so a later run can resume it
+func (r *Repository) SavePartial(ctx context.Context, media *types.Media) error {
+	if util.ReadOnly() {
+		return nil // Nothing to resume from; the next run fetches again
+	}
+	if err := os.MkdirAll(filepath.Join(r.baseDir, media.Provider), 0755); err != nil {
+		return fmt.Errorf("failed to create provider directory: %w", err)
+	}
+
+	data, err := json.Marshal(media)
+	if err != nil {
+		return fmt.Errorf("failed to marshal partial media data: %w", err)
+	}
+
+	if err := os.WriteFile(r.partialPath(media.Provider, media.ID), data, 0644); err != nil {
+		return fmt.Errorf("failed to write partial database file: %w", err)
+	}
+
+	return nil
+}
+
+// LoadPartial loads the resume record for an interrupted fetch, or nil if none exists
+func (r *Repository) LoadPartial(ctx context.Context, provider, id string) (*types.Media, error) {
+	data, err := os.ReadFile(r.partialPath(provider, id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read partial database file: %w", err)
+	}
+
+	var media types.Media
+	if err := json.Unmarshal(data, &media); err != nil {
+		return nil, fmt.Errorf("failed to parse partial database file: %w", err)
+	}
+
+	return &media, nil
+}
+
+// DeletePartial removes the resume record for a fetch, if any
+func (r *Repository) DeletePartial(provider, id string) error {
+	if util.ReadOnly() {
+		return nil
+	}
+	if err := os.Remove(r.partialPath(provider, id)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete partial database file: %w", err)
+	}
+	return nil
+}
+
 // DeleteAll removes all database entries
 func (r *Repository) DeleteAll(ctx context.Context) error {
+	if err := util.CheckWritable("delete database entries"); err != nil {
+		return err
+	}
 	entries, err := os.ReadDir(r.baseDir)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -226,7 +342,8 @@ func (r *Repository) List(ctx context.Context, provider string) ([]types.MediaSu
 	return summaries, nil
 }
 
-// Search finds entries matching a query
+// Search finds entries whose ID, titles, aliases or slug match a query,
+// best matches first
 func (r *Repository) Search(ctx context.Context, query string) ([]types.MediaSummary, error) {
 	all, err := r.List(ctx, "")
 	if err != nil {
@@ -237,24 +354,244 @@ func (r *Repository) Search(ctx context.Context, query string) ([]types.MediaSum
 		return all, nil
 	}
 
-	queryLower := strings.ToLower(query)
-	var results []types.MediaSummary
+	type scored struct {
+		summary types.MediaSummary
+		score   int
+	}
+	var matches []scored
 
 	for _, s := range all {
-		// Match by ID or title
-		if s.ID == query || strings.Contains(strings.ToLower(s.Title), queryLower) {
-			results = append(results, s)
+		media, err := r.Load(ctx, s.Provider, s.ID)
+		if err != nil || media == nil {
+			continue
+		}
+		if score := matchScore(media, query); score > 0 {
+			matches = append(matches, scored{s, score})
 		}
 	}
 
-	// Sort by title
-	slices.SortFunc(results, func(a, b types.MediaSummary) int {
-		return strings.Compare(a.Title, b.Title)
+	// Sort by score, then title
+	slices.SortFunc(matches, func(a, b scored) int {
+		if a.score != b.score {
+			return b.score - a.score
+		}
+		return strings.Compare(a.summary.Title, b.summary.Title)
 	})
 
+	results := make([]types.MediaSummary, len(matches))
+	for i, m := range matches {
+		results[i] = m.summary
+	}
 	return results, nil
 }
 
+// matchScore rates how well media matches query (0 = no match). Exact
+// matches beat prefixes, which beat substrings; the main title beats
+// English/Japanese titles, which beat aliases and the slug.
+func matchScore(media *types.Media, query string) int {
+	if media.ID == query {
+		return 100
+	}
+
+	type name struct {
+		value  string
+		weight int
+	}
+	names := []name{{media.Title, 3}, {media.TitleEN, 2}, {media.TitleJP, 2}}
+	for _, alias := range media.Aliases {
+		names = append(names, name{alias, 1})
+	}
+
+	q := strings.ToLower(strings.TrimSpace(query))
+
+	best := 0
+	for _, n := range names {
+		v := strings.ToLower(n.value)
+		if v == "" {
+			continue
+		}
+		switch {
+		case v == q:
+			best = max(best, 60+n.weight)
+		case strings.HasPrefix(v, q):
+			best = max(best, 40+n.weight)
+		case strings.Contains(v, q):
+			best = max(best, 20+n.weight)
+		}
+	}
+
+	// Slug match catches romanized and punctuation-free queries ("rezero")
+	if qs := util.Slugify(query); best == 0 && qs != "" && media.Slug != "" {
+		switch {
+		case media.Slug == qs:
+			best = 50
+		case strings.Contains(media.Slug, qs) || strings.Contains(strings.ReplaceAll(media.Slug, "-", ""), qs):
+			best = 10
+		}
+	}
+
+	return best
+}
+
+// Stats summarizes the database: series per provider, episodes, disk usage,
+// update range and airing series due for refresh
+func (r *Repository) Stats(ctx context.Context) (*types.DatabaseStats, error) {
+	stats := &types.DatabaseStats{Providers: make(map[string]int)}
+
+	err := filepath.WalkDir(r.baseDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if info, err := d.Info(); err == nil {
+			stats.DiskUsage += info.Size()
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read database directory: %w", err)
+	}
+
+	summaries, err := r.List(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	now := r.clock.Now()
+	for _, s := range summaries {
+		media, err := r.Load(ctx, s.Provider, s.ID)
+		if err != nil || media == nil {
+			continue
+		}
+
+		stats.Providers[s.Provider]++
+		stats.Series++
+		stats.Episodes += s.EpisodeCount
+
+		if !media.LastUpdate.IsZero() {
+			if stats.Oldest.IsZero() || media.LastUpdate.Before(stats.Oldest) {
+				stats.Oldest = media.LastUpdate
+			}
+			if media.LastUpdate.After(stats.Newest) {
+				stats.Newest = media.LastUpdate
+			}
+		}
+
+		if NeedsRefresh(media, now) {
+			stats.DueForRefresh = append(stats.DueForRefresh, s)
+		}
+	}
+
+	return stats, nil
+}
+
+// NeedsRefresh reports whether fetching media again could yield new episodes:
+// it is still airing and its next episode is unknown or has already aired.
+// The air date is a day in the provider's time zone, so it counts as aired
+// once that day is over there. Music releases have a fixed tracklist.
+func NeedsRefresh(media *types.Media, now time.Time) bool {
+	if media.Status == "Finished Airing" || media.Type == types.MediaTypeMusic {
+		return false
+	}
+	if media.NextEpisodeAirDate != nil {
+		t, ok := util.AiredBy(*media.NextEpisodeAirDate, media.AirTimeZone)
+		if ok && t.After(now) {
+			return false
+		}
+	}
+	return true
+}
+
+// Touch marks an entry as used now, so size-capped eviction (Evict) keeps it
+// over entries that sat unused for longer
+func (r *Repository) Touch(provider, id string) {
+	if util.ReadOnly() {
+		return
+	}
+	matches, _ := filepath.Glob(r.pattern(provider, id))
+	now := r.clock.Now()
+	for _, path := range matches {
+		_ = os.Chtimes(path, now, now)
+	}
+}
+
+// cacheFile is a database file and when it was last used (saved or touched)
+type cacheFile struct {
+	path string
+	size int64
+	used time.Time
+}
+
+// files lists the database files, least recently used first
+func (r *Repository) files() ([]cacheFile, error) {
+	var files []cacheFile
+	err := filepath.WalkDir(r.baseDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if info, err := d.Info(); err == nil {
+			files = append(files, cacheFile{path: path, size: info.Size(), used: info.ModTime()})
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read database directory: %w", err)
+	}
+	slices.SortFunc(files, func(a, b cacheFile) int { return a.used.Compare(b.used) })
+	return files, nil
+}
+
+// CacheStats reports the size of the database against maxSize (0 for no cap)
+func (r *Repository) CacheStats(maxSize int64) (*types.CacheStats, error) {
+	files, err := r.files()
+	if err != nil {
+		return nil, err
+	}
+	stats := &types.CacheStats{Path: r.baseDir, Files: len(files), MaxSize: maxSize}
+	for _, f := range files {
+		stats.Size += f.size
+	}
+	if len(files) > 0 {
+		stats.LeastRecent = files[0].used
+		stats.MostRecent = files[len(files)-1].used
+	}
+	return stats, nil
+}
+
+// Evict removes the least recently used entries until the database fits in
+// maxSize bytes, and returns the removed files. The files of the keep entry
+// ("provider/id") are never removed. A maxSize of 0 means no cap.
+func (r *Repository) Evict(maxSize int64, keep string) ([]string, error) {
+	if maxSize <= 0 || util.ReadOnly() {
+		return nil, nil
+	}
+	files, err := r.files()
+	if err != nil {
+		return nil, err
+	}
+	var total int64
+	for _, f := range files {
+		total += f.size
+	}
+
+	prov, id, _ := strings.Cut(keep, "/")
+	kept := r.pattern(prov, id)
+	var removed []string
+	for _, f := range files {
+		if total <= maxSize {
+			break
+		}
+		if ok, _ := filepath.Match(kept, f.path); ok || f.path == r.partialPath(prov, id) {
+			continue
+		}
+		if err := os.Remove(f.path); err != nil {
+			return removed, fmt.Errorf("failed to evict %s: %w", f.path, err)
+		}
+		total -= f.size
+		removed = append(removed, f.path)
+	}
+	return removed, nil
+}
+
 // Path returns the base database directory
 func (r *Repository) Path() string {
 	return r.baseDir
@@ -0,0 +1,231 @@
+// Package dupes finds media files with identical contents across a library.
+package dupes
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/mydehq/autotitle/internal/config"
+	"github.com/mydehq/autotitle/internal/matcher"
+	"github.com/mydehq/autotitle/internal/types"
+	"github.com/mydehq/autotitle/internal/util"
+)
+
+// sampleSize is the length of each of the three samples of the fast hash
+const sampleSize = 64 << 10
+
+// Scan lists the media files under root, skipping ignored directories
+func Scan(ctx context.Context, root string, ig *config.Ignorer, formats []string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && ig.SkipDir(d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || config.IsMetadataClutter(d.Name(), false) {
+			return nil
+		}
+		ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(d.Name())), ".")
+		if slices.Contains(formats, ext) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// Find groups files with identical contents. Files are compared by size
+// first, then by a hash of samples from their start, middle and end; only
+// the candidates left are hashed in full. Hardlinks of one file are not
+// duplicates. Sets and the files in them are sorted by path.
+func Find(ctx context.Context, files []string) ([]types.DuplicateSet, error) {
+	bySize := make(map[int64][]string)
+	var seen []os.FileInfo
+	for _, f := range files {
+		info, err := os.Stat(f)
+		if err != nil {
+			return nil, err
+		}
+		if info.Size() == 0 || slices.ContainsFunc(seen, func(s os.FileInfo) bool { return os.SameFile(s, info) }) {
+			continue
+		}
+		seen = append(seen, info)
+		bySize[info.Size()] = append(bySize[info.Size()], f)
+	}
+
+	var sets []types.DuplicateSet
+	for size, candidates := range bySize {
+		if len(candidates) < 2 {
+			continue
+		}
+		bySample, err := groupBy(ctx, candidates, func(f string) (string, error) { return sampleHash(f, size) })
+		if err != nil {
+			return nil, err
+		}
+		for _, group := range bySample {
+			if len(group) < 2 {
+				continue
+			}
+			byHash, err := groupBy(ctx, group, fullHash)
+			if err != nil {
+				return nil, err
+			}
+			for hash, same := range byHash {
+				if len(same) < 2 {
+					continue
+				}
+				slices.Sort(same)
+				sets = append(sets, types.DuplicateSet{Hash: hash, Size: size, Files: same})
+			}
+		}
+	}
+
+	slices.SortFunc(sets, func(a, b types.DuplicateSet) int {
+		return cmp.Compare(a.Files[0], b.Files[0])
+	})
+	return sets, nil
+}
+
+// Rank moves the file from the best-ranked release group to the front of
+// each set, so it is the copy kept. groups lists group names best first and
+// matches case-insensitively; files from unlisted groups (or with none in
+// their name) rank last. Ties keep path order.
+func Rank(sets []types.DuplicateSet, groups []string) {
+	if len(groups) == 0 {
+		return
+	}
+	rank := func(path string) int {
+		g := matcher.ParseFilename(filepath.Base(path)).ReleaseGroup
+		for i, want := range groups {
+			if g != "" && strings.EqualFold(g, want) {
+				return i
+			}
+		}
+		return len(groups)
+	}
+	for _, set := range sets {
+		slices.SortStableFunc(set.Files, func(a, b string) int {
+			return cmp.Compare(rank(a), rank(b))
+		})
+	}
+}
+
+// groupBy groups files by the key computed for each
+func groupBy(ctx context.Context, files []string, key func(string) (string, error)) (map[string][]string, error) {
+	groups := make(map[string][]string)
+	for _, f := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		k, err := key(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash %s: %w", f, err)
+		}
+		groups[k] = append(groups[k], f)
+	}
+	return groups, nil
+}
+
+// sampleHash hashes the start, middle and end of a file of the given size
+func sampleHash(path string, size int64) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	buf := make([]byte, sampleSize)
+	for _, off := range []int64{0, size/2 - sampleSize/2, size - sampleSize} {
+		off = max(off, 0)
+		n, err := f.ReadAt(buf, off)
+		if err != nil && err != io.EOF {
+			return "", err
+		}
+		h.Write(buf[:n])
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// fullHash hashes the whole file
+func fullHash(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Apply replaces the redundant copies of set (all files but the first) with
+// hardlinks to it, or removes them. Copies that changed size since the scan
+// or already are links to the kept file are left alone.
+func Apply(set types.DuplicateSet, action types.DedupeAction) ([]string, error) {
+	if err := util.CheckWritable("resolve duplicates"); err != nil {
+		return nil, err
+	}
+	if action != types.DedupeLink && action != types.DedupeRemove {
+		return nil, nil
+	}
+	keep, err := os.Stat(set.Files[0])
+	if err != nil {
+		return nil, err
+	}
+
+	var done []string
+	for _, dup := range set.Files[1:] {
+		info, err := os.Stat(dup)
+		if err != nil {
+			return done, err
+		}
+		if os.SameFile(info, keep) || info.Size() != set.Size {
+			continue
+		}
+
+		if action == types.DedupeRemove {
+			if err := os.Remove(dup); err != nil {
+				return done, err
+			}
+		} else if err := link(set.Files[0], dup); err != nil {
+			return done, err
+		}
+		done = append(done, dup)
+	}
+	return done, nil
+}
+
+// link replaces dup with a hardlink to keep. The link is made under a
+// temporary name first, so dup is never lost if linking fails (e.g. across
+// filesystems).
+func link(keep, dup string) error {
+	tmp := dup + ".autotitle-link"
+	if err := os.Link(keep, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, dup); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package dupes
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mydehq/autotitle/internal/config"
+	"github.com/mydehq/autotitle/internal/types"
+)
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFind(t *testing.T) {
+	root := t.TempDir()
+	episode := bytes.Repeat([]byte("frame"), 100_000)
+	// Same size, same samples, different contents between them
+	altered := bytes.Clone(episode)
+	altered[sampleSize+10] = 'X'
+
+	writeFile(t, filepath.Join(root, "Show A", "01.mkv"), episode)
+	writeFile(t, filepath.Join(root, "Show B", "01.mkv"), episode)
+	writeFile(t, filepath.Join(root, "Show C", "01.mkv"), altered)
+	writeFile(t, filepath.Join(root, "Show A", ".autotitle_backup", "01.mkv"), episode)
+	writeFile(t, filepath.Join(root, "Show A", "notes.txt"), episode)
+	if err := os.Link(filepath.Join(root, "Show A", "01.mkv"), filepath.Join(root, "Show D.mkv")); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := Scan(context.Background(), root, config.NewIgnorer(nil), []string{"mkv"})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(files) != 4 {
+		t.Fatalf("Expected 4 media files outside the backup, got %v", files)
+	}
+
+	sets, err := Find(context.Background(), files)
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if len(sets) != 1 {
+		t.Fatalf("Expected 1 duplicate set, got %+v", sets)
+	}
+	want := []string{filepath.Join(root, "Show A", "01.mkv"), filepath.Join(root, "Show B", "01.mkv")}
+	if got := sets[0].Files; len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if sets[0].Wasted() != int64(len(episode)) {
+		t.Errorf("Expected %d wasted bytes, got %d", len(episode), sets[0].Wasted())
+	}
+}
+
+func TestApply(t *testing.T) {
+	for _, action := range []types.DedupeAction{types.DedupeLink, types.DedupeRemove} {
+		t.Run(string(action), func(t *testing.T) {
+			root := t.TempDir()
+			keep, dup := filepath.Join(root, "a.mkv"), filepath.Join(root, "b.mkv")
+			writeFile(t, keep, []byte("episode"))
+			writeFile(t, dup, []byte("episode"))
+
+			done, err := Apply(types.DuplicateSet{Size: 7, Files: []string{keep, dup}}, action)
+			if err != nil || len(done) != 1 {
+				t.Fatalf("Expected 1 copy resolved, got %v, %v", done, err)
+			}
+
+			keepInfo, _ := os.Stat(keep)
+			dupInfo, err := os.Stat(dup)
+			switch action {
+			case types.DedupeLink:
+				if err != nil || !os.SameFile(keepInfo, dupInfo) {
+					t.Errorf("Expected %s to be a hardlink of %s", dup, keep)
+				}
+			case types.DedupeRemove:
+				if !os.IsNotExist(err) {
+					t.Errorf("Expected %s removed, got %v", dup, err)
+				}
+			}
+			if keepInfo == nil {
+				t.Errorf("Kept file is gone")
+			}
+		})
+	}
+}
+
+func TestRank(t *testing.T) {
+	sets := []types.DuplicateSet{{Files: []string{
+		"/a/Frieren - 01.mkv",
+		"/b/[Erai-raws] Frieren - 01 [1080p].mkv",
+		"/c/[SubsPlease] Frieren - 01 (1080p).mkv",
+		"/d/[Other] Frieren - 01.mkv",
+	}}}
+	Rank(sets, []string{"subsplease", "Erai-raws"})
+
+	want := []string{
+		"/c/[SubsPlease] Frieren - 01 (1080p).mkv",
+		"/b/[Erai-raws] Frieren - 01 [1080p].mkv",
+		"/a/Frieren - 01.mkv",
+		"/d/[Other] Frieren - 01.mkv",
+	}
+	for i, f := range sets[0].Files {
+		if f != want[i] {
+			t.Fatalf("Expected order %v, got %v", want, sets[0].Files)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+// Package history keeps a local, append-only ledger of completed renames.
+// Nothing in it ever leaves the machine.
+package history
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/mydehq/autotitle/internal/types"
+	"github.com/mydehq/autotitle/internal/util"
+)
+
+const FileName = "history.jsonl"
+
+// ManualRenameTime is the estimated time to rename one file by hand
+const ManualRenameTime = 30 * time.Second
+
+// Ledger appends rename runs to ~/.cache/autotitle/history.jsonl
+type Ledger struct {
+	path  string
+	Clock types.Clock // Source of entry timestamps
+}
+
+// New creates a Ledger in cacheRoot
+func New(cacheRoot string) *Ledger {
+	return &Ledger{
+		path:  filepath.Join(cacheRoot, FileName),
+		Clock: types.SystemClock{},
+	}
+}
+
+// Path returns the ledger file path
+func (l *Ledger) Path() string {
+	return l.path
+}
+
+// Record appends e to the ledger, stamping it with the current time if unset
+func (l *Ledger) Record(e types.HistoryEntry) error {
+	if err := util.CheckWritable("record history"); err != nil {
+		return err
+	}
+	if e.Time.IsZero() {
+		e.Time = l.Clock.Now()
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open history: %w", err)
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// Entries returns every entry in the ledger, oldest first. Malformed lines
+// (e.g. from an interrupted write) are skipped.
+func (l *Ledger) Entries() ([]types.HistoryEntry, error) {
+	f, err := os.Open(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history: %w", err)
+	}
+	defer f.Close()
+
+	var entries []types.HistoryEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e types.HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// Summarize totals entries into usage statistics
+func Summarize(entries []types.HistoryEntry) *types.UsageStats {
+	stats := &types.UsageStats{}
+	bySeries := make(map[string]*types.SeriesUsage)
+
+	for _, e := range entries {
+		stats.Runs++
+		stats.Files += e.Files
+		if stats.First.IsZero() || e.Time.Before(stats.First) {
+			stats.First = e.Time
+		}
+		if e.Time.After(stats.Last) {
+			stats.Last = e.Time
+		}
+
+		key := e.Provider + "/" + e.ID
+		s, ok := bySeries[key]
+		if !ok {
+			s = &types.SeriesUsage{Provider: e.Provider, ID: e.ID}
+			bySeries[key] = s
+		}
+		s.Series = e.Series // Latest title wins
+		s.Files += e.Files
+	}
+
+	for _, s := range bySeries {
+		stats.TopSeries = append(stats.TopSeries, *s)
+	}
+	slices.SortFunc(stats.TopSeries, func(a, b types.SeriesUsage) int {
+		if c := cmp.Compare(b.Files, a.Files); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Series, b.Series)
+	})
+
+	stats.TimeSaved = time.Duration(stats.Files) * ManualRenameTime
+	return stats
+}
@@ -0,0 +1,117 @@
+// Package journal persists the progress of rename batches so an interrupted
+// run can be resumed.
+package journal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/mydehq/autotitle/internal/types"
+	"github.com/mydehq/autotitle/internal/util"
+)
+
+const FileName = "batches.json"
+
+// Journal stores unfinished batches in ~/.cache/autotitle/batches.json,
+// at most one per directory
+type Journal struct {
+	path string
+}
+
+// New creates a Journal in cacheRoot
+func New(cacheRoot string) *Journal {
+	return &Journal{path: filepath.Join(cacheRoot, FileName)}
+}
+
+// Path returns the journal file path
+func (j *Journal) Path() string {
+	return j.path
+}
+
+// List returns all unfinished batches
+func (j *Journal) List() ([]types.Batch, error) {
+	data, err := os.ReadFile(j.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read batch journal: %w", err)
+	}
+
+	var batches []types.Batch
+	if err := json.Unmarshal(data, &batches); err != nil {
+		return nil, fmt.Errorf("failed to parse batch journal: %w", err)
+	}
+	return batches, nil
+}
+
+// Get returns the unfinished batch of dir (an absolute path), or nil
+func (j *Journal) Get(dir string) (*types.Batch, error) {
+	batches, err := j.List()
+	if err != nil {
+		return nil, err
+	}
+	for i := range batches {
+		if batches[i].Dir == dir {
+			return &batches[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// Put records b, replacing any batch of the same directory
+func (j *Journal) Put(b types.Batch) error {
+	batches, err := j.List()
+	if err != nil {
+		return err
+	}
+	if i := slices.IndexFunc(batches, func(x types.Batch) bool { return x.Dir == b.Dir }); i >= 0 {
+		batches[i] = b
+	} else {
+		batches = append(batches, b)
+	}
+	return j.save(batches)
+}
+
+// Remove deletes the batch of dir; removing a missing batch is not an error
+func (j *Journal) Remove(dir string) error {
+	batches, err := j.List()
+	if err != nil {
+		return err
+	}
+	kept := slices.DeleteFunc(batches, func(b types.Batch) bool { return b.Dir == dir })
+	if len(kept) == len(batches) {
+		return nil
+	}
+	return j.save(kept)
+}
+
+func (j *Journal) save(batches []types.Batch) error {
+	if err := util.CheckWritable("journal rename batches"); err != nil {
+		return err
+	}
+	if len(batches) == 0 {
+		if err := os.Remove(j.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+
+	data, err := json.MarshalIndent(batches, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(j.path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	// Write through a temp file so a kill mid-write can't lose the journal
+	tmp := j.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, j.path)
+}
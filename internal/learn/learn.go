@@ -0,0 +1,105 @@
+// Package learn persists matches the user corrected by hand so later runs
+// repeat them instead of the original mistake.
+package learn
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mydehq/autotitle/internal/sorter"
+	"github.com/mydehq/autotitle/internal/types"
+	"github.com/mydehq/autotitle/internal/util"
+)
+
+const FileName = "learned.json"
+
+// Store keeps learned data in ~/.cache/autotitle/learned.json
+type Store struct {
+	path string
+}
+
+// data is the on-disk layout of the store
+type data struct {
+	Aliases map[string]types.Alias `json:"aliases,omitempty"` // By sorter.Key of the name
+}
+
+// New creates a Store in cacheRoot
+func New(cacheRoot string) *Store {
+	return &Store{path: filepath.Join(cacheRoot, FileName)}
+}
+
+// Alias returns the match learned for a series name, or nil
+func (s *Store) Alias(name string) (*types.Alias, error) {
+	d, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	if a, ok := d.Aliases[sorter.Key(name)]; ok {
+		return &a, nil
+	}
+	return nil, nil
+}
+
+// Aliases returns all learned aliases
+func (s *Store) Aliases() ([]types.Alias, error) {
+	d, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	aliases := make([]types.Alias, 0, len(d.Aliases))
+	for _, a := range d.Aliases {
+		aliases = append(aliases, a)
+	}
+	return aliases, nil
+}
+
+// Learn records a as the match for its name, replacing an earlier one.
+// Patterns learned earlier for the same match are kept when a has none.
+func (s *Store) Learn(a types.Alias) error {
+	d, err := s.load()
+	if err != nil {
+		return err
+	}
+	if d.Aliases == nil {
+		d.Aliases = make(map[string]types.Alias)
+	}
+	key := sorter.Key(a.Name)
+	if old, ok := d.Aliases[key]; ok && len(a.Patterns) == 0 && old.Match.URL == a.Match.URL {
+		a.Patterns = old.Patterns
+	}
+	d.Aliases[key] = a
+	return s.save(d)
+}
+
+func (s *Store) load() (*data, error) {
+	raw, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &data{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read learned data: %w", err)
+	}
+
+	var d data
+	if err := json.Unmarshal(raw, &d); err != nil {
+		return nil, fmt.Errorf("failed to parse learned data: %w", err)
+	}
+	return &d, nil
+}
+
+func (s *Store) save(d *data) error {
+	if err := util.CheckWritable("save learned matches"); err != nil {
+		return err
+	}
+	raw, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return os.WriteFile(s.path, raw, 0644)
+}
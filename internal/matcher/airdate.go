@@ -0,0 +1,35 @@
+package matcher
+
+import (
+	"strings"
+	"time"
+)
+
+// DefaultDateFormat is the AIR_DATE format when none is configured
+const DefaultDateFormat = "YYYY-MM-DD"
+
+// FormatAirDate renders a provider air date ("2013-04-07" or RFC 3339) with
+// format, where YYYY, YY, MM and DD stand for the date parts. The date is
+// taken as the provider reports it, without converting time zones. An
+// unparseable date renders as empty so the field is dropped.
+func FormatAirDate(airDate, format string) string {
+	if airDate == "" {
+		return ""
+	}
+	t, err := time.Parse(time.RFC3339, airDate)
+	if err != nil {
+		if t, err = time.Parse(time.DateOnly, airDate); err != nil {
+			return ""
+		}
+	}
+
+	if format == "" {
+		format = DefaultDateFormat
+	}
+	return strings.NewReplacer(
+		"YYYY", t.Format("2006"),
+		"YY", t.Format("06"),
+		"MM", t.Format("01"),
+		"DD", t.Format("02"),
+	).Replace(format)
+}
@@ -8,10 +8,11 @@ import (
 
 var (
 	reCRC       = regexp.MustCompile(`\[[A-Fa-f0-9]{8}\]`)
-	reRes       = regexp.MustCompile(`(?i)\b(\d{3,4}p|\d{3,4}x\d{3,4})\b`)
+	reRes       = regexp.MustCompile(`(?i)\b(\d{3,4}[pi]|\d{3,4}x\d{3,4}|[48]K|UHD|FHD)\b`)
 	reSxxExx    = regexp.MustCompile(`(?i)(\bS\s*\d+\s*[Ex]\s*)(\d+)`)
 	reXxEyy     = regexp.MustCompile(`(?i)(\b\d+\s*[Ex]\s*)(\d+)`)
 	rePrefix    = regexp.MustCompile(`(?i)(\bEpisode\s*|\bEp\.?\s*|\bE\s*| - )(\d+)`)
+	rePart      = regexp.MustCompile(`(?i)(\bPart\s*|\bPt\.?\s*|\bCD\s*)(\d+)\b`)
 	reNumber    = regexp.MustCompile(`\d+`)
 	reBracketed = regexp.MustCompile(`\[([^\]]+)\]`)
 )
@@ -68,6 +69,14 @@ func GuessPattern(filename string) string {
 		tagOffset = start + len("[{{ANY}}]")
 	}
 
+	// Split movies/episodes: "Movie Part 1", "Movie pt2", "Movie CD1"
+	hasPart := false
+	if reParts := rePart.FindAllStringSubmatchIndex(pattern, -1); len(reParts) > 0 {
+		last := reParts[len(reParts)-1]
+		pattern = pattern[:last[4]] + "{{PART}}" + pattern[last[5]:]
+		hasPart = true
+	}
+
 	matched := false
 
 	// SxxExx format - replace all occurrences
@@ -86,7 +95,8 @@ func GuessPattern(filename string) string {
 		matched = true
 	}
 
-	if matched {
+	// A part number alone is enough; other numbers are likely part of the title
+	if matched || hasPart {
 		goto Finalize
 	}
 
@@ -142,8 +152,8 @@ func maskTrailer(pattern string) string {
 	}
 
 	trailer := pattern[idx+len("{{EP_NUM}}"):]
-	if trailer == "" {
-		return pattern
+	if trailer == "" || strings.Contains(trailer, "{{PART}}") {
+		return pattern // A trailing part number is not a title
 	}
 
 	// Find the first occurrence of a separator block.
@@ -161,7 +171,7 @@ func maskTrailer(pattern string) string {
 		remaining := trailer[eIdx:]
 
 		// Find first metadata block [{{ANY}}] or [{{RES}}]
-		metaRe := regexp.MustCompile(`\[\{\{[A-Z_]+\}\}\]`)
+		metaRe := regexp.MustCompile(`\[` + RePlaceholder.String() + `\]`)
 		m := metaRe.FindStringIndex(remaining)
 
 		if m == nil {
@@ -0,0 +1,91 @@
+package matcher
+
+import (
+	"errors"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/mydehq/autotitle/internal/types"
+)
+
+// fuzzFilenames is a seed corpus of real-world release names
+var fuzzFilenames = []string{
+	"[Sub] Series - 01 [1080p].mkv",
+	"[SubsPlease] Sousou no Frieren - 28 (1080p) [A1B2C3D4].mkv",
+	"[Erai-raws] One Piece - 1100 [720p][Multiple Subtitle].mkv",
+	"Series.S01E01.Title.1080p.WEB-DL.x264.mkv",
+	"Show_Name_-_01v2_[BD_1920x1080_HEVC_FLAC].mkv",
+	"Detective Conan - Episode 1000 - The Scarlet Return.mp4",
+	"DC_remastered_0123_720p.avi",
+	"進撃の巨人 第01話.mkv",
+	"01x05 - Pilot.mkv",
+	"E01 - Episode 1.mkv",
+	"[[[[]]]].mkv",
+	"{{EP_NUM}}.{{EXT}}",
+	"no-extension",
+	".mkv",
+	"",
+}
+
+// fuzzTemplates is a seed corpus of input patterns
+var fuzzTemplates = []string{
+	"{{SERIES}} - {{EP_NUM}} [{{RES}}].{{EXT}}",
+	"[{{ANY}}] [{{ANY}}] {{SERIES}} - {{EP_NUM}}.{{EXT}}",
+	"{{ANY}}{{ANY}}{{ANY}}{{EP_NUM}}{{ANY}}{{ANY}}.{{EXT}}",
+	"Episode {{EP_NUM}}.{{EXT}}",
+	"{{UNKNOWN}} {{EP_NUM}}",
+	"(?P<EpNum>\\d+)",
+	"{{EP_NUM",
+	"",
+}
+
+func FuzzCompile(f *testing.F) {
+	for _, tmpl := range fuzzTemplates {
+		f.Add(tmpl)
+	}
+	f.Fuzz(func(t *testing.T, template string) {
+		p, err := Compile(template)
+		if err != nil {
+			return
+		}
+		if p.String() == "" {
+			t.Errorf("Compile(%q) produced an empty regex", template)
+		}
+	})
+}
+
+func FuzzMatch(f *testing.F) {
+	for _, tmpl := range fuzzTemplates {
+		for _, name := range fuzzFilenames {
+			f.Add(tmpl, name)
+		}
+	}
+	f.Fuzz(func(t *testing.T, template, filename string) {
+		p, err := Compile(template)
+		if err != nil {
+			return
+		}
+		_ = p.Match(filename)
+		if res, ok := p.MatchTyped(filename); ok && res == nil {
+			t.Errorf("MatchTyped(%q) reported a match with a nil result", filename)
+		}
+	})
+}
+
+func FuzzGuessPattern(f *testing.F) {
+	for _, name := range fuzzFilenames {
+		f.Add(name)
+	}
+	f.Fuzz(func(t *testing.T, filename string) {
+		guessed := GuessPattern(filename)
+		// Templates must be valid UTF-8 to compile; invalid names are only matched against
+		if guessed == "" || !utf8.ValidString(guessed) {
+			return
+		}
+		// Every guessed pattern must be usable as an input pattern (or cleanly refused)
+		var tooComplex types.ErrPatternTooComplex
+		if _, err := Compile(guessed); err != nil && !errors.As(err, &tooComplex) {
+			t.Errorf("GuessPattern(%q) = %q does not compile: %v", filename, guessed, err)
+		}
+	})
+}
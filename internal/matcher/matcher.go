@@ -6,6 +6,8 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+
+	"github.com/mydehq/autotitle/internal/types"
 )
 
 const (
@@ -28,36 +30,79 @@ var (
 		"SERIES_EN": ".+?",
 		"SERIES_JP": ".+?",
 		"EP_NUM":    `\d+`,
+		"PART":      `\d+`,
 		"EP_NAME":   ".+?",
 		"FILLER":    ".*?",
-		"RES":       `\d{3,4}p|\d{3,4}x\d{3,4}`,
+		"RES":       `(?i:\d{3,4}[pi]|\d{3,4}x\d{3,4}|[48]k|uhd|fhd)`,
 		"ANY":       ".*?",
 	}
 )
 
+const (
+	// MaxWildcards is the maximum number of wildcard placeholders allowed in one pattern
+	MaxWildcards = 8
+
+	// MaxMatchLength is the longest filename (in bytes) a pattern is matched against
+	MaxMatchLength = 1024
+
+	placeholderName = `[A-Z][A-Z0-9_]*`
+)
+
+var (
+	// wildcardPlaceholders are placeholders that match arbitrary text
+	wildcardPlaceholders = map[string]bool{
+		"SERIES":    true,
+		"SERIES_EN": true,
+		"SERIES_JP": true,
+		"EP_NAME":   true,
+		"FILLER":    true,
+		"ANY":       true,
+	}
+
+	// RePlaceholder matches a {{NAME}} placeholder, capturing its name:
+	// upper case letters, digits and _, starting with a letter (e.g. CRC32)
+	RePlaceholder = regexp.MustCompile(`\{\{(` + placeholderName + `)\}\}`)
+)
+
 type TemplateVars struct {
-	Series   string
-	SeriesEn string
-	SeriesJp string
-	EpNum    string
-	EpName   string
-	Filler   string
-	Res      string
-	Ext      string
+	Series    string
+	SeriesEn  string
+	SeriesJp  string
+	EpNum     string
+	EpName    string
+	EpNameJp  string
+	Filler    string
+	Res       string
+	Year      string
+	Part      string
+	Source    string
+	Dual      string
+	AudioLang string
+	AirDate   string
+	Watched   string
+	Artist    string
+	Volume    string
+	Ext       string
+	Custom    map[string]string // Custom placeholder name -> matched value
 }
 
 // MatchResult contains extracted values from a filename match
 type MatchResult struct {
 	EpisodeNum int
+	Part       int // Part of a split movie/episode, 0 if the pattern has no PART
 	Resolution string
+	Release    ReleaseTags
 	Extension  string
+	Custom     map[string]string // Custom placeholder name -> matched value, transformed
 }
 
 type Pattern struct {
 	raw      string
 	regex    *regexp.Regexp
 	idxEpNum int
+	idxPart  int
 	idxRes   int
+	custom   []customGroup
 }
 
 func (p *Pattern) String() string {
@@ -68,13 +113,17 @@ func (p *Pattern) String() string {
 // Supports multiple occurrences of the same placeholder by generating
 // unique named capture groups (e.g., Any_1, Any_2).
 func Compile(template string) (*Pattern, error) {
+	if err := checkComplexity(template); err != nil {
+		return nil, err
+	}
+
 	templateBase := strings.ReplaceAll(template, "."+PlaceholderExt, "")
 	templateBase = strings.ReplaceAll(templateBase, PlaceholderExt, "")
 
 	regexStr := regexp.QuoteMeta(templateBase)
 
 	// Replace placeholders in a single pass using unique group names.
-	rePlaceholderFinder := regexp.MustCompile(`\\{\\{([A-Z_]+)\\}\\}`)
+	rePlaceholderFinder := regexp.MustCompile(`\\{\\{(` + placeholderName + `)\\}\\}`)
 
 	placeholderCounts := make(map[string]int)
 
@@ -87,17 +136,20 @@ func Compile(template string) (*Pattern, error) {
 	resultRegex := rePlaceholderFinder.ReplaceAllStringFunc(regexStr, func(m string) string {
 		match := rePlaceholderFinder.FindStringSubmatch(m)
 		baseName := match[1]
+		groupName := formatGroupName(baseName)
 		placeholderRegex, ok := placeholderRegexMap[baseName]
 		if !ok {
-			// Unknown placeholder, treat as literal
-			return m
+			def, ok := customPlaceholder(baseName)
+			if !ok {
+				// Unknown placeholder, treat as literal
+				return m
+			}
+			placeholderRegex, groupName = def.Regex, customGroupPrefix+baseName
 		}
 
 		placeholderCounts[baseName]++
 		count := placeholderCounts[baseName]
 
-		groupName := formatGroupName(baseName)
-
 		// Only add suffix if there are multiple occurrences of this specific placeholder
 		if totals[baseName] > 1 {
 			groupName = fmt.Sprintf("%s_%d", groupName, count)
@@ -113,12 +165,56 @@ func Compile(template string) (*Pattern, error) {
 		return nil, fmt.Errorf("failed to compile pattern %q: %w (regex: %s)", template, err, resultRegex)
 	}
 
-	return &Pattern{
+	p := &Pattern{
 		raw:      template,
 		regex:    re,
 		idxEpNum: getFirstSubexpIndex(re, "EpNum"),
+		idxPart:  getFirstSubexpIndex(re, "Part"),
 		idxRes:   getFirstSubexpIndex(re, "Res"),
-	}, nil
+	}
+	for name := range totals {
+		if idx := getFirstSubexpIndex(re, customGroupPrefix+name); idx >= 0 {
+			def, _ := customPlaceholder(name)
+			p.custom = append(p.custom, customGroup{idx: idx, name: name, transform: def.Transform})
+		}
+	}
+	return p, nil
+}
+
+// checkComplexity refuses templates whose wildcard arrangement would make
+// matching ambiguous or expensive on long filenames.
+func checkComplexity(template string) error {
+	locs := RePlaceholder.FindAllStringSubmatchIndex(template, -1)
+
+	wildcards := 0
+	prevEnd := -1
+	prevName := ""
+	for _, loc := range locs {
+		name := template[loc[2]:loc[3]]
+		if !isWildcard(name) {
+			prevEnd = -1
+			continue
+		}
+		wildcards++
+
+		// Two wildcards with nothing between them can split the text in any position
+		if prevEnd == loc[0] {
+			return types.ErrPatternTooComplex{
+				Pattern: template,
+				Reason:  fmt.Sprintf("{{%s}}{{%s}} are adjacent; separate them with literal text", prevName, name),
+			}
+		}
+		prevEnd = loc[1]
+		prevName = name
+	}
+
+	if wildcards > MaxWildcards {
+		return types.ErrPatternTooComplex{
+			Pattern: template,
+			Reason:  fmt.Sprintf("%d wildcard placeholders (max %d); replace some {{ANY}} with literal text", wildcards, MaxWildcards),
+		}
+	}
+	return nil
 }
 
 func formatGroupName(baseName string) string {
@@ -142,7 +238,7 @@ func getFirstSubexpIndex(re *regexp.Regexp, baseName string) int {
 // Match attempts to match a filename against the compiled pattern
 func (p *Pattern) Match(filename string) map[string]string {
 	ext := filepath.Ext(filename)
-	if ext == "" {
+	if ext == "" || len(filename) > MaxMatchLength {
 		return nil
 	}
 
@@ -167,7 +263,7 @@ func (p *Pattern) Match(filename string) map[string]string {
 // MatchTyped attempts to match a filename and returns a structured result
 func (p *Pattern) MatchTyped(filename string) (*MatchResult, bool) {
 	ext := filepath.Ext(filename)
-	if ext == "" {
+	if ext == "" || len(filename) > MaxMatchLength {
 		return nil, false
 	}
 
@@ -186,15 +282,31 @@ func (p *Pattern) MatchTyped(filename string) (*MatchResult, bool) {
 		}
 	}
 
+	var part int
+	if p.idxPart >= 0 && p.idxPart < len(match) {
+		part, _ = strconv.Atoi(match[p.idxPart])
+	}
+
 	var res string
 	if p.idxRes >= 0 && p.idxRes < len(match) {
 		res = match[p.idxRes]
 	}
 
+	var custom map[string]string
+	if len(p.custom) > 0 {
+		custom = make(map[string]string, len(p.custom))
+		for _, g := range p.custom {
+			custom[g.name] = applyTransform(match[g.idx], g.transform)
+		}
+	}
+
 	return &MatchResult{
 		EpisodeNum: epNum,
+		Part:       part,
+		Release:    DetectReleaseTags(nameWithoutExt),
 		Resolution: res,
 		Extension:  strings.TrimPrefix(ext, "."),
+		Custom:     custom,
 	}, true
 }
 
@@ -240,6 +352,21 @@ func GenerateFilenameFromFields(fields []string, separator string, vars Template
 	return builder.String(), nil
 }
 
+// isKnownField reports whether field is a template variable name
+func isKnownField(field string) bool {
+	_, custom := customPlaceholder(field)
+	return custom || isBuiltinField(field)
+}
+
+// isBuiltinField reports whether field is a built-in template variable name
+func isBuiltinField(field string) bool {
+	switch field {
+	case "SERIES", "SERIES_EN", "SERIES_JP", "EP_NUM", "EP_NAME", "EP_NAME_JP", "FILLER", "RES", "YEAR", "PART", "SOURCE", "DUAL", "AUDIO_LANG", "AIR_DATE", "WATCHED", "ARTIST", "VOLUME":
+		return true
+	}
+	return false
+}
+
 func resolveField(field string, vars TemplateVars, padding int) (string, error) {
 	switch field {
 	case "SERIES":
@@ -252,10 +379,53 @@ func resolveField(field string, vars TemplateVars, padding int) (string, error)
 		return padNumber(vars.EpNum, padding), nil
 	case "EP_NAME":
 		return vars.EpName, nil
+	case "EP_NAME_JP":
+		return vars.EpNameJp, nil
 	case "FILLER":
 		return vars.Filler, nil
 	case "RES":
 		return vars.Res, nil
+	case "YEAR":
+		return vars.Year, nil
+	case "SOURCE":
+		return vars.Source, nil
+	case "DUAL":
+		return vars.Dual, nil
+	case "AUDIO_LANG":
+		return vars.AudioLang, nil
+	case "AIR_DATE":
+		return vars.AirDate, nil
+	case "WATCHED":
+		return vars.Watched, nil
+	case "ARTIST":
+		return vars.Artist, nil
+	case "VOLUME":
+		return vars.Volume, nil
+	case "PART":
+		// "pt1" is the multi-part convention media servers stack on
+		if vars.Part == "" {
+			return "", nil
+		}
+		return "pt" + vars.Part, nil
+	}
+
+	// A custom placeholder renders what its input placeholder matched, if anything
+	if _, ok := customPlaceholder(field); ok {
+		return vars.Custom[field], nil
+	}
+
+	// A known field wrapped in brackets, e.g. "(YEAR)" -> "(2019)", dropped when empty
+	if len(field) > 2 {
+		first, inner, last := field[0], field[1:len(field)-1], field[len(field)-1]
+		if (first == '(' && last == ')') || (first == '[' && last == ']') {
+			if isKnownField(inner) {
+				value, err := resolveField(inner, vars, padding)
+				if err != nil || value == "" {
+					return "", err
+				}
+				return string(first) + value + string(last), nil
+			}
+		}
 	}
 
 	// Check if it's explicitly quoted (to allow using "SERIES" as a literal)
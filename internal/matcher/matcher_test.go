@@ -1,8 +1,13 @@
 package matcher
 
 import (
+	"errors"
 	"log"
+	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/mydehq/autotitle/internal/types"
 )
 
 func TestGuessPattern(t *testing.T) {
@@ -25,6 +30,10 @@ func TestGuessPattern(t *testing.T) {
 		{"Spaced Hyphen", "S01E01 - Title.mkv", "S01E{{EP_NUM}} - {{ANY}}.{{EXT}}"},
 		{"Triple Hyphen", "S01E01---Title.mkv", "S01E{{EP_NUM}}---{{ANY}}.{{EXT}}"},
 		{"Redundant Episode Numbers", "E01 - Episode 1.mkv", "E{{EP_NUM}} - Episode {{EP_NUM}}.{{EXT}}"},
+		{"Movie Part", "Movie 2 Part 1.mkv", "Movie 2 Part {{PART}}.{{EXT}}"},
+		{"Episode Part", "Series - 05 Pt2.mkv", "Series - {{EP_NUM}} Pt{{PART}}.{{EXT}}"},
+		{"4K Resolution", "[Sub] Series - 01 [4K].mkv", "[{{ANY}}] Series - {{EP_NUM}} [{{RES}}].{{EXT}}"},
+		{"UHD Resolution", "Series - 01 [UHD].mkv", "Series - {{EP_NUM}} [{{RES}}].{{EXT}}"},
 	}
 
 	for _, tt := range tests {
@@ -42,6 +51,7 @@ func TestGenerateFilenameFromFields(t *testing.T) {
 		EpNum:  "1",
 		EpName: "Episode Title",
 		Res:    "1080p",
+		Year:   "2019",
 		Ext:    "mkv",
 	}
 
@@ -94,6 +104,27 @@ func TestGenerateFilenameFromFields(t *testing.T) {
 			3,
 			"[Draft] Test Series  -  001.mkv",
 		},
+		{
+			"Bracketed fields wrap their value",
+			[]string{"SERIES", "(YEAR)", "[RES]"},
+			" ",
+			2,
+			"Test Series (2019) [1080p].mkv",
+		},
+		{
+			"Part absent",
+			[]string{"SERIES", "PART"},
+			" ",
+			2,
+			"Test Series.mkv",
+		},
+		{
+			"Bracketed empty field skipped",
+			[]string{"SERIES", "[FILLER]", "EP_NUM"},
+			" ",
+			2,
+			"Test Series 01.mkv",
+		},
 	}
 
 	for _, tt := range tests {
@@ -188,3 +219,233 @@ func TestMultiplePlaceholders(t *testing.T) {
 		t.Errorf("Series = %q, want %q", match["Series"], "My show")
 	}
 }
+
+func TestCompileComplexityLimits(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		wantErr  bool
+	}{
+		{"Separated wildcards", "[{{ANY}}] {{SERIES}} - {{EP_NUM}} - {{EP_NAME}}.{{EXT}}", false},
+		{"Wildcard next to number", "{{SERIES}}{{EP_NUM}}.{{EXT}}", false},
+		{"Adjacent wildcards", "{{ANY}}{{SERIES}} - {{EP_NUM}}.{{EXT}}", true},
+		{"Too many wildcards", strings.Repeat("{{ANY}}-", MaxWildcards+1) + "{{EP_NUM}}.{{EXT}}", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compile(tt.template)
+			if tt.wantErr {
+				var tooComplex types.ErrPatternTooComplex
+				if !errors.As(err, &tooComplex) {
+					t.Errorf("Compile(%q) error = %v; want ErrPatternTooComplex", tt.template, err)
+				}
+			} else if err != nil {
+				t.Errorf("Compile(%q) unexpected error: %v", tt.template, err)
+			}
+		})
+	}
+}
+
+func TestMatchRejectsOverlongNames(t *testing.T) {
+	p, err := Compile("{{SERIES}} - {{EP_NUM}}.{{EXT}}")
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+
+	name := strings.Repeat("a", MaxMatchLength) + " - 01.mkv"
+	if _, ok := p.MatchTyped(name); ok {
+		t.Error("MatchTyped() matched a filename longer than MaxMatchLength")
+	}
+}
+
+func TestTitleCleaner(t *testing.T) {
+	c, err := NewTitleCleaner([]types.TitleRule{
+		{Match: `\s*\(TV\)`, Replace: ""},
+		{Match: `^Episode \d+:\s*`, Replace: ""},
+		{Match: `\.+$`, Replace: ""},
+	})
+	if err != nil {
+		t.Fatalf("NewTitleCleaner failed: %v", err)
+	}
+
+	tests := map[string]string{
+		"The Beginning (TV).":   "The Beginning",
+		"Episode 12: A Promise": "A Promise",
+		"Already clean":         "Already clean",
+		"Wait... What (TV)":     "Wait... What",
+	}
+	for in, want := range tests {
+		if got := c.Clean(in); got != want {
+			t.Errorf("Clean(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	var nilCleaner *TitleCleaner
+	if got := nilCleaner.Clean(" untouched "); got != " untouched " {
+		t.Errorf("nil cleaner changed title: %q", got)
+	}
+
+	if _, err := NewTitleCleaner([]types.TitleRule{{Match: "("}}); err == nil {
+		t.Error("expected error for invalid regex")
+	}
+}
+
+func TestDetectReleaseTags(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		dual   bool
+		langs  []string
+	}{
+		{"[Group] Show - 01 [BD 1080p][Dual-Audio]", "BD", true, nil},
+		{"Show.S01E01.1080p.WEB-DL.JPN.ENG", "WEB", true, []string{"JPN", "ENG"}},
+		{"Show - 01 [Blu-ray][JPN][ENG SUB]", "BD", false, []string{"JPN"}},
+		{"Show - 01 (HDTV)", "TV", false, nil},
+		{"Show (TV) - May Day", "", false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DetectReleaseTags(tt.name)
+			if got.Source != tt.source || got.Dual != tt.dual || !reflect.DeepEqual(got.AudioLangs, tt.langs) {
+				t.Errorf("DetectReleaseTags(%q) = %+v; want source=%q dual=%v langs=%v", tt.name, got, tt.source, tt.dual, tt.langs)
+			}
+		})
+	}
+}
+
+func TestFormatResolution(t *testing.T) {
+	tests := []struct {
+		res, format, want string
+	}{
+		{"4K", "", "2160p"},
+		{"uhd", types.ResFormatP, "2160p"},
+		{"8k", types.ResFormatP, "4320p"},
+		{"FHD", "", "1080p"},
+		{"1920x1080", "", "1080p"},
+		{"1080P", "", "1080p"},
+		{"1080i", "", "1080i"},
+		{"2160p", types.ResFormatK, "4K"},
+		{"UHD", types.ResFormatK, "4K"},
+		{"1080p", types.ResFormatK, "1080p"},
+		{"4K", types.ResFormatOriginal, "4K"},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		if got := FormatResolution(tt.res, tt.format); got != tt.want {
+			t.Errorf("FormatResolution(%q, %q) = %q; want %q", tt.res, tt.format, got, tt.want)
+		}
+	}
+
+	p, err := Compile("Series - {{EP_NUM}} [{{RES}}].{{EXT}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"Series - 01 [4K].mkv", "Series - 01 [UHD].mkv", "Series - 01 [2160p].mkv"} {
+		if m, ok := p.MatchTyped(name); !ok || FormatResolution(m.Resolution, "") != "2160p" {
+			t.Errorf("%s: expected RES to normalize to 2160p, got %+v", name, m)
+		}
+	}
+}
+
+func TestFormatAirDate(t *testing.T) {
+	tests := []struct {
+		airDate, format, want string
+	}{
+		{"2013-04-07", "", "2013-04-07"},
+		{"2013-04-07T00:00:00+00:00", "YYYY.MM.DD", "2013.04.07"},
+		{"2013-04-07T23:30:00+09:00", "YY-MM-DD", "13-04-07"},
+		{"", "", ""},
+		{"not a date", "", ""},
+	}
+	for _, tt := range tests {
+		if got := FormatAirDate(tt.airDate, tt.format); got != tt.want {
+			t.Errorf("FormatAirDate(%q, %q) = %q; want %q", tt.airDate, tt.format, got, tt.want)
+		}
+	}
+
+	got, err := GenerateFilenameFromFields([]string{"SERIES", "AIR_DATE", "EP_NAME"}, " - ",
+		TemplateVars{Series: "Show", AirDate: "2013.04.07", EpName: "Title", Ext: "mkv"}, 2)
+	if err != nil || got != "Show - 2013.04.07 - Title.mkv" {
+		t.Errorf("Unexpected filename %q (%v)", got, err)
+	}
+}
+
+func TestCustomPlaceholders(t *testing.T) {
+	t.Cleanup(func() { _ = SetCustomPlaceholders(nil) })
+	err := SetCustomPlaceholders([]types.PlaceholderDef{
+		{Name: "CRC", Regex: `[0-9A-Fa-f]{8}`, Transform: "upper"},
+		{Name: "{{CHECKSUM8}}", Regex: `[0-9a-f]{8}`},
+	})
+	if err != nil {
+		t.Fatalf("SetCustomPlaceholders failed: %v", err)
+	}
+
+	p, err := Compile("[Group] Show - {{EP_NUM}} [{{CRC}}].{{EXT}}")
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	result, ok := p.MatchTyped("[Group] Show - 05 [ab12cd34].mkv")
+	if !ok {
+		t.Fatal("Expected a match")
+	}
+	if result.EpisodeNum != 5 || result.Custom["CRC"] != "AB12CD34" {
+		t.Errorf("Got episode %d, CRC %q", result.EpisodeNum, result.Custom["CRC"])
+	}
+
+	vars := TemplateVars{Series: "Show", EpNum: "5", Ext: "mkv", Custom: result.Custom}
+	got, err := GenerateFilenameFromFields([]string{"SERIES", "EP_NUM", "[CRC]", "CHECKSUM8"}, " - ", vars, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Show - 05 - [AB12CD34].mkv"; got != want {
+		t.Errorf("Got %q, want %q (an unmatched placeholder renders empty)", got, want)
+	}
+
+	for _, def := range []types.PlaceholderDef{
+		{Name: "crc", Regex: `\w+`},
+		{Name: "EP_NUM", Regex: `\d+`},
+		{Name: "HASH", Regex: `[`},
+		{Name: "HASH", Regex: `\w+`, Transform: "reverse"},
+	} {
+		if err := SetCustomPlaceholders([]types.PlaceholderDef{def}); err == nil {
+			t.Errorf("Expected %+v to be rejected", def)
+		}
+	}
+	if _, ok := customPlaceholder("CRC"); !ok {
+		t.Error("A rejected set should keep the previous placeholders")
+	}
+}
+
+func TestCustomPlaceholderComplexity(t *testing.T) {
+	t.Cleanup(func() { _ = SetCustomPlaceholders(nil) })
+	if err := SetCustomPlaceholders([]types.PlaceholderDef{
+		{Name: "CRC32", Regex: `[0-9A-F]{8}`},
+		{Name: "TAGS", Regex: `\[[^\]]+\]`},
+		{Name: "NOTE", Regex: `.+`},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		wantErr  bool
+	}{
+		{"Fixed width next to wildcard", "{{SERIES}}{{CRC32}} - {{EP_NUM}}.{{EXT}}", false},
+		{"Fenced next to wildcard", "{{SERIES}}{{TAGS}} - {{EP_NUM}}.{{EXT}}", false},
+		{"Unbounded next to wildcard", "{{SERIES}}{{NOTE}} - {{EP_NUM}}.{{EXT}}", true},
+		{"Unbounded pair", "{{NOTE}}{{ANY}} - {{EP_NUM}}.{{EXT}}", true},
+		{"Unbounded counts as wildcard", strings.Repeat("{{NOTE}}-", MaxWildcards+1) + "{{EP_NUM}}.{{EXT}}", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compile(tt.template)
+			var tooComplex types.ErrPatternTooComplex
+			if got := errors.As(err, &tooComplex); got != tt.wantErr {
+				t.Errorf("Compile(%q) error = %v; want too complex: %v", tt.template, err, tt.wantErr)
+			}
+		})
+	}
+}
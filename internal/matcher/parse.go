@@ -0,0 +1,114 @@
+package matcher
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/mydehq/autotitle/internal/types"
+)
+
+var (
+	reGroup       = regexp.MustCompile(`^\s*\[([^\]]+)\]`)
+	reVersion     = regexp.MustCompile(`(?i)(?:\d|\b)v(\d)\b`)
+	reSeason      = regexp.MustCompile(`(?i)\bS(\d+)\s*[Ex]\s*\d+|\bSeason\s*(\d+)`)
+	reYear        = regexp.MustCompile(`(?:^|[\s._(\[])((?:19|20)\d{2})(?:$|[\s._)\]])`)
+	reTagBrackets = regexp.MustCompile(`\[[^\]]*\]|\([^)]*\)|\{[^}]*\}`)
+	reEpPrefix    = regexp.MustCompile(`(?i)(\bS\d+\s*E?|\bSeason\s*\d+|\bEpisode|\bEp\.?|\bE|\bPart|\bPt\.?)\s*$`)
+	reSpaces      = regexp.MustCompile(`\s+`)
+)
+
+// SeriesName extracts the series name from a release filename: the text before
+// the episode (or part) number of the pattern GuessPattern detects, minus tags
+func SeriesName(filename string) string {
+	return seriesFromPattern(GuessPattern(filename))
+}
+
+func seriesFromPattern(pattern string) string {
+	head := strings.TrimSuffix(pattern, ".{{EXT}}")
+	for _, marker := range []string{PlaceholderEpNum, "{{PART}}"} {
+		if idx := strings.Index(head, marker); idx >= 0 {
+			head = head[:idx]
+		}
+	}
+
+	head = reTagBrackets.ReplaceAllString(head, " ")
+	head = RePlaceholder.ReplaceAllString(head, " ")
+	head = strings.NewReplacer(".", " ", "_", " ").Replace(head)
+	head = strings.Trim(head, " -")
+	head = reEpPrefix.ReplaceAllString(head, "")
+	head = strings.Trim(head, " -")
+	return reSpaces.ReplaceAllString(head, " ")
+}
+
+// ParseFilename breaks a release filename down into its elements (title,
+// episode, group, resolution, ...) with the same detection a rename uses
+func ParseFilename(filename string) types.ParsedFilename {
+	ext := filepath.Ext(filename)
+	base := strings.TrimSuffix(filename, ext)
+	pattern := GuessPattern(filename)
+
+	parsed := types.ParsedFilename{
+		FileName:      filename,
+		FileExtension: strings.TrimPrefix(ext, "."),
+		AnimeTitle:    seriesFromPattern(pattern),
+		Pattern:       pattern,
+	}
+
+	// Match the guessed pattern back, reading the masked episode title
+	if p, err := Compile(episodeTitlePattern(pattern)); err == nil {
+		if m := p.regex.FindStringSubmatch(base); m != nil {
+			if i := getFirstSubexpIndex(p.regex, "EpNum"); i >= 0 {
+				parsed.EpisodeNumber = m[i]
+			}
+			if i := getFirstSubexpIndex(p.regex, "Part"); i >= 0 {
+				parsed.Part = m[i]
+			}
+			if i := getFirstSubexpIndex(p.regex, "EpName"); i >= 0 {
+				parsed.EpisodeTitle = strings.TrimSpace(m[i])
+			}
+		}
+	}
+
+	if m := reGroup.FindStringSubmatch(base); m != nil && !reRes.MatchString(m[1]) && !reCRC.MatchString(m[0]) {
+		parsed.ReleaseGroup = m[1]
+	}
+	if m := reCRC.FindString(base); m != "" {
+		parsed.FileChecksum = strings.Trim(m, "[]")
+	}
+	if m := reRes.FindString(base); m != "" {
+		parsed.VideoResolution = m
+	}
+	if m := reVersion.FindStringSubmatch(base); m != nil {
+		parsed.ReleaseVersion = m[1]
+	}
+	if m := reSeason.FindStringSubmatch(base); m != nil {
+		parsed.AnimeSeason = m[1] + m[2]
+	}
+	if m := reYear.FindStringSubmatch(base); m != nil && m[1] != parsed.EpisodeNumber {
+		parsed.AnimeYear = m[1]
+	}
+
+	tags := DetectReleaseTags(base)
+	parsed.Source = tags.Source
+	parsed.Language = tags.AudioLangs
+	if tags.Dual {
+		parsed.AudioTerm = "Dual Audio"
+	}
+	return parsed
+}
+
+// episodeTitlePattern turns the {{ANY}} GuessPattern puts over an episode
+// title (the first unbracketed one after the last episode number) into {{EP_NAME}}
+func episodeTitlePattern(pattern string) string {
+	idx := strings.LastIndex(pattern, PlaceholderEpNum)
+	if idx < 0 {
+		return pattern
+	}
+	trailer := pattern[idx:]
+	at := strings.Index(trailer, PlaceholderAny)
+	if at < 0 || strings.HasSuffix(trailer[:at], "[") {
+		return pattern
+	}
+	return pattern[:idx] + trailer[:at] + PlaceholderEpName + trailer[at+len(PlaceholderAny):]
+}
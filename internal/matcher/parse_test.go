@@ -0,0 +1,57 @@
+package matcher
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSeriesName(t *testing.T) {
+	tests := []struct {
+		filename string
+		want     string
+	}{
+		{"[SubsPlease] Frieren - 01 [1080p].mkv", "Frieren"},
+		{"Frieren.S01E02.1080p.WEB-DL.mkv", "Frieren"},
+		{"Dungeon Meshi Episode 3.mkv", "Dungeon Meshi"},
+		{"[Group] Akira Part 1 [BD].mkv", "Akira"},
+		{"Your Name (2016).mkv", "Your Name"},
+	}
+	for _, tt := range tests {
+		if got := SeriesName(tt.filename); got != tt.want {
+			t.Errorf("SeriesName(%q) = %q; want %q", tt.filename, got, tt.want)
+		}
+	}
+}
+
+func TestParseFilename(t *testing.T) {
+	p := ParseFilename("[SubsPlease] Frieren - 01v2 - The Journey's End [1080p] [JPN ENG] [ABCD1234].mkv")
+	checks := map[string][2]string{
+		"file_extension":   {p.FileExtension, "mkv"},
+		"anime_title":      {p.AnimeTitle, "Frieren"},
+		"episode_number":   {p.EpisodeNumber, "01"},
+		"episode_title":    {p.EpisodeTitle, "The Journey's End"},
+		"release_group":    {p.ReleaseGroup, "SubsPlease"},
+		"release_version":  {p.ReleaseVersion, "2"},
+		"video_resolution": {p.VideoResolution, "1080p"},
+		"file_checksum":    {p.FileChecksum, "ABCD1234"},
+		"audio_term":       {p.AudioTerm, "Dual Audio"},
+	}
+	for key, c := range checks {
+		if c[0] != c[1] {
+			t.Errorf("%s = %q; want %q (pattern %q)", key, c[0], c[1], p.Pattern)
+		}
+	}
+	if !slices.Equal(p.Language, []string{"JPN", "ENG"}) {
+		t.Errorf("language = %v", p.Language)
+	}
+
+	p = ParseFilename("Frieren.S02E05.2023.WEB-DL.mkv")
+	if p.AnimeTitle != "Frieren" || p.AnimeSeason != "02" || p.EpisodeNumber != "05" || p.AnimeYear != "2023" || p.Source != "WEB" {
+		t.Errorf("Unexpected parse: %+v", p)
+	}
+
+	p = ParseFilename("Akira (1988) Part 2.mkv")
+	if p.AnimeTitle != "Akira" || p.Part != "2" || p.EpisodeNumber != "" || p.AnimeYear != "1988" {
+		t.Errorf("Unexpected parse: %+v", p)
+	}
+}
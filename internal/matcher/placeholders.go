@@ -0,0 +1,141 @@
+package matcher
+
+import (
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/mydehq/autotitle/internal/types"
+)
+
+// customGroupPrefix keeps the capture groups of custom placeholders apart
+// from the built-in ones (e.g. a custom "EPNUM" next to EP_NUM's "EpNum")
+const customGroupPrefix = "Custom_"
+
+var (
+	customMu     sync.RWMutex
+	customByName map[string]types.PlaceholderDef
+
+	reCustomName = regexp.MustCompile(`^[A-Z][A-Z0-9]*(?:_[A-Z0-9]+)*$`)
+)
+
+// customGroup is a custom placeholder captured by a compiled pattern
+type customGroup struct {
+	idx       int
+	name      string
+	transform string
+}
+
+// SetCustomPlaceholders replaces the custom placeholders (placeholders in the
+// global config). Names are upper case like the built-in ones, which they
+// may not shadow; on error the previous set is kept.
+func SetCustomPlaceholders(defs []types.PlaceholderDef) error {
+	next, err := parseCustomPlaceholders(defs)
+	if err != nil {
+		return err
+	}
+
+	customMu.Lock()
+	customByName = next
+	customMu.Unlock()
+	return nil
+}
+
+// CheckCustomPlaceholders reports the first error SetCustomPlaceholders would
+// return for defs, without installing them
+func CheckCustomPlaceholders(defs []types.PlaceholderDef) error {
+	_, err := parseCustomPlaceholders(defs)
+	return err
+}
+
+func parseCustomPlaceholders(defs []types.PlaceholderDef) (map[string]types.PlaceholderDef, error) {
+	next := make(map[string]types.PlaceholderDef, len(defs))
+	for i, d := range defs {
+		d.Name = strings.TrimSuffix(strings.TrimPrefix(d.Name, "{{"), "}}")
+		switch {
+		case !reCustomName.MatchString(d.Name):
+			return nil, fmt.Errorf("placeholder %d: invalid name %q (use upper case letters, digits and _)", i, d.Name)
+		case isBuiltinPlaceholder(d.Name):
+			return nil, fmt.Errorf("placeholder %s: shadows a built-in placeholder", d.Name)
+		case next[d.Name].Name != "":
+			return nil, fmt.Errorf("placeholder %s: defined twice", d.Name)
+		case d.Regex == "":
+			return nil, fmt.Errorf("placeholder %s: regex is required", d.Name)
+		}
+		if _, err := regexp.Compile(d.Regex); err != nil {
+			return nil, fmt.Errorf("placeholder %s: invalid regex %q: %w", d.Name, d.Regex, err)
+		}
+		switch d.Transform {
+		case "", "upper", "lower":
+		default:
+			return nil, fmt.Errorf("placeholder %s: unknown transform %q (use upper or lower)", d.Name, d.Transform)
+		}
+		next[d.Name] = d
+	}
+	return next, nil
+}
+
+// customPlaceholder returns the custom placeholder called name
+func customPlaceholder(name string) (types.PlaceholderDef, bool) {
+	customMu.RLock()
+	defer customMu.RUnlock()
+	d, ok := customByName[name]
+	return d, ok
+}
+
+// isWildcard reports whether the placeholder called name can match text of
+// any length: a built-in wildcard, or a custom placeholder whose regex
+// repeats without bound and is not fenced in by literal text on both sides
+// (as \[[^\]]+\] is)
+func isWildcard(name string) bool {
+	if wildcardPlaceholders[name] {
+		return true
+	}
+	def, ok := customPlaceholder(name)
+	if !ok {
+		return false
+	}
+	re, err := syntax.Parse(def.Regex, syntax.Perl)
+	if err != nil {
+		return true
+	}
+	re = re.Simplify()
+	if !unbounded(re) {
+		return false
+	}
+	return !(re.Op == syntax.OpConcat && len(re.Sub) > 1 &&
+		re.Sub[0].Op == syntax.OpLiteral && re.Sub[len(re.Sub)-1].Op == syntax.OpLiteral)
+}
+
+// unbounded reports whether re can match arbitrarily long text
+func unbounded(re *syntax.Regexp) bool {
+	switch re.Op {
+	case syntax.OpStar, syntax.OpPlus:
+		return true
+	case syntax.OpRepeat:
+		if re.Max == -1 {
+			return true
+		}
+	}
+	return slices.ContainsFunc(re.Sub, unbounded)
+}
+
+// isBuiltinPlaceholder reports whether name is a built-in placeholder or field
+func isBuiltinPlaceholder(name string) bool {
+	_, ok := placeholderRegexMap[name]
+	return ok || name == "EXT" || isBuiltinField(name)
+}
+
+// applyTransform renders a captured value as its placeholder's transform says
+func applyTransform(value, transform string) string {
+	switch transform {
+	case "upper":
+		return strings.ToUpper(value)
+	case "lower":
+		return strings.ToLower(value)
+	}
+	return value
+}
@@ -0,0 +1,70 @@
+package matcher
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/mydehq/autotitle/internal/util"
+)
+
+// ReleaseTags are quality tokens found in a release filename
+type ReleaseTags struct {
+	Source     string   // BD, WEB, TV or DVD
+	Dual       bool     // Dual/multi audio release
+	AudioLangs []string // ISO 639-2 audio languages, e.g. ["JPN", "ENG"]
+}
+
+var reReleaseToken = regexp.MustCompile(`[A-Za-z0-9]+`)
+
+// releaseSources maps lowercase source tokens to their normalized name.
+// A bare "TV" is left out: it is too common in titles ("Show (TV)").
+var releaseSources = map[string]string{
+	"bd": "BD", "bluray": "BD", "bdrip": "BD", "brrip": "BD", "bdremux": "BD", "bdmv": "BD",
+	"web": "WEB", "webdl": "WEB", "webrip": "WEB",
+	"hdtv": "TV", "tvrip": "TV",
+	"dvd": "DVD", "dvdrip": "DVD",
+}
+
+// DetectReleaseTags finds source, dual-audio and audio language tokens in a
+// filename (without extension)
+func DetectReleaseTags(name string) ReleaseTags {
+	var tags ReleaseTags
+	tokens := reReleaseToken.FindAllString(name, -1)
+
+	for i, tok := range tokens {
+		lower := strings.ToLower(tok)
+		next := ""
+		if i+1 < len(tokens) {
+			next = strings.ToLower(tokens[i+1])
+		}
+
+		// "WEB-DL" and "Blu-ray" arrive as two tokens
+		if src, ok := releaseSources[lower+next]; ok && tags.Source == "" {
+			tags.Source = src
+			continue
+		}
+		if src, ok := releaseSources[lower]; ok && tags.Source == "" {
+			tags.Source = src
+			continue
+		}
+
+		switch {
+		case lower == "dual" || lower == "multi" || lower == "dualaudio" || lower == "multiaudio":
+			tags.Dual = true
+		case len(tok) == 3 && tok == strings.ToUpper(tok) && !strings.HasPrefix(next, "sub"):
+			// Only upper-case three-letter codes (JPN, ENG); words are too
+			// likely to be part of the title
+			if l, ok := util.LookupLanguage(lower); ok && (l.ISO2 == lower || slices.Contains(l.Aliases, lower)) {
+				if code := strings.ToUpper(l.ISO2); !slices.Contains(tags.AudioLangs, code) {
+					tags.AudioLangs = append(tags.AudioLangs, code)
+				}
+			}
+		}
+	}
+
+	if len(tags.AudioLangs) > 1 {
+		tags.Dual = true
+	}
+	return tags
+}
@@ -0,0 +1,50 @@
+package matcher
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mydehq/autotitle/internal/types"
+)
+
+var reResHeight = regexp.MustCompile(`(?i)^(?:\d{3,4}x)?(\d{3,4})([pi]?)$`)
+
+// resolutionNames maps resolutions written as names to their line count
+var resolutionNames = map[string]int{
+	"fhd": 1080,
+	"uhd": 2160,
+	"4k":  2160,
+	"8k":  4320,
+}
+
+// FormatResolution normalizes a matched resolution ("4K", "UHD", "1920x1080",
+// "1080P") to the display form of format. Unrecognized values are returned
+// unchanged, as is everything with types.ResFormatOriginal.
+func FormatResolution(res, format string) string {
+	if res == "" || format == types.ResFormatOriginal {
+		return res
+	}
+
+	height, scan := 0, "p"
+	if h, ok := resolutionNames[strings.ToLower(res)]; ok {
+		height = h
+	} else if m := reResHeight.FindStringSubmatch(res); m != nil {
+		height, _ = strconv.Atoi(m[1])
+		if strings.EqualFold(m[2], "i") {
+			scan = "i"
+		}
+	} else {
+		return res
+	}
+
+	if format == types.ResFormatK && scan == "p" {
+		switch height {
+		case 2160:
+			return "4K"
+		case 4320:
+			return "8K"
+		}
+	}
+	return strconv.Itoa(height) + scan
+}
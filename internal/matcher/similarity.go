@@ -0,0 +1,289 @@
+package matcher
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/mydehq/autotitle/internal/types"
+)
+
+// DefaultSimilarity is the metric used unless matching.similarity names another
+const DefaultSimilarity = "dice"
+
+var (
+	similarityMu sync.RWMutex
+	similarities = map[string]types.SimilarityMetric{}
+	configured   string // Named by matching.similarity
+	override     string // Named by SetSimilarity; wins over the config
+)
+
+func init() {
+	for _, m := range []types.SimilarityMetric{diceMetric{}, levenshteinMetric{}, jaroWinklerMetric{}, tokenSetMetric{}} {
+		RegisterSimilarity(m)
+	}
+}
+
+// RegisterSimilarity adds a metric that matching.similarity (or
+// SetSimilarity) can then select; a metric of the same name is replaced
+func RegisterSimilarity(m types.SimilarityMetric) {
+	similarityMu.Lock()
+	defer similarityMu.Unlock()
+	similarities[m.Name()] = m
+}
+
+// SetSimilarity selects the registered metric Similarity uses, process-wide
+// and over matching.similarity; "" goes back to the configured one. On
+// error the current metric is kept.
+func SetSimilarity(name string) error {
+	return selectSimilarity(&override, name)
+}
+
+// SetConfiguredSimilarity selects the metric named by matching.similarity
+// ("" for the default); SetSimilarity overrides it
+func SetConfiguredSimilarity(name string) error {
+	return selectSimilarity(&configured, name)
+}
+
+// CheckSimilarity reports an error unless name is "" or a registered metric
+func CheckSimilarity(name string) error {
+	similarityMu.RLock()
+	defer similarityMu.RUnlock()
+	return checkSimilarity(name)
+}
+
+func checkSimilarity(name string) error {
+	if _, ok := similarities[name]; name != "" && !ok {
+		return fmt.Errorf("unknown similarity metric %q (use %s)", name, strings.Join(listSimilarities(), ", "))
+	}
+	return nil
+}
+
+func selectSimilarity(slot *string, name string) error {
+	similarityMu.Lock()
+	defer similarityMu.Unlock()
+	if err := checkSimilarity(name); err != nil {
+		return err
+	}
+	*slot = name
+	return nil
+}
+
+// currentSimilarity returns the selected metric
+func currentSimilarity() types.SimilarityMetric {
+	similarityMu.RLock()
+	defer similarityMu.RUnlock()
+	for _, name := range []string{override, configured} {
+		if m, ok := similarities[name]; ok {
+			return m
+		}
+	}
+	return similarities[DefaultSimilarity]
+}
+
+// ListSimilarities returns the names of the registered metrics, sorted
+func ListSimilarities() []string {
+	similarityMu.RLock()
+	defer similarityMu.RUnlock()
+	return listSimilarities()
+}
+
+func listSimilarities() []string {
+	names := make([]string, 0, len(similarities))
+	for name := range similarities {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Similarity scores how alike two titles are with the selected metric, from
+// 0 (unrelated) to 1 (same normalized title)
+func Similarity(a, b string) float64 {
+	a, b = NormalizeTitle(a), NormalizeTitle(b)
+	if a == "" || b == "" {
+		return 0
+	}
+	if a == b {
+		return 1
+	}
+	return min(max(currentSimilarity().Score(a, b), 0), 1)
+}
+
+// NormalizeTitle reduces a title to lower case letters and digits separated
+// by single spaces, for grouping and comparison
+func NormalizeTitle(name string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteRune(r)
+			space = false
+		} else {
+			space = true
+		}
+	}
+	return b.String()
+}
+
+// diceMetric is the Sørensen–Dice coefficient of character bigrams: robust
+// to reordered words and small typos
+type diceMetric struct{}
+
+func (diceMetric) Name() string { return "dice" }
+
+func (diceMetric) Score(a, b string) float64 {
+	bigrams := func(s string) map[string]int {
+		m := make(map[string]int)
+		r := []rune(s)
+		for i := 0; i+1 < len(r); i++ {
+			m[string(r[i:i+2])]++
+		}
+		return m
+	}
+	ba, bb := bigrams(a), bigrams(b)
+	total := 0
+	for _, n := range ba {
+		total += n
+	}
+	for _, n := range bb {
+		total += n
+	}
+	if total == 0 {
+		return 0
+	}
+
+	shared := 0
+	for g, n := range ba {
+		shared += min(n, bb[g])
+	}
+	return 2 * float64(shared) / float64(total)
+}
+
+// levenshteinMetric is one minus the edit distance over the longer length:
+// strict, for titles that differ only by typos
+type levenshteinMetric struct{}
+
+func (levenshteinMetric) Name() string { return "levenshtein" }
+
+func (levenshteinMetric) Score(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	return 1 - float64(levenshtein(ra, rb))/float64(max(len(ra), len(rb)))
+}
+
+// levenshtein returns the edit distance of a and b
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// jaroWinklerMetric is the Jaro-Winkler similarity: favours titles sharing
+// their start, e.g. a full title and its truncated form
+type jaroWinklerMetric struct{}
+
+func (jaroWinklerMetric) Name() string { return "jaro-winkler" }
+
+func (jaroWinklerMetric) Score(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	window := max(max(len(ra), len(rb))/2-1, 0)
+
+	matchedA := make([]bool, len(ra))
+	matchedB := make([]bool, len(rb))
+	matches := 0
+	for i := range ra {
+		for j := max(0, i-window); j < min(len(rb), i+window+1); j++ {
+			if !matchedB[j] && ra[i] == rb[j] {
+				matchedA[i], matchedB[j] = true, true
+				matches++
+				break
+			}
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+
+	transpositions, j := 0, 0
+	for i := range ra {
+		if !matchedA[i] {
+			continue
+		}
+		for !matchedB[j] {
+			j++
+		}
+		if ra[i] != rb[j] {
+			transpositions++
+		}
+		j++
+	}
+	m := float64(matches)
+	jaro := (m/float64(len(ra)) + m/float64(len(rb)) + (m-float64(transpositions/2))/m) / 3
+
+	prefix := 0
+	for prefix < min(4, len(ra), len(rb)) && ra[prefix] == rb[prefix] {
+		prefix++
+	}
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
+
+// tokenSetMetric is the token set ratio: words both titles share count in
+// full, so a title contained in another ("Frieren" in "Sousou no Frieren")
+// scores 1. For release names with extra or missing words.
+type tokenSetMetric struct{}
+
+func (tokenSetMetric) Name() string { return "token-set" }
+
+func (tokenSetMetric) Score(a, b string) float64 {
+	ta, tb := uniqueWords(a), uniqueWords(b)
+	var shared, onlyA, onlyB []string
+	for _, w := range ta {
+		if slices.Contains(tb, w) {
+			shared = append(shared, w)
+		} else {
+			onlyA = append(onlyA, w)
+		}
+	}
+	for _, w := range tb {
+		if !slices.Contains(ta, w) {
+			onlyB = append(onlyB, w)
+		}
+	}
+
+	base := strings.Join(shared, " ")
+	withA := strings.TrimSpace(base + " " + strings.Join(onlyA, " "))
+	withB := strings.TrimSpace(base + " " + strings.Join(onlyB, " "))
+	ratio := func(x, y string) float64 {
+		if x == "" || y == "" {
+			return 0
+		}
+		return levenshteinMetric{}.Score(x, y)
+	}
+	return max(ratio(base, withA), ratio(base, withB), ratio(withA, withB))
+}
+
+// uniqueWords returns the sorted distinct words of s
+func uniqueWords(s string) []string {
+	words := strings.Fields(s)
+	slices.Sort(words)
+	return slices.Compact(words)
+}
@@ -0,0 +1,76 @@
+package matcher
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestSimilarityMetrics(t *testing.T) {
+	tests := []struct {
+		metric string
+		a, b   string
+		want   float64
+	}{
+		{"dice", "night", "nacht", 0.25},
+		{"levenshtein", "kitten", "sitting", 1 - 3.0/7},
+		{"jaro-winkler", "martha", "marhta", 0.9611},
+		{"jaro-winkler", "dixon", "dicksonx", 0.8133},
+		{"token-set", "Frieren", "Sousou no Frieren", 1},
+		{"token-set", "Frieren Sousou no", "Sousou no Frieren", 1},
+		{"dice", "Frieren", "frieren!", 1},
+		{"levenshtein", "", "Frieren", 0},
+	}
+	t.Cleanup(func() { _ = SetSimilarity("") })
+	for _, tt := range tests {
+		if err := SetSimilarity(tt.metric); err != nil {
+			t.Fatal(err)
+		}
+		if got := Similarity(tt.a, tt.b); math.Abs(got-tt.want) > 0.001 {
+			t.Errorf("%s(%q, %q) = %.4f, want %.4f", tt.metric, tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+// constMetric scores every pair the same
+type constMetric float64
+
+func (constMetric) Name() string                { return "const" }
+func (m constMetric) Score(a, b string) float64 { return float64(m) }
+
+func TestSetSimilarity(t *testing.T) {
+	t.Cleanup(func() {
+		_ = SetSimilarity("")
+		_ = SetConfiguredSimilarity("")
+	})
+
+	if err := SetSimilarity("soundex"); err == nil {
+		t.Error("SetSimilarity(soundex) = nil, want an error")
+	}
+	if got := currentSimilarity().Name(); got != DefaultSimilarity {
+		t.Errorf("metric after a failed SetSimilarity = %s, want %s", got, DefaultSimilarity)
+	}
+
+	RegisterSimilarity(constMetric(2))
+	if !slices.Contains(ListSimilarities(), "const") {
+		t.Errorf("ListSimilarities() = %v, want const included", ListSimilarities())
+	}
+	if err := SetConfiguredSimilarity("const"); err != nil {
+		t.Fatal(err)
+	}
+	if got := Similarity("a", "b"); got != 1 {
+		t.Errorf("Similarity with a custom metric = %v, want 1 (clamped)", got)
+	}
+
+	// SetSimilarity wins over the config until cleared
+	if err := SetSimilarity("levenshtein"); err != nil {
+		t.Fatal(err)
+	}
+	if got := currentSimilarity().Name(); got != "levenshtein" {
+		t.Errorf("metric = %s, want levenshtein", got)
+	}
+	_ = SetSimilarity("")
+	if got := currentSimilarity().Name(); got != "const" {
+		t.Errorf("metric after clearing the override = %s, want const", got)
+	}
+}
@@ -0,0 +1,45 @@
+package matcher
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mydehq/autotitle/internal/types"
+)
+
+// TitleCleaner applies compiled title rules to episode titles
+type TitleCleaner struct {
+	rules []compiledTitleRule
+}
+
+type compiledTitleRule struct {
+	re      *regexp.Regexp
+	replace string
+}
+
+// NewTitleCleaner compiles title rules in order. It returns an error naming
+// the first rule whose expression does not compile.
+func NewTitleCleaner(rules []types.TitleRule) (*TitleCleaner, error) {
+	c := &TitleCleaner{}
+	for i, r := range rules {
+		re, err := regexp.Compile(r.Match)
+		if err != nil {
+			return nil, fmt.Errorf("title rule %d: invalid regex %q: %w", i, r.Match, err)
+		}
+		c.rules = append(c.rules, compiledTitleRule{re: re, replace: r.Replace})
+	}
+	return c, nil
+}
+
+// Clean applies every rule in order and trims surrounding whitespace.
+// A nil cleaner returns the title unchanged.
+func (c *TitleCleaner) Clean(title string) string {
+	if c == nil || len(c.rules) == 0 {
+		return title
+	}
+	for _, r := range c.rules {
+		title = r.re.ReplaceAllString(title, r.replace)
+	}
+	return strings.TrimSpace(title)
+}
@@ -0,0 +1,160 @@
+// Package mediaserver asks Plex and Jellyfin to rescan folders autotitle
+// renamed, so new titles show up without waiting for the scheduled scan.
+package mediaserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mydehq/autotitle/internal/types"
+)
+
+// Client is the HTTP client used for refresh requests
+var Client = &http.Client{Timeout: 15 * time.Second}
+
+// Name returns a display name for a server, e.g. "Plex (http://nas:32400)"
+func Name(s types.MediaServer) string {
+	switch s.Type {
+	case types.MediaServerPlex:
+		return fmt.Sprintf("Plex (%s)", s.URL)
+	case types.MediaServerJellyfin:
+		return fmt.Sprintf("Jellyfin (%s)", s.URL)
+	}
+	return s.URL
+}
+
+// Refresh asks s to rescan dir, an absolute local path. Plex scans only the
+// library section holding dir, limited to dir; Jellyfin is told dir changed.
+func Refresh(ctx context.Context, s types.MediaServer, dir string) error {
+	if s.URL == "" {
+		return fmt.Errorf("url is required")
+	}
+	path := ServerPath(s, dir)
+	switch s.Type {
+	case types.MediaServerPlex:
+		return refreshPlex(ctx, s, path)
+	case types.MediaServerJellyfin:
+		return refreshJellyfin(ctx, s, path)
+	default:
+		return fmt.Errorf("unknown media server type %q (use plex or jellyfin)", s.Type)
+	}
+}
+
+// ServerPath maps a local path to the path the server sees, using the
+// longest matching prefix in s.Paths (e.g. a NAS share mounted elsewhere)
+func ServerPath(s types.MediaServer, dir string) string {
+	best := ""
+	for local := range s.Paths {
+		if within(dir, local) && len(local) > len(best) {
+			best = local
+		}
+	}
+	if best == "" {
+		return dir
+	}
+	rest := strings.TrimPrefix(dir, strings.TrimRight(best, `/\`))
+	return strings.TrimRight(s.Paths[best], `/\`) + filepath.ToSlash(rest)
+}
+
+// within reports whether path is dir or inside it
+func within(path, dir string) bool {
+	dir = strings.TrimRight(dir, `/\`)
+	if path == dir {
+		return true
+	}
+	return strings.HasPrefix(path, dir) && strings.ContainsRune(`/\`, rune(path[len(dir)]))
+}
+
+// plexSections is the part of /library/sections autotitle reads
+type plexSections struct {
+	MediaContainer struct {
+		Directory []struct {
+			Key      string `json:"key"`
+			Title    string `json:"title"`
+			Location []struct {
+				Path string `json:"path"`
+			} `json:"Location"`
+		} `json:"Directory"`
+	} `json:"MediaContainer"`
+}
+
+func refreshPlex(ctx context.Context, s types.MediaServer, path string) error {
+	body, err := do(ctx, s, http.MethodGet, "/library/sections", nil)
+	if err != nil {
+		return err
+	}
+	var sections plexSections
+	if err := json.Unmarshal(body, &sections); err != nil {
+		return fmt.Errorf("failed to parse library sections: %w", err)
+	}
+
+	key, longest := "", -1
+	for _, d := range sections.MediaContainer.Directory {
+		for _, loc := range d.Location {
+			if within(path, loc.Path) && len(loc.Path) > longest {
+				key, longest = d.Key, len(loc.Path)
+			}
+		}
+	}
+	if key == "" {
+		return fmt.Errorf("no library contains %s", path)
+	}
+
+	_, err = do(ctx, s, http.MethodGet, "/library/sections/"+url.PathEscape(key)+"/refresh?path="+url.QueryEscape(path), nil)
+	return err
+}
+
+func refreshJellyfin(ctx context.Context, s types.MediaServer, path string) error {
+	payload, err := json.Marshal(map[string]any{
+		"Updates": []map[string]string{{"Path": path, "UpdateType": "Modified"}},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = do(ctx, s, http.MethodPost, "/Library/Media/Updated", payload)
+	return err
+}
+
+// do sends an authenticated request to the server and returns the body
+func do(ctx context.Context, s types.MediaServer, method, endpoint string, payload []byte) ([]byte, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(s.URL, "/")+endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	switch s.Type {
+	case types.MediaServerPlex:
+		req.Header.Set("X-Plex-Token", s.Token)
+	case types.MediaServerJellyfin:
+		req.Header.Set("Authorization", fmt.Sprintf(`MediaBrowser Token="%s"`, s.Token))
+	}
+
+	resp, err := Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("%s %s: %s", method, endpoint, resp.Status)
+	}
+	return data, nil
+}
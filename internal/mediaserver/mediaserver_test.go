@@ -0,0 +1,106 @@
+package mediaserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mydehq/autotitle/internal/types"
+)
+
+func TestRefresh_Plex(t *testing.T) {
+	var refreshed string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Plex-Token") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/library/sections":
+			w.Write([]byte(`{"MediaContainer":{"Directory":[
+				{"key":"1","title":"Movies","Location":[{"path":"/data/movies"}]},
+				{"key":"2","title":"Anime","Location":[{"path":"/data/anime"},{"path":"/data/anime-archive"}]}
+			]}}`))
+		case "/library/sections/2/refresh":
+			refreshed = r.URL.Query().Get("path")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	s := types.MediaServer{
+		Type:  types.MediaServerPlex,
+		URL:   srv.URL + "/",
+		Token: "secret",
+		Paths: map[string]string{"/mnt/nas": "/data"},
+	}
+	if err := Refresh(context.Background(), s, "/mnt/nas/anime-archive/Frieren"); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if refreshed != "/data/anime-archive/Frieren" {
+		t.Errorf("Expected a scan of /data/anime-archive/Frieren, got %q", refreshed)
+	}
+
+	err := Refresh(context.Background(), s, "/mnt/nas/music/Album")
+	if err == nil || !strings.Contains(err.Error(), "no library contains") {
+		t.Errorf("Expected no matching library, got %v", err)
+	}
+
+	s.Token = "wrong"
+	if err := Refresh(context.Background(), s, "/mnt/nas/anime/Frieren"); err == nil {
+		t.Error("Expected an error for a rejected token")
+	}
+}
+
+func TestRefresh_Jellyfin(t *testing.T) {
+	var update struct {
+		Updates []struct{ Path, UpdateType string }
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/Library/Media/Updated" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Authorization") != `MediaBrowser Token="key"` {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	s := types.MediaServer{Type: types.MediaServerJellyfin, URL: srv.URL, Token: "key"}
+	if err := Refresh(context.Background(), s, "/srv/anime/Frieren"); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if len(update.Updates) != 1 || update.Updates[0].Path != "/srv/anime/Frieren" || update.Updates[0].UpdateType != "Modified" {
+		t.Errorf("Unexpected update %+v", update)
+	}
+}
+
+func TestServerPath(t *testing.T) {
+	s := types.MediaServer{Paths: map[string]string{
+		"/mnt/nas":       "/data",
+		"/mnt/nas/anime": "/anime/",
+	}}
+	cases := map[string]string{
+		"/mnt/nas/anime/Frieren": "/anime/Frieren",
+		"/mnt/nas/anime":         "/anime",
+		"/mnt/nas/movies":        "/data/movies",
+		"/mnt/nasty/x":           "/mnt/nasty/x",
+		"/home/me/Anime":         "/home/me/Anime",
+	}
+	for dir, want := range cases {
+		if got := ServerPath(s, dir); got != want {
+			t.Errorf("ServerPath(%q) = %q, want %q", dir, got, want)
+		}
+	}
+}
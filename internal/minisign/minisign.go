@@ -0,0 +1,113 @@
+// Package minisign verifies minisign signatures (https://jedisct1.github.io/minisign/),
+// used to check that a shared database file comes from someone the user trusts.
+package minisign
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+const (
+	algLegacy    = "Ed" // Signs the file itself (minisign -l)
+	algPrehashed = "ED" // Signs the BLAKE2b-512 of the file, minisign's default
+
+	trustedPrefix = "trusted comment: "
+)
+
+// PublicKey is an Ed25519 key, with the minisign key ID when it has one
+type PublicKey struct {
+	ID  []byte // 8 bytes, nil for a bare Ed25519 key that matches any signature
+	Key ed25519.PublicKey
+}
+
+// ParsePublicKey reads a key as minisign prints it ("RW..."), optionally with
+// the "untrusted comment:" line of a .pub file, or a bare base64 Ed25519 key
+func ParsePublicKey(s string) (PublicKey, error) {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	encoded := strings.TrimSpace(lines[len(lines)-1])
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return PublicKey{}, fmt.Errorf("invalid public key %q: %w", encoded, err)
+	}
+	switch {
+	case len(raw) == 42 && string(raw[:2]) == algLegacy:
+		return PublicKey{ID: raw[2:10], Key: ed25519.PublicKey(raw[10:])}, nil
+	case len(raw) == ed25519.PublicKeySize:
+		return PublicKey{Key: ed25519.PublicKey(raw)}, nil
+	}
+	return PublicKey{}, fmt.Errorf("invalid public key %q: not a minisign or Ed25519 key", encoded)
+}
+
+// Signature is a parsed .minisig file
+type Signature struct {
+	Algorithm      string
+	KeyID          []byte
+	Signature      []byte
+	TrustedComment string
+	GlobalSig      []byte // Signs Signature and TrustedComment
+}
+
+// ParseSignature reads the contents of a .minisig file
+func ParseSignature(data []byte) (Signature, error) {
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	if len(lines) < 4 || !strings.HasPrefix(lines[2], trustedPrefix) {
+		return Signature{}, fmt.Errorf("not a minisign signature")
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil || len(raw) != 74 {
+		return Signature{}, fmt.Errorf("not a minisign signature")
+	}
+	global, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+	if err != nil || len(global) != ed25519.SignatureSize {
+		return Signature{}, fmt.Errorf("invalid minisign global signature")
+	}
+	sig := Signature{
+		Algorithm:      string(raw[:2]),
+		KeyID:          raw[2:10],
+		Signature:      raw[10:],
+		TrustedComment: strings.TrimPrefix(lines[2], trustedPrefix),
+		GlobalSig:      global,
+	}
+	if sig.Algorithm != algLegacy && sig.Algorithm != algPrehashed {
+		return Signature{}, fmt.Errorf("unsupported minisign algorithm %q", sig.Algorithm)
+	}
+	return sig, nil
+}
+
+// Verify checks that one of keys signed data, returning the signature's
+// trusted comment
+func Verify(keys []PublicKey, data []byte, sig Signature) (string, error) {
+	msg := data
+	if sig.Algorithm == algPrehashed {
+		sum := blake2b.Sum512(data)
+		msg = sum[:]
+	}
+	for _, k := range keys {
+		if k.ID != nil && !bytes.Equal(k.ID, sig.KeyID) {
+			continue
+		}
+		if !ed25519.Verify(k.Key, msg, sig.Signature) {
+			continue
+		}
+		// The trusted comment is only trusted once its signature checks out
+		if !ed25519.Verify(k.Key, append(bytes.Clone(sig.Signature), sig.TrustedComment...), sig.GlobalSig) {
+			return "", fmt.Errorf("invalid signature of the trusted comment")
+		}
+		return sig.TrustedComment, nil
+	}
+	return "", fmt.Errorf("not signed by a trusted key (key ID %X)", reverse(sig.KeyID))
+}
+
+// reverse returns the key ID in the byte order minisign displays it in
+func reverse(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i, c := range b {
+		r[len(b)-1-i] = c
+	}
+	return r
+}
@@ -0,0 +1,71 @@
+package minisign
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// sign builds a .minisig file the way minisign does
+func sign(priv ed25519.PrivateKey, keyID []byte, alg string, data []byte, comment string) []byte {
+	msg := data
+	if alg == algPrehashed {
+		sum := blake2b.Sum512(data)
+		msg = sum[:]
+	}
+	sig := ed25519.Sign(priv, msg)
+	global := ed25519.Sign(priv, append(append([]byte{}, sig...), comment...))
+	raw := append(append([]byte(alg), keyID...), sig...)
+	return fmt.Appendf(nil, "untrusted comment: signature from minisign secret key\n%s\ntrusted comment: %s\n%s\n",
+		base64.StdEncoding.EncodeToString(raw), comment, base64.StdEncoding.EncodeToString(global))
+}
+
+func TestVerify(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	keyID := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	encoded := base64.StdEncoding.EncodeToString(append(append([]byte(algLegacy), keyID...), pub...))
+	key, err := ParsePublicKey("untrusted comment: minisign public key\n" + encoded)
+	if err != nil {
+		t.Fatalf("ParsePublicKey failed: %v", err)
+	}
+	data := []byte(`{"title":"Frieren"}`)
+
+	for _, alg := range []string{algLegacy, algPrehashed} {
+		sig, err := ParseSignature(sign(priv, keyID, alg, data, "timestamp:1700000000\tfile:frieren.json"))
+		if err != nil {
+			t.Fatalf("%s: ParseSignature failed: %v", alg, err)
+		}
+		comment, err := Verify([]PublicKey{key}, data, sig)
+		if err != nil || !strings.HasPrefix(comment, "timestamp:") {
+			t.Errorf("%s: Verify = %q, %v", alg, comment, err)
+		}
+		if _, err := Verify([]PublicKey{key}, append(data, ' '), sig); err == nil {
+			t.Errorf("%s: a modified file should not verify", alg)
+		}
+	}
+
+	// A bare Ed25519 key matches any key ID; another key never verifies
+	bare, err := ParsePublicKey(base64.StdEncoding.EncodeToString(pub))
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPub, _, _ := ed25519.GenerateKey(nil)
+	sig, _ := ParseSignature(sign(priv, []byte("otherkey"), algPrehashed, data, "c"))
+	if _, err := Verify([]PublicKey{bare}, data, sig); err != nil {
+		t.Errorf("bare key: %v", err)
+	}
+	if _, err := Verify([]PublicKey{{Key: otherPub}}, data, sig); err == nil {
+		t.Error("expected an untrusted key to fail")
+	}
+
+	// The trusted comment is covered by the global signature
+	tampered := strings.Replace(string(sign(priv, keyID, algPrehashed, data, "file:a.json")), "file:a.json", "file:b.json", 1)
+	sig, _ = ParseSignature([]byte(tampered))
+	if _, err := Verify([]PublicKey{key}, data, sig); err == nil {
+		t.Error("expected a tampered trusted comment to fail")
+	}
+}
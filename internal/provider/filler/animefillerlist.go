@@ -3,6 +3,7 @@ package filler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -17,6 +18,14 @@ import (
 
 const fillerListURL = "https://www.animefillerlist.com/shows"
 
+// MaxPageSize caps how much of a filler list page is read. Real pages are a
+// few hundred KB; anything far larger is an error page loop or garbage.
+var MaxPageSize int64 = 8 << 20
+
+// maxTokenSize caps a single HTML token, so a malformed page (e.g. an
+// unterminated attribute) can't grow the tokenizer's buffer unbounded
+const maxTokenSize = 1 << 20
+
 // aflURLPatterns are URL patterns that this filler source handles
 var aflURLPatterns = []string{
 	"animefillerlist.com/shows/",
@@ -25,7 +34,8 @@ var aflURLPatterns = []string{
 
 // AnimeFillerListSource implements FillerSource for AnimeFillerList.com
 type AnimeFillerListSource struct {
-	client *http.Client
+	client  *http.Client
+	baseURL string
 }
 
 // NewAnimeFillerListSource creates a new AnimeFillerList source
@@ -34,9 +44,16 @@ func NewAnimeFillerListSource() *AnimeFillerListSource {
 		client: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		baseURL: fillerListURL,
 	}
 }
 
+// WithBaseURL points the source at another shows endpoint (mirrors, testing)
+func (s *AnimeFillerListSource) WithBaseURL(url string) *AnimeFillerListSource {
+	s.baseURL = strings.TrimSuffix(url, "/")
+	return s
+}
+
 // Name returns the filler source identifier
 func (s *AnimeFillerListSource) Name() string {
 	return "animefillerlist"
@@ -52,6 +69,23 @@ func (s *AnimeFillerListSource) SupportedURLs() []string {
 	return aflURLPatterns
 }
 
+// Schema returns the page layout the scraper targets
+func (s *AnimeFillerListSource) Schema() types.Schema {
+	return types.Schema{Target: "animefillerlist.com show pages (tr.filler > td.Number)", Checked: "2026-10-15"}
+}
+
+// HealthCheck fetches a show known to have fillers and checks some are found
+func (s *AnimeFillerListSource) HealthCheck(ctx context.Context) error {
+	fillers, err := s.FetchFillers(ctx, "naruto")
+	if err != nil {
+		return err
+	}
+	if len(fillers) == 0 {
+		return fmt.Errorf("no fillers found for naruto; the page layout may have changed")
+	}
+	return nil
+}
+
 // MatchesURL returns true if this source can handle the given URL
 func (s *AnimeFillerListSource) MatchesURL(url string) bool {
 	for _, pattern := range aflURLPatterns {
@@ -79,8 +113,8 @@ func DeriveURLFromProvider(providerURL string) string {
 		return ""
 	}
 	slug := parts[len(parts)-1]
-	if slug == "" {
-		return ""
+	if slug == "" || strings.Trim(slug, "0123456789") == "" {
+		return "" // No title slug, only an ID
 	}
 	slug = strings.ToLower(slug)
 	slug = strings.ReplaceAll(slug, "_", "-")
@@ -90,7 +124,7 @@ func DeriveURLFromProvider(providerURL string) string {
 
 // FetchFillers fetches filler episode numbers from AnimeFillerList
 func (s *AnimeFillerListSource) FetchFillers(ctx context.Context, slug string) ([]int, error) {
-	url := fmt.Sprintf("%s/%s", fillerListURL, slug)
+	url := fmt.Sprintf("%s/%s", s.baseURL, slug)
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -117,80 +151,114 @@ func (s *AnimeFillerListSource) FetchFillers(ctx context.Context, slug string) (
 		}
 	}
 
-	return parseFillerHTML(resp.Body)
+	if ct := resp.Header.Get("Content-Type"); ct != "" && !strings.HasPrefix(ct, "text/html") {
+		return nil, types.ErrAPIError{
+			Service:    "AnimeFillerList",
+			StatusCode: resp.StatusCode,
+			Message:    fmt.Sprintf("expected an HTML page for %s, got %s", slug, ct),
+		}
+	}
+	if resp.ContentLength > MaxPageSize {
+		return nil, fmt.Errorf("filler list page for %s is larger than %d bytes", slug, MaxPageSize)
+	}
+
+	fillers, err := parseFillerHTML(&sizeGuard{r: resp.Body, left: MaxPageSize})
+	if errors.Is(err, errPageTooLarge) {
+		return nil, fmt.Errorf("filler list page for %s is larger than %d bytes", slug, MaxPageSize)
+	}
+	return fillers, err
 }
 
-func parseFillerHTML(r io.Reader) ([]int, error) {
-	doc, err := html.Parse(r)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+// errPageTooLarge is returned by sizeGuard once its limit is passed
+var errPageTooLarge = errors.New("page too large")
+
+// sizeGuard reads from r until left bytes are used up, then fails. Unlike
+// io.LimitReader it reports the overrun, so a truncated page is never
+// taken for a complete one.
+type sizeGuard struct {
+	r    io.Reader
+	left int64
+}
+
+func (g *sizeGuard) Read(p []byte) (int, error) {
+	if g.left <= 0 {
+		return 0, errPageTooLarge
 	}
+	if int64(len(p)) > g.left {
+		p = p[:g.left]
+	}
+	n, err := g.r.Read(p)
+	g.left -= int64(n)
+	return n, err
+}
+
+// parseFillerHTML streams the page through a tokenizer and collects the
+// episode numbers of filler rows, without building the whole document tree.
+// Rows look like <tr class="filler ..."><td class="Number">12</td>...</tr>;
+// every filler kind ("filler", "mostly_filler", "mixed_filler") counts, but
+// "canon" rows don't.
+func parseFillerHTML(r io.Reader) ([]int, error) {
+	z := html.NewTokenizer(r)
+	z.SetMaxBuf(maxTokenSize)
 
 	var fillers []int
 	seen := make(map[int]bool)
-	var crawler func(*html.Node)
-
-	crawler = func(node *html.Node) {
-		// Look for table rows: <tr class="filler ...">
-		if node.Type == html.ElementNode && node.Data == "tr" {
-			class := getAttr(node, "class")
-			// Capture all filler types:
-			// - "filler" - pure filler
-			// - "mostly-filler" or "mostly_filler" - mostly filler content
-			// - "mixed-filler" or "mixed_filler" - mixed filler/canon
-			// Exclude "canon" only entries
-			isFiller := strings.Contains(class, "filler") && !strings.HasPrefix(strings.TrimSpace(class), "canon")
-			if isFiller {
-				// Find <td class="Number">
-				if td := findChildByClass(node, "td", "Number"); td != nil {
-					text := getText(td)
-					var num int
-					if _, err := fmt.Sscanf(strings.TrimSpace(text), "%d", &num); err == nil {
-						if !seen[num] {
-							fillers = append(fillers, num)
-							seen[num] = true
-						}
-					}
-				}
+	inFiller, inNumber := false, false
+	var number strings.Builder
+
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if err := z.Err(); err != io.EOF {
+				return nil, fmt.Errorf("failed to parse HTML: %w", err)
 			}
-		}
-		for c := node.FirstChild; c != nil; c = c.NextSibling {
-			crawler(c)
-		}
-	}
+			return fillers, nil
 
-	crawler(doc)
-	return fillers, nil
-}
+		case html.StartTagToken:
+			tn, hasAttr := z.TagName()
+			tag := string(tn)
+			if tag != "tr" && !(tag == "td" && inFiller) {
+				continue
+			}
+			var class string
+			for hasAttr {
+				var key, val []byte
+				key, val, hasAttr = z.TagAttr()
+				if string(key) == "class" {
+					class = string(val)
+				}
+			}
+			if tag == "tr" {
+				inFiller = strings.Contains(class, "filler") && !strings.HasPrefix(strings.TrimSpace(class), "canon")
+				inNumber = false
+			} else if strings.Contains(class, "Number") {
+				inNumber = true
+				number.Reset()
+			}
 
-func getAttr(n *html.Node, key string) string {
-	for _, a := range n.Attr {
-		if a.Key == key {
-			return a.Val
-		}
-	}
-	return ""
-}
+		case html.TextToken:
+			if inNumber {
+				number.Write(z.Text())
+			}
 
-func findChildByClass(n *html.Node, tag, class string) *html.Node {
-	// Direct child check
-	for c := n.FirstChild; c != nil; c = c.NextSibling {
-		if c.Type == html.ElementNode && c.Data == tag && strings.Contains(getAttr(c, "class"), class) {
-			return c
+		case html.EndTagToken:
+			tn, _ := z.TagName()
+			switch string(tn) {
+			case "td":
+				if !inNumber {
+					continue
+				}
+				inNumber = false
+				var num int
+				if _, err := fmt.Sscanf(strings.TrimSpace(number.String()), "%d", &num); err == nil && !seen[num] {
+					fillers = append(fillers, num)
+					seen[num] = true
+				}
+			case "tr":
+				inFiller, inNumber = false, false
+			}
 		}
 	}
-	return nil
-}
-
-func getText(n *html.Node) string {
-	if n.Type == html.TextNode {
-		return n.Data
-	}
-	var text string
-	for c := n.FirstChild; c != nil; c = c.NextSibling {
-		text += getText(c)
-	}
-	return text
 }
 
 // init registers the AnimeFillerList source
@@ -0,0 +1,54 @@
+package filler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const aflPage = `<html><body><table class="EpisodeList"><tbody>
+<tr class="manga_canon even"><td class="Number">1</td><td class="Title"><a>Start</a></td></tr>
+<tr class="filler odd"><td class="Number">2</td><td class="Title"><a>Side Story</a></td></tr>
+<tr class="mixed_canon/filler even"><td class="Number"> 3 </td><td class="Title">Mixed</td></tr>
+<tr class="filler odd"><td class="Number">2</td><td class="Title">Listed twice</td></tr>
+</tbody></table></body></html>`
+
+func TestFetchFillers(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/naruto":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(aflPage))
+		case "/json":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{}`))
+		case "/huge":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(aflPage + strings.Repeat("<p>error</p>", 1000)))
+		}
+	}))
+	defer srv.Close()
+	s := NewAnimeFillerListSource().WithBaseURL(srv.URL)
+
+	fillers, err := s.FetchFillers(context.Background(), "naruto")
+	if err != nil {
+		t.Fatalf("FetchFillers failed: %v", err)
+	}
+	if !slices.Equal(fillers, []int{2, 3}) {
+		t.Errorf("Expected fillers [2 3], got %v", fillers)
+	}
+
+	if _, err := s.FetchFillers(context.Background(), "json"); err == nil {
+		t.Error("Expected an error for a non-HTML response")
+	}
+
+	old := MaxPageSize
+	MaxPageSize = int64(len(aflPage))
+	t.Cleanup(func() { MaxPageSize = old })
+	if _, err := s.FetchFillers(context.Background(), "huge"); err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Errorf("Expected a size error, got %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mydehq/autotitle/internal/types"
+)
+
+// healthTimeout bounds each health check, so one unreachable site doesn't
+// hold up the report
+const healthTimeout = 20 * time.Second
+
+// Health is the parser schema and live health of a provider or filler source
+type Health struct {
+	Name    string        `json:"name"`
+	Kind    string        `json:"kind"`             // provider or filler
+	Schema  *types.Schema `json:"schema,omitempty"` // nil if not reported
+	Checked bool          `json:"checked"`          // Has a health check
+	Error   string        `json:"error,omitempty"`  // Why the check failed
+	Latency time.Duration `json:"latency,omitempty"`
+}
+
+// OK reports whether the health check ran and passed
+func (h Health) OK() bool {
+	return h.Checked && h.Error == ""
+}
+
+// CheckHealth reports the schema of every registered provider and filler
+// source and runs their health checks concurrently. The providers checked
+// are fresh instances configured with cfg (API keys, base URLs; nil for the
+// defaults), so a check can run alongside renames.
+func CheckHealth(ctx context.Context, cfg *types.APIConfig) []Health {
+	type target struct {
+		name, kind string
+		impl       any
+	}
+	var targets []target
+	for _, newProvider := range factories {
+		p := newProvider(cfg)
+		targets = append(targets, target{p.Name(), "provider", p})
+	}
+	for _, s := range fillerSources {
+		targets = append(targets, target{s.Name(), "filler", s})
+	}
+
+	report := make([]Health, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		report[i] = Health{Name: t.name, Kind: t.kind}
+		if sr, ok := t.impl.(types.SchemaReporter); ok {
+			schema := sr.Schema()
+			report[i].Schema = &schema
+		}
+		hc, ok := t.impl.(types.HealthChecker)
+		if !ok {
+			continue
+		}
+		report[i].Checked = true
+		wg.Add(1)
+		go func(h *Health) {
+			defer wg.Done()
+			cctx, cancel := context.WithTimeout(ctx, healthTimeout)
+			defer cancel()
+			start := time.Now()
+			if err := hc.HealthCheck(cctx); err != nil {
+				h.Error = err.Error()
+			}
+			h.Latency = time.Since(start)
+		}(&report[i])
+	}
+	wg.Wait()
+	return report
+}
+
+// searchHealth checks a provider by searching for a title it is sure to
+// have: the search must succeed and its results parse into IDs and titles
+func searchHealth(ctx context.Context, p types.Provider, query string) error {
+	results, err := p.Search(ctx, query)
+	if err != nil {
+		return err
+	}
+	if len(results) == 0 {
+		return fmt.Errorf("searching %q found nothing; the parser may be outdated", query)
+	}
+	for _, r := range results {
+		if r.ID == "" || r.Title == "" {
+			return fmt.Errorf("search results for %q have no ID or title; the parser may be outdated", query)
+		}
+	}
+	return nil
+}
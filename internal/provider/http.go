@@ -8,18 +8,30 @@ import (
 	"time"
 
 	"github.com/mydehq/autotitle/internal/types"
+	"github.com/mydehq/autotitle/internal/version"
 )
 
+// userAgent identifies autotitle to APIs that ask clients to (MusicBrainz,
+// MangaDex) instead of posing as a browser
+func userAgent() string {
+	return "autotitle/" + version.Get() + " ( https://github.com/mydehq/autotitle )"
+}
+
 // DoWithRetry executes an HTTP request with exponential backoff for 429 errors.
-func DoWithRetry(ctx context.Context, client *http.Client, req *http.Request, service string, preRequest func()) (*http.Response, error) {
+func DoWithRetry(ctx context.Context, client *http.Client, req *http.Request, service string, preRequest func(context.Context)) (*http.Response, error) {
 	const maxRetries = 3
 	for i := 0; i <= maxRetries; i++ {
 		if preRequest != nil {
-			preRequest()
+			preRequest(ctx)
+		}
+		// Mimic a modern browser to avoid being flagged by WAFs/Gateways, unless
+		// the caller identifies itself (APIs like MusicBrainz require that)
+		if req.Header.Get("User-Agent") == "" {
+			req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/122.0.0.0 Safari/537.36")
+		}
+		if req.Header.Get("Accept") == "" {
+			req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7")
 		}
-		// Mimic a modern browser to avoid being flagged by WAFs/Gateways
-		req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/122.0.0.0 Safari/537.36")
-		req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7")
 		req.Header.Set("Accept-Language", "en-US,en;q=0.9")
 		req.Header.Set("Cache-Control", "no-cache")
 		req.Header.Set("Pragma", "no-cache")
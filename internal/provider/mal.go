@@ -4,6 +4,7 @@ package provider
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -13,10 +14,14 @@ import (
 	"time"
 
 	"github.com/mydehq/autotitle/internal/types"
+	"github.com/mydehq/autotitle/internal/util"
 )
 
 const (
 	jikanAPIURL = "https://api.jikan.moe/v4"
+
+	// malTimeZone is the zone MAL air dates are local to (Japanese broadcast)
+	malTimeZone = "Asia/Tokyo"
 )
 
 // malURLPatterns are URL patterns that this provider handles
@@ -28,6 +33,7 @@ var malURLPatterns = []string{
 // MALProvider implements the Provider interface for MyAnimeList
 type MALProvider struct {
 	client    *http.Client
+	baseURL   string
 	rateLimit time.Duration
 }
 
@@ -47,12 +53,17 @@ func NewMALProvider(cfg *types.APIConfig) *MALProvider {
 		}
 	}
 
-	return &MALProvider{
+	p := &MALProvider{
 		client: &http.Client{
 			Timeout: timeout,
 		},
+		baseURL:   jikanAPIURL,
 		rateLimit: rateLimit,
 	}
+	if cfg != nil && cfg.BaseURLs[p.Name()] != "" {
+		p.baseURL = strings.TrimSuffix(cfg.BaseURLs[p.Name()], "/")
+	}
+	return p
 }
 
 // Name returns the provider identifier
@@ -76,6 +87,9 @@ func (p *MALProvider) Configure(cfg *types.APIConfig) {
 	if cfg.RateLimit > 0 {
 		p.rateLimit = time.Duration(float64(time.Second) / cfg.RateLimit)
 	}
+	if u := cfg.BaseURLs[p.Name()]; u != "" {
+		p.baseURL = strings.TrimSuffix(u, "/")
+	}
 }
 
 // Type returns the media type this provider handles
@@ -88,6 +102,16 @@ func (p *MALProvider) SupportedURLs() []string {
 	return malURLPatterns
 }
 
+// Schema returns the API the parser targets
+func (p *MALProvider) Schema() types.Schema {
+	return types.Schema{Target: "Jikan API v4", Checked: "2026-10-15"}
+}
+
+// HealthCheck searches for a well-known title and checks the results parse
+func (p *MALProvider) HealthCheck(ctx context.Context) error {
+	return searchHealth(ctx, p, "Cowboy Bebop")
+}
+
 // MatchesURL returns true if this provider can handle the given URL
 func (p *MALProvider) MatchesURL(url string) bool {
 	for _, pattern := range malURLPatterns {
@@ -110,6 +134,13 @@ func (p *MALProvider) ExtractID(url string) (string, error) {
 
 // FetchMedia fetches anime data from MyAnimeList via Jikan API
 func (p *MALProvider) FetchMedia(ctx context.Context, id string) (*types.Media, error) {
+	return p.FetchMediaFrom(ctx, id, nil)
+}
+
+// FetchMediaFrom fetches anime data, continuing the episode list of partial
+// from its ResumePage. If Jikan keeps rate limiting or ctx is canceled, the
+// episodes fetched so far are returned alongside the error.
+func (p *MALProvider) FetchMediaFrom(ctx context.Context, id string, partial *types.Media) (*types.Media, error) {
 	malID, err := strconv.Atoi(id)
 	if err != nil {
 		return nil, fmt.Errorf("invalid MAL ID: %s", id)
@@ -118,24 +149,52 @@ func (p *MALProvider) FetchMedia(ctx context.Context, id string) (*types.Media,
 	// Fetch anime info
 	info, err := p.fetchAnimeInfo(ctx, malID)
 	if err != nil {
+		if isRateLimit(err) {
+			return nil, types.ErrRateLimited{Service: "Jikan", LastPage: 0}
+		}
 		return nil, err
 	}
 
+	startPage := 1
+	var episodes []types.Episode
+	if partial != nil && partial.ResumePage > 1 {
+		startPage = partial.ResumePage
+		episodes = partial.Episodes
+	}
+
 	// Fetch episodes
-	episodes, err := p.fetchEpisodes(ctx, malID)
+	episodes, page, err := p.fetchEpisodes(ctx, malID, startPage, episodes)
 	if err != nil {
-		return nil, err
+		canceled := errors.Is(err, context.Canceled)
+		if !isRateLimit(err) && !canceled {
+			return nil, err
+		}
+		partial := &types.Media{
+			ID:         id,
+			Provider:   p.Name(),
+			Title:      info.Title,
+			Slug:       util.Slugify(info.Title),
+			Type:       info.Type,
+			Year:       info.Year,
+			Episodes:   episodes,
+			ResumePage: page,
+			LastUpdate: types.ClockFrom(ctx).Now(),
+		}
+		if canceled {
+			return partial, err
+		}
+		return partial, types.ErrRateLimited{Service: "Jikan", LastPage: page - 1}
 	}
 
 	// Calculate next episode air date
 	var nextEpisodeAirDate *string
-	now := time.Now()
+	now := types.ClockFrom(ctx).Now()
 
 	for _, ep := range episodes {
 		if ep.AirDate != "" {
-			// Jikan format: "2006-04-04T00:00:00+00:00"
-			t, err := time.Parse(time.RFC3339, ep.AirDate)
-			if err == nil && t.After(now) {
+			// Jikan format: "2006-04-04T00:00:00+00:00", a day in Japan
+			t, ok := util.AiredBy(ep.AirDate, malTimeZone)
+			if ok && t.After(now) {
 				dateStr := ep.AirDate
 				nextEpisodeAirDate = &dateStr
 				break // Found the first future episode
@@ -149,14 +208,17 @@ func (p *MALProvider) FetchMedia(ctx context.Context, id string) (*types.Media,
 		Title:              info.Title,
 		TitleEN:            info.TitleEN,
 		TitleJP:            info.TitleJP,
-		Slug:               generateSlug(info.Title),
+		Slug:               util.Slugify(info.Title),
 		Aliases:            info.Aliases,
-		Type:               types.MediaTypeAnime,
+		Type:               info.Type,
+		Year:               info.Year,
+		Season:             info.Season,
 		Status:             info.Status,
 		NextEpisodeAirDate: nextEpisodeAirDate,
+		AirTimeZone:        malTimeZone,
 		Episodes:           episodes,
 		EpisodeCount:       len(episodes),
-		LastUpdate:         time.Now(),
+		LastUpdate:         now,
 	}, nil
 }
 
@@ -166,12 +228,15 @@ type animeInfoResponse struct {
 	TitleJP string
 	Aliases []string
 	Status  string
+	Type    types.MediaType
+	Year    int
+	Season  string
 }
 
 func (p *MALProvider) fetchAnimeInfo(ctx context.Context, malID int) (*animeInfoResponse, error) {
-	p.sleep()
+	p.sleep(ctx)
 
-	url := fmt.Sprintf("%s/anime/%d", jikanAPIURL, malID)
+	url := fmt.Sprintf("%s/anime/%d", p.baseURL, malID)
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
@@ -198,6 +263,17 @@ func (p *MALProvider) fetchAnimeInfo(ctx context.Context, malID int) (*animeInfo
 			TitleJapanese string   `json:"title_japanese"`
 			TitleSynonyms []string `json:"title_synonyms"`
 			Status        string   `json:"status"`
+			Type          string   `json:"type"`
+			Year          *int     `json:"year"`
+			Season        string   `json:"season"`
+			Aired         struct {
+				Prop struct {
+					From struct {
+						Year  *int `json:"year"`
+						Month *int `json:"month"`
+					} `json:"from"`
+				} `json:"prop"`
+			} `json:"aired"`
 		} `json:"data"`
 	}
 
@@ -211,30 +287,34 @@ func (p *MALProvider) fetchAnimeInfo(ctx context.Context, malID int) (*animeInfo
 		TitleJP: result.Data.TitleJapanese,
 		Aliases: result.Data.TitleSynonyms,
 		Status:  result.Data.Status,
+		Type:    malMediaType(result.Data.Type),
+		Year:    firstYear(result.Data.Year, result.Data.Aired.Prop.From.Year),
+		Season:  malSeason(result.Data.Season, result.Data.Aired.Prop.From.Month),
 	}, nil
 }
 
-func (p *MALProvider) fetchEpisodes(ctx context.Context, malID int) ([]types.Episode, error) {
-	var episodes []types.Episode
-	page := 1
+// fetchEpisodes appends episodes from startPage onwards. On error it returns the
+// episodes collected so far and the page that failed.
+func (p *MALProvider) fetchEpisodes(ctx context.Context, malID, startPage int, episodes []types.Episode) ([]types.Episode, int, error) {
+	page := startPage
 
 	for {
-		p.sleep()
+		p.sleep(ctx)
 
-		url := fmt.Sprintf("%s/anime/%d/episodes?page=%d", jikanAPIURL, malID, page)
+		url := fmt.Sprintf("%s/anime/%d/episodes?page=%d", p.baseURL, malID, page)
 		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 		if err != nil {
-			return nil, err
+			return episodes, page, err
 		}
 
 		resp, err := DoWithRetry(ctx, p.client, req, "Jikan", p.sleep)
 		if err != nil {
-			return nil, err
+			return episodes, page, err
 		}
 
 		if resp.StatusCode != http.StatusOK {
 			_ = resp.Body.Close()
-			return nil, types.ErrAPIError{
+			return episodes, page, types.ErrAPIError{
 				Service:    "Jikan",
 				StatusCode: resp.StatusCode,
 				Message:    fmt.Sprintf("failed to fetch episodes for anime %d", malID),
@@ -243,26 +323,41 @@ func (p *MALProvider) fetchEpisodes(ctx context.Context, malID int) ([]types.Epi
 
 		var result struct {
 			Data []struct {
-				MalID int    `json:"mal_id"`
-				Title string `json:"title"`
-				Aired string `json:"aired"`
+				MalID         int    `json:"mal_id"`
+				Title         string `json:"title"`
+				TitleJapanese string `json:"title_japanese"`
+				TitleRomanji  string `json:"title_romanji"`
+				Aired         string `json:"aired"`
 			} `json:"data"`
 			Pagination struct {
-				HasNextPage bool `json:"has_next_page"`
+				LastVisiblePage int  `json:"last_visible_page"`
+				HasNextPage     bool `json:"has_next_page"`
 			} `json:"pagination"`
 		}
 
 		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 			_ = resp.Body.Close()
-			return nil, fmt.Errorf("failed to parse episodes: %w", err)
+			return episodes, page, fmt.Errorf("failed to parse episodes: %w", err)
 		}
 		_ = resp.Body.Close()
 
 		for _, ep := range result.Data {
 			episodes = append(episodes, types.Episode{
-				Number:  ep.MalID,
-				Title:   ep.Title,
-				AirDate: ep.Aired,
+				Number:      ep.MalID,
+				Title:       ep.Title,
+				TitleJP:     ep.TitleJapanese,
+				TitleRomaji: ep.TitleRomanji,
+				AirDate:     ep.Aired,
+			})
+		}
+
+		if progress := types.ProgressFrom(ctx); progress != nil {
+			last := max(result.Pagination.LastVisiblePage, page)
+			progress(types.FetchProgress{
+				Page:     page,
+				LastPage: last,
+				Episodes: len(episodes),
+				ETA:      time.Duration(last-page) * p.rateLimit,
 			})
 		}
 
@@ -272,13 +367,13 @@ func (p *MALProvider) fetchEpisodes(ctx context.Context, malID int) ([]types.Epi
 		page++
 	}
 
-	return episodes, nil
+	return episodes, page, nil
 }
 
 func (p *MALProvider) Search(ctx context.Context, query string) ([]types.SearchResult, error) {
-	p.sleep()
+	p.sleep(ctx)
 
-	urlStr := fmt.Sprintf("%s/anime?q=%s", jikanAPIURL, url.QueryEscape(query))
+	urlStr := fmt.Sprintf("%s/anime?q=%s", p.baseURL, url.QueryEscape(query))
 	req, err := http.NewRequestWithContext(ctx, "GET", urlStr, nil)
 	if err != nil {
 		return nil, err
@@ -302,6 +397,7 @@ func (p *MALProvider) Search(ctx context.Context, query string) ([]types.SearchR
 		Data []struct {
 			MalID int    `json:"mal_id"`
 			Title string `json:"title"`
+			Type  string `json:"type"`
 			Year  *int   `json:"year"`
 			Aired struct {
 				Prop struct {
@@ -320,18 +416,12 @@ func (p *MALProvider) Search(ctx context.Context, query string) ([]types.SearchR
 
 	var searchResults []types.SearchResult
 	for _, item := range result.Data {
-		var year int
-		if item.Year != nil {
-			year = *item.Year
-		} else if item.Aired.Prop.From.Year != nil {
-			year = *item.Aired.Prop.From.Year
-		}
-
 		searchResults = append(searchResults, types.SearchResult{
 			Provider: p.Name(),
 			ID:       strconv.Itoa(item.MalID),
 			Title:    item.Title,
-			Year:     year,
+			Year:     firstYear(item.Year, item.Aired.Prop.From.Year),
+			Type:     malMediaType(item.Type),
 			URL:      item.URL,
 		})
 	}
@@ -339,20 +429,47 @@ func (p *MALProvider) Search(ctx context.Context, query string) ([]types.SearchR
 	return searchResults, nil
 }
 
-func (p *MALProvider) sleep() {
-	time.Sleep(p.rateLimit)
+// malMediaType maps a Jikan type ("TV", "Movie", "OVA", ...) to a MediaType
+func malMediaType(t string) types.MediaType {
+	if strings.EqualFold(t, "Movie") {
+		return types.MediaTypeMovie
+	}
+	return types.MediaTypeAnime
+}
+
+// malSeason returns the premiere season from Jikan's season ("fall"), or
+// from the premiere month for entries without one (OVAs, movies)
+func malSeason(season string, month *int) string {
+	if season != "" {
+		return strings.ToUpper(season[:1]) + season[1:]
+	}
+	if month != nil {
+		return util.SeasonName(time.Month(*month))
+	}
+	return ""
+}
+
+// firstYear returns the first non-nil year, or 0
+func firstYear(years ...*int) int {
+	for _, y := range years {
+		if y != nil {
+			return *y
+		}
+	}
+	return 0
+}
+
+// isRateLimit reports whether err is a 429 that outlasted the retry budget
+func isRateLimit(err error) bool {
+	var apiErr types.ErrAPIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusTooManyRequests
 }
 
-// generateSlug converts a title to a URL-safe slug
-func generateSlug(title string) string {
-	slug := strings.ToLower(title)
-	slug = regexp.MustCompile(`[^a-z0-9\s-]`).ReplaceAllString(slug, "")
-	slug = strings.ReplaceAll(slug, " ", "-")
-	slug = regexp.MustCompile(`-+`).ReplaceAllString(slug, "-")
-	return strings.Trim(slug, "-")
+func (p *MALProvider) sleep(ctx context.Context) {
+	types.ClockFrom(ctx).Sleep(p.rateLimit)
 }
 
 // init registers the MAL provider
 func init() {
-	RegisterProvider(NewMALProvider(nil))
+	RegisterProvider(func(cfg *types.APIConfig) types.Provider { return NewMALProvider(cfg) })
 }
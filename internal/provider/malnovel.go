@@ -0,0 +1,252 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mydehq/autotitle/internal/types"
+	"github.com/mydehq/autotitle/internal/util"
+)
+
+// malNovelURLPatterns are URL patterns that this provider handles
+var malNovelURLPatterns = []string{
+	"myanimelist.net/manga/",
+	"myanimelist.com/manga/",
+}
+
+// malNovelURLRe matches a MAL manga URL and captures its ID
+var malNovelURLRe = regexp.MustCompile(`myanimelist\.(?:net|com)/manga/(\d+)`)
+
+// MALNovelProvider implements the Provider interface for light novels on
+// MyAnimeList (via Jikan). MAL has no per-volume data beyond the volume
+// count, so the episodes are the volumes 1..n titled "Volume n". Manga
+// entries work the same way, for libraries kept as one file per volume.
+type MALNovelProvider struct {
+	client    *http.Client
+	baseURL   string
+	rateLimit time.Duration
+}
+
+// NewMALNovelProvider creates a new MAL light novel provider
+func NewMALNovelProvider(cfg *types.APIConfig) *MALNovelProvider {
+	p := &MALNovelProvider{
+		client:    &http.Client{Timeout: 30 * time.Second},
+		baseURL:   jikanAPIURL,
+		rateLimit: time.Second / 2,
+	}
+	p.Configure(cfg)
+	return p
+}
+
+// Name returns the provider identifier
+func (p *MALNovelProvider) Name() string {
+	return "mal-novel"
+}
+
+// Website returns the provider's website URL
+func (p *MALNovelProvider) Website() string {
+	return "https://myanimelist.net"
+}
+
+// Configure updates provider settings. Jikan is shared with the anime
+// provider, so a base URL set for "mal" applies here too.
+func (p *MALNovelProvider) Configure(cfg *types.APIConfig) {
+	if cfg == nil {
+		return
+	}
+	if cfg.Timeout > 0 {
+		p.client.Timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+	if cfg.RateLimit > 0 {
+		p.rateLimit = time.Duration(float64(time.Second) / cfg.RateLimit)
+	}
+	for _, name := range []string{"mal", p.Name()} {
+		if u := cfg.BaseURLs[name]; u != "" {
+			p.baseURL = strings.TrimSuffix(u, "/")
+		}
+	}
+}
+
+// Type returns the media type this provider handles
+func (p *MALNovelProvider) Type() types.MediaType {
+	return types.MediaTypeNovel
+}
+
+// SupportedURLs returns the URL patterns this provider handles
+func (p *MALNovelProvider) SupportedURLs() []string {
+	return malNovelURLPatterns
+}
+
+// Schema returns the API the parser targets
+func (p *MALNovelProvider) Schema() types.Schema {
+	return types.Schema{Target: "Jikan API v4 (manga)", Checked: "2026-10-15"}
+}
+
+// HealthCheck searches for a well-known title and checks the results parse
+func (p *MALNovelProvider) HealthCheck(ctx context.Context) error {
+	return searchHealth(ctx, p, "Spice and Wolf")
+}
+
+// MatchesURL returns true if this provider can handle the given URL
+func (p *MALNovelProvider) MatchesURL(url string) bool {
+	for _, pattern := range malNovelURLPatterns {
+		if strings.Contains(url, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExtractID extracts the MAL manga ID from a URL
+func (p *MALNovelProvider) ExtractID(url string) (string, error) {
+	if m := malNovelURLRe.FindStringSubmatch(url); m != nil {
+		return m[1], nil
+	}
+	return "", fmt.Errorf("could not extract MAL manga ID from URL: %s", url)
+}
+
+// jikanManga is a manga entry as Jikan returns it
+type jikanManga struct {
+	MalID         int      `json:"mal_id"`
+	Title         string   `json:"title"`
+	TitleEnglish  string   `json:"title_english"`
+	TitleJapanese string   `json:"title_japanese"`
+	TitleSynonyms []string `json:"title_synonyms"`
+	Type          string   `json:"type"`
+	Volumes       *int     `json:"volumes"`
+	Status        string   `json:"status"`
+	Published     struct {
+		Prop struct {
+			From struct {
+				Year *int `json:"year"`
+			} `json:"from"`
+		} `json:"prop"`
+	} `json:"published"`
+}
+
+// mediaType returns novel for (light) novels and manga for everything else
+func (m *jikanManga) mediaType() types.MediaType {
+	if strings.Contains(m.Type, "Novel") {
+		return types.MediaTypeNovel
+	}
+	return types.MediaTypeManga
+}
+
+// FetchMedia fetches a light novel and lists its volumes
+func (p *MALNovelProvider) FetchMedia(ctx context.Context, id string) (*types.Media, error) {
+	var result struct {
+		Data jikanManga `json:"data"`
+	}
+	if err := p.get(ctx, "/manga/"+url.PathEscape(id), &result); err != nil {
+		return nil, err
+	}
+	m := result.Data
+	if m.Volumes == nil {
+		// MAL only fills in the count once a series has finished
+		return nil, fmt.Errorf("MAL lists no volume count for %s yet", m.Title)
+	}
+
+	volumes := make([]types.Episode, *m.Volumes)
+	for i := range volumes {
+		n := i + 1
+		volumes[i] = types.Episode{
+			Number: n,
+			Title:  fmt.Sprintf("Volume %d", n),
+			Volume: fmt.Sprintf("%d", n),
+		}
+	}
+
+	return &types.Media{
+		ID:           id,
+		Provider:     p.Name(),
+		Title:        m.Title,
+		TitleEN:      m.TitleEnglish,
+		TitleJP:      m.TitleJapanese,
+		Slug:         util.Slugify(m.Title),
+		Aliases:      m.TitleSynonyms,
+		Type:         m.mediaType(),
+		Year:         firstYear(m.Published.Prop.From.Year),
+		Status:       malPublishingStatus(m.Status),
+		Episodes:     volumes,
+		EpisodeCount: len(volumes),
+		LastUpdate:   types.ClockFrom(ctx).Now(),
+	}, nil
+}
+
+// malPublishingStatus maps a MAL publishing status to the airing statuses
+// the database uses, so finished series aren't refetched on every run
+func malPublishingStatus(status string) string {
+	switch status {
+	case "Finished", "Discontinued":
+		return "Finished Airing"
+	case "Publishing", "On Hiatus":
+		return "Currently Airing"
+	case "Not yet published":
+		return "Not yet aired"
+	}
+	return status
+}
+
+// Search queries MAL for light novels
+func (p *MALNovelProvider) Search(ctx context.Context, query string) ([]types.SearchResult, error) {
+	var result struct {
+		Data []jikanManga `json:"data"`
+	}
+	if err := p.get(ctx, "/manga?q="+url.QueryEscape(query)+"&type=lightnovel&limit=10", &result); err != nil {
+		return nil, err
+	}
+
+	var searchResults []types.SearchResult
+	for _, m := range result.Data {
+		searchResults = append(searchResults, types.SearchResult{
+			Provider: p.Name(),
+			ID:       fmt.Sprintf("%d", m.MalID),
+			Title:    m.Title,
+			Year:     firstYear(m.Published.Prop.From.Year),
+			Type:     m.mediaType(),
+			URL:      fmt.Sprintf("https://myanimelist.net/manga/%d", m.MalID),
+		})
+	}
+	return searchResults, nil
+}
+
+// get fetches a Jikan API endpoint and decodes the JSON response into v
+func (p *MALNovelProvider) get(ctx context.Context, endpoint string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+endpoint, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := DoWithRetry(ctx, p.client, req, "Jikan", p.sleep)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return types.ErrAPIError{
+			Service:    "Jikan",
+			StatusCode: resp.StatusCode,
+			Message:    fmt.Sprintf("request to %s failed", strings.SplitN(endpoint, "?", 2)[0]),
+		}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse Jikan response: %w", err)
+	}
+	return nil
+}
+
+func (p *MALNovelProvider) sleep(ctx context.Context) {
+	types.ClockFrom(ctx).Sleep(p.rateLimit)
+}
+
+// init registers the MAL light novel provider
+func init() {
+	RegisterProvider(func(cfg *types.APIConfig) types.Provider { return NewMALNovelProvider(cfg) })
+}
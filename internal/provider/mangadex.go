@@ -0,0 +1,303 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mydehq/autotitle/internal/types"
+	"github.com/mydehq/autotitle/internal/util"
+)
+
+const mangaDexAPIURL = "https://api.mangadex.org"
+
+// mangaDexFeedPage is the largest page the chapter feed returns
+const mangaDexFeedPage = 500
+
+// mangaDexURLPatterns are URL patterns that this provider handles
+var mangaDexURLPatterns = []string{
+	"mangadex.org/title/",
+}
+
+// mangaDexURLRe matches a title URL and captures its UUID
+var mangaDexURLRe = regexp.MustCompile(`mangadex\.org/title/([0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12})`)
+
+// MangaDexProvider implements the Provider interface for manga on MangaDex.
+// Chapters are the episodes, numbered as published; English chapter titles
+// and volumes are used. Fractional chapters (extras like 34.5) can't be
+// matched by number and are left out.
+type MangaDexProvider struct {
+	client    *http.Client
+	baseURL   string
+	rateLimit time.Duration
+}
+
+// NewMangaDexProvider creates a new MangaDex provider
+func NewMangaDexProvider(cfg *types.APIConfig) *MangaDexProvider {
+	p := &MangaDexProvider{
+		client:    &http.Client{Timeout: 30 * time.Second},
+		baseURL:   mangaDexAPIURL,
+		rateLimit: time.Second / 2,
+	}
+	p.Configure(cfg)
+	return p
+}
+
+// Name returns the provider identifier
+func (p *MangaDexProvider) Name() string {
+	return "mangadex"
+}
+
+// Website returns the provider's website URL
+func (p *MangaDexProvider) Website() string {
+	return "https://mangadex.org"
+}
+
+// Configure updates provider settings
+func (p *MangaDexProvider) Configure(cfg *types.APIConfig) {
+	if cfg == nil {
+		return
+	}
+	if cfg.Timeout > 0 {
+		p.client.Timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+	if cfg.RateLimit > 0 {
+		p.rateLimit = time.Duration(float64(time.Second) / cfg.RateLimit)
+	}
+	if u := cfg.BaseURLs[p.Name()]; u != "" {
+		p.baseURL = strings.TrimSuffix(u, "/")
+	}
+}
+
+// Type returns the media type this provider handles
+func (p *MangaDexProvider) Type() types.MediaType {
+	return types.MediaTypeManga
+}
+
+// SupportedURLs returns the URL patterns this provider handles
+func (p *MangaDexProvider) SupportedURLs() []string {
+	return mangaDexURLPatterns
+}
+
+// Schema returns the API the parser targets
+func (p *MangaDexProvider) Schema() types.Schema {
+	return types.Schema{Target: "MangaDex API v5", Checked: "2026-10-15"}
+}
+
+// HealthCheck searches for a well-known title and checks the results parse
+func (p *MangaDexProvider) HealthCheck(ctx context.Context) error {
+	return searchHealth(ctx, p, "Berserk")
+}
+
+// MatchesURL returns true if this provider can handle the given URL
+func (p *MangaDexProvider) MatchesURL(url string) bool {
+	for _, pattern := range mangaDexURLPatterns {
+		if strings.Contains(url, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExtractID extracts the manga UUID from a URL
+func (p *MangaDexProvider) ExtractID(url string) (string, error) {
+	if m := mangaDexURLRe.FindStringSubmatch(url); m != nil {
+		return m[1], nil
+	}
+	return "", fmt.Errorf("could not extract MangaDex title ID from URL: %s", url)
+}
+
+// mdManga is the attributes of a MangaDex manga
+type mdManga struct {
+	Title     map[string]string   `json:"title"`
+	AltTitles []map[string]string `json:"altTitles"`
+	Year      int                 `json:"year"`
+	Status    string              `json:"status"`
+}
+
+// title returns the English title, or the first one given
+func (m *mdManga) title() string {
+	if t := m.Title["en"]; t != "" {
+		return t
+	}
+	for _, t := range m.Title {
+		return t
+	}
+	return ""
+}
+
+// FetchMedia fetches a manga and its English chapter list from MangaDex
+func (p *MangaDexProvider) FetchMedia(ctx context.Context, id string) (*types.Media, error) {
+	var manga struct {
+		Data struct {
+			Attributes mdManga `json:"attributes"`
+		} `json:"data"`
+	}
+	if err := p.get(ctx, "/manga/"+url.PathEscape(id), &manga); err != nil {
+		return nil, err
+	}
+	attrs := manga.Data.Attributes
+
+	var chapters []types.Episode
+	seen := make(map[int]int) // Chapter number -> index; groups often upload the same chapter
+	for offset := 0; ; offset += mangaDexFeedPage {
+		var feed struct {
+			Data []struct {
+				Attributes struct {
+					Volume    *string `json:"volume"`
+					Chapter   *string `json:"chapter"`
+					Title     *string `json:"title"`
+					PublishAt string  `json:"publishAt"`
+				} `json:"attributes"`
+			} `json:"data"`
+			Total int `json:"total"`
+		}
+		endpoint := fmt.Sprintf("/manga/%s/feed?translatedLanguage[]=en&order[chapter]=asc&limit=%d&offset=%d",
+			url.PathEscape(id), mangaDexFeedPage, offset)
+		if err := p.get(ctx, endpoint, &feed); err != nil {
+			return nil, err
+		}
+
+		for _, c := range feed.Data {
+			if c.Attributes.Chapter == nil {
+				continue // Oneshots have no chapter number
+			}
+			num, err := strconv.Atoi(*c.Attributes.Chapter)
+			if err != nil {
+				continue
+			}
+			ch := types.Episode{Number: num}
+			if c.Attributes.Title != nil {
+				ch.Title = *c.Attributes.Title
+			}
+			if c.Attributes.Volume != nil {
+				ch.Volume = *c.Attributes.Volume
+			}
+			if t, err := time.Parse(time.RFC3339, c.Attributes.PublishAt); err == nil {
+				ch.AirDate = t.UTC().Format(time.DateOnly)
+			}
+			if i, ok := seen[num]; ok {
+				// Fill in what the earlier upload left out
+				if chapters[i].Title == "" {
+					chapters[i].Title = ch.Title
+				}
+				if chapters[i].Volume == "" {
+					chapters[i].Volume = ch.Volume
+				}
+				continue
+			}
+			seen[num] = len(chapters)
+			chapters = append(chapters, ch)
+		}
+
+		if len(feed.Data) == 0 || offset+mangaDexFeedPage >= feed.Total {
+			break
+		}
+	}
+
+	title := attrs.title()
+	media := &types.Media{
+		ID:           id,
+		Provider:     p.Name(),
+		Title:        title,
+		TitleEN:      attrs.Title["en"],
+		Slug:         util.Slugify(title),
+		Type:         types.MediaTypeManga,
+		Year:         attrs.Year,
+		Status:       mangaDexStatus(attrs.Status),
+		Episodes:     chapters,
+		EpisodeCount: len(chapters),
+		LastUpdate:   types.ClockFrom(ctx).Now(),
+	}
+	for _, alt := range attrs.AltTitles {
+		if t := alt["ja"]; t != "" && media.TitleJP == "" {
+			media.TitleJP = t
+		}
+		for _, t := range alt {
+			media.Aliases = append(media.Aliases, t)
+		}
+	}
+	return media, nil
+}
+
+// mangaDexStatus maps a MangaDex publication status to the airing statuses
+// the database uses, so finished series aren't refetched on every run
+func mangaDexStatus(status string) string {
+	switch status {
+	case "completed", "cancelled":
+		return "Finished Airing"
+	case "ongoing", "hiatus":
+		return "Currently Airing"
+	}
+	return status
+}
+
+// Search queries MangaDex for manga
+func (p *MangaDexProvider) Search(ctx context.Context, query string) ([]types.SearchResult, error) {
+	var result struct {
+		Data []struct {
+			ID         string  `json:"id"`
+			Attributes mdManga `json:"attributes"`
+		} `json:"data"`
+	}
+	if err := p.get(ctx, "/manga?title="+url.QueryEscape(query)+"&limit=10", &result); err != nil {
+		return nil, err
+	}
+
+	var searchResults []types.SearchResult
+	for _, m := range result.Data {
+		searchResults = append(searchResults, types.SearchResult{
+			Provider: p.Name(),
+			ID:       m.ID,
+			Title:    m.Attributes.title(),
+			Year:     m.Attributes.Year,
+			Type:     types.MediaTypeManga,
+			URL:      "https://mangadex.org/title/" + m.ID,
+		})
+	}
+	return searchResults, nil
+}
+
+// get fetches a MangaDex API endpoint and decodes the JSON response into v
+func (p *MangaDexProvider) get(ctx context.Context, endpoint string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+endpoint, nil)
+	if err != nil {
+		return err
+	}
+	// MangaDex rejects spoofed browser agents
+	req.Header.Set("User-Agent", userAgent())
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := DoWithRetry(ctx, p.client, req, "MangaDex", p.sleep)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return types.ErrAPIError{
+			Service:    "MangaDex",
+			StatusCode: resp.StatusCode,
+			Message:    fmt.Sprintf("request to %s failed", strings.SplitN(endpoint, "?", 2)[0]),
+		}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse MangaDex response: %w", err)
+	}
+	return nil
+}
+
+func (p *MangaDexProvider) sleep(ctx context.Context) {
+	types.ClockFrom(ctx).Sleep(p.rateLimit)
+}
+
+// init registers the MangaDex provider
+func init() {
+	RegisterProvider(func(cfg *types.APIConfig) types.Provider { return NewMangaDexProvider(cfg) })
+}
@@ -0,0 +1,257 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mydehq/autotitle/internal/types"
+	"github.com/mydehq/autotitle/internal/util"
+)
+
+const musicBrainzAPIURL = "https://musicbrainz.org/ws/2"
+
+// musicBrainzURLPatterns are URL patterns that this provider handles
+var musicBrainzURLPatterns = []string{
+	"musicbrainz.org/release/",
+}
+
+// musicBrainzURLRe matches a release URL and captures its MBID
+var musicBrainzURLRe = regexp.MustCompile(`musicbrainz\.org/release/([0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12})`)
+
+// MusicBrainzProvider implements the Provider interface for music releases
+// (OSTs, character song albums) on MusicBrainz. Tracks are the episodes:
+// numbered across all discs of the release, with the track artist.
+type MusicBrainzProvider struct {
+	client    *http.Client
+	baseURL   string
+	rateLimit time.Duration
+}
+
+// NewMusicBrainzProvider creates a new MusicBrainz provider
+func NewMusicBrainzProvider(cfg *types.APIConfig) *MusicBrainzProvider {
+	p := &MusicBrainzProvider{
+		client:    &http.Client{Timeout: 30 * time.Second},
+		baseURL:   musicBrainzAPIURL,
+		rateLimit: time.Second, // MusicBrainz allows one request per second
+	}
+	p.Configure(cfg)
+	return p
+}
+
+// Name returns the provider identifier
+func (p *MusicBrainzProvider) Name() string {
+	return "musicbrainz"
+}
+
+// Website returns the provider's website URL
+func (p *MusicBrainzProvider) Website() string {
+	return "https://musicbrainz.org"
+}
+
+// Configure updates provider settings. The rate limit is never raised above
+// MusicBrainz's one request per second.
+func (p *MusicBrainzProvider) Configure(cfg *types.APIConfig) {
+	if cfg == nil {
+		return
+	}
+	if cfg.Timeout > 0 {
+		p.client.Timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+	if cfg.RateLimit > 0 {
+		p.rateLimit = max(time.Duration(float64(time.Second)/cfg.RateLimit), time.Second)
+	}
+	if u := cfg.BaseURLs[p.Name()]; u != "" {
+		p.baseURL = strings.TrimSuffix(u, "/")
+	}
+}
+
+// Type returns the media type this provider handles
+func (p *MusicBrainzProvider) Type() types.MediaType {
+	return types.MediaTypeMusic
+}
+
+// SupportedURLs returns the URL patterns this provider handles
+func (p *MusicBrainzProvider) SupportedURLs() []string {
+	return musicBrainzURLPatterns
+}
+
+// Schema returns the API the parser targets
+func (p *MusicBrainzProvider) Schema() types.Schema {
+	return types.Schema{Target: "MusicBrainz Web Service v2 (JSON)", Checked: "2026-10-15"}
+}
+
+// HealthCheck searches for a well-known title and checks the results parse
+func (p *MusicBrainzProvider) HealthCheck(ctx context.Context) error {
+	return searchHealth(ctx, p, "Abbey Road")
+}
+
+// MatchesURL returns true if this provider can handle the given URL
+func (p *MusicBrainzProvider) MatchesURL(url string) bool {
+	for _, pattern := range musicBrainzURLPatterns {
+		if strings.Contains(url, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExtractID extracts the release MBID from a URL
+func (p *MusicBrainzProvider) ExtractID(url string) (string, error) {
+	if m := musicBrainzURLRe.FindStringSubmatch(url); m != nil {
+		return m[1], nil
+	}
+	return "", fmt.Errorf("could not extract MusicBrainz release ID from URL: %s", url)
+}
+
+// mbArtistCredit is a MusicBrainz artist credit, e.g. "A feat. B"
+type mbArtistCredit []struct {
+	Name       string `json:"name"`
+	JoinPhrase string `json:"joinphrase"`
+}
+
+func (c mbArtistCredit) String() string {
+	var b strings.Builder
+	for _, a := range c {
+		b.WriteString(a.Name + a.JoinPhrase)
+	}
+	return b.String()
+}
+
+// FetchMedia fetches a release and its tracklist from MusicBrainz
+func (p *MusicBrainzProvider) FetchMedia(ctx context.Context, id string) (*types.Media, error) {
+	var release struct {
+		Title        string         `json:"title"`
+		Date         string         `json:"date"`
+		Status       string         `json:"status"`
+		ArtistCredit mbArtistCredit `json:"artist-credit"`
+		Media        []struct {
+			Tracks []struct {
+				Title        string         `json:"title"`
+				ArtistCredit mbArtistCredit `json:"artist-credit"`
+			} `json:"tracks"`
+		} `json:"media"`
+	}
+	if err := p.get(ctx, "/release/"+url.PathEscape(id)+"?inc=recordings+artist-credits&fmt=json", &release); err != nil {
+		return nil, err
+	}
+
+	albumArtist := release.ArtistCredit.String()
+	var tracks []types.Episode
+	for _, disc := range release.Media {
+		for _, t := range disc.Tracks {
+			artist := t.ArtistCredit.String()
+			if artist == "" {
+				artist = albumArtist
+			}
+			tracks = append(tracks, types.Episode{
+				Number:  len(tracks) + 1,
+				Title:   t.Title,
+				Artist:  artist,
+				AirDate: release.Date,
+			})
+		}
+	}
+
+	media := &types.Media{
+		ID:           id,
+		Provider:     p.Name(),
+		Title:        release.Title,
+		Slug:         util.Slugify(release.Title),
+		Type:         types.MediaTypeMusic,
+		Status:       release.Status,
+		Episodes:     tracks,
+		EpisodeCount: len(tracks),
+		LastUpdate:   types.ClockFrom(ctx).Now(),
+	}
+	// Dates are YYYY, YYYY-MM or YYYY-MM-DD
+	if len(release.Date) >= 4 {
+		media.Year, _ = strconv.Atoi(release.Date[:4])
+	}
+	if len(release.Date) >= 7 {
+		if m, err := strconv.Atoi(release.Date[5:7]); err == nil {
+			media.Season = util.SeasonName(time.Month(m))
+		}
+	}
+	return media, nil
+}
+
+// Search queries MusicBrainz for releases
+func (p *MusicBrainzProvider) Search(ctx context.Context, query string) ([]types.SearchResult, error) {
+	var result struct {
+		Releases []struct {
+			ID           string         `json:"id"`
+			Title        string         `json:"title"`
+			Date         string         `json:"date"`
+			ArtistCredit mbArtistCredit `json:"artist-credit"`
+		} `json:"releases"`
+	}
+	if err := p.get(ctx, "/release?query="+url.QueryEscape(query)+"&limit=10&fmt=json", &result); err != nil {
+		return nil, err
+	}
+
+	var searchResults []types.SearchResult
+	for _, r := range result.Releases {
+		title := r.Title
+		if artist := r.ArtistCredit.String(); artist != "" {
+			title += " - " + artist
+		}
+		var year int
+		if len(r.Date) >= 4 {
+			year, _ = strconv.Atoi(r.Date[:4])
+		}
+		searchResults = append(searchResults, types.SearchResult{
+			Provider: p.Name(),
+			ID:       r.ID,
+			Title:    title,
+			Year:     year,
+			Type:     types.MediaTypeMusic,
+			URL:      "https://musicbrainz.org/release/" + r.ID,
+		})
+	}
+	return searchResults, nil
+}
+
+// get fetches a MusicBrainz API endpoint and decodes the JSON response into v
+func (p *MusicBrainzProvider) get(ctx context.Context, endpoint string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+endpoint, nil)
+	if err != nil {
+		return err
+	}
+	// MusicBrainz blocks anonymous and browser-like agents
+	req.Header.Set("User-Agent", userAgent())
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := DoWithRetry(ctx, p.client, req, "MusicBrainz", p.sleep)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return types.ErrAPIError{
+			Service:    "MusicBrainz",
+			StatusCode: resp.StatusCode,
+			Message:    fmt.Sprintf("request to %s failed", strings.SplitN(endpoint, "?", 2)[0]),
+		}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse MusicBrainz response: %w", err)
+	}
+	return nil
+}
+
+func (p *MusicBrainzProvider) sleep(ctx context.Context) {
+	types.ClockFrom(ctx).Sleep(p.rateLimit)
+}
+
+// init registers the MusicBrainz provider
+func init() {
+	RegisterProvider(func(cfg *types.APIConfig) types.Provider { return NewMusicBrainzProvider(cfg) })
+}
@@ -1,7 +1,17 @@
 package provider
 
 import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/mydehq/autotitle/internal/types"
 )
 
 func TestMALProvider_MatchesURL(t *testing.T) {
@@ -91,3 +101,371 @@ func TestGetProviderForURL(t *testing.T) {
 		})
 	}
 }
+
+func TestListProviderDetails(t *testing.T) {
+	for _, info := range ListProviderDetails() {
+		if info.Name != "mal" {
+			continue
+		}
+		if info.Type != types.MediaTypeAnime {
+			t.Errorf("expected type %q, got %q", types.MediaTypeAnime, info.Type)
+		}
+		if len(info.MatchURLs) == 0 {
+			t.Error("expected supported URL patterns")
+		}
+		if info.RequiresKey {
+			t.Error("mal should not require an API key")
+		}
+		return
+	}
+	t.Error("mal provider not listed")
+}
+
+func TestTraktProvider_ExtractID(t *testing.T) {
+	p := NewTraktProvider(nil)
+
+	tests := map[string]string{
+		"https://trakt.tv/shows/breaking-bad":                       "breaking-bad",
+		"https://trakt.tv/shows/breaking-bad/seasons/2":             "breaking-bad.s2",
+		"https://trakt.tv/shows/the-office-us/seasons/3/episodes/1": "the-office-us.s3",
+	}
+	for url, want := range tests {
+		if id, err := p.ExtractID(url); err != nil || id != want {
+			t.Errorf("ExtractID(%q) = %q, %v; want %q", url, id, err, want)
+		}
+	}
+	if _, err := p.ExtractID("https://trakt.tv/movies/inception-2010"); err == nil {
+		t.Error("expected error for a movie URL")
+	}
+}
+
+func TestTraktProvider_FetchMedia(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("trakt-api-key") != "client" || r.Header.Get("trakt-api-version") != "2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/shows/severance":
+			w.Write([]byte(`{"title":"Severance","year":2022,"status":"returning series","airs":{"timezone":"America/New_York"}}`))
+		case "/shows/severance/seasons":
+			w.Write([]byte(`[
+				{"number":0,"episodes":[{"number":1,"title":"Special","first_aired":"2022-01-01T00:00:00.000Z"}]},
+				{"number":1,"episodes":[
+					{"number":1,"title":"Good News About Hell","first_aired":"2022-02-18T02:00:00.000Z"},
+					{"number":2,"title":"Half Loop","first_aired":"2022-02-18T02:00:00.000Z"}]},
+				{"number":2,"episodes":[
+					{"number":1,"title":"Hello, Ms. Cobel","first_aired":"2025-01-17T02:00:00.000Z"},
+					{"number":2,"title":"Goodbye, Mrs. Selvig","first_aired":null}]}
+			]`))
+		case "/users/mark/watched/shows":
+			w.Write([]byte(`[
+				{"show":{"ids":{"slug":"other"}},"seasons":[{"number":1,"episodes":[{"number":2,"plays":1}]}]},
+				{"show":{"ids":{"slug":"severance"}},"seasons":[{"number":1,"episodes":[{"number":2,"plays":3}]}]}
+			]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	p := NewTraktProvider(&types.APIConfig{
+		RateLimit: 1000,
+		Keys:      map[string]string{"trakt": "client"},
+		Users:     map[string]string{"trakt": "mark"},
+		BaseURLs:  map[string]string{"trakt": srv.URL},
+	})
+	ctx := types.ContextWithClock(context.Background(), types.NowFunc(func() time.Time { return time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC) }))
+
+	media, err := p.FetchMedia(ctx, "severance")
+	if err != nil {
+		t.Fatalf("FetchMedia failed: %v", err)
+	}
+	if len(media.Episodes) != 4 {
+		t.Fatalf("expected 4 regular episodes, got %+v", media.Episodes)
+	}
+	if ep := media.Episodes[2]; ep.Number != 3 || ep.Title != "Hello, Ms. Cobel" {
+		t.Errorf("expected S2E1 as episode 3, got %+v", ep)
+	}
+	// 02:00 UTC is the evening before in New York
+	if media.Episodes[0].AirDate != "2022-02-17" || media.AirTimeZone != "America/New_York" {
+		t.Errorf("expected the local air date, got %q in %q", media.Episodes[0].AirDate, media.AirTimeZone)
+	}
+	if media.Episodes[0].Watched || !media.Episodes[1].Watched || media.Episodes[2].Watched {
+		t.Errorf("expected only episode 2 watched, got %+v", media.Episodes)
+	}
+	if media.Status != "Currently Airing" {
+		t.Errorf("expected Trakt status mapped to Currently Airing, got %q", media.Status)
+	}
+	if media.NextEpisodeAirDate == nil || *media.NextEpisodeAirDate != "2025-01-16" {
+		t.Errorf("expected next air date 2025-01-16, got %v", media.NextEpisodeAirDate)
+	}
+
+	season, err := p.FetchMedia(ctx, "severance.s2")
+	if err != nil {
+		t.Fatalf("FetchMedia of a season failed: %v", err)
+	}
+	if len(season.Episodes) != 2 || season.Episodes[0].Number != 1 {
+		t.Errorf("expected season 2 numbered from 1, got %+v", season.Episodes)
+	}
+	if _, err := p.FetchMedia(ctx, "severance.s9"); err == nil {
+		t.Error("expected error for a missing season")
+	}
+
+	p.Configure(&types.APIConfig{})
+	if _, err := p.FetchMedia(ctx, "severance"); err == nil {
+		t.Error("expected error without an API key")
+	}
+}
+
+func TestMusicBrainzProvider_FetchMedia(t *testing.T) {
+	const mbid = "0f1a2b3c-4d5e-6f70-8192-a3b4c5d6e7f8"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("User-Agent"), "autotitle/") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.URL.Path != "/release/"+mbid {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"title":"Attack on Titan OST","date":"2013-06-28","status":"Official",
+			"artist-credit":[{"name":"Hiroyuki Sawano","joinphrase":""}],
+			"media":[
+				{"tracks":[{"title":"Vogel im Käfig","artist-credit":[{"name":"Hiroyuki Sawano","joinphrase":" feat. "},{"name":"Cyua","joinphrase":""}]}]},
+				{"tracks":[{"title":"The Reluctant Heroes"}]}
+			]}`))
+	}))
+	defer srv.Close()
+
+	p := NewMusicBrainzProvider(&types.APIConfig{BaseURLs: map[string]string{"musicbrainz": srv.URL}})
+	p.rateLimit = 0 // Configure never goes below MusicBrainz's limit
+	ctx := types.ContextWithClock(context.Background(), types.NowFunc(func() time.Time { return time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC) }))
+
+	id, err := p.ExtractID("https://musicbrainz.org/release/" + mbid)
+	if err != nil || id != mbid {
+		t.Fatalf("ExtractID = %q, %v", id, err)
+	}
+	media, err := p.FetchMedia(ctx, id)
+	if err != nil {
+		t.Fatalf("FetchMedia failed: %v", err)
+	}
+	if media.Type != types.MediaTypeMusic || media.Year != 2013 || media.Season != "Spring" {
+		t.Errorf("unexpected release info: %+v", media)
+	}
+	if len(media.Episodes) != 2 {
+		t.Fatalf("expected 2 tracks, got %+v", media.Episodes)
+	}
+	if ep := media.Episodes[0]; ep.Number != 1 || ep.Artist != "Hiroyuki Sawano feat. Cyua" {
+		t.Errorf("unexpected first track: %+v", ep)
+	}
+	// Disc 2 continues the numbering and falls back to the album artist
+	if ep := media.Episodes[1]; ep.Number != 2 || ep.Artist != "Hiroyuki Sawano" {
+		t.Errorf("unexpected second track: %+v", ep)
+	}
+}
+
+func TestMangaDexProvider_FetchMedia(t *testing.T) {
+	const id = "a1c7c817-4e59-43b7-9365-09675a149a6f"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/manga/" + id:
+			w.Write([]byte(`{"data":{"attributes":{"title":{"en":"One Piece"},"altTitles":[{"ja":"ワンピース"}],"year":1997,"status":"ongoing"}}}`))
+		case "/manga/" + id + "/feed":
+			if r.URL.Query().Get("translatedLanguage[]") != "en" {
+				t.Errorf("expected English chapters, got query %q", r.URL.RawQuery)
+			}
+			w.Write([]byte(`{"total":4,"data":[
+				{"attributes":{"volume":"1","chapter":"1","title":"Romance Dawn","publishAt":"2018-01-18T12:00:00+00:00"}},
+				{"attributes":{"volume":null,"chapter":"1","title":"Romance Dawn (re-upload)"}},
+				{"attributes":{"volume":"1","chapter":"1.5","title":"Extra"}},
+				{"attributes":{"volume":null,"chapter":"2","title":null}},
+				{"attributes":{"volume":"1","chapter":"2","title":"They Call Him Straw Hat Luffy"}}
+			]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	p := NewMangaDexProvider(&types.APIConfig{RateLimit: 1000, BaseURLs: map[string]string{"mangadex": srv.URL}})
+	if got, err := p.ExtractID("https://mangadex.org/title/" + id + "/one-piece"); err != nil || got != id {
+		t.Fatalf("ExtractID = %q, %v", got, err)
+	}
+
+	media, err := p.FetchMedia(context.Background(), id)
+	if err != nil {
+		t.Fatalf("FetchMedia failed: %v", err)
+	}
+	if media.Type != types.MediaTypeManga || media.TitleJP != "ワンピース" || media.Status != "Currently Airing" {
+		t.Errorf("unexpected manga info: %+v", media)
+	}
+	if len(media.Episodes) != 2 {
+		t.Fatalf("expected 2 whole chapters, got %+v", media.Episodes)
+	}
+	if ch := media.Episodes[0]; ch.Title != "Romance Dawn" || ch.Volume != "1" || ch.AirDate != "2018-01-18" {
+		t.Errorf("unexpected chapter 1: %+v", ch)
+	}
+	// The second upload fills in what the first left out
+	if ch := media.Episodes[1]; ch.Number != 2 || ch.Title != "They Call Him Straw Hat Luffy" || ch.Volume != "1" {
+		t.Errorf("unexpected chapter 2: %+v", ch)
+	}
+}
+
+func TestMALNovelProvider_FetchMedia(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/manga/48399":
+			w.Write([]byte(`{"data":{"mal_id":48399,"title":"Overlord","type":"Light Novel","volumes":3,"status":"Finished","published":{"prop":{"from":{"year":2012}}}}}`))
+		case "/manga/1":
+			w.Write([]byte(`{"data":{"mal_id":1,"title":"Ongoing","type":"Light Novel","volumes":null,"status":"Publishing"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	// The base URL configured for the anime provider is shared
+	p := NewMALNovelProvider(&types.APIConfig{RateLimit: 1000, BaseURLs: map[string]string{"mal": srv.URL}})
+	if id, err := p.ExtractID("https://myanimelist.net/manga/48399/Overlord"); err != nil || id != "48399" {
+		t.Fatalf("ExtractID = %q, %v", id, err)
+	}
+
+	media, err := p.FetchMedia(context.Background(), "48399")
+	if err != nil {
+		t.Fatalf("FetchMedia failed: %v", err)
+	}
+	if media.Type != types.MediaTypeNovel || media.Year != 2012 || media.Status != "Finished Airing" {
+		t.Errorf("unexpected novel info: %+v", media)
+	}
+	if len(media.Episodes) != 3 || media.Episodes[2].Title != "Volume 3" || media.Episodes[2].Volume != "3" {
+		t.Errorf("expected volumes 1-3, got %+v", media.Episodes)
+	}
+
+	if _, err := p.FetchMedia(context.Background(), "1"); err == nil {
+		t.Error("expected error without a volume count")
+	}
+}
+
+func TestURLProvider_FetchMedia(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/db/frieren.json":
+			w.Write([]byte(`{"id":"52991","provider":"mal","title":"Frieren","status":"Finished Airing","episodes":[{"number":1,"title":"The Journey's End"},{"number":2,"title":"It Didn't Have to Be Magic..."}]}`))
+		case "/db/empty.json":
+			w.Write([]byte(`{"title":"Nothing"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	p := NewURLProvider(nil)
+	if p.MatchesURL("https://myanimelist.net/anime/52991") || p.MatchesURL("ftp://example.com/db.json") {
+		t.Error("only http(s) URLs of .json files should match")
+	}
+	id, err := p.ExtractID(srv.URL + "/db/frieren.json")
+	if err != nil {
+		t.Fatalf("ExtractID failed: %v", err)
+	}
+
+	media, err := p.FetchMedia(context.Background(), id)
+	if err != nil {
+		t.Fatalf("FetchMedia failed: %v", err)
+	}
+	if media.Provider != "url" || media.ID != id || media.Type != types.MediaTypeAnime || media.Slug != "frieren" {
+		t.Errorf("the entry should be cached under the url provider: %+v", media)
+	}
+	if media.EpisodeCount != 2 || media.Episodes[1].Title != "It Didn't Have to Be Magic..." {
+		t.Errorf("unexpected episodes: %+v", media.Episodes)
+	}
+
+	for _, path := range []string{"/db/empty.json", "/db/missing.json"} {
+		id, _ := p.ExtractID(srv.URL + path)
+		if _, err := p.FetchMedia(context.Background(), id); err == nil {
+			t.Errorf("%s: expected an error", path)
+		}
+	}
+}
+
+func TestURLProvider_TrustedKeys(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	keyID := []byte("autotitl")
+	data := []byte(`{"title":"Frieren","episodes":[{"number":1,"title":"The Journey's End"}]}`)
+
+	// A legacy (unhashed) minisign signature, as `minisign -S -l` writes it
+	sig := ed25519.Sign(priv, data)
+	comment := "file:frieren.json"
+	global := ed25519.Sign(priv, append(append([]byte{}, sig...), comment...))
+	minisig := fmt.Sprintf("untrusted comment: signature\n%s\ntrusted comment: %s\n%s\n",
+		base64.StdEncoding.EncodeToString(append(append([]byte("Ed"), keyID...), sig...)), comment,
+		base64.StdEncoding.EncodeToString(global))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/signed.json", "/unsigned.json":
+			w.Write(data)
+		case "/signed.json.minisig":
+			w.Write([]byte(minisig))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	key := base64.StdEncoding.EncodeToString(append(append([]byte("Ed"), keyID...), pub...))
+	p := NewURLProvider(&types.APIConfig{TrustedKeys: []string{key}})
+
+	id, _ := p.ExtractID(srv.URL + "/signed.json")
+	media, err := p.FetchMedia(context.Background(), id)
+	if err != nil {
+		t.Fatalf("signed file: %v", err)
+	}
+	if !p.ValidCache(media) {
+		t.Error("a file signed by a trusted key should stay valid in the cache")
+	}
+	if p.ValidCache(&types.Media{Title: "Frieren"}) {
+		t.Error("an entry cached without a signature should be fetched again once keys are trusted")
+	}
+	id, _ = p.ExtractID(srv.URL + "/unsigned.json")
+	if _, err := p.FetchMedia(context.Background(), id); err == nil {
+		t.Error("expected an unsigned file to be refused")
+	}
+
+	otherPub, _, _ := ed25519.GenerateKey(nil)
+	p.Configure(&types.APIConfig{TrustedKeys: []string{base64.StdEncoding.EncodeToString(otherPub)}})
+	if p.ValidCache(media) {
+		t.Error("an entry signed by a key no longer trusted should be fetched again")
+	}
+	id, _ = p.ExtractID(srv.URL + "/signed.json")
+	if _, err := p.FetchMedia(context.Background(), id); err == nil {
+		t.Error("expected a file signed by an untrusted key to be refused")
+	}
+
+	p.Configure(&types.APIConfig{})
+	if !p.ValidCache(&types.Media{Title: "Frieren"}) {
+		t.Error("without trusted keys any cached entry is valid")
+	}
+}
+
+func TestMangaDexProvider_HealthCheck(t *testing.T) {
+	body := `{"data":[{"id":"801513ba-a712-498c-8f57-cae55b38cc92","attributes":{"title":{"en":"Berserk"}}}]}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	p := NewMangaDexProvider(&types.APIConfig{RateLimit: 1000, BaseURLs: map[string]string{"mangadex": srv.URL}})
+	if p.Schema().Target == "" {
+		t.Error("Schema should name the API")
+	}
+	if err := p.HealthCheck(context.Background()); err != nil {
+		t.Errorf("HealthCheck failed: %v", err)
+	}
+
+	// A renamed field leaves the results without titles
+	body = `{"data":[{"id":"801513ba-a712-498c-8f57-cae55b38cc92","attributes":{"name":{"en":"Berserk"}}}]}`
+	if err := p.HealthCheck(context.Background()); err == nil || !strings.Contains(err.Error(), "outdated") {
+		t.Errorf("HealthCheck = %v, want the parser reported outdated", err)
+	}
+}
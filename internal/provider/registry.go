@@ -8,12 +8,19 @@ import (
 // providers is the global registry of available providers
 var providers []types.Provider
 
+// factories build a fresh instance of each registered provider, in the
+// order of providers
+var factories []func(cfg *types.APIConfig) types.Provider
+
 // fillerSources is the global registry of available filler sources
 var fillerSources []types.FillerSource
 
-// RegisterProvider adds a provider to the registry
-func RegisterProvider(p types.Provider) {
-	providers = append(providers, p)
+// RegisterProvider adds a provider to the registry. newProvider builds one
+// configured with cfg (nil for the defaults): the registry keeps one built
+// with none, and CheckHealth builds its own.
+func RegisterProvider(newProvider func(cfg *types.APIConfig) types.Provider) {
+	providers = append(providers, newProvider(nil))
+	factories = append(factories, newProvider)
 }
 
 // RegisterFillerSource adds a filler source to the registry
@@ -82,6 +89,27 @@ func ListFillerSources() []string {
 	return names
 }
 
+// ProviderInfo holds metadata about a registered provider
+type ProviderInfo struct {
+	Name        string
+	Website     string
+	Type        types.MediaType
+	MatchURLs   []string
+	RequiresKey bool
+}
+
+// ListProviderDetails returns all registered providers with their supported URLs
+func ListProviderDetails() []ProviderInfo {
+	infos := make([]ProviderInfo, len(providers))
+	for i, p := range providers {
+		infos[i] = ProviderInfo{Name: p.Name(), Website: p.Website(), Type: p.Type(), MatchURLs: p.SupportedURLs()}
+		if kp, ok := p.(types.KeyedProvider); ok {
+			infos[i].RequiresKey = kp.RequiresAPIKey()
+		}
+	}
+	return infos
+}
+
 // FillerSourceInfo holds metadata about a registered filler source
 type FillerSourceInfo struct {
 	Name      string
@@ -0,0 +1,372 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mydehq/autotitle/internal/types"
+	"github.com/mydehq/autotitle/internal/util"
+)
+
+const traktAPIURL = "https://api.trakt.tv"
+
+// traktURLPatterns are URL patterns that this provider handles
+var traktURLPatterns = []string{
+	"trakt.tv/shows/",
+}
+
+// traktURLRe matches a show URL, optionally narrowed to one season
+var traktURLRe = regexp.MustCompile(`trakt\.tv/shows/([a-z0-9-]+)(?:/seasons/(\d+))?`)
+
+// traktIDRe splits a media ID into the show slug and an optional season
+var traktIDRe = regexp.MustCompile(`^([a-z0-9-]+)(?:\.s(\d+))?$`)
+
+// TraktProvider implements the Provider interface for TV shows on Trakt.
+// A show URL gives every regular episode numbered across seasons; a season
+// URL (.../seasons/2) gives that season numbered from 1. With a Trakt user
+// set in api.users.trakt, episodes that user has watched are flagged.
+type TraktProvider struct {
+	client    *http.Client
+	baseURL   string
+	rateLimit time.Duration
+	apiKey    string // Trakt app client ID
+	user      string // Public profile whose watched state is read
+}
+
+// NewTraktProvider creates a new Trakt provider
+func NewTraktProvider(cfg *types.APIConfig) *TraktProvider {
+	p := &TraktProvider{
+		client:    &http.Client{Timeout: 30 * time.Second},
+		baseURL:   traktAPIURL,
+		rateLimit: time.Second / 2,
+	}
+	p.Configure(cfg)
+	return p
+}
+
+// Name returns the provider identifier
+func (p *TraktProvider) Name() string {
+	return "trakt"
+}
+
+// Website returns the provider's website URL
+func (p *TraktProvider) Website() string {
+	return "https://trakt.tv"
+}
+
+// Configure updates provider settings
+func (p *TraktProvider) Configure(cfg *types.APIConfig) {
+	if cfg == nil {
+		return
+	}
+	if cfg.Timeout > 0 {
+		p.client.Timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+	if cfg.RateLimit > 0 {
+		p.rateLimit = time.Duration(float64(time.Second) / cfg.RateLimit)
+	}
+	if u := cfg.BaseURLs[p.Name()]; u != "" {
+		p.baseURL = strings.TrimSuffix(u, "/")
+	}
+	p.apiKey = cfg.Keys[p.Name()]
+	p.user = cfg.Users[p.Name()]
+}
+
+// RequiresAPIKey returns true: Trakt needs an app client ID
+func (p *TraktProvider) RequiresAPIKey() bool {
+	return true
+}
+
+// Type returns the media type this provider handles
+func (p *TraktProvider) Type() types.MediaType {
+	return types.MediaTypeTVShow
+}
+
+// SupportedURLs returns the URL patterns this provider handles
+func (p *TraktProvider) SupportedURLs() []string {
+	return traktURLPatterns
+}
+
+// Schema returns the API the parser targets
+func (p *TraktProvider) Schema() types.Schema {
+	return types.Schema{Target: "Trakt API v2", Checked: "2026-10-15"}
+}
+
+// HealthCheck searches for a well-known title and checks the results parse
+func (p *TraktProvider) HealthCheck(ctx context.Context) error {
+	if p.apiKey == "" {
+		return fmt.Errorf("cannot check without an API key (set api.keys.trakt)")
+	}
+	return searchHealth(ctx, p, "Breaking Bad")
+}
+
+// MatchesURL returns true if this provider can handle the given URL
+func (p *TraktProvider) MatchesURL(url string) bool {
+	for _, pattern := range traktURLPatterns {
+		if strings.Contains(url, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExtractID extracts the show slug from a URL, with ".s<N>" appended for a
+// season URL
+func (p *TraktProvider) ExtractID(url string) (string, error) {
+	m := traktURLRe.FindStringSubmatch(url)
+	if m == nil {
+		return "", fmt.Errorf("could not extract Trakt show from URL: %s", url)
+	}
+	if m[2] != "" {
+		return m[1] + ".s" + m[2], nil
+	}
+	return m[1], nil
+}
+
+type traktShow struct {
+	Title      string `json:"title"`
+	Year       int    `json:"year"`
+	Status     string `json:"status"`
+	FirstAired string `json:"first_aired"`
+	IDs        struct {
+		Trakt int    `json:"trakt"`
+		Slug  string `json:"slug"`
+	} `json:"ids"`
+	Airs struct {
+		Timezone string `json:"timezone"`
+	} `json:"airs"`
+}
+
+type traktSeason struct {
+	Number   int `json:"number"`
+	Episodes []struct {
+		Number     int     `json:"number"`
+		Title      string  `json:"title"`
+		FirstAired *string `json:"first_aired"`
+	} `json:"episodes"`
+}
+
+// FetchMedia fetches a show (or one season of it) from Trakt
+func (p *TraktProvider) FetchMedia(ctx context.Context, id string) (*types.Media, error) {
+	m := traktIDRe.FindStringSubmatch(id)
+	if m == nil {
+		return nil, fmt.Errorf("invalid Trakt ID: %s", id)
+	}
+	slug, season := m[1], -1
+	if m[2] != "" {
+		season, _ = strconv.Atoi(m[2])
+	}
+	if p.apiKey == "" {
+		return nil, fmt.Errorf("trakt needs an API key: set api.keys.trakt to your app's client ID")
+	}
+
+	var show traktShow
+	if err := p.get(ctx, "/shows/"+slug+"?extended=full", &show); err != nil {
+		return nil, err
+	}
+	var seasons []traktSeason
+	if err := p.get(ctx, "/shows/"+slug+"/seasons?extended=full,episodes", &seasons); err != nil {
+		return nil, err
+	}
+
+	var watched map[[2]int]bool
+	if p.user != "" {
+		w, err := p.fetchWatched(ctx, slug)
+		if err != nil {
+			return nil, err
+		}
+		watched = w
+	}
+
+	zone := show.Airs.Timezone
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		zone, loc = "UTC", time.UTC
+	}
+
+	now := types.ClockFrom(ctx).Now()
+	var episodes []types.Episode
+	var nextEpisodeAirDate *string
+	for _, s := range seasons {
+		// Specials only when asked for season 0; whole shows number regular episodes
+		if (season >= 0 && s.Number != season) || (season < 0 && s.Number == 0) {
+			continue
+		}
+		for _, e := range s.Episodes {
+			ep := types.Episode{
+				Number:  e.Number,
+				Title:   e.Title,
+				Watched: watched[[2]int{s.Number, e.Number}],
+			}
+			if season < 0 {
+				ep.Number = len(episodes) + 1
+			}
+			if e.FirstAired != nil {
+				// Trakt stamps the broadcast instant; keep the day in the show's zone
+				if t, err := time.Parse(time.RFC3339, *e.FirstAired); err == nil {
+					ep.AirDate = t.In(loc).Format(time.DateOnly)
+					if aired, ok := util.AiredBy(ep.AirDate, zone); ok && aired.After(now) && nextEpisodeAirDate == nil {
+						date := ep.AirDate
+						nextEpisodeAirDate = &date
+					}
+				}
+			}
+			episodes = append(episodes, ep)
+		}
+	}
+	if season >= 0 && len(episodes) == 0 {
+		return nil, fmt.Errorf("%s has no season %d on Trakt", show.Title, season)
+	}
+
+	var premiere string
+	if t, err := time.Parse(time.RFC3339, show.FirstAired); err == nil {
+		premiere = util.SeasonName(t.In(loc).Month())
+	}
+
+	return &types.Media{
+		ID:                 id,
+		Provider:           p.Name(),
+		Title:              show.Title,
+		TitleEN:            show.Title,
+		Slug:               util.Slugify(show.Title),
+		Type:               types.MediaTypeTVShow,
+		Year:               show.Year,
+		Season:             premiere,
+		Status:             traktStatus(show.Status),
+		NextEpisodeAirDate: nextEpisodeAirDate,
+		AirTimeZone:        zone,
+		Episodes:           episodes,
+		EpisodeCount:       len(episodes),
+		LastUpdate:         now,
+	}, nil
+}
+
+// traktStatus maps a Trakt show status to the airing statuses the database
+// uses (as MAL reports them), so ended shows aren't refetched on every run
+func traktStatus(status string) string {
+	switch status {
+	case "ended", "canceled":
+		return "Finished Airing"
+	case "returning series", "continuing":
+		return "Currently Airing"
+	case "in production", "planned", "upcoming", "pilot":
+		return "Not yet aired"
+	}
+	return status
+}
+
+// fetchWatched returns the (season, episode) pairs of slug the configured
+// user has watched. The user's profile must be public.
+func (p *TraktProvider) fetchWatched(ctx context.Context, slug string) (map[[2]int]bool, error) {
+	var shows []struct {
+		Show struct {
+			IDs struct {
+				Slug string `json:"slug"`
+			} `json:"ids"`
+		} `json:"show"`
+		Seasons []struct {
+			Number   int `json:"number"`
+			Episodes []struct {
+				Number int `json:"number"`
+				Plays  int `json:"plays"`
+			} `json:"episodes"`
+		} `json:"seasons"`
+	}
+	if err := p.get(ctx, "/users/"+url.PathEscape(p.user)+"/watched/shows", &shows); err != nil {
+		return nil, fmt.Errorf("failed to read watched episodes of %s (is the profile public?): %w", p.user, err)
+	}
+
+	watched := make(map[[2]int]bool)
+	for _, s := range shows {
+		if s.Show.IDs.Slug != slug {
+			continue
+		}
+		for _, season := range s.Seasons {
+			for _, e := range season.Episodes {
+				if e.Plays > 0 {
+					watched[[2]int{season.Number, e.Number}] = true
+				}
+			}
+		}
+	}
+	return watched, nil
+}
+
+// Search queries Trakt for shows. Without an API key Trakt is left out of
+// searches rather than reporting an error in every one.
+func (p *TraktProvider) Search(ctx context.Context, query string) ([]types.SearchResult, error) {
+	if p.apiKey == "" {
+		return nil, nil
+	}
+
+	var result []struct {
+		Show struct {
+			Title string `json:"title"`
+			Year  int    `json:"year"`
+			IDs   struct {
+				Slug string `json:"slug"`
+			} `json:"ids"`
+		} `json:"show"`
+	}
+	if err := p.get(ctx, "/search/show?query="+url.QueryEscape(query), &result); err != nil {
+		return nil, err
+	}
+
+	var searchResults []types.SearchResult
+	for _, item := range result {
+		searchResults = append(searchResults, types.SearchResult{
+			Provider: p.Name(),
+			ID:       item.Show.IDs.Slug,
+			Title:    item.Show.Title,
+			Year:     item.Show.Year,
+			Type:     types.MediaTypeTVShow,
+			URL:      "https://trakt.tv/shows/" + item.Show.IDs.Slug,
+		})
+	}
+	return searchResults, nil
+}
+
+// get fetches a Trakt API endpoint and decodes the JSON response into v
+func (p *TraktProvider) get(ctx context.Context, endpoint string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("trakt-api-version", "2")
+	req.Header.Set("trakt-api-key", p.apiKey)
+
+	resp, err := DoWithRetry(ctx, p.client, req, "Trakt", p.sleep)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return types.ErrAPIError{
+			Service:    "Trakt",
+			StatusCode: resp.StatusCode,
+			Message:    fmt.Sprintf("request to %s failed", strings.SplitN(endpoint, "?", 2)[0]),
+		}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse Trakt response: %w", err)
+	}
+	return nil
+}
+
+func (p *TraktProvider) sleep(ctx context.Context) {
+	types.ClockFrom(ctx).Sleep(p.rateLimit)
+}
+
+// init registers the Trakt provider
+func init() {
+	RegisterProvider(func(cfg *types.APIConfig) types.Provider { return NewTraktProvider(cfg) })
+}
@@ -0,0 +1,257 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mydehq/autotitle/internal/minisign"
+	"github.com/mydehq/autotitle/internal/types"
+	"github.com/mydehq/autotitle/internal/util"
+)
+
+// maxDatabaseSize caps the download of a hosted database file
+const maxDatabaseSize = 32 << 20
+
+// maxURLIDLength keeps the database file name of an entry under 255 bytes
+const maxURLIDLength = 200
+
+// URLProvider implements the Provider interface for pre-built autotitle
+// database files hosted over HTTP(S), e.g. a community's curated titles for
+// a show the APIs cover poorly. The file is what the database stores for
+// any provider (see `autotitle db path`); it is fetched and cached like
+// provider data but never written back. The ID is the URL itself, encoded
+// so it can name the cached file.
+//
+// With api.trusted_keys set, every file must come with a minisign signature
+// by one of the keys at "<url>.minisig", so a third party's titles only
+// drive renames once the user has chosen to trust them.
+type URLProvider struct {
+	client      *http.Client
+	trustedKeys []string
+}
+
+// NewURLProvider creates a new database URL provider
+func NewURLProvider(cfg *types.APIConfig) *URLProvider {
+	p := &URLProvider{
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+	p.Configure(cfg)
+	return p
+}
+
+// Name returns the provider identifier
+func (p *URLProvider) Name() string {
+	return "url"
+}
+
+// Website returns the provider's website URL
+func (p *URLProvider) Website() string {
+	return "https://github.com/mydehq/autotitle"
+}
+
+// Configure updates provider settings. There is no base URL to override:
+// the URL is the source.
+func (p *URLProvider) Configure(cfg *types.APIConfig) {
+	if cfg == nil {
+		return
+	}
+	if cfg.Timeout > 0 {
+		p.client.Timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+	p.trustedKeys = cfg.TrustedKeys
+}
+
+// Type returns the media type this provider handles; the file sets its own
+func (p *URLProvider) Type() types.MediaType {
+	return types.MediaTypeAnime
+}
+
+// SupportedURLs returns the URL patterns this provider handles
+func (p *URLProvider) SupportedURLs() []string {
+	return []string{"https://<any host>/<path>.json"}
+}
+
+// MatchesURL returns true for http(s) URLs of .json files
+func (p *URLProvider) MatchesURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return false
+	}
+	return strings.HasSuffix(strings.ToLower(u.Path), ".json")
+}
+
+// ExtractID encodes the URL as the media ID
+func (p *URLProvider) ExtractID(rawURL string) (string, error) {
+	if !p.MatchesURL(rawURL) {
+		return "", fmt.Errorf("not a database file URL: %s", rawURL)
+	}
+	id := base64.RawURLEncoding.EncodeToString([]byte(rawURL))
+	if len(id) > maxURLIDLength {
+		return "", fmt.Errorf("database file URL is too long: %s", rawURL)
+	}
+	return id, nil
+}
+
+// FetchMedia downloads and checks the database file behind an encoded URL
+func (p *URLProvider) FetchMedia(ctx context.Context, id string) (*types.Media, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid database URL ID %q", id)
+	}
+	source := string(raw)
+
+	data, err := p.download(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := p.verify(ctx, source, data)
+	if err != nil {
+		return nil, err
+	}
+
+	var media types.Media
+	if err := json.Unmarshal(data, &media); err != nil {
+		return nil, fmt.Errorf("%s is not an autotitle database file: %w", source, err)
+	}
+	if media.Title == "" || len(media.Episodes) == 0 {
+		return nil, fmt.Errorf("%s is not an autotitle database file: no title or episodes", source)
+	}
+
+	// Cached under this provider, whatever the file was built from
+	media.ID = id
+	media.Provider = p.Name()
+	if media.Slug == "" {
+		media.Slug = util.Slugify(media.Title)
+	}
+	if media.Type == "" {
+		media.Type = types.MediaTypeAnime
+	}
+	media.EpisodeCount = len(media.Episodes)
+	media.ResumePage = 0
+	media.SignedBy = signer
+	media.LastUpdate = types.ClockFrom(ctx).Now()
+	return &media, nil
+}
+
+// verify checks the minisign signature of a database file against the
+// trusted keys, returning the key that signed it; without any keys, files
+// are taken as they are
+func (p *URLProvider) verify(ctx context.Context, source string, data []byte) (string, error) {
+	if len(p.trustedKeys) == 0 {
+		return "", nil
+	}
+	keys, err := p.keys()
+	if err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(source)
+	if err != nil {
+		return "", err
+	}
+	u.Path += ".minisig"
+	sigData, err := p.download(ctx, u.String())
+	if err != nil {
+		return "", fmt.Errorf("no signature for %s (api.trusted_keys is set): %w", source, err)
+	}
+	sig, err := minisign.ParseSignature(sigData)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", u, err)
+	}
+	for _, k := range keys {
+		if _, err := minisign.Verify([]minisign.PublicKey{k}, data, sig); err == nil {
+			return keyFingerprint(k), nil
+		}
+	}
+	if _, err := minisign.Verify(keys, data, sig); err != nil {
+		return "", fmt.Errorf("refusing %s: %w", source, err)
+	}
+	return "", fmt.Errorf("refusing %s: not signed by a trusted key", source)
+}
+
+// ValidCache reports whether a cached file may still be used: with trusted
+// keys set, only one signed by a key still trusted, so a file cached before
+// api.trusted_keys was set (or a key was dropped) is fetched and checked
+// again
+func (p *URLProvider) ValidCache(media *types.Media) bool {
+	if len(p.trustedKeys) == 0 {
+		return true
+	}
+	keys, err := p.keys()
+	if err != nil {
+		return false
+	}
+	return slices.ContainsFunc(keys, func(k minisign.PublicKey) bool {
+		return media.SignedBy != "" && keyFingerprint(k) == media.SignedBy
+	})
+}
+
+// keys parses the trusted keys
+func (p *URLProvider) keys() ([]minisign.PublicKey, error) {
+	var keys []minisign.PublicKey
+	for _, k := range p.trustedKeys {
+		key, err := minisign.ParsePublicKey(k)
+		if err != nil {
+			return nil, fmt.Errorf("api.trusted_keys: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// keyFingerprint identifies a trusted key in the entries it signed
+func keyFingerprint(k minisign.PublicKey) string {
+	return base64.StdEncoding.EncodeToString(k.Key)
+}
+
+// download fetches a file of at most maxDatabaseSize
+func (p *URLProvider) download(ctx context.Context, source string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", source, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent())
+	req.Header.Set("Accept", "application/json, */*")
+
+	service := req.URL.Host
+	resp, err := DoWithRetry(ctx, p.client, req, service, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, types.ErrAPIError{
+			Service:    service,
+			StatusCode: resp.StatusCode,
+			Message:    fmt.Sprintf("request to %s failed", req.URL.Path),
+		}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxDatabaseSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", source, err)
+	}
+	if len(data) > maxDatabaseSize {
+		return nil, fmt.Errorf("%s is larger than %d MiB", source, maxDatabaseSize>>20)
+	}
+	return data, nil
+}
+
+// Search is not supported: a database file is only found by its URL
+func (p *URLProvider) Search(ctx context.Context, query string) ([]types.SearchResult, error) {
+	return nil, nil
+}
+
+// init registers the database URL provider
+func init() {
+	RegisterProvider(func(cfg *types.APIConfig) types.Provider { return NewURLProvider(cfg) })
+}
@@ -0,0 +1,120 @@
+package renamer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mydehq/autotitle/internal/types"
+)
+
+// resolveCollisions checks the whole plan against the files in dir before
+// anything is renamed. A rename onto a file that stays put (unmatched,
+// ignored, skipped or not a video) is dropped as a collision, which may in
+// turn keep another file in place. The rest is ordered so every file is
+// moved away before another takes its name; renames that form a cycle
+// (a → b, b → a) cannot be ordered and are dropped too. A dropped video
+// keeps its subtitles (sidecars, by video name) with it. Dropped files are
+// removed from mappings and episodes, so they are not backed up.
+func (r *Renamer) resolveCollisions(dir string, ops []types.RenameOperation, sidecars map[string][]string, mappings map[string]string, episodes map[string]int) []types.RenameOperation {
+	// Ignored files are still on disk, so list dir directly
+	onDisk := make(map[string]bool)
+	if entries, err := os.ReadDir(dir); err == nil {
+		for _, e := range entries {
+			onDisk[e.Name()] = true
+		}
+	}
+
+	pending := make(map[string]int) // Source name -> index of its rename
+	for i, op := range ops {
+		if op.Status == types.StatusPending {
+			pending[filepath.Base(op.SourcePath)] = i
+		}
+	}
+
+	dropped := make([]bool, len(ops))
+	var drop func(i int, msg string)
+	drop = func(i int, msg string) {
+		dropped[i] = true
+		name := filepath.Base(ops[i].SourcePath)
+		delete(pending, name)
+		delete(mappings, name)
+		delete(episodes, name)
+		r.skip(ops[i].SourcePath, types.ReasonCollision, types.EventError, msg)
+		for _, sub := range sidecars[name] {
+			if j, ok := pending[sub]; ok {
+				drop(j, fmt.Sprintf("Collision detected: %s stays with %s", sub, name))
+			}
+		}
+	}
+
+	var order []int
+	for {
+		// Dropping a rename keeps its file in place, which can block another
+		for changed := true; changed; {
+			changed = false
+			for i, op := range ops {
+				if dropped[i] || op.Status != types.StatusPending {
+					continue
+				}
+				target := filepath.Base(op.TargetPath)
+				if _, moving := pending[target]; onDisk[target] && !moving {
+					drop(i, fmt.Sprintf("Collision detected: %s would overwrite %s, which is not being renamed", filepath.Base(op.SourcePath), target))
+					changed = true
+				}
+			}
+		}
+
+		// Place each rename once the file holding its target name has moved on
+		placed := make([]bool, len(ops))
+		order = order[:0]
+		for progress := true; progress; {
+			progress = false
+			for i, op := range ops {
+				if dropped[i] || placed[i] || op.Status != types.StatusPending {
+					continue
+				}
+				if j, ok := pending[filepath.Base(op.TargetPath)]; ok && !placed[j] {
+					continue
+				}
+				placed[i] = true
+				order = append(order, i)
+				progress = true
+			}
+		}
+
+		cycles := false
+		for i, op := range ops {
+			if !dropped[i] && !placed[i] && op.Status == types.StatusPending {
+				drop(i, fmt.Sprintf("Collision detected: %s → %s is part of a rename cycle", filepath.Base(op.SourcePath), filepath.Base(op.TargetPath)))
+				cycles = true
+			}
+		}
+		if !cycles {
+			break
+		}
+	}
+
+	ordered := make([]types.RenameOperation, 0, len(ops))
+	for _, i := range order {
+		ordered = append(ordered, ops[i])
+	}
+	for i, op := range ops {
+		if op.Status != types.StatusPending {
+			ordered = append(ordered, ops[i])
+		}
+	}
+	return ordered
+}
+
+// targetTaken reports whether renaming source to target would overwrite
+// another file, e.g. one created after the plan was made. A case-only
+// rename on a case-insensitive filesystem finds source itself.
+func targetTaken(source, target string) bool {
+	ti, err := os.Lstat(target)
+	if err != nil {
+		return false
+	}
+	si, err := os.Lstat(source)
+	return err != nil || !os.SameFile(si, ti)
+}
@@ -3,30 +3,48 @@ package renamer
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"slices"
 	"strings"
+	"syscall"
 
 	"github.com/mydehq/autotitle/internal/backup"
+	"github.com/mydehq/autotitle/internal/chapters"
 	"github.com/mydehq/autotitle/internal/config"
+	"github.com/mydehq/autotitle/internal/history"
+	"github.com/mydehq/autotitle/internal/journal"
 	"github.com/mydehq/autotitle/internal/matcher"
 	"github.com/mydehq/autotitle/internal/tagger"
+	"github.com/mydehq/autotitle/internal/thumbnail"
 	"github.com/mydehq/autotitle/internal/types"
+	"github.com/mydehq/autotitle/internal/util"
 )
 
 // Renamer handles file renaming operations
 type Renamer struct {
 	DB            types.DatabaseRepository
 	BackupManager types.BackupManager
+	History       *history.Ledger
+	Journal       *journal.Journal
 	Events        types.EventHandler
 	DryRun        bool
 	NoBackup      bool
 	Tag           bool
+	Tagger        *tagger.Tagger
 	BackupConfig  types.BackupConfig
 	Formats       []string
 	Offset        *int
+	Ignorer       *config.Ignorer
+	TitleCleaner  *matcher.TitleCleaner
+	Subtitles     types.SubtitleConfig
+	Thumbnails    types.ThumbnailConfig
+	Chapters      types.ChapterConfig
+	Origins       map[string]string // Current -> original name; set when re-renaming
+	Clock         types.Clock       // Source of batch journal timestamps
 }
 
 // New creates a new Renamer
@@ -35,6 +53,8 @@ func New(db types.DatabaseRepository, backupConfig types.BackupConfig, formats [
 	cacheRoot := filepath.Dir(dbPath)
 
 	bm := backup.New(cacheRoot, backupConfig.DirName)
+	bm.WithRateLimit(backupConfig.MaxMBps)
+	bm.WithSnapshots(backupConfig.Snapshot)
 
 	if len(formats) == 0 {
 		formats = config.GetDefaults().Formats
@@ -43,11 +63,46 @@ func New(db types.DatabaseRepository, backupConfig types.BackupConfig, formats [
 	return &Renamer{
 		DB:            db,
 		BackupManager: bm,
+		History:       history.New(cacheRoot),
+		Journal:       journal.New(cacheRoot),
 		BackupConfig:  backupConfig,
 		Formats:       formats,
+		Ignorer:       config.NewIgnorer(nil).WithBackupDir(backupConfig.DirName),
+		Clock:         types.SystemClock{},
+		Tagger:        tagger.Default(),
 	}
 }
 
+// WithTitleCleaner sets the cleanup rules applied to EP_NAME
+func (r *Renamer) WithTitleCleaner(c *matcher.TitleCleaner) *Renamer {
+	r.TitleCleaner = c
+	return r
+}
+
+// WithSubtitles sets how subtitles are renamed alongside their video
+func (r *Renamer) WithSubtitles(cfg types.SubtitleConfig) *Renamer {
+	r.Subtitles = cfg
+	return r
+}
+
+// WithThumbnails sets whether and where a thumbnail is taken of each renamed episode
+func (r *Renamer) WithThumbnails(cfg types.ThumbnailConfig) *Renamer {
+	r.Thumbnails = cfg
+	return r
+}
+
+// WithChapters sets whether opening/ending chapters are written into renamed MKVs
+func (r *Renamer) WithChapters(cfg types.ChapterConfig) *Renamer {
+	r.Chapters = cfg
+	return r
+}
+
+// WithIgnorer sets the rules for directory entries to skip
+func (r *Renamer) WithIgnorer(ig *config.Ignorer) *Renamer {
+	r.Ignorer = ig
+	return r
+}
+
 // WithEvents sets the event handler
 func (r *Renamer) WithEvents(h types.EventHandler) *Renamer {
 	r.Events = h
@@ -55,6 +110,14 @@ func (r *Renamer) WithEvents(h types.EventHandler) *Renamer {
 	return r
 }
 
+// WithClock sets the clock used for backup, history and journal timestamps
+func (r *Renamer) WithClock(c types.Clock) *Renamer {
+	r.BackupManager.WithClock(c)
+	r.History.Clock = c
+	r.Clock = c
+	return r
+}
+
 // WithDryRun enables dry-run mode
 func (r *Renamer) WithDryRun() *Renamer {
 	r.DryRun = true
@@ -73,15 +136,36 @@ func (r *Renamer) WithTagging(enabled bool) *Renamer {
 	return r
 }
 
+// WithTagger sets the tagger choosing a backend per file extension
+func (r *Renamer) WithTagger(t *tagger.Tagger) *Renamer {
+	r.Tagger = t
+	return r
+}
+
 // WithOffset sets the episode number offset
 func (r *Renamer) WithOffset(offset int) *Renamer {
 	r.Offset = &offset
 	return r
 }
 
+// WithOrigins makes Execute re-rename the files of an earlier run: each file
+// is matched by its name before that run (origins maps current to original
+// name), files without one are left alone, and the existing backup is
+// remapped to the new names instead of replaced.
+func (r *Renamer) WithOrigins(origins map[string]string) *Renamer {
+	r.Origins = origins
+	return r
+}
+
 // Execute performs the rename operation for a target
 func (r *Renamer) Execute(ctx context.Context, dir string, target *types.Target, media *types.Media) ([]types.RenameOperation, error) {
-	entries, err := os.ReadDir(dir)
+	if !r.DryRun {
+		if err := util.CheckWritable("rename files"); err != nil {
+			return nil, err
+		}
+	}
+
+	entries, err := r.Ignorer.ReadDir(dir)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read directory: %w", err)
 	}
@@ -94,12 +178,20 @@ func (r *Renamer) Execute(ctx context.Context, dir string, target *types.Target,
 		}
 	}
 
-	smartPadding := r.calculatePadding(media)
-
 	var operations []types.RenameOperation
 	renameMappings := make(map[string]string)
+	renameEpisodes := make(map[string]int)
 
 	usedTargets := make(map[string]bool)
+	sidecars := make(map[string][]string) // Video name -> its renamed subtitles
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	withSubtitles := r.Subtitles.Enabled == nil || *r.Subtitles.Enabled
 
 	for _, entry := range entries {
 		if entry.IsDir() {
@@ -112,6 +204,16 @@ func (r *Renamer) Execute(ctx context.Context, dir string, target *types.Target,
 			continue
 		}
 
+		matchName := filename
+		if r.Origins != nil {
+			original, ok := r.Origins[filename]
+			if !ok {
+				r.skip(filepath.Join(dir, filename), types.ReasonNoPattern, types.EventWarning, fmt.Sprintf("No original name recorded: %s", filename))
+				continue
+			}
+			matchName = original
+		}
+
 		var matchResult *matcher.MatchResult
 		var matchPattern *types.Pattern
 
@@ -121,7 +223,7 @@ func (r *Renamer) Execute(ctx context.Context, dir string, target *types.Target,
 			for range target.Patterns[i].Input {
 				if patIdx < len(patterns) {
 					p := patterns[patIdx]
-					if result, ok := p.MatchTyped(filename); ok {
+					if result, ok := p.MatchTyped(matchName); ok {
 						matchResult = result
 						matchPattern = &target.Patterns[i]
 						found = true
@@ -136,50 +238,20 @@ func (r *Renamer) Execute(ctx context.Context, dir string, target *types.Target,
 		}
 
 		if matchResult == nil {
-			r.emit(types.Event{Type: types.EventWarning, Message: fmt.Sprintf("No pattern matched: %s", filename)})
+			r.skip(filepath.Join(dir, filename), types.ReasonNoPattern, types.EventWarning, fmt.Sprintf("No pattern matched: %s", matchName))
 			continue
 		}
 
-		outputCfg := matchPattern.Output
-
-		padding := outputCfg.Padding
-		if padding == 0 {
-			padding = smartPadding
-		}
-
-		// Calculate Offset
-		offset := MatchResultOffset(r.Offset, matchPattern)
-
-		// Get Episode
-		episodeNum := matchResult.EpisodeNum + offset
-		ep := media.GetEpisode(episodeNum)
-		if ep == nil {
+		ep, newFilename, err := NewName(media, target, matchPattern, matchResult, r.Offset, r.TitleCleaner)
+		var notFound types.ErrEpisodeNotFound
+		if errors.As(err, &notFound) {
 			msg := fmt.Sprintf("Episode %d not found in database", matchResult.EpisodeNum)
-			if offset != 0 {
-				msg = fmt.Sprintf("Episode %d (mapped to %d) not found in database", matchResult.EpisodeNum, episodeNum)
+			if notFound.Number != matchResult.EpisodeNum {
+				msg = fmt.Sprintf("Episode %d (mapped to %d) not found in database", matchResult.EpisodeNum, notFound.Number)
 			}
-			r.emit(types.Event{Type: types.EventWarning, Message: msg})
+			r.skip(filepath.Join(dir, filename), types.ReasonNoEpisode, types.EventWarning, msg)
 			continue
 		}
-
-		// Build Variables
-		vars := matcher.TemplateVars{
-			Series:   media.GetTitle("SERIES"),
-			SeriesEn: media.GetTitle("SERIES_EN"),
-			SeriesJp: media.GetTitle("SERIES_JP"),
-			EpNum:    fmt.Sprintf("%d", ep.Number),
-			EpName:   ep.Title,
-			Res:      matchResult.Resolution,
-			Ext:      matchResult.Extension,
-		}
-		if ep.IsFiller {
-			vars.Filler = "[F]"
-		}
-
-		// Generate Filename
-		separator := outputCfg.Separator
-
-		newFilename, err := matcher.GenerateFilenameFromFields(outputCfg.Fields, separator, vars, padding)
 		if err != nil {
 			r.emit(types.Event{Type: types.EventError, Message: fmt.Sprintf("Failed to generate filename: %v", err)})
 			continue
@@ -190,7 +262,7 @@ func (r *Renamer) Execute(ctx context.Context, dir string, target *types.Target,
 
 		// Check for target collision
 		if usedTargets[targetPath] {
-			r.emit(types.Event{Type: types.EventError, Message: fmt.Sprintf("Collision detected: %s and another file both want to rename to %s", filename, newFilename)})
+			r.skip(sourcePath, types.ReasonCollision, types.EventError, fmt.Sprintf("Collision detected: %s and another file both want to rename to %s", filename, newFilename))
 			continue
 		}
 		usedTargets[targetPath] = true
@@ -205,28 +277,133 @@ func (r *Renamer) Execute(ctx context.Context, dir string, target *types.Target,
 
 		if sourcePath == targetPath {
 			op.Status = types.StatusSkipped
+			op.Reason = types.ReasonUnchanged
 			r.emit(types.Event{Type: types.EventInfo, Message: fmt.Sprintf("Skipped (unchanged): %s", filename)})
 		} else {
 			renameMappings[filename] = newFilename
-			if r.DryRun {
-				r.emit(types.Event{Type: types.EventInfo, Message: fmt.Sprintf("[DRY-RUN] %s → %s", filename, newFilename)})
-			}
+			renameEpisodes[filename] = ep.Number
 		}
 
 		operations = append(operations, op)
+
+		if !withSubtitles {
+			continue
+		}
+		for _, sub := range r.findSubtitles(names, filename) {
+			newSub := r.subtitleName(sub, filename, newFilename)
+			if newSub == sub {
+				continue
+			}
+			subTarget := filepath.Join(dir, newSub)
+			if usedTargets[subTarget] {
+				r.skip(filepath.Join(dir, sub), types.ReasonCollision, types.EventError, fmt.Sprintf("Collision detected: %s and another file both want to rename to %s", sub, newSub))
+				continue
+			}
+			usedTargets[subTarget] = true
+
+			operations = append(operations, types.RenameOperation{
+				SourcePath: filepath.Join(dir, sub),
+				TargetPath: subTarget,
+				Episode:    ep,
+				Series:     media.Title,
+				Status:     types.StatusPending,
+			})
+			renameMappings[sub] = newSub
+			renameEpisodes[sub] = ep.Number
+			sidecars[filename] = append(sidecars[filename], sub)
+		}
 	}
 
-	// Perform Backup
-	if err := r.performBackup(ctx, dir, renameMappings); err != nil {
-		return nil, err
+	operations = r.resolveCollisions(dir, operations, sidecars, renameMappings, renameEpisodes)
+
+	if r.DryRun {
+		for _, op := range operations {
+			if op.Status == types.StatusPending {
+				r.emit(types.Event{Type: types.EventInfo, Message: fmt.Sprintf("[DRY-RUN] %s → %s", filepath.Base(op.SourcePath), filepath.Base(op.TargetPath))})
+			}
+		}
+		r.simulate(ctx, dir, operations, renameMappings)
 	}
 
-	// Perform Rename
-	r.performRenames(operations)
+	// Perform Backup; a re-rename keeps the backup of the originals
+	if r.Origins == nil {
+		if err := r.performBackup(ctx, dir, renameMappings, renameEpisodes); err != nil {
+			return nil, err
+		}
+	}
 
+	// Perform Rename; a plain batch is journaled so it can be resumed
+	var batch *types.Batch
+	if r.Origins == nil {
+		batch = r.startBatch(dir, media, operations)
+	}
+	interrupted := r.performRenames(ctx, operations)
+	if r.Origins != nil {
+		r.remapBackup(ctx, dir, operations)
+	} else if interrupted != nil && len(renameMappings) > 0 {
+		r.trimBackup(ctx, dir, operations)
+	}
+	r.recordHistory(dir, media, operations)
+	r.finishBatch(batch, operations, interrupted != nil)
+
+	if interrupted != nil {
+		return operations, *interrupted
+	}
 	return operations, nil
 }
 
+// trimBackup drops the files an interrupted batch never reached from the
+// backup, so Undo only touches what was renamed
+func (r *Renamer) trimBackup(ctx context.Context, dir string, ops []types.RenameOperation) {
+	if r.DryRun || r.NoBackup || !r.BackupConfig.Enabled {
+		return
+	}
+	renamed := make(map[string]bool)
+	for _, op := range ops {
+		if op.Status == types.StatusSuccess {
+			renamed[filepath.Base(op.SourcePath)] = true
+		}
+	}
+	// The batch is over, so the trim must not see the cancelled context
+	err := r.BackupManager.Trim(context.WithoutCancel(ctx), dir, func(e types.RestoreEntry) bool {
+		return renamed[e.Original]
+	})
+	if err != nil {
+		r.emit(types.Event{Type: types.EventWarning, Message: fmt.Sprintf("Failed to update backup: %v", err)})
+	}
+}
+
+// recordHistory adds the run to the local history ledger. A failure only
+// costs the usage report, so it is a warning.
+func (r *Renamer) recordHistory(dir string, media *types.Media, ops []types.RenameOperation) {
+	if r.DryRun || r.History == nil {
+		return
+	}
+	renamed := 0
+	for _, op := range ops {
+		if op.Status == types.StatusSuccess {
+			renamed++
+		}
+	}
+	if renamed == 0 {
+		return
+	}
+
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	err := r.History.Record(types.HistoryEntry{
+		Dir:      dir,
+		Provider: media.Provider,
+		ID:       media.ID,
+		Series:   media.Title,
+		Files:    renamed,
+	})
+	if err != nil {
+		r.emit(types.Event{Type: types.EventWarning, Message: fmt.Sprintf("Failed to record history: %v", err)})
+	}
+}
+
 func (r *Renamer) compilePatterns(target *types.Target) ([]*matcher.Pattern, error) {
 	var patterns []*matcher.Pattern
 	var errs []string
@@ -248,7 +425,116 @@ func (r *Renamer) compilePatterns(target *types.Target) ([]*matcher.Pattern, err
 	return patterns, nil
 }
 
-func (r *Renamer) calculatePadding(media *types.Media) int {
+// BuildTemplateVars builds the output template variables for a matched episode
+func BuildTemplateVars(media *types.Media, ep *types.Episode, match *matcher.MatchResult) matcher.TemplateVars {
+	vars := matcher.TemplateVars{
+		Series:   media.GetTitle("SERIES"),
+		SeriesEn: media.GetTitle("SERIES_EN"),
+		SeriesJp: media.GetTitle("SERIES_JP"),
+		EpNum:    fmt.Sprintf("%d", ep.Number),
+		EpName:   ep.Title,
+		EpNameJp: ep.TitleJP,
+		Artist:   ep.Artist,
+		Volume:   ep.Volume,
+		Res:      match.Resolution,
+		Ext:      match.Extension,
+		Custom:   match.Custom,
+	}
+	if media.Year > 0 {
+		vars.Year = fmt.Sprintf("%d", media.Year)
+	}
+	if match.Part > 0 {
+		vars.Part = fmt.Sprintf("%d", match.Part)
+	}
+	vars.Source = match.Release.Source
+	if match.Release.Dual {
+		vars.Dual = "Dual Audio"
+	}
+	vars.AudioLang = strings.Join(match.Release.AudioLangs, "+")
+	if ep.IsFiller {
+		vars.Filler = "[F]"
+	}
+	if ep.Watched {
+		vars.Watched = "[W]"
+	}
+	return vars
+}
+
+// MatchedEpisode returns the episode number a match refers to. Without an
+// EP_NUM, a part number is used so multi-part OVAs map to provider episodes.
+func MatchedEpisode(match *matcher.MatchResult) int {
+	if match.EpisodeNum == 0 && match.Part > 0 {
+		return match.Part
+	}
+	return match.EpisodeNum
+}
+
+// LookupEpisode returns episode n of media. Movies have at most one entry, so
+// they fall back to the first (or a stand-in) when n is not listed.
+func LookupEpisode(media *types.Media, output types.OutputConfig, n int) *types.Episode {
+	if ep := media.GetEpisode(n); ep != nil {
+		return ep
+	}
+	if media.Type != types.MediaTypeMovie && output.Preset != types.PresetMovie {
+		return nil
+	}
+	if len(media.Episodes) > 0 {
+		return &media.Episodes[0]
+	}
+	return &types.Episode{Number: 1, Title: media.Title}
+}
+
+// RomanizeVars transliterates the Japanese variables to romaji. Kana are
+// converted locally; titles with kanji fall back to the provider's romaji.
+func RomanizeVars(vars *matcher.TemplateVars, media *types.Media, ep *types.Episode) {
+	if ep.TitleRomaji != "" && util.ContainsKanji(ep.TitleJP) {
+		vars.EpNameJp = ep.TitleRomaji
+	} else {
+		vars.EpNameJp = util.Romanize(vars.EpNameJp)
+	}
+
+	if util.ContainsKanji(vars.SeriesJp) {
+		vars.SeriesJp = media.Title
+	} else {
+		vars.SeriesJp = util.Romanize(vars.SeriesJp)
+	}
+}
+
+// NewName returns the episode that match refers to and the name Execute
+// gives the file, following the output of pattern, the numbering of target
+// and offset. It returns ErrEpisodeNotFound with the mapped number when
+// media has no such episode.
+func NewName(media *types.Media, target *types.Target, pattern *types.Pattern, match *matcher.MatchResult, offset *int, cleaner *matcher.TitleCleaner) (*types.Episode, string, error) {
+	output := config.ResolveOutput(pattern.Output, media.Type)
+
+	episodeNum := MatchedEpisode(match) + target.NumberingShift() + MatchResultOffset(offset, pattern)
+	ep := LookupEpisode(media, output, episodeNum)
+	if ep == nil {
+		return nil, "", types.ErrEpisodeNotFound{Number: episodeNum}
+	}
+
+	padding := output.Padding
+	if padding == 0 {
+		padding = CalculatePadding(media)
+	}
+
+	vars := BuildTemplateVars(media, ep, match)
+	vars.EpName = cleaner.Clean(vars.EpName)
+	vars.Res = matcher.FormatResolution(vars.Res, output.ResFormat)
+	vars.AirDate = matcher.FormatAirDate(ep.AirDate, output.DateFormat)
+	if output.Romanize {
+		RomanizeVars(&vars, media, ep)
+	}
+
+	name, err := matcher.GenerateFilenameFromFields(output.Fields, output.Separator, vars, padding)
+	if err != nil {
+		return nil, "", err
+	}
+	return ep, name, nil
+}
+
+// CalculatePadding returns the episode number width needed for the media's highest episode
+func CalculatePadding(media *types.Media) int {
 	smartPadding := 2
 	maxEp := media.EpisodeCount
 
@@ -277,18 +563,38 @@ func MatchResultOffset(globalOffset *int, pattern *types.Pattern) int {
 	return 0
 }
 
-func (r *Renamer) performBackup(ctx context.Context, dir string, mappings map[string]string) error {
+func (r *Renamer) performBackup(ctx context.Context, dir string, mappings map[string]string, episodes map[string]int) error {
 	shouldBackup := !r.DryRun && !r.NoBackup && r.BackupConfig.Enabled
 	if shouldBackup && len(mappings) > 0 {
 		r.emit(types.Event{Type: types.EventInfo, Message: "Creating backup..."})
-		if err := r.BackupManager.Backup(ctx, dir, mappings); err != nil {
+		if err := r.BackupManager.Backup(ctx, dir, mappings, episodes); err != nil {
 			return fmt.Errorf("backup failed: %w", err)
 		}
 	}
 	return nil
 }
 
-func (r *Renamer) performRenames(ops []types.RenameOperation) {
+// remapBackup points the backup at the new names of the files that were
+// renamed, so Undo still restores the originals
+func (r *Renamer) remapBackup(ctx context.Context, dir string, ops []types.RenameOperation) {
+	renames := make(map[string]string)
+	for _, op := range ops {
+		if op.Status == types.StatusSuccess {
+			renames[filepath.Base(op.SourcePath)] = filepath.Base(op.TargetPath)
+		}
+	}
+	if len(renames) == 0 {
+		return
+	}
+	if err := r.BackupManager.Remap(ctx, dir, renames); err != nil {
+		r.emit(types.Event{Type: types.EventWarning, Message: fmt.Sprintf("Failed to update backup: %v", err)})
+	}
+}
+
+// performRenames renames the pending operations in order. On cancellation
+// it stops after the current file, leaves the rest pending and reports how
+// far it got.
+func (r *Renamer) performRenames(ctx context.Context, ops []types.RenameOperation) *types.ErrInterrupted {
 	for i, op := range ops {
 		if op.Status == types.StatusSkipped {
 			continue
@@ -296,18 +602,83 @@ func (r *Renamer) performRenames(ops []types.RenameOperation) {
 		if r.DryRun {
 			continue
 		}
+		if ctx.Err() != nil {
+			e := &types.ErrInterrupted{}
+			for _, op := range ops {
+				switch op.Status {
+				case types.StatusSuccess:
+					e.Renamed++
+				case types.StatusPending:
+					e.Remaining++
+				}
+			}
+			return e
+		}
+
+		// The plan is collision-free; a file that appeared since is never overwritten
+		if targetTaken(op.SourcePath, op.TargetPath) {
+			ops[i].Status = types.StatusFailed
+			ops[i].Reason = types.ReasonCollision
+			ops[i].Error = "target already exists"
+			r.emit(types.Event{Type: types.EventError, Message: fmt.Sprintf("Failed: %s: %s already exists", filepath.Base(op.SourcePath), filepath.Base(op.TargetPath))})
+			continue
+		}
 
 		if err := os.Rename(op.SourcePath, op.TargetPath); err != nil {
 			ops[i].Status = types.StatusFailed
+			ops[i].Reason = types.ReasonError
+			if errors.Is(err, fs.ErrPermission) || errors.Is(err, syscall.EBUSY) {
+				ops[i].Reason = types.ReasonLocked
+			}
 			ops[i].Error = err.Error()
 			r.emit(types.Event{Type: types.EventError, Message: fmt.Sprintf("Failed: %s: %v", filepath.Base(op.SourcePath), err)})
 		} else {
 			ops[i].Status = types.StatusSuccess
 			r.emit(types.Event{Type: types.EventSuccess, Message: fmt.Sprintf("Renamed: %s → %s", filepath.Base(op.SourcePath), filepath.Base(op.TargetPath))})
 
-			if r.Tag && op.Episode != nil {
+			if r.Tag && op.Episode != nil && r.isVideoFile(filepath.Ext(op.TargetPath)) {
 				r.tagFile(op.TargetPath, op.Episode, ops[i].Series)
 			}
+			if r.Chapters.Enabled && op.Episode != nil && chapters.IsSupported(op.TargetPath) {
+				r.markChapters(ctx, op.TargetPath)
+			}
+			if r.Thumbnails.Enabled && op.Episode != nil && r.isVideoFile(filepath.Ext(op.TargetPath)) {
+				r.thumbnail(ctx, op.TargetPath)
+			}
+		}
+	}
+	return nil
+}
+
+// simulate fills in what a real run would do besides renaming: the backend
+// that would tag each file and how its backup would be stored
+func (r *Renamer) simulate(ctx context.Context, dir string, ops []types.RenameOperation, mappings map[string]string) {
+	if r.Tag {
+		for i, op := range ops {
+			if op.Status != types.StatusPending || op.Episode == nil || !r.isVideoFile(filepath.Ext(op.TargetPath)) {
+				continue
+			}
+			if backend := r.Tagger.Backend(op.TargetPath); backend != tagger.BackendNone {
+				ops[i].TagBackend = backend
+			}
+		}
+	}
+
+	if r.NoBackup || !r.BackupConfig.Enabled || r.Origins != nil || len(mappings) == 0 {
+		return
+	}
+	estimates, err := r.BackupManager.Estimate(ctx, dir, mappings)
+	if err != nil {
+		r.emit(types.Event{Type: types.EventWarning, Message: fmt.Sprintf("Cannot estimate backup: %v", err)})
+		return
+	}
+	byName := make(map[string]types.BackupEstimate, len(estimates))
+	for _, e := range estimates {
+		byName[e.Name] = e
+	}
+	for i, op := range ops {
+		if e, ok := byName[filepath.Base(op.SourcePath)]; ok && op.Status == types.StatusPending {
+			ops[i].Backup, ops[i].BackupBytes = e.Method, e.Bytes
 		}
 	}
 }
@@ -320,13 +691,49 @@ func (r *Renamer) tagFile(path string, ep *types.Episode, show string) {
 		EpisodeSort: ep.Number,
 		AirDate:     ep.AirDate,
 	}
-	if err := tagger.TagFile(context.Background(), path, info); err != nil {
+	if err := r.Tagger.TagFile(context.Background(), path, info); err != nil {
 		r.emit(types.Event{Type: types.EventWarning, Message: fmt.Sprintf("Tagging failed for %s: %v", filepath.Base(path), err)})
 	} else {
 		r.emit(types.Event{Type: types.EventInfo, Message: fmt.Sprintf("Tagged: %s", filepath.Base(path))})
 	}
 }
 
+// markChapters writes the opening and ending of a renamed episode as
+// chapters. Like tagging, failures are only warned about.
+func (r *Renamer) markChapters(ctx context.Context, path string) {
+	marked, err := chapters.Mark(ctx, path, r.Chapters)
+	if err != nil {
+		r.emit(types.Event{Type: types.EventWarning, Message: fmt.Sprintf("Chapters failed for %s: %v", filepath.Base(path), err)})
+	} else if len(marked) > 0 {
+		r.emit(types.Event{Type: types.EventInfo, Message: fmt.Sprintf("Chapters: %s (%d)", filepath.Base(path), len(marked))})
+	}
+}
+
+// thumbnail extracts the thumbnail of a renamed episode. Failures are only
+// warned about: the rename itself succeeded.
+func (r *Renamer) thumbnail(ctx context.Context, path string) {
+	out, err := thumbnail.Extract(ctx, path, r.Thumbnails)
+	if err != nil {
+		r.emit(types.Event{Type: types.EventWarning, Message: fmt.Sprintf("Thumbnail failed for %s: %v", filepath.Base(path), err)})
+	} else if out != "" {
+		r.emit(types.Event{Type: types.EventInfo, Message: fmt.Sprintf("Thumbnail: %s", filepath.Base(out))})
+	}
+}
+
+// skip reports a file that was left out of the plan. The event carries a
+// skipped RenameOperation in its Data so callers can group skips by reason.
+func (r *Renamer) skip(sourcePath string, reason types.SkipReason, t types.EventType, msg string) {
+	r.emit(types.Event{
+		Type:    t,
+		Message: msg,
+		Data: types.RenameOperation{
+			SourcePath: sourcePath,
+			Status:     types.StatusSkipped,
+			Reason:     reason,
+		},
+	})
+}
+
 func (r *Renamer) emit(e types.Event) {
 	if r.Events != nil {
 		r.Events(e)
@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/mydehq/autotitle/internal/config"
+	"github.com/mydehq/autotitle/internal/journal"
 	"github.com/mydehq/autotitle/internal/types"
 )
 
@@ -134,3 +135,334 @@ func TestRenamer_OffsetZeroOverride(t *testing.T) {
 		t.Errorf("Expected matched episode number 1, got %d", op.Episode.Number)
 	}
 }
+
+func TestRenamer_NumberingBase(t *testing.T) {
+	media := &types.Media{
+		Title: "Test Series",
+		Episodes: []types.Episode{
+			{Number: 1, Title: "Episode 1"},
+			{Number: 2, Title: "Episode 2"},
+		},
+	}
+
+	base := 0
+	target := &config.Target{
+		NumberingBase: &base,
+		Patterns: []config.Pattern{
+			{
+				Input: []string{"{{SERIES}} - {{EP_NUM}}"},
+				Output: config.OutputConfig{
+					Fields:    []string{"SERIES", "EP_NUM", "EP_NAME"},
+					Separator: " - ",
+				},
+			},
+		},
+	}
+
+	tmpDir := t.TempDir()
+	for _, name := range []string{"Test Series - 00.mkv", "Test Series - 01.mkv"} {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := New(&MockDB{}, types.BackupConfig{Enabled: false}, []string{"mkv"})
+	r.WithDryRun()
+
+	ops, err := r.Execute(context.Background(), tmpDir, target, media)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if len(ops) != 2 {
+		t.Fatalf("Expected 2 operations, got %d", len(ops))
+	}
+
+	got := map[string]string{}
+	for _, op := range ops {
+		got[filepath.Base(op.SourcePath)] = filepath.Base(op.TargetPath)
+	}
+	want := map[string]string{
+		"Test Series - 00.mkv": "Test Series - 01 - Episode 1.mkv",
+		"Test Series - 01.mkv": "Test Series - 02 - Episode 2.mkv",
+	}
+	for src, dst := range want {
+		if got[src] != dst {
+			t.Errorf("%s: expected %s, got %s", src, dst, got[src])
+		}
+	}
+}
+
+func TestRenamer_SkipReasons(t *testing.T) {
+	media := &types.Media{
+		Title:    "Test Series",
+		Episodes: []types.Episode{{Number: 1, Title: "Episode 1"}},
+	}
+
+	target := &config.Target{
+		Patterns: []config.Pattern{
+			{
+				Input: []string{"{{SERIES}} - {{EP_NUM}}"},
+				Output: config.OutputConfig{
+					Fields:    []string{"SERIES", "EP_NUM", "EP_NAME"},
+					Separator: " - ",
+				},
+			},
+		},
+	}
+
+	tmpDir := t.TempDir()
+	for _, name := range []string{"Test Series - 02.mkv", "random.mkv"} {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reasons := make(map[string]types.SkipReason)
+	r := New(&MockDB{}, types.BackupConfig{Enabled: false}, []string{"mkv"})
+	r.WithDryRun()
+	r.WithEvents(func(e types.Event) {
+		if op, ok := e.Data.(types.RenameOperation); ok {
+			reasons[filepath.Base(op.SourcePath)] = op.Reason
+		}
+	})
+
+	if _, err := r.Execute(context.Background(), tmpDir, target, media); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if got := reasons["Test Series - 02.mkv"]; got != types.ReasonNoEpisode {
+		t.Errorf("Expected %q for missing episode, got %q", types.ReasonNoEpisode, got)
+	}
+	if got := reasons["random.mkv"]; got != types.ReasonNoPattern {
+		t.Errorf("Expected %q for unmatched file, got %q", types.ReasonNoPattern, got)
+	}
+}
+
+func TestRenamer_Subtitles(t *testing.T) {
+	media := &types.Media{
+		Title:    "Show",
+		Episodes: []types.Episode{{Number: 1, Title: "One"}, {Number: 10, Title: "Ten"}},
+	}
+	target := &config.Target{
+		Patterns: []config.Pattern{{
+			Input:  []string{"Ep {{EP_NUM}}.{{EXT}}"},
+			Output: config.OutputConfig{Fields: []string{"SERIES", "EP_NUM"}, Separator: " - "},
+		}},
+	}
+
+	dir := t.TempDir()
+	for _, name := range []string{
+		"Ep 1.mkv", "Ep 1.srt", "Ep 1.en.forced.srt", "Ep 1 [Japanese].ass",
+		"Ep 10.mkv", "Ep 10.English.srt",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := New(&MockDB{}, types.BackupConfig{Enabled: false}, []string{"mkv"})
+	r.History = nil
+	if _, err := r.Execute(context.Background(), dir, target, media); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	for _, want := range []string{
+		"Show - 01.mkv", "Show - 01.srt", "Show - 01.eng.forced.srt", "Show - 01.jpn.ass",
+		"Show - 10.mkv", "Show - 10.eng.srt",
+	} {
+		if _, err := os.Stat(filepath.Join(dir, want)); err != nil {
+			t.Errorf("Expected %s: %v", want, err)
+		}
+	}
+
+	// 639-1 codes and custom tags
+	r = New(&MockDB{}, types.BackupConfig{Enabled: false}, []string{"mkv"})
+	r.WithSubtitles(types.SubtitleConfig{Codes: "639-1", Languages: map[string]string{"eng": "en-US"}})
+	if got := r.subtitleName("a.eng.srt", "a.mkv", "b.mkv"); got != "b.en-US.srt" {
+		t.Errorf("Custom language tag: got %q", got)
+	}
+	if got := r.subtitleName("a [Japanese].ass", "a.mkv", "b.mkv"); got != "b.ja.ass" {
+		t.Errorf("639-1 code: got %q", got)
+	}
+}
+
+func TestRenamer_DryRunSimulation(t *testing.T) {
+	media := &types.Media{
+		Title:    "Test Series",
+		Episodes: []types.Episode{{Number: 1, Title: "Episode 1"}},
+	}
+
+	target := &config.Target{
+		Patterns: []config.Pattern{
+			{
+				Input: []string{"{{SERIES}} - {{EP_NUM}}"},
+				Output: config.OutputConfig{
+					Fields:    []string{"SERIES", "EP_NUM", "EP_NAME"},
+					Separator: " - ",
+				},
+			},
+		},
+	}
+
+	tmpDir := t.TempDir()
+	for _, name := range []string{"Test Series - 01.mkv", "Test Series - 01.ass"} {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := New(&MockDB{}, types.BackupConfig{Enabled: true}, []string{"mkv"})
+	r.WithDryRun()
+	r.WithTagging(true)
+
+	ops, err := r.Execute(context.Background(), tmpDir, target, media)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if len(ops) != 2 {
+		t.Fatalf("Expected the episode and its subtitle, got %d operations", len(ops))
+	}
+	for _, op := range ops {
+		isVideo := filepath.Ext(op.SourcePath) == ".mkv"
+		if (op.TagBackend != "") != isVideo {
+			t.Errorf("%s: tag backend %q", filepath.Base(op.SourcePath), op.TagBackend)
+		}
+		if op.Backup != types.BackupLink || op.BackupBytes != 0 {
+			t.Errorf("%s: expected a linked backup, got %q (%d bytes)", filepath.Base(op.SourcePath), op.Backup, op.BackupBytes)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, ".autotitle_backup")); !os.IsNotExist(err) {
+		t.Error("A dry-run should not create a backup")
+	}
+}
+
+func TestRenamer_ResolveCollisions(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.mkv", "b.mkv", "c.mkv", "kept.mkv", "d.ass"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	op := func(from, to string) types.RenameOperation {
+		return types.RenameOperation{SourcePath: filepath.Join(dir, from), TargetPath: filepath.Join(dir, to), Status: types.StatusPending}
+	}
+	ops := []types.RenameOperation{
+		op("a.mkv", "b.mkv"),    // Swap with the next one: a cycle
+		op("b.mkv", "a.mkv"),    //
+		op("c.mkv", "kept.mkv"), // kept.mkv is not renamed
+		op("d.ass", "e.ass"),    // Subtitle of c.mkv, stays with it
+	}
+	mappings := map[string]string{"a.mkv": "b.mkv", "b.mkv": "a.mkv", "c.mkv": "kept.mkv", "d.ass": "e.ass"}
+
+	var skipped []string
+	r := New(&MockDB{}, types.BackupConfig{Enabled: false}, []string{"mkv"})
+	r.WithEvents(func(e types.Event) {
+		if op, ok := e.Data.(types.RenameOperation); ok && op.Reason == types.ReasonCollision {
+			skipped = append(skipped, filepath.Base(op.SourcePath))
+		}
+	})
+
+	got := r.resolveCollisions(dir, ops, map[string][]string{"c.mkv": {"d.ass"}}, mappings, map[string]int{})
+	if len(got) != 0 {
+		t.Errorf("Expected every rename dropped, got %+v", got)
+	}
+	if len(skipped) != 4 {
+		t.Errorf("Expected 4 collisions, got %v", skipped)
+	}
+	if len(mappings) != 0 {
+		t.Errorf("Dropped files should not be backed up, mappings left: %v", mappings)
+	}
+}
+
+func TestRenamer_RenameChain(t *testing.T) {
+	media := &types.Media{
+		Title:    "Test Series",
+		Episodes: []types.Episode{{Number: 1}, {Number: 2}, {Number: 3}},
+	}
+
+	// Ep 1 -> Ep 2 only works once Ep 2 -> Ep 3 has moved out of the way
+	target := &config.Target{
+		Patterns: []config.Pattern{
+			{
+				Input: []string{"Ep {{EP_NUM}}"},
+				Output: config.OutputConfig{
+					Fields:    []string{`"Ep"`, "EP_NUM"},
+					Separator: " ",
+					Padding:   1,
+					Offset:    1,
+				},
+			},
+		},
+	}
+
+	tmpDir := t.TempDir()
+	for _, name := range []string{"Ep 1.mkv", "Ep 2.mkv"} {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := New(&MockDB{}, types.BackupConfig{Enabled: false}, []string{"mkv"})
+	r.History, r.Journal = nil, nil
+	if _, err := r.Execute(context.Background(), tmpDir, target, media); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	for name, want := range map[string]string{"Ep 2.mkv": "Ep 1.mkv", "Ep 3.mkv": "Ep 2.mkv"} {
+		data, err := os.ReadFile(filepath.Join(tmpDir, name))
+		if err != nil || string(data) != want {
+			t.Errorf("%s holds %q (%v), want the former %s", name, data, err, want)
+		}
+	}
+}
+
+func TestRenamer_ResumeKilledChain(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := func(name string) string { return filepath.Join(tmpDir, name) }
+	// Planned as Ep 2 -> Ep 3, then Ep 1 -> Ep 2
+	plan := func() []types.RenameOperation {
+		return []types.RenameOperation{
+			{SourcePath: path("Ep 2.mkv"), TargetPath: path("Ep 3.mkv"), Status: types.StatusPending},
+			{SourcePath: path("Ep 1.mkv"), TargetPath: path("Ep 2.mkv"), Status: types.StatusPending},
+		}
+	}
+
+	tests := []struct {
+		name  string
+		files map[string]string // Name -> content, as the kill left them
+	}{
+		{"killed after the first rename", map[string]string{"Ep 1.mkv": "Ep 1.mkv", "Ep 3.mkv": "Ep 2.mkv"}},
+		{"killed after both renames", map[string]string{"Ep 2.mkv": "Ep 1.mkv", "Ep 3.mkv": "Ep 2.mkv"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"Ep 1.mkv", "Ep 2.mkv", "Ep 3.mkv"} {
+				os.Remove(path(name))
+			}
+			for name, content := range tt.files {
+				if err := os.WriteFile(path(name), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			r := New(&MockDB{}, types.BackupConfig{Enabled: false}, []string{"mkv"})
+			r.History, r.Journal = nil, journal.New(t.TempDir())
+			ops, err := r.Resume(context.Background(), &types.Batch{Dir: tmpDir, Operations: plan()})
+			if err != nil {
+				t.Fatalf("Resume failed: %v", err)
+			}
+			for _, op := range ops {
+				if op.Status != types.StatusSuccess {
+					t.Errorf("Expected %s done, got %+v", filepath.Base(op.TargetPath), op)
+				}
+			}
+			for name, want := range map[string]string{"Ep 2.mkv": "Ep 1.mkv", "Ep 3.mkv": "Ep 2.mkv"} {
+				data, err := os.ReadFile(path(name))
+				if err != nil || string(data) != want {
+					t.Errorf("%s holds %q (%v), want the former %s", name, data, err, want)
+				}
+			}
+		})
+	}
+}
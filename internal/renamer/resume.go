@@ -0,0 +1,168 @@
+package renamer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mydehq/autotitle/internal/types"
+	"github.com/mydehq/autotitle/internal/util"
+)
+
+// startBatch journals the plan of a batch about to be renamed and returns
+// it, or nil if there is nothing to journal. Progress is only written when
+// the batch ends, so a batch killed outright is reconciled on resume.
+func (r *Renamer) startBatch(dir string, media *types.Media, ops []types.RenameOperation) *types.Batch {
+	if r.DryRun || r.Journal == nil {
+		return nil
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil
+	}
+
+	b := &types.Batch{
+		Dir:        absDir,
+		Provider:   media.Provider,
+		ID:         media.ID,
+		Series:     media.Title,
+		Backup:     !r.NoBackup && r.BackupConfig.Enabled,
+		BackupDir:  r.BackupConfig.DirName,
+		Tag:        r.Tag,
+		Started:    r.Clock.Now(),
+		Operations: ops,
+	}
+	if b.Pending() == 0 {
+		// Nothing to resume; a batch left over from an earlier run is stale
+		r.finishBatch(b, ops, false)
+		return nil
+	}
+	if err := r.Journal.Put(*b); err != nil {
+		r.emit(types.Event{Type: types.EventWarning, Message: fmt.Sprintf("Failed to journal batch: %v", err)})
+		return nil
+	}
+	return b
+}
+
+// finishBatch drops the batch from the journal once it is done, or records
+// how far it got if it was interrupted
+func (r *Renamer) finishBatch(b *types.Batch, ops []types.RenameOperation, interrupted bool) {
+	if b == nil {
+		return
+	}
+	var err error
+	if interrupted {
+		b.Operations = ops
+		err = r.Journal.Put(*b)
+	} else {
+		err = r.Journal.Remove(b.Dir)
+	}
+	if err != nil {
+		r.emit(types.Event{Type: types.EventWarning, Message: fmt.Sprintf("Failed to update batch journal: %v", err)})
+	}
+}
+
+// Resume renames the pending operations of an interrupted batch as planned,
+// backing them up first if the batch was backed up. Pending files that were
+// already renamed (the batch was killed before it could record them) are
+// marked done rather than renamed twice. It returns the whole batch.
+func (r *Renamer) Resume(ctx context.Context, b *types.Batch) ([]types.RenameOperation, error) {
+	if !r.DryRun {
+		if err := util.CheckWritable("rename files"); err != nil {
+			return nil, err
+		}
+	}
+
+	ops := b.Operations
+	done := renamedUnrecorded(ops)
+
+	var pending []int
+	mappings := make(map[string]string)
+	episodes := make(map[string]int)
+	for i, op := range ops {
+		if op.Status != types.StatusPending {
+			continue
+		}
+		if done[i] {
+			ops[i].Status = types.StatusSuccess
+			r.emit(types.Event{Type: types.EventInfo, Message: fmt.Sprintf("Already renamed: %s", filepath.Base(op.TargetPath))})
+			continue
+		}
+		pending = append(pending, i)
+
+		name := filepath.Base(op.SourcePath)
+		mappings[name] = filepath.Base(op.TargetPath)
+		if op.Episode != nil {
+			episodes[name] = op.Episode.Number
+		}
+		if r.DryRun {
+			r.emit(types.Event{Type: types.EventInfo, Message: fmt.Sprintf("[DRY-RUN] %s → %s", name, mappings[name])})
+		}
+	}
+	if r.DryRun {
+		return ops, nil
+	}
+
+	if err := r.performBackupExtend(ctx, b.Dir, mappings, episodes); err != nil {
+		return nil, err
+	}
+
+	rest := make([]types.RenameOperation, len(pending))
+	for i, idx := range pending {
+		rest[i] = ops[idx]
+	}
+	interrupted := r.performRenames(ctx, rest)
+	for i, idx := range pending {
+		ops[idx] = rest[i]
+	}
+
+	if interrupted != nil {
+		r.trimBackup(ctx, b.Dir, ops)
+	}
+	r.recordHistory(b.Dir, &types.Media{Provider: b.Provider, ID: b.ID, Title: b.Series}, rest)
+	r.finishBatch(b, ops, interrupted != nil)
+
+	if interrupted != nil {
+		return ops, *interrupted
+	}
+	return ops, nil
+}
+
+// renamedUnrecorded reports which pending operations a killed batch did
+// without recording them: their source is gone and their target is there.
+// In a chain (b→c, then a→b) the source of b→c is back once a→b ran, so an
+// operation also counts as done if a later one that renamed onto its source
+// did: operations run in order.
+func renamedUnrecorded(ops []types.RenameOperation) []bool {
+	done := make([]bool, len(ops))
+	for i := len(ops) - 1; i >= 0; i-- {
+		op := ops[i]
+		if op.Status != types.StatusPending || !exists(op.TargetPath) {
+			continue
+		}
+		done[i] = !exists(op.SourcePath)
+		for j := i + 1; j < len(ops) && !done[i]; j++ {
+			refilled := done[j] || ops[j].Status == types.StatusSuccess
+			done[i] = refilled && ops[j].TargetPath == op.SourcePath
+		}
+	}
+	return done
+}
+
+// performBackupExtend backs up the files a resumed batch is about to rename
+func (r *Renamer) performBackupExtend(ctx context.Context, dir string, mappings map[string]string, episodes map[string]int) error {
+	if r.NoBackup || !r.BackupConfig.Enabled || len(mappings) == 0 {
+		return nil
+	}
+	r.emit(types.Event{Type: types.EventInfo, Message: "Creating backup..."})
+	if err := r.BackupManager.Extend(ctx, dir, mappings, episodes); err != nil {
+		return fmt.Errorf("backup failed: %w", err)
+	}
+	return nil
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
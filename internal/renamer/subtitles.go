@@ -0,0 +1,95 @@
+package renamer
+
+import (
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/mydehq/autotitle/internal/util"
+)
+
+// subtitleExts are the sidecar subtitle formats renamed alongside videos
+var subtitleExts = []string{"srt", "ass", "ssa", "vtt", "sub", "idx", "sup"}
+
+// reSubtitleTag splits the part between a video's name and a subtitle's
+// extension into tags: "[English]" or dot/space/underscore separated words
+var reSubtitleTag = regexp.MustCompile(`\[([^\]]+)\]|[^.\s_\[\]]+`)
+
+// isSubtitle reports whether name has a subtitle extension
+func isSubtitle(name string) bool {
+	ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(name)), ".")
+	return slices.Contains(subtitleExts, ext)
+}
+
+// findSubtitles returns the subtitles in names belonging to the video: those
+// named after it, optionally with tags (e.g. "Ep 01.eng.srt", "Ep 01 [English].ass")
+func (r *Renamer) findSubtitles(names []string, video string) []string {
+	baseLen := len(strings.TrimSuffix(video, filepath.Ext(video)))
+	var subs []string
+	for _, name := range names {
+		if !isSubtitle(name) || !belongsTo(name, video) {
+			continue
+		}
+		// "Ep 1 v2.srt" belongs to "Ep 1 v2.mkv", not "Ep 1.mkv"
+		claimed := slices.ContainsFunc(names, func(other string) bool {
+			return len(strings.TrimSuffix(other, filepath.Ext(other))) > baseLen && r.isVideoFile(filepath.Ext(other)) && belongsTo(name, other)
+		})
+		if !claimed {
+			subs = append(subs, name)
+		}
+	}
+	return subs
+}
+
+// Subtitles returns the subtitles in names belonging to video, as a rename
+// finds them; formats are the video extensions
+func Subtitles(names []string, video string, formats []string) []string {
+	return (&Renamer{Formats: formats}).findSubtitles(names, video)
+}
+
+// belongsTo reports whether sub is named after video
+func belongsTo(sub, video string) bool {
+	base := strings.TrimSuffix(video, filepath.Ext(video))
+	if sub == video || !strings.HasPrefix(sub, base) {
+		return false
+	}
+	middle := strings.TrimSuffix(sub[len(base):], filepath.Ext(sub))
+	// "Ep 1.mkv" must not claim "Ep 10.srt"
+	return middle == "" || strings.ContainsRune(". _[-", rune(middle[0]))
+}
+
+// subtitleName returns the subtitle's name after its video is renamed to
+// newVideo. Language tags become ISO-639 codes; other tags (forced, sdh) are kept.
+func (r *Renamer) subtitleName(sub, video, newVideo string) string {
+	base := strings.TrimSuffix(video, filepath.Ext(video))
+	ext := filepath.Ext(sub)
+	middle := strings.TrimSuffix(sub[len(base):], ext)
+
+	var b strings.Builder
+	b.WriteString(strings.TrimSuffix(newVideo, filepath.Ext(newVideo)))
+	for _, m := range reSubtitleTag.FindAllStringSubmatch(middle, -1) {
+		tag := m[0]
+		if m[1] != "" {
+			tag = strings.TrimSpace(m[1])
+		}
+		b.WriteString(".")
+		b.WriteString(r.languageCode(tag))
+	}
+	b.WriteString(ext)
+	return b.String()
+}
+
+// languageCode normalizes a language tag to the configured code style,
+// returning other tags unchanged
+func (r *Renamer) languageCode(tag string) string {
+	for k, code := range r.Subtitles.Languages {
+		if strings.EqualFold(k, tag) {
+			return code
+		}
+	}
+	if l, ok := util.LookupLanguage(tag); ok {
+		return l.Code(r.Subtitles.Codes)
+	}
+	return tag
+}
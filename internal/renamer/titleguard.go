@@ -0,0 +1,52 @@
+package renamer
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/mydehq/autotitle/internal/matcher"
+	"github.com/mydehq/autotitle/internal/types"
+)
+
+// SeriesTitle returns the series name most video files in dir are named
+// with, or "" when their names carry none (e.g. "05.mkv")
+func (r *Renamer) SeriesTitle(dir string) (string, error) {
+	entries, err := r.Ignorer.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	counts := make(map[string]int)
+	names := make(map[string]string) // Key -> first name seen
+	best := ""
+	for _, e := range entries {
+		if e.IsDir() || !r.isVideoFile(filepath.Ext(e.Name())) {
+			continue
+		}
+		name := matcher.SeriesName(e.Name())
+		key := matcher.NormalizeTitle(name)
+		if key == "" {
+			continue
+		}
+		if _, ok := names[key]; !ok {
+			names[key] = name
+		}
+		counts[key]++
+		if best == "" || counts[key] > counts[best] {
+			best = key
+		}
+	}
+	return names[best], nil
+}
+
+// TitleSimilarity scores how similar a series name is to the closest title
+// of media (main, English, Japanese or an alias), from 0 to 1
+func TitleSimilarity(name string, media *types.Media) float64 {
+	best := 0.0
+	for _, title := range append([]string{media.Title, media.TitleEN, media.TitleJP}, media.Aliases...) {
+		if title != "" {
+			best = max(best, matcher.Similarity(name, title))
+		}
+	}
+	return best
+}
@@ -0,0 +1,172 @@
+// Package report builds the per-run report written by --report: the plan and
+// its results, every event, and what the run depended on (versions, config
+// hashes, external tools), for bug reports and auditing.
+package report
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/mydehq/autotitle/internal/types"
+	"github.com/mydehq/autotitle/internal/version"
+)
+
+// tools are the external programs a run may use; the report notes which exist
+var tools = []string{"mkvpropedit", "AtomicParsley", "ffmpeg", "ffprobe", "ebook-meta"}
+
+// Report is one run, as written to the report file
+type Report struct {
+	Command     []string                `json:"command"`
+	Path        string                  `json:"path"`
+	DryRun      bool                    `json:"dry_run"`
+	Started     time.Time               `json:"started"`
+	Finished    time.Time               `json:"finished"`
+	DurationMS  int64                   `json:"duration_ms"`
+	Error       string                  `json:"error,omitempty"`
+	Summary     Summary                 `json:"summary"`
+	Environment Environment             `json:"environment"`
+	Config      []ConfigFile            `json:"config"`
+	Operations  []types.RenameOperation `json:"operations"` // Plan and results, skips included
+	Events      []Event                 `json:"events"`
+}
+
+// Summary counts the operations by status
+type Summary struct {
+	Renamed int `json:"renamed"`
+	Pending int `json:"pending"` // Planned but not renamed: dry-run or interrupted
+	Skipped int `json:"skipped"`
+	Failed  int `json:"failed"`
+}
+
+// Environment is what the run ran on
+type Environment struct {
+	Version   string            `json:"version"`
+	Commit    string            `json:"commit"`
+	Built     string            `json:"built"`
+	GoVersion string            `json:"go_version"`
+	OS        string            `json:"os"`
+	Arch      string            `json:"arch"`
+	Tools     map[string]string `json:"tools"` // Path in $PATH, or "" if missing
+}
+
+// ConfigFile identifies a config file by its hash, so two reports show
+// whether the config changed between runs without including it
+type ConfigFile struct {
+	Kind   string `json:"kind"` // global or map
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Event is an event of the run with the time it was emitted
+type Event struct {
+	Time    time.Time       `json:"time"`
+	Type    types.EventType `json:"type"`
+	Message string          `json:"message"`
+}
+
+// New starts the report of a run on path
+func New(path string, dryRun bool, now time.Time) *Report {
+	return &Report{
+		Command: os.Args,
+		Path:    path,
+		DryRun:  dryRun,
+		Started: now,
+		Events:  []Event{},
+	}
+}
+
+// Record adds an event. Progress events only move a progress bar, so they
+// are left out.
+func (r *Report) Record(e types.Event, now time.Time) {
+	if e.Type == types.EventProgress {
+		return
+	}
+	r.Events = append(r.Events, Event{Time: now, Type: e.Type, Message: e.Message})
+}
+
+// AddConfig records the hash of a config file; a missing file is noted as such
+func (r *Report) AddConfig(kind, path string) {
+	if path == "" {
+		return
+	}
+	f := ConfigFile{Kind: kind, Path: path}
+	if data, err := os.ReadFile(path); err != nil {
+		f.Error = err.Error()
+	} else {
+		sum := sha256.Sum256(data)
+		f.SHA256 = hex.EncodeToString(sum[:])
+	}
+	r.Config = append(r.Config, f)
+}
+
+// Finish records the operations and outcome of the run
+func (r *Report) Finish(ops []types.RenameOperation, err error, now time.Time) {
+	r.Finished = now
+	r.DurationMS = now.Sub(r.Started).Milliseconds()
+	if err != nil {
+		r.Error = err.Error()
+	}
+	r.Operations = ops
+	if r.Operations == nil {
+		r.Operations = []types.RenameOperation{}
+	}
+	r.Summary = Summary{}
+	for _, op := range ops {
+		switch op.Status {
+		case types.StatusSuccess:
+			r.Summary.Renamed++
+		case types.StatusPending:
+			r.Summary.Pending++
+		case types.StatusSkipped:
+			r.Summary.Skipped++
+		case types.StatusFailed:
+			r.Summary.Failed++
+		}
+	}
+
+	r.Environment = CurrentEnvironment()
+}
+
+// CurrentEnvironment describes this build and the tools found in $PATH
+func CurrentEnvironment() Environment {
+	env := Environment{
+		Version:   version.Get(),
+		Commit:    version.Commit,
+		Built:     version.Date,
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		Tools:     make(map[string]string, len(tools)),
+	}
+	for _, t := range tools {
+		path, _ := exec.LookPath(t)
+		env.Tools[t] = path
+	}
+	return env
+}
+
+// Write saves the report to path as indented JSON. It is written even in
+// read-only mode: the user asked for this one file.
+func (r *Report) Write(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create report dir: %w", err)
+		}
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
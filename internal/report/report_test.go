@@ -0,0 +1,68 @@
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mydehq/autotitle/internal/types"
+)
+
+func TestReport(t *testing.T) {
+	dir := t.TempDir()
+	cfg := filepath.Join(dir, "_autotitle.yml")
+	if err := os.WriteFile(cfg, []byte("targets: []\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	r := New(dir, true, start)
+	r.AddConfig("map", cfg)
+	r.AddConfig("global", filepath.Join(dir, "missing.yml"))
+	r.AddConfig("global", "")
+	r.Record(types.Event{Type: types.EventProgress, Message: "1/2"}, start)
+	r.Record(types.Event{Type: types.EventInfo, Message: "Planning"}, start)
+
+	ops := []types.RenameOperation{
+		{SourcePath: "a.mkv", TargetPath: "A.mkv", Status: types.StatusSuccess},
+		{SourcePath: "b.mkv", TargetPath: "B.mkv", Status: types.StatusPending},
+		{SourcePath: "c.mkv", Status: types.StatusSkipped, Reason: types.ReasonCollision},
+		{SourcePath: "d.mkv", Status: types.StatusFailed},
+	}
+	r.Finish(ops, errors.New("boom"), start.Add(1500*time.Millisecond))
+
+	if r.DurationMS != 1500 {
+		t.Errorf("DurationMS = %d, want 1500", r.DurationMS)
+	}
+	if want := (Summary{Renamed: 1, Pending: 1, Skipped: 1, Failed: 1}); r.Summary != want {
+		t.Errorf("Summary = %+v, want %+v", r.Summary, want)
+	}
+	if len(r.Events) != 1 || r.Events[0].Message != "Planning" {
+		t.Errorf("Events = %+v, want only the non-progress event", r.Events)
+	}
+	if len(r.Config) != 2 || r.Config[0].SHA256 == "" || r.Config[1].Error == "" {
+		t.Errorf("Config = %+v, want a hash for the map file and an error for the missing one", r.Config)
+	}
+	if _, ok := r.Environment.Tools["mkvpropedit"]; !ok {
+		t.Error("Environment.Tools does not list mkvpropedit")
+	}
+
+	out := filepath.Join(dir, "reports", "run.json")
+	if err := r.Write(out); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Report
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("report is not valid JSON: %v", err)
+	}
+	if got.Error != "boom" || !got.DryRun || len(got.Operations) != 4 || got.Summary != r.Summary {
+		t.Errorf("round trip = %+v", got)
+	}
+}
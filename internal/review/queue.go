@@ -0,0 +1,111 @@
+// Package review persists sort groups parked for manual review.
+package review
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/mydehq/autotitle/internal/types"
+	"github.com/mydehq/autotitle/internal/util"
+)
+
+const FileName = "review_queue.json"
+
+// Queue stores parked items in ~/.cache/autotitle/review_queue.json
+type Queue struct {
+	path string
+}
+
+// New creates a Queue in cacheRoot
+func New(cacheRoot string) *Queue {
+	return &Queue{path: filepath.Join(cacheRoot, FileName)}
+}
+
+// ItemID returns the stable ID of the group name parked from dir, so sorting
+// the same dump again updates its item instead of adding another
+func ItemID(dir, name string) string {
+	sum := sha256.Sum256([]byte(dir + "\x00" + name))
+	return hex.EncodeToString(sum[:4])
+}
+
+// List returns all parked items, oldest first
+func (q *Queue) List() ([]types.ReviewItem, error) {
+	data, err := os.ReadFile(q.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read review queue: %w", err)
+	}
+
+	var items []types.ReviewItem
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("failed to parse review queue: %w", err)
+	}
+	return items, nil
+}
+
+// Get returns the item with id, or nil
+func (q *Queue) Get(id string) (*types.ReviewItem, error) {
+	items, err := q.List()
+	if err != nil {
+		return nil, err
+	}
+	for i := range items {
+		if items[i].ID == id {
+			return &items[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// Put adds item, replacing any item with the same ID
+func (q *Queue) Put(item types.ReviewItem) error {
+	items, err := q.List()
+	if err != nil {
+		return err
+	}
+	if i := slices.IndexFunc(items, func(it types.ReviewItem) bool { return it.ID == item.ID }); i >= 0 {
+		items[i] = item
+	} else {
+		items = append(items, item)
+	}
+	return q.save(items)
+}
+
+// Remove deletes the item with id; removing a missing item is not an error
+func (q *Queue) Remove(id string) error {
+	items, err := q.List()
+	if err != nil {
+		return err
+	}
+	kept := slices.DeleteFunc(items, func(it types.ReviewItem) bool { return it.ID == id })
+	return q.save(kept)
+}
+
+func (q *Queue) save(items []types.ReviewItem) error {
+	if err := util.CheckWritable("update the review queue"); err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		if err := os.Remove(q.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+
+	data, err := json.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(q.path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return os.WriteFile(q.path, data, 0644)
+}
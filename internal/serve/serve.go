@@ -0,0 +1,304 @@
+// Package serve exposes a running watch mode over HTTP: liveness and
+// readiness probes for orchestrators, and a status report for dashboards
+// and `autotitle status`. Everything but the probes takes a bearer token,
+// and the server refuses to listen beyond loopback without one.
+package serve
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mydehq/autotitle/internal/provider"
+	"github.com/mydehq/autotitle/internal/types"
+)
+
+// TokenEnv overrides serve.token, so the token need not be in a file
+const TokenEnv = "AUTOTITLE_TOKEN"
+
+// Options secures the server and its clients; the zero value is plain HTTP
+// open to every client, which ListenAndServe only allows on loopback
+type Options struct {
+	Token    string   // Bearer token for every endpoint but the probes
+	CertFile string   // TLS certificate; clients trust it besides the system roots
+	KeyFile  string   // TLS key
+	Allow    []string // IPs and CIDRs clients may connect from; none allows all
+}
+
+// FromConfig returns the options of serve in the global config, with the
+// token of $AUTOTITLE_TOKEN if set
+func FromConfig(cfg types.ServeConfig) Options {
+	o := Options{Token: cfg.Token, CertFile: cfg.TLSCert, KeyFile: cfg.TLSKey, Allow: cfg.Allow}
+	if token := os.Getenv(TokenEnv); token != "" {
+		o.Token = token
+	}
+	return o
+}
+
+// TLS reports whether the server speaks HTTPS
+func (o Options) TLS() bool {
+	return o.CertFile != ""
+}
+
+// Daemon states
+const (
+	StateStarting = "starting" // Setting up watches
+	StateWatching = "watching" // Idle, waiting for changes
+	StateRenaming = "renaming" // Renaming a folder
+	StateStopped  = "stopped"
+)
+
+// Status is what /status reports
+type Status struct {
+	State       string            `json:"state"`
+	Started     time.Time         `json:"started"`
+	Roots       []string          `json:"roots"`
+	Reloaded    time.Time         `json:"reloaded,omitzero"`      // Last reload of the global config
+	ReloadError string            `json:"reload_error,omitempty"` // Why the last reload failed; the config before it is in use
+	Runs        []Run             `json:"runs,omitempty"`         // Latest first
+	Providers   []provider.Health `json:"providers,omitempty"`
+}
+
+// Run is the result of renaming one folder
+type Run struct {
+	Dir     string    `json:"dir"`
+	At      time.Time `json:"at"`
+	Renamed int       `json:"renamed"`
+	Skipped int       `json:"skipped"`
+	Failed  int       `json:"failed"`
+	Error   string    `json:"error,omitempty"` // Why the folder was not renamed
+}
+
+// Ready reports whether the daemon is watching
+func (s Status) Ready() bool {
+	return s.State == StateWatching || s.State == StateRenaming
+}
+
+// Daemon is what the server reports on
+type Daemon interface {
+	// Status returns the current status, with the provider checks if
+	// providers is set
+	Status(ctx context.Context, providers bool) Status
+}
+
+// Handler serves the endpoints:
+//
+//	GET /healthz  200 while the daemon runs, 503 once it stopped
+//	GET /readyz   200 once it is watching, 503 before
+//	GET /status   Status as JSON; ?providers=false skips the provider checks
+//
+// A client outside o.Allow gets 403 from all of them, and one without the
+// token 401 from all but the probes. It fails on an invalid allow entry.
+func Handler(d Daemon, o Options) (http.Handler, error) {
+	allow, err := parseAllow(o.Allow)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		s := d.Status(r.Context(), false)
+		probe(w, s.State != StateStopped, s.State)
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		s := d.Status(r.Context(), false)
+		probe(w, s.Ready(), s.State)
+	})
+	mux.HandleFunc("GET /status", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, d.Status(r.Context(), r.URL.Query().Get("providers") != "false"))
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !allowed(allow, r.RemoteAddr) {
+			writeError(w, http.StatusForbidden, "client not in serve.allow")
+			return
+		}
+		if o.Token != "" && r.URL.Path != "/healthz" && r.URL.Path != "/readyz" && !authorized(r, o.Token) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="autotitle"`)
+			writeError(w, http.StatusUnauthorized, "missing or wrong token")
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}), nil
+}
+
+// parseAllow parses IPs and CIDRs; an IP allows just itself
+func parseAllow(entries []string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, e := range entries {
+		if p, err := netip.ParsePrefix(e); err == nil {
+			prefixes = append(prefixes, p.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(e)
+		if err != nil {
+			return nil, fmt.Errorf("invalid serve.allow entry %q: want an IP or CIDR", e)
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+	}
+	return prefixes, nil
+}
+
+// allowed reports whether a client at remote may connect; no prefixes
+// allow all
+func allowed(prefixes []netip.Prefix, remote string) bool {
+	if len(prefixes) == 0 {
+		return true
+	}
+	ap, err := netip.ParseAddrPort(remote)
+	if err != nil {
+		return false
+	}
+	addr := ap.Addr().Unmap()
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// authorized reports whether r carries the bearer token
+func authorized(r *http.Request, token string) bool {
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
+// probe answers a health probe
+func probe(w http.ResponseWriter, ok bool, state string) {
+	code := http.StatusOK
+	if !ok {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, map[string]string{"state": state})
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]string{"error": msg})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// ListenAndServe serves h on addr, over TLS if o has a certificate, until
+// ctx is cancelled, then shuts down gracefully. It returns once the
+// listener is closed. Without a token it only listens on loopback.
+func ListenAndServe(ctx context.Context, addr string, h http.Handler, o Options) error {
+	ln, err := Listen(addr, o)
+	if err != nil {
+		return err
+	}
+	return Serve(ctx, ln, h, o)
+}
+
+// Listen checks o and binds addr, so a caller can report an address in use
+// (or a refused one) before it starts serving. Without a token it only
+// listens on loopback.
+func Listen(addr string, o Options) (net.Listener, error) {
+	if o.Token == "" && !loopback(addr) {
+		return nil, fmt.Errorf("refusing to serve on %s without a token: set serve.token or $%s, or listen on 127.0.0.1", addr, TokenEnv)
+	}
+	if (o.CertFile == "") != (o.KeyFile == "") {
+		return nil, errors.New("serve.tls_cert and serve.tls_key go together")
+	}
+	return net.Listen("tcp", addr)
+}
+
+// Serve serves h on ln like ListenAndServe, and closes ln when it returns
+func Serve(ctx context.Context, ln net.Listener, h http.Handler, o Options) error {
+	srv := &http.Server{Handler: h, ReadHeaderTimeout: 10 * time.Second}
+	done := make(chan error, 1)
+	go func() {
+		if o.TLS() {
+			done <- srv.ServeTLS(ln, o.CertFile, o.KeyFile)
+		} else {
+			done <- srv.Serve(ln)
+		}
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+	shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdown); err != nil {
+		return err
+	}
+	if err := <-done; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// loopback reports whether addr only takes connections from this machine
+func loopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip, err := netip.ParseAddr(host)
+	return err == nil && ip.IsLoopback()
+}
+
+// Fetch asks the daemon serving on addr for its status, with the token and
+// over TLS as o says
+func Fetch(ctx context.Context, addr string, providers bool, o Options) (*Status, error) {
+	client, scheme := http.DefaultClient, "http"
+	if o.TLS() {
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+		pem, err := os.ReadFile(o.CertFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read serve.tls_cert: %w", err)
+		}
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate in %s", o.CertFile)
+		}
+		client = &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+		scheme = "https"
+	}
+
+	url := scheme + "://" + addr + "/status"
+	if !providers {
+		url += "?providers=false"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if o.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+o.Token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	var s Status
+	if err := json.NewDecoder(resp.Body).Decode(&s); err != nil {
+		return nil, fmt.Errorf("invalid status from %s: %w", url, err)
+	}
+	return &s, nil
+}
@@ -0,0 +1,205 @@
+package serve
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mydehq/autotitle/internal/provider"
+)
+
+// fakeDaemon reports a fixed status
+type fakeDaemon struct {
+	status    Status
+	providers bool // Whether the last call asked for the provider checks
+}
+
+func (d *fakeDaemon) Status(ctx context.Context, providers bool) Status {
+	d.providers = providers
+	s := d.status
+	if !providers {
+		s.Providers = nil
+	}
+	return s
+}
+
+func TestHandler(t *testing.T) {
+	d := &fakeDaemon{status: Status{
+		State:     StateStarting,
+		Roots:     []string{"/anime"},
+		Runs:      []Run{{Dir: "/anime/Show", At: time.Now(), Renamed: 2}},
+		Providers: []provider.Health{{Name: "mal", Kind: "provider", Checked: true}},
+	}}
+	h, err := Handler(d, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+
+	get := func(path string) int {
+		t.Helper()
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	tests := []struct {
+		state           string
+		healthz, readyz int
+	}{
+		{StateStarting, http.StatusOK, http.StatusServiceUnavailable},
+		{StateWatching, http.StatusOK, http.StatusOK},
+		{StateRenaming, http.StatusOK, http.StatusOK},
+		{StateStopped, http.StatusServiceUnavailable, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		d.status.State = tt.state
+		if got := get("/healthz"); got != tt.healthz {
+			t.Errorf("%s: /healthz = %d, want %d", tt.state, got, tt.healthz)
+		}
+		if got := get("/readyz"); got != tt.readyz {
+			t.Errorf("%s: /readyz = %d, want %d", tt.state, got, tt.readyz)
+		}
+	}
+
+	if got := get("/rename"); got != http.StatusNotFound {
+		t.Errorf("/rename = %d, want 404", got)
+	}
+
+	addr := strings.TrimPrefix(srv.URL, "http://")
+	s, err := Fetch(context.Background(), addr, false, Options{})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if d.providers || s.Providers != nil {
+		t.Errorf("Fetch without providers checked them: %+v", s.Providers)
+	}
+	if len(s.Roots) != 1 || len(s.Runs) != 1 || s.Runs[0].Renamed != 2 {
+		t.Errorf("status = %+v", s)
+	}
+	if s, err = Fetch(context.Background(), addr, true, Options{}); err != nil || len(s.Providers) != 1 {
+		t.Errorf("Fetch with providers = %+v, %v", s, err)
+	}
+}
+
+func TestHandler_Auth(t *testing.T) {
+	d := &fakeDaemon{status: Status{State: StateWatching}}
+	tests := []struct {
+		name            string
+		options         Options
+		token           string
+		healthz, status int
+	}{
+		{"no token configured", Options{}, "", 200, 200},
+		{"token missing", Options{Token: "s3cret"}, "", 200, 401},
+		{"token wrong", Options{Token: "s3cret"}, "guess", 200, 401},
+		{"token right", Options{Token: "s3cret"}, "s3cret", 200, 200},
+		{"allowed", Options{Allow: []string{"10.0.0.0/8", "127.0.0.1"}}, "", 200, 200},
+		{"not allowed", Options{Allow: []string{"10.0.0.0/8", "::1"}}, "", 403, 403},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := Handler(d, tt.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			srv := httptest.NewServer(h)
+			defer srv.Close()
+			for path, want := range map[string]int{"/healthz": tt.healthz, "/status": tt.status} {
+				req, _ := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+				if tt.token != "" {
+					req.Header.Set("Authorization", "Bearer "+tt.token)
+				}
+				resp, err := http.DefaultClient.Do(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				_ = resp.Body.Close()
+				if resp.StatusCode != want {
+					t.Errorf("%s = %d, want %d", path, resp.StatusCode, want)
+				}
+			}
+		})
+	}
+
+	if _, err := Handler(d, Options{Allow: []string{"10.0.0.300"}}); err == nil {
+		t.Error("Handler accepted an invalid allow entry")
+	}
+}
+
+func TestFetch_TLS(t *testing.T) {
+	d := &fakeDaemon{status: Status{State: StateWatching}}
+	h, err := Handler(d, Options{Token: "s3cret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewTLSServer(h)
+	t.Cleanup(srv.Close)
+	cert := filepath.Join(t.TempDir(), "cert.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(cert, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	addr := strings.TrimPrefix(srv.URL, "https://")
+	if _, err := Fetch(context.Background(), addr, false, Options{Token: "s3cret"}); err == nil {
+		t.Error("Fetch over plain HTTP reached a TLS server")
+	}
+	if _, err := Fetch(context.Background(), addr, false, Options{CertFile: cert}); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Fetch without the token = %v, want 401", err)
+	}
+	s, err := Fetch(context.Background(), addr, false, Options{Token: "s3cret", CertFile: cert})
+	if err != nil || s.State != StateWatching {
+		t.Errorf("Fetch = %+v, %v", s, err)
+	}
+}
+
+func TestListenAndServe_RefusesOpenNonLoopback(t *testing.T) {
+	for _, addr := range []string{":0", "0.0.0.0:0", "[::]:0"} {
+		err := ListenAndServe(context.Background(), addr, http.NotFoundHandler(), Options{})
+		if err == nil || !strings.Contains(err.Error(), "without a token") {
+			t.Errorf("%s: ListenAndServe = %v, want a refusal", addr, err)
+		}
+	}
+	err := ListenAndServe(context.Background(), "127.0.0.1:0", http.NotFoundHandler(), Options{CertFile: "cert.pem"})
+	if err == nil || !strings.Contains(err.Error(), "tls_key") {
+		t.Errorf("ListenAndServe without a key = %v, want an error", err)
+	}
+}
+
+func TestListen_AddressInUse(t *testing.T) {
+	ln, err := Listen("127.0.0.1:0", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	if other, err := Listen(ln.Addr().String(), Options{}); err == nil {
+		other.Close()
+		t.Errorf("Listen on %s in use succeeded", ln.Addr())
+	}
+}
+
+func TestListenAndServe_StopsWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- ListenAndServe(ctx, "127.0.0.1:0", http.NotFoundHandler(), Options{}) }()
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("ListenAndServe = %v, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ListenAndServe did not stop")
+	}
+}
@@ -0,0 +1,360 @@
+// Package sinks sends events to destinations configured in the global config
+// (stderr, a JSON lines file, syslog, journald or a webhook) next to the main
+// handler.
+package sinks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mydehq/autotitle/internal/types"
+	"github.com/mydehq/autotitle/internal/util"
+)
+
+// DefaultTag identifies autotitle in syslog and the journal
+const DefaultTag = "autotitle"
+
+// Socket paths of the local syslog daemon and the systemd journal
+var (
+	SyslogSocket   = "/dev/log"
+	JournaldSocket = "/run/systemd/journal/socket"
+)
+
+// severity orders event types from least to most severe
+var severity = map[types.EventType]int{
+	types.EventProgress: 0,
+	types.EventInfo:     1,
+	types.EventSuccess:  2,
+	types.EventWarning:  3,
+	types.EventError:    4,
+}
+
+// Sink is one destination for events
+type Sink interface {
+	Write(e types.Event, at time.Time) error
+	Close() error
+}
+
+// Set is the sinks opened from the config, each with its level filter
+type Set struct {
+	mu     sync.Mutex
+	sinks  []Sink
+	levels []int
+	Clock  types.Clock // Source of event timestamps
+}
+
+// Open opens every configured sink. A sink that fails to open is skipped and
+// reported in the returned error, so the others still work.
+func Open(cfgs []types.EventSink) (*Set, error) {
+	s := &Set{Clock: types.SystemClock{}}
+	var errs []error
+	for i, cfg := range cfgs {
+		level, err := parseLevel(cfg.Level)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("sink %d: %w", i, err))
+			continue
+		}
+		sink, err := open(cfg)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("sink %d (%s): %w", i, cfg.Type, err))
+			continue
+		}
+		s.sinks = append(s.sinks, sink)
+		s.levels = append(s.levels, level)
+	}
+	return s, errors.Join(errs...)
+}
+
+// parseLevel returns the severity of a level name; empty means info
+func parseLevel(level string) (int, error) {
+	if level == "" {
+		return severity[types.EventInfo], nil
+	}
+	if n, ok := severity[types.EventType(level)]; ok {
+		return n, nil
+	}
+	return 0, fmt.Errorf("unknown level %q (use progress, info, success, warning or error)", level)
+}
+
+func open(cfg types.EventSink) (Sink, error) {
+	tag := cfg.Tag
+	if tag == "" {
+		tag = DefaultTag
+	}
+	switch cfg.Type {
+	case types.SinkStderr:
+		return &lineSink{w: os.Stderr}, nil
+	case types.SinkJSON:
+		return openJSON(cfg.Path)
+	case types.SinkSyslog:
+		conn, err := net.Dial("unixgram", SyslogSocket)
+		if err != nil {
+			return nil, err
+		}
+		return &syslogSink{conn: conn, tag: tag}, nil
+	case types.SinkJournald:
+		conn, err := net.Dial("unixgram", JournaldSocket)
+		if err != nil {
+			return nil, err
+		}
+		return &journaldSink{conn: conn, tag: tag}, nil
+	case types.SinkWebhook:
+		return openWebhook(cfg.URL, cfg.Secret)
+	default:
+		return nil, fmt.Errorf("unknown sink type %q (use stderr, json, syslog, journald or webhook)", cfg.Type)
+	}
+}
+
+// Len returns the number of open sinks
+func (s *Set) Len() int {
+	return len(s.sinks)
+}
+
+// Handler returns an event handler that sends events to the sinks before
+// passing them on to next (if not nil). Sink failures never stop the run.
+func (s *Set) Handler(next types.EventHandler) types.EventHandler {
+	return func(e types.Event) {
+		s.Send(e)
+		if next != nil {
+			next(e)
+		}
+	}
+}
+
+// Send writes e to every sink whose level it reaches
+func (s *Set) Send(e types.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.sinks) == 0 {
+		return
+	}
+	at := s.Clock.Now()
+	for i, sink := range s.sinks {
+		if severity[e.Type] >= s.levels[i] {
+			_ = sink.Write(e, at)
+		}
+	}
+}
+
+// Close closes every sink
+func (s *Set) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var errs []error
+	for _, sink := range s.sinks {
+		errs = append(errs, sink.Close())
+	}
+	s.sinks, s.levels = nil, nil
+	return errors.Join(errs...)
+}
+
+// lineSink writes plain "time LEVEL message" lines
+type lineSink struct {
+	w io.Writer
+}
+
+func (l *lineSink) Write(e types.Event, at time.Time) error {
+	_, err := fmt.Fprintf(l.w, "%s %-8s %s\n", at.Format(time.RFC3339), e.Type, e.Message)
+	return err
+}
+
+func (l *lineSink) Close() error { return nil }
+
+// jsonSink appends one JSON object per event to a file
+type jsonSink struct {
+	f *os.File
+}
+
+// jsonEvent is the line written for an event
+type jsonEvent struct {
+	Time    time.Time       `json:"time"`
+	Type    types.EventType `json:"type"`
+	Message string          `json:"message"`
+	Data    any             `json:"data,omitempty"`
+}
+
+func openJSON(path string) (Sink, error) {
+	if path == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+	if err := util.CheckWritable("write event logs"); err != nil {
+		return nil, err
+	}
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(home, rest)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &jsonSink{f: f}, nil
+}
+
+func (j *jsonSink) Write(e types.Event, at time.Time) error {
+	data, err := json.Marshal(jsonEvent{Time: at, Type: e.Type, Message: e.Message, Data: e.Data})
+	if err != nil {
+		return err
+	}
+	_, err = j.f.Write(append(data, '\n'))
+	return err
+}
+
+func (j *jsonSink) Close() error { return j.f.Close() }
+
+// priority maps an event type to a syslog severity
+func priority(t types.EventType) int {
+	switch t {
+	case types.EventError:
+		return 3 // err
+	case types.EventWarning:
+		return 4 // warning
+	case types.EventSuccess:
+		return 5 // notice
+	case types.EventProgress:
+		return 7 // debug
+	default:
+		return 6 // info
+	}
+}
+
+// facilityUser is the syslog "user" facility, shifted for the PRI field
+const facilityUser = 1 << 3
+
+// syslogSink writes RFC 3164 messages to the local syslog socket
+type syslogSink struct {
+	conn net.Conn
+	tag  string
+}
+
+func (s *syslogSink) Write(e types.Event, at time.Time) error {
+	_, err := fmt.Fprintf(s.conn, "<%d>%s %s[%d]: %s", facilityUser|priority(e.Type), at.Format(time.Stamp), s.tag, os.Getpid(), e.Message)
+	return err
+}
+
+func (s *syslogSink) Close() error { return s.conn.Close() }
+
+// journaldSink writes to the systemd journal using its native protocol
+type journaldSink struct {
+	conn net.Conn
+	tag  string
+}
+
+func (j *journaldSink) Write(e types.Event, at time.Time) error {
+	var b []byte
+	b = appendField(b, "MESSAGE", e.Message)
+	b = appendField(b, "PRIORITY", fmt.Sprint(priority(e.Type)))
+	b = appendField(b, "SYSLOG_IDENTIFIER", j.tag)
+	b = appendField(b, "AUTOTITLE_EVENT", string(e.Type))
+	_, err := j.conn.Write(b)
+	return err
+}
+
+func (j *journaldSink) Close() error { return j.conn.Close() }
+
+// appendField encodes one journal field. Values with newlines use the
+// binary form: name, newline, little-endian 64-bit length, value.
+func appendField(b []byte, name, value string) []byte {
+	b = append(b, name...)
+	if !strings.Contains(value, "\n") {
+		b = append(b, '=')
+		b = append(b, value...)
+		return append(b, '\n')
+	}
+	b = append(b, '\n')
+	n := uint64(len(value))
+	for i := 0; i < 8; i++ {
+		b = append(b, byte(n>>(8*i)))
+	}
+	b = append(b, value...)
+	return append(b, '\n')
+}
+
+// Webhook headers: the Unix time the body was sent, and its signature,
+// "sha256=" and the hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the
+// secret. A receiver recomputes it, and rejects old timestamps to stop
+// replays.
+const (
+	TimestampHeader = "X-Autotitle-Timestamp"
+	SignatureHeader = "X-Autotitle-Signature"
+)
+
+// webhookTimeout bounds one POST, which holds up the events behind it
+const webhookTimeout = 10 * time.Second
+
+// webhookSink POSTs each batch manifest as a signed JSON event; other
+// events are not sent
+type webhookSink struct {
+	url    string
+	secret []byte
+	client *http.Client
+}
+
+func openWebhook(rawURL, secret string) (Sink, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("url must be an http(s) URL, got %q", rawURL)
+	}
+	if secret == "" {
+		return nil, fmt.Errorf("secret is required to sign the manifests")
+	}
+	return &webhookSink{url: rawURL, secret: []byte(secret), client: &http.Client{Timeout: webhookTimeout}}, nil
+}
+
+func (w *webhookSink) Write(e types.Event, at time.Time) error {
+	if _, ok := e.Data.(types.BatchManifest); !ok {
+		return nil
+	}
+	body, err := json.Marshal(jsonEvent{Time: at, Type: e.Type, Message: e.Message, Data: e.Data})
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Sign(w.secret, timestamp, body))
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %s: %s", w.url, resp.Status)
+	}
+	return nil
+}
+
+func (w *webhookSink) Close() error { return nil }
+
+// Sign returns the SignatureHeader value of a webhook body
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
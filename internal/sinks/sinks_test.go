@@ -0,0 +1,170 @@
+package sinks
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mydehq/autotitle/internal/types"
+)
+
+var fixedTime = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func TestJSONSink_FiltersByLevel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "events.jsonl")
+	s, err := Open([]types.EventSink{{Type: types.SinkJSON, Path: path, Level: "warning"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Clock = types.NowFunc(func() time.Time { return fixedTime })
+
+	for _, e := range []types.Event{
+		{Type: types.EventProgress, Message: "copying"},
+		{Type: types.EventInfo, Message: "Scanning"},
+		{Type: types.EventWarning, Message: "Episode 13 not in database"},
+		{Type: types.EventError, Message: "Rename failed"},
+	} {
+		s.Send(e)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var got []jsonEvent
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e jsonEvent
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("Invalid JSON line %q: %v", scanner.Text(), err)
+		}
+		got = append(got, e)
+	}
+	if len(got) != 2 || got[0].Type != types.EventWarning || got[1].Type != types.EventError {
+		t.Fatalf("Expected the warning and the error, got %+v", got)
+	}
+	if !got[0].Time.Equal(fixedTime) {
+		t.Errorf("Expected time %v, got %v", fixedTime, got[0].Time)
+	}
+}
+
+func TestOpen_BadSinkLeavesOthers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	s, err := Open([]types.EventSink{
+		{Type: "carrier-pigeon"},
+		{Type: types.SinkJSON, Path: path, Level: "loud"},
+		{Type: types.SinkJSON, Path: path},
+	})
+	if err == nil || !strings.Contains(err.Error(), "carrier-pigeon") || !strings.Contains(err.Error(), "loud") {
+		t.Errorf("Expected errors for the unknown type and level, got %v", err)
+	}
+	if s.Len() != 1 {
+		t.Errorf("Expected 1 open sink, got %d", s.Len())
+	}
+	s.Close()
+}
+
+func TestJournaldSink(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "journal.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: sock, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unixgram sockets unavailable: %v", err)
+	}
+	defer conn.Close()
+
+	old := JournaldSocket
+	JournaldSocket = sock
+	t.Cleanup(func() { JournaldSocket = old })
+
+	s, err := Open([]types.EventSink{{Type: types.SinkJournald, Tag: "library"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.Send(types.Event{Type: types.EventError, Message: "two\nlines"})
+
+	buf := make([]byte, 4096)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "MESSAGE\n\x09\x00\x00\x00\x00\x00\x00\x00two\nlines\nPRIORITY=3\nSYSLOG_IDENTIFIER=library\nAUTOTITLE_EVENT=error\n"
+	if got := string(buf[:n]); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestWebhookSink_PostsSignedManifests(t *testing.T) {
+	type post struct {
+		header http.Header
+		body   []byte
+	}
+	posts := make(chan post, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		posts <- post{r.Header, body}
+	}))
+	defer srv.Close()
+
+	s, err := Open([]types.EventSink{{Type: types.SinkWebhook, URL: srv.URL, Secret: "s3cret"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.Clock = types.NowFunc(func() time.Time { return fixedTime })
+
+	manifest := types.BatchManifest{
+		Directory: "/anime/Show",
+		Started:   fixedTime,
+		Finished:  fixedTime,
+		Mappings:  []types.ManifestMapping{{From: "a.mkv", To: "b.mkv", FromSHA256: "x", ToSHA256: "y"}},
+	}
+	s.Send(types.Event{Type: types.EventWarning, Message: "Episode 13 not in database"})
+	s.Send(types.Event{Type: types.EventInfo, Message: "Renamed 1 file(s)", Data: manifest})
+
+	p := <-posts
+	if len(posts) != 0 {
+		t.Errorf("Expected only the manifest to be posted, got %d more", len(posts))
+	}
+	timestamp := p.header.Get(TimestampHeader)
+	if timestamp != "1709294400" {
+		t.Errorf("Expected timestamp 1709294400, got %q", timestamp)
+	}
+	if got, want := p.header.Get(SignatureHeader), Sign([]byte("s3cret"), timestamp, p.body); got != want {
+		t.Errorf("Expected signature %q, got %q", want, got)
+	}
+	var got struct {
+		Data types.BatchManifest `json:"data"`
+	}
+	if err := json.Unmarshal(p.body, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Data.Directory != manifest.Directory || len(got.Data.Mappings) != 1 || got.Data.Mappings[0] != manifest.Mappings[0] {
+		t.Errorf("Expected the manifest, got %+v", got.Data)
+	}
+}
+
+func TestWebhookSink_NeedsURLAndSecret(t *testing.T) {
+	for _, cfg := range []types.EventSink{
+		{Type: types.SinkWebhook, Secret: "s3cret"},
+		{Type: types.SinkWebhook, URL: "ftp://example.com", Secret: "s3cret"},
+		{Type: types.SinkWebhook, URL: "https://example.com/hook"},
+	} {
+		if _, err := Open([]types.EventSink{cfg}); err == nil {
+			t.Errorf("Expected an error for %+v", cfg)
+		}
+	}
+}
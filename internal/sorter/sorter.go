@@ -0,0 +1,103 @@
+// Package sorter groups loose media files by series for `autotitle sort`.
+package sorter
+
+import (
+	"cmp"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/mydehq/autotitle/internal/matcher"
+	"github.com/mydehq/autotitle/internal/types"
+)
+
+var (
+	reSpaces = regexp.MustCompile(`\s+`)
+	reUnsafe = regexp.MustCompile(`[/\\:*?"<>|]`)
+	reTags   = regexp.MustCompile(`\[[^\]]*\]|\([^)]*\)`)
+)
+
+// Key normalizes a series name for grouping and comparison
+func Key(name string) string {
+	return matcher.NormalizeTitle(name)
+}
+
+// Group groups files by series name. Files whose name yields no series are
+// grouped under their own name.
+func Group(files []string) []types.SortGroup {
+	var groups []types.SortGroup
+	index := make(map[string]int)
+
+	for _, file := range files {
+		name := matcher.SeriesName(file)
+		if name == "" {
+			name = strings.TrimSuffix(file, filepath.Ext(file))
+		}
+		key := Key(name)
+
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, types.SortGroup{Name: name})
+		}
+		g := &groups[i]
+		g.Files = append(g.Files, file)
+		if p := matcher.GuessPattern(file); !slices.Contains(g.Patterns, p) {
+			g.Patterns = append(g.Patterns, p)
+		}
+	}
+
+	slices.SortFunc(groups, func(a, b types.SortGroup) int {
+		return cmp.Compare(Key(a.Name), Key(b.Name))
+	})
+	return groups
+}
+
+// Confidence scores how similar a provider title is to a parsed series name,
+// from 0 (unrelated) to 1 (same normalized name), with the metric selected
+// by matching.similarity
+func Confidence(name, title string) float64 {
+	return matcher.Similarity(name, title)
+}
+
+// Rank returns the usable results ordered by confidence, best first.
+// Results carrying an error are dropped.
+func Rank(name string, results []types.SearchResult) []types.SearchResult {
+	var ranked []types.SearchResult
+	for _, r := range results {
+		if r.Error == nil && r.URL != "" {
+			ranked = append(ranked, r)
+		}
+	}
+	slices.SortStableFunc(ranked, func(a, b types.SearchResult) int {
+		return cmp.Compare(Confidence(name, b.Title), Confidence(name, a.Title))
+	})
+	return ranked
+}
+
+// BestMatch returns the result most similar to name and its confidence
+func BestMatch(name string, results []types.SearchResult) (*types.SearchResult, float64) {
+	ranked := Rank(name, results)
+	if len(ranked) == 0 {
+		return nil, 0
+	}
+	return &ranked[0], Confidence(name, ranked[0].Title)
+}
+
+// FolderSeries returns the series name of a folder name: without the tags
+// in brackets or parentheses (group, year, resolution) and with dots and
+// underscores as spaces
+func FolderSeries(name string) string {
+	name = reTags.ReplaceAllString(name, " ")
+	name = strings.NewReplacer(".", " ", "_", " ").Replace(name)
+	return strings.Trim(reSpaces.ReplaceAllString(name, " "), " -")
+}
+
+// FolderName returns a filesystem-safe folder name for a title
+func FolderName(title string) string {
+	name := reUnsafe.ReplaceAllString(title, "")
+	name = reSpaces.ReplaceAllString(name, " ")
+	return strings.Trim(name, " .")
+}
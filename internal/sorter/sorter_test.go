@@ -0,0 +1,52 @@
+package sorter
+
+import "testing"
+
+func TestGroup(t *testing.T) {
+	groups := Group([]string{
+		"[SubsPlease] Frieren - 01 [1080p].mkv",
+		"Dungeon Meshi - 01.mkv",
+		"[SubsPlease] Frieren - 02 [1080p].mkv",
+		"dungeon.meshi.02.mkv",
+	})
+	if len(groups) != 2 {
+		t.Fatalf("Expected 2 groups, got %+v", groups)
+	}
+	if groups[0].Name != "Dungeon Meshi" || len(groups[0].Files) != 2 || len(groups[0].Patterns) != 2 {
+		t.Errorf("Unexpected first group: %+v", groups[0])
+	}
+	if groups[1].Name != "Frieren" || len(groups[1].Files) != 2 || len(groups[1].Patterns) != 1 {
+		t.Errorf("Unexpected second group: %+v", groups[1])
+	}
+}
+
+func TestConfidence(t *testing.T) {
+	if c := Confidence("Sousou no Frieren", "Sousou no Frieren"); c != 1 {
+		t.Errorf("Identical names should score 1, got %v", c)
+	}
+	near := Confidence("Frieren", "Sousou no Frieren")
+	far := Confidence("Frieren", "One Piece")
+	if near <= far {
+		t.Errorf("Expected related title to score higher: %v <= %v", near, far)
+	}
+}
+
+func TestFolderName(t *testing.T) {
+	if got := FolderName(`Re:Zero  "Starting Life"?`); got != "ReZero Starting Life" {
+		t.Errorf("FolderName = %q", got)
+	}
+}
+
+func TestFolderSeries(t *testing.T) {
+	tests := map[string]string{
+		"Frieren":                             "Frieren",
+		"[SubsPlease] Frieren (2023) [1080p]": "Frieren",
+		"Dungeon_Meshi - [BD]":                "Dungeon Meshi",
+		"one.piece":                           "one piece",
+	}
+	for in, want := range tests {
+		if got := FolderSeries(in); got != want {
+			t.Errorf("FolderSeries(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
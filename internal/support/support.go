@@ -0,0 +1,349 @@
+// Package support builds the tarball of `autotitle support-bundle`: what a
+// maintainer needs to triage an issue (configs, recent logs, versions, cache
+// stats and the plan of a failing folder), with secrets redacted and,
+// optionally, directory paths hashed.
+package support
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mydehq/autotitle"
+	"github.com/mydehq/autotitle/internal/config"
+	"github.com/mydehq/autotitle/internal/report"
+	"github.com/mydehq/autotitle/internal/types"
+	"gopkg.in/yaml.v3"
+)
+
+// logLines is how many of the latest lines of each log file are included
+const logLines = 500
+
+// redacted replaces secrets in the bundled configs
+const redacted = "REDACTED"
+
+// Options selects what goes into a bundle
+type Options struct {
+	Path      string // Folder whose dry-run plan is included, "" for none
+	Anonymize bool   // Replace directory paths with hashes; filenames are kept
+}
+
+// file is one entry of the bundle
+type file struct {
+	name string
+	data []byte
+}
+
+// bundle collects the entries, and notes what could not be collected
+type bundle struct {
+	files []file
+	notes []string
+}
+
+func (b *bundle) add(name string, data []byte) {
+	b.files = append(b.files, file{name, data})
+}
+
+func (b *bundle) addJSON(name string, v any) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		b.note("%s: %v", name, err)
+		return
+	}
+	b.add(name, append(data, '\n'))
+}
+
+func (b *bundle) note(format string, args ...any) {
+	b.notes = append(b.notes, fmt.Sprintf(format, args...))
+}
+
+// Build writes the bundle as a gzipped tarball to w, its entries under a
+// folder called name. It returns the names of the entries. A part that
+// cannot be collected is noted in MANIFEST.txt rather than failing the
+// bundle: a broken setup is what it is for.
+func Build(ctx context.Context, w io.Writer, name string, opts Options, now time.Time) ([]string, error) {
+	b := &bundle{}
+
+	b.addJSON("version.json", report.CurrentEnvironment())
+
+	globalPath := config.FindGlobal()
+	globalCfg, globalErr := config.LoadGlobal()
+	if globalErr != nil {
+		b.note("global config: %v", globalErr)
+	}
+	if globalPath != "" {
+		b.addConfig("config.yml", globalPath)
+	}
+
+	if stats, err := autotitle.CacheUsage(ctx); err != nil {
+		b.note("cache stats: %v", err)
+	} else {
+		b.addJSON("cache.json", stats)
+	}
+
+	if globalCfg != nil {
+		for i, sink := range globalCfg.Events.Sinks {
+			if sink.Type == types.SinkJSON && sink.Path != "" {
+				b.addLog(fmt.Sprintf("logs/events-%d.jsonl", i+1), sink.Path)
+			}
+		}
+	}
+
+	if opts.Path != "" {
+		b.addConfig("map.yml", config.MapFilePath(opts.Path))
+		b.addPlan(ctx, opts.Path, now)
+	}
+
+	if opts.Anonymize {
+		b.anonymize(opts.Path, globalPath)
+	}
+	b.addManifest(opts, now)
+
+	return b.write(w, name, now)
+}
+
+// addConfig adds a config file with its secrets redacted
+func (b *bundle) addConfig(name, path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		b.note("%s: %v", name, err)
+		return
+	}
+	clean, err := Sanitize(data)
+	if err != nil {
+		// Unparsable, so secrets cannot be told apart: leave it out
+		b.note("%s: not included, %v", name, err)
+		return
+	}
+	b.add(name, clean)
+}
+
+// addLog adds the last logLines lines of a log file
+func (b *bundle) addLog(name, path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		b.note("%s: %v", name, err)
+		return
+	}
+	defer func() { _ = f.Close() }()
+
+	var lines []string
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for sc.Scan() {
+		lines = append(lines, sc.Text())
+		if len(lines) > logLines {
+			lines = lines[1:]
+		}
+	}
+	if err := sc.Err(); err != nil {
+		b.note("%s: %v", name, err)
+	}
+	b.add(name, []byte(strings.Join(lines, "\n")+"\n"))
+}
+
+// addPlan adds the report of a dry-run of path: the plan, skips and every
+// event, including the error that stops it
+func (b *bundle) addPlan(ctx context.Context, path string, now time.Time) {
+	rep := report.New(path, true, now)
+	rep.Command = nil // The bundle command, not a rename
+	rep.AddConfig("global", config.FindGlobal())
+	rep.AddConfig("map", config.MapFilePath(path))
+
+	var excluded []types.RenameOperation
+	ops, err := autotitle.Rename(ctx, path, autotitle.WithDryRun(), autotitle.WithEvents(func(e types.Event) {
+		if op, ok := e.Data.(types.RenameOperation); ok {
+			excluded = append(excluded, op)
+		}
+		rep.Record(e, time.Now())
+	}))
+	rep.Finish(append(ops, excluded...), err, time.Now())
+	b.addJSON("plan.json", rep)
+}
+
+// anonymize replaces the directories the bundle mentions (the folder, the
+// home, config and cache dirs) with hashes, so paths stay consistent across
+// files without naming anything. Filenames are kept: they are what
+// patterns are matched against.
+func (b *bundle) anonymize(path, globalPath string) {
+	var dirs []string
+	addDir := func(d string) {
+		if d == "" || d == "." || d == string(filepath.Separator) {
+			return
+		}
+		if abs, err := filepath.Abs(d); err == nil {
+			d = abs
+		}
+		if !slices.Contains(dirs, d) {
+			dirs = append(dirs, d)
+		}
+	}
+	if path != "" {
+		addDir(path)
+		addDir(filepath.Dir(path))
+	}
+	if globalPath != "" {
+		addDir(filepath.Dir(globalPath))
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		addDir(home)
+	}
+	if wd, err := os.Getwd(); err == nil {
+		addDir(wd)
+	}
+	if cache, err := os.UserCacheDir(); err == nil {
+		addDir(cache)
+	}
+
+	// Longest first, so a folder is not half replaced through its parent
+	slices.SortFunc(dirs, func(a, b string) int { return len(b) - len(a) })
+	var pairs []string
+	for _, d := range dirs {
+		pairs = append(pairs, d, hashDir(d))
+	}
+	r := strings.NewReplacer(pairs...)
+	for i := range b.files {
+		b.files[i].data = []byte(r.Replace(string(b.files[i].data)))
+	}
+	for i := range b.notes {
+		b.notes[i] = r.Replace(b.notes[i])
+	}
+}
+
+// hashDir names a directory by a short hash of its path
+func hashDir(dir string) string {
+	sum := sha256.Sum256([]byte(dir))
+	return "<dir-" + hex.EncodeToString(sum[:4]) + ">"
+}
+
+// addManifest adds MANIFEST.txt, listing the entries and anything missing
+func (b *bundle) addManifest(opts Options, now time.Time) {
+	var m bytes.Buffer
+	fmt.Fprintf(&m, "autotitle support bundle, created %s\n\n", now.UTC().Format(time.RFC3339))
+	for _, f := range b.files {
+		fmt.Fprintf(&m, "  %s\n", f.name)
+	}
+	fmt.Fprintf(&m, "\nSecrets (api.keys, api.users, media server and serve tokens, webhook secrets) are redacted.\n")
+	if opts.Anonymize {
+		fmt.Fprintf(&m, "Directory paths are replaced with <dir-...> hashes; filenames are kept.\n")
+	}
+	if len(b.notes) > 0 {
+		fmt.Fprintf(&m, "\nNot collected:\n")
+		for _, n := range b.notes {
+			fmt.Fprintf(&m, "  %s\n", n)
+		}
+	}
+	b.files = append([]file{{"MANIFEST.txt", m.Bytes()}}, b.files...)
+}
+
+// write packs the entries into a gzipped tarball
+func (b *bundle) write(w io.Writer, name string, now time.Time) ([]string, error) {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	var names []string
+	for _, f := range b.files {
+		hdr := &tar.Header{
+			Name:    name + "/" + f.name,
+			Mode:    0644,
+			Size:    int64(len(f.data)),
+			ModTime: now,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, fmt.Errorf("failed to write bundle: %w", err)
+		}
+		if _, err := tw.Write(f.data); err != nil {
+			return nil, fmt.Errorf("failed to write bundle: %w", err)
+		}
+		names = append(names, f.name)
+	}
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return names, nil
+}
+
+// Sanitize redacts the secrets of a global config or map file: api.keys and
+// api.users values, media server and serve tokens, webhook secrets, the user
+// info of URLs (e.g. api.base_urls with user:pass@host) and the commands of
+// backup.snapshot, which may carry credentials. Comments and layout are kept.
+func Sanitize(data []byte) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return data, nil
+	}
+	redact(&doc, false)
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// redact walks a YAML tree; under a secret mapping (keys, users) every
+// value is a secret
+func redact(n *yaml.Node, secret bool) {
+	if n.Kind == yaml.ScalarNode {
+		if secret && n.Value != "" {
+			n.Value, n.Style = redacted, 0
+		} else if u, err := url.Parse(n.Value); err == nil && u.User != nil && u.Host != "" {
+			u.User = url.User(redacted)
+			n.Value = u.String()
+		}
+		return
+	}
+	if n.Kind != yaml.MappingNode {
+		for _, c := range n.Content {
+			redact(c, secret)
+		}
+		return
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		switch n.Content[i].Value {
+		case "token", "secret":
+			redact(n.Content[i+1], true)
+		case "keys", "users":
+			redact(n.Content[i+1], n.Content[i+1].Kind == yaml.MappingNode)
+		case "snapshot":
+			redactCommands(n.Content[i+1])
+		default:
+			redact(n.Content[i+1], secret)
+		}
+	}
+}
+
+// redactCommands redacts the create, rollback and delete commands of a
+// snapshot config, which may hold passwords or tokens for the snapshot tool
+func redactCommands(n *yaml.Node) {
+	if n.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		switch n.Content[i].Value {
+		case "create", "rollback", "delete":
+			redact(n.Content[i+1], true)
+		default:
+			redact(n.Content[i+1], false)
+		}
+	}
+}
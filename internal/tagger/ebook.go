@@ -0,0 +1,185 @@
+package tagger
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	reDCTitle     = regexp.MustCompile(`(?s)(<dc:title[^>]*>).*?(</dc:title>)`)
+	reMetadataEnd = regexp.MustCompile(`</(?:opf:)?metadata>`)
+	reSeriesMeta  = regexp.MustCompile(`\s*<meta\s+name="calibre:series(?:_index)?"[^>]*/>`)
+)
+
+// bookTitle is the title written into an ebook: "Series, Volume 3", or
+// whichever of the two is known
+func bookTitle(info TagInfo) string {
+	switch {
+	case info.Show == "":
+		return info.Title
+	case info.Title == "":
+		return info.Show
+	}
+	return info.Show + ", " + info.Title
+}
+
+// tagEPUB sets the title and the series (as calibre and most readers read
+// it) in the EPUB's package document. The archive is rewritten next to the
+// original with every other entry copied as is, then replaces it.
+func tagEPUB(path string, info TagInfo) (err error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return fmt.Errorf("not a valid EPUB: %w", err)
+	}
+	defer zr.Close()
+
+	opfPath, err := epubPackagePath(&zr.Reader)
+	if err != nil {
+		return err
+	}
+
+	ext := filepath.Ext(path)
+	tmp := filepath.Join(filepath.Dir(path), "."+strings.TrimSuffix(filepath.Base(path), ext)+".autotitle-tag"+ext)
+	out, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, stat.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp)
+		}
+	}()
+
+	zw := zip.NewWriter(out)
+	for _, f := range zr.File {
+		if f.Name != opfPath {
+			// Raw copy keeps "mimetype" first and stored, as EPUB requires
+			if err := zw.Copy(f); err != nil {
+				_ = out.Close()
+				return err
+			}
+			continue
+		}
+		opf, err := readZipFile(f)
+		if err != nil {
+			_ = out.Close()
+			return err
+		}
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: f.Name, Method: f.Method, Modified: f.Modified})
+		if err != nil {
+			_ = out.Close()
+			return err
+		}
+		if _, err := w.Write(setEPUBMetadata(opf, info)); err != nil {
+			_ = out.Close()
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// epubPackagePath returns the package document (.opf) named by
+// META-INF/container.xml
+func epubPackagePath(zr *zip.Reader) (string, error) {
+	for _, f := range zr.File {
+		if f.Name != "META-INF/container.xml" {
+			continue
+		}
+		data, err := readZipFile(f)
+		if err != nil {
+			return "", err
+		}
+		var container struct {
+			Rootfiles []struct {
+				FullPath string `xml:"full-path,attr"`
+			} `xml:"rootfiles>rootfile"`
+		}
+		if err := xml.Unmarshal(data, &container); err != nil {
+			return "", fmt.Errorf("invalid EPUB container: %w", err)
+		}
+		if len(container.Rootfiles) == 0 {
+			break
+		}
+		return container.Rootfiles[0].FullPath, nil
+	}
+	return "", fmt.Errorf("not a valid EPUB: no package document")
+}
+
+// setEPUBMetadata replaces the first dc:title and the calibre series entries
+// of a package document, leaving the rest of it untouched
+func setEPUBMetadata(opf []byte, info TagInfo) []byte {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(bookTitle(info)))
+	title := buf.String()
+
+	if loc := reDCTitle.FindSubmatchIndex(opf); loc != nil {
+		opf = append(opf[:loc[3]:loc[3]], append([]byte(title), opf[loc[4]:]...)...)
+	} else if loc := reMetadataEnd.FindIndex(opf); loc != nil {
+		opf = append(opf[:loc[0]:loc[0]], append([]byte("<dc:title>"+title+"</dc:title>\n"), opf[loc[0]:]...)...)
+	}
+
+	if info.Show == "" {
+		return opf
+	}
+	opf = reSeriesMeta.ReplaceAll(opf, nil)
+	buf.Reset()
+	_ = xml.EscapeText(&buf, []byte(info.Show))
+	series := fmt.Sprintf("<meta name=\"calibre:series\" content=\"%s\"/>\n", buf.String())
+	if info.EpisodeSort > 0 {
+		series += fmt.Sprintf("<meta name=\"calibre:series_index\" content=\"%d\"/>\n", info.EpisodeSort)
+	}
+	if loc := reMetadataEnd.FindIndex(opf); loc != nil {
+		opf = append(opf[:loc[0]:loc[0]], append([]byte(series), opf[loc[0]:]...)...)
+	}
+	return opf
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// tagEbookMeta sets the title and series with calibre's ebook-meta, which
+// edits EPUB, AZW3 and MOBI in place
+func tagEbookMeta(ctx context.Context, path string, info TagInfo) error {
+	args := []string{path, "--title", bookTitle(info)}
+	if info.Show != "" {
+		args = append(args, "--series", info.Show)
+		if info.EpisodeSort > 0 {
+			args = append(args, "--index", fmt.Sprintf("%d", info.EpisodeSort))
+		}
+	}
+	if info.AirDate != "" {
+		args = append(args, "--date", info.AirDate)
+	}
+
+	cmd := exec.CommandContext(ctx, calibreBin, args...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("ebook-meta failed: %w\noutput: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -1,5 +1,7 @@
-// Package tagger embeds metadata into media files using mkvpropedit (MKV)
-// and AtomicParsley (MP4/M4V/M4A).
+// Package tagger embeds metadata into media files using mkvpropedit
+// (MKV/WebM), AtomicParsley (MP4/M4V/M4A) or an ffmpeg remux, and into
+// ebooks (EPUB built in, AZW3/MOBI via calibre's ebook-meta), chosen per
+// extension from a backend preference list.
 package tagger
 
 import (
@@ -8,13 +10,19 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 	"text/template"
+
+	"github.com/mydehq/autotitle/internal/types"
+	"github.com/mydehq/autotitle/internal/util"
 )
 
 const (
-	mkvBin = "mkvpropedit"
-	mp4Bin = "atomicparsley"
+	mkvBin     = "mkvpropedit"
+	mp4Bin     = "atomicparsley"
+	ffmpegBin  = "ffmpeg"
+	calibreBin = "ebook-meta"
 )
 
 // TagInfo contains the metadata to embed into a media file.
@@ -26,6 +34,169 @@ type TagInfo struct {
 	AirDate     string // ISO date string (e.g. "2013-04-07"), optional
 }
 
+// Tagging backends
+const (
+	BackendMKV    = "mkvpropedit"   // Edits Matroska/WebM in place
+	BackendMP4    = "atomicparsley" // Edits the MP4 family in place
+	BackendFFmpeg = "ffmpeg"        // Remuxes to a temp file with new metadata
+	BackendEPUB   = "epub"          // Rewrites the EPUB package metadata, no tool needed
+	BackendEbook  = "ebook-meta"    // calibre's ebook metadata editor
+	BackendNone   = "none"          // Never tagged
+)
+
+// DefaultBackends is the backend preference order used when tagging.backend
+// is not set. ffmpeg and the ebook backends are opt-in: they rewrite the
+// whole file.
+var DefaultBackends = []string{BackendMKV, BackendMP4}
+
+// backendFormats lists the extensions each backend can tag. ffmpeg keeps
+// the container, so it only covers containers its muxers can write back
+// with metadata.
+var backendFormats = map[string][]string{
+	BackendMKV:    {"mkv", "mka", "mk3d", "webm"},
+	BackendMP4:    {"mp4", "m4v", "m4a"},
+	BackendFFmpeg: {"mkv", "mka", "webm", "mp4", "m4v", "m4a", "mov", "avi", "wmv", "flv"},
+	BackendEPUB:   {"epub"},
+	BackendEbook:  {"epub", "azw3", "azw", "mobi"},
+}
+
+// backendBins are the tools each backend runs; built-in backends have none
+var backendBins = map[string]string{
+	BackendMKV:    mkvBin,
+	BackendMP4:    mp4Bin,
+	BackendFFmpeg: ffmpegBin,
+	BackendEPUB:   "",
+	BackendEbook:  calibreBin,
+}
+
+// hasTool reports whether backend's tool is in $PATH (built-ins always are)
+func hasTool(backend string) bool {
+	bin, ok := backendBins[backend]
+	return ok && (bin == "" || lookPath(bin))
+}
+
+// lookPath reports whether a tool is in $PATH; replaced in tests
+var lookPath = func(bin string) bool {
+	_, err := exec.LookPath(bin)
+	return err == nil
+}
+
+// Tagger tags each file with the first backend in its preference list that
+// can handle the file's extension and is installed, unless the extension is
+// pinned to a backend (or to none) in its format table
+type Tagger struct {
+	backends []string
+	formats  map[string]string
+}
+
+// New creates a Tagger from the tagging section of the global config:
+// Backend is the preference list (default DefaultBackends) and Formats pins
+// extensions to a backend or none. Extensions are case-insensitive and may
+// start with a dot.
+func New(cfg types.TaggingConfig) (*Tagger, error) {
+	t := &Tagger{backends: DefaultBackends, formats: make(map[string]string, len(cfg.Formats))}
+	if len(cfg.Backend) > 0 {
+		t.backends = nil
+		for _, backend := range cfg.Backend {
+			backend = strings.ToLower(backend)
+			if _, ok := backendBins[backend]; !ok {
+				return nil, fmt.Errorf("unknown tagging backend %q (use mkvpropedit, atomicparsley, ffmpeg, epub or ebook-meta)", backend)
+			}
+			t.backends = append(t.backends, backend)
+		}
+	}
+	for ext, backend := range cfg.Formats {
+		backend = strings.ToLower(backend)
+		if _, ok := backendBins[backend]; !ok && backend != BackendNone {
+			return nil, fmt.Errorf("unknown tagging backend %q for %s (use mkvpropedit, atomicparsley, ffmpeg, epub, ebook-meta or none)", backend, ext)
+		}
+		t.formats[normalizeExt(ext)] = backend
+	}
+	return t, nil
+}
+
+// Default returns a Tagger using DefaultBackends
+func Default() *Tagger {
+	t, _ := New(types.TaggingConfig{})
+	return t
+}
+
+func normalizeExt(ext string) string {
+	return strings.ToLower(strings.TrimPrefix(ext, "."))
+}
+
+// Backend returns the backend that tags path, or BackendNone. When no
+// capable backend is installed, the first capable one is returned so
+// TagFile can report the missing tool.
+func (t *Tagger) Backend(path string) string {
+	ext := normalizeExt(filepath.Ext(path))
+	if b, ok := t.formats[ext]; ok {
+		return b
+	}
+	fallback := BackendNone
+	for _, backend := range t.backends {
+		if !slices.Contains(backendFormats[backend], ext) {
+			continue
+		}
+		if hasTool(backend) {
+			return backend
+		}
+		if fallback == BackendNone {
+			fallback = backend
+		}
+	}
+	return fallback
+}
+
+// IsTaggable returns true if some backend would tag path
+func (t *Tagger) IsTaggable(path string) bool {
+	return t.Backend(path) != BackendNone
+}
+
+// IsAvailable returns true if the tool of at least one usable backend is in $PATH.
+func (t *Tagger) IsAvailable() bool {
+	backends := slices.Clone(t.backends)
+	for _, backend := range t.formats {
+		backends = append(backends, backend)
+	}
+	for _, backend := range backends {
+		if hasTool(backend) {
+			return true
+		}
+	}
+	return false
+}
+
+// TagFile embeds metadata into a media file with the backend chosen for it.
+// Files no backend handles are silently skipped. Returns an error if the
+// backend's tool is not installed.
+func (t *Tagger) TagFile(ctx context.Context, path string, info TagInfo) error {
+	if err := util.CheckWritable("tag files"); err != nil {
+		return err
+	}
+	backend := t.Backend(path)
+	if backend == BackendNone {
+		return nil
+	}
+	if !hasTool(backend) {
+		return fmt.Errorf("%s not found; cannot tag %s", backendBins[backend], filepath.Base(path))
+	}
+
+	switch backend {
+	case BackendMKV:
+		return tagMKV(ctx, path, info)
+	case BackendMP4:
+		return tagMP4(ctx, path, info)
+	case BackendFFmpeg:
+		return tagFFmpeg(ctx, path, info)
+	case BackendEPUB:
+		return tagEPUB(path, info)
+	case BackendEbook:
+		return tagEbookMeta(ctx, path, info)
+	}
+	return nil
+}
+
 // IsAvailable returns true if at least one supported tagging tool is in $PATH.
 func IsAvailable() bool {
 	return IsMKVAvailable() || IsMP4Available()
@@ -33,14 +204,12 @@ func IsAvailable() bool {
 
 // IsMKVAvailable returns true if mkvpropedit is in $PATH.
 func IsMKVAvailable() bool {
-	_, err := exec.LookPath(mkvBin)
-	return err == nil
+	return lookPath(mkvBin)
 }
 
 // IsMP4Available returns true if AtomicParsley is in $PATH.
 func IsMP4Available() bool {
-	_, err := exec.LookPath(mp4Bin)
-	return err == nil
+	return lookPath(mp4Bin)
 }
 
 // isMKV returns true if the file has an .mkv extension (used in tests).
@@ -48,41 +217,19 @@ func isMKV(path string) bool {
 	return strings.EqualFold(filepath.Ext(path), ".mkv")
 }
 
-// isTaggable returns true if the file format is supported for tagging.
+// isTaggable returns true if the file format is tagged by default.
 func isTaggable(path string) bool {
-	switch strings.ToLower(filepath.Ext(path)) {
-	case ".mkv", ".mp4", ".m4v", ".m4a":
-		return true
-	}
-	return false
+	return Default().IsTaggable(path)
 }
 
-// TagFile embeds metadata into a media file, dispatching based on file extension:
-//   - .mkv          → mkvpropedit
-//   - .mp4/.m4v/.m4a → AtomicParsley
+// TagFile embeds metadata into a media file using DefaultBackends:
+//   - .mkv/.mka/.mk3d/.webm → mkvpropedit
+//   - .mp4/.m4v/.m4a        → AtomicParsley
 //
-// Unsupported extensions are silently skipped (returns nil).
+// Other extensions are silently skipped (returns nil).
 // Returns an error if the required tool is not installed for the given format.
 func TagFile(ctx context.Context, path string, info TagInfo) error {
-	ext := strings.ToLower(filepath.Ext(path))
-
-	switch ext {
-	case ".mkv":
-		if !IsMKVAvailable() {
-			return fmt.Errorf("mkvpropedit not found; cannot tag %s", filepath.Base(path))
-		}
-		return tagMKV(ctx, path, info)
-
-	case ".mp4", ".m4v", ".m4a":
-		if !IsMP4Available() {
-			return fmt.Errorf("atomicparsley not found; cannot tag %s", filepath.Base(path))
-		}
-		return tagMP4(ctx, path, info)
-
-	default:
-		// Unsupported format — silently skip
-		return nil
-	}
+	return Default().TagFile(ctx, path, info)
 }
 
 // MKV via mkvpropedit
@@ -181,3 +328,45 @@ func tagMP4(ctx context.Context, path string, info TagInfo) error {
 	}
 	return nil
 }
+
+// tagFFmpeg remuxes the file with ffmpeg into a temp file next to it,
+// copying every stream and the existing metadata and setting ours, then
+// replaces the original. The container is kept, so the name doesn't change.
+func tagFFmpeg(ctx context.Context, path string, info TagInfo) (err error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	ext := filepath.Ext(path)
+	tmp := filepath.Join(filepath.Dir(path), "."+strings.TrimSuffix(filepath.Base(path), ext)+".autotitle-tag"+ext)
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp)
+		}
+	}()
+
+	args := []string{"-nostdin", "-loglevel", "error", "-y", "-i", path, "-map", "0", "-c", "copy", "-map_metadata", "0"}
+	for _, kv := range [][2]string{
+		{"title", info.Title},
+		{"show", info.Show},
+		{"episode_id", info.EpisodeID},
+		{"date", info.AirDate},
+	} {
+		if kv[1] != "" {
+			args = append(args, "-metadata", kv[0]+"="+kv[1])
+		}
+	}
+	if info.EpisodeSort > 0 {
+		args = append(args, "-metadata", fmt.Sprintf("episode_sort=%d", info.EpisodeSort))
+	}
+	args = append(args, tmp)
+
+	cmd := exec.CommandContext(ctx, ffmpegBin, args...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("ffmpeg failed: %w\noutput: %s", err, strings.TrimSpace(string(out)))
+	}
+	if err := os.Chmod(tmp, stat.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
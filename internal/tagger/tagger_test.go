@@ -1,11 +1,16 @@
 package tagger
 
 import (
+	"archive/zip"
 	"context"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+
+	"github.com/mydehq/autotitle/internal/types"
 )
 
 // renderTagXML is a test helper that renders the tag XML template to a string.
@@ -94,7 +99,9 @@ func TestIsTaggable(t *testing.T) {
 		{"/path/to/file.m4v", true},
 		{"/path/to/file.m4a", true},
 		{"/path/to/file.avi", false},
-		{"/path/to/file.webm", false},
+		{"/path/to/file.webm", true},
+		{"/path/to/file.ogm", false},
+		{"/path/to/file.wmv", false},
 		{"/path/to/file.ts", false},
 		{"/path/to/file", false},
 	}
@@ -106,6 +113,88 @@ func TestIsTaggable(t *testing.T) {
 	}
 }
 
+// installed fakes which tagging tools are in $PATH
+func installed(t *testing.T, bins ...string) {
+	t.Helper()
+	orig := lookPath
+	lookPath = func(bin string) bool { return slices.Contains(bins, bin) }
+	t.Cleanup(func() { lookPath = orig })
+}
+
+func TestNew_Overrides(t *testing.T) {
+	installed(t, mkvBin, mp4Bin)
+	tg, err := New(types.TaggingConfig{Formats: map[string]string{".WEBM": "none", "ogm": "MKVPropEdit"}})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	cases := map[string]string{
+		"ep.webm": BackendNone,
+		"ep.ogm":  BackendMKV,
+		"ep.mp4":  BackendMP4,
+		"ep.avi":  BackendNone,
+	}
+	for path, want := range cases {
+		if got := tg.Backend(path); got != want {
+			t.Errorf("Backend(%q) = %q, want %q", path, got, want)
+		}
+	}
+
+	if _, err := New(types.TaggingConfig{Formats: map[string]string{"wmv": "handbrake"}}); err == nil {
+		t.Error("Expected an error for an unknown backend")
+	}
+	if _, err := New(types.TaggingConfig{Backend: []string{"ffmpeg", "vlc"}}); err == nil {
+		t.Error("Expected an error for an unknown backend in the preference list")
+	}
+}
+
+func TestBackend_Preference(t *testing.T) {
+	tg, err := New(types.TaggingConfig{Backend: []string{BackendMKV, BackendMP4, BackendFFmpeg}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Everything installed: the native tools win, ffmpeg covers the rest
+	installed(t, mkvBin, mp4Bin, ffmpegBin)
+	for path, want := range map[string]string{
+		"ep.mkv":  BackendMKV,
+		"ep.m4v":  BackendMP4,
+		"ep.avi":  BackendFFmpeg,
+		"ep.webm": BackendMKV,
+		"ep.ts":   BackendNone,
+	} {
+		if got := tg.Backend(path); got != want {
+			t.Errorf("Backend(%q) = %q, want %q", path, got, want)
+		}
+	}
+
+	// No mkvpropedit: ffmpeg takes over Matroska
+	installed(t, ffmpegBin)
+	if got := tg.Backend("ep.mkv"); got != BackendFFmpeg {
+		t.Errorf("Expected ffmpeg without mkvpropedit, got %q", got)
+	}
+
+	// Nothing installed: the first capable backend, so the missing tool is reported
+	installed(t)
+	if got := tg.Backend("ep.mkv"); got != BackendMKV {
+		t.Errorf("Expected mkvpropedit as fallback, got %q", got)
+	}
+	err = tg.TagFile(context.Background(), "/nonexistent/ep.mkv", TagInfo{Title: "x"})
+	if err == nil || !strings.Contains(err.Error(), "mkvpropedit not found") {
+		t.Errorf("Expected a missing tool error, got %v", err)
+	}
+}
+
+func TestTagFile_SkipsUnmapped(t *testing.T) {
+	tg, err := New(types.TaggingConfig{Formats: map[string]string{"mkv": "none"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Never reaches mkvpropedit, so this works without the file or the tool
+	if err := tg.TagFile(context.Background(), "/nonexistent/ep.mkv", TagInfo{Title: "x"}); err != nil {
+		t.Errorf("Expected unmapped file to be skipped, got %v", err)
+	}
+}
+
 // Verify the template is valid XML (basic sanity)
 func TestWriteTagXML_ValidXML(t *testing.T) {
 	info := TagInfo{Title: "Test", Show: "Series"}
@@ -214,6 +303,44 @@ func TestTagFile_MP4_Integration(t *testing.T) {
 	t.Logf("✓ MP4 tags verified: title=%q show=%q", info.Title, info.Show)
 }
 
+// TestTagFile_FFmpeg_Integration remuxes a real AVI with the ffmpeg backend and verifies with ffprobe.
+func TestTagFile_FFmpeg_Integration(t *testing.T) {
+	for _, bin := range []string{"ffmpeg", "ffprobe"} {
+		if _, err := exec.LookPath(bin); err != nil {
+			t.Skipf("%s not found; skipping ffmpeg integration test", bin)
+		}
+	}
+
+	tmpDir := t.TempDir()
+	aviPath := tmpDir + "/ep01.avi"
+	ffmpegArgs := []string{
+		"-f", "lavfi", "-i", "color=c=black:s=64x64:d=1",
+		"-c:v", "mpeg4", aviPath, "-y", "-loglevel", "quiet",
+	}
+	if out, err := exec.Command("ffmpeg", ffmpegArgs...).CombinedOutput(); err != nil {
+		t.Fatalf("ffmpeg failed to create test AVI: %v\n%s", err, out)
+	}
+
+	tg, err := New(types.TaggingConfig{Backend: []string{BackendFFmpeg}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tg.TagFile(context.Background(), aviPath, TagInfo{Title: "To You, in 2000 Years", Show: "Attack on Titan"}); err != nil {
+		t.Fatalf("TagFile (ffmpeg) failed: %v", err)
+	}
+
+	out, err := exec.Command("ffprobe", "-v", "quiet", "-show_entries", "format_tags=title", aviPath).CombinedOutput()
+	if err != nil {
+		t.Fatalf("ffprobe failed: %v\n%s", err, out)
+	}
+	if !strings.Contains(string(out), "To You, in 2000 Years") {
+		t.Errorf("ffprobe output missing title\nFull output:\n%s", out)
+	}
+	if entries, _ := os.ReadDir(tmpDir); len(entries) != 1 {
+		t.Errorf("Expected the temp file to be gone, got %d files", len(entries))
+	}
+}
+
 func assertContains(t *testing.T, haystack, needle string) {
 	t.Helper()
 	if !strings.Contains(haystack, needle) {
@@ -227,3 +354,56 @@ func min(a, b int) int {
 	}
 	return b
 }
+
+func TestTagFile_EPUB(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vol.epub")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for _, entry := range []struct {
+		name, body string
+		method     uint16
+	}{
+		{"mimetype", "application/epub+zip", zip.Store},
+		{"META-INF/container.xml", `<container><rootfiles><rootfile full-path="OEBPS/content.opf"/></rootfiles></container>`, zip.Deflate},
+		{"OEBPS/content.opf", `<package><metadata><dc:title id="t">Old Title</dc:title><meta name="calibre:series" content="Old"/></metadata></package>`, zip.Deflate},
+		{"OEBPS/ch1.xhtml", "<html/>", zip.Deflate},
+	} {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: entry.name, Method: entry.method})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(entry.body))
+	}
+	zw.Close()
+	f.Close()
+
+	tg, err := New(types.TaggingConfig{Backend: []string{BackendEPUB}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tg.TagFile(context.Background(), path, TagInfo{Title: "Volume 3", Show: "Overlord & Co", EpisodeSort: 3}); err != nil {
+		t.Fatalf("TagFile failed: %v", err)
+	}
+
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatalf("tagged EPUB is not a zip: %v", err)
+	}
+	defer zr.Close()
+	if first := zr.File[0]; first.Name != "mimetype" || first.Method != zip.Store {
+		t.Errorf("expected mimetype first and stored, got %s (method %d)", first.Name, first.Method)
+	}
+	opf, err := readZipFile(zr.File[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<package><metadata><dc:title id="t">Overlord &amp; Co, Volume 3</dc:title><meta name="calibre:series" content="Overlord &amp; Co"/>
+<meta name="calibre:series_index" content="3"/>
+</metadata></package>`
+	if string(opf) != want {
+		t.Errorf("unexpected package document:\n%s", opf)
+	}
+}
@@ -0,0 +1,141 @@
+// Package thumbnail extracts episode thumbnails with ffmpeg, named the way
+// media servers (Kodi, Jellyfin, Emby) pick them up: "<episode>-thumb.jpg"
+// next to the video.
+package thumbnail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mydehq/autotitle/internal/types"
+	"github.com/mydehq/autotitle/internal/util"
+)
+
+const (
+	ffmpegBin  = "ffmpeg"
+	ffprobeBin = "ffprobe"
+
+	// DefaultAt skips most cold opens and openings without reaching the story
+	DefaultAt = "10%"
+)
+
+// lookPath reports whether a tool is in $PATH; replaced in tests
+var lookPath = func(bin string) bool {
+	_, err := exec.LookPath(bin)
+	return err == nil
+}
+
+// IsAvailable returns true if ffmpeg is in $PATH
+func IsAvailable() bool {
+	return lookPath(ffmpegBin)
+}
+
+// Path returns the thumbnail path for a video
+func Path(video string) string {
+	return strings.TrimSuffix(video, filepath.Ext(video)) + "-thumb.jpg"
+}
+
+// position is a parsed "at" setting: a share of the runtime or an offset
+type position struct {
+	percent float64
+	offset  time.Duration
+}
+
+// parseAt parses "10%", "90s", "90" or "[hh:]mm:ss"
+func parseAt(at string) (position, error) {
+	at = strings.TrimSpace(at)
+	if at == "" {
+		at = DefaultAt
+	}
+	if p, ok := strings.CutSuffix(at, "%"); ok {
+		v, err := strconv.ParseFloat(p, 64)
+		if err != nil || v < 0 || v >= 100 {
+			return position{}, fmt.Errorf("invalid thumbnail position %q: percent must be 0-99", at)
+		}
+		return position{percent: v}, nil
+	}
+	if strings.Contains(at, ":") {
+		var secs float64
+		for _, part := range strings.Split(at, ":") {
+			v, err := strconv.ParseFloat(part, 64)
+			if err != nil || v < 0 {
+				return position{}, fmt.Errorf("invalid thumbnail position %q", at)
+			}
+			secs = secs*60 + v
+		}
+		return position{offset: time.Duration(secs * float64(time.Second))}, nil
+	}
+	v, err := strconv.ParseFloat(strings.TrimSuffix(at, "s"), 64)
+	if err != nil || v < 0 {
+		return position{}, fmt.Errorf("invalid thumbnail position %q (use e.g. 10%%, 90s or 00:01:30)", at)
+	}
+	return position{offset: time.Duration(v * float64(time.Second))}, nil
+}
+
+// Extract writes the thumbnail of video, taken at cfg.At, and returns its
+// path. An existing thumbnail is kept unless cfg.Overwrite is set; the
+// returned path is empty then.
+func Extract(ctx context.Context, video string, cfg types.ThumbnailConfig) (string, error) {
+	if err := util.CheckWritable("write thumbnails"); err != nil {
+		return "", err
+	}
+	pos, err := parseAt(cfg.At)
+	if err != nil {
+		return "", err
+	}
+	out := Path(video)
+	if _, err := os.Stat(out); err == nil && !cfg.Overwrite {
+		return "", nil
+	}
+	if !IsAvailable() {
+		return "", fmt.Errorf("%s not found; cannot extract a thumbnail of %s", ffmpegBin, filepath.Base(video))
+	}
+
+	offset := pos.offset
+	if pos.percent > 0 {
+		d, err := duration(ctx, video)
+		if err != nil {
+			return "", err
+		}
+		offset = time.Duration(float64(d) * pos.percent / 100)
+	}
+
+	// Seeking before the input is fast; -update writes one image, not a sequence
+	args := []string{"-nostdin", "-loglevel", "error", "-y",
+		"-ss", strconv.FormatFloat(offset.Seconds(), 'f', 3, 64), "-i", video,
+		"-frames:v", "1", "-q:v", "2", "-update", "1", out}
+	cmd := exec.CommandContext(ctx, ffmpegBin, args...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		_ = os.Remove(out)
+		return "", fmt.Errorf("ffmpeg failed: %w\noutput: %s", err, strings.TrimSpace(string(output)))
+	}
+	if _, err := os.Stat(out); err != nil {
+		// ffmpeg succeeds without output when seeking past the end
+		return "", fmt.Errorf("no frame at %s in %s", offset, filepath.Base(video))
+	}
+	return out, nil
+}
+
+// duration returns the runtime of video as ffprobe reports it
+func duration(ctx context.Context, video string) (time.Duration, error) {
+	if !lookPath(ffprobeBin) {
+		return 0, fmt.Errorf("%s not found; needed for a percent thumbnail position", ffprobeBin)
+	}
+	cmd := exec.CommandContext(ctx, ffprobeBin, "-v", "error", "-show_entries", "format=duration",
+		"-of", "default=noprint_wrappers=1:nokey=1", video)
+	output, err := cmd.Output()
+	if err != nil {
+		return 0, fmt.Errorf("ffprobe failed on %s: %w", filepath.Base(video), err)
+	}
+	secs, err := strconv.ParseFloat(strings.TrimSpace(string(output)), 64)
+	if err != nil {
+		return 0, fmt.Errorf("ffprobe reported no duration for %s", filepath.Base(video))
+	}
+	return time.Duration(secs * float64(time.Second)), nil
+}
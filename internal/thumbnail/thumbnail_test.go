@@ -0,0 +1,62 @@
+package thumbnail
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mydehq/autotitle/internal/types"
+)
+
+func TestParseAt(t *testing.T) {
+	tests := map[string]position{
+		"":         {percent: 10},
+		"25%":      {percent: 25},
+		"90s":      {offset: 90 * time.Second},
+		"42":       {offset: 42 * time.Second},
+		"01:30":    {offset: 90 * time.Second},
+		"01:00:05": {offset: time.Hour + 5*time.Second},
+	}
+	for at, want := range tests {
+		got, err := parseAt(at)
+		if err != nil || got != want {
+			t.Errorf("parseAt(%q) = %+v, %v; want %+v", at, got, err, want)
+		}
+	}
+	for _, at := range []string{"100%", "-5s", "1:xx", "soon"} {
+		if _, err := parseAt(at); err == nil {
+			t.Errorf("parseAt(%q): expected error", at)
+		}
+	}
+}
+
+func TestPath(t *testing.T) {
+	if got := Path("/tv/Show - 01 - Pilot.mkv"); got != "/tv/Show - 01 - Pilot-thumb.jpg" {
+		t.Errorf("Path = %q", got)
+	}
+}
+
+func TestExtract_KeepsExisting(t *testing.T) {
+	orig := lookPath
+	lookPath = func(string) bool { return false }
+	t.Cleanup(func() { lookPath = orig })
+
+	video := filepath.Join(t.TempDir(), "ep.mkv")
+	if _, err := Extract(context.Background(), video, types.ThumbnailConfig{Enabled: true}); err == nil {
+		t.Error("expected error without ffmpeg")
+	}
+
+	// An existing thumbnail is kept without ever running ffmpeg
+	if err := os.WriteFile(Path(video), []byte("jpg"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := Extract(context.Background(), video, types.ThumbnailConfig{Enabled: true})
+	if err != nil || out != "" {
+		t.Errorf("expected the existing thumbnail to be kept, got %q, %v", out, err)
+	}
+	if _, err := Extract(context.Background(), video, types.ThumbnailConfig{Enabled: true, Overwrite: true}); err == nil {
+		t.Error("expected overwrite to need ffmpeg")
+	}
+}
@@ -0,0 +1,28 @@
+package types
+
+import "time"
+
+// Batch is a rename batch in progress, journaled so an interrupted run can
+// be resumed without planning it again
+type Batch struct {
+	Dir        string            `json:"dir"` // Absolute path of the renamed directory
+	Provider   string            `json:"provider"`
+	ID         string            `json:"id"`
+	Series     string            `json:"series"`
+	Backup     bool              `json:"backup"`               // Files are backed up before renaming
+	BackupDir  string            `json:"backup_dir,omitempty"` // Backup dir name, or an absolute backup root
+	Tag        bool              `json:"tag"`                  // Renamed videos are tagged
+	Started    time.Time         `json:"started"`
+	Operations []RenameOperation `json:"operations"`
+}
+
+// Pending returns the number of operations not yet attempted
+func (b *Batch) Pending() int {
+	n := 0
+	for _, op := range b.Operations {
+		if op.Status == StatusPending {
+			n++
+		}
+	}
+	return n
+}
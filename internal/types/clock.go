@@ -0,0 +1,48 @@
+package types
+
+import (
+	"context"
+	"time"
+)
+
+// Clock abstracts the current time and sleeping so time-dependent logic
+// (refresh expiry, timestamps, rate limiting) can be tested
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+// SystemClock is the real clock
+type SystemClock struct{}
+
+// Now returns the current time
+func (SystemClock) Now() time.Time { return time.Now() }
+
+// Sleep pauses for d
+func (SystemClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+// NowFunc is a Clock reading the time from a function. Sleep still blocks for real.
+type NowFunc func() time.Time
+
+// Now returns f()
+func (f NowFunc) Now() time.Time { return f() }
+
+// Sleep pauses for d
+func (NowFunc) Sleep(d time.Duration) { time.Sleep(d) }
+
+type clockKey struct{}
+
+// ContextWithClock returns a copy of ctx carrying c. Providers take their
+// timestamps and rate limiting from the clock of the context they are
+// called with, so each fetch can follow its own.
+func ContextWithClock(ctx context.Context, c Clock) context.Context {
+	return context.WithValue(ctx, clockKey{}, c)
+}
+
+// ClockFrom returns the clock carried by ctx, or the system clock
+func ClockFrom(ctx context.Context) Clock {
+	if c, ok := ctx.Value(clockKey{}).(Clock); ok && c != nil {
+		return c
+	}
+	return SystemClock{}
+}
@@ -17,6 +17,10 @@ type Target struct {
 	URL       string    `yaml:"url"`                  // Provider URL (MAL, TMDB, etc.)
 	FillerURL string    `yaml:"filler_url,omitempty"` // Optional filler source URL
 	Patterns  []Pattern `yaml:"patterns"`
+	Backup    *bool     `yaml:"backup,omitempty"`     // Overrides the global backup.enabled
+	BackupDir string    `yaml:"backup_dir,omitempty"` // Overrides the global backup.dir_name; resolved against the map file
+
+	NumberingBase *int `yaml:"numbering_base,omitempty"` // First local episode number: 1 (default) or 0
 }
 
 // Pattern represents input/output pattern configuration
@@ -27,20 +31,63 @@ type Pattern struct {
 
 // OutputConfig represents output format configuration
 type OutputConfig struct {
-	Fields    []string `yaml:"fields,flow"`
-	Separator string   `yaml:"separator,omitempty"`
-	Offset    int      `yaml:"offset,omitempty"`  // Episode number offset
-	Padding   int      `yaml:"padding,omitempty"` // Episode number padding (e.g. 2 -> 01, 3 -> 001)
+	Fields     []string `yaml:"fields,flow"`
+	Separator  string   `yaml:"separator,omitempty"`
+	Offset     int      `yaml:"offset,omitempty"`      // Episode number offset
+	Padding    int      `yaml:"padding,omitempty"`     // Episode number padding (e.g. 2 -> 01, 3 -> 001)
+	Romanize   bool     `yaml:"romanize,omitempty"`    // Transliterate SERIES_JP/EP_NAME_JP to romaji
+	Preset     string   `yaml:"preset,omitempty"`      // auto (default), episode or movie
+	ResFormat  string   `yaml:"res_format,omitempty"`  // p (default), k or original
+	DateFormat string   `yaml:"date_format,omitempty"` // AIR_DATE format, e.g. YYYY.MM.DD (default YYYY-MM-DD)
 }
 
+// Output presets. Auto switches episode-centric fields to the movie preset
+// when the provider reports a movie.
+const (
+	PresetAuto    = "auto"
+	PresetEpisode = "episode"
+	PresetMovie   = "movie"
+)
+
+// Resolution display forms for the RES field
+const (
+	ResFormatP        = "p"        // 2160p, 1080p
+	ResFormatK        = "k"        // 4K, 8K; lower resolutions as 1080p
+	ResFormatOriginal = "original" // As written in the original filename
+)
+
 // GlobalConfig represents the global configuration file (~/.config/autotitle/config.yml)
 type GlobalConfig struct {
-	MapFile  string        `yaml:"map_file"`
-	Patterns []Pattern     `yaml:"patterns"`
-	Formats  []string      `yaml:"formats"`
-	API      APIConfig     `yaml:"api"`
-	Backup   BackupConfig  `yaml:"backup"`
-	Tagging  TaggingConfig `yaml:"tagging"`
+	MapFile      string        `yaml:"map_file"`
+	Patterns     []Pattern     `yaml:"patterns"`
+	Formats      []string      `yaml:"formats"`
+	MusicFormats []string      `yaml:"music_formats"` // Used instead of formats for music targets
+	MangaFormats []string      `yaml:"manga_formats"` // Used instead of formats for manga targets
+	NovelFormats []string      `yaml:"novel_formats"` // Used instead of formats for light novel targets
+	API          APIConfig     `yaml:"api"`
+	Backup       BackupConfig  `yaml:"backup"`
+	Tagging      TaggingConfig `yaml:"tagging"`
+
+	Subtitles  SubtitleConfig   `yaml:"subtitles,omitempty"`
+	Thumbnails ThumbnailConfig  `yaml:"thumbnails,omitempty"`
+	Chapters   ChapterConfig    `yaml:"chapters,omitempty"`
+	Sort       SortConfig       `yaml:"sort,omitempty"`
+	TitleGuard TitleGuardConfig `yaml:"title_guard,omitempty"`
+	Matching   MatchingConfig   `yaml:"matching,omitempty"`
+	Cache      CacheConfig      `yaml:"cache,omitempty"`
+	Dupes      DupesConfig      `yaml:"dupes,omitempty"`
+	Watch      WatchConfig      `yaml:"watch,omitempty"`
+	Serve      ServeConfig      `yaml:"serve,omitempty"`
+	Events     EventsConfig     `yaml:"events,omitempty"`
+	Priority   PriorityConfig   `yaml:"priority,omitempty"`
+
+	MediaServers []MediaServer `yaml:"media_servers,omitempty"` // Rescanned after renames
+	Libraries    []Library     `yaml:"libraries,omitempty"`     // Named roots, usable instead of paths
+
+	IgnoreDirs []string    `yaml:"ignore_dirs"`           // Directory names/globs skipped by every scan
+	TitleRules []TitleRule `yaml:"title_rules,omitempty"` // Episode title cleanup, applied in order
+
+	Placeholders []PlaceholderDef `yaml:"placeholders,omitempty"` // Custom placeholders for patterns and fields
 }
 
 // Clone returns a deep copy of the configuration
@@ -64,6 +111,14 @@ func (t *Target) Clone() *Target {
 		return nil
 	}
 	res := *t
+	if t.Backup != nil {
+		enabled := *t.Backup
+		res.Backup = &enabled
+	}
+	if t.NumberingBase != nil {
+		base := *t.NumberingBase
+		res.NumberingBase = &base
+	}
 	if len(t.Patterns) > 0 {
 		res.Patterns = make([]Pattern, len(t.Patterns))
 		for i, p := range t.Patterns {
@@ -103,6 +158,129 @@ func (g *GlobalConfig) Clone() GlobalConfig {
 		res.Formats = make([]string, len(g.Formats))
 		copy(res.Formats, g.Formats)
 	}
+	if len(g.MusicFormats) > 0 {
+		res.MusicFormats = make([]string, len(g.MusicFormats))
+		copy(res.MusicFormats, g.MusicFormats)
+	}
+	if len(g.MangaFormats) > 0 {
+		res.MangaFormats = make([]string, len(g.MangaFormats))
+		copy(res.MangaFormats, g.MangaFormats)
+	}
+	if len(g.NovelFormats) > 0 {
+		res.NovelFormats = make([]string, len(g.NovelFormats))
+		copy(res.NovelFormats, g.NovelFormats)
+	}
+	if len(g.TitleRules) > 0 {
+		res.TitleRules = make([]TitleRule, len(g.TitleRules))
+		copy(res.TitleRules, g.TitleRules)
+	}
+	if len(g.Placeholders) > 0 {
+		res.Placeholders = make([]PlaceholderDef, len(g.Placeholders))
+		copy(res.Placeholders, g.Placeholders)
+	}
+	if len(g.Events.Sinks) > 0 {
+		res.Events.Sinks = make([]EventSink, len(g.Events.Sinks))
+		copy(res.Events.Sinks, g.Events.Sinks)
+	}
+	if len(g.MediaServers) > 0 {
+		res.MediaServers = make([]MediaServer, len(g.MediaServers))
+		for i, s := range g.MediaServers {
+			res.MediaServers[i] = s
+			if len(s.Paths) > 0 {
+				res.MediaServers[i].Paths = make(map[string]string, len(s.Paths))
+				for k, v := range s.Paths {
+					res.MediaServers[i].Paths[k] = v
+				}
+			}
+		}
+	}
+	if len(g.Libraries) > 0 {
+		res.Libraries = make([]Library, len(g.Libraries))
+		for i, l := range g.Libraries {
+			res.Libraries[i] = l
+			if len(l.Providers) > 0 {
+				res.Libraries[i].Providers = make([]string, len(l.Providers))
+				copy(res.Libraries[i].Providers, l.Providers)
+			}
+		}
+	}
+	if len(g.Dupes.PreferGroups) > 0 {
+		res.Dupes.PreferGroups = make([]string, len(g.Dupes.PreferGroups))
+		copy(res.Dupes.PreferGroups, g.Dupes.PreferGroups)
+	}
+	if len(g.IgnoreDirs) > 0 {
+		res.IgnoreDirs = make([]string, len(g.IgnoreDirs))
+		copy(res.IgnoreDirs, g.IgnoreDirs)
+	}
+	if len(g.API.Keys) > 0 {
+		res.API.Keys = make(map[string]string, len(g.API.Keys))
+		for k, v := range g.API.Keys {
+			res.API.Keys[k] = v
+		}
+	}
+	if len(g.API.Users) > 0 {
+		res.API.Users = make(map[string]string, len(g.API.Users))
+		for k, v := range g.API.Users {
+			res.API.Users[k] = v
+		}
+	}
+	if len(g.API.TrustedKeys) > 0 {
+		res.API.TrustedKeys = make([]string, len(g.API.TrustedKeys))
+		copy(res.API.TrustedKeys, g.API.TrustedKeys)
+	}
+	if len(g.API.SearchTimeouts) > 0 {
+		res.API.SearchTimeouts = make(map[string]int, len(g.API.SearchTimeouts))
+		for k, v := range g.API.SearchTimeouts {
+			res.API.SearchTimeouts[k] = v
+		}
+	}
+	if len(g.Tagging.Backend) > 0 {
+		res.Tagging.Backend = make([]string, len(g.Tagging.Backend))
+		copy(res.Tagging.Backend, g.Tagging.Backend)
+	}
+	if len(g.Tagging.Formats) > 0 {
+		res.Tagging.Formats = make(map[string]string, len(g.Tagging.Formats))
+		for k, v := range g.Tagging.Formats {
+			res.Tagging.Formats[k] = v
+		}
+	}
+	if len(g.Subtitles.Languages) > 0 {
+		res.Subtitles.Languages = make(map[string]string, len(g.Subtitles.Languages))
+		for k, v := range g.Subtitles.Languages {
+			res.Subtitles.Languages[k] = v
+		}
+	}
+	if g.Subtitles.Enabled != nil {
+		enabled := *g.Subtitles.Enabled
+		res.Subtitles.Enabled = &enabled
+	}
+	if len(g.API.BaseURLs) > 0 {
+		res.API.BaseURLs = make(map[string]string, len(g.API.BaseURLs))
+		for k, v := range g.API.BaseURLs {
+			res.API.BaseURLs[k] = v
+		}
+	}
+	return res
+}
+
+// NumberingShift returns what to add to a local episode number so that
+// releases numbered from 0 map to provider episodes, which start at 1
+func (t *Target) NumberingShift() int {
+	if t.NumberingBase == nil {
+		return 0
+	}
+	return 1 - *t.NumberingBase
+}
+
+// EffectiveBackup applies the target's backup overrides to the global backup settings
+func (t *Target) EffectiveBackup(global BackupConfig) BackupConfig {
+	res := global
+	if t.Backup != nil {
+		res.Enabled = *t.Backup
+	}
+	if t.BackupDir != "" {
+		res.DirName = t.BackupDir
+	}
 	return res
 }
 
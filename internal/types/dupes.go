@@ -0,0 +1,22 @@
+package types
+
+// DuplicateSet is a group of files with identical contents
+type DuplicateSet struct {
+	Hash  string   `json:"hash"`  // SHA-256 of the contents
+	Size  int64    `json:"size"`  // Size of each file in bytes
+	Files []string `json:"files"` // Paths; the first is the copy that is kept
+}
+
+// Wasted returns the bytes taken by the copies beyond the first
+func (d DuplicateSet) Wasted() int64 {
+	return d.Size * int64(len(d.Files)-1)
+}
+
+// DedupeAction is what happens to the redundant copies of a duplicate set
+type DedupeAction string
+
+const (
+	DedupeReport DedupeAction = "report" // Leave every copy alone
+	DedupeLink   DedupeAction = "link"   // Replace copies with hardlinks to the kept file
+	DedupeRemove DedupeAction = "remove" // Delete the copies
+)
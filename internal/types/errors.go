@@ -1,7 +1,12 @@
 // Package types defines custom error types for autotitle.
 package types
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
 
 // ErrPatternNotMatched indicates a filename didn't match any pattern
 type ErrPatternNotMatched struct {
@@ -87,3 +92,146 @@ type ErrBackupNotFound struct {
 func (e ErrBackupNotFound) Error() string {
 	return fmt.Sprintf("no backup found for: %s", e.Directory)
 }
+
+// ErrSnapshotRollback indicates a backup kept in a snapshot autotitle cannot
+// read files from or roll back itself; Hint says how to roll it back
+type ErrSnapshotRollback struct {
+	Directory string
+	Snapshot  Snapshot
+	Hint      string
+}
+
+func (e ErrSnapshotRollback) Error() string {
+	return fmt.Sprintf("the originals of %s are in %s snapshot %s; %s", e.Directory, e.Snapshot.Type, e.Snapshot.ID, e.Hint)
+}
+
+// ErrPatternTooComplex indicates an input pattern exceeds the matcher's complexity budget
+type ErrPatternTooComplex struct {
+	Pattern string
+	Reason  string
+}
+
+func (e ErrPatternTooComplex) Error() string {
+	return fmt.Sprintf("pattern %q is too complex: %s", e.Pattern, e.Reason)
+}
+
+// ErrRateLimited indicates an API kept rate limiting requests beyond the retry budget.
+// LastPage is the last page fetched successfully; the next fetch resumes after it.
+type ErrRateLimited struct {
+	Service  string
+	LastPage int
+}
+
+func (e ErrRateLimited) Error() string {
+	return fmt.Sprintf("%s rate limit exhausted after page %d; run again later to resume", e.Service, e.LastPage)
+}
+
+// ErrPartialFetch indicates a database fetch was cut short by Err (a rate
+// limit or cancellation) after the episodes fetched so far were saved; the
+// next fetch of the same media resumes from them
+type ErrPartialFetch struct {
+	Provider string
+	ID       string
+	Episodes int
+	Err      error
+}
+
+func (e ErrPartialFetch) Error() string {
+	return e.Err.Error()
+}
+
+func (e ErrPartialFetch) Unwrap() error {
+	return e.Err
+}
+
+// ErrRestoreConflict indicates restoring a backup would overwrite files created since the rename
+type ErrRestoreConflict struct {
+	Files []string
+}
+
+func (e ErrRestoreConflict) Error() string {
+	return fmt.Sprintf("restore would overwrite %d existing file(s): %s", len(e.Files), strings.Join(e.Files, ", "))
+}
+
+// ErrSearchTimeout indicates a provider did not answer a search in time
+type ErrSearchTimeout struct {
+	Provider string
+	Timeout  time.Duration
+}
+
+func (e ErrSearchTimeout) Error() string {
+	return fmt.Sprintf("%s search timed out after %s", e.Provider, e.Timeout)
+}
+
+// ErrSearchFailed indicates providers could not be searched (network down,
+// provider errors), so a missing or weak match says nothing about the query
+type ErrSearchFailed struct {
+	Query  string
+	Errors []error // One per provider that failed
+}
+
+func (e ErrSearchFailed) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("search for %q failed: %s", e.Query, strings.Join(msgs, "; "))
+}
+
+func (e ErrSearchFailed) Unwrap() []error {
+	return e.Errors
+}
+
+// ErrReviewItemNotFound indicates no parked sort group has the given ID
+type ErrReviewItemNotFound struct {
+	ID string
+}
+
+func (e ErrReviewItemNotFound) Error() string {
+	return fmt.Sprintf("review item not found: %s", e.ID)
+}
+
+// ErrInterrupted indicates a rename batch was cancelled part way; the files
+// renamed so far keep their new names and stay covered by the backup
+type ErrInterrupted struct {
+	Renamed   int
+	Remaining int
+}
+
+func (e ErrInterrupted) Error() string {
+	return fmt.Sprintf("interrupted after %d rename(s), %d left", e.Renamed, e.Remaining)
+}
+
+func (e ErrInterrupted) Unwrap() error {
+	return context.Canceled
+}
+
+// ErrBatchNotFound indicates no interrupted rename batch is journaled for the directory
+type ErrBatchNotFound struct {
+	Directory string
+}
+
+func (e ErrBatchNotFound) Error() string {
+	return fmt.Sprintf("no interrupted batch found for: %s", e.Directory)
+}
+
+// ErrTitleMismatch indicates the series title in the filenames is unlike
+// every title of the database entry the map file points at
+type ErrTitleMismatch struct {
+	Series     string  // Parsed from the filenames
+	Title      string  // Database title
+	Similarity float64 // 0-1, of the closest database title
+}
+
+func (e ErrTitleMismatch) Error() string {
+	return fmt.Sprintf("files are named %q, but the database title is %q (%.0f%% similar); check the URL in the map file", e.Series, e.Title, e.Similarity*100)
+}
+
+// ErrReadOnly indicates a write was refused because read-only mode is on
+type ErrReadOnly struct {
+	Op string
+}
+
+func (e ErrReadOnly) Error() string {
+	return fmt.Sprintf("read-only mode: refusing to %s", e.Op)
+}
@@ -0,0 +1,31 @@
+package types
+
+import "time"
+
+// HistoryEntry records one completed rename run in the local history ledger
+type HistoryEntry struct {
+	Time     time.Time `json:"time"`
+	Dir      string    `json:"dir"`
+	Provider string    `json:"provider"`
+	ID       string    `json:"id"`
+	Series   string    `json:"series"`
+	Files    int       `json:"files"` // Files successfully renamed
+}
+
+// SeriesUsage is the all-time rename count for one series
+type SeriesUsage struct {
+	Provider string `json:"provider"`
+	ID       string `json:"id"`
+	Series   string `json:"series"`
+	Files    int    `json:"files"`
+}
+
+// UsageStats summarizes the local history ledger
+type UsageStats struct {
+	Runs      int           `json:"runs"`
+	Files     int           `json:"files"`
+	First     time.Time     `json:"first"`
+	Last      time.Time     `json:"last"`
+	TopSeries []SeriesUsage `json:"top_series"` // Most renamed first
+	TimeSaved time.Duration `json:"time_saved"` // Estimate against renaming by hand
+}
@@ -1,7 +1,10 @@
 // Package types defines interfaces for autotitle components.
 package types
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // Provider is the core abstraction for data sources (anime, movies, TV, etc.)
 type Provider interface {
@@ -33,14 +36,60 @@ type Provider interface {
 	Search(ctx context.Context, query string) ([]SearchResult, error)
 }
 
+// KeyedProvider is an optional interface for providers that need an API key.
+// The key is read from api.keys.<name> in the global config.
+type KeyedProvider interface {
+	// RequiresAPIKey returns true if the provider cannot work without a key
+	RequiresAPIKey() bool
+}
+
+// ResumableProvider is an optional interface for providers that can continue
+// an interrupted fetch. When rate limiting or cancellation aborts a fetch,
+// FetchMediaFrom returns the partial media (with ResumePage set) alongside
+// ErrRateLimited or the context error.
+type ResumableProvider interface {
+	// FetchMediaFrom fetches media data, continuing from partial if non-nil
+	FetchMediaFrom(ctx context.Context, id string, partial *Media) (*Media, error)
+}
+
+// CacheValidator is an optional interface for providers whose cached entries
+// can be unusable for reasons other than age, e.g. a database file cached
+// before api.trusted_keys was set. An invalid entry is fetched again.
+type CacheValidator interface {
+	// ValidCache reports whether a cached entry may still be used
+	ValidCache(media *Media) bool
+}
+
+// SchemaReporter is an optional interface for providers and filler sources
+// whose parser targets one version of an API or layout of a scraped page
+type SchemaReporter interface {
+	// Schema returns what the parser targets and when it was last checked
+	Schema() Schema
+}
+
+// Schema is the API version or page layout a parser was written against
+type Schema struct {
+	Target  string `json:"target"`  // e.g. "Jikan API v4"
+	Checked string `json:"checked"` // Date (YYYY-MM-DD) the parser last matched it
+}
+
+// HealthChecker is an optional interface for providers and filler sources
+// that can check, with a cheap live request, that their parser still
+// understands the response
+type HealthChecker interface {
+	HealthCheck(ctx context.Context) error
+}
+
 // SearchResult represents a normalized search response
 type SearchResult struct {
-	Provider string
-	ID       string
-	Title    string
-	Year     int
-	URL      string
-	Error    error
+	Provider string    `json:"provider"`
+	ID       string    `json:"id"`
+	Title    string    `json:"title"`
+	Year     int       `json:"year,omitempty"`
+	Type     MediaType `json:"type,omitempty"`
+	URL      string    `json:"url"`
+	Error    error     `json:"-"`
+	TimedOut bool      `json:"-"` // Provider missed its search timeout; Error is ErrSearchTimeout
 }
 
 // FillerSource is a source for filler episode data (decoupled from providers)
@@ -99,26 +148,74 @@ type MediaSummary struct {
 	EpisodeCount int    `json:"episode_count"`
 }
 
+// DatabaseStats summarizes the contents of the media database
+type DatabaseStats struct {
+	Providers     map[string]int `json:"providers"` // Series count by provider
+	Series        int            `json:"series"`
+	Episodes      int            `json:"episodes"`
+	DiskUsage     int64          `json:"disk_usage"` // Bytes
+	Oldest        time.Time      `json:"oldest"`
+	Newest        time.Time      `json:"newest"`
+	DueForRefresh []MediaSummary `json:"due_for_refresh"` // Airing series a fetch would update
+}
+
+// CacheStats reports the size of the database cache against its cap
+type CacheStats struct {
+	Path        string    `json:"path"`
+	Files       int       `json:"files"`
+	Size        int64     `json:"size"`     // Bytes
+	MaxSize     int64     `json:"max_size"` // cache.max_size in bytes, 0 for no cap
+	LeastRecent time.Time `json:"least_recent"`
+	MostRecent  time.Time `json:"most_recent"`
+}
+
 // BackupManager handles file backup/restore operations
 type BackupManager interface {
 	// Backup creates a backup of files before renaming
-	// mappings is oldName -> newName
-	Backup(ctx context.Context, dir string, mappings map[string]string) error
+	// mappings is oldName -> newName, episodes is oldName -> episode number (may be nil)
+	Backup(ctx context.Context, dir string, mappings map[string]string, episodes map[string]int) error
+
+	// Estimate reports how Backup would store mappings without touching any files
+	Estimate(ctx context.Context, dir string, mappings map[string]string) ([]BackupEstimate, error)
 
 	// Restore restores files from the backup
 	Restore(ctx context.Context, dir string) error
 
+	// RestoreOnly restores the entries selected by keep, leaving the rest renamed and backed up
+	RestoreOnly(ctx context.Context, dir string, keep func(RestoreEntry) bool) error
+
+	// Plan reports what Restore would do without touching any files
+	Plan(ctx context.Context, dir string) ([]RestoreEntry, error)
+
+	// Remap records that renamed files were renamed again (current -> new name)
+	Remap(ctx context.Context, dir string, renames map[string]string) error
+
+	// Trim drops the entries not selected by keep, e.g. renames that never happened
+	Trim(ctx context.Context, dir string, keep func(RestoreEntry) bool) error
+
+	// Extend adds files to the existing backup, creating one if there is none
+	Extend(ctx context.Context, dir string, mappings map[string]string, episodes map[string]int) error
+
 	// Clean removes the backup for a specific directory
 	Clean(ctx context.Context, dir string) error
 
 	// WithEvents sets the event handler for progress updates
 	WithEvents(h EventHandler) BackupManager
 
+	// WithClock sets the clock used for backup timestamps
+	WithClock(c Clock) BackupManager
+
+	// WithRateLimit caps copies at mbps megabytes per second (0 for no cap)
+	WithRateLimit(mbps float64) BackupManager
+
 	// ListAll returns all backup records (global)
 	ListAll(ctx context.Context) ([]BackupRecord, error)
 
 	// CleanAll removes all backups globally
 	CleanAll(ctx context.Context) error
+
+	// Verify checks that every registered backup can still be restored
+	Verify(ctx context.Context) ([]BackupReport, error)
 }
 
 // ConfigRepository handles configuration loading and saving
@@ -132,3 +229,15 @@ type ConfigRepository interface {
 	// Validate validates configuration
 	Validate(cfg *Config) error
 }
+
+// SimilarityMetric scores how alike two titles are, e.g. the series name in
+// filenames and a provider title. It is used to rank search results for
+// sort, to decide which groups sort moves unattended, and by the title guard.
+type SimilarityMetric interface {
+	// Name identifies the metric in matching.similarity
+	Name() string
+
+	// Score returns 0 (unrelated) to 1 (same) for two normalized titles:
+	// lower case letters and digits separated by single spaces, never empty
+	Score(a, b string) float64
+}
@@ -0,0 +1,12 @@
+package types
+
+import "time"
+
+// Alias is a series name the user matched by hand, reused by later runs
+// instead of searching again
+type Alias struct {
+	Name     string       `json:"name"` // Series name as parsed from the filenames
+	Match    SearchResult `json:"match"`
+	Patterns []string     `json:"patterns,omitempty"` // Input patterns the user settled on
+	Learned  time.Time    `json:"learned"`
+}
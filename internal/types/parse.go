@@ -0,0 +1,22 @@
+package types
+
+// ParsedFilename is the breakdown of a release filename. The JSON keys follow
+// anitopy's element names so tools built on it can read the output as is.
+type ParsedFilename struct {
+	FileName        string   `json:"file_name"`
+	FileExtension   string   `json:"file_extension,omitempty"`
+	AnimeTitle      string   `json:"anime_title,omitempty"`
+	AnimeSeason     string   `json:"anime_season,omitempty"`
+	AnimeYear       string   `json:"anime_year,omitempty"`
+	EpisodeNumber   string   `json:"episode_number,omitempty"`
+	EpisodeTitle    string   `json:"episode_title,omitempty"`
+	Part            string   `json:"part,omitempty"` // Split movie part, not an anitopy element
+	ReleaseGroup    string   `json:"release_group,omitempty"`
+	ReleaseVersion  string   `json:"release_version,omitempty"`
+	VideoResolution string   `json:"video_resolution,omitempty"`
+	Source          string   `json:"source,omitempty"`
+	AudioTerm       string   `json:"audio_term,omitempty"`
+	Language        []string `json:"language,omitempty"`
+	FileChecksum    string   `json:"file_checksum,omitempty"`
+	Pattern         string   `json:"pattern,omitempty"` // Input pattern guessed for the file
+}
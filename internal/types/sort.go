@@ -0,0 +1,28 @@
+package types
+
+import "time"
+
+// SortGroup is a set of loose files from a dump directory that belong to one series
+type SortGroup struct {
+	Name       string        `json:"name"`             // Series name parsed from the filenames
+	Files      []string      `json:"files"`            // File names in the dump directory
+	Patterns   []string      `json:"patterns"`         // Input patterns guessed from the files
+	Match      *SearchResult `json:"match,omitempty"`  // Best provider match, nil if none
+	Confidence float64       `json:"confidence"`       // 0..1 similarity of Match to Name
+	Folder     string        `json:"folder,omitempty"` // Destination folder
+	Error      string        `json:"error,omitempty"`  // Why the group was not sorted
+	Parked     bool          `json:"parked,omitempty"` // Below the confidence threshold; queued for review
+	// InPlace groups are a series folder of their own, set up by watch mode:
+	// the files stay where they are and Folder is their directory
+	InPlace bool `json:"in_place,omitempty"`
+}
+
+// ReviewItem is a sort group parked for review because its best match was
+// below the confidence threshold
+type ReviewItem struct {
+	ID         string         `json:"id"`
+	Dir        string         `json:"dir"` // Directory holding the files: the dump directory, or the folder of an InPlace group
+	Group      SortGroup      `json:"group"`
+	Candidates []SearchResult `json:"candidates,omitempty"` // Best matches first
+	Added      time.Time      `json:"added"`
+}
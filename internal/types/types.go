@@ -1,7 +1,10 @@
 // Package types defines core domain types used throughout autotitle.
 package types
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 // MediaType represents the type of media content
 type MediaType string
@@ -10,15 +13,28 @@ const (
 	MediaTypeAnime  MediaType = "anime"
 	MediaTypeMovie  MediaType = "movie"
 	MediaTypeTVShow MediaType = "tvshow"
+	MediaTypeMusic  MediaType = "music" // Albums/OSTs; episodes are tracks
+	MediaTypeManga  MediaType = "manga" // Episodes are chapters
+	MediaTypeNovel  MediaType = "novel" // Light novels; episodes are volumes
 )
 
+// Video reports whether media of this type are video files
+func (t MediaType) Video() bool {
+	return t != MediaTypeMusic && t != MediaTypeManga && t != MediaTypeNovel
+}
+
 // Episode represents a single episode in a series
 type Episode struct {
-	Number   int    `json:"number"`
-	Title    string `json:"title"`
-	IsFiller bool   `json:"is_filler,omitempty"`
-	IsMixed  bool   `json:"is_mixed,omitempty"`
-	AirDate  string `json:"air_date,omitempty"`
+	Number      int    `json:"number"`
+	Title       string `json:"title"`
+	TitleJP     string `json:"title_jp,omitempty"`
+	TitleRomaji string `json:"title_romaji,omitempty"` // Provider-supplied romanization of TitleJP
+	IsFiller    bool   `json:"is_filler,omitempty"`
+	IsMixed     bool   `json:"is_mixed,omitempty"`
+	AirDate     string `json:"air_date,omitempty"`
+	Watched     bool   `json:"watched,omitempty"` // Seen by the configured provider account (Trakt)
+	Artist      string `json:"artist,omitempty"`  // Track artist (music)
+	Volume      string `json:"volume,omitempty"`  // Volume the chapter is collected in (manga)
 }
 
 // Media is the unified type for all content (anime, movies, TV shows)
@@ -31,30 +47,280 @@ type Media struct {
 	Slug               string    `json:"slug,omitempty"`
 	Aliases            []string  `json:"aliases,omitempty"`
 	Type               MediaType `json:"type"`
+	Year               int       `json:"year,omitempty"`   // Premiere year
+	Season             string    `json:"season,omitempty"` // Premiere season: Winter, Spring, Summer or Fall
 	Status             string    `json:"status,omitempty"`
 	NextEpisodeAirDate *string   `json:"next_episode_air_date,omitempty"`
+	AirTimeZone        string    `json:"air_time_zone,omitempty"` // IANA zone the air dates are local to
 	EpisodeCount       int       `json:"episode_count,omitempty"`
 	FillerSource       string    `json:"filler_source,omitempty"`
+	FillerURL          string    `json:"filler_url,omitempty"` // Filler list the flags were taken from
+	SignedBy           string    `json:"signed_by,omitempty"`  // Trusted key that signed a database file (url provider)
 	LastUpdate         time.Time `json:"last_update"`
 	Episodes           []Episode `json:"episodes,omitempty"`
+	ResumePage         int       `json:"resume_page,omitempty"` // Next page to fetch for an interrupted fetch
+}
+
+// Renumbering describes episodes a provider removed or moved to another
+// number between two fetches, e.g. when recaps are merged
+type Renumbering struct {
+	Removed  []int // Numbers no longer listed
+	Moved    []int // Old numbers whose title is now listed under another number
+	OldCount int
+	NewCount int
 }
 
 // APIConfig holds API-related settings
 type APIConfig struct {
-	RateLimit float64 `yaml:"rate_limit"` // Requests per second
-	Timeout   int     `yaml:"timeout"`    // Seconds
+	RateLimit float64           `yaml:"rate_limit"`          // Requests per second
+	Timeout   int               `yaml:"timeout"`             // Seconds
+	Keys      map[string]string `yaml:"keys,omitempty"`      // API keys by provider name
+	BaseURLs  map[string]string `yaml:"base_urls,omitempty"` // API endpoint overrides by provider name (mirrors, testing)
+	Users     map[string]string `yaml:"users,omitempty"`     // Account names by provider name, for watch state
+
+	TrustedKeys []string `yaml:"trusted_keys,omitempty"` // minisign public keys database file URLs must be signed with
+
+	SearchTimeout  int            `yaml:"search_timeout,omitempty"`  // Seconds a provider may take to answer a search
+	SearchTimeouts map[string]int `yaml:"search_timeouts,omitempty"` // Per-provider overrides of SearchTimeout
+}
+
+// SearchTimeoutFor returns the search timeout of a provider (default 10s)
+func (c APIConfig) SearchTimeoutFor(provider string) time.Duration {
+	if s := c.SearchTimeouts[provider]; s > 0 {
+		return time.Duration(s) * time.Second
+	}
+	if c.SearchTimeout > 0 {
+		return time.Duration(c.SearchTimeout) * time.Second
+	}
+	return 10 * time.Second
 }
 
 // BackupConfig holds backup-related settings
 type BackupConfig struct {
-	Enabled bool   `yaml:"enabled"`
-	DirName string `yaml:"dir_name"`
+	Enabled  bool           `yaml:"enabled"`
+	DirName  string         `yaml:"dir_name"`
+	MaxMBps  float64        `yaml:"max_mbps,omitempty"` // Cap on backup copy speed, 0 for none
+	Snapshot SnapshotConfig `yaml:"snapshot,omitempty"`
+}
+
+// SnapshotConfig backs up a directory by snapshotting its filesystem before
+// renaming instead of linking or copying each file, for directories too big
+// to copy. The backup dir then only holds the mappings.
+type SnapshotConfig struct {
+	Type string `yaml:"type,omitempty"` // btrfs, zfs or command; "" backs up files
+
+	// Shell commands of the command type, run with $AUTOTITLE_DIR set to the
+	// directory, and $AUTOTITLE_SNAPSHOT to the snapshot for rollback and delete
+	Create   string `yaml:"create,omitempty"`   // Takes a snapshot and prints its ID
+	Rollback string `yaml:"rollback,omitempty"` // Rolls the directory back; undo only explains how without it
+	Delete   string `yaml:"delete,omitempty"`   // Removes the snapshot once the backup is cleaned
+}
+
+// Snapshot types
+const (
+	SnapshotBtrfs   = "btrfs"
+	SnapshotZFS     = "zfs"
+	SnapshotCommand = "command"
+)
+
+// BtrfsSnapshotDir holds the btrfs snapshots, at the root of the subvolume
+const BtrfsSnapshotDir = ".autotitle-snapshots"
+
+// Snapshot is the filesystem snapshot holding the originals of a backup
+type Snapshot struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`             // Snapshot subvolume, dataset@name, or what the create command printed
+	View string `json:"view,omitempty"` // The directory as the snapshot has it, if mounted
+}
+
+// MediaServer is a Plex or Jellyfin server asked to rescan folders after
+// renames
+type MediaServer struct {
+	Type  string            `yaml:"type"`            // plex or jellyfin
+	URL   string            `yaml:"url"`             // e.g. http://nas:32400
+	Token string            `yaml:"token"`           // Plex token or Jellyfin API key
+	Paths map[string]string `yaml:"paths,omitempty"` // Local path prefix -> path the server sees
+}
+
+// Library is a named root of the collection (Anime, TV, Movies), accepted by
+// commands in place of its path. Its defaults apply to every folder under it.
+type Library struct {
+	Name      string   `yaml:"name"`
+	Path      string   `yaml:"path"`                // As written; ~/ and ${VAR} are expanded into Root
+	Root      string   `yaml:"-"`                   // Absolute root, resolved from Path on load
+	Preset    string   `yaml:"preset,omitempty"`    // Output preset for patterns that set none
+	Providers []string `yaml:"providers,omitempty"` // Searched by sort and init instead of all
+}
+
+// Media server types
+const (
+	MediaServerPlex     = "plex"
+	MediaServerJellyfin = "jellyfin"
+)
+
+// PriorityConfig lowers the CPU and IO priority of the whole process so
+// renames and backup copies don't starve other users of the disk (Linux)
+type PriorityConfig struct {
+	Nice    int    `yaml:"nice,omitempty"`     // CPU niceness, 0 to 19
+	IOClass string `yaml:"io_class,omitempty"` // "idle" or "best-effort" (lowest level)
+}
+
+// IO scheduling classes
+const (
+	IOClassIdle       = "idle"
+	IOClassBestEffort = "best-effort"
+)
+
+// TitleRule rewrites episode titles matching a regular expression before they
+// are rendered as EP_NAME. Replace may use $1-style group references.
+type TitleRule struct {
+	Match   string `yaml:"match"`
+	Replace string `yaml:"replace"`
+}
+
+// PlaceholderDef defines a custom placeholder such as {{CRC}}: in input
+// patterns it matches Regex, and as an output field it renders what was
+// matched, optionally passed through Transform ("upper" or "lower").
+type PlaceholderDef struct {
+	Name      string `yaml:"name"`
+	Regex     string `yaml:"regex"`
+	Transform string `yaml:"transform,omitempty"`
 }
 
 // TaggingConfig holds metadata tagging settings
 type TaggingConfig struct {
 	// Enabled controls MKV metadata tagging. If nil, auto-detect mkvpropedit.
 	Enabled *bool `yaml:"enabled,omitempty"`
+
+	// Backend lists the tagging backends to try, in order of preference
+	// (mkvpropedit, atomicparsley, ffmpeg, epub, ebook-meta); each file uses the first one
+	// that handles its extension and is installed
+	Backend []string `yaml:"backend,omitempty"`
+
+	// Formats pins file extensions to a backend, or to none to never tag them
+	Formats map[string]string `yaml:"formats,omitempty"`
+}
+
+// SubtitleConfig controls renaming subtitles alongside their video
+type SubtitleConfig struct {
+	// Enabled renames subtitles sharing the video's name. If nil, enabled.
+	Enabled *bool `yaml:"enabled,omitempty"`
+	// Codes is the language code style: "639-2" (eng, default) or "639-1" (en)
+	Codes string `yaml:"codes,omitempty"`
+	// Languages maps extra language tags to codes, e.g. "castellano": "spa"
+	Languages map[string]string `yaml:"languages,omitempty"`
+}
+
+// ThumbnailConfig controls extracting a thumbnail for each renamed episode
+type ThumbnailConfig struct {
+	// Enabled writes "<episode>-thumb.jpg" next to each renamed video (needs ffmpeg)
+	Enabled bool `yaml:"enabled,omitempty"`
+	// At is where the frame is taken: a share of the runtime ("10%", the
+	// default), seconds ("90s") or a timestamp ("00:01:30")
+	At string `yaml:"at,omitempty"`
+	// Overwrite replaces existing thumbnails instead of keeping them
+	Overwrite bool `yaml:"overwrite,omitempty"`
+}
+
+// ChapterConfig controls writing opening/ending chapters into renamed MKVs
+type ChapterConfig struct {
+	// Enabled detects the opening and ending of each renamed MKV and writes
+	// them as chapters (needs ffmpeg and mkvpropedit)
+	Enabled bool `yaml:"enabled,omitempty"`
+	// Window is how much of the start and end is searched, e.g. "6m" (default)
+	Window string `yaml:"window,omitempty"`
+	// Overwrite replaces chapters a file already has instead of keeping them
+	Overwrite bool `yaml:"overwrite,omitempty"`
+}
+
+// CacheConfig bounds the cache directory (~/.cache/autotitle)
+type CacheConfig struct {
+	// MaxSize caps the database cache, e.g. "200MB". When a fetch pushes it
+	// past the cap, the least recently used entries are evicted.
+	MaxSize string `yaml:"max_size,omitempty"`
+}
+
+// SortConfig controls `autotitle sort`
+type SortConfig struct {
+	// MinConfidence is the match confidence (0-1) needed to sort a group
+	// unattended; weaker matches are parked for `autotitle review`
+	MinConfidence float64 `yaml:"min_confidence,omitempty"`
+}
+
+// MatchingConfig tunes fuzzy title matching
+type MatchingConfig struct {
+	// Similarity names the metric titles are compared with: dice (default),
+	// levenshtein, jaro-winkler, token-set, or one registered by a program
+	// using autotitle as a library
+	Similarity string `yaml:"similarity,omitempty"`
+}
+
+// TitleGuardConfig checks, before renaming, that the series title in the
+// filenames resembles a title of the database entry, so a wrong URL pasted
+// into a map file does not rename a whole season after another show
+type TitleGuardConfig struct {
+	// MinSimilarity (0-1) below which the titles are taken as different
+	MinSimilarity float64 `yaml:"min_similarity,omitempty"`
+	// Action on a mismatch: confirm (default) asks first, warn carries on
+	Action string `yaml:"action,omitempty"`
+}
+
+// Title guard actions
+const (
+	TitleGuardConfirm = "confirm"
+	TitleGuardWarn    = "warn"
+	TitleGuardOff     = "off"
+)
+
+// WatchConfig tunes `autotitle watch`
+type WatchConfig struct {
+	// Settle is how many seconds a folder's files must stay unchanged
+	// before it is renamed, so downloads still being written are left be
+	Settle int `yaml:"settle,omitempty"`
+}
+
+// ServeConfig sets up the HTTP server of `autotitle watch`
+type ServeConfig struct {
+	// Listen is the address served on, e.g. 127.0.0.1:7979; "" serves nothing
+	Listen string `yaml:"listen,omitempty"`
+	// Token is the bearer token of every endpoint but the probes;
+	// $AUTOTITLE_TOKEN overrides it. Required beyond loopback.
+	Token   string   `yaml:"token,omitempty"`
+	TLSCert string   `yaml:"tls_cert,omitempty"` // PEM certificate; serves HTTPS with TLSKey
+	TLSKey  string   `yaml:"tls_key,omitempty"`
+	Allow   []string `yaml:"allow,omitempty"` // IPs and CIDRs clients may connect from; none allows all
+}
+
+// DupesConfig controls `autotitle dupes`
+type DupesConfig struct {
+	// PreferGroups ranks release groups, best first. In a duplicate set the
+	// file from the best-ranked group is kept.
+	PreferGroups []string `yaml:"prefer_groups,omitempty"`
+}
+
+// EventsConfig routes progress events to extra sinks, e.g. for daemon
+// deployments that log through syslog or journald
+type EventsConfig struct {
+	Sinks []EventSink `yaml:"sinks,omitempty"`
+}
+
+// Event sink types
+const (
+	SinkStderr   = "stderr"   // Plain timestamped lines on stderr
+	SinkJSON     = "json"     // JSON lines appended to a file
+	SinkSyslog   = "syslog"   // The local syslog socket
+	SinkJournald = "journald" // The systemd journal
+	SinkWebhook  = "webhook"  // Signed POSTs of each batch manifest
+)
+
+// EventSink configures one destination for events
+type EventSink struct {
+	Type   string `yaml:"type"`             // stderr, json, syslog, journald or webhook
+	Path   string `yaml:"path,omitempty"`   // File written by the json sink
+	Level  string `yaml:"level,omitempty"`  // Least severe event type sent (default info)
+	Tag    string `yaml:"tag,omitempty"`    // syslog/journald identifier (default autotitle)
+	URL    string `yaml:"url,omitempty"`    // Endpoint of the webhook sink
+	Secret string `yaml:"secret,omitempty"` // HMAC-SHA256 key signing the webhook body
 }
 
 // GetTitle returns the requested title variant with fallback to default
@@ -92,6 +358,18 @@ const (
 	StatusFailed  OperationStatus = "failed"
 )
 
+// SkipReason explains why a file was skipped or failed
+type SkipReason string
+
+const (
+	ReasonNoPattern SkipReason = "no_pattern" // No input pattern matched the filename
+	ReasonNoEpisode SkipReason = "no_episode" // Matched episode is not in the database
+	ReasonCollision SkipReason = "collision"  // Another file already claimed the target name
+	ReasonUnchanged SkipReason = "unchanged"  // File already has the target name
+	ReasonLocked    SkipReason = "locked"     // File could not be renamed due to permissions or locks
+	ReasonError     SkipReason = "error"      // Any other rename failure
+)
+
 // RenameOperation represents a planned or completed file rename
 type RenameOperation struct {
 	SourcePath string          `json:"source_path"`
@@ -99,7 +377,29 @@ type RenameOperation struct {
 	Episode    *Episode        `json:"episode,omitempty"`
 	Series     string          `json:"series,omitempty"` // Series title (populated after match)
 	Status     OperationStatus `json:"status"`
+	Reason     SkipReason      `json:"reason,omitempty"` // Set for skipped and failed operations
 	Error      string          `json:"error,omitempty"`
+
+	// Set by dry-runs: what a real run would do besides renaming
+	TagBackend  string       `json:"tag_backend,omitempty"`  // Tagger backend that would tag the file
+	Backup      BackupMethod `json:"backup,omitempty"`       // How the original would be backed up
+	BackupBytes int64        `json:"backup_bytes,omitempty"` // Extra disk space the backup would take
+}
+
+// BackupMethod is how a backup stores an original file
+type BackupMethod string
+
+const (
+	BackupLink     BackupMethod = "link"     // Hard link, no extra disk space
+	BackupCopy     BackupMethod = "copy"     // Full copy, when the backup is on another filesystem
+	BackupSnapshot BackupMethod = "snapshot" // In a filesystem snapshot (backup.snapshot)
+)
+
+// BackupEstimate is how Backup would store one file
+type BackupEstimate struct {
+	Name   string       `json:"name"` // Original file name
+	Method BackupMethod `json:"method"`
+	Bytes  int64        `json:"bytes"` // Extra disk space, 0 for links
 }
 
 // BackupRecord tracks a backup in the global registry
@@ -107,8 +407,34 @@ type BackupRecord struct {
 	Path      string    `json:"path"`       // Full path to backup dir
 	SourceDir string    `json:"source_dir"` // Original directory
 	Timestamp time.Time `json:"timestamp"`
+	Snapshot  *Snapshot `json:"snapshot,omitempty"` // Holds the originals instead of the backup dir
+}
+
+// BackupReport is the result of verifying one registered backup
+type BackupReport struct {
+	Record   BackupRecord `json:"record"`
+	Files    int          `json:"files"`              // Backed-up files listed in the mappings
+	Problems []string     `json:"problems,omitempty"` // Empty when the backup can be restored
 }
 
+// RestoreEntry describes what restoring one backed-up file would do
+type RestoreEntry struct {
+	Original string `json:"original"` // Name restored from the backup
+	Renamed  string `json:"renamed"`  // Current name, removed after restore
+	Episode  int    `json:"episode"`  // Episode number, 0 if unknown
+	Conflict bool   `json:"conflict"` // A different file now exists under Original
+}
+
+// ConflictAction is how undo resolves a restore onto a file created under
+// the original name since the rename
+type ConflictAction string
+
+const (
+	ConflictKeepNew  ConflictAction = "keep-new"  // Leave the new file; the entry stays renamed and backed up
+	ConflictRestore  ConflictAction = "restore"   // Replace the new file with the original
+	ConflictKeepBoth ConflictAction = "keep-both" // Restore the original under a numbered name beside it
+)
+
 // EventType represents the type of progress event
 type EventType string
 
@@ -127,5 +453,59 @@ type Event struct {
 	Data    any       `json:"data,omitempty"`
 }
 
+// CopyProgress is the Data of EventProgress events emitted while copying a file
+type CopyProgress struct {
+	File   string `json:"file"`
+	Copied int64  `json:"copied"` // Bytes copied so far
+	Total  int64  `json:"total"`  // File size in bytes
+}
+
+// FetchProgress is the Data of EventProgress events emitted while fetching episode pages
+type FetchProgress struct {
+	Page     int           `json:"page"`
+	LastPage int           `json:"last_page"` // 0 if the provider doesn't report it
+	Episodes int           `json:"episodes"`  // Episodes fetched so far
+	ETA      time.Duration `json:"eta"`       // Estimated from the rate limit
+}
+
+// ConfigReload is the Data of the EventInfo emitted when watch mode reloads
+// the global config or a map file after an edit
+type ConfigReload struct {
+	Path    string   `json:"path"`
+	Changed []string `json:"changed,omitempty"` // Top-level keys whose values changed
+}
+
+// BatchManifest is the Data of the EventInfo emitted after a batch renamed
+// files, for audit trails such as the webhook sink
+type BatchManifest struct {
+	Directory string            `json:"directory"`
+	Started   time.Time         `json:"started"`
+	Finished  time.Time         `json:"finished"`
+	Mappings  []ManifestMapping `json:"mappings"`
+}
+
+// ManifestMapping is one rename of a BatchManifest, with the SHA-256 of
+// each name so a receiver can match them without storing the names
+type ManifestMapping struct {
+	From       string `json:"from"`
+	To         string `json:"to"`
+	FromSHA256 string `json:"from_sha256"`
+	ToSHA256   string `json:"to_sha256"`
+}
+
+type progressKey struct{}
+
+// ContextWithProgress returns a copy of ctx carrying h, which providers that
+// fetch in pages call after each page
+func ContextWithProgress(ctx context.Context, h func(FetchProgress)) context.Context {
+	return context.WithValue(ctx, progressKey{}, h)
+}
+
+// ProgressFrom returns the progress handler carried by ctx, or nil
+func ProgressFrom(ctx context.Context) func(FetchProgress) {
+	h, _ := ctx.Value(progressKey{}).(func(FetchProgress))
+	return h
+}
+
 // EventHandler receives progress events during operations
 type EventHandler func(Event)
@@ -5,7 +5,6 @@ import (
 
 	"github.com/charmbracelet/huh"
 	"github.com/mydehq/autotitle/internal/matcher"
-	"github.com/mydehq/autotitle/internal/types"
 	"gopkg.in/yaml.v3"
 )
 
@@ -18,6 +17,8 @@ func buildFilenamePreview(outputFields []string, separator string) string {
 		EpNum:    "1",
 		EpName:   "The Day I Became a Shinigami",
 		Res:      "1080p",
+		Year:     "2004",
+		AirDate:  "2004-10-05",
 		Ext:      "mkv",
 	}
 
@@ -30,14 +31,19 @@ func buildFilenamePreview(outputFields []string, separator string) string {
 }
 
 // showPreviewAndConfirm marshals the config to YAML and shows a confirmation prompt.
-func showPreviewAndConfirm(cfg *types.Config, theme *huh.Theme) (bool, error) {
+// cfg may be a map file (*types.Config) or a global config (*types.GlobalConfig).
+func showPreviewAndConfirm(cfg any, theme *huh.Theme) (bool, error) {
 	data, err := yaml.Marshal(cfg)
 	if err != nil {
 		return false, fmt.Errorf("failed to preview config: %w", err)
 	}
+	return confirmPreview(data, theme)
+}
 
+// confirmPreview shows the config YAML in data and asks whether to write it
+func confirmPreview(data []byte, theme *huh.Theme) (bool, error) {
 	confirmed := true
-	err = RunForm(huh.NewForm(
+	err := RunForm(huh.NewForm(
 		huh.NewGroup(
 			huh.NewNote().
 				Title("Configuration Preview").
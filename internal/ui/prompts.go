@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/charmbracelet/huh"
+	"github.com/mydehq/autotitle/internal/config"
 	"github.com/mydehq/autotitle/internal/matcher"
 	"github.com/mydehq/autotitle/internal/provider"
 )
@@ -142,17 +143,28 @@ func selectInputPatterns(detected []string, theme *huh.Theme) ([]string, error)
 	}
 }
 
+// outputPreset is a named set of output fields offered by the wizards
+type outputPreset struct {
+	name   string
+	fields []string
+}
+
+// outputPresets lists the built-in output formats; a nil fields entry means custom input
+var outputPresets = []outputPreset{
+	{"Default", []string{"E", "+", "EP_NUM", "FILLER", "-", "EP_NAME"}},
+	{"Minimal", []string{"EP_NUM", "-", "EP_NAME"}},
+	{"Full", []string{"SERIES", "-", "EP_NUM", "-", "EP_NAME"}},
+	{"Custom", nil},
+}
+
+// moviePreset is offered first when the selected media is a movie
+var moviePreset = outputPreset{"Movie", config.MovieFields()}
+
 // selectOutputFields implements the output field preset selection step.
-func selectOutputFields(theme *huh.Theme) ([]string, error) {
-	type preset struct {
-		name   string
-		fields []string
-	}
-	presets := []preset{
-		{"Default", []string{"E", "+", "EP_NUM", "FILLER", "-", "EP_NAME"}},
-		{"Minimal", []string{"EP_NUM", "-", "EP_NAME"}},
-		{"Full", []string{"SERIES", "-", "EP_NUM", "-", "EP_NAME"}},
-		{"Custom", nil},
+func selectOutputFields(theme *huh.Theme, movie bool) ([]string, error) {
+	presets := outputPresets
+	if movie {
+		presets = append([]outputPreset{moviePreset}, outputPresets...)
 	}
 
 	opts := make([]huh.Option[string], len(presets))
@@ -191,7 +203,7 @@ func selectOutputFields(theme *huh.Theme) ([]string, error) {
 					huh.NewGroup(
 						huh.NewNote().
 							Title("Output Format Legend").
-							Description("\n• SERIES  — Series name (English)\n• EP\\_NUM  — Episode number (e.g. 01)\n• EP\\_NAME — Episode title\n• FILLER  — Filler tag (if detected)\n• RES     — Resolution (e.g. 1080p)\n• +       — Dynamic spacing/glue"),
+							Description("\n• SERIES  — Series name (English)\n• EP\\_NUM  — Episode number (e.g. 01)\n• EP\\_NAME — Episode title\n• FILLER  — Filler tag (if detected)\n• RES     — Resolution (e.g. 1080p; 4K/UHD become 2160p)\n• YEAR    — Premiere year; (YEAR) adds parentheses\n• PART    — Part of a split movie (e.g. pt1)\n• SOURCE  — Release source (BD, WEB, TV, DVD)\n• DUAL / AUDIO\\_LANG — Dual audio, audio languages (JPN+ENG)\n• AIR\\_DATE — Episode air date (e.g. 2013-04-07)\n• +       — Dynamic spacing/glue"),
 						huh.NewInput().
 							Title("Custom output fields").
 							Description("\nEnter fields (comma-separated). e.g: SERIES, -, EP_NUM, -, EP_NAME").
@@ -258,7 +270,7 @@ func promptCustomPatterns(theme *huh.Theme) ([]string, error) {
 			huh.NewGroup(
 				huh.NewNote().
 					Title("Input Placeholder Legend").
-					Description("\n• {{SERIES}} — Matches series name\n• {{EP\\_NUM}} — Matches episode number\n• {{RES}}    — Matches resolution (e.g. 1080p)\n• {{ANY}}    — Matches any character(s)\n• {{EXT}}    — Matches file extension"),
+					Description("\n• {{SERIES}} — Matches series name\n• {{EP\\_NUM}} — Matches episode number\n• {{RES}}    — Matches resolution (e.g. 1080p, 4K, UHD)\n• {{ANY}}    — Matches any character(s)\n• {{EXT}}    — Matches file extension"),
 				huh.NewInput().
 					Title("Custom input patterns").
 					Description("\nLeave empty to go back").
@@ -0,0 +1,239 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mydehq/autotitle"
+	"github.com/mydehq/autotitle/internal/types"
+)
+
+// reviewAction is what the user chose for the highlighted review item
+type reviewAction int
+
+const (
+	reviewQuit reviewAction = iota
+	reviewAccept
+	reviewSearch
+	reviewSkip
+)
+
+// reviewTable is a Bubble Tea model listing the parked sort groups
+type reviewTable struct {
+	items  []types.ReviewItem
+	cursor int
+	action reviewAction
+	width  int // Terminal width, 0 until known
+}
+
+func (m reviewTable) Init() tea.Cmd {
+	return nil
+}
+
+func (m reviewTable) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if size, ok := msg.(tea.WindowSizeMsg); ok {
+		m.width = size.Width
+		return m, nil
+	}
+	k, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	switch k.String() {
+	case "ctrl+c", "esc", "q":
+		m.action = reviewQuit
+		return m, tea.Quit
+	case "enter", "a":
+		m.action = reviewAccept
+		return m, tea.Quit
+	case "r", "/":
+		m.action = reviewSearch
+		return m, tea.Quit
+	case "s":
+		m.action = reviewSkip
+		return m, tea.Quit
+	case "up", "k", "shift+tab":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case "down", "j", "tab":
+		if m.cursor < len(m.items)-1 {
+			m.cursor++
+		}
+	}
+	return m, nil
+}
+
+func (m reviewTable) View() string {
+	var b strings.Builder
+
+	b.WriteString(StyleHeader.Render("Review parked groups") + StyleDim.Render(fmt.Sprintf("  %d left", len(m.items))) + "\n\n")
+
+	// Series and match columns share what the terminal leaves them
+	nameW, matchW := 28, 36
+	if avail := m.width - reviewFixedWidth; m.width > 0 && avail < nameW+matchW {
+		avail = max(avail, 16)
+		nameW = avail * 28 / 64
+		matchW = avail - nameW
+	}
+	row := func(name, files, match, conf string) string {
+		return fmt.Sprintf("%-*s %5s  %-*s %5s", nameW, clip(name, nameW), files, matchW, clip(match, matchW), conf)
+	}
+	b.WriteString("    " + StyleDim.Render(row("SERIES", "FILES", "PROPOSED MATCH", "CONF")) + "\n")
+
+	selectedStyle := lipgloss.NewStyle().Bold(true).Foreground(colorCommand)
+	for i, item := range m.items {
+		g := item.Group
+		match, conf := "no match", "-"
+		if g.Match != nil {
+			match = g.Match.Title
+			if g.Match.Year > 0 {
+				match += fmt.Sprintf(" (%d)", g.Match.Year)
+			}
+			conf = fmt.Sprintf("%.0f%%", g.Confidence*100)
+		}
+
+		line := row(g.Name, fmt.Sprint(len(g.Files)), match, conf)
+		if i == m.cursor {
+			b.WriteString("  " + selectedStyle.Render("> "+line) + "\n")
+		} else {
+			b.WriteString("    " + line + "\n")
+		}
+	}
+
+	// Details of the highlighted item
+	item := m.items[m.cursor]
+	b.WriteString("\n  " + StyleDim.Render("in ") + StylePath.Render(TruncateMiddle(item.Dir, m.width-5)) + "\n")
+	for i, f := range item.Group.Files {
+		if i == 3 {
+			b.WriteString(StyleDim.Render(fmt.Sprintf("    … %d more", len(item.Group.Files)-i)) + "\n")
+			break
+		}
+		b.WriteString(StyleDim.Render("    "+TruncateMiddle(f, m.width-4)) + "\n")
+	}
+
+	b.WriteString("\n" + StyleDim.Render("  ↑/↓ navigate • ") + StyleCommand.Render("enter accept") +
+		StyleDim.Render(" • r re-search • s skip • esc quit") + "\n")
+	return b.String()
+}
+
+// reviewFixedWidth is the review table row without the series and match
+// columns: indent, file count, confidence and the spaces between
+const reviewFixedWidth = 4 + 1 + 5 + 2 + 1 + 5
+
+// clip shortens s to n cells, marking the cut with an ellipsis; n of 0 or
+// less means no limit
+func clip(s string, n int) string {
+	if n <= 0 || lipgloss.Width(s) <= n {
+		return s
+	}
+	return headCells(s, n-1) + ellipsis
+}
+
+// RunReview walks the user through the sort groups parked for review. Each
+// item can be accepted with its proposed match, matched through a new
+// search, or skipped for this session. Accepted matches are sorted with
+// opts and learned for later sorts.
+func RunReview(ctx context.Context, opts ...autotitle.Option) error {
+	skipped := make(map[string]bool)
+	cursor := 0
+
+	for {
+		items, err := autotitle.ReviewQueue(ctx)
+		if err != nil {
+			return err
+		}
+		items = slices.DeleteFunc(items, func(it types.ReviewItem) bool { return skipped[it.ID] })
+		if len(items) == 0 {
+			if logger != nil {
+				logger.Info("Nothing left to review")
+			}
+			return nil
+		}
+
+		interceptedKey = ""
+		final, err := tea.NewProgram(reviewTable{items: items, cursor: min(cursor, len(items)-1)}, tea.WithFilter(wizardFilter)).Run()
+		if err != nil {
+			return fmt.Errorf("review table failed: %w", err)
+		}
+		m := final.(reviewTable)
+		cursor = m.cursor
+		item := m.items[m.cursor]
+
+		match := item.Group.Match
+		switch m.action {
+		case reviewQuit:
+			return nil
+		case reviewSkip:
+			skipped[item.ID] = true
+			continue
+		case reviewSearch:
+			match = nil
+		}
+
+		if match == nil {
+			chosen, err := searchForReview(ctx, item.Group.Name)
+			if errors.Is(err, ErrUserBack) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			match = &chosen
+		}
+
+		if _, err := autotitle.AcceptReview(ctx, item.ID, *match, opts...); err != nil && logger != nil {
+			logger.Error("Failed to accept match", "series", item.Group.Name, "error", err)
+			skipped[item.ID] = true
+		}
+	}
+}
+
+// searchForReview asks for a query (prefilled with the group name) and lets
+// the user pick a result. Returns ErrUserBack when nothing was picked.
+func searchForReview(ctx context.Context, query string) (types.SearchResult, error) {
+	theme := AutotitleTheme()
+	for {
+		err := RunForm(huh.NewForm(
+			huh.NewGroup(
+				huh.NewInput().
+					Title("Search query").
+					Description("\nEdit the query to search for this series\n").
+					Value(&query),
+			),
+		).WithTheme(theme).WithKeyMap(AutotitleKeyMap()))
+		if err != nil {
+			return types.SearchResult{}, reviewAbort(err)
+		}
+
+		result, err := runStreamingSearch(ctx, query)
+		if errors.Is(err, ErrSearchAgain) {
+			continue
+		}
+		if err != nil {
+			if err = reviewAbort(err); errors.Is(err, ErrUserBack) {
+				continue // Back to the query
+			}
+			return types.SearchResult{}, err
+		}
+		if result.URL == "" {
+			return types.SearchResult{}, ErrUserBack
+		}
+		return result, nil
+	}
+}
+
+// reviewAbort maps esc to ErrUserBack and ctrl+c to huh.ErrUserAborted
+func reviewAbort(err error) error {
+	if errors.Is(err, huh.ErrUserAborted) && interceptedKey != "ctrl+c" {
+		return ErrUserBack
+	}
+	return err
+}
@@ -30,7 +30,7 @@ type searchPicker struct {
 	ch       <-chan types.SearchResult
 	results  []types.SearchResult
 	cursor   int
-	selected string
+	selected types.SearchResult
 	done     bool // all providers finished
 	aborted  bool
 	chosen   bool
@@ -40,6 +40,7 @@ type searchPicker struct {
 
 	// Visible window for scrolling
 	windowSize int
+	width      int // Terminal width, 0 until known
 
 	spinner spinner.Model
 }
@@ -89,6 +90,10 @@ func (m searchPicker) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.done = true
 		return m, nil
 
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		return m, nil
+
 	case spinner.TickMsg:
 		var cmd tea.Cmd
 		m.spinner, cmd = m.spinner.Update(msg)
@@ -115,7 +120,7 @@ func (m searchPicker) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			if len(filtered) > 0 && m.cursor < len(filtered) {
 				m.chosen = true
-				m.selected = filtered[m.cursor].URL
+				m.selected = filtered[m.cursor]
 				return m, tea.Quit
 			}
 
@@ -217,7 +222,11 @@ func (m searchPicker) View() string {
 			if r.Year > 0 {
 				label += fmt.Sprintf(" (%d)", r.Year)
 			}
-			provTag := providerStyle.Render(" [" + strings.ToUpper(r.Provider) + "]")
+			tag := " [" + strings.ToUpper(r.Provider) + "]"
+			if m.width > 0 {
+				label = TruncateMiddle(label, max(m.width-4-lipgloss.Width(tag), minLineWidth))
+			}
+			provTag := providerStyle.Render(tag)
 
 			if i == m.cursor {
 				b.WriteString("  " + selectedStyle.Render("> "+label) + provTag + "\n")
@@ -226,6 +235,10 @@ func (m searchPicker) View() string {
 			}
 		}
 
+		// Partial results: name the providers that failed or timed out
+		for _, err := range m.errs {
+			b.WriteString(StyleDim.Render(clip(fmt.Sprintf("    (%v)", err), m.width)) + "\n")
+		}
 	}
 
 	// Static "Search again..." item
@@ -265,32 +278,29 @@ func (m searchPicker) filteredResults() []types.SearchResult {
 	return out
 }
 
-// runStreamingSearch launches a parallel search and runs the streaming picker.
-// Returns the selected URL, or "" if no results were found. Returns ErrUserBack on esc.
-func runStreamingSearch(ctx context.Context, query string) (string, error) {
-	ch := autotitle.SearchStream(ctx, query)
+// runStreamingSearch launches a parallel search (of the given providers, or
+// all) and runs the streaming picker.
+// Returns the selected result, or a zero result if none were found. Returns ErrUserBack on esc.
+func runStreamingSearch(ctx context.Context, query string, providers ...string) (types.SearchResult, error) {
+	ch := autotitle.SearchStream(ctx, query, autotitle.WithProvider(providers...))
 	picker := newSearchPicker(ch)
 
 	p := tea.NewProgram(picker, tea.WithFilter(wizardFilter))
 	finalModel, err := p.Run()
 	if err != nil {
-		return "", fmt.Errorf("search picker failed: %w", err)
+		return types.SearchResult{}, fmt.Errorf("search picker failed: %w", err)
 	}
 
 	m := finalModel.(searchPicker)
 
 	if m.aborted {
-		if interceptedKey == "ctrl+c" {
-			fmt.Println()
-			logger.Warn(StyleDim.Render("Init cancelled"))
-			return "", huh.ErrUserAborted
-		}
-		return "", huh.ErrUserAborted
+		// Callers tell esc from ctrl+c via interceptedKey
+		return types.SearchResult{}, huh.ErrUserAborted
 	}
 
 	if m.rescan {
 		autotitle.ClearSearchCache()
-		return "", ErrSearchAgain
+		return types.SearchResult{}, ErrSearchAgain
 	}
 
 	if m.chosen {
@@ -298,5 +308,5 @@ func runStreamingSearch(ctx context.Context, query string) (string, error) {
 	}
 
 	// Done but no results selected (no results found)
-	return "", nil
+	return types.SearchResult{}, nil
 }
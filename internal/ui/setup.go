@@ -0,0 +1,215 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+	"github.com/mydehq/autotitle/internal/config"
+	"github.com/mydehq/autotitle/internal/provider"
+	"github.com/mydehq/autotitle/internal/support"
+	"github.com/mydehq/autotitle/internal/types"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	taggingAuto = "auto"
+	taggingOn   = "on"
+	taggingOff  = "off"
+)
+
+// RunSetupWizard walks the user through creating the global configuration,
+// starting from cfg. It returns the edited config and whether the user
+// confirmed writing it.
+func RunSetupWizard(cfg types.GlobalConfig) (types.GlobalConfig, bool, error) {
+	theme := AutotitleTheme()
+	res := cfg.Clone()
+
+	// Offer every known format, keeping any custom ones from the existing config
+	allFormats := config.GetDefaults().Formats
+	for _, f := range res.Formats {
+		if !slices.Contains(allFormats, f) {
+			allFormats = append(allFormats, f)
+		}
+	}
+	formatOpts := make([]huh.Option[string], len(allFormats))
+	for i, f := range allFormats {
+		formatOpts[i] = huh.NewOption(f, f).Selected(slices.Contains(res.Formats, f))
+	}
+	formats := slices.Clone(res.Formats)
+
+	// Output preset, defaulting to the one matching the current first pattern
+	var presetOpts []huh.Option[string]
+	preset := ""
+	for _, p := range outputPresets {
+		if p.fields == nil {
+			continue
+		}
+		val := strings.Join(p.fields, ",")
+		label := fmt.Sprintf("%-8s (%s)", p.name, buildFilenamePreview(p.fields, " "))
+		presetOpts = append(presetOpts, huh.NewOption(label, val))
+		if len(res.Patterns) > 0 && slices.Equal(res.Patterns[0].Output.Fields, p.fields) {
+			preset = val
+		}
+	}
+	if preset == "" && len(res.Patterns) > 0 {
+		current := strings.Join(res.Patterns[0].Output.Fields, ",")
+		presetOpts = append(presetOpts, huh.NewOption(fmt.Sprintf("%-8s (%s)", "Current", current), current))
+		preset = current
+	}
+
+	tagging := taggingAuto
+	if res.Tagging.Enabled != nil {
+		tagging = taggingOff
+		if *res.Tagging.Enabled {
+			tagging = taggingOn
+		}
+	}
+
+	backupEnabled := res.Backup.Enabled
+	backupDir := res.Backup.DirName
+	rateLimit := strconv.FormatFloat(res.API.RateLimit, 'f', -1, 64)
+	timeout := strconv.Itoa(res.API.Timeout)
+
+	// One optional key per provider that needs one, starting from the current
+	var keyed []string
+	for _, p := range provider.ListProviderDetails() {
+		if p.RequiresKey {
+			keyed = append(keyed, p.Name)
+		}
+	}
+	keys := make([]string, len(keyed))
+	keyInputs := make([]huh.Field, len(keyed))
+	for i, name := range keyed {
+		keys[i] = res.API.Keys[name]
+		keyInputs[i] = huh.NewInput().
+			Title(fmt.Sprintf("%s API key", name)).
+			Description(fmt.Sprintf("\nOptional: only %s URLs need it; leave empty to skip", name)).
+			EchoMode(huh.EchoModePassword).
+			Value(&keys[i])
+	}
+
+	groups := []*huh.Group{
+		huh.NewGroup(
+			huh.NewMultiSelect[string]().
+				Title("Media formats").
+				Description("File extensions autotitle should pick up\n").
+				Options(formatOpts...).
+				Value(&formats).
+				Validate(func(s []string) error {
+					if len(s) == 0 {
+						return fmt.Errorf("select at least one format")
+					}
+					return nil
+				}),
+		),
+		huh.NewGroup(
+			huh.NewConfirm().
+				Title("Back up files before renaming?").
+				Description("Backups allow `autotitle undo` to restore original names").
+				Value(&backupEnabled),
+			huh.NewInput().
+				Title("Backup directory name").
+				Description("\nCreated inside each media directory").
+				Value(&backupDir),
+		),
+		huh.NewGroup(
+			huh.NewSelect[string]().
+				Title("Default output format\n").
+				Options(presetOpts...).
+				Value(&preset),
+		),
+		huh.NewGroup(
+			huh.NewSelect[string]().
+				Title("Metadata tagging\n").
+				Description("Embed titles into MKV/MP4 files after renaming\n").
+				Options(
+					huh.NewOption("Auto (when mkvpropedit/atomicparsley are installed)", taggingAuto),
+					huh.NewOption("Always on", taggingOn),
+					huh.NewOption("Off", taggingOff),
+				).
+				Value(&tagging),
+		),
+		huh.NewGroup(
+			huh.NewInput().
+				Title("API rate limit").
+				Description("\nRequests per second sent to providers").
+				Value(&rateLimit).
+				Validate(validatePositiveFloat),
+			huh.NewInput().
+				Title("API timeout").
+				Description("\nHTTP timeout in seconds").
+				Value(&timeout).
+				Validate(validateInt),
+		),
+	}
+	if len(keyInputs) > 0 {
+		groups = append(groups, huh.NewGroup(keyInputs...))
+	}
+
+	ClearAndPrintBanner(false)
+	err := RunForm(huh.NewForm(groups...).WithTheme(theme).WithKeyMap(AutotitleKeyMap()))
+	if err != nil {
+		return res, false, err
+	}
+
+	res.Formats = formats
+	res.Backup.Enabled = backupEnabled
+	if strings.TrimSpace(backupDir) != "" {
+		res.Backup.DirName = strings.TrimSpace(backupDir)
+	}
+	if len(res.Patterns) > 0 && preset != "" {
+		res.Patterns[0].Output.Fields = strings.Split(preset, ",")
+	}
+
+	switch tagging {
+	case taggingAuto:
+		res.Tagging.Enabled = nil
+	default:
+		enabled := tagging == taggingOn
+		res.Tagging.Enabled = &enabled
+	}
+
+	if v, err := strconv.ParseFloat(strings.TrimSpace(rateLimit), 64); err == nil {
+		res.API.RateLimit = v
+	}
+	if v, err := strconv.Atoi(strings.TrimSpace(timeout)); err == nil && v > 0 {
+		res.API.Timeout = v
+	}
+	for i, name := range keyed {
+		key := strings.TrimSpace(keys[i])
+		if key == "" {
+			delete(res.API.Keys, name)
+			continue
+		}
+		if res.API.Keys == nil {
+			res.API.Keys = map[string]string{}
+		}
+		res.API.Keys[name] = key
+	}
+
+	// The preview masks the keys, which should not be left on the screen
+	data, err := yaml.Marshal(&res)
+	if err != nil {
+		return res, false, fmt.Errorf("failed to preview config: %w", err)
+	}
+	if data, err = support.Sanitize(data); err != nil {
+		return res, false, fmt.Errorf("failed to preview config: %w", err)
+	}
+	ClearAndPrintBanner(false)
+	confirmed, err := confirmPreview(data, theme)
+	if err != nil {
+		return res, false, err
+	}
+	return res, confirmed, nil
+}
+
+func validatePositiveFloat(s string) error {
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || v <= 0 {
+		return fmt.Errorf("must be a positive number")
+	}
+	return nil
+}
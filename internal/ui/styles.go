@@ -3,6 +3,7 @@ package ui
 import (
 	"errors"
 	"fmt"
+	"regexp"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -121,7 +122,8 @@ func ClearAndPrintBanner(dryRun bool) {
 	}
 }
 
-// ColorizeEvent adds CLI styling to known event message patterns.
+// ColorizeEvent adds CLI styling to known event message patterns. Filenames
+// are shortened to fit the terminal (see TruncateMiddle).
 func ColorizeEvent(msg string) string {
 	// Messages with "→" (rename/restore): "Renamed: old.mkv → new.mkv"
 	if parts := strings.SplitN(msg, " → ", 2); len(parts) == 2 {
@@ -131,12 +133,19 @@ func ColorizeEvent(msg string) string {
 		// Split label from filename: "Renamed: old.mkv" → "Renamed:" + "old.mkv"
 		var label, oldName string
 		if idx := strings.Index(left, ": "); idx >= 0 {
-			label = StyleHeader.Render(left[:idx+1]) + " "
+			label = left[:idx+1]
 			oldName = left[idx+2:]
 		} else {
 			oldName = left
 		}
 
+		used := logPrefixWidth + ArrowWidth
+		if label != "" {
+			used += lipgloss.Width(label) + 1
+			label = StyleHeader.Render(label) + " "
+		}
+		oldName, right = FitPair(oldName, right, LineWidth(used))
+
 		return fmt.Sprintf("%s%s %s %s",
 			label,
 			StyleDim.Render(oldName),
@@ -149,12 +158,25 @@ func ColorizeEvent(msg string) string {
 	if idx := strings.Index(msg, ": "); idx >= 0 {
 		label := msg[:idx+1]
 		value := msg[idx+2:]
+		// Only filenames are shortened; error messages are kept whole
+		if isFilename(value) {
+			value = TruncateMiddle(value, LineWidth(logPrefixWidth+lipgloss.Width(label)+1))
+		}
 		return fmt.Sprintf("%s %s", StyleHeader.Render(label), StylePath.Render(value))
 	}
 
 	return msg
 }
 
+// reExtension matches a file extension at the end of a name
+var reExtension = regexp.MustCompile(`\.[A-Za-z0-9]{2,5}$`)
+
+// isFilename reports whether an event value is a single filename rather
+// than a message ("a.mkv: permission denied")
+func isFilename(s string) bool {
+	return !strings.Contains(s, ": ") && reExtension.MatchString(s)
+}
+
 // HighlightYAML applies simple syntax highlighting to a YAML string for TUI display.
 func HighlightYAML(input string) string {
 	lines := strings.Split(input, "\n")
@@ -0,0 +1,148 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/term"
+)
+
+const (
+	ellipsis = "…"
+
+	// minLineWidth is the least room a line is given; narrower than that,
+	// it is left to wrap
+	minLineWidth = 20
+
+	// logPrefixWidth is the widest level label of the logger ("SUCCESS ")
+	logPrefixWidth = 8
+
+	// ArrowWidth is the width of " → " between two names
+	ArrowWidth = 3
+)
+
+// TermWidth returns the width of the terminal on stdout, or 0 (no limit)
+// when stdout is not a terminal, e.g. piped to a log file. $COLUMNS
+// overrides it. Replaced in tests.
+var TermWidth = func() int {
+	if c, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && c > 0 {
+		return c
+	}
+	if w, _, err := term.GetSize(os.Stdout.Fd()); err == nil && w > 0 {
+		return w
+	}
+	return 0
+}
+
+// LineWidth returns the room left on a terminal line once used cells are
+// taken, or 0 (no limit) outside a terminal
+func LineWidth(used int) int {
+	w := TermWidth()
+	if w <= 0 {
+		return 0
+	}
+	return max(w-used, minLineWidth)
+}
+
+// reEpisode finds episode number candidates: S01E05, E05, EP05 or a bare
+// number, with an optional version suffix (05v2)
+var reEpisode = regexp.MustCompile(`(?i)\b(s\d{1,2}e\d{1,4}|ep?\d{1,4}|\d{1,4})(?:v\d)?\b`)
+
+// episodeSpan returns where the episode number of a filename is, preferring
+// an S01E05/E05 token, then a number after " - " or "#", then the last bare
+// number that is not a year. ok is false when there is none.
+func episodeSpan(s string) (start, end int, ok bool) {
+	var bare []int
+	for _, m := range reEpisode.FindAllStringSubmatchIndex(s, -1) {
+		token := s[m[2]:m[3]]
+		if token[0] < '0' || token[0] > '9' {
+			return m[0], m[1], true
+		}
+		if prefix := s[:m[0]]; strings.HasSuffix(prefix, "- ") || strings.HasSuffix(prefix, "#") {
+			return m[0], m[1], true
+		}
+		if n, _ := strconv.Atoi(token); len(token) == 4 && n >= 1900 && n < 2100 {
+			continue
+		}
+		bare = m
+	}
+	if bare == nil {
+		return 0, 0, false
+	}
+	return bare[0], bare[1], true
+}
+
+// TruncateMiddle shortens s to width terminal cells by cutting out its
+// middle, so a filename keeps its start, its episode number and its
+// extension: "Sousou no Frieren - 05 - Phan…[1080p].mkv". A width of 0 or
+// less means no limit.
+func TruncateMiddle(s string, width int) string {
+	if width <= 0 || lipgloss.Width(s) <= width {
+		return s
+	}
+	if width == 1 {
+		return ellipsis
+	}
+
+	if a, b, ok := episodeSpan(s); ok && lipgloss.Width(s[a:b])+2 <= width {
+		head, ep, tail := s[:a], s[a:b], s[b:]
+		// Cut after the episode number while the extension still fits
+		if room := width - lipgloss.Width(head+ep) - 1; room >= lipgloss.Width(filepath.Ext(s)) {
+			return head + ep + ellipsis + tailCells(tail, room)
+		}
+		avail := width - lipgloss.Width(ep) - 2
+		if lipgloss.Width(tail) <= avail-avail/2+1 {
+			return headCells(head, width-lipgloss.Width(ep+tail)-1) + ellipsis + ep + tail
+		}
+		return headCells(head, avail/2) + ellipsis + ep + ellipsis + tailCells(tail, avail-avail/2)
+	}
+
+	n := width - 1
+	return headCells(s, n-n/2) + ellipsis + tailCells(s, n/2)
+}
+
+// FitPair shortens two names shown side by side ("old → new") to width cells
+// together; a name that fits in half leaves the rest to the other
+func FitPair(a, b string, width int) (string, string) {
+	wa, wb := lipgloss.Width(a), lipgloss.Width(b)
+	if width <= 0 || wa+wb <= width {
+		return a, b
+	}
+	half := width / 2
+	switch {
+	case wa <= half:
+		return a, TruncateMiddle(b, width-wa)
+	case wb <= half:
+		return TruncateMiddle(a, width-wb), b
+	}
+	return TruncateMiddle(a, half), TruncateMiddle(b, width-half)
+}
+
+// headCells returns the longest prefix of s at most n cells wide
+func headCells(s string, n int) string {
+	w := 0
+	for i, r := range s {
+		if w += lipgloss.Width(string(r)); w > n {
+			return s[:i]
+		}
+	}
+	return s
+}
+
+// tailCells returns the longest suffix of s at most n cells wide
+func tailCells(s string, n int) string {
+	w := 0
+	for i := len(s); i > 0; {
+		r, size := utf8.DecodeLastRuneInString(s[:i])
+		if w += lipgloss.Width(string(r)); w > n {
+			return s[i:]
+		}
+		i -= size
+	}
+	return s
+}
@@ -0,0 +1,48 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestTruncateMiddle(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    string
+		width int
+		want  string
+	}{
+		{"fits", "Frieren - 05.mkv", 40, "Frieren - 05.mkv"},
+		{"no limit", "Sousou no Frieren - 05 - Phantoms of the Dead.mkv", 0, "Sousou no Frieren - 05 - Phantoms of the Dead.mkv"},
+		{"cut after episode", "Sousou no Frieren - 05 - Phantoms of the Dead [1080p].mkv", 40, "Sousou no Frieren - 05… Dead [1080p].mkv"},
+		{"cut both sides", "Sousou no Frieren - 05 - Phantoms of the Dead [1080p].mkv", 20, "Sousou n…05…80p].mkv"},
+		{"season episode", "[SubsPlease] Spy x Family S02E07 (1080p) [ABCD1234].mkv", 24, "[SubsPle…S02E07…234].mkv"},
+		{"skips year", "Bleach (2004) - Episode Title Here 12.mkv", 30, "Bleach (2004) - Episode…12.mkv"},
+		{"no episode", "A very long file name without numbers.txt", 20, "A very lon…mbers.txt"},
+		{"wide runes", "ブリーチ ブリーチ ブリーチ - 05.mkv", 16, "ブリーチ …05.mkv"},
+		{"one cell", "Frieren - 05.mkv", 1, "…"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TruncateMiddle(tt.in, tt.width)
+			if got != tt.want {
+				t.Errorf("TruncateMiddle(%q, %d) = %q, want %q", tt.in, tt.width, got, tt.want)
+			}
+			if tt.width > 0 && lipgloss.Width(got) > tt.width {
+				t.Errorf("TruncateMiddle(%q, %d) is %d cells wide", tt.in, tt.width, lipgloss.Width(got))
+			}
+		})
+	}
+}
+
+func TestFitPair(t *testing.T) {
+	a, b := FitPair("Frieren 05.mkv", "Sousou no Frieren - 05 - Phantoms of the Dead.mkv", 40)
+	if a != "Frieren 05.mkv" || lipgloss.Width(b) != 26 {
+		t.Errorf("FitPair kept %q, %q; want the short name whole and the long one cut to 26 cells", a, b)
+	}
+	a, b = FitPair("Sousou no Frieren - 05 - Phantoms of the Dead.mkv", "Sousou no Frieren - S01E05 - Phantoms.mkv", 40)
+	if lipgloss.Width(a)+lipgloss.Width(b) > 40 {
+		t.Errorf("FitPair(…, 40) = %q, %q: %d cells", a, b, lipgloss.Width(a)+lipgloss.Width(b))
+	}
+}
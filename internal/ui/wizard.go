@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -13,6 +14,7 @@ import (
 	"github.com/mydehq/autotitle"
 	"github.com/mydehq/autotitle/internal/config"
 	"github.com/mydehq/autotitle/internal/provider/filler"
+	"github.com/mydehq/autotitle/internal/types"
 )
 
 // InitFlags encapsulates all the CLI flags required by the init wizard.
@@ -27,6 +29,7 @@ type InitFlags struct {
 	Padding      int
 	HasPadding   bool
 	DryRun       bool
+	Providers    []string // Searched instead of all, e.g. those of the folder's library
 }
 
 // RunInitWizard orchestrates the full interactive init wizard.
@@ -39,11 +42,13 @@ func RunInitWizard(ctx context.Context, absPath string, scan *config.ScanResult,
 	step := 0
 
 	searchQuery := filepath.Base(absPath)
-	var selectedURL string
+	var selectedURL, selectedTitle string
+	var mediaType types.MediaType
 	var fillerURL string
 	var inputPatterns []string
 	var outputFields []string
 	var showAdvanced bool
+	var learnedPatterns []string
 
 	separator := " "
 	offsetStr := "0"
@@ -62,7 +67,71 @@ func RunInitWizard(ctx context.Context, absPath string, scan *config.ScanResult,
 	defer autotitle.ClearSearchCache()
 	autotitle.ClearSearchCache()
 
+	// A series matched by hand before (e.g. an earlier season) starts the
+	// search from that match and offers its patterns first
+	if a, _ := autotitle.LearnedMatch(ctx, absPath); a != nil {
+		searchQuery = a.Match.Title
+		learnedPatterns = a.Patterns
+	}
+
+	// Offer to resume an interrupted wizard for this directory
+	if saved := loadWizardState(absPath); saved != nil && saved.SelectedURL != "" {
+		resume := true
+		err := RunForm(huh.NewForm(
+			huh.NewGroup(
+				huh.NewConfirm().
+					Title("Resume previous setup?").
+					Description(fmt.Sprintf("An unfinished setup for %s was interrupted on %s.",
+						StylePath.Render(saved.SelectedURL), saved.Saved.Local().Format("2006-01-02 15:04"))).
+					Value(&resume),
+			),
+		).WithTheme(theme).WithKeyMap(AutotitleKeyMap()))
+		if err != nil {
+			if !errors.Is(HandleAbort(err), ErrUserBack) {
+				return false, err
+			}
+			resume = false
+		}
+
+		if resume {
+			step = saved.Step
+			searchQuery = saved.SearchQuery
+			selectedURL = saved.SelectedURL
+			selectedTitle = saved.SelectedTitle
+			mediaType = saved.MediaType
+			fillerURL = saved.FillerURL
+			inputPatterns = saved.InputPatterns
+			outputFields = saved.OutputFields
+			separator = saved.Separator
+			offsetStr = saved.Offset
+			paddingStr = saved.Padding
+			showAdvanced = saved.ShowAdvanced
+		} else {
+			clearWizardState(absPath)
+		}
+	}
+
 	for {
+		// Persist progress so an interrupted wizard can resume at this step.
+		// Once the config is written (step 9) there is nothing left to resume.
+		if step < 9 {
+			(&wizardState{
+				Path:          absPath,
+				Step:          step,
+				SearchQuery:   searchQuery,
+				SelectedURL:   selectedURL,
+				SelectedTitle: selectedTitle,
+				MediaType:     mediaType,
+				FillerURL:     fillerURL,
+				InputPatterns: inputPatterns,
+				OutputFields:  outputFields,
+				Separator:     separator,
+				Offset:        offsetStr,
+				Padding:       paddingStr,
+				ShowAdvanced:  showAdvanced,
+			}).save()
+		}
+
 		ClearAndPrintBanner(flags.DryRun)
 		switch step {
 		case 0:
@@ -79,6 +148,7 @@ func RunInitWizard(ctx context.Context, absPath string, scan *config.ScanResult,
 			if err != nil {
 				if errors.Is(HandleAbort(err), ErrUserBack) {
 					// We are at the first step, so "back" means abort.
+					clearWizardState(absPath)
 					fmt.Println()
 					if logger != nil {
 						logger.Warn(StyleDim.Render("Init cancelled"))
@@ -91,7 +161,7 @@ func RunInitWizard(ctx context.Context, absPath string, scan *config.ScanResult,
 
 		case 1:
 			// Live streaming search across all providers
-			url, err := runStreamingSearch(ctx, searchQuery) // Note: small 'r'
+			result, err := runStreamingSearch(ctx, searchQuery, flags.Providers...) // Note: small 'r'
 			if err != nil {
 				if errors.Is(err, ErrSearchAgain) {
 					step--
@@ -103,10 +173,13 @@ func RunInitWizard(ctx context.Context, absPath string, scan *config.ScanResult,
 				}
 				return false, err
 			}
-			if url == "" {
+			mediaType = result.Type
+			selectedTitle = result.Title
+			if result.URL == "" {
 				// No results or user chose manual entry
 				var manualErr error
 				selectedURL, manualErr = promptManualURL(theme)
+				selectedTitle = searchQuery
 				if manualErr != nil {
 					if errors.Is(HandleAbort(manualErr), ErrUserBack) {
 						continue
@@ -114,7 +187,7 @@ func RunInitWizard(ctx context.Context, absPath string, scan *config.ScanResult,
 					return false, manualErr
 				}
 			} else {
-				selectedURL = url
+				selectedURL = result.URL
 			}
 			step++
 
@@ -141,7 +214,13 @@ func RunInitWizard(ctx context.Context, absPath string, scan *config.ScanResult,
 		case 3:
 			// Pattern selection
 			var err error
-			inputPatterns, err = selectInputPatterns(scan.DetectedPatterns, theme)
+			detected := slices.Clone(learnedPatterns)
+			for _, p := range scan.DetectedPatterns {
+				if !slices.Contains(detected, p) {
+					detected = append(detected, p)
+				}
+			}
+			inputPatterns, err = selectInputPatterns(detected, theme)
 			if err != nil {
 				if errors.Is(HandleAbort(err), ErrUserBack) {
 					step--
@@ -154,7 +233,7 @@ func RunInitWizard(ctx context.Context, absPath string, scan *config.ScanResult,
 		case 4:
 			// Output fields
 			var err error
-			outputFields, err = selectOutputFields(theme)
+			outputFields, err = selectOutputFields(theme, mediaType == types.MediaTypeMovie)
 			if err != nil {
 				if errors.Is(HandleAbort(err), ErrUserBack) {
 					step--
@@ -274,6 +353,7 @@ func RunInitWizard(ctx context.Context, absPath string, scan *config.ScanResult,
 				return false, err
 			}
 			if !confirmed {
+				clearWizardState(absPath)
 				fmt.Println()
 				if logger != nil {
 					logger.Warn(StyleDim.Render("Init cancelled"))
@@ -286,6 +366,13 @@ func RunInitWizard(ctx context.Context, absPath string, scan *config.ScanResult,
 			if err := config.SaveToDir(absPath, cfg); err != nil {
 				return false, fmt.Errorf("failed to save config: %w", err)
 			}
+			clearWizardState(absPath)
+
+			// Remember the choice for later runs on this series
+			match := types.SearchResult{URL: selectedURL, Title: selectedTitle, Type: mediaType}
+			if err := autotitle.Learn(ctx, absPath, match, inputPatterns); err != nil && logger != nil {
+				logger.Debug("Failed to remember match", "error", err)
+			}
 			step++
 
 		case 9:
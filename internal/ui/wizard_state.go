@@ -0,0 +1,95 @@
+package ui
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mydehq/autotitle/internal/types"
+	"github.com/mydehq/autotitle/internal/util"
+)
+
+// wizardState is the init wizard progress persisted between steps, so an
+// interrupted wizard (closed terminal, crash) can resume where it stopped.
+type wizardState struct {
+	Path          string          `json:"path"`
+	Step          int             `json:"step"`
+	SearchQuery   string          `json:"search_query"`
+	SelectedURL   string          `json:"selected_url"`
+	SelectedTitle string          `json:"selected_title,omitempty"`
+	MediaType     types.MediaType `json:"media_type,omitempty"`
+	FillerURL     string          `json:"filler_url"`
+	InputPatterns []string        `json:"input_patterns,omitempty"`
+	OutputFields  []string        `json:"output_fields,omitempty"`
+	Separator     string          `json:"separator"`
+	Offset        string          `json:"offset"`
+	Padding       string          `json:"padding"`
+	ShowAdvanced  bool            `json:"show_advanced"`
+	Saved         time.Time       `json:"saved"`
+}
+
+// wizardStatePath returns the file holding the wizard state for absPath,
+// in ~/.cache/autotitle/wizard next to the database
+func wizardStatePath(absPath string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(absPath))
+	return filepath.Join(home, ".cache", "autotitle", "wizard", hex.EncodeToString(sum[:6])+".json"), nil
+}
+
+// loadWizardState returns the saved state for absPath, or nil if there is none
+func loadWizardState(absPath string) *wizardState {
+	path, err := wizardStatePath(absPath)
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var s wizardState
+	if err := json.Unmarshal(data, &s); err != nil || s.Path != absPath {
+		return nil
+	}
+	return &s
+}
+
+// save writes the state; failures only cost the ability to resume
+func (s *wizardState) save() {
+	if util.ReadOnly() {
+		return
+	}
+	path, err := wizardStatePath(s.Path)
+	if err != nil {
+		return
+	}
+	s.Saved = time.Now()
+	data, err := json.Marshal(s)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return
+	}
+	// Write through a temp file so a kill mid-write can't leave half a state
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return
+	}
+	_ = os.Rename(tmp, path)
+}
+
+// clearWizardState removes the saved state for absPath
+func clearWizardState(absPath string) {
+	if util.ReadOnly() {
+		return
+	}
+	if path, err := wizardStatePath(absPath); err == nil {
+		_ = os.Remove(path)
+	}
+}
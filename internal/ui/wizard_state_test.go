@@ -0,0 +1,68 @@
+package ui
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWizardState_RoundTrip(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dir := "/media/Anime/Frieren"
+
+	if s := loadWizardState(dir); s != nil {
+		t.Fatalf("loadWizardState before any save = %+v, want nil", s)
+	}
+
+	saved := &wizardState{Path: dir, Step: 2, SelectedURL: "https://myanimelist.net/anime/52991", OutputFields: []string{"SERIES", "EP_NUM"}}
+	saved.save()
+
+	path, err := wizardStatePath(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(path, filepath.Join(home, ".cache", "autotitle")) {
+		t.Errorf("state saved to %s, want it under the cache directory", path)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("state file = %v, %v; want it readable by the user only", info, err)
+	}
+
+	got := loadWizardState(dir)
+	if got == nil || got.Step != 2 || got.SelectedURL != saved.SelectedURL || len(got.OutputFields) != 2 || got.Saved.IsZero() {
+		t.Fatalf("loadWizardState = %+v, want the saved state", got)
+	}
+	if other := loadWizardState("/media/Anime/Other"); other != nil {
+		t.Errorf("state of another folder = %+v, want nil", other)
+	}
+
+	clearWizardState(dir)
+	if s := loadWizardState(dir); s != nil {
+		t.Errorf("loadWizardState after clear = %+v, want nil", s)
+	}
+}
+
+func TestWizardState_RejectsPathMismatch(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := "/media/Anime/Frieren"
+
+	// A state file naming another folder, e.g. a hash collision or a planted file
+	path, err := wizardStatePath(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(wizardState{Path: "/elsewhere", Step: 3})
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if s := loadWizardState(dir); s != nil {
+		t.Errorf("loadWizardState = %+v, want nil for a state of another path", s)
+	}
+}
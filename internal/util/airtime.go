@@ -0,0 +1,38 @@
+package util
+
+import (
+	"time"
+	_ "time/tzdata" // Provider time zones must resolve on systems without a zone database
+)
+
+// SeasonName returns the broadcast season a month falls in, as anime
+// seasons are named: Winter (Jan-Mar), Spring, Summer or Fall (Oct-Dec)
+func SeasonName(m time.Month) string {
+	if m < time.January || m > time.December {
+		return ""
+	}
+	return [...]string{"Winter", "Spring", "Summer", "Fall"}[(m-1)/3]
+}
+
+// AiredBy returns when an episode dated airDate has certainly aired: the end
+// of that calendar day in zone (an IANA name, UTC if empty or unknown).
+// Providers give air dates without a broadcast time (Jikan stamps them
+// midnight UTC), so the date as written is taken as a day in the provider's
+// zone. Reports false if airDate is neither RFC 3339 nor YYYY-MM-DD.
+func AiredBy(airDate, zone string) (time.Time, bool) {
+	t, err := time.Parse(time.RFC3339, airDate)
+	if err != nil {
+		if t, err = time.Parse(time.DateOnly, airDate); err != nil {
+			return time.Time{}, false
+		}
+	}
+
+	loc := time.UTC
+	if zone != "" {
+		if l, err := time.LoadLocation(zone); err == nil {
+			loc = l
+		}
+	}
+	y, m, d := t.Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, loc), true
+}
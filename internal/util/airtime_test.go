@@ -0,0 +1,38 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAiredBy(t *testing.T) {
+	// A Tokyo day ends at 15:00 UTC
+	got, ok := AiredBy("2024-01-10T00:00:00+00:00", "Asia/Tokyo")
+	want := time.Date(2024, 1, 10, 15, 0, 0, 0, time.UTC)
+	if !ok || !got.Equal(want) {
+		t.Errorf("AiredBy(Tokyo) = %v, %v; want %v", got, ok, want)
+	}
+	if !got.After(want.Add(-time.Second)) || got.After(want) {
+		t.Errorf("Boundary off: %v", got)
+	}
+
+	// Date-only and unknown zones fall back to the end of the UTC day
+	got, ok = AiredBy("2024-01-10", "")
+	if want := time.Date(2024, 1, 11, 0, 0, 0, 0, time.UTC); !ok || !got.Equal(want) {
+		t.Errorf("AiredBy(UTC) = %v, %v; want %v", got, ok, want)
+	}
+	got, _ = AiredBy("2024-01-10", "Not/AZone")
+	if want := time.Date(2024, 1, 11, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Unknown zone should use UTC, got %v", got)
+	}
+
+	// The written date counts, not the instant its offset implies
+	got, _ = AiredBy("2024-01-10T23:00:00-05:00", "Asia/Tokyo")
+	if want := time.Date(2024, 1, 10, 15, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Expected the written date to be used, got %v", got)
+	}
+
+	if _, ok := AiredBy("soon", ""); ok {
+		t.Error("Expected an unparseable date to be rejected")
+	}
+}
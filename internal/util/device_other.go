@@ -0,0 +1,34 @@
+//go:build !unix
+
+package util
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SameDevice reports whether the existing paths a and b are on the same
+// volume, i.e. whether a file in one can be hard linked into the other
+func SameDevice(a, b string) (bool, error) {
+	for _, p := range []string{a, b} {
+		if _, err := os.Stat(p); err != nil {
+			return false, err
+		}
+	}
+	va, err := filepath.Abs(a)
+	if err != nil {
+		return false, err
+	}
+	vb, err := filepath.Abs(b)
+	if err != nil {
+		return false, err
+	}
+	return strings.EqualFold(filepath.VolumeName(va), filepath.VolumeName(vb)), nil
+}
+
+// FileID returns the device and inode numbers of path; there are none here
+func FileID(path string) (dev, ino uint64, err error) {
+	return 0, 0, fmt.Errorf("inode numbers are not supported on this system")
+}
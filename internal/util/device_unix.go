@@ -0,0 +1,48 @@
+//go:build unix
+
+package util
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// SameDevice reports whether the existing paths a and b are on the same
+// filesystem, i.e. whether a file in one can be hard linked into the other
+func SameDevice(a, b string) (bool, error) {
+	da, err := device(a)
+	if err != nil {
+		return false, err
+	}
+	db, err := device(b)
+	if err != nil {
+		return false, err
+	}
+	return da == db, nil
+}
+
+func device(path string) (uint64, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, fmt.Errorf("no device number for %s", path)
+	}
+	return uint64(st.Dev), nil // Dev is 32-bit on some systems
+}
+
+// FileID returns the device and inode numbers of path
+func FileID(path string) (dev, ino uint64, err error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, fmt.Errorf("no inode number for %s", path)
+	}
+	return uint64(st.Dev), uint64(st.Ino), nil
+}
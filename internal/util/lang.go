@@ -0,0 +1,69 @@
+package util
+
+import "strings"
+
+// Language is an ISO-639 language with the tags it is commonly written as
+type Language struct {
+	Name    string
+	ISO1    string   // ISO 639-1, e.g. "en"
+	ISO2    string   // ISO 639-2/B, e.g. "eng"
+	Aliases []string // Other tags seen in filenames (639-2/T codes, native names)
+}
+
+// Language code styles
+const (
+	CodeISO6391 = "639-1"
+	CodeISO6392 = "639-2"
+)
+
+var languages = []Language{
+	{"English", "en", "eng", nil},
+	{"Japanese", "ja", "jpn", []string{"jp", "jap", "nihongo"}},
+	{"Spanish", "es", "spa", []string{"español", "espanol", "castellano"}},
+	{"Latin American Spanish", "es", "spa", []string{"es-419", "latino"}},
+	{"Portuguese", "pt", "por", []string{"português", "portugues"}},
+	{"Brazilian Portuguese", "pt", "por", []string{"pt-br", "ptbr", "brazilian"}},
+	{"French", "fr", "fre", []string{"fra", "français", "francais"}},
+	{"German", "de", "ger", []string{"deu", "deutsch"}},
+	{"Italian", "it", "ita", []string{"italiano"}},
+	{"Russian", "ru", "rus", []string{"русский"}},
+	{"Arabic", "ar", "ara", nil},
+	{"Chinese", "zh", "chi", []string{"zho", "chs", "cht", "zh-cn", "zh-tw", "chinese simplified", "chinese traditional"}},
+	{"Korean", "ko", "kor", nil},
+	{"Indonesian", "id", "ind", []string{"bahasa indonesia"}},
+	{"Malay", "ms", "may", []string{"msa"}},
+	{"Thai", "th", "tha", nil},
+	{"Vietnamese", "vi", "vie", nil},
+	{"Polish", "pl", "pol", []string{"polski"}},
+	{"Turkish", "tr", "tur", nil},
+	{"Dutch", "nl", "dut", []string{"nld"}},
+	{"Hindi", "hi", "hin", nil},
+}
+
+// LookupLanguage finds the language for a tag such as "en", "eng" or "English".
+// Matching is case-insensitive.
+func LookupLanguage(tag string) (Language, bool) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" {
+		return Language{}, false
+	}
+	for _, l := range languages {
+		if tag == strings.ToLower(l.Name) || tag == l.ISO1 || tag == l.ISO2 {
+			return l, true
+		}
+		for _, a := range l.Aliases {
+			if tag == a {
+				return l, true
+			}
+		}
+	}
+	return Language{}, false
+}
+
+// Code returns the language code in the given style, defaulting to ISO 639-2
+func (l Language) Code(style string) string {
+	if style == CodeISO6391 {
+		return l.ISO1
+	}
+	return l.ISO2
+}
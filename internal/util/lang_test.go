@@ -0,0 +1,37 @@
+package util
+
+import "testing"
+
+func TestLookupLanguage(t *testing.T) {
+	tests := []struct {
+		tag   string
+		want1 string
+		want2 string
+	}{
+		{"en", "en", "eng"},
+		{"ENG", "en", "eng"},
+		{"English", "en", "eng"},
+		{"jpn", "ja", "jpn"},
+		{"deu", "de", "ger"},
+		{"Brazilian Portuguese", "pt", "por"},
+	}
+	for _, tt := range tests {
+		l, ok := LookupLanguage(tt.tag)
+		if !ok {
+			t.Errorf("LookupLanguage(%q) not found", tt.tag)
+			continue
+		}
+		if got := l.Code(CodeISO6391); got != tt.want1 {
+			t.Errorf("%q 639-1 = %q, want %q", tt.tag, got, tt.want1)
+		}
+		if got := l.Code(CodeISO6392); got != tt.want2 {
+			t.Errorf("%q 639-2 = %q, want %q", tt.tag, got, tt.want2)
+		}
+	}
+
+	for _, tag := range []string{"", "forced", "1080p"} {
+		if _, ok := LookupLanguage(tag); ok {
+			t.Errorf("LookupLanguage(%q) should not match", tag)
+		}
+	}
+}
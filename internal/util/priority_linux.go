@@ -0,0 +1,88 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"syscall"
+
+	"github.com/mydehq/autotitle/internal/types"
+)
+
+// ioprio_set(2) constants
+const (
+	ioprioWhoProcess = 1
+	ioprioClassShift = 13
+	ioprioClassBE    = 2
+	ioprioClassIdle  = 3
+	ioprioLowestBE   = 7
+)
+
+// SetPriority lowers the CPU niceness and IO scheduling class of the whole
+// process. Zero values leave the current priority alone.
+//
+// Linux keeps both per thread, so every thread of the process is changed;
+// threads the Go runtime starts later inherit the priority of their parent.
+func SetPriority(p types.PriorityConfig) error {
+	class, err := ioClass(p.IOClass)
+	if err != nil {
+		return err
+	}
+	if p.Nice < 0 || p.Nice > 19 {
+		return fmt.Errorf("nice must be between 0 and 19, got %d", p.Nice)
+	}
+	if p.Nice == 0 && class == 0 {
+		return nil
+	}
+
+	tids, err := threads()
+	if err != nil {
+		return err
+	}
+	for _, tid := range tids {
+		if p.Nice != 0 {
+			if err := syscall.Setpriority(syscall.PRIO_PROCESS, tid, p.Nice); err != nil {
+				return fmt.Errorf("failed to set nice: %w", err)
+			}
+		}
+		if class != 0 {
+			level := 0
+			if class == ioprioClassBE {
+				level = ioprioLowestBE
+			}
+			prio := class<<ioprioClassShift | level
+			if _, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(tid), uintptr(prio)); errno != 0 {
+				return fmt.Errorf("failed to set IO class: %w", errno)
+			}
+		}
+	}
+	return nil
+}
+
+// threads lists the thread IDs of the process
+func threads() ([]int, error) {
+	entries, err := os.ReadDir("/proc/self/task")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list threads: %w", err)
+	}
+	var tids []int
+	for _, e := range entries {
+		if tid, err := strconv.Atoi(e.Name()); err == nil {
+			tids = append(tids, tid)
+		}
+	}
+	return tids, nil
+}
+
+func ioClass(name string) (int, error) {
+	switch name {
+	case "":
+		return 0, nil
+	case types.IOClassIdle:
+		return ioprioClassIdle, nil
+	case types.IOClassBestEffort:
+		return ioprioClassBE, nil
+	default:
+		return 0, fmt.Errorf("unknown IO class %q (use idle or best-effort)", name)
+	}
+}
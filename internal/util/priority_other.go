@@ -0,0 +1,17 @@
+//go:build !linux
+
+package util
+
+import (
+	"fmt"
+
+	"github.com/mydehq/autotitle/internal/types"
+)
+
+// SetPriority is only supported on Linux; elsewhere any setting is an error
+func SetPriority(p types.PriorityConfig) error {
+	if p.Nice != 0 || p.IOClass != "" {
+		return fmt.Errorf("process priority settings are only supported on Linux")
+	}
+	return nil
+}
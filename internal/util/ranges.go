@@ -51,3 +51,23 @@ func ParseRanges(s string) ([]int, error) {
 	slices.Sort(results)
 	return slices.Compact(results), nil
 }
+
+// FormatRanges formats integers as "1-3, 5, 7-9", the inverse of ParseRanges
+func FormatRanges(nums []int) string {
+	sorted := slices.Compact(slices.Sorted(slices.Values(nums)))
+
+	var parts []string
+	for i := 0; i < len(sorted); {
+		j := i
+		for j+1 < len(sorted) && sorted[j+1] == sorted[j]+1 {
+			j++
+		}
+		if j > i {
+			parts = append(parts, fmt.Sprintf("%d-%d", sorted[i], sorted[j]))
+		} else {
+			parts = append(parts, strconv.Itoa(sorted[i]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ", ")
+}
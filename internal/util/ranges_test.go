@@ -0,0 +1,26 @@
+package util
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFormatRanges(t *testing.T) {
+	tests := []struct {
+		nums []int
+		want string
+	}{
+		{nil, ""},
+		{[]int{5}, "5"},
+		{[]int{3, 1, 2, 5, 7, 8, 9, 8}, "1-3, 5, 7-9"},
+	}
+	for _, tt := range tests {
+		got := FormatRanges(tt.nums)
+		if got != tt.want {
+			t.Errorf("FormatRanges(%v) = %q; want %q", tt.nums, got, tt.want)
+		}
+		if back, err := ParseRanges(got); err != nil || !slices.Equal(back, slices.Compact(slices.Sorted(slices.Values(tt.nums)))) {
+			t.Errorf("ParseRanges(%q) = %v, %v; want the original numbers", got, back, err)
+		}
+	}
+}
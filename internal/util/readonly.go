@@ -0,0 +1,30 @@
+package util
+
+import (
+	"sync/atomic"
+
+	"github.com/mydehq/autotitle/internal/types"
+)
+
+// readOnly forbids every write to disk for the whole process
+var readOnly atomic.Bool
+
+// SetReadOnly turns read-only mode on or off. In read-only mode nothing is
+// written: no renames, backups, registries or caches, so autotitle can be
+// pointed at read-only snapshots.
+func SetReadOnly(on bool) {
+	readOnly.Store(on)
+}
+
+// ReadOnly reports whether read-only mode is on
+func ReadOnly() bool {
+	return readOnly.Load()
+}
+
+// CheckWritable returns types.ErrReadOnly for op in read-only mode
+func CheckWritable(op string) error {
+	if readOnly.Load() {
+		return types.ErrReadOnly{Op: op}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package util
+
+import (
+	"strings"
+	"unicode"
+)
+
+// kanaRomaji maps hiragana (single kana and yōon digraphs) to Hepburn romaji.
+// Katakana is folded to hiragana before lookup.
+var kanaRomaji = map[string]string{
+	"あ": "a", "い": "i", "う": "u", "え": "e", "お": "o",
+	"か": "ka", "き": "ki", "く": "ku", "け": "ke", "こ": "ko",
+	"さ": "sa", "し": "shi", "す": "su", "せ": "se", "そ": "so",
+	"た": "ta", "ち": "chi", "つ": "tsu", "て": "te", "と": "to",
+	"な": "na", "に": "ni", "ぬ": "nu", "ね": "ne", "の": "no",
+	"は": "ha", "ひ": "hi", "ふ": "fu", "へ": "he", "ほ": "ho",
+	"ま": "ma", "み": "mi", "む": "mu", "め": "me", "も": "mo",
+	"や": "ya", "ゆ": "yu", "よ": "yo",
+	"ら": "ra", "り": "ri", "る": "ru", "れ": "re", "ろ": "ro",
+	"わ": "wa", "ゐ": "i", "ゑ": "e", "を": "o", "ん": "n",
+	"が": "ga", "ぎ": "gi", "ぐ": "gu", "げ": "ge", "ご": "go",
+	"ざ": "za", "じ": "ji", "ず": "zu", "ぜ": "ze", "ぞ": "zo",
+	"だ": "da", "ぢ": "ji", "づ": "zu", "で": "de", "ど": "do",
+	"ば": "ba", "び": "bi", "ぶ": "bu", "べ": "be", "ぼ": "bo",
+	"ぱ": "pa", "ぴ": "pi", "ぷ": "pu", "ぺ": "pe", "ぽ": "po",
+	"ゔ": "vu",
+	"ぁ": "a", "ぃ": "i", "ぅ": "u", "ぇ": "e", "ぉ": "o",
+	"ゃ": "ya", "ゅ": "yu", "ょ": "yo", "ゎ": "wa",
+
+	"きゃ": "kya", "きゅ": "kyu", "きょ": "kyo",
+	"しゃ": "sha", "しゅ": "shu", "しょ": "sho", "しぇ": "she",
+	"ちゃ": "cha", "ちゅ": "chu", "ちょ": "cho", "ちぇ": "che",
+	"にゃ": "nya", "にゅ": "nyu", "にょ": "nyo",
+	"ひゃ": "hya", "ひゅ": "hyu", "ひょ": "hyo",
+	"みゃ": "mya", "みゅ": "myu", "みょ": "myo",
+	"りゃ": "rya", "りゅ": "ryu", "りょ": "ryo",
+	"ぎゃ": "gya", "ぎゅ": "gyu", "ぎょ": "gyo",
+	"じゃ": "ja", "じゅ": "ju", "じょ": "jo", "じぇ": "je",
+	"びゃ": "bya", "びゅ": "byu", "びょ": "byo",
+	"ぴゃ": "pya", "ぴゅ": "pyu", "ぴょ": "pyo",
+	"ふぁ": "fa", "ふぃ": "fi", "ふぇ": "fe", "ふぉ": "fo",
+	"てぃ": "ti", "でぃ": "di", "とぅ": "tu", "どぅ": "du",
+	"うぃ": "wi", "うぇ": "we", "うぉ": "wo",
+	"ゔぁ": "va", "ゔぃ": "vi", "ゔぇ": "ve", "ゔぉ": "vo",
+}
+
+// punctRomaji maps Japanese punctuation to ASCII equivalents
+var punctRomaji = map[rune]string{
+	'、': ",", '。': ".", '・': " ", '「': "\"", '」': "\"", '『': "\"", '』': "\"",
+	'（': "(", '）': ")", '！': "!", '？': "?", '：': ":", '〜': "~", '　': " ",
+}
+
+// Romanize transliterates hiragana and katakana to Hepburn romaji and folds
+// full-width ASCII and Japanese punctuation. Kanji cannot be read without a
+// dictionary and are kept as-is; use ContainsKanji to detect them.
+func Romanize(s string) string {
+	runes := []rune(s)
+	var b strings.Builder
+	geminate := false // Pending sokuon (っ): double the next consonant
+
+	for i := 0; i < len(runes); i++ {
+		r := toHiragana(runes[i])
+
+		if r == 'っ' {
+			geminate = true
+			continue
+		}
+
+		if r == 'ー' {
+			// Long vowel mark: repeat the previous vowel
+			out := b.String()
+			if n := len(out); n > 0 && strings.ContainsRune("aeiou", rune(out[n-1])) {
+				b.WriteByte(out[n-1])
+			}
+			continue
+		}
+
+		// Prefer digraphs (きゃ) over single kana
+		var romaji string
+		if i+1 < len(runes) {
+			if v, ok := kanaRomaji[string([]rune{r, toHiragana(runes[i+1])})]; ok {
+				romaji = v
+				i++
+			}
+		}
+		if romaji == "" {
+			if v, ok := kanaRomaji[string(r)]; ok {
+				romaji = v
+			}
+		}
+
+		if romaji == "" {
+			geminate = false
+			switch {
+			case punctRomaji[r] != "":
+				b.WriteString(punctRomaji[r])
+			case r >= '！' && r <= '～':
+				b.WriteRune(r - 0xFEE0) // Full-width ASCII
+			default:
+				b.WriteRune(r)
+			}
+			continue
+		}
+
+		if geminate {
+			if strings.HasPrefix(romaji, "ch") {
+				b.WriteByte('t')
+			} else if !strings.ContainsRune("aeioun", rune(romaji[0])) {
+				b.WriteByte(romaji[0])
+			}
+			geminate = false
+		}
+		b.WriteString(romaji)
+	}
+
+	return b.String()
+}
+
+// ContainsKanji reports whether s contains Han characters
+func ContainsKanji(s string) bool {
+	for _, r := range s {
+		if unicode.Is(unicode.Han, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// toHiragana folds katakana to the corresponding hiragana
+func toHiragana(r rune) rune {
+	if r >= 'ァ' && r <= 'ヶ' {
+		return r - 0x60
+	}
+	return r
+}
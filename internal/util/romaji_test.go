@@ -0,0 +1,25 @@
+package util
+
+import "testing"
+
+func TestRomanize(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"ラーメン", "raamen"},
+		{"マッチ", "matchi"},
+		{"きょうのりょうり", "kyounoryouri"},
+		{"がっこう", "gakkou"},
+		{"進撃の巨人", "進撃no巨人"},
+		{"ＡＢＣ！", "ABC!"},
+	}
+	for _, tt := range tests {
+		if got := Romanize(tt.in); got != tt.want {
+			t.Errorf("Romanize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	if !ContainsKanji("進撃") || ContainsKanji("しんげき") {
+		t.Error("ContainsKanji misclassified input")
+	}
+}
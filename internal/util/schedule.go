@@ -0,0 +1,60 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// maxTaskCommand is the longest command schtasks accepts for /TR
+const maxTaskCommand = 261
+
+// TaskCommand builds the command line a scheduled task runs: exe and args,
+// each quoted the way Windows programs split their command line
+func TaskCommand(exe string, args []string) (string, error) {
+	parts := []string{quoteWindowsArg(exe)}
+	for _, a := range args {
+		parts = append(parts, quoteWindowsArg(a))
+	}
+	cmd := strings.Join(parts, " ")
+	if len(cmd) > maxTaskCommand {
+		return "", fmt.Errorf("task command is %d characters; scheduled tasks allow %d", len(cmd), maxTaskCommand)
+	}
+	return cmd, nil
+}
+
+// TaskTime checks a daily start time and returns it as schtasks wants it (HH:MM)
+func TaskTime(at string) (string, error) {
+	t, err := time.Parse("15:04", at)
+	if err != nil {
+		return "", fmt.Errorf("invalid time %q (use HH:MM, e.g. 03:00)", at)
+	}
+	return t.Format("15:04"), nil
+}
+
+// quoteWindowsArg quotes s for CommandLineToArgvW: backslashes are literal
+// unless they precede a quote, which is escaped with a backslash
+func quoteWindowsArg(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\"") {
+		return s
+	}
+	var b strings.Builder
+	b.WriteByte('"')
+	slashes := 0
+	for _, c := range s {
+		switch c {
+		case '\\':
+			slashes++
+		case '"':
+			b.WriteString(strings.Repeat(`\`, slashes+1))
+			slashes = 0
+		default:
+			slashes = 0
+		}
+		b.WriteRune(c)
+	}
+	// Trailing backslashes would escape the closing quote
+	b.WriteString(strings.Repeat(`\`, slashes))
+	b.WriteByte('"')
+	return b.String()
+}
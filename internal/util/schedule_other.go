@@ -0,0 +1,19 @@
+//go:build !windows
+
+package util
+
+import "fmt"
+
+// errNoTaskScheduler is returned off Windows, where cron or a systemd timer
+// running the same command does the job
+var errNoTaskScheduler = fmt.Errorf("scheduled tasks are only supported on Windows; use cron or a systemd timer instead")
+
+// InstallTask is only supported on Windows
+func InstallTask(name, command, at string) error {
+	return errNoTaskScheduler
+}
+
+// UninstallTask is only supported on Windows
+func UninstallTask(name string) error {
+	return errNoTaskScheduler
+}
@@ -0,0 +1,31 @@
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTaskCommand(t *testing.T) {
+	got, err := TaskCommand(`C:\Program Files\autotitle\autotitle.exe`, []string{"sort", `D:\My Downloads\`, `say "hi"`, "--dry-run"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `"C:\Program Files\autotitle\autotitle.exe" sort "D:\My Downloads\\" "say \"hi\"" --dry-run`
+	if got != want {
+		t.Errorf("TaskCommand =\n%s\nwant\n%s", got, want)
+	}
+	if _, err := TaskCommand("autotitle.exe", []string{strings.Repeat("x", 300)}); err == nil {
+		t.Error("expected error for a command over the schtasks limit")
+	}
+}
+
+func TestTaskTime(t *testing.T) {
+	if got, err := TaskTime("3:05"); err != nil || got != "03:05" {
+		t.Errorf("TaskTime(3:05) = %q, %v", got, err)
+	}
+	for _, at := range []string{"25:00", "3am", ""} {
+		if _, err := TaskTime(at); err == nil {
+			t.Errorf("TaskTime(%q): expected error", at)
+		}
+	}
+}
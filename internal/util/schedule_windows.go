@@ -0,0 +1,32 @@
+package util
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// InstallTask registers (or replaces) a Scheduled Task named name that runs
+// command every day at the given time, as the current user
+func InstallTask(name, command, at string) error {
+	if err := CheckWritable("install scheduled tasks"); err != nil {
+		return err
+	}
+	return schtasks("/Create", "/F", "/TN", name, "/TR", command, "/SC", "DAILY", "/ST", at)
+}
+
+// UninstallTask removes the Scheduled Task named name
+func UninstallTask(name string) error {
+	if err := CheckWritable("remove scheduled tasks"); err != nil {
+		return err
+	}
+	return schtasks("/Delete", "/F", "/TN", name)
+}
+
+func schtasks(args ...string) error {
+	out, err := exec.Command("schtasks.exe", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("schtasks failed: %w\noutput: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
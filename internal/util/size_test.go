@@ -0,0 +1,24 @@
+package util
+
+import "testing"
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]int64{
+		"":        0,
+		"4096":    4096,
+		"512K":    512 << 10,
+		"500MB":   500 << 20,
+		"1.5 GiB": 3 << 29,
+		"2gb":     2 << 30,
+	}
+	for in, want := range tests {
+		if got, err := ParseSize(in); err != nil || got != want {
+			t.Errorf("ParseSize(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"MB", "12 parsecs", "-5MB", "1.2.3G"} {
+		if _, err := ParseSize(in); err == nil {
+			t.Errorf("ParseSize(%q): expected an error", in)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package util
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Slugify converts a title to a lowercase ASCII slug. Kana are romanized and
+// accents stripped; if letters remain that cannot be transliterated (e.g.
+// kanji), a short hash of the title is appended so the slug stays unique and
+// is never empty.
+func Slugify(title string) string {
+	var b strings.Builder
+	dropped := false
+	dash := false
+
+	for _, r := range strings.ToLower(norm.NFD.String(Romanize(title))) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		case r == '-' || unicode.IsSpace(r):
+			dash = true
+		case unicode.Is(unicode.Mn, r):
+			// Combining accent left by NFD (é -> e)
+		case r > unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			dropped = true
+		}
+	}
+
+	slug := b.String()
+	if dropped {
+		sum := sha256.Sum256([]byte(title))
+		hash := hex.EncodeToString(sum[:4])
+		if slug == "" {
+			return hash
+		}
+		return slug + "-" + hash
+	}
+	return slug
+}
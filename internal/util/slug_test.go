@@ -0,0 +1,32 @@
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Shingeki no Kyojin", "shingeki-no-kyojin"},
+		{"Re:Zero - Starting Life", "rezero-starting-life"},
+		{"Pokémon", "pokemon"},
+		{"ドラゴンボール", "doragonbooru"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Slugify(tt.in); got != tt.want {
+			t.Errorf("Slugify(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	// Kanji-only titles get a deterministic, distinct hash instead of an empty slug
+	a, b := Slugify("進撃の巨人"), Slugify("鋼の錬金術師")
+	if a == "" || a == b || a != Slugify("進撃の巨人") {
+		t.Errorf("Expected stable distinct slugs, got %q and %q", a, b)
+	}
+	if !strings.HasPrefix(a, "no-") {
+		t.Errorf("Expected kana to be kept alongside the hash, got %q", a)
+	}
+}
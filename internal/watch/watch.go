@@ -0,0 +1,248 @@
+// Package watch follows folder trees with fsnotify for watch mode. A folder
+// is reported once its files have settled, so a download still being
+// written is not renamed halfway, and the global config as soon as it
+// changes.
+package watch
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// configSettle is how long the global config must stay unchanged before it
+// is reported: editors write a file in more than one step
+const configSettle = 500 * time.Millisecond
+
+// Change is what happened in a folder since it was last reported
+type Change struct {
+	Dir     string
+	Created bool     // The folder appeared while watching
+	Files   []string // Names of the entries written, renamed or removed
+}
+
+// Options sets up a Watcher
+type Options struct {
+	Roots  []string
+	Global string        // Global config file, "" for none
+	Settle time.Duration // Quiet time before a folder is reported
+	// Skip reports whether a folder, by base name, is left unwatched (the
+	// backup dirs, ignore_dirs); nil watches all
+	Skip func(name string) bool
+}
+
+// Handler receives what the watcher saw. The callbacks run one at a time on
+// the goroutine of Run, so a config reload never lands in the middle of a
+// rename.
+type Handler struct {
+	Folder func(c Change) // A folder settled
+	Global func()         // The global config changed, or Reload was called
+	Error  func(err error)
+}
+
+// Watcher watches folder trees; create it with New, then Run it
+type Watcher struct {
+	fs      *fsnotify.Watcher
+	options Options
+	dirs    map[string]bool     // Folders watched
+	pending map[string]*pending // Folders changed, by path
+	global  time.Time           // When the global config last changed, zero if reported
+	reload  chan struct{}
+}
+
+// pending is a change not yet reported, and when it last grew
+type pending struct {
+	Change
+	at time.Time
+}
+
+// New starts watching the roots and every folder under them
+func New(options Options) (*Watcher, error) {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{
+		fs:      fsw,
+		options: options,
+		dirs:    make(map[string]bool),
+		pending: make(map[string]*pending),
+		reload:  make(chan struct{}, 1),
+	}
+	for _, root := range options.Roots {
+		if _, err := w.addTree(root); err != nil {
+			_ = fsw.Close()
+			return nil, err
+		}
+	}
+	if options.Global != "" {
+		// Watch the folder: editors replace the file rather than write it
+		if err := fsw.Add(filepath.Dir(options.Global)); err != nil && !os.IsNotExist(err) {
+			_ = fsw.Close()
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// Reload has Run report the global config as changed, e.g. on SIGHUP
+func (w *Watcher) Reload() {
+	select {
+	case w.reload <- struct{}{}:
+	default:
+	}
+}
+
+// Run reports changes to h until ctx is cancelled, and returns its error
+func (w *Watcher) Run(ctx context.Context, h Handler) error {
+	defer func() { _ = w.fs.Close() }()
+	tick := time.NewTicker(min(max(w.options.Settle/4, 10*time.Millisecond), time.Second))
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case e, ok := <-w.fs.Events:
+			if !ok {
+				return nil
+			}
+			w.observe(e, time.Now())
+		case err, ok := <-w.fs.Errors:
+			if ok && h.Error != nil {
+				h.Error(err)
+			}
+		case <-w.reload:
+			if h.Global != nil {
+				h.Global()
+			}
+		case now := <-tick.C:
+			w.dispatch(ctx, now, h)
+		}
+	}
+}
+
+// observe records one event
+func (w *Watcher) observe(e fsnotify.Event, now time.Time) {
+	if e.Op == fsnotify.Chmod {
+		return
+	}
+	if w.options.Global != "" && e.Name == w.options.Global {
+		w.global = now
+		return
+	}
+	dir := filepath.Dir(e.Name)
+	if !w.dirs[dir] {
+		return // Another file next to the global config
+	}
+
+	if e.Has(fsnotify.Create) {
+		if info, err := os.Stat(e.Name); err == nil && info.IsDir() {
+			if w.skip(e.Name) {
+				return
+			}
+			// Files moved in with the folder raise no events of their own
+			added, _ := w.addTree(e.Name)
+			for _, d := range added {
+				w.mark(d, "", true, now)
+			}
+			return
+		}
+	}
+	if e.Has(fsnotify.Remove) || e.Has(fsnotify.Rename) {
+		if w.dirs[e.Name] {
+			w.forget(e.Name)
+		}
+	}
+	w.mark(dir, filepath.Base(e.Name), false, now)
+}
+
+// mark adds to the pending change of dir
+func (w *Watcher) mark(dir, file string, created bool, now time.Time) {
+	p := w.pending[dir]
+	if p == nil {
+		p = &pending{Change: Change{Dir: dir}}
+		w.pending[dir] = p
+	}
+	p.at = now
+	p.Created = p.Created || created
+	if file != "" && !slices.Contains(p.Files, file) {
+		p.Files = append(p.Files, file)
+	}
+}
+
+// dispatch reports the global config, then the folders, that have settled
+func (w *Watcher) dispatch(ctx context.Context, now time.Time, h Handler) {
+	if !w.global.IsZero() && now.Sub(w.global) >= configSettle {
+		w.global = time.Time{}
+		if h.Global != nil {
+			h.Global()
+		}
+	}
+
+	var settled []string
+	for dir, p := range w.pending {
+		if now.Sub(p.at) >= w.options.Settle {
+			settled = append(settled, dir)
+		}
+	}
+	slices.Sort(settled)
+	for _, dir := range settled {
+		if ctx.Err() != nil {
+			return
+		}
+		p := w.pending[dir]
+		delete(w.pending, dir)
+		if _, err := os.Stat(dir); err != nil {
+			continue // Gone again, e.g. a temporary folder
+		}
+		if h.Folder != nil {
+			h.Folder(p.Change)
+		}
+	}
+}
+
+// addTree watches dir and the folders under it, and returns them
+func (w *Watcher) addTree(dir string) ([]string, error) {
+	var added []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != dir && w.skip(path) {
+			return filepath.SkipDir
+		}
+		if err := w.fs.Add(path); err != nil {
+			return err
+		}
+		w.dirs[path] = true
+		added = append(added, path)
+		return nil
+	})
+	return added, err
+}
+
+// forget stops tracking dir and the folders under it; their watches went
+// with them
+func (w *Watcher) forget(dir string) {
+	prefix := dir + string(filepath.Separator)
+	for d := range w.dirs {
+		if d == dir || strings.HasPrefix(d, prefix) {
+			delete(w.dirs, d)
+			delete(w.pending, d)
+		}
+	}
+}
+
+func (w *Watcher) skip(path string) bool {
+	return w.options.Skip != nil && w.options.Skip(filepath.Base(path))
+}
@@ -0,0 +1,96 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestWatcher(t *testing.T) {
+	root := t.TempDir()
+	show := filepath.Join(root, "Show")
+	if err := os.MkdirAll(filepath.Join(show, "backups"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	global := filepath.Join(t.TempDir(), "config.yml")
+
+	w, err := New(Options{
+		Roots:  []string{root},
+		Global: global,
+		Settle: 100 * time.Millisecond,
+		Skip:   func(name string) bool { return name == "backups" },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes := make(chan Change, 10)
+	globals := make(chan struct{}, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- w.Run(ctx, Handler{
+			Folder: func(c Change) { changes <- c },
+			Global: func() { globals <- struct{}{} },
+		})
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	next := func() Change {
+		t.Helper()
+		select {
+		case c := <-changes:
+			return c
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a change")
+			return Change{}
+		}
+	}
+
+	// Writes in a row are reported once, after they settle; the skipped
+	// folder is not watched
+	for _, name := range []string{"ep1.mkv", "ep2.mkv", "backups/ep1.mkv"} {
+		if err := os.WriteFile(filepath.Join(show, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := next()
+	slices.Sort(c.Files)
+	if c.Dir != show || c.Created || !slices.Equal(c.Files, []string{"ep1.mkv", "ep2.mkv"}) {
+		t.Errorf("change = %+v, want ep1.mkv and ep2.mkv in %s", c, show)
+	}
+
+	// A folder moved in whole is reported as created, with its subfolders
+	incoming := filepath.Join(t.TempDir(), "New Show")
+	if err := os.MkdirAll(filepath.Join(incoming, "Season 1"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(incoming, filepath.Join(root, "New Show")); err != nil {
+		t.Fatal(err)
+	}
+	got := []string{next().Dir, next().Dir}
+	slices.Sort(got)
+	want := []string{filepath.Join(root, "New Show"), filepath.Join(root, "New Show", "Season 1")}
+	if !slices.Equal(got, want) {
+		t.Errorf("created = %v, want %v", got, want)
+	}
+
+	// The global config, written or asked for
+	if err := os.WriteFile(global, []byte("map_file: _autotitle.yml\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w.Reload()
+	for range 2 {
+		select {
+		case <-globals:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the global config")
+		}
+	}
+}
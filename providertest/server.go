@@ -0,0 +1,321 @@
+// Package providertest provides a fake Jikan and AnimeFillerList server for
+// testing code that fetches media, including outage scenarios such as 429
+// storms, truncated responses and schema drift.
+package providertest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	jikanPrefix  = "/jikan/v4"
+	fillerPrefix = "/afl/shows"
+)
+
+// Anime is a series served by the fake Jikan API
+type Anime struct {
+	ID       int
+	Title    string
+	TitleEN  string
+	TitleJP  string
+	Synonyms []string
+	Status   string
+	Type     string // Jikan type, e.g. "TV" or "Movie"
+	Year     int
+	Episodes []Episode
+}
+
+// Episode is an episode served by the fake Jikan API
+type Episode struct {
+	Number      int
+	Title       string
+	TitleJP     string
+	TitleRomaji string
+	Aired       string
+}
+
+// Fault is a failure mode injected into matching responses
+type Fault int
+
+const (
+	// FaultRateLimit responds 429 with "Retry-After: 0"
+	FaultRateLimit Fault = iota + 1
+	// FaultUnavailable responds 503 with "Retry-After: 0"
+	FaultUnavailable
+	// FaultTruncate cuts the JSON body in half
+	FaultTruncate
+	// FaultSchemaDrift serves fields with unexpected types
+	FaultSchemaDrift
+	// FaultHang never answers; the request blocks until the client gives up
+	FaultHang
+)
+
+type faultRule struct {
+	match     string
+	fault     Fault
+	remaining int // < 0 means forever
+}
+
+// Server is a fake provider server. Point providers at it with BaseURLs and FillerURL.
+type Server struct {
+	*httptest.Server
+
+	// PageSize is the number of episodes per page (Jikan uses 100)
+	PageSize int
+
+	mu       sync.Mutex
+	anime    map[int]Anime
+	fillers  map[string][]int
+	faults   []*faultRule
+	requests []string
+}
+
+// NewServer starts a fake provider server. Call Close when done.
+func NewServer() *Server {
+	s := &Server{
+		PageSize: 100,
+		anime:    make(map[int]Anime),
+		fillers:  make(map[string][]int),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// AddAnime registers a series on the fake Jikan API
+func (s *Server) AddAnime(a Anime) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.anime[a.ID] = a
+}
+
+// AddFillers registers the filler episodes of a show slug on the fake AnimeFillerList
+func (s *Server) AddFillers(slug string, episodes []int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fillers[slug] = episodes
+}
+
+// Inject makes the next times requests whose URI contains match fail with
+// fault (times < 0 fails forever). The URI is relative to the API root,
+// e.g. "/anime/1/episodes?page=2".
+func (s *Server) Inject(match string, fault Fault, times int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &faultRule{match: match, fault: fault, remaining: times})
+}
+
+// ClearFaults removes all injected faults, ending an outage
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// Requests returns the URIs requested so far, relative to the API root
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.requests...)
+}
+
+// JikanURL returns the base URL of the fake Jikan API
+func (s *Server) JikanURL() string {
+	return s.URL + jikanPrefix
+}
+
+// FillerURL returns the shows URL of the fake AnimeFillerList
+func (s *Server) FillerURL() string {
+	return s.URL + fillerPrefix
+}
+
+// BaseURLs returns the api.base_urls overrides that route providers to this server
+func (s *Server) BaseURLs() map[string]string {
+	return map[string]string{"mal": s.JikanURL()}
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	uri := r.URL.RequestURI()
+	var prefix string
+	switch {
+	case strings.HasPrefix(uri, jikanPrefix):
+		prefix = jikanPrefix
+	case strings.HasPrefix(uri, fillerPrefix):
+		prefix = fillerPrefix
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	rel := strings.TrimPrefix(uri, prefix)
+
+	s.mu.Lock()
+	s.requests = append(s.requests, rel)
+	fault := s.takeFault(rel)
+	s.mu.Unlock()
+
+	switch fault {
+	case FaultRateLimit:
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+		return
+	case FaultUnavailable:
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	case FaultHang:
+		<-r.Context().Done()
+		return
+	}
+
+	if prefix == fillerPrefix {
+		s.serveFillers(w, strings.Trim(r.URL.Path[len(fillerPrefix):], "/"))
+		return
+	}
+
+	body, status := s.jikanResponse(r, fault == FaultSchemaDrift)
+	if fault == FaultTruncate {
+		body = body[:len(body)/2]
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
+
+// takeFault returns the first active fault matching rel. Callers hold s.mu.
+func (s *Server) takeFault(rel string) Fault {
+	for _, f := range s.faults {
+		if f.remaining == 0 || !strings.Contains(rel, f.match) {
+			continue
+		}
+		if f.remaining > 0 {
+			f.remaining--
+		}
+		return f.fault
+	}
+	return 0
+}
+
+func (s *Server) jikanResponse(r *http.Request, drift bool) ([]byte, int) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, jikanPrefix), "/"), "/")
+	if len(parts) == 0 || parts[0] != "anime" {
+		return jsonError(http.StatusNotFound), http.StatusNotFound
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(parts) == 1 {
+		return s.search(r.URL.Query().Get("q")), http.StatusOK
+	}
+
+	id, err := strconv.Atoi(parts[1])
+	a, ok := s.anime[id]
+	if err != nil || !ok {
+		return jsonError(http.StatusNotFound), http.StatusNotFound
+	}
+
+	if len(parts) == 3 && parts[2] == "episodes" {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		return s.episodes(a, max(page, 1), drift), http.StatusOK
+	}
+
+	data := map[string]any{
+		"mal_id":         a.ID,
+		"title":          a.Title,
+		"title_english":  a.TitleEN,
+		"title_japanese": a.TitleJP,
+		"title_synonyms": a.Synonyms,
+		"status":         a.Status,
+		"type":           a.Type,
+	}
+	if a.Year > 0 {
+		data["year"] = a.Year
+	}
+	if drift {
+		data["title_synonyms"] = strings.Join(a.Synonyms, ", ")
+	}
+	return mustJSON(map[string]any{"data": data}), http.StatusOK
+}
+
+func (s *Server) episodes(a Anime, page int, drift bool) []byte {
+	size := max(s.PageSize, 1)
+	lastPage := max((len(a.Episodes)+size-1)/size, 1)
+	start := min((page-1)*size, len(a.Episodes))
+	end := min(start+size, len(a.Episodes))
+
+	data := make([]map[string]any, 0, end-start)
+	for _, ep := range a.Episodes[start:end] {
+		var id any = ep.Number
+		if drift {
+			id = strconv.Itoa(ep.Number)
+		}
+		data = append(data, map[string]any{
+			"mal_id":         id,
+			"title":          ep.Title,
+			"title_japanese": ep.TitleJP,
+			"title_romanji":  ep.TitleRomaji,
+			"aired":          ep.Aired,
+		})
+	}
+
+	return mustJSON(map[string]any{
+		"data": data,
+		"pagination": map[string]any{
+			"last_visible_page": lastPage,
+			"has_next_page":     page < lastPage,
+		},
+	})
+}
+
+func (s *Server) search(query string) []byte {
+	query = strings.ToLower(query)
+	data := []map[string]any{}
+	for _, a := range s.anime {
+		if strings.Contains(strings.ToLower(a.Title), query) {
+			data = append(data, map[string]any{
+				"mal_id": a.ID,
+				"title":  a.Title,
+				"type":   a.Type,
+				"url":    fmt.Sprintf("https://myanimelist.net/anime/%d", a.ID),
+			})
+		}
+	}
+	return mustJSON(map[string]any{"data": data})
+}
+
+func (s *Server) serveFillers(w http.ResponseWriter, slug string) {
+	s.mu.Lock()
+	episodes, ok := s.fillers[slug]
+	s.mu.Unlock()
+	if !ok {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString("<html><body><table>")
+	for _, n := range episodes {
+		fmt.Fprintf(&b, `<tr class="filler odd"><td class="Number">%d</td><td class="Title">Episode %d</td></tr>`, n, n)
+	}
+	b.WriteString("</table></body></html>")
+
+	w.Header().Set("Content-Type", "text/html")
+	_, _ = w.Write([]byte(b.String()))
+}
+
+func jsonError(status int) []byte {
+	return mustJSON(map[string]any{"status": status, "message": http.StatusText(status)})
+}
+
+func mustJSON(v any) []byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
@@ -0,0 +1,78 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mydehq/autotitle"
+	"github.com/mydehq/autotitle/providertest"
+)
+
+func TestDBGen_InjectedClock(t *testing.T) {
+	ctx := context.Background()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	srv := providertest.NewServer()
+	defer srv.Close()
+	srv.AddAnime(providertest.Anime{
+		ID:     7,
+		Title:  "Airing Show",
+		Status: "Currently Airing",
+		Episodes: []providertest.Episode{
+			{Number: 1, Title: "Pilot", Aired: "2030-01-01T00:00:00+00:00"},
+			{Number: 2, Title: "Next", Aired: "2030-01-08T00:00:00+00:00"},
+		},
+	})
+
+	cfgDir := filepath.Join(home, ".config", "autotitle")
+	if err := os.MkdirAll(cfgDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	global := fmt.Sprintf("api:\n  rate_limit: 1000\n  base_urls:\n    mal: %q\n", srv.JikanURL())
+	if err := os.WriteFile(filepath.Join(cfgDir, "config.yml"), []byte(global), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	const url = "https://myanimelist.net/anime/7/Airing_Show"
+	day := func(d int) func() time.Time {
+		return func() time.Time { return time.Date(2030, 1, d, 12, 0, 0, 0, time.UTC) }
+	}
+
+	generated, err := autotitle.DBGen(ctx, url, autotitle.WithNow(day(2)))
+	if err != nil || !generated {
+		t.Fatalf("Initial DBGen: generated=%v err=%v", generated, err)
+	}
+	media, err := autotitle.DBInfo(ctx, "mal", "7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !media.LastUpdate.Equal(day(2)()) {
+		t.Errorf("LastUpdate should follow the injected clock, got %v", media.LastUpdate)
+	}
+	if media.NextEpisodeAirDate == nil || *media.NextEpisodeAirDate != "2030-01-08T00:00:00+00:00" {
+		t.Errorf("Expected next air date 2030-01-08, got %v", media.NextEpisodeAirDate)
+	}
+
+	// Before the next episode airs the cached entry is still fresh
+	if generated, err := autotitle.DBGen(ctx, url, autotitle.WithNow(day(5))); err != nil || generated {
+		t.Errorf("Expected cached entry to be reused: generated=%v err=%v", generated, err)
+	}
+
+	// Once it has aired the entry expires
+	if generated, err := autotitle.DBGen(ctx, url, autotitle.WithNow(day(9))); err != nil || !generated {
+		t.Errorf("Expected expired entry to be refetched: generated=%v err=%v", generated, err)
+	}
+
+	stats, err := autotitle.DBStats(ctx, autotitle.WithNow(day(9)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats.DueForRefresh) != 1 {
+		t.Errorf("Expected the airing show to be due for refresh at day 9, got %v", stats.DueForRefresh)
+	}
+}
@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mydehq/autotitle"
+	"github.com/mydehq/autotitle/internal/database"
+	"github.com/mydehq/autotitle/internal/provider"
+	"github.com/mydehq/autotitle/internal/types"
+)
+
+// stubFillers is a filler source for https://fillers.test/shows/<slug>.
+// The "down" show fails to fetch.
+type stubFillers struct {
+	mu      sync.Mutex
+	lists   map[string][]int
+	fetches int
+}
+
+func (s *stubFillers) Name() string            { return "stub" }
+func (s *stubFillers) Website() string         { return "https://fillers.test" }
+func (s *stubFillers) SupportedURLs() []string { return []string{"fillers.test/shows/"} }
+func (s *stubFillers) MatchesURL(url string) bool {
+	return strings.Contains(url, "fillers.test/shows/")
+}
+func (s *stubFillers) ExtractSlug(url string) (string, error) {
+	_, slug, _ := strings.Cut(url, "fillers.test/shows/")
+	return slug, nil
+}
+func (s *stubFillers) FetchFillers(ctx context.Context, slug string) ([]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fetches++
+	if slug == "down" {
+		return nil, errors.New("filler site unavailable")
+	}
+	return s.lists[slug], nil
+}
+
+var (
+	stubFillerSource = &stubFillers{lists: map[string][]int{"first": {2}, "second": {3}}}
+	registerStubOnce sync.Once
+)
+
+func TestDBGen_FillerURLChange(t *testing.T) {
+	ctx := context.Background()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	registerStubOnce.Do(func() { provider.RegisterFillerSource(stubFillerSource) })
+
+	// Fresh cache generated without fillers
+	db, err := database.NewRepository("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	media := &types.Media{
+		ID: "77", Provider: "mal", Title: "Filler Change", Slug: "filler-change", Status: "Finished Airing",
+		Episodes: []types.Episode{{Number: 1}, {Number: 2}, {Number: 3}},
+	}
+	if err := db.Save(ctx, media); err != nil {
+		t.Fatal(err)
+	}
+
+	const url = "https://myanimelist.net/anime/77/Filler_Change"
+	fillersOf := func() []int {
+		t.Helper()
+		m, err := db.Load(ctx, "mal", "77")
+		if err != nil || m == nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		var nums []int
+		for _, ep := range m.Episodes {
+			if ep.IsFiller {
+				nums = append(nums, ep.Number)
+			}
+		}
+		return nums
+	}
+
+	quiet := autotitle.WithEvents(func(types.Event) {})
+	fetches := stubFillerSource.fetches
+
+	// Config gains a filler URL: fillers are applied without a forced refresh
+	if updated, err := autotitle.DBGen(ctx, url, quiet, autotitle.WithFiller("https://fillers.test/shows/first")); err != nil || !updated {
+		t.Fatalf("Expected filler update: updated=%v err=%v", updated, err)
+	}
+	if got := fillersOf(); len(got) != 1 || got[0] != 2 {
+		t.Errorf("Expected episode 2 flagged, got %v", got)
+	}
+
+	// Same URL again: nothing to do
+	if updated, _ := autotitle.DBGen(ctx, url, quiet, autotitle.WithFiller("https://fillers.test/shows/first")); updated {
+		t.Error("Unchanged filler URL should not update the database")
+	}
+	if stubFillerSource.fetches != fetches+1 {
+		t.Errorf("Expected one filler fetch, got %d", stubFillerSource.fetches-fetches)
+	}
+
+	// Different list replaces the old flags
+	if _, err := autotitle.DBGen(ctx, url, quiet, autotitle.WithFiller("https://fillers.test/shows/second")); err != nil {
+		t.Fatal(err)
+	}
+	if got := fillersOf(); len(got) != 1 || got[0] != 3 {
+		t.Errorf("Expected only episode 3 flagged, got %v", got)
+	}
+
+	// A list that fails to fetch keeps the entry and its flags
+	var warnings []string
+	collect := autotitle.WithEvents(func(e types.Event) {
+		if e.Type == types.EventWarning {
+			warnings = append(warnings, e.Message)
+		}
+	})
+	updated, err := autotitle.DBGen(ctx, url, collect, autotitle.WithFiller("https://fillers.test/shows/down"))
+	if err != nil || updated {
+		t.Fatalf("Expected a failed filler fetch to keep the entry: updated=%v err=%v", updated, err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "filler site unavailable") {
+		t.Errorf("Expected one warning naming the failure, got %v", warnings)
+	}
+	if got := fillersOf(); len(got) != 1 || got[0] != 3 {
+		t.Errorf("Expected episode 3 still flagged, got %v", got)
+	}
+}
@@ -0,0 +1,142 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mydehq/autotitle"
+	"github.com/mydehq/autotitle/internal/backup"
+	"github.com/mydehq/autotitle/internal/database"
+	"github.com/mydehq/autotitle/internal/types"
+)
+
+var update = flag.Bool("update", false, "rewrite expected.txt of golden scenarios")
+
+// A golden scenario is a directory under testdata/scenarios containing:
+//
+//	input/        directory fixture, including its _autotitle.yml
+//	media.json    fake database entry (types.Media) the map file's URL resolves to
+//	config.yml    optional global config
+//	expected.txt  final tree after rename, one relative path per line
+//
+// Each scenario runs the full Rename pipeline in an isolated HOME, compares
+// the resulting tree, then undoes the rename and expects the input tree back.
+// Run `go test ./tests -run TestGolden -update` to regenerate expected.txt.
+func TestGoldenScenarios(t *testing.T) {
+	scenarios, err := filepath.Glob(filepath.Join("testdata", "scenarios", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scenarios) == 0 {
+		t.Fatal("No golden scenarios found")
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(filepath.Base(scenario), func(t *testing.T) {
+			runGoldenScenario(t, scenario)
+		})
+	}
+}
+
+func runGoldenScenario(t *testing.T, scenario string) {
+	ctx := context.Background()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	// Fake database
+	data, err := os.ReadFile(filepath.Join(scenario, "media.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var media types.Media
+	if err := json.Unmarshal(data, &media); err != nil {
+		t.Fatalf("Invalid media.json: %v", err)
+	}
+	db, err := database.NewRepository(filepath.Join(home, ".cache", "autotitle", "db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Save(ctx, &media); err != nil {
+		t.Fatal(err)
+	}
+
+	// Optional global config
+	if global, err := os.ReadFile(filepath.Join(scenario, "config.yml")); err == nil {
+		dir := filepath.Join(home, ".config", "autotitle")
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "config.yml"), global, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dir := filepath.Join(t.TempDir(), "media")
+	if err := os.CopyFS(dir, os.DirFS(filepath.Join(scenario, "input"))); err != nil {
+		t.Fatal(err)
+	}
+	before := listTree(t, dir)
+
+	quiet := autotitle.WithEvents(func(types.Event) {})
+	if _, err := autotitle.Rename(ctx, dir, quiet, autotitle.WithNoTagging()); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+
+	got := listTree(t, dir)
+	expectedPath := filepath.Join(scenario, "expected.txt")
+	if *update {
+		if err := os.WriteFile(expectedPath, []byte(strings.Join(got, "\n")+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(expectedPath)
+	if err != nil {
+		t.Fatalf("Missing expected.txt (run with -update): %v", err)
+	}
+	expected := strings.Split(strings.TrimSpace(string(want)), "\n")
+	if !slices.Equal(got, expected) {
+		t.Errorf("Tree mismatch\n got:\n  %s\nwant:\n  %s", strings.Join(got, "\n  "), strings.Join(expected, "\n  "))
+	}
+
+	if err := autotitle.Undo(ctx, dir, quiet); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if restored := listTree(t, dir); !slices.Equal(restored, before) {
+		t.Errorf("Undo did not restore the input tree\n got: %v\nwant: %v", restored, before)
+	}
+}
+
+// listTree returns the sorted relative paths of all files under dir, excluding backups
+func listTree(t *testing.T, dir string) []string {
+	t.Helper()
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == backup.DefaultDirName {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(files)
+	return files
+}
@@ -0,0 +1,119 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mydehq/autotitle"
+	"github.com/mydehq/autotitle/internal/database"
+	"github.com/mydehq/autotitle/internal/history"
+	"github.com/mydehq/autotitle/internal/types"
+)
+
+func TestStats_RecordsRenames(t *testing.T) {
+	ctx := context.Background()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	scenario := filepath.Join("testdata", "scenarios", "basic")
+	data, err := os.ReadFile(filepath.Join(scenario, "media.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var media types.Media
+	if err := json.Unmarshal(data, &media); err != nil {
+		t.Fatal(err)
+	}
+	db, err := database.NewRepository(filepath.Join(home, ".cache", "autotitle", "db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Save(ctx, &media); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filepath.Join(t.TempDir(), "media")
+	if err := os.CopyFS(dir, os.DirFS(filepath.Join(scenario, "input"))); err != nil {
+		t.Fatal(err)
+	}
+
+	quiet := autotitle.WithEvents(func(types.Event) {})
+	now := time.Date(2030, 1, 2, 12, 0, 0, 0, time.UTC)
+
+	// Dry runs are not history
+	if _, err := autotitle.Rename(ctx, dir, quiet, autotitle.WithNoTagging(), autotitle.WithDryRun()); err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	stats, err := autotitle.Stats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Runs != 0 {
+		t.Fatalf("Dry run must not be recorded, got %+v", stats)
+	}
+
+	ops, err := autotitle.Rename(ctx, dir, quiet, autotitle.WithNoTagging(), autotitle.WithNow(func() time.Time { return now }))
+	if err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	renamed := 0
+	for _, op := range ops {
+		if op.Status == types.StatusSuccess {
+			renamed++
+		}
+	}
+
+	stats, err = autotitle.Stats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Runs != 1 || stats.Files != renamed || renamed == 0 {
+		t.Fatalf("Expected 1 run with %d files, got %+v", renamed, stats)
+	}
+	if !stats.First.Equal(now) {
+		t.Errorf("Entry should use the injected clock, got %v", stats.First)
+	}
+	if len(stats.TopSeries) != 1 || stats.TopSeries[0].Series != media.Title {
+		t.Errorf("Expected %q as top series, got %+v", media.Title, stats.TopSeries)
+	}
+	if stats.TimeSaved != time.Duration(renamed)*history.ManualRenameTime {
+		t.Errorf("Unexpected time saved estimate: %v", stats.TimeSaved)
+	}
+}
+
+func TestHistory_SummarizeRanksSeries(t *testing.T) {
+	ledger := history.New(t.TempDir())
+	for _, e := range []types.HistoryEntry{
+		{Provider: "mal", ID: "1", Series: "A", Files: 3},
+		{Provider: "mal", ID: "2", Series: "B", Files: 10},
+		{Provider: "mal", ID: "1", Series: "A", Files: 9},
+	} {
+		if err := ledger.Record(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A torn write must not hide the rest of the ledger
+	f, err := os.OpenFile(ledger.Path(), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"time":`)
+	f.Close()
+
+	entries, err := ledger.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats := history.Summarize(entries)
+	if stats.Runs != 3 || stats.Files != 22 {
+		t.Fatalf("Expected 3 runs / 22 files, got %+v", stats)
+	}
+	if stats.TopSeries[0].ID != "1" || stats.TopSeries[0].Files != 12 {
+		t.Errorf("Expected mal/1 first with 12 files, got %+v", stats.TopSeries)
+	}
+}
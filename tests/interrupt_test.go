@@ -0,0 +1,73 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mydehq/autotitle"
+	"github.com/mydehq/autotitle/internal/types"
+)
+
+func TestRename_InterruptStopsAfterCurrentFile(t *testing.T) {
+	useFakeServer(t, newSortServer(t))
+
+	root := t.TempDir()
+	writeFiles(t, root,
+		"[Group] Golden Show - 01 [1080p].mkv",
+		"[Group] Golden Show - 02 [1080p].mkv",
+		"[Group] Golden Show - 03 [1080p].mkv",
+	)
+	quiet := autotitle.WithEvents(func(types.Event) {})
+	if _, err := autotitle.Sort(context.Background(), root, quiet, autotitle.WithSortOnly()); err != nil {
+		t.Fatalf("Sort failed: %v", err)
+	}
+	show := filepath.Join(root, "Golden Show")
+
+	// Ctrl+C arrives while the first file is being renamed
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	onRename := autotitle.WithEvents(func(e types.Event) {
+		if e.Type == types.EventSuccess && strings.HasPrefix(e.Message, "Renamed:") {
+			cancel()
+		}
+	})
+
+	ops, err := autotitle.Rename(ctx, show, onRename)
+	var interrupted types.ErrInterrupted
+	if !errors.As(err, &interrupted) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected ErrInterrupted, got %v", err)
+	}
+	if interrupted.Renamed != 1 || interrupted.Remaining != 2 {
+		t.Errorf("Expected 1 renamed and 2 left, got %+v", interrupted)
+	}
+	if len(ops) != 3 {
+		t.Fatalf("Expected the whole plan back, got %+v", ops)
+	}
+	for _, op := range ops[1:] {
+		if op.Status != types.StatusPending {
+			t.Errorf("Expected pending, got %+v", op)
+		}
+		if _, err := os.Stat(op.SourcePath); err != nil {
+			t.Errorf("Unreached file was touched: %v", err)
+		}
+	}
+
+	// The backup covers only the renamed file, so undo restores cleanly
+	plan, err := autotitle.UndoPlan(context.Background(), show)
+	if err != nil {
+		t.Fatalf("UndoPlan failed: %v", err)
+	}
+	if len(plan) != 1 || plan[0].Renamed != filepath.Base(ops[0].TargetPath) {
+		t.Fatalf("Expected backup of the renamed file only, got %+v", plan)
+	}
+	if err := autotitle.Undo(context.Background(), show); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if _, err := os.Stat(ops[0].SourcePath); err != nil {
+		t.Errorf("Expected original restored: %v", err)
+	}
+}
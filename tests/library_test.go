@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mydehq/autotitle"
+	"github.com/mydehq/autotitle/internal/database"
+	"github.com/mydehq/autotitle/internal/types"
+)
+
+func TestRenameAll_Library(t *testing.T) {
+	ctx := context.Background()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	db, err := database.NewRepository(filepath.Join(home, ".cache", "autotitle", "db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	media := &types.Media{
+		ID: "52991", Provider: "mal", Title: "Sousou no Frieren", Type: types.MediaTypeAnime, Year: 2023,
+		Episodes: []types.Episode{{Number: 1, Title: "The Journey's End"}},
+	}
+	if err := db.Save(ctx, media); err != nil {
+		t.Fatal(err)
+	}
+
+	configDir := filepath.Join(home, ".config", "autotitle")
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	globalCfg := `libraries:
+  - name: Movies
+    path: ~/Movies
+    preset: movie
+`
+	if err := os.WriteFile(filepath.Join(configDir, "config.yml"), []byte(globalCfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mapFile := func(url string) string {
+		return `targets:
+  - path: "."
+    url: "` + url + `"
+    patterns:
+      - input: ["Sousou no Frieren - {{EP_NUM}}.{{EXT}}"]
+        output:
+          fields: [SERIES, EP_NUM, EP_NAME]
+`
+	}
+	root := filepath.Join(home, "Movies")
+	for name, url := range map[string]string{
+		"Frieren": "https://myanimelist.net/anime/52991/Sousou_no_Frieren",
+		"Broken":  "https://example.com/not-a-provider",
+	} {
+		dir := filepath.Join(root, name)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		writeFiles(t, dir, "Sousou no Frieren - 01.mkv")
+		if err := os.WriteFile(filepath.Join(dir, "_autotitle.yml"), []byte(mapFile(url)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lib, err := autotitle.FindLibrary("movies")
+	if err != nil || lib == nil {
+		t.Fatalf("FindLibrary(movies) = %v, %v", lib, err)
+	}
+	if lib.Root != root || lib.Path != "~/Movies" {
+		t.Fatalf("Library root = %q (path %q), want %q (~/Movies)", lib.Root, lib.Path, root)
+	}
+
+	var warnings []string
+	_, err = autotitle.RenameAll(ctx, lib.Root, autotitle.WithNoBackup(), autotitle.WithNoTagging(),
+		autotitle.WithEvents(func(e types.Event) {
+			if e.Type == types.EventWarning {
+				warnings = append(warnings, e.Message)
+			}
+		}))
+	if err != nil {
+		t.Fatalf("RenameAll failed: %v", err)
+	}
+
+	// The library's movie preset applies to the pattern, which sets none
+	if _, err := os.Stat(filepath.Join(root, "Frieren", "Sousou no Frieren (2023).mkv")); err != nil {
+		entries, _ := os.ReadDir(filepath.Join(root, "Frieren"))
+		t.Errorf("Expected the movie preset name: %v (have %v)", err, entries)
+	}
+	if !strings.Contains(strings.Join(warnings, "\n"), "Skipped "+filepath.Join(root, "Broken")) {
+		t.Errorf("Expected the broken folder to be skipped with a warning, got %v", warnings)
+	}
+}
@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mydehq/autotitle"
+	"github.com/mydehq/autotitle/internal/types"
+)
+
+func TestMigrateTemplate_RerendersFromOriginals(t *testing.T) {
+	ctx := context.Background()
+	useFakeServer(t, newSortServer(t))
+
+	root := t.TempDir()
+	writeFiles(t, root,
+		"[Group] Golden Show - 01 [1080p].mkv",
+		"[Group] Golden Show - 02 [720p].mkv",
+	)
+	quiet := autotitle.WithEvents(func(types.Event) {})
+	if _, err := autotitle.Sort(ctx, root, quiet); err != nil {
+		t.Fatalf("Sort failed: %v", err)
+	}
+	show := filepath.Join(root, "Golden Show")
+	if _, err := os.Stat(filepath.Join(show, "E01 - Golden Show 1.mkv")); err != nil {
+		t.Fatalf("Expected sorted file: %v", err)
+	}
+
+	fields := autotitle.WithFields("SERIES", "-", "EP_NUM", "RES")
+
+	// Dry run plans without touching anything
+	plan, err := autotitle.MigrateTemplate(ctx, root, quiet, fields, autotitle.WithDryRun())
+	if err != nil {
+		t.Fatalf("MigrateTemplate dry run failed: %v", err)
+	}
+	if len(plan) != 2 {
+		t.Fatalf("Expected 2 operations, got %+v", plan)
+	}
+	if _, err := os.Stat(filepath.Join(show, "E01 - Golden Show 1.mkv")); err != nil {
+		t.Fatalf("Dry run renamed files: %v", err)
+	}
+
+	ops, err := autotitle.MigrateTemplate(ctx, root, quiet, fields)
+	if err != nil {
+		t.Fatalf("MigrateTemplate failed: %v", err)
+	}
+	for _, op := range ops {
+		if op.Status != types.StatusSuccess {
+			t.Errorf("Expected success, got %+v", op)
+		}
+	}
+
+	// RES comes from the original names, which the old template dropped
+	for _, name := range []string{"Golden Show - 01 1080p.mkv", "Golden Show - 02 720p.mkv"} {
+		if _, err := os.Stat(filepath.Join(show, name)); err != nil {
+			t.Errorf("Expected %s: %v", name, err)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(show, "_autotitle.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "[SERIES, '-', EP_NUM, RES]") {
+		t.Errorf("Map file not updated:\n%s", data)
+	}
+
+	// A second run finds everything already named
+	again, err := autotitle.MigrateTemplate(ctx, root, quiet, fields)
+	if err != nil {
+		t.Fatalf("Second MigrateTemplate failed: %v", err)
+	}
+	for _, op := range again {
+		if op.Reason != types.ReasonUnchanged {
+			t.Errorf("Expected unchanged, got %+v", op)
+		}
+	}
+
+	// Undo still restores the names from before the first rename
+	if err := autotitle.Undo(ctx, show); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(show, "[Group] Golden Show - 02 [720p].mkv")); err != nil {
+		t.Errorf("Expected original name restored: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(show, "Golden Show - 02 720p.mkv")); !os.IsNotExist(err) {
+		t.Errorf("Expected migrated name removed, got %v", err)
+	}
+}
+
+func TestMigrateTemplate_RequiresTemplate(t *testing.T) {
+	if _, err := autotitle.MigrateTemplate(context.Background(), t.TempDir()); err == nil {
+		t.Error("Expected an error without fields or preset")
+	}
+}
@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mydehq/autotitle"
+	"github.com/mydehq/autotitle/providertest"
+)
+
+// TestPreview_MatchesRename checks that Preview names each file as a dry run
+// of Rename does, numbering base and library preset included
+func TestPreview_MatchesRename(t *testing.T) {
+	ctx := context.Background()
+	srv := providertest.NewServer()
+	t.Cleanup(srv.Close)
+	useFakeServer(t, srv)
+	srv.AddAnime(providertest.Anime{
+		ID: 77, Title: "Zero Show", Status: "Finished Airing", Year: 2020,
+		Episodes: []providertest.Episode{{Number: 1, Title: "First"}, {Number: 2, Title: "Second"}, {Number: 3, Title: "Third"}},
+	})
+	const url = "https://myanimelist.net/anime/77/Zero_Show"
+	const input = "Zero Show - {{EP_NUM}}.{{EXT}}"
+
+	home := os.Getenv("HOME")
+	global, err := os.OpenFile(filepath.Join(home, ".config", "autotitle", "config.yml"), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = global.WriteString("libraries:\n  - name: Films\n    path: ~/Films\n    preset: movie\n")
+	global.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		dir   string
+		base  *int
+		files []string
+	}{
+		{"numbering base 0", filepath.Join(home, "Shows", "Zero"), new(0), []string{"Zero Show - 00.mkv", "Zero Show - 01.mkv", "Zero Show - 02.mkv"}},
+		{"library preset", filepath.Join(home, "Films", "Zero"), nil, []string{"Zero Show - 01.mkv"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.MkdirAll(tt.dir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			writeFiles(t, tt.dir, tt.files...)
+			mapFile := fmt.Sprintf("targets:\n  - path: \".\"\n    url: %q\n", url)
+			if tt.base != nil {
+				mapFile += fmt.Sprintf("    numbering_base: %d\n", *tt.base)
+			}
+			mapFile += fmt.Sprintf("    patterns:\n      - input: [%q]\n        output:\n          fields: [SERIES, EP_NUM, EP_NAME]\n          separator: \" - \"\n", input)
+			if err := os.WriteFile(filepath.Join(tt.dir, "_autotitle.yml"), []byte(mapFile), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			ops, err := autotitle.Rename(ctx, tt.dir, autotitle.WithDryRun(), autotitle.WithNoBackup(), autotitle.WithNoTagging())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ops) != len(tt.files) {
+				t.Fatalf("Rename planned %d operations, want %d", len(ops), len(tt.files))
+			}
+
+			for _, op := range ops {
+				opts := []autotitle.Option{
+					autotitle.WithURL(url),
+					autotitle.WithPattern(input),
+					autotitle.WithFields("SERIES", "EP_NUM", "EP_NAME"),
+					autotitle.WithSeparator(" - "),
+				}
+				if tt.base != nil {
+					opts = append(opts, autotitle.WithNumberingBase(*tt.base))
+				}
+				got, err := autotitle.Preview(ctx, op.SourcePath, opts...)
+				if err != nil {
+					t.Fatalf("Preview(%s) failed: %v", filepath.Base(op.SourcePath), err)
+				}
+				if want := filepath.Base(op.TargetPath); got != want {
+					t.Errorf("Preview(%s) = %q, Rename gives %q", filepath.Base(op.SourcePath), got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/mydehq/autotitle"
+	"github.com/mydehq/autotitle/internal/database"
+	"github.com/mydehq/autotitle/internal/provider"
+	"github.com/mydehq/autotitle/internal/provider/filler"
+	"github.com/mydehq/autotitle/internal/types"
+	"github.com/mydehq/autotitle/providertest"
+)
+
+// newFakeMAL starts a fake server with a 5-episode series split over 3 pages
+func newFakeMAL(t *testing.T) (*providertest.Server, *provider.MALProvider) {
+	t.Helper()
+	srv := providertest.NewServer()
+	t.Cleanup(srv.Close)
+	srv.PageSize = 2
+
+	anime := providertest.Anime{ID: 1, Title: "Fake Show", Status: "Finished Airing"}
+	for i := 1; i <= 5; i++ {
+		anime.Episodes = append(anime.Episodes, providertest.Episode{Number: i, Title: fmt.Sprintf("Episode %d", i)})
+	}
+	srv.AddAnime(anime)
+
+	return srv, provider.NewMALProvider(&types.APIConfig{RateLimit: 1000, BaseURLs: srv.BaseURLs()})
+}
+
+func TestProviderOutage_TransientRateLimit(t *testing.T) {
+	srv, p := newFakeMAL(t)
+	srv.Inject("/episodes?page=2", providertest.FaultRateLimit, 2)
+
+	media, err := p.FetchMedia(context.Background(), "1")
+	if err != nil {
+		t.Fatalf("Retries should absorb a short 429 burst: %v", err)
+	}
+	if len(media.Episodes) != 5 {
+		t.Errorf("Expected 5 episodes, got %d", len(media.Episodes))
+	}
+}
+
+func TestProviderOutage_RateLimitStormResume(t *testing.T) {
+	ctx := context.Background()
+	srv, p := newFakeMAL(t)
+	srv.Inject("/episodes?page=2", providertest.FaultRateLimit, -1)
+
+	partial, err := p.FetchMediaFrom(ctx, "1", nil)
+	var rateErr types.ErrRateLimited
+	if !errors.As(err, &rateErr) {
+		t.Fatalf("Expected ErrRateLimited, got %v", err)
+	}
+	if partial == nil || partial.ResumePage != 2 || len(partial.Episodes) != 2 {
+		t.Fatalf("Expected partial with page 1 fetched, got %+v", partial)
+	}
+
+	// Outage ends: resume must not refetch page 1
+	srv.ClearFaults()
+	before := len(srv.Requests())
+	media, err := p.FetchMediaFrom(ctx, "1", partial)
+	if err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+	if len(media.Episodes) != 5 || media.ResumePage != 0 {
+		t.Errorf("Expected complete media, got %d episodes (resume page %d)", len(media.Episodes), media.ResumePage)
+	}
+	if slices.Contains(srv.Requests()[before:], "/anime/1/episodes?page=1") {
+		t.Error("Resumed fetch requested page 1 again")
+	}
+}
+
+func TestProviderOutage_MalformedResponses(t *testing.T) {
+	tests := []struct {
+		name  string
+		match string
+		fault providertest.Fault
+	}{
+		{"truncated page", "/episodes?page=2", providertest.FaultTruncate},
+		{"episode schema drift", "/episodes?page=1", providertest.FaultSchemaDrift},
+		{"info schema drift", "/anime/1", providertest.FaultSchemaDrift},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, p := newFakeMAL(t)
+			srv.Inject(tt.match, tt.fault, 1)
+
+			_, err := p.FetchMedia(context.Background(), "1")
+			if err == nil {
+				t.Fatal("Expected an error for a malformed response")
+			}
+			var rateErr types.ErrRateLimited
+			if errors.As(err, &rateErr) {
+				t.Errorf("Malformed response must not be reported as rate limiting: %v", err)
+			}
+		})
+	}
+}
+
+func TestProviderOutage_Fillers(t *testing.T) {
+	srv := providertest.NewServer()
+	defer srv.Close()
+	srv.AddFillers("fake-show", []int{3, 4})
+	srv.Inject("/fake-show", providertest.FaultUnavailable, 1)
+
+	src := filler.NewAnimeFillerListSource().WithBaseURL(srv.FillerURL())
+	fillers, err := src.FetchFillers(context.Background(), "fake-show")
+	if err != nil {
+		t.Fatalf("FetchFillers failed: %v", err)
+	}
+	if !slices.Equal(fillers, []int{3, 4}) {
+		t.Errorf("Expected fillers [3 4], got %v", fillers)
+	}
+
+	if fillers, err := src.FetchFillers(context.Background(), "unknown"); err != nil || fillers != nil {
+		t.Errorf("Unknown show should yield no fillers, got %v (%v)", fillers, err)
+	}
+}
+
+func TestProviderOutage_CancelSavesPartial(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	srv, _ := newFakeMAL(t)
+
+	cfgDir := filepath.Join(home, ".config", "autotitle")
+	if err := os.MkdirAll(cfgDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	global := fmt.Sprintf("api:\n  rate_limit: 1000\n  base_urls:\n    mal: %q\n", srv.JikanURL())
+	if err := os.WriteFile(filepath.Join(cfgDir, "config.yml"), []byte(global), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Ctrl+C while page 2 is in flight
+	srv.Inject("/episodes?page=2", providertest.FaultHang, -1)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+
+	const url = "https://myanimelist.net/anime/1/Fake_Show"
+	quiet := autotitle.WithEvents(func(types.Event) {})
+	_, err := autotitle.DBGen(ctx, url, quiet)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	var saved types.ErrPartialFetch
+	if !errors.As(err, &saved) || saved.Episodes != 2 {
+		t.Errorf("Expected the error to report 2 saved episodes, got %v", err)
+	}
+
+	db, err := database.NewRepository("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	partial, err := db.LoadPartial(context.Background(), "mal", "1")
+	if err != nil || partial == nil || partial.ResumePage != 2 || len(partial.Episodes) != 2 {
+		t.Fatalf("Expected partial with page 1 saved, got %+v (%v)", partial, err)
+	}
+
+	// Next run resumes from page 2
+	srv.ClearFaults()
+	before := len(srv.Requests())
+	if generated, err := autotitle.DBGen(context.Background(), url, quiet); err != nil || !generated {
+		t.Fatalf("Resume failed: generated=%v err=%v", generated, err)
+	}
+	if slices.Contains(srv.Requests()[before:], "/anime/1/episodes?page=1") {
+		t.Error("Resumed fetch requested page 1 again")
+	}
+	media, err := db.Load(context.Background(), "mal", "1")
+	if err != nil || media == nil || len(media.Episodes) != 5 {
+		t.Fatalf("Expected all 5 episodes after resume, got %+v (%v)", media, err)
+	}
+}
+
+func TestDBGen_ReportsPageProgress(t *testing.T) {
+	srv, _ := newFakeMAL(t)
+	useFakeServer(t, srv)
+
+	var got []types.FetchProgress
+	_, err := autotitle.DBGen(context.Background(), "https://myanimelist.net/anime/1/Fake_Show",
+		autotitle.WithEvents(func(e types.Event) {
+			if e.Type != types.EventProgress {
+				return
+			}
+			p, ok := e.Data.(types.FetchProgress)
+			if !ok {
+				t.Fatalf("Progress event without FetchProgress data: %+v", e)
+			}
+			got = append(got, p)
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []types.FetchProgress{
+		{Page: 1, LastPage: 3, Episodes: 2},
+		{Page: 2, LastPage: 3, Episodes: 4},
+		{Page: 3, LastPage: 3, Episodes: 5},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Got %d progress events, want %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		g := got[i]
+		if g.Page != w.Page || g.LastPage != w.LastPage || g.Episodes != w.Episodes {
+			t.Errorf("Progress %d = %+v, want page %d/%d with %d episodes", i, g, w.Page, w.LastPage, w.Episodes)
+		}
+	}
+	if got[0].ETA <= got[2].ETA {
+		t.Errorf("ETA should shrink as pages are fetched: %v then %v", got[0].ETA, got[2].ETA)
+	}
+}
+
+func TestMALProvider_ProgressPerCall(t *testing.T) {
+	_, p := newFakeMAL(t)
+
+	// Concurrent fetches through the shared provider report to their own handler
+	counts := make([]int, 2)
+	errs := make(chan error, len(counts))
+	for i := range counts {
+		go func() {
+			ctx := types.ContextWithProgress(context.Background(), func(types.FetchProgress) { counts[i]++ })
+			_, err := p.FetchMedia(ctx, "1")
+			errs <- err
+		}()
+	}
+	for range counts {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if counts[0] != 3 || counts[1] != 3 {
+		t.Errorf("Each fetch should see its own 3 pages, got %v", counts)
+	}
+}
@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mydehq/autotitle"
+	"github.com/mydehq/autotitle/internal/types"
+)
+
+// snapshotTree lists every path under root with its modification time
+func snapshotTree(t *testing.T, root string) map[string]time.Time {
+	t.Helper()
+	tree := make(map[string]time.Time)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		tree[path] = info.ModTime()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tree
+}
+
+func TestReadOnly_PlansWithoutWriting(t *testing.T) {
+	useFakeServer(t, newSortServer(t))
+
+	root := t.TempDir()
+	writeFiles(t, root,
+		"[Group] Golden Show - 01 [1080p].mkv",
+		"[Group] Golden Show - 02 [1080p].mkv",
+	)
+	quiet := autotitle.WithEvents(func(types.Event) {})
+	if _, err := autotitle.Sort(context.Background(), root, quiet, autotitle.WithSortOnly()); err != nil {
+		t.Fatalf("Sort failed: %v", err)
+	}
+	show := filepath.Join(root, "Golden Show")
+
+	home := os.Getenv("HOME")
+	beforeHome, beforeLib := snapshotTree(t, home), snapshotTree(t, root)
+
+	autotitle.SetReadOnly(true)
+	t.Cleanup(func() { autotitle.SetReadOnly(false) })
+
+	// The database is fetched, but only kept in memory
+	ops, err := autotitle.Rename(context.Background(), show, quiet)
+	if err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	if len(ops) != 2 {
+		t.Fatalf("Expected a plan for 2 files, got %+v", ops)
+	}
+	for _, op := range ops {
+		if op.Status != types.StatusPending {
+			t.Errorf("Expected a planned rename, got %+v", op)
+		}
+	}
+
+	var readOnly types.ErrReadOnly
+	if err := autotitle.Clean(context.Background(), show); !errors.As(err, &readOnly) {
+		t.Errorf("Expected ErrReadOnly from Clean, got %v", err)
+	}
+	if err := autotitle.DBDeleteAll(context.Background()); !errors.As(err, &readOnly) {
+		t.Errorf("Expected ErrReadOnly from DBDeleteAll, got %v", err)
+	}
+
+	for name, before := range map[string]map[string]time.Time{home: beforeHome, root: beforeLib} {
+		after := snapshotTree(t, name)
+		if len(after) != len(before) {
+			t.Errorf("Expected no files created under %s, got %d paths instead of %d", name, len(after), len(before))
+		}
+		for path, mod := range before {
+			if !after[path].Equal(mod) {
+				t.Errorf("Expected %s untouched", path)
+			}
+		}
+	}
+}
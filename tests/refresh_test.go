@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mydehq/autotitle"
+	"github.com/mydehq/autotitle/providertest"
+)
+
+// TestRefresh_RefetchesAndRenames runs what `autotitle refresh` does, a
+// forced rename, after a new episode aired
+func TestRefresh_RefetchesAndRenames(t *testing.T) {
+	ctx := context.Background()
+	srv := providertest.NewServer()
+	t.Cleanup(srv.Close)
+	useFakeServer(t, srv)
+
+	// Cached as finished, so a plain rename never re-fetches it
+	anime := providertest.Anime{
+		ID: 88, Title: "Fixed Show", Status: "Finished Airing",
+		Episodes: []providertest.Episode{{Number: 1, Title: "Arrival"}, {Number: 2, Title: "Departure"}},
+	}
+	srv.AddAnime(anime)
+
+	dir := filepath.Join(os.Getenv("HOME"), "Fixed Show")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, dir, "Fixed Show - 01.mkv", "Fixed Show - 02.mkv")
+	mapFile := `targets:
+  - path: "."
+    url: "https://myanimelist.net/anime/88/Fixed_Show"
+    patterns:
+      - input: ["Fixed Show - {{EP_NUM}}.{{EXT}}"]
+        output:
+          fields: [SERIES, EP_NUM, EP_NAME]
+          separator: " - "
+`
+	if err := os.WriteFile(filepath.Join(dir, "_autotitle.yml"), []byte(mapFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	files := func() []string {
+		t.Helper()
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, e := range entries {
+			if strings.HasSuffix(e.Name(), ".mkv") {
+				names = append(names, e.Name())
+			}
+		}
+		return names
+	}
+	episodeFetches := func() int {
+		n := 0
+		for _, r := range srv.Requests() {
+			if strings.Contains(r, "/anime/88/episodes") {
+				n++
+			}
+		}
+		return n
+	}
+
+	opts := []autotitle.Option{autotitle.WithNoBackup(), autotitle.WithNoTagging()}
+	if _, err := autotitle.Rename(ctx, dir, opts...); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new episode airs and its file lands in the folder
+	anime.Episodes = append(anime.Episodes, providertest.Episode{Number: 3, Title: "Return"})
+	srv.AddAnime(anime)
+	writeFiles(t, dir, "Fixed Show - 03.mkv")
+
+	fetches := episodeFetches()
+	if _, err := autotitle.Rename(ctx, dir, opts...); err != nil {
+		t.Fatal(err)
+	}
+	if episodeFetches() != fetches {
+		t.Error("A plain rename should use the cached database")
+	}
+	if !slices.Contains(files(), "Fixed Show - 03.mkv") {
+		t.Fatalf("The new file should wait for a refresh, got %v", files())
+	}
+
+	// Refresh re-fetches the database and renames the folder again
+	if _, err := autotitle.Rename(ctx, dir, append(opts, autotitle.WithForce())...); err != nil {
+		t.Fatal(err)
+	}
+	if episodeFetches() == fetches {
+		t.Error("Refresh should re-fetch the database")
+	}
+	want := []string{"Fixed Show - 01 - Arrival.mkv", "Fixed Show - 02 - Departure.mkv", "Fixed Show - 03 - Return.mkv"}
+	if !slices.Equal(files(), want) {
+		t.Errorf("Refresh gave %v, want %v", files(), want)
+	}
+}
@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mydehq/autotitle"
+	"github.com/mydehq/autotitle/internal/types"
+	"github.com/mydehq/autotitle/providertest"
+)
+
+func TestDBGen_WarnsOnRenumbering(t *testing.T) {
+	ctx := context.Background()
+	srv := providertest.NewServer()
+	useFakeServer(t, srv)
+
+	anime := providertest.Anime{
+		ID:     55,
+		Title:  "Recap Show",
+		Status: "Currently Airing",
+		Episodes: []providertest.Episode{
+			{Number: 1, Title: "Start"},
+			{Number: 2, Title: "Recap"},
+			{Number: 3, Title: "Battle"},
+			{Number: 4, Title: "Aftermath"},
+		},
+	}
+	srv.AddAnime(anime)
+
+	const url = "https://myanimelist.net/anime/55/Recap_Show"
+	var warnings []types.Event
+	collect := autotitle.WithEvents(func(e types.Event) {
+		if e.Type == types.EventWarning {
+			warnings = append(warnings, e)
+		}
+	})
+
+	if _, err := autotitle.DBGen(ctx, url, collect); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new episode is not renumbering
+	anime.Episodes = append(anime.Episodes, providertest.Episode{Number: 5, Title: "Return"})
+	srv.AddAnime(anime)
+	if _, err := autotitle.DBGen(ctx, url, collect, autotitle.WithForce()); err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 0 {
+		t.Fatalf("Expected no warnings for an appended episode, got %v", warnings)
+	}
+
+	// The recap is merged away and later episodes shift down
+	anime.Episodes = []providertest.Episode{
+		{Number: 1, Title: "Start"},
+		{Number: 2, Title: "Battle"},
+		{Number: 3, Title: "Aftermath"},
+		{Number: 4, Title: "Return"},
+	}
+	srv.AddAnime(anime)
+	if _, err := autotitle.DBGen(ctx, url, collect, autotitle.WithForce()); err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 {
+		t.Fatalf("Expected one renumbering warning, got %v", warnings)
+	}
+	r, ok := warnings[0].Data.(types.Renumbering)
+	if !ok || r.OldCount != 5 || r.NewCount != 4 || !slices.Equal(r.Removed, []int{5}) || !slices.Equal(r.Moved, []int{3, 4}) {
+		t.Errorf("Unexpected renumbering: %+v", warnings[0].Data)
+	}
+	if !strings.Contains(warnings[0].Message, "--dry-run") {
+		t.Errorf("Expected a re-verify hint, got %q", warnings[0].Message)
+	}
+}
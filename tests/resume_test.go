@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mydehq/autotitle"
+	"github.com/mydehq/autotitle/internal/types"
+)
+
+// interruptedShow sorts three episodes into a series folder and interrupts
+// their rename after the first file
+func interruptedShow(t *testing.T) (string, []types.RenameOperation) {
+	t.Helper()
+	useFakeServer(t, newSortServer(t))
+
+	root := t.TempDir()
+	writeFiles(t, root,
+		"[Group] Golden Show - 01 [1080p].mkv",
+		"[Group] Golden Show - 02 [1080p].mkv",
+		"[Group] Golden Show - 03 [1080p].mkv",
+	)
+	quiet := autotitle.WithEvents(func(types.Event) {})
+	if _, err := autotitle.Sort(context.Background(), root, quiet, autotitle.WithSortOnly()); err != nil {
+		t.Fatalf("Sort failed: %v", err)
+	}
+	show := filepath.Join(root, "Golden Show")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	onRename := autotitle.WithEvents(func(e types.Event) {
+		if e.Type == types.EventSuccess && strings.HasPrefix(e.Message, "Renamed:") {
+			cancel()
+		}
+	})
+	ops, err := autotitle.Rename(ctx, show, onRename)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected an interrupted batch, got %v", err)
+	}
+	return show, ops
+}
+
+func TestResume_FinishesInterruptedBatch(t *testing.T) {
+	show, interrupted := interruptedShow(t)
+	quiet := autotitle.WithEvents(func(types.Event) {})
+
+	batch, err := autotitle.ResumableBatch(context.Background(), show)
+	if err != nil || batch == nil {
+		t.Fatalf("Expected a journaled batch, got %v, %v", batch, err)
+	}
+	if batch.Pending() != 2 {
+		t.Errorf("Expected 2 pending renames, got %d", batch.Pending())
+	}
+
+	// The map file is gone: resume must not plan the batch again
+	if err := os.Remove(filepath.Join(show, "_autotitle.yml")); err != nil {
+		t.Fatal(err)
+	}
+
+	ops, err := autotitle.Resume(context.Background(), show, quiet)
+	if err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+	if len(ops) != 3 {
+		t.Fatalf("Expected the whole batch back, got %+v", ops)
+	}
+	for i, op := range ops {
+		if op.Status != types.StatusSuccess || op.TargetPath != interrupted[i].TargetPath {
+			t.Errorf("Expected %s renamed, got %+v", interrupted[i].TargetPath, op)
+		}
+		if _, err := os.Stat(op.TargetPath); err != nil {
+			t.Errorf("Expected renamed file: %v", err)
+		}
+	}
+
+	if batch, _ := autotitle.ResumableBatch(context.Background(), show); batch != nil {
+		t.Errorf("Expected the finished batch to leave the journal, got %+v", batch)
+	}
+	if _, err := autotitle.Resume(context.Background(), show, quiet); !errors.As(err, new(types.ErrBatchNotFound)) {
+		t.Errorf("Expected ErrBatchNotFound, got %v", err)
+	}
+
+	// The backup covers the whole batch again
+	plan, err := autotitle.UndoPlan(context.Background(), show)
+	if err != nil {
+		t.Fatalf("UndoPlan failed: %v", err)
+	}
+	if len(plan) != 3 {
+		t.Errorf("Expected 3 backed up files, got %+v", plan)
+	}
+}
+
+func TestResume_SkipsFilesRenamedAfterJournal(t *testing.T) {
+	show, ops := interruptedShow(t)
+
+	// A killed run renamed the second file without recording it
+	if err := os.Rename(ops[1].SourcePath, ops[1].TargetPath); err != nil {
+		t.Fatal(err)
+	}
+
+	resumed, err := autotitle.Resume(context.Background(), show, autotitle.WithEvents(func(types.Event) {}))
+	if err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+	for _, op := range resumed {
+		if op.Status != types.StatusSuccess {
+			t.Errorf("Expected success, got %+v", op)
+		}
+	}
+	if _, err := os.Stat(ops[2].TargetPath); err != nil {
+		t.Errorf("Expected third file renamed: %v", err)
+	}
+}
+
+func TestUndo_DiscardsInterruptedBatch(t *testing.T) {
+	show, _ := interruptedShow(t)
+
+	if err := autotitle.Undo(context.Background(), show); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if batch, _ := autotitle.ResumableBatch(context.Background(), show); batch != nil {
+		t.Errorf("Expected undo to discard the batch, got %+v", batch)
+	}
+}
@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mydehq/autotitle"
+	"github.com/mydehq/autotitle/internal/types"
+	"github.com/mydehq/autotitle/providertest"
+)
+
+func TestSearchStream_ProviderTimeout(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	autotitle.ClearSearchCache()
+	t.Cleanup(autotitle.ClearSearchCache)
+
+	srv := providertest.NewServer()
+	defer srv.Close()
+	srv.AddAnime(providertest.Anime{ID: 5, Title: "Timeout Show"})
+
+	cfgDir := filepath.Join(home, ".config", "autotitle")
+	if err := os.MkdirAll(cfgDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	global := fmt.Sprintf("api:\n  rate_limit: 1000\n  search_timeouts:\n    mal: 1\n  base_urls:\n    mal: %q\n", srv.JikanURL())
+	if err := os.WriteFile(filepath.Join(cfgDir, "config.yml"), []byte(global), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	search := func() []types.SearchResult {
+		var results []types.SearchResult
+		for r := range autotitle.SearchStream(context.Background(), "timeout", autotitle.WithProvider("mal")) {
+			results = append(results, r)
+		}
+		return results
+	}
+
+	// A hanging provider is reported as timed out instead of stalling the search
+	srv.Inject("/anime?q=", providertest.FaultHang, -1)
+	results := search()
+	var timeoutErr types.ErrSearchTimeout
+	if len(results) != 1 || !results[0].TimedOut || !errors.As(results[0].Error, &timeoutErr) {
+		t.Fatalf("Expected a single timed-out result, got %+v", results)
+	}
+
+	// Failures are not cached: once the provider recovers it is queried again
+	srv.ClearFaults()
+	results = search()
+	if len(results) != 1 || results[0].Error != nil || results[0].ID != "5" {
+		t.Fatalf("Expected the recovered provider's result, got %+v", results)
+	}
+
+	// Successful answers are served from the cache
+	srv.Inject("/anime?q=", providertest.FaultHang, -1)
+	if results := search(); len(results) != 1 || results[0].Error != nil {
+		t.Errorf("Expected cached result, got %+v", results)
+	}
+}
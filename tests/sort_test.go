@@ -0,0 +1,314 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mydehq/autotitle"
+	"github.com/mydehq/autotitle/internal/types"
+	"github.com/mydehq/autotitle/providertest"
+)
+
+// useFakeServer isolates HOME and points the providers at srv
+func useFakeServer(t *testing.T, srv *providertest.Server) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	cfgDir := filepath.Join(home, ".config", "autotitle")
+	if err := os.MkdirAll(cfgDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	global := fmt.Sprintf("api:\n  rate_limit: 1000\n  base_urls:\n    mal: %q\n", srv.JikanURL())
+	if err := os.WriteFile(filepath.Join(cfgDir, "config.yml"), []byte(global), 0644); err != nil {
+		t.Fatal(err)
+	}
+	autotitle.ClearSearchCache()
+	t.Cleanup(autotitle.ClearSearchCache)
+}
+
+// newSortServer serves two finished series for the sort tests
+func newSortServer(t *testing.T) *providertest.Server {
+	t.Helper()
+	srv := providertest.NewServer()
+	t.Cleanup(srv.Close)
+	for id, title := range map[int]string{101: "Golden Show", 102: "Silver Saga"} {
+		anime := providertest.Anime{ID: id, Title: title, Status: "Finished Airing"}
+		for i := 1; i <= 3; i++ {
+			anime.Episodes = append(anime.Episodes, providertest.Episode{Number: i, Title: fmt.Sprintf("%s %d", title, i)})
+		}
+		srv.AddAnime(anime)
+	}
+	return srv
+}
+
+func writeFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSort_DumpDirectory(t *testing.T) {
+	ctx := context.Background()
+	useFakeServer(t, newSortServer(t))
+
+	dump := t.TempDir()
+	writeFiles(t, dump,
+		"[Group] Golden Show - 01 [1080p].mkv",
+		"[Group] Golden Show - 02 [1080p].mkv",
+		"Silver.Saga.E01.mkv",
+		"Bronze Tale - 01.mkv",
+	)
+	quiet := autotitle.WithEvents(func(types.Event) {})
+
+	// Dry run plans without touching anything
+	plan, err := autotitle.Sort(ctx, dump, quiet, autotitle.WithDryRun())
+	if err != nil {
+		t.Fatalf("Sort dry run failed: %v", err)
+	}
+	if len(plan) != 3 {
+		t.Fatalf("Expected 3 groups, got %+v", plan)
+	}
+	if _, err := os.Stat(filepath.Join(dump, "Silver.Saga.E01.mkv")); err != nil {
+		t.Fatal("Dry run must not move files")
+	}
+
+	groups, err := autotitle.Sort(ctx, dump, quiet, autotitle.WithNoTagging())
+	if err != nil {
+		t.Fatalf("Sort failed: %v", err)
+	}
+
+	byName := make(map[string]types.SortGroup)
+	for _, g := range groups {
+		byName[g.Name] = g
+	}
+	if g := byName["Bronze Tale"]; g.Match != nil || g.Error == "" {
+		t.Errorf("Unknown series should stay unmatched, got %+v", g)
+	}
+	if _, err := os.Stat(filepath.Join(dump, "Bronze Tale - 01.mkv")); err != nil {
+		t.Error("Unmatched files must stay in the dump directory")
+	}
+
+	for folder, want := range map[string][]string{
+		"Golden Show": {"_autotitle.yml", "E01 - Golden Show 1.mkv", "E02 - Golden Show 2.mkv"},
+		"Silver Saga": {"_autotitle.yml", "E01 - Silver Saga 1.mkv"},
+	} {
+		for _, name := range want {
+			if _, err := os.Stat(filepath.Join(dump, folder, name)); err != nil {
+				t.Errorf("Expected %s/%s: %v", folder, name, err)
+			}
+		}
+	}
+}
+
+func TestSort_MovesSubtitles(t *testing.T) {
+	ctx := context.Background()
+	useFakeServer(t, newSortServer(t))
+
+	dump := t.TempDir()
+	writeFiles(t, dump,
+		"[Group] Golden Show - 01 [1080p].mkv",
+		"[Group] Golden Show - 01 [1080p].eng.srt",
+		"[Group] Golden Show - 01 [1080p] [Spanish].ass",
+		"[Group] Golden Show - 01 [1080p].forced.en.ass",
+		"Bronze Tale - 01.srt",
+	)
+	quiet := autotitle.WithEvents(func(types.Event) {})
+
+	if _, err := autotitle.Sort(ctx, dump, quiet, autotitle.WithSortOnly()); err != nil {
+		t.Fatalf("Sort failed: %v", err)
+	}
+	for _, name := range []string{
+		"[Group] Golden Show - 01 [1080p].mkv",
+		"[Group] Golden Show - 01 [1080p].eng.srt",
+		"[Group] Golden Show - 01 [1080p] [Spanish].ass",
+		"[Group] Golden Show - 01 [1080p].forced.en.ass",
+	} {
+		if _, err := os.Stat(filepath.Join(dump, "Golden Show", name)); err != nil {
+			t.Errorf("Expected Golden Show/%s: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dump, "Bronze Tale - 01.srt")); err != nil {
+		t.Error("A subtitle without its video must stay in the dump directory")
+	}
+}
+
+func TestSort_LowConfidenceIsParked(t *testing.T) {
+	ctx := context.Background()
+	useFakeServer(t, newSortServer(t))
+
+	dump := t.TempDir()
+	writeFiles(t, dump, "Golden - 01.mkv", "Golden - 02.mkv")
+	quiet := autotitle.WithEvents(func(types.Event) {})
+
+	groups, err := autotitle.Sort(ctx, dump, quiet, autotitle.WithNoTagging())
+	if err != nil {
+		t.Fatalf("Sort failed: %v", err)
+	}
+	if len(groups) != 1 || !groups[0].Parked || groups[0].Match == nil || groups[0].Match.Title != "Golden Show" {
+		t.Fatalf("Expected a parked Golden Show proposal, got %+v", groups)
+	}
+	if _, err := os.Stat(filepath.Join(dump, "Golden - 01.mkv")); err != nil {
+		t.Fatal("Parked files must not be moved")
+	}
+
+	items, err := autotitle.ReviewQueue(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Dir != dump || len(items[0].Group.Files) != 2 || len(items[0].Candidates) == 0 {
+		t.Fatalf("Expected one queued item, got %+v", items)
+	}
+
+	// Sorting again does not duplicate the item
+	if _, err := autotitle.Sort(ctx, dump, quiet, autotitle.WithNoTagging()); err != nil {
+		t.Fatal(err)
+	}
+	if items, _ := autotitle.ReviewQueue(ctx); len(items) != 1 {
+		t.Fatalf("Re-sorting duplicated the review item: %+v", items)
+	}
+
+	// A lower threshold lets it through and clears the queue
+	if _, err := autotitle.Sort(ctx, dump, quiet, autotitle.WithNoTagging(), autotitle.WithMinConfidence(0.5)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dump, "Golden Show", "E01 - Golden Show 1.mkv")); err != nil {
+		t.Errorf("Expected the group to be sorted: %v", err)
+	}
+	if items, _ := autotitle.ReviewQueue(ctx); len(items) != 0 {
+		t.Errorf("Sorted group should leave the queue, got %+v", items)
+	}
+}
+
+func TestSort_ProviderDownIsNotParked(t *testing.T) {
+	ctx := context.Background()
+	srv := newSortServer(t)
+	useFakeServer(t, srv)
+	srv.Inject("/anime?q=", providertest.FaultUnavailable, -1)
+
+	dump := t.TempDir()
+	writeFiles(t, dump, "Golden - 01.mkv", "Golden - 02.mkv")
+	quiet := autotitle.WithEvents(func(types.Event) {})
+
+	groups, err := autotitle.Sort(ctx, dump, quiet, autotitle.WithNoTagging())
+	if err != nil {
+		t.Fatalf("Sort failed: %v", err)
+	}
+	if len(groups) != 1 || groups[0].Parked || groups[0].Match != nil || groups[0].Error == "" {
+		t.Fatalf("Expected the group left unsorted with an error, got %+v", groups)
+	}
+	if _, err := os.Stat(filepath.Join(dump, "Golden - 01.mkv")); err != nil {
+		t.Fatal("Unsorted files must not be moved")
+	}
+	if items, _ := autotitle.ReviewQueue(ctx); len(items) != 0 {
+		t.Fatalf("A failed search must not park the group, got %+v", items)
+	}
+
+	// Once the provider answers the group is matched (and parked) as usual
+	srv.ClearFaults()
+	groups, err = autotitle.Sort(ctx, dump, quiet, autotitle.WithNoTagging())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 1 || !groups[0].Parked || groups[0].Match == nil {
+		t.Fatalf("Expected a parked proposal after the outage, got %+v", groups)
+	}
+}
+
+func TestAcceptReview_LearnsMatch(t *testing.T) {
+	ctx := context.Background()
+	useFakeServer(t, newSortServer(t))
+
+	dump := t.TempDir()
+	writeFiles(t, dump, "Golden - 01.mkv")
+	quiet := autotitle.WithEvents(func(types.Event) {})
+
+	if _, err := autotitle.Sort(ctx, dump, quiet, autotitle.WithNoTagging()); err != nil {
+		t.Fatal(err)
+	}
+	items, _ := autotitle.ReviewQueue(ctx)
+	if len(items) != 1 || len(items[0].Candidates) == 0 {
+		t.Fatalf("Expected a parked item with candidates, got %+v", items)
+	}
+
+	if _, err := autotitle.AcceptReview(ctx, "missing", items[0].Candidates[0], quiet); !errors.As(err, new(types.ErrReviewItemNotFound)) {
+		t.Errorf("Expected ErrReviewItemNotFound, got %v", err)
+	}
+
+	g, err := autotitle.AcceptReview(ctx, items[0].ID, items[0].Candidates[0], quiet, autotitle.WithNoTagging())
+	if err != nil {
+		t.Fatalf("AcceptReview failed: %v", err)
+	}
+	if g.Parked || g.Folder != filepath.Join(dump, "Golden Show") {
+		t.Errorf("Unexpected accepted group: %+v", g)
+	}
+	if _, err := os.Stat(filepath.Join(dump, "Golden Show", "E01 - Golden Show 1.mkv")); err != nil {
+		t.Errorf("Expected the accepted group to be sorted and renamed: %v", err)
+	}
+	if items, _ := autotitle.ReviewQueue(ctx); len(items) != 0 {
+		t.Errorf("Accepted item should leave the queue, got %+v", items)
+	}
+
+	// The next episode of the same series is sorted without review
+	writeFiles(t, dump, "Golden - 02.mkv")
+	groups, err := autotitle.Sort(ctx, dump, quiet, autotitle.WithNoTagging())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 1 || groups[0].Parked || groups[0].Confidence != 1 {
+		t.Fatalf("Expected the learned match to be used, got %+v", groups)
+	}
+	if _, err := os.Stat(filepath.Join(dump, "Golden Show", "E02 - Golden Show 2.mkv")); err != nil {
+		t.Errorf("Expected episode 2 to be sorted: %v", err)
+	}
+}
+
+func TestLearn_ConsultedBySort(t *testing.T) {
+	ctx := context.Background()
+	useFakeServer(t, newSortServer(t))
+	quiet := autotitle.WithEvents(func(types.Event) {})
+
+	// The user set up an earlier season by hand with a tweaked pattern
+	season := t.TempDir()
+	writeFiles(t, season, "Golden - 01.mkv")
+	match := types.SearchResult{Provider: "mal", URL: "https://myanimelist.net/anime/101/Golden_Show", Title: "Golden Show"}
+	tweak := "Golden - {{EP_NUM}}{{ANY}}.{{EXT}}"
+	if err := autotitle.Learn(ctx, season, match, []string{tweak}); err != nil {
+		t.Fatalf("Learn failed: %v", err)
+	}
+
+	a, err := autotitle.LearnedMatch(ctx, season)
+	if err != nil || a == nil || a.Match.URL != match.URL {
+		t.Fatalf("Expected the learned match, got %+v (%v)", a, err)
+	}
+
+	// This week's episode lands in the dump directory
+	dump := t.TempDir()
+	writeFiles(t, dump, "Golden - 02.mkv")
+	groups, err := autotitle.Sort(ctx, dump, quiet, autotitle.WithSortOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 1 || groups[0].Parked || groups[0].Match.URL != match.URL {
+		t.Fatalf("Expected the learned match to be used, got %+v", groups)
+	}
+	if len(groups[0].Patterns) == 0 || groups[0].Patterns[0] != tweak {
+		t.Errorf("Expected the learned pattern first, got %v", groups[0].Patterns)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dump, "Golden Show", "_autotitle.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), tweak) {
+		t.Errorf("Map file does not use the learned pattern:\n%s", data)
+	}
+}
@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mydehq/autotitle"
+	"github.com/mydehq/autotitle/internal/database"
+	"github.com/mydehq/autotitle/internal/types"
+)
+
+func TestRename_TargetPathTemplate(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("HOME", t.TempDir())
+
+	db, err := database.NewRepository("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []*types.Media{
+		{ID: "52991", Provider: "mal", Title: "Frieren", Slug: "frieren", Year: 2023, Season: "Fall", Status: "Finished Airing",
+			Episodes: []types.Episode{{Number: 1, Title: "The Journey's End"}}},
+		{ID: "5114", Provider: "mal", Title: "Fullmetal Alchemist", Slug: "fma", Year: 2009, Season: "Spring", Status: "Finished Airing",
+			Episodes: []types.Episode{{Number: 1, Title: "Fullmetal Alchemist"}}},
+	} {
+		if err := db.Save(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	root := t.TempDir()
+	content := `targets:
+  - path: "{{YEAR}}/{{SEASON_NAME}}/*"
+    url: "https://myanimelist.net/anime/5114"
+    patterns:
+      - input: ["Episode {{EP_NUM}}.{{EXT}}"]
+        output:
+          fields: [SERIES, EP_NUM, EP_NAME]
+          separator: " - "
+  - path: "{{YEAR}}/{{SEASON_NAME}}/*"
+    url: "https://myanimelist.net/anime/52991"
+    patterns:
+      - input: ["Episode {{EP_NUM}}.{{EXT}}"]
+        output:
+          fields: [SERIES, EP_NUM, EP_NAME]
+          separator: " - "
+`
+	if err := os.WriteFile(filepath.Join(root, "_autotitle.yml"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(root, "2023", "Fall", "Sousou no Frieren")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "Episode 01.mkv"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ops, err := autotitle.Rename(ctx, dir, autotitle.WithDryRun(), autotitle.WithNoTagging(), autotitle.WithEvents(func(types.Event) {}))
+	if err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	if len(ops) != 1 || filepath.Base(ops[0].TargetPath) != "Frieren - 01 - The Journey's End.mkv" {
+		t.Fatalf("Expected the Frieren target to apply, got %+v", ops)
+	}
+
+	other := filepath.Join(root, "2024", "Winter", "Unknown")
+	if err := os.MkdirAll(other, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := autotitle.Rename(ctx, other, autotitle.WithDryRun(), autotitle.WithEvents(func(types.Event) {})); err == nil {
+		t.Error("Expected no target for a folder outside every template")
+	}
+}
@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mydehq/autotitle"
+	"github.com/mydehq/autotitle/internal/database"
+	"github.com/mydehq/autotitle/internal/types"
+)
+
+// wrongSeries sets up a folder of Frieren episodes whose map file points at
+// another show, and returns the folder
+func wrongSeries(t *testing.T, globalCfg string) string {
+	t.Helper()
+	ctx := context.Background()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	db, err := database.NewRepository(filepath.Join(home, ".cache", "autotitle", "db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	media := &types.Media{
+		ID: "20", Provider: "mal", Title: "Naruto", Type: types.MediaTypeAnime,
+		Episodes: []types.Episode{{Number: 1, Title: "Enter: Naruto Uzumaki!"}, {Number: 2, Title: "My Name is Konohamaru!"}},
+	}
+	if err := db.Save(ctx, media); err != nil {
+		t.Fatal(err)
+	}
+
+	if globalCfg != "" {
+		dir := filepath.Join(home, ".config", "autotitle")
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "config.yml"), []byte(globalCfg), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dir := t.TempDir()
+	writeFiles(t, dir,
+		"[Group] Sousou no Frieren - 01 [1080p].mkv",
+		"[Group] Sousou no Frieren - 02 [1080p].mkv",
+	)
+	mapFile := `targets:
+  - path: "."
+    url: "https://myanimelist.net/anime/20/Naruto"
+    patterns:
+      - input: ["[Group] Sousou no Frieren - {{EP_NUM}} [{{RES}}].{{EXT}}"]
+        output:
+          fields: [SERIES, EP_NUM, EP_NAME]
+`
+	if err := os.WriteFile(filepath.Join(dir, "_autotitle.yml"), []byte(mapFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestTitleGuard(t *testing.T) {
+	ctx := context.Background()
+	quiet := autotitle.WithEvents(func(types.Event) {})
+
+	t.Run("confirm", func(t *testing.T) {
+		dir := wrongSeries(t, "")
+		_, err := autotitle.Rename(ctx, dir, quiet, autotitle.WithNoTagging())
+		var mismatch types.ErrTitleMismatch
+		if !errors.As(err, &mismatch) {
+			t.Fatalf("Expected ErrTitleMismatch, got %v", err)
+		}
+		if mismatch.Series != "Sousou no Frieren" || mismatch.Title != "Naruto" {
+			t.Errorf("Unexpected mismatch %+v", mismatch)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "[Group] Sousou no Frieren - 01 [1080p].mkv")); err != nil {
+			t.Errorf("File was renamed despite the mismatch: %v", err)
+		}
+
+		ops, err := autotitle.Rename(ctx, dir, quiet, autotitle.WithNoTagging(), autotitle.WithAcceptTitle())
+		if err != nil || len(ops) != 2 || ops[0].Status != types.StatusSuccess {
+			t.Fatalf("Expected the confirmed rename to go ahead, got %+v, %v", ops, err)
+		}
+	})
+
+	t.Run("dry-run warns", func(t *testing.T) {
+		dir := wrongSeries(t, "")
+		var warned bool
+		events := autotitle.WithEvents(func(e types.Event) {
+			warned = warned || e.Type == types.EventWarning
+		})
+		ops, err := autotitle.Rename(ctx, dir, events, autotitle.WithDryRun(), autotitle.WithNoTagging())
+		if err != nil || len(ops) != 2 || !warned {
+			t.Errorf("Expected a warned plan, got %+v, %v (warned %v)", ops, err, warned)
+		}
+	})
+
+	t.Run("warn", func(t *testing.T) {
+		dir := wrongSeries(t, "title_guard:\n  action: warn\n")
+		if _, err := autotitle.Rename(ctx, dir, quiet, autotitle.WithNoTagging()); err != nil {
+			t.Errorf("Expected only a warning, got %v", err)
+		}
+	})
+
+	t.Run("threshold", func(t *testing.T) {
+		dir := wrongSeries(t, "title_guard:\n  min_similarity: 0\n")
+		if _, err := autotitle.Rename(ctx, dir, quiet, autotitle.WithNoTagging()); err != nil {
+			t.Errorf("Expected no check at similarity 0, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,65 @@
+package tests
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mydehq/autotitle"
+	"github.com/mydehq/autotitle/internal/database"
+	"github.com/mydehq/autotitle/internal/types"
+)
+
+// TestDBGen_TrustedKeysInvalidateCache checks that a database file cached
+// before api.trusted_keys was set is not used once it is
+func TestDBGen_TrustedKeysInvalidateCache(t *testing.T) {
+	ctx := context.Background()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/frieren.json" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"title":"Frieren","status":"Finished Airing","episodes":[{"number":1,"title":"The Journey's End"}]}`))
+	}))
+	defer srv.Close()
+	url := srv.URL + "/frieren.json"
+
+	quiet := autotitle.WithEvents(func(types.Event) {})
+	if _, err := autotitle.DBGen(ctx, url, quiet); err != nil {
+		t.Fatalf("Unsigned file without trusted keys: %v", err)
+	}
+	db, err := database.NewRepository("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := base64.RawURLEncoding.EncodeToString([]byte(url))
+	if !db.Exists("url", id) {
+		t.Fatal("Expected the file to be cached")
+	}
+
+	pub, _, _ := ed25519.GenerateKey(nil)
+	cfgDir := filepath.Join(home, ".config", "autotitle")
+	if err := os.MkdirAll(cfgDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	global := "api:\n  trusted_keys:\n    - " + base64.StdEncoding.EncodeToString(pub) + "\n"
+	if err := os.WriteFile(filepath.Join(cfgDir, "config.yml"), []byte(global), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The unsigned entry is dropped rather than served from the cache
+	if _, err := autotitle.DBGen(ctx, url, quiet); err == nil {
+		t.Error("Expected the unsigned file to be refused once keys are trusted")
+	}
+	if db.Exists("url", id) {
+		t.Error("The unsigned cached entry should be gone")
+	}
+}
@@ -0,0 +1,299 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mydehq/autotitle/internal/backup"
+	"github.com/mydehq/autotitle/internal/types"
+)
+
+// renameWithBackup backs up and applies mappings the way the renamer does
+func renameWithBackup(t *testing.T, bm *backup.Manager, dir string, mappings map[string]string, episodes map[string]int) {
+	t.Helper()
+	if err := bm.Backup(context.Background(), dir, mappings, episodes); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	// Two-phase rename so chained mappings don't overwrite each other
+	for oldName := range mappings {
+		if err := os.Rename(filepath.Join(dir, oldName), filepath.Join(dir, oldName+".tmp")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for oldName, newName := range mappings {
+		if err := os.Rename(filepath.Join(dir, oldName+".tmp"), filepath.Join(dir, newName)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	return string(data)
+}
+
+func TestUndo_ConflictDetection(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	bm := backup.New(t.TempDir(), "")
+
+	if err := os.WriteFile(filepath.Join(dir, "01.mkv"), []byte("original"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	renameWithBackup(t, bm, dir, map[string]string{"01.mkv": "Show - 01.mkv"}, nil)
+
+	// User creates a new file under the original name
+	if err := os.WriteFile(filepath.Join(dir, "01.mkv"), []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := bm.Plan(ctx, dir)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if len(entries) != 1 || !entries[0].Conflict {
+		t.Fatalf("Expected one conflicting entry, got %+v", entries)
+	}
+
+	var conflictErr types.ErrRestoreConflict
+	if err := bm.Restore(ctx, dir); !errors.As(err, &conflictErr) {
+		t.Fatalf("Expected ErrRestoreConflict, got %v", err)
+	}
+	if got := readFile(t, filepath.Join(dir, "01.mkv")); got != "new" {
+		t.Errorf("Refused restore must not touch files, got %q", got)
+	}
+
+	bm.Overwrite = true
+	if err := bm.Restore(ctx, dir); err != nil {
+		t.Fatalf("Restore with Overwrite failed: %v", err)
+	}
+	if got := readFile(t, filepath.Join(dir, "01.mkv")); got != "original" {
+		t.Errorf("Expected original content, got %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Show - 01.mkv")); !os.IsNotExist(err) {
+		t.Error("Renamed file should be removed after restore")
+	}
+}
+
+func TestUndo_ConflictResolutions(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	bm := backup.New(t.TempDir(), "")
+
+	mappings := map[string]string{"01.mkv": "Show - 01.mkv", "02.mkv": "Show - 02.mkv", "03.mkv": "Show - 03.mkv"}
+	for oldName := range mappings {
+		if err := os.WriteFile(filepath.Join(dir, oldName), []byte("original "+oldName), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	renameWithBackup(t, bm, dir, mappings, nil)
+	for oldName := range mappings {
+		if err := os.WriteFile(filepath.Join(dir, oldName), []byte("new "+oldName), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// An unresolved conflict still aborts the restore
+	bm.Resolutions = map[string]types.ConflictAction{"01.mkv": types.ConflictKeepNew, "02.mkv": types.ConflictRestore}
+	var conflictErr types.ErrRestoreConflict
+	if err := bm.Restore(ctx, dir); !errors.As(err, &conflictErr) || len(conflictErr.Files) != 1 || conflictErr.Files[0] != "03.mkv" {
+		t.Fatalf("Expected ErrRestoreConflict for 03.mkv, got %v", err)
+	}
+
+	bm.Resolutions["03.mkv"] = types.ConflictKeepBoth
+	if err := bm.Restore(ctx, dir); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+
+	want := map[string]string{
+		"01.mkv":        "new 01.mkv",
+		"Show - 01.mkv": "original 01.mkv",
+		"02.mkv":        "original 02.mkv",
+		"03.mkv":        "new 03.mkv",
+		"03 (1).mkv":    "original 03.mkv",
+	}
+	for name, content := range want {
+		if got := readFile(t, filepath.Join(dir, name)); got != content {
+			t.Errorf("%s = %q, want %q", name, got, content)
+		}
+	}
+	for _, name := range []string{"Show - 02.mkv", "Show - 03.mkv"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("%s should be removed after restore", name)
+		}
+	}
+
+	// The file whose new version was kept stays renamed and backed up
+	entries, err := bm.Plan(ctx, dir)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Original != "01.mkv" {
+		t.Errorf("Expected only 01.mkv left in the backup, got %+v", entries)
+	}
+}
+
+func TestUndo_ChainedRenames(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	bm := backup.New(t.TempDir(), "")
+
+	for _, name := range []string{"01.mkv", "02.mkv"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Offset shift: each file takes the name of the next one
+	renameWithBackup(t, bm, dir, map[string]string{"01.mkv": "02.mkv", "02.mkv": "03.mkv"}, nil)
+
+	entries, err := bm.Plan(ctx, dir)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	for _, e := range entries {
+		if e.Conflict {
+			t.Errorf("Chained rename should not conflict: %+v", e)
+		}
+	}
+
+	if err := bm.Restore(ctx, dir); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	for _, name := range []string{"01.mkv", "02.mkv"} {
+		if got := readFile(t, filepath.Join(dir, name)); got != name {
+			t.Errorf("%s: expected content %q, got %q", name, name, got)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "03.mkv")); !os.IsNotExist(err) {
+		t.Error("03.mkv should be removed after restore")
+	}
+}
+
+func TestUndo_NoBackup(t *testing.T) {
+	bm := backup.New(t.TempDir(), "")
+	var notFound types.ErrBackupNotFound
+	if _, err := bm.Plan(context.Background(), t.TempDir()); !errors.As(err, &notFound) {
+		t.Errorf("Expected ErrBackupNotFound, got %v", err)
+	}
+}
+
+func TestUndo_RestoreOnlySelected(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	bm := backup.New(t.TempDir(), "")
+
+	mappings := map[string]string{}
+	episodes := map[string]int{}
+	for i, name := range []string{"e3.mkv", "e4.mkv", "e5.mkv"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		mappings[name] = "Show - " + name
+		episodes[name] = i + 3
+	}
+	renameWithBackup(t, bm, dir, mappings, episodes)
+
+	// Restore only episode 4
+	err := bm.RestoreOnly(ctx, dir, func(e types.RestoreEntry) bool { return e.Episode == 4 })
+	if err != nil {
+		t.Fatalf("RestoreOnly failed: %v", err)
+	}
+	if got := readFile(t, filepath.Join(dir, "e4.mkv")); got != "e4.mkv" {
+		t.Errorf("Expected e4.mkv restored, got %q", got)
+	}
+	for _, name := range []string{"Show - e3.mkv", "Show - e5.mkv"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("Unselected file %s should stay renamed: %v", name, err)
+		}
+	}
+
+	// The rest remains restorable
+	entries, err := bm.Plan(ctx, dir)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Episode != 3 || entries[1].Episode != 5 {
+		t.Fatalf("Expected episodes 3 and 5 left in backup, got %+v", entries)
+	}
+	if err := bm.Restore(ctx, dir); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "e3.mkv")); err != nil {
+		t.Errorf("Expected e3.mkv restored: %v", err)
+	}
+}
+
+func TestBackup_Verify(t *testing.T) {
+	ctx := context.Background()
+	bm := backup.New(t.TempDir(), "")
+
+	healthy, broken := t.TempDir(), t.TempDir()
+	for _, dir := range []string{healthy, broken} {
+		if err := os.WriteFile(filepath.Join(dir, "01.mkv"), []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		renameWithBackup(t, bm, dir, map[string]string{"01.mkv": "Show - 01.mkv"}, nil)
+	}
+
+	// Lose the backed-up file of one backup
+	if err := os.Remove(filepath.Join(broken, backup.DefaultDirName, "01.mkv")); err != nil {
+		t.Fatal(err)
+	}
+
+	reports, err := bm.Verify(ctx)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if len(reports) != 2 {
+		t.Fatalf("Expected 2 reports, got %d", len(reports))
+	}
+	for _, r := range reports {
+		switch r.Record.SourceDir {
+		case healthy:
+			if len(r.Problems) != 0 || r.Files != 1 {
+				t.Errorf("Healthy backup reported %+v", r)
+			}
+		case broken:
+			if len(r.Problems) != 1 {
+				t.Errorf("Expected one problem for broken backup, got %v", r.Problems)
+			}
+		default:
+			t.Errorf("Unexpected source dir %s", r.Record.SourceDir)
+		}
+	}
+}
+
+func TestUndo_AbsoluteBackupDir(t *testing.T) {
+	ctx := context.Background()
+	cacheRoot := t.TempDir()
+	backupRoot := t.TempDir()
+	dir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(dir, "01.mkv"), []byte("original"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	renameWithBackup(t, backup.New(cacheRoot, backupRoot), dir, map[string]string{"01.mkv": "Show - 01.mkv"}, nil)
+
+	if _, err := os.Stat(filepath.Join(dir, backup.DefaultDirName)); !os.IsNotExist(err) {
+		t.Error("No backup should be created inside the media directory")
+	}
+	entries, err := os.ReadDir(backupRoot)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Expected one backup under the backup root, got %v (%v)", entries, err)
+	}
+
+	// Undo with the default dir name still finds the backup via the registry
+	if err := backup.New(cacheRoot, "").Restore(ctx, dir); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if got := readFile(t, filepath.Join(dir, "01.mkv")); got != "original" {
+		t.Errorf("Expected original content, got %q", got)
+	}
+}
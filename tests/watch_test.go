@@ -0,0 +1,391 @@
+package tests
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mydehq/autotitle"
+	"github.com/mydehq/autotitle/internal/config"
+	"github.com/mydehq/autotitle/internal/database"
+	"github.com/mydehq/autotitle/internal/types"
+	"github.com/mydehq/autotitle/providertest"
+)
+
+// eventLog collects the events of a daemon running in the background
+type eventLog struct {
+	mu     sync.Mutex
+	events []types.Event
+}
+
+func (l *eventLog) add(e types.Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, e)
+}
+
+// find returns the first event matching match, if any
+func (l *eventLog) find(match func(types.Event) bool) (types.Event, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	i := slices.IndexFunc(l.events, match)
+	if i < 0 {
+		return types.Event{}, false
+	}
+	return l.events[i], true
+}
+
+// eventually fails the test unless cond holds within a few seconds
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+		if cond() {
+			return
+		}
+	}
+	t.Fatalf("timed out waiting for %s", what)
+}
+
+// seedShow points HOME at an empty directory with a global config, and
+// stores the database entry of a finished series there, so renaming its
+// folder needs no network. It returns the map file of the folder.
+func seedShow(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	cfgDir := filepath.Join(home, ".config", "autotitle")
+	if err := os.MkdirAll(cfgDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(cfgDir, "config.yml"), []byte("map_file: _autotitle.yml\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := database.NewRepository("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	media := &types.Media{
+		ID: "88", Provider: "mal", Title: "Fixed Show", Slug: "fixed-show", Status: "Finished Airing",
+		EpisodeCount: 2, Episodes: []types.Episode{{Number: 1, Title: "Arrival"}, {Number: 2, Title: "Departure"}},
+	}
+	if err := db.Save(context.Background(), media); err != nil {
+		t.Fatal(err)
+	}
+	return `targets:
+  - path: "."
+    url: "https://myanimelist.net/anime/88/Fixed_Show"
+    patterns:
+      - input: ["Fixed Show - {{EP_NUM}}.{{EXT}}"]
+        output:
+          fields: [SERIES, EP_NUM, EP_NAME]
+          separator: " - "
+`
+}
+
+// startDaemon runs a daemon watching root until the test ends
+func startDaemon(t *testing.T, root string, log *eventLog, opts ...autotitle.Option) *autotitle.Daemon {
+	t.Helper()
+	opts = append([]autotitle.Option{
+		autotitle.WithSettle(50 * time.Millisecond), autotitle.WithNoBackup(), autotitle.WithNoTagging(), autotitle.WithEvents(log.add),
+	}, opts...)
+	d, err := autotitle.NewDaemon([]string{root}, opts...)
+	if err != nil {
+		t.Fatalf("NewDaemon failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- d.Run(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; !errors.Is(err, context.Canceled) {
+			t.Errorf("Run = %v, want context.Canceled", err)
+		}
+	})
+	return d
+}
+
+func TestDaemon_RenamesAndReloads(t *testing.T) {
+	mapFile := seedShow(t)
+	home := os.Getenv("HOME")
+	root := filepath.Join(home, "Anime")
+	dir := filepath.Join(root, "Fixed Show")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	mapPath := filepath.Join(dir, "_autotitle.yml")
+	if err := os.WriteFile(mapPath, []byte(mapFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	log := &eventLog{}
+	d := startDaemon(t, root, log)
+
+	// A new episode is renamed once it has settled
+	writeFiles(t, dir, "Fixed Show - 01.mkv")
+	eventually(t, "episode 1 renamed", func() bool {
+		_, err := os.Stat(filepath.Join(dir, "Fixed Show - 01 - Arrival.mkv"))
+		return err == nil
+	})
+
+	// The rename itself is not taken for a change to rename again, and the
+	// next episode does not report the files renamed before
+	writeFiles(t, dir, "Fixed Show - 03.mkv")
+	eventually(t, "episode 3 skipped", func() bool {
+		_, ok := log.find(func(e types.Event) bool {
+			op, ok := e.Data.(types.RenameOperation)
+			return ok && filepath.Base(op.SourcePath) == "Fixed Show - 03.mkv"
+		})
+		return ok
+	})
+	if _, ok := log.find(func(e types.Event) bool {
+		return strings.Contains(e.Message, "Fixed Show - 01 - Arrival.mkv") && e.Type == types.EventWarning
+	}); ok {
+		t.Error("Files renamed before should not be reported as skipped again")
+	}
+
+	reloaded := func(path, key string) func() bool {
+		return func() bool {
+			_, ok := log.find(func(e types.Event) bool {
+				r, ok := e.Data.(types.ConfigReload)
+				return ok && r.Path == path && slices.Contains(r.Changed, key)
+			})
+			return ok
+		}
+	}
+
+	// An edit to the global config is reloaded and reported
+	globalPath := filepath.Join(home, ".config", "autotitle", "config.yml")
+	global, err := os.ReadFile(globalPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(globalPath, append(global, "api:\n  timeout: 5\n"...), 0644); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, "global config reload", reloaded(globalPath, "api"))
+
+	// A broken one is reported, and the last good one kept
+	if err := os.WriteFile(globalPath, []byte("api: [unclosed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	d.Reload()
+	eventually(t, "broken config warning", func() bool {
+		_, ok := log.find(func(e types.Event) bool { return strings.Contains(e.Message, "Global config not reloaded") })
+		return ok
+	})
+	if cfg, err := config.LoadGlobal(); err != nil || cfg.API.Timeout != 5 {
+		t.Errorf("LoadGlobal = %v, %v; want the last good config", cfg, err)
+	}
+
+	// A map file edit is reported, and the folder renamed with it
+	if err := os.WriteFile(mapPath, []byte(strings.Replace(mapFile, `" - "`, `" | "`, 1)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, "map file load", func() bool {
+		_, ok := log.find(func(e types.Event) bool {
+			r, ok := e.Data.(types.ConfigReload)
+			return ok && r.Path == mapPath
+		})
+		return ok
+	})
+	writeFiles(t, dir, "Fixed Show - 02.mkv")
+	eventually(t, "episode 2 renamed", func() bool {
+		_, err := os.Stat(filepath.Join(dir, "Fixed Show | 02 | Departure.mkv"))
+		return err == nil
+	})
+	if err := os.WriteFile(mapPath, []byte(mapFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, "map file reload", reloaded(mapPath, "targets"))
+}
+
+func TestDaemon_Status(t *testing.T) {
+	mapFile := seedShow(t)
+	root := filepath.Join(os.Getenv("HOME"), "Anime")
+	dir := filepath.Join(root, "Fixed Show")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "_autotitle.yml"), []byte(mapFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	log := &eventLog{}
+	d := startDaemon(t, root, log)
+	eventually(t, "daemon ready", func() bool { return d.Status(context.Background(), false).Ready() })
+
+	writeFiles(t, dir, "Fixed Show - 01.mkv", "Fixed Show - 02.mkv")
+	eventually(t, "run recorded", func() bool { return len(d.Status(context.Background(), false).Runs) > 0 })
+
+	s := d.Status(context.Background(), false)
+	if run := s.Runs[0]; run.Dir != dir || run.Renamed != 2 || run.Error != "" {
+		t.Errorf("run = %+v, want 2 renamed in %s", run, dir)
+	}
+	if !slices.Equal(s.Roots, []string{root}) || s.Started.IsZero() {
+		t.Errorf("status = %+v, want started on %s", s, root)
+	}
+	if s.Providers != nil {
+		t.Errorf("Providers = %v, want none without the checks", s.Providers)
+	}
+
+	// The batch is reported as a manifest, for the webhook sink
+	e, ok := log.find(func(e types.Event) bool { _, ok := e.Data.(types.BatchManifest); return ok })
+	if !ok {
+		t.Fatal("No batch manifest emitted")
+	}
+	m := e.Data.(types.BatchManifest)
+	sum := sha256.Sum256([]byte("Fixed Show - 01.mkv"))
+	if m.Directory != dir || len(m.Mappings) != 2 || m.Finished.Before(m.Started) {
+		t.Fatalf("manifest = %+v, want 2 renames in %s", m, dir)
+	}
+	if i := slices.IndexFunc(m.Mappings, func(mm types.ManifestMapping) bool { return mm.From == "Fixed Show - 01.mkv" }); i < 0 ||
+		m.Mappings[i].To != "Fixed Show - 01 - Arrival.mkv" || m.Mappings[i].FromSHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("mappings = %+v, want Fixed Show - 01.mkv with its checksum", m.Mappings)
+	}
+}
+
+// The provider checks of /status run alongside a rename: run with -race
+func TestDaemon_StatusWhileRenaming(t *testing.T) {
+	srv := providertest.NewServer()
+	t.Cleanup(srv.Close)
+	srv.PageSize = 1
+	srv.AddAnime(providertest.Anime{
+		ID: 88, Title: "Fixed Show", Status: "Finished Airing",
+		Episodes: []providertest.Episode{{Number: 1, Title: "Arrival"}, {Number: 2, Title: "Departure"}},
+	})
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	cfgDir := filepath.Join(home, ".config", "autotitle")
+	if err := os.MkdirAll(cfgDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// Every provider checked goes to the fake server. The rate limit keeps
+	// the rename waiting between its two episode pages.
+	global := fmt.Sprintf("api:\n  rate_limit: 2\n  base_urls:\n    mal: %q\n    mal-novel: %q\n    mangadex: %q\n    musicbrainz: %q\n    trakt: %q\n",
+		srv.JikanURL(), srv.JikanURL(), srv.URL, srv.URL, srv.URL)
+	if err := os.WriteFile(filepath.Join(cfgDir, "config.yml"), []byte(global), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	root := filepath.Join(home, "Anime")
+	dir := filepath.Join(root, "Fixed Show")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	mapFile := `targets:
+  - path: "."
+    url: "https://myanimelist.net/anime/88/Fixed_Show"
+    patterns:
+      - input: ["Fixed Show - {{EP_NUM}}.{{EXT}}"]
+        output:
+          fields: [SERIES, EP_NUM, EP_NAME]
+          separator: " - "
+`
+	if err := os.WriteFile(filepath.Join(dir, "_autotitle.yml"), []byte(mapFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The fetch reports its pages to the default handler
+	progress := &eventLog{}
+	autotitle.SetDefaultEventHandler(progress.add)
+	t.Cleanup(func() { autotitle.SetDefaultEventHandler(nil) })
+
+	d := startDaemon(t, root, &eventLog{})
+	eventually(t, "daemon ready", func() bool { return d.Status(context.Background(), false).Ready() })
+	writeFiles(t, dir, "Fixed Show - 01.mkv", "Fixed Show - 02.mkv")
+	eventually(t, "first page fetched", func() bool {
+		_, ok := progress.find(func(e types.Event) bool { _, ok := e.Data.(types.FetchProgress); return ok })
+		return ok
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s := d.Status(ctx, true)
+	if i := slices.IndexFunc(s.Providers, func(h autotitle.ProviderHealth) bool { return h.Name == "mal" }); i < 0 || !s.Providers[i].Checked {
+		t.Errorf("Providers = %+v, want mal checked", s.Providers)
+	}
+
+	eventually(t, "run recorded", func() bool { return len(d.Status(context.Background(), false).Runs) > 0 })
+	if run := d.Status(context.Background(), false).Runs[0]; run.Renamed != 2 || run.Error != "" {
+		t.Errorf("run = %+v, want 2 renamed", run)
+	}
+}
+
+func TestDaemon_SetsUpNewFolders(t *testing.T) {
+	ctx := context.Background()
+	useFakeServer(t, newSortServer(t))
+
+	home := os.Getenv("HOME")
+	root := filepath.Join(home, "Anime")
+	if err := os.MkdirAll(root, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// moveIn moves a folder of files into the library in one go, as a
+	// download client finishing does
+	moveIn := func(name string, files ...string) string {
+		t.Helper()
+		staged := filepath.Join(home, "incoming", name)
+		if err := os.MkdirAll(staged, 0755); err != nil {
+			t.Fatal(err)
+		}
+		writeFiles(t, staged, files...)
+		dir := filepath.Join(root, name)
+		if err := os.Rename(staged, dir); err != nil {
+			t.Fatal(err)
+		}
+		return dir
+	}
+	exists := func(path string) func() bool {
+		return func() bool { _, err := os.Stat(path); return err == nil }
+	}
+
+	log := &eventLog{}
+	startDaemon(t, root, log)
+
+	// A confident match gets a map file, and the folder is renamed
+	golden := moveIn("[Group] Golden Show (2024)", "Golden Show - 01.mkv", "Golden Show - 02.mkv")
+	eventually(t, "new folder renamed", exists(filepath.Join(golden, "E02 - Golden Show 2.mkv")))
+	if _, err := os.Stat(filepath.Join(golden, "_autotitle.yml")); err != nil {
+		t.Errorf("Expected a map file: %v", err)
+	}
+
+	// A weak one is parked for review, which sets the folder up in place
+	weak := moveIn("Golden", "Golden - 01.mkv")
+	var items []types.ReviewItem
+	eventually(t, "new folder parked", func() bool {
+		items, _ = autotitle.ReviewQueue(ctx)
+		return len(items) == 1
+	})
+	if item := items[0]; item.Dir != weak || !item.Group.InPlace || len(item.Candidates) == 0 {
+		t.Fatalf("Expected the folder parked in place, got %+v", item)
+	}
+	if _, err := os.Stat(filepath.Join(weak, "_autotitle.yml")); err == nil {
+		t.Error("A parked folder must not get a map file")
+	}
+
+	g, err := autotitle.AcceptReview(ctx, items[0].ID, items[0].Candidates[0], autotitle.WithEvents(log.add), autotitle.WithNoTagging(), autotitle.WithNoBackup())
+	if err != nil {
+		t.Fatalf("AcceptReview failed: %v", err)
+	}
+	if g.Folder != weak {
+		t.Errorf("Folder = %s, want %s", g.Folder, weak)
+	}
+	if _, err := os.Stat(filepath.Join(weak, "E01 - Golden Show 1.mkv")); err != nil {
+		t.Errorf("Expected the file renamed in place: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(weak, "Golden Show")); err == nil {
+		t.Error("An in-place group must not be moved into a new folder")
+	}
+}